@@ -6,13 +6,17 @@ import (
 	"ChatServer/apps/connect/internal/manager"
 	"ChatServer/apps/connect/internal/server"
 	"ChatServer/apps/connect/internal/svc"
+	msgpb "ChatServer/apps/msg/pb"
 	userpb "ChatServer/apps/user/pb"
 	"ChatServer/config"
+	"ChatServer/pkg/connectroute"
 	"ChatServer/pkg/ctxmeta"
 	"ChatServer/pkg/deviceactive"
 	"ChatServer/pkg/logger"
 	pkgredis "ChatServer/pkg/redis"
+	"ChatServer/pkg/util"
 	"context"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -65,6 +69,8 @@ func main() {
 		userGRPCAddr = ":9090"
 	}
 	var userDeviceClient userpb.DeviceServiceClient
+	var userFriendClient userpb.FriendServiceClient
+	var userBlacklistClient userpb.BlacklistServiceClient
 	var userGRPCConn *googlegrpc.ClientConn
 	userGRPCConn, err = googlegrpc.NewClient(
 		userGRPCAddr,
@@ -77,6 +83,8 @@ func main() {
 		)
 	} else {
 		userDeviceClient = userpb.NewDeviceServiceClient(userGRPCConn)
+		userFriendClient = userpb.NewFriendServiceClient(userGRPCConn)
+		userBlacklistClient = userpb.NewBlacklistServiceClient(userGRPCConn)
 		logger.Info(ctx, "user-service gRPC 客户端初始化成功",
 			logger.String("addr", userGRPCAddr),
 		)
@@ -153,20 +161,199 @@ func main() {
 	// - svc:     connect 业务逻辑（鉴权、心跳、活跃时间、设备状态）。
 	// - handler: Gin /ws 入口，承接协议层逻辑。
 	connManager := manager.NewConnectionManager()
-	connectSvc := svc.NewConnectService(redisClient, userDeviceClient, activeSyncer)
-	wsHandler := handler.NewWSHandler(connManager, connectSvc)
+	wsCfg := config.DefaultConnectWSConfig()
+	keepaliveCfg := config.DefaultConnectKeepaliveConfig()
+	connectSvc := svc.NewConnectService(redisClient, userDeviceClient, activeSyncer, svc.UplinkLimitConfig{
+		ConnLimits:      toRateSpecs(wsCfg.UplinkLimits),
+		UserLimits:      toRateSpecs(wsCfg.UplinkUserLimits),
+		ViolationWindow: wsCfg.UplinkViolationWindow,
+		CloseAfter:      wsCfg.UplinkCloseAfter,
+		BanAfter:        wsCfg.UplinkBanAfter,
+		BanWindow:       wsCfg.UplinkBanWindow,
+		BanDuration:     wsCfg.UplinkBanDuration,
+		BanByIP:         wsCfg.UplinkBanByIP,
+	})
+	ackCfg := config.DefaultConnectAckConfig()
+	authCfg := config.DefaultConnectAuthConfig()
+	slowConsumerCfg, err := manager.NewSlowConsumerConfig(wsCfg.SendQueueSize, wsCfg.SlowConsumerPolicies, wsCfg.SlowConsumerDefaultPolicy)
+	if err != nil {
+		logger.Warn(ctx, "Connect 慢消费策略配置非法，使用默认策略",
+			logger.ErrorField("error", err),
+		)
+		slowConsumerCfg = manager.DefaultSlowConsumerConfig()
+	}
+	wsHandler := handler.NewWSHandler(connManager, connectSvc, manager.ClientOptions{
+		Ack: manager.AckConfig{
+			WindowSize: ackCfg.WindowSize,
+			Timeout:    ackCfg.Timeout,
+			MaxBackoff: ackCfg.MaxBackoff,
+			MaxRetries: ackCfg.MaxRetries,
+		},
+		SlowConsumer: slowConsumerCfg,
+		Compression: manager.CompressionConfig{
+			Enabled:   wsCfg.Compression,
+			Level:     wsCfg.CompressionLevel,
+			Threshold: wsCfg.CompressionThreshold,
+		},
+		Batch:     wsCfg.Batch,
+		ReadLimit: int64(wsCfg.MaxMessageBytes),
+		MaxTopics: wsCfg.MaxTopics,
+		Keepalive: manager.KeepaliveConfig{
+			PingPeriod: keepaliveCfg.PingPeriod,
+			PongWait:   keepaliveCfg.PongWait,
+		},
+	}, svc.AuthWatchConfig{
+		ExpiringLead:        authCfg.ExpiringLead,
+		RevokeCheckInterval: authCfg.RevokeCheckInterval,
+	})
+	wsHandler.SetHandshake(handler.HandshakeConfig{
+		AllowedOrigins:  authCfg.AllowedOrigins,
+		AllowQueryToken: authCfg.AllowQueryToken,
+	})
+	if len(authCfg.AllowedOrigins) == 0 {
+		logger.Warn(ctx, "Connect 未配置握手 Origin 白名单，接受任意来源（生产环境请配置 CONNECT_WS_ALLOWED_ORIGINS）")
+	}
+	wsHandler.SetHeartbeat(svc.HeartbeatConfig{
+		Interval:          keepaliveCfg.HeartbeatInterval,
+		PlatformIntervals: keepaliveCfg.PlatformHeartbeatIntervals,
+		MissedLimit:       keepaliveCfg.HeartbeatMissedLimit,
+	})
+	logger.Info(ctx, "Connect 保活配置已加载",
+		logger.Duration("ping_period", keepaliveCfg.PingPeriod),
+		logger.Duration("pong_wait", keepaliveCfg.PongWait),
+		logger.Duration("heartbeat_interval", keepaliveCfg.HeartbeatInterval),
+		logger.Int("heartbeat_missed_limit", keepaliveCfg.HeartbeatMissedLimit),
+	)
+
+	grpcAddr := os.Getenv("CONNECT_GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = ":9091"
+	}
+
+	// 4.5) 初始化集群路由注册表。
+	// 本节点上的连接登记到 Redis（user_uuid/device_id -> node），供其他服务的 Router 定位节点。
+	// 降级策略：无 Redis 或节点登记失败时以单节点模式运行，跨节点投递不可用。
+	routeCfg := config.DefaultConnectRouteConfig()
+	nodeAddr := routeCfg.NodeAddr
+	if nodeAddr == "" {
+		nodeAddr = defaultNodeAddr(grpcAddr)
+	}
+	routeRegistry := connectroute.NewRegistry(redisClient, connectroute.Node{
+		ID:   util.NewUUID(),
+		Addr: nodeAddr,
+	}, connectroute.RegistryConfig{
+		RouteTTL:              routeCfg.RouteTTL,
+		NodeTTL:               routeCfg.NodeTTL,
+		NodeHeartbeatInterval: routeCfg.NodeHeartbeatInterval,
+	})
+	if routeRegistry != nil {
+		if err := routeRegistry.Start(ctx); err != nil {
+			logger.Warn(ctx, "Connect 节点路由登记失败，降级为单节点模式",
+				logger.String("node_addr", nodeAddr),
+				logger.ErrorField("error", err),
+			)
+			routeRegistry = nil
+		} else {
+			connManager.SetRouteHook(svc.NewRouteHook(routeRegistry))
+			logger.Info(ctx, "Connect 节点路由登记完成",
+				logger.String("node_id", routeRegistry.Node().ID),
+				logger.String("node_addr", nodeAddr),
+				logger.Duration("route_ttl", routeCfg.RouteTTL),
+				logger.Duration("node_ttl", routeCfg.NodeTTL),
+			)
+		}
+	}
+
+	// 4.6) 初始化好友在线状态推送。
+	// 用户首个设备上线/最后一个设备下线时防抖结算全局在线状态，经集群路由推送给在线好友。
+	// 依赖集群路由表判断全局在线，单节点模式（无路由注册表）下不推送。
+	presenceCfg := config.DefaultConnectPresenceConfig()
+	var presenceRouter *connectroute.Router
+	var presence *svc.Presence
+	if presenceCfg.Enabled && routeRegistry != nil {
+		presenceRouter = connectroute.NewRouter(redisClient, connectroute.RouterConfig{})
+		presence = svc.NewPresence(redisClient, presenceRouter, svc.PresenceConfig{
+			OnlineDelay:  presenceCfg.OnlineDelay,
+			OfflineGrace: presenceCfg.OfflineGrace,
+		})
+		connManager.SetPresenceHook(presence)
+		logger.Info(ctx, "Connect 好友在线状态推送已启用",
+			logger.Duration("online_delay", presenceCfg.OnlineDelay),
+			logger.Duration("offline_grace", presenceCfg.OfflineGrace),
+		)
+	}
+
+	// 4.7) 初始化 1:1 通话信令中继。
+	// 集群模式下通话状态存 Redis、信令经集群路由投递（主被叫可在不同节点）；单节点模式存内存、直接投递本地连接。
+	// 配置了 MSG_GRPC_ADDR 时通话结束后经 msg 服务在单聊会话写入通话记录。
+	// 只能呼叫好友且双方未拉黑：优先读 Redis 关系缓存，未命中时回源 user-service。
+	callCfg := config.DefaultConnectCallConfig()
+	var callRouter *connectroute.Router
+	var msgGRPCConn *googlegrpc.ClientConn
+	if callCfg.Enabled {
+		callDelivery := svc.NewLocalCallDelivery(connManager)
+		callRedis := redisClient
+		if routeRegistry != nil {
+			callRouter = connectroute.NewRouter(redisClient, connectroute.RouterConfig{})
+			callDelivery = svc.NewRouterCallDelivery(callRouter)
+		} else {
+			// 单节点模式下通话状态只存本节点内存，与本地投递保持一致。
+			callRedis = nil
+		}
+
+		var callRecorder svc.CallRecorder
+		if callCfg.MsgGRPCAddr != "" {
+			msgGRPCConn, err = googlegrpc.NewClient(
+				callCfg.MsgGRPCAddr,
+				googlegrpc.WithTransportCredentials(insecure.NewCredentials()),
+			)
+			if err != nil {
+				logger.Warn(ctx, "msg-service gRPC 连接创建失败，通话记录不写入",
+					logger.String("addr", callCfg.MsgGRPCAddr),
+					logger.ErrorField("error", err),
+				)
+			} else {
+				callRecorder = svc.NewMsgCallRecorder(msgpb.NewMsgServiceClient(msgGRPCConn))
+			}
+		} else {
+			logger.Info(ctx, "未配置 MSG_GRPC_ADDR，通话记录不写入")
+		}
+
+		callGuard := svc.NewRelationCallGuard(redisClient, userFriendClient, userBlacklistClient)
+		wsHandler.SetCalls(svc.NewCalls(callRedis, callDelivery, callRecorder, callGuard, svc.CallConfig{
+			RingTimeout:     callCfg.RingTimeout,
+			DisconnectGrace: callCfg.DisconnectGrace,
+			StateTTL:        callCfg.StateTTL,
+		}))
+		logger.Info(ctx, "Connect 通话信令已启用",
+			logger.Bool("cluster", callRouter != nil),
+			logger.Bool("record", callRecorder != nil),
+			logger.Duration("ring_timeout", callCfg.RingTimeout),
+			logger.Duration("disconnect_grace", callCfg.DisconnectGrace),
+		)
+	}
 
 	// 5) 构建 HTTP 服务（包含 /health、/metrics 与 /ws）。
 	srvCfg := server.DefaultConfig()
+	srvCfg.HandshakeBan = connectSvc
 	srv := server.New(srvCfg, wsHandler, connManager)
 
+	// 5.5) 构建运维 HTTP 服务（连接查询、强制断开、节点排空），独立端口 + 令牌鉴权。
+	// 未配置 CONNECT_ADMIN_TOKEN 时不启动，避免无鉴权暴露。
+	adminCfg := config.DefaultConnectAdminConfig()
+	var adminSrv *server.Server
+	if adminCfg.Token != "" {
+		adminSrv = server.NewAdmin(adminCfg.Addr, adminCfg.Token, handler.NewAdminHandler(connManager, connectSvc, handler.DrainConfig{
+			Endpoint: adminCfg.DrainEndpoint,
+			Rate:     adminCfg.DrainRate,
+		}))
+	} else {
+		logger.Warn(ctx, "未配置 CONNECT_ADMIN_TOKEN，Connect 运维接口未启用")
+	}
+
 	// 6) 构建 gRPC 服务。
 	// gRPC 监听独立端口，提供 PushToDevice/PushToUser/BroadcastToUsers/
 	// KickConnection/GetOnlineStatus/BatchGetOnlineStatus。
-	grpcAddr := os.Getenv("CONNECT_GRPC_ADDR")
-	if grpcAddr == "" {
-		grpcAddr = ":9091"
-	}
 	grpcSrv := grpc.NewServer(grpcAddr, connManager)
 
 	// 7) 后台启动 HTTP 监听。
@@ -194,6 +381,20 @@ func main() {
 		}
 	}()
 
+	// 8.5) 后台启动运维 HTTP 监听。
+	if adminSrv != nil {
+		go func() {
+			logger.Info(ctx, "Connect 运维服务启动中",
+				logger.String("addr", adminCfg.Addr),
+			)
+			if err := adminSrv.Start(); err != nil && err != http.ErrServerClosed {
+				logger.Error(ctx, "Connect 运维服务启动失败",
+					logger.ErrorField("error", err),
+				)
+			}
+		}()
+	}
+
 	// 9) 阻塞等待系统退出信号（Ctrl+C / SIGTERM）。
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -201,16 +402,50 @@ func main() {
 
 	// 10) 优雅关闭流程：
 	// - 先停 gRPC（不再接受新的 RPC 调用）。
+	// - 注销节点路由，集群内不再把推送转发到本节点。
 	// - 再关闭连接管理器，主动断开所有 WebSocket 连接，避免悬挂连接。
-	// - 关闭 user-service gRPC 连接。
+	// - 关闭 user-service/msg-service gRPC 连接。
+	// - 关闭运维 HTTP 服务。
 	// - 最后关闭 HTTP 服务，等待进行中的请求在超时时间内结束。
 	logger.Info(ctx, "Connect 服务开始优雅停机")
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
 	grpcSrv.Stop()
+	if routeRegistry != nil {
+		// 先删除节点存活 Key，其他服务的 Router 会立即停止向本节点转发并清理残留路由。
+		if err := routeRegistry.Stop(shutdownCtx); err != nil {
+			logger.Warn(ctx, "Connect 节点路由注销失败",
+				logger.ErrorField("error", err),
+			)
+		}
+	}
+	if presence != nil {
+		presence.Stop()
+	}
 	connManager.Shutdown()
 	connectSvc.ShutdownStatusWorkers()
+	if presenceRouter != nil {
+		if closeErr := presenceRouter.Close(); closeErr != nil {
+			logger.Warn(ctx, "关闭在线状态推送路由连接失败",
+				logger.ErrorField("error", closeErr),
+			)
+		}
+	}
+	if callRouter != nil {
+		if closeErr := callRouter.Close(); closeErr != nil {
+			logger.Warn(ctx, "关闭通话信令路由连接失败",
+				logger.ErrorField("error", closeErr),
+			)
+		}
+	}
+	if msgGRPCConn != nil {
+		if closeErr := msgGRPCConn.Close(); closeErr != nil {
+			logger.Warn(ctx, "关闭 msg-service gRPC 连接失败",
+				logger.ErrorField("error", closeErr),
+			)
+		}
+	}
 	if userGRPCConn != nil {
 		if closeErr := userGRPCConn.Close(); closeErr != nil {
 			logger.Warn(ctx, "关闭 user-service gRPC 连接失败",
@@ -218,6 +453,13 @@ func main() {
 			)
 		}
 	}
+	if adminSrv != nil {
+		if err := adminSrv.Shutdown(shutdownCtx); err != nil {
+			logger.Warn(ctx, "Connect 运维服务关闭失败",
+				logger.ErrorField("error", err),
+			)
+		}
+	}
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		logger.Error(ctx, "Connect 服务优雅停机失败",
 			logger.ErrorField("error", err),
@@ -227,3 +469,26 @@ func main() {
 
 	logger.Info(ctx, "Connect 服务已退出")
 }
+
+// defaultNodeAddr 未配置 CONNECT_NODE_ADDR 时，用 hostname + gRPC 监听端口推导节点地址。
+func defaultNodeAddr(grpcAddr string) string {
+	host, port, err := net.SplitHostPort(grpcAddr)
+	if err != nil {
+		return grpcAddr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		if hostname, hostErr := os.Hostname(); hostErr == nil && hostname != "" {
+			host = hostname
+		}
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// toRateSpecs 把配置层的令牌桶参数转换为 svc 层类型。
+func toRateSpecs(specs map[string]config.RateLimitSpec) map[string]svc.RateSpec {
+	result := make(map[string]svc.RateSpec, len(specs))
+	for msgType, spec := range specs {
+		result[msgType] = svc.RateSpec{Rate: spec.Rate, Burst: spec.Burst}
+	}
+	return result
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"ChatServer/apps/connect/internal/codec"
+	"ChatServer/apps/connect/pb"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// benchPushType 压测推送的下行帧类型，data 为 benchPushData。
+const benchPushType = "bench_push"
+
+// benchPushData 压测推送的 data，sent_at 为调用推送 RPC 前的纳秒时间戳（压测机本地时钟）。
+type benchPushData struct {
+	SentAt int64 `json:"sent_at"`
+}
+
+// helloData hello 帧中压测关心的字段。
+type helloData struct {
+	HeartbeatInterval int64 `json:"heartbeat_interval"`
+}
+
+// benchClient 模拟的单个客户端连接。
+type benchClient struct {
+	userUUID string
+	deviceID string
+	conn     *websocket.Conn
+	codec    codec.Codec
+	// heartbeat 心跳间隔：命令行指定优先，否则使用 hello 帧下发的推荐值。
+	heartbeat time.Duration
+
+	writeMu sync.Mutex
+	// pending 已发送、等待 message_ack 的 message 发送时间（服务端按序回执）。
+	pendingMu sync.Mutex
+	pending   []time.Time
+}
+
+// dialClient 建立连接并读取 hello 帧，返回从发起握手到收到 hello 的耗时。
+func dialClient(ctx context.Context, cfg benchConfig, userUUID, deviceID, token string) (*benchClient, time.Duration, error) {
+	query := url.Values{}
+	query.Set("token", token)
+	query.Set("device_id", deviceID)
+	if cfg.platform != "" {
+		query.Set("platform", cfg.platform)
+	}
+	target := cfg.wsURL
+	if strings.Contains(target, "?") {
+		target += "&" + query.Encode()
+	} else {
+		target += "?" + query.Encode()
+	}
+
+	subprotocol := codec.SubprotocolJSON
+	if cfg.proto {
+		subprotocol = codec.SubprotocolProto
+	}
+	dialer := websocket.Dialer{
+		HandshakeTimeout: 10 * time.Second,
+		Subprotocols:     []string{subprotocol},
+		ReadBufferSize:   4096,
+		WriteBufferSize:  4096,
+	}
+
+	start := time.Now()
+	conn, resp, err := dialer.DialContext(ctx, target, nil)
+	if err != nil {
+		if resp != nil {
+			return nil, 0, fmt.Errorf("dial: %w (http %d)", err, resp.StatusCode)
+		}
+		return nil, 0, fmt.Errorf("dial: %w", err)
+	}
+	client := &benchClient{
+		userUUID:  userUUID,
+		deviceID:  deviceID,
+		conn:      conn,
+		codec:     codec.ForSubprotocol(conn.Subprotocol()),
+		heartbeat: cfg.heartbeat,
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	envelope, err := client.read()
+	if err != nil {
+		_ = conn.Close()
+		return nil, 0, fmt.Errorf("read hello: %w", err)
+	}
+	latency := time.Since(start)
+	_ = conn.SetReadDeadline(time.Time{})
+	if envelope.GetType() != "hello" {
+		_ = conn.Close()
+		return nil, 0, fmt.Errorf("unexpected first frame %q", envelope.GetType())
+	}
+	if client.heartbeat <= 0 {
+		var hello helloData
+		if err := json.Unmarshal(envelope.GetData(), &hello); err == nil && hello.HeartbeatInterval > 0 {
+			client.heartbeat = time.Duration(hello.HeartbeatInterval) * time.Second
+		} else {
+			client.heartbeat = 30 * time.Second
+		}
+	}
+	return client, latency, nil
+}
+
+// run 启动读循环并按配置发送心跳与消息，ctx 结束时正常关闭连接。
+func (c *benchClient) run(ctx context.Context, cfg benchConfig, b *bench) {
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		c.readLoop(ctx, b)
+	}()
+
+	heartbeat := time.NewTicker(c.heartbeat)
+	defer heartbeat.Stop()
+	// 随机错开首次发送 message 的时间，避免所有连接同一时刻上行。
+	var messageTick <-chan time.Time
+	if cfg.msgInterval > 0 {
+		sleepContext(ctx, rand.N(cfg.msgInterval))
+		ticker := time.NewTicker(cfg.msgInterval)
+		defer ticker.Stop()
+		messageTick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.writeMu.Lock()
+			_ = c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bench done"),
+				time.Now().Add(time.Second))
+			c.writeMu.Unlock()
+			_ = c.conn.Close()
+			<-readDone
+			return
+		case <-readDone:
+			return
+		case <-heartbeat.C:
+			if err := c.write(&pb.MessageEnvelope{Type: "heartbeat"}); err != nil {
+				b.writeErrors.Add(1)
+			}
+		case <-messageTick:
+			c.pendingMu.Lock()
+			c.pending = append(c.pending, time.Now())
+			c.pendingMu.Unlock()
+			if err := c.write(&pb.MessageEnvelope{Type: "message", Data: cfg.messageData}); err != nil {
+				b.writeErrors.Add(1)
+				continue
+			}
+			b.messagesSent.Add(1)
+		}
+	}
+}
+
+// readLoop 读取下行帧：统计 message_ack 往返与推送到达延迟；非主动关闭的断开计入 dropped。
+func (c *benchClient) readLoop(ctx context.Context, b *bench) {
+	for {
+		envelope, err := c.read()
+		if err != nil {
+			if ctx.Err() == nil {
+				b.dropped.Add(1)
+				var closeErr *websocket.CloseError
+				if errors.As(err, &closeErr) {
+					b.recordClose(closeErr.Code)
+				} else {
+					b.recordClose(0)
+				}
+			}
+			return
+		}
+		now := time.Now()
+		switch envelope.GetType() {
+		case "message_ack":
+			c.pendingMu.Lock()
+			if len(c.pending) > 0 {
+				b.messageRTT.add(now.Sub(c.pending[0]))
+				c.pending = c.pending[1:]
+			}
+			c.pendingMu.Unlock()
+		case benchPushType:
+			var data benchPushData
+			if err := json.Unmarshal(envelope.GetData(), &data); err == nil && data.SentAt > 0 {
+				b.pushLatency.add(now.Sub(time.Unix(0, data.SentAt)))
+			}
+			b.pushReceived.Add(1)
+		case "error":
+			b.errorFrames.Add(1)
+		}
+	}
+}
+
+func (c *benchClient) read() (*pb.MessageEnvelope, error) {
+	_, raw, err := c.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	return c.codec.Decode(raw)
+}
+
+func (c *benchClient) write(envelope *pb.MessageEnvelope) error {
+	payload, err := c.codec.Encode(envelope)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	return c.conn.WriteMessage(c.codec.MessageType(), payload)
+}
@@ -0,0 +1,449 @@
+// wsbench connect 节点压测工具：模拟大量 WebSocket 客户端并调用 connect gRPC 推送接口，
+// 统计建连延迟、消息回执往返、推送扇出延迟分位数，以及（配置了 -metrics 时）服务端单连接内存开销。
+//
+// 本地单节点用法（connect 不配置 Redis 时鉴权退化为仅 JWT 校验，无需准备登录态；
+// 所有连接来自同一 IP，需调大握手限流）：
+//
+//	CONNECT_WS_HANDSHAKE_RATE=100000 CONNECT_WS_HANDSHAKE_BURST=100000 go run ./apps/connect/cmd
+//	go run ./apps/connect/cmd/wsbench -conns 5000 -rate 500 -duration 60s \
+//		-grpc 127.0.0.1:9091 -push-rate 50 -push-mode broadcast -broadcast-size 200 \
+//		-metrics http://127.0.0.1:8081/metrics
+//
+// 需要验证 Redis 相关链路时，可用内嵌 miniredis 代替真实 Redis：
+//
+//	go run ./apps/connect/cmd/wsbench -miniredis 127.0.0.1:6379 -conns 0   # 仅启动 miniredis
+//	REDIS_ADDR=127.0.0.1:6379 CONNECT_WS_HANDSHAKE_RATE=100000 CONNECT_WS_HANDSHAKE_BURST=100000 go run ./apps/connect/cmd
+//	go run ./apps/connect/cmd/wsbench -redis 127.0.0.1:6379 -conns 5000 ...
+//
+// 指定 -redis/-miniredis 时会为每个测试设备写入 auth:at 登录态，使握手通过 Redis 校验。
+package main
+
+import (
+	"ChatServer/apps/connect/pb"
+	rediskey "ChatServer/consts/redisKey"
+	"ChatServer/pkg/util"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// benchConfig 命令行参数。
+type benchConfig struct {
+	wsURL    string
+	platform string
+	proto    bool
+
+	conns    int
+	users    int
+	rate     int
+	duration time.Duration
+	settle   time.Duration
+
+	heartbeat   time.Duration
+	msgInterval time.Duration
+	msgSize     int
+	messageData []byte
+
+	grpcAddr      string
+	pushRate      float64
+	pushMode      string
+	broadcastSize int
+	pushInflight  int
+
+	metricsURL    string
+	redisAddr     string
+	miniredisAddr string
+}
+
+// bench 压测运行状态与统计。
+type bench struct {
+	cfg benchConfig
+
+	connectLatency latencyStats
+	messageRTT     latencyStats
+	pushRPCLatency latencyStats
+	pushLatency    latencyStats
+
+	connected    atomic.Int64
+	connectFails atomic.Int64
+	dropped      atomic.Int64
+	writeErrors  atomic.Int64
+	errorFrames  atomic.Int64
+	messagesSent atomic.Int64
+	pushCalls    atomic.Int64
+	pushFails    atomic.Int64
+	pushExpected atomic.Int64
+	pushReceived atomic.Int64
+
+	mu         sync.Mutex
+	firstError string
+	closeCodes map[int]int64
+	online     []string
+}
+
+func main() {
+	cfg := parseFlags()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.miniredisAddr != "" {
+		mr := miniredis.NewMiniRedis()
+		if err := mr.StartAddr(cfg.miniredisAddr); err != nil {
+			fatalf("启动 miniredis 失败: %v", err)
+		}
+		defer mr.Close()
+		cfg.redisAddr = mr.Addr()
+		fmt.Printf("miniredis 已启动: %s\n", mr.Addr())
+		if cfg.conns == 0 {
+			fmt.Println("未指定连接数，仅提供 miniredis，Ctrl+C 退出")
+			<-ctx.Done()
+			return
+		}
+	}
+
+	b := &bench{cfg: cfg, closeCodes: make(map[int]int64)}
+	b.run(ctx)
+}
+
+func parseFlags() benchConfig {
+	var cfg benchConfig
+	flag.StringVar(&cfg.wsURL, "ws", "ws://127.0.0.1:8081/ws", "connect WebSocket 地址")
+	flag.StringVar(&cfg.platform, "platform", "", "握手携带的 platform（ios/android/web/windows/mac），影响 hello 下发的心跳间隔")
+	flag.BoolVar(&cfg.proto, "proto", false, "使用 protobuf 子协议（默认 JSON）")
+	flag.IntVar(&cfg.conns, "conns", 1000, "并发连接数")
+	flag.IntVar(&cfg.users, "users", 0, "用户数，连接按用户轮流分配设备（默认与连接数相同，即每用户一台设备）")
+	flag.IntVar(&cfg.rate, "rate", 200, "每秒新建连接数")
+	flag.DurationVar(&cfg.duration, "duration", 60*time.Second, "全部连接建立后的保持时长")
+	flag.DurationVar(&cfg.settle, "settle", 3*time.Second, "建连完成后等待多久再抓取服务端内存")
+	flag.DurationVar(&cfg.heartbeat, "heartbeat", 0, "心跳间隔（默认使用 hello 帧下发的推荐值）")
+	flag.DurationVar(&cfg.msgInterval, "msg-interval", 0, "每个连接发送 message 的间隔（0 表示不发送）")
+	flag.IntVar(&cfg.msgSize, "msg-size", 64, "message 内容字节数")
+	flag.StringVar(&cfg.grpcAddr, "grpc", "", "connect gRPC 地址（为空时不压测推送）")
+	flag.Float64Var(&cfg.pushRate, "push-rate", 0, "每秒推送 RPC 调用次数")
+	flag.StringVar(&cfg.pushMode, "push-mode", "user", "推送方式：user（PushToUser）/ broadcast（BroadcastToUsers）")
+	flag.IntVar(&cfg.broadcastSize, "broadcast-size", 100, "broadcast 模式每次推送的用户数（上限 1000）")
+	flag.IntVar(&cfg.pushInflight, "push-inflight", 64, "推送 RPC 最大并发数")
+	flag.StringVar(&cfg.metricsURL, "metrics", "", "connect /metrics 地址，用于估算单连接内存（为空时不统计）")
+	flag.StringVar(&cfg.redisAddr, "redis", "", "connect 使用的 Redis 地址，指定时为测试设备写入 auth:at 登录态")
+	flag.StringVar(&cfg.miniredisAddr, "miniredis", "", "启动内嵌 miniredis 并监听该地址（代替真实 Redis）")
+	flag.Parse()
+
+	if cfg.users <= 0 || cfg.users > cfg.conns {
+		cfg.users = cfg.conns
+	}
+	cfg.rate = max(cfg.rate, 1)
+	cfg.broadcastSize = min(max(cfg.broadcastSize, 1), 1000)
+	cfg.pushInflight = max(cfg.pushInflight, 1)
+	if cfg.pushMode != "user" && cfg.pushMode != "broadcast" {
+		fatalf("未知的 push-mode: %s", cfg.pushMode)
+	}
+	data, err := json.Marshal(map[string]any{
+		"conversationUuid": "bench",
+		"content":          strings.Repeat("x", max(cfg.msgSize, 1)),
+		"msgType":          1,
+	})
+	if err != nil {
+		fatalf("构造 message 失败: %v", err)
+	}
+	cfg.messageData = data
+	return cfg
+}
+
+// run 依次执行：签发 token（按需写入登录态）→ 抓取基线指标 → 按速率建连 → 抓取建连后指标 → 保持并推送 → 关闭并输出报告。
+func (b *bench) run(ctx context.Context) {
+	tokens := b.mintTokens()
+	if b.cfg.redisAddr != "" {
+		if err := b.seedAuth(ctx, tokens); err != nil {
+			fatalf("写入登录态失败: %v", err)
+		}
+	}
+
+	var before serverMetrics
+	if b.cfg.metricsURL != "" {
+		var err error
+		if before, err = scrapeMetrics(ctx, b.cfg.metricsURL); err != nil {
+			fmt.Printf("抓取基线指标失败（不统计内存）: %v\n", err)
+			b.cfg.metricsURL = ""
+		}
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var clients sync.WaitGroup
+	rampStart := time.Now()
+	b.connectAll(runCtx, tokens, &clients)
+	fmt.Printf("建连完成: %d/%d 成功，耗时 %s\n", b.connected.Load(), b.cfg.conns, time.Since(rampStart).Round(time.Millisecond))
+
+	var after serverMetrics
+	if b.cfg.metricsURL != "" && ctx.Err() == nil {
+		sleepContext(ctx, b.cfg.settle)
+		var err error
+		if after, err = scrapeMetrics(ctx, b.cfg.metricsURL); err != nil {
+			fmt.Printf("抓取建连后指标失败: %v\n", err)
+			b.cfg.metricsURL = ""
+		}
+	}
+
+	holdCtx, holdCancel := context.WithTimeout(runCtx, b.cfg.duration)
+	defer holdCancel()
+	var pushes sync.WaitGroup
+	if b.cfg.grpcAddr != "" && b.cfg.pushRate > 0 {
+		pushes.Add(1)
+		go func() {
+			defer pushes.Done()
+			b.pushLoop(holdCtx)
+		}()
+	}
+	<-holdCtx.Done()
+	pushes.Wait()
+	// 等待在途推送到达后再关闭连接。
+	sleepContext(ctx, time.Second)
+
+	cancel()
+	clients.Wait()
+	b.report(before, after)
+}
+
+// mintTokens 为每个测试设备签发 access token，key 为 user_uuid/device_id。
+func (b *bench) mintTokens() map[string]string {
+	tokens := make(map[string]string, b.cfg.conns)
+	for i := 0; i < b.cfg.conns; i++ {
+		userUUID, deviceID := b.identity(i)
+		token, err := util.GenerateToken(userUUID, deviceID)
+		if err != nil {
+			fatalf("签发 token 失败: %v", err)
+		}
+		tokens[userUUID+"/"+deviceID] = token
+	}
+	return tokens
+}
+
+// identity 第 i 个连接对应的用户与设备：连接按用户轮流分配，同一用户的多台设备编号递增。
+func (b *bench) identity(i int) (string, string) {
+	return fmt.Sprintf("bench-user-%06d", i%b.cfg.users), fmt.Sprintf("bench-device-%d", i/b.cfg.users)
+}
+
+// seedAuth 按 user 服务的存储规则写入 auth:at:{user_uuid}:{device_id} = md5(access_token)。
+func (b *bench) seedAuth(ctx context.Context, tokens map[string]string) error {
+	rdb := redis.NewClient(&redis.Options{Addr: b.cfg.redisAddr})
+	defer rdb.Close()
+
+	pipe := rdb.Pipeline()
+	for key, token := range tokens {
+		userUUID, deviceID, _ := strings.Cut(key, "/")
+		sum := md5.Sum([]byte(token))
+		pipe.Set(ctx, rediskey.AccessTokenKey(userUUID, deviceID), hex.EncodeToString(sum[:]), 24*time.Hour)
+		if pipe.Len() >= 1000 {
+			if _, err := pipe.Exec(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// connectAll 按 -rate 速率发起建连，成功的连接在独立 goroutine 中运行到 ctx 结束。
+func (b *bench) connectAll(ctx context.Context, tokens map[string]string, clients *sync.WaitGroup) {
+	ticker := time.NewTicker(time.Second / time.Duration(b.cfg.rate))
+	defer ticker.Stop()
+
+	var dials sync.WaitGroup
+	for i := 0; i < b.cfg.conns; i++ {
+		select {
+		case <-ctx.Done():
+			dials.Wait()
+			return
+		case <-ticker.C:
+		}
+		userUUID, deviceID := b.identity(i)
+		token := tokens[userUUID+"/"+deviceID]
+		dials.Add(1)
+		go func() {
+			defer dials.Done()
+			client, latency, err := dialClient(ctx, b.cfg, userUUID, deviceID, token)
+			if err != nil {
+				b.connectFails.Add(1)
+				b.recordError(err)
+				return
+			}
+			b.connectLatency.add(latency)
+			b.connected.Add(1)
+			b.mu.Lock()
+			if deviceID == "bench-device-0" {
+				b.online = append(b.online, userUUID)
+			}
+			b.mu.Unlock()
+
+			clients.Add(1)
+			go func() {
+				defer clients.Done()
+				client.run(ctx, b.cfg, b)
+			}()
+		}()
+	}
+	dials.Wait()
+}
+
+// pushLoop 按 -push-rate 调用推送 RPC，推送体携带发送时间，由接收连接统计到达延迟。
+func (b *bench) pushLoop(ctx context.Context) {
+	conn, err := grpc.NewClient(b.cfg.grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		fmt.Printf("创建 gRPC 连接失败，跳过推送压测: %v\n", err)
+		return
+	}
+	defer conn.Close()
+	client := pb.NewConnectServiceClient(conn)
+
+	b.mu.Lock()
+	online := slices.Clone(b.online)
+	b.mu.Unlock()
+	if len(online) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / b.cfg.pushRate))
+	defer ticker.Stop()
+	inflight := make(chan struct{}, b.cfg.pushInflight)
+	var calls sync.WaitGroup
+	defer calls.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		select {
+		case inflight <- struct{}{}:
+		default:
+			// 并发已满说明服务端处理不过来，跳过本次并计为失败。
+			b.pushFails.Add(1)
+			continue
+		}
+		calls.Add(1)
+		go func() {
+			defer calls.Done()
+			defer func() { <-inflight }()
+			b.push(client, online)
+		}()
+	}
+}
+
+// push 执行一次推送 RPC（PushToUser 或 BroadcastToUsers）。
+func (b *bench) push(client pb.ConnectServiceClient, online []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	data, _ := json.Marshal(benchPushData{SentAt: start.UnixNano()})
+	envelope := &pb.MessageEnvelope{Type: benchPushType, Data: data, ServerTs: start.UnixMilli()}
+
+	var delivered int64
+	var err error
+	if b.cfg.pushMode == "broadcast" {
+		targets := make([]string, 0, b.cfg.broadcastSize)
+		for range min(b.cfg.broadcastSize, len(online)) {
+			targets = append(targets, online[rand.IntN(len(online))])
+		}
+		var resp *pb.BroadcastToUsersResponse
+		resp, err = client.BroadcastToUsers(ctx, &pb.BroadcastToUsersRequest{UserUuids: targets, Message: envelope})
+		delivered = int64(resp.GetTotalDelivered())
+	} else {
+		var resp *pb.PushToUserResponse
+		resp, err = client.PushToUser(ctx, &pb.PushToUserRequest{UserUuid: online[rand.IntN(len(online))], Message: envelope})
+		delivered = int64(resp.GetDeliveredCount())
+	}
+	b.pushCalls.Add(1)
+	if err != nil {
+		b.pushFails.Add(1)
+		b.recordError(fmt.Errorf("push: %w", err))
+		return
+	}
+	b.pushRPCLatency.add(time.Since(start))
+	b.pushExpected.Add(delivered)
+}
+
+func (b *bench) recordError(err error) {
+	b.mu.Lock()
+	if b.firstError == "" {
+		b.firstError = err.Error()
+	}
+	b.mu.Unlock()
+}
+
+func (b *bench) recordClose(code int) {
+	b.mu.Lock()
+	b.closeCodes[code]++
+	b.mu.Unlock()
+}
+
+// report 输出压测报告。
+func (b *bench) report(before, after serverMetrics) {
+	fmt.Println()
+	fmt.Println("==== wsbench 报告 ====")
+	fmt.Printf("连接: 目标=%d 成功=%d 失败=%d 异常断开=%d\n", b.cfg.conns, b.connected.Load(), b.connectFails.Load(), b.dropped.Load())
+	fmt.Printf("建连延迟（握手到 hello）: %s\n", b.connectLatency.summary())
+	if b.cfg.msgInterval > 0 {
+		fmt.Printf("message 回执往返: sent=%d %s\n", b.messagesSent.Load(), b.messageRTT.summary())
+	}
+	if b.cfg.grpcAddr != "" && b.cfg.pushRate > 0 {
+		fmt.Printf("推送 RPC（%s）: calls=%d fails=%d %s\n", b.cfg.pushMode, b.pushCalls.Load(), b.pushFails.Load(), b.pushRPCLatency.summary())
+		fmt.Printf("推送扇出到达延迟: expected=%d received=%d %s\n", b.pushExpected.Load(), b.pushReceived.Load(), b.pushLatency.summary())
+	}
+	if b.cfg.metricsURL != "" {
+		fmt.Printf("服务端单连接开销: %s\n", perConnection(before, after, b.connected.Load()))
+	}
+	fmt.Printf("写失败=%d error 帧=%d\n", b.writeErrors.Load(), b.errorFrames.Load())
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.closeCodes) > 0 {
+		codes := make([]int, 0, len(b.closeCodes))
+		for code := range b.closeCodes {
+			codes = append(codes, code)
+		}
+		slices.Sort(codes)
+		parts := make([]string, 0, len(codes))
+		for _, code := range codes {
+			parts = append(parts, fmt.Sprintf("%d=%d", code, b.closeCodes[code]))
+		}
+		fmt.Printf("异常断开关闭码（0 表示网络错误）: %s\n", strings.Join(parts, " "))
+	}
+	if b.firstError != "" {
+		fmt.Printf("首个错误: %s\n", b.firstError)
+	}
+}
+
+// sleepContext 等待 d 或 ctx 结束。
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// serverMetrics 从 connect /metrics 抓取的进程级指标，用于估算单连接内存。
+type serverMetrics struct {
+	HeapInuse  float64
+	RSS        float64
+	Goroutines float64
+}
+
+// scrapeMetrics 抓取 Prometheus 文本格式指标，只解析无标签的 Go 运行时与进程指标。
+func scrapeMetrics(ctx context.Context, url string) (serverMetrics, error) {
+	var m serverMetrics
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return m, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return m, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return m, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok || strings.HasPrefix(name, "#") {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			continue
+		}
+		switch name {
+		case "go_memstats_heap_inuse_bytes":
+			m.HeapInuse = parsed
+		case "process_resident_memory_bytes":
+			m.RSS = parsed
+		case "go_goroutines":
+			m.Goroutines = parsed
+		}
+	}
+	return m, scanner.Err()
+}
+
+// perConnection 以建连前后的差值估算单连接开销。
+func perConnection(before, after serverMetrics, conns int64) string {
+	if conns <= 0 {
+		return "n/a"
+	}
+	n := float64(conns)
+	return fmt.Sprintf("heap_inuse=%.1fKiB rss=%.1fKiB goroutines=%.2f (heap %.1fMiB -> %.1fMiB, rss %.1fMiB -> %.1fMiB)",
+		(after.HeapInuse-before.HeapInuse)/n/1024,
+		(after.RSS-before.RSS)/n/1024,
+		(after.Goroutines-before.Goroutines)/n,
+		before.HeapInuse/1024/1024, after.HeapInuse/1024/1024,
+		before.RSS/1024/1024, after.RSS/1024/1024,
+	)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxSamples 单个延迟统计最多保留的样本数，超出后只计数不再采样，避免长时间压测内存无限增长。
+const maxSamples = 1_000_000
+
+// latencyStats 延迟样本统计（并发安全）。
+type latencyStats struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	count   int64
+}
+
+func (s *latencyStats) add(d time.Duration) {
+	s.mu.Lock()
+	s.count++
+	if len(s.samples) < maxSamples {
+		s.samples = append(s.samples, d)
+	}
+	s.mu.Unlock()
+}
+
+// summary 返回 count/p50/p90/p99/max 摘要。
+func (s *latencyStats) summary() string {
+	s.mu.Lock()
+	samples := slices.Clone(s.samples)
+	count := s.count
+	s.mu.Unlock()
+
+	if len(samples) == 0 {
+		return "n=0"
+	}
+	slices.Sort(samples)
+	return fmt.Sprintf("n=%d p50=%s p90=%s p99=%s max=%s",
+		count,
+		percentile(samples, 0.50),
+		percentile(samples, 0.90),
+		percentile(samples, 0.99),
+		samples[len(samples)-1],
+	)
+}
+
+// percentile 取已排序样本的分位值（最近秩法）。
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	idx := int(float64(len(sorted))*p+0.5) - 1
+	idx = min(max(idx, 0), len(sorted)-1)
+	return sorted[idx].Round(time.Microsecond)
+}
@@ -0,0 +1,207 @@
+package codec
+
+import (
+	"ChatServer/apps/connect/pb"
+	"encoding/json"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// SubprotocolJSON JSON 文本帧子协议；未协商子协议的旧客户端同样按 JSON 处理。
+	SubprotocolJSON = "lcchat.json.v1"
+	// SubprotocolProto protobuf 二进制帧子协议，帧体为 connect.MessageEnvelope。
+	SubprotocolProto = "lcchat.proto.v1"
+
+	// TypeBatch 批量帧的信封类型，data 内按顺序打包多条信封：
+	// - JSON: data 为信封数组；
+	// - protobuf: data 为 connect.MessageBatch。
+	TypeBatch = "batch"
+)
+
+// Codec 定义单条连接的帧编解码方式。
+// 由握手阶段 Sec-WebSocket-Protocol 协商决定，连接生命周期内不变。
+type Codec interface {
+	// Subprotocol 返回对应的子协议名。
+	Subprotocol() string
+	// MessageType 返回下行帧的 WebSocket 帧类型（TextMessage/BinaryMessage）。
+	MessageType() int
+	// Encode 将信封编码为下行帧载荷。
+	Encode(envelope *pb.MessageEnvelope) ([]byte, error)
+	// Decode 将上行帧载荷解码为信封。
+	Decode(raw []byte) (*pb.MessageEnvelope, error)
+	// EncodeBatch 将多条已编码的下行帧载荷打包为一条 type=batch 的帧，不重新编码单条信封。
+	EncodeBatch(payloads [][]byte) ([]byte, error)
+}
+
+var (
+	// JSON JSON 文本帧编解码器。
+	JSON Codec = jsonCodec{}
+	// Proto protobuf 二进制帧编解码器。
+	Proto Codec = protoCodec{}
+)
+
+// Subprotocols 返回服务端支持的子协议列表（按优先级排列）。
+// 客户端同时声明两种时优先选择 protobuf。
+func Subprotocols() []string {
+	return []string{SubprotocolProto, SubprotocolJSON}
+}
+
+// ForSubprotocol 按协商结果返回编解码器，空值或未知值回退到 JSON。
+func ForSubprotocol(subprotocol string) Codec {
+	if subprotocol == SubprotocolProto {
+		return Proto
+	}
+	return JSON
+}
+
+// jsonEnvelope MessageEnvelope 的 JSON 表示。
+// data 为合法 JSON 时原样内嵌，否则按 base64 字符串输出。
+type jsonEnvelope struct {
+	Type        string          `json:"type"`
+	Data        json.RawMessage `json:"data,omitempty"`
+	Seq         int64           `json:"seq,omitempty"`
+	ServerTs    int64           `json:"server_ts,omitempty"`
+	TraceID     string          `json:"trace_id,omitempty"`
+	AckRequired bool            `json:"ack_required,omitempty"`
+	ConvID      string          `json:"conv_id,omitempty"`
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Subprotocol() string { return SubprotocolJSON }
+
+func (jsonCodec) MessageType() int { return websocket.TextMessage }
+
+func (jsonCodec) Encode(envelope *pb.MessageEnvelope) ([]byte, error) {
+	out := jsonEnvelope{
+		Type:        envelope.GetType(),
+		Seq:         envelope.GetSeq(),
+		ServerTs:    envelope.GetServerTs(),
+		TraceID:     envelope.GetTraceId(),
+		AckRequired: envelope.GetAckRequired(),
+		ConvID:      envelope.GetConvId(),
+	}
+	if data := envelope.GetData(); len(data) > 0 {
+		if json.Valid(data) {
+			out.Data = data
+		} else {
+			encoded, err := json.Marshal(data)
+			if err != nil {
+				return nil, err
+			}
+			out.Data = encoded
+		}
+	}
+	return json.Marshal(out)
+}
+
+func (jsonCodec) Decode(raw []byte) (*pb.MessageEnvelope, error) {
+	var in jsonEnvelope
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return nil, err
+	}
+	return &pb.MessageEnvelope{
+		Type:        in.Type,
+		Data:        in.Data,
+		Seq:         in.Seq,
+		ServerTs:    in.ServerTs,
+		TraceId:     in.TraceID,
+		AckRequired: in.AckRequired,
+		ConvId:      in.ConvID,
+	}, nil
+}
+
+// EncodeBatch 直接拼接已编码的 JSON 信封：{"type":"batch","data":[...]}。
+func (jsonCodec) EncodeBatch(payloads [][]byte) ([]byte, error) {
+	size := len(`{"type":"batch","data":[]}`) + len(payloads)
+	for _, payload := range payloads {
+		size += len(payload)
+	}
+	out := make([]byte, 0, size)
+	out = append(out, `{"type":"batch","data":[`...)
+	for i, payload := range payloads {
+		if i > 0 {
+			out = append(out, ',')
+		}
+		out = append(out, payload...)
+	}
+	return append(out, "]}"...), nil
+}
+
+type protoCodec struct{}
+
+func (protoCodec) Subprotocol() string { return SubprotocolProto }
+
+func (protoCodec) MessageType() int { return websocket.BinaryMessage }
+
+func (protoCodec) Encode(envelope *pb.MessageEnvelope) ([]byte, error) {
+	return proto.Marshal(envelope)
+}
+
+func (protoCodec) Decode(raw []byte) (*pb.MessageEnvelope, error) {
+	var envelope pb.MessageEnvelope
+	if err := proto.Unmarshal(raw, &envelope); err != nil {
+		return nil, err
+	}
+	return &envelope, nil
+}
+
+// EncodeBatch 已编码信封即 MessageBatch.envelopes 的字段值，按 wire 格式直接拼接。
+func (protoCodec) EncodeBatch(payloads [][]byte) ([]byte, error) {
+	size := 0
+	for _, payload := range payloads {
+		size += protowire.SizeTag(1) + protowire.SizeBytes(len(payload))
+	}
+	batch := make([]byte, 0, size)
+	for _, payload := range payloads {
+		batch = protowire.AppendTag(batch, 1, protowire.BytesType)
+		batch = protowire.AppendBytes(batch, payload)
+	}
+	return proto.Marshal(&pb.MessageEnvelope{
+		Type: TypeBatch,
+		Data: batch,
+	})
+}
+
+// Frame 一条待下发的信封。
+// 广播场景下同一 Frame 会投递给大量连接，按编解码器缓存编码结果，
+// 每种子协议只编码一次。
+type Frame struct {
+	envelope *pb.MessageEnvelope
+
+	mu      sync.Mutex
+	encoded map[string][]byte
+}
+
+// NewFrame 创建下行帧。
+func NewFrame(envelope *pb.MessageEnvelope) *Frame {
+	return &Frame{envelope: envelope}
+}
+
+// Envelope 返回原始信封（只读）。
+func (f *Frame) Envelope() *pb.MessageEnvelope {
+	return f.envelope
+}
+
+// Encode 按编解码器编码，结果会被缓存，返回值不可修改。
+func (f *Frame) Encode(c Codec) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if data, ok := f.encoded[c.Subprotocol()]; ok {
+		return data, nil
+	}
+	data, err := c.Encode(f.envelope)
+	if err != nil {
+		return nil, err
+	}
+	if f.encoded == nil {
+		f.encoded = make(map[string][]byte, 2)
+	}
+	f.encoded[c.Subprotocol()] = data
+	return data, nil
+}
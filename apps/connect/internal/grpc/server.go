@@ -1,18 +1,21 @@
 package grpc
 
 import (
+	"ChatServer/apps/connect/internal/codec"
 	"ChatServer/apps/connect/internal/manager"
 	"ChatServer/apps/connect/pb"
+	"ChatServer/pkg/connectroute"
+	"ChatServer/pkg/ctxmeta"
 	"ChatServer/pkg/grpcx"
 	"ChatServer/pkg/logger"
 	"context"
+	"encoding/json"
 	"net"
 	"os"
 	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
-	"google.golang.org/protobuf/proto"
 )
 
 // Server 封装 connect gRPC 服务的启动与停机。
@@ -82,61 +85,89 @@ func (s *Server) Stop() {
 // ==================== RPC 实现 ====================
 
 // PushToDevice 向指定用户的指定设备投递消息。
+// 信封按目标连接协商的子协议编码（JSON 文本帧 / protobuf 二进制帧）。
 func (s *Server) PushToDevice(ctx context.Context, req *pb.PushToDeviceRequest) (*pb.PushToDeviceResponse, error) {
-	data, err := proto.Marshal(req.Message)
-	if err != nil {
-		logger.Warn(ctx, "PushToDevice: 序列化 MessageEnvelope 失败",
-			logger.ErrorField("error", err),
-		)
+	if req.Message == nil {
+		logger.Warn(ctx, "PushToDevice: MessageEnvelope 为空")
 		return &pb.PushToDeviceResponse{Delivered: false}, nil
 	}
 
-	delivered := s.connManager.SendToDevice(req.UserUuid, req.DeviceId, data)
+	delivered := s.connManager.SendToDevice(req.UserUuid, req.DeviceId, codec.NewFrame(req.Message))
 	return &pb.PushToDeviceResponse{Delivered: delivered}, nil
 }
 
 // PushToUser 向用户所有在线设备广播。
 func (s *Server) PushToUser(ctx context.Context, req *pb.PushToUserRequest) (*pb.PushToUserResponse, error) {
-	data, err := proto.Marshal(req.Message)
-	if err != nil {
-		logger.Warn(ctx, "PushToUser: 序列化 MessageEnvelope 失败",
-			logger.ErrorField("error", err),
-		)
+	if req.Message == nil {
+		logger.Warn(ctx, "PushToUser: MessageEnvelope 为空")
 		return &pb.PushToUserResponse{DeliveredCount: 0}, nil
 	}
 
-	count := s.connManager.SendToUser(req.UserUuid, data)
+	count := s.connManager.SendToUser(req.UserUuid, codec.NewFrame(req.Message))
 	return &pb.PushToUserResponse{DeliveredCount: int32(count)}, nil
 }
 
 // BroadcastToUsers 批量向多个用户广播相同的消息。
+// 所有用户共享同一 Frame，每种子协议只编码一次；没有任何设备入队的用户在响应中列出。
 func (s *Server) BroadcastToUsers(ctx context.Context, req *pb.BroadcastToUsersRequest) (*pb.BroadcastToUsersResponse, error) {
-	data, err := proto.Marshal(req.Message)
-	if err != nil {
-		logger.Warn(ctx, "BroadcastToUsers: 序列化 MessageEnvelope 失败",
-			logger.ErrorField("error", err),
-		)
+	if req.Message == nil {
+		logger.Warn(ctx, "BroadcastToUsers: MessageEnvelope 为空")
 		return &pb.BroadcastToUsersResponse{}, nil
 	}
 
+	frame := codec.NewFrame(req.Message)
 	var successCount, totalDelivered int32
+	var undelivered []string
 	for _, userUUID := range req.UserUuids {
-		count := s.connManager.SendToUser(userUUID, data)
+		count := s.connManager.SendToUser(userUUID, frame)
 		if count > 0 {
 			successCount++
 			totalDelivered += int32(count)
+		} else {
+			undelivered = append(undelivered, userUUID)
 		}
 	}
 
 	return &pb.BroadcastToUsersResponse{
-		SuccessCount:   successCount,
-		TotalDelivered: totalDelivered,
+		SuccessCount:         successCount,
+		TotalDelivered:       totalDelivered,
+		UndeliveredUserUuids: undelivered,
 	}, nil
 }
 
+// PublishToTopic 向本节点订阅了主题的连接广播，topic=all 投递全部在线连接。
+// 集群内由 connectroute.Router 转发到每个存活节点，这里只处理本地连接。
+func (s *Server) PublishToTopic(ctx context.Context, req *pb.PublishToTopicRequest) (*pb.PublishToTopicResponse, error) {
+	if req.Message == nil {
+		logger.Warn(ctx, "PublishToTopic: MessageEnvelope 为空")
+		return &pb.PublishToTopicResponse{}, nil
+	}
+
+	frame := codec.NewFrame(req.Message)
+	var count int
+	if req.Topic == connectroute.TopicAll {
+		count = s.connManager.Broadcast(frame)
+	} else {
+		count = s.connManager.PublishToTopic(req.Topic, frame)
+	}
+	return &pb.PublishToTopicResponse{DeliveredCount: int32(count)}, nil
+}
+
 // KickConnection 主动断开指定设备连接。
+// 关闭前向目标连接下发 kickout 帧（data.reason 为请求中的 reason）。
+// issued_before 非 0 时只断开在该时间之前建立的连接，重新登录后的新连接不会被过期的踢线指令断开。
 func (s *Server) KickConnection(ctx context.Context, req *pb.KickConnectionRequest) (*pb.KickConnectionResponse, error) {
-	success := s.connManager.KickDevice(req.UserUuid, req.DeviceId)
+	frame, err := newKickoutFrame(ctx, req.Reason)
+	if err != nil {
+		logger.Warn(ctx, "KickConnection: kickout 帧序列化失败，直接断开",
+			logger.ErrorField("error", err),
+		)
+	}
+	var issuedBefore time.Time
+	if req.IssuedBefore > 0 {
+		issuedBefore = time.UnixMilli(req.IssuedBefore)
+	}
+	success := s.connManager.KickDeviceBefore(req.UserUuid, req.DeviceId, frame, req.Reason, issuedBefore)
 
 	if success {
 		logger.Info(ctx, "KickConnection: 连接已断开",
@@ -149,6 +180,25 @@ func (s *Server) KickConnection(ctx context.Context, req *pb.KickConnectionReque
 	return &pb.KickConnectionResponse{Success: success}, nil
 }
 
+// kickoutData 定义 type=kickout 时的 data 结构。
+type kickoutData struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// newKickoutFrame 组装踢线通知帧，客户端收到后应停止自动重连并提示用户。
+func newKickoutFrame(ctx context.Context, reason string) (*codec.Frame, error) {
+	data, err := json.Marshal(kickoutData{Reason: reason})
+	if err != nil {
+		return nil, err
+	}
+	return codec.NewFrame(&pb.MessageEnvelope{
+		Type:     "kickout",
+		Data:     data,
+		ServerTs: time.Now().UnixMilli(),
+		TraceId:  ctxmeta.TraceID(ctx),
+	}), nil
+}
+
 // GetOnlineStatus 获取单个用户的在线设备列表。
 func (s *Server) GetOnlineStatus(_ context.Context, req *pb.GetOnlineStatusRequest) (*pb.GetOnlineStatusResponse, error) {
 	devices := s.connManager.GetOnlineDevices(req.UserUuid)
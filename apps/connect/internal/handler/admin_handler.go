@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"ChatServer/apps/connect/internal/manager"
+	"ChatServer/apps/connect/internal/svc"
+	"ChatServer/consts"
+	"ChatServer/pkg/ctxmeta"
+	"ChatServer/pkg/logger"
+	"ChatServer/pkg/result"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// adminKickReason 运维强制断开时 kickout 帧携带的原因。
+	adminKickReason = "admin_closed"
+	// drainReason 节点排空时 reconnect 帧携带的原因。
+	drainReason = "drain"
+)
+
+// DrainConfig 节点排空的默认参数，请求体未指定时使用。
+type DrainConfig struct {
+	// Endpoint 建议客户端重连的备用接入地址。
+	Endpoint string
+	// Rate 每秒关闭的连接数。
+	Rate int
+}
+
+// AdminHandler connect 运维接口：查看连接分布、查询/断开用户连接、排空节点。
+// 仅挂载在独立的运维端口上，并由 AdminAuthMiddleware 鉴权。
+type AdminHandler struct {
+	connManager *manager.ConnectionManager
+	connectSvc  *svc.ConnectService
+	drainCfg    DrainConfig
+}
+
+// NewAdminHandler 创建运维接口处理器。
+func NewAdminHandler(connManager *manager.ConnectionManager, connectSvc *svc.ConnectService, drainCfg DrainConfig) *AdminHandler {
+	return &AdminHandler{
+		connManager: connManager,
+		connectSvc:  connectSvc,
+		drainCfg:    drainCfg,
+	}
+}
+
+// connectionsResponse GET /admin/connections 响应。
+type connectionsResponse struct {
+	Total      int            `json:"total"`
+	Buckets    []int          `json:"buckets"`
+	Transports map[string]int `json:"transports"`
+	Draining   bool           `json:"draining"`
+}
+
+// closeResponse 强制断开响应。
+type closeResponse struct {
+	Closed []string `json:"closed"`
+}
+
+// drainRequest POST /admin/drain 请求体，字段均可省略。
+type drainRequest struct {
+	Endpoint string `json:"endpoint"`
+	Rate     int    `json:"rate" binding:"gte=0,lte=10000"` // 上限同 manager.MaxDrainRate
+}
+
+// Connections 返回本节点连接总数、各分桶连接数与各传输方式连接数。
+func (h *AdminHandler) Connections(c *gin.Context) {
+	buckets := h.connManager.BucketCounts()
+	total := 0
+	for _, count := range buckets {
+		total += count
+	}
+	result.Success(c, connectionsResponse{
+		Total:      total,
+		Buckets:    buckets,
+		Transports: h.connManager.TransportCounts(),
+		Draining:   h.connManager.Draining(),
+	})
+}
+
+// UserConnections 返回用户在本节点的设备连接（连接时间、写队列深度、待回执数、IP）。
+func (h *AdminHandler) UserConnections(c *gin.Context) {
+	result.Success(c, h.connManager.UserClients(c.Param("user_uuid")))
+}
+
+// CloseConnections 强制断开用户在本节点的连接，指定 device_id 时只断开该设备。
+// 连接先收到 kickout（reason=admin_closed），随后以关闭码 4005 断开。
+func (h *AdminHandler) CloseConnections(c *gin.Context) {
+	ctx := ctxmeta.BuildContextFromGin(c)
+	userUUID := c.Param("user_uuid")
+
+	devices := []string{c.Param("device_id")}
+	if devices[0] == "" {
+		devices = h.connManager.GetOnlineDevices(userUUID)
+	}
+
+	frame, err := h.connectSvc.NewFrame("kickout", svc.KickoutData{Reason: adminKickReason})
+	if err != nil {
+		logger.Warn(ctx, "kickout 帧序列化失败，直接断开",
+			logger.ErrorField("error", err),
+		)
+	}
+	closed := make([]string, 0, len(devices))
+	for _, deviceID := range devices {
+		if h.connManager.KickDevice(userUUID, deviceID, frame, adminKickReason) {
+			closed = append(closed, deviceID)
+		}
+	}
+
+	logger.Info(ctx, "运维接口强制断开连接",
+		logger.String("user_uuid", userUUID),
+		logger.String("device_id", c.Param("device_id")),
+		logger.Int("closed_count", len(closed)),
+	)
+	result.Success(c, closeResponse{Closed: closed})
+}
+
+// DrainStatus 返回排空进度。
+func (h *AdminHandler) DrainStatus(c *gin.Context) {
+	result.Success(c, h.connManager.DrainStatus())
+}
+
+// StartDrain 开始排空本节点：立即拒绝新握手，后台按速率下发 reconnect 并关闭已有连接。
+// 排空完成后节点保持拒绝握手，由发布系统停机或调用 DELETE /admin/drain 恢复。
+func (h *AdminHandler) StartDrain(c *gin.Context) {
+	ctx := ctxmeta.BuildContextFromGin(c)
+
+	var req drainRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			result.Fail(c, nil, consts.CodeParamError)
+			return
+		}
+	}
+	if req.Endpoint == "" {
+		req.Endpoint = h.drainCfg.Endpoint
+	}
+	if req.Rate == 0 {
+		req.Rate = h.drainCfg.Rate
+	}
+
+	hint, err := h.connectSvc.NewFrame("reconnect", svc.ReconnectData{
+		Reason:   drainReason,
+		Endpoint: req.Endpoint,
+	})
+	if err != nil {
+		logger.Error(ctx, "reconnect 帧序列化失败",
+			logger.ErrorField("error", err),
+		)
+		result.Fail(c, nil, consts.CodeInternalError)
+		return
+	}
+
+	done, err := h.connManager.StartDrain(req.Endpoint, hint, req.Rate)
+	if errors.Is(err, manager.ErrDraining) {
+		// 重复调用幂等：返回进行中的排空进度。
+		result.Success(c, h.connManager.DrainStatus())
+		return
+	}
+	if err != nil {
+		result.Fail(c, nil, consts.CodeServiceUnavailable)
+		return
+	}
+	status := h.connManager.DrainStatus()
+	logger.Info(ctx, "connect 节点开始排空",
+		logger.String("endpoint", status.Endpoint),
+		logger.Int("rate", status.Rate),
+		logger.Int("online_count", status.Remaining),
+	)
+	go func() {
+		if err := <-done; err != nil {
+			logger.Info(ctx, "connect 节点排空已中止",
+				logger.ErrorField("error", err),
+			)
+			return
+		}
+		logger.Info(ctx, "connect 节点排空完成",
+			logger.Int64("closed", h.connManager.DrainStatus().Closed),
+		)
+	}()
+
+	result.Success(c, status)
+}
+
+// StopDrain 停止排空并恢复接受握手。
+func (h *AdminHandler) StopDrain(c *gin.Context) {
+	if h.connManager.StopDrain() {
+		logger.Info(ctxmeta.BuildContextFromGin(c), "connect 节点已恢复接入")
+	}
+	result.Success(c, h.connManager.DrainStatus())
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"ChatServer/apps/connect/internal/manager"
+	"ChatServer/apps/connect/internal/svc"
+	"ChatServer/consts"
+	"ChatServer/pkg/logger"
+	"context"
+	"errors"
+	"time"
+)
+
+// watchAuth 在连接存活期间跟踪登录态，直到连接关闭。
+// - 距 token 过期不足 ExpiringLead 时下发一次 auth_expiring（reauth 续期后对新 token 重新计时）；
+// - token 过期且未 reauth 时以 CloseCodeAuthExpired 断开；
+// - 按 RevokeCheckInterval 检查登录态是否被吊销，吊销时以 CloseCodeAuthRevoked 断开。
+func (h *WSHandler) watchAuth(ctx context.Context, client *manager.Client, session *svc.Session) {
+	lead := h.authCfg.ExpiringLead
+	revokeInterval := h.authCfg.RevokeCheckInterval
+
+	var warnedFor time.Time
+	nextRevokeCheck := time.Now().Add(revokeInterval)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-client.Done():
+			return
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		if revokeInterval > 0 && !now.Before(nextRevokeCheck) {
+			if h.connectSvc.CheckRevoked(ctx, session) {
+				logger.Info(ctx, "WebSocket 登录态已吊销，断开连接")
+				client.CloseWithCode(manager.CloseCodeAuthRevoked, "auth revoked")
+				return
+			}
+			nextRevokeCheck = now.Add(revokeInterval)
+		}
+
+		var wait time.Duration
+		if revokeInterval > 0 {
+			wait = nextRevokeCheck.Sub(now)
+		}
+		if expiresAt := session.ExpiresAt(); !expiresAt.IsZero() {
+			if !now.Before(expiresAt) {
+				logger.Info(ctx, "WebSocket 登录态已过期，断开连接")
+				client.CloseWithCode(manager.CloseCodeAuthExpired, "auth expired")
+				return
+			}
+			warnAt := expiresAt.Add(-lead)
+			if !warnedFor.Equal(expiresAt) && !now.Before(warnAt) {
+				h.sendAuthFrame(ctx, client, session, "auth_expiring")
+				warnedFor = expiresAt
+			}
+			if !warnedFor.Equal(expiresAt) {
+				wait = minPositive(wait, warnAt.Sub(now))
+			}
+			wait = minPositive(wait, expiresAt.Sub(now))
+		}
+		if wait <= 0 {
+			// 既无过期时间也不检查吊销。
+			return
+		}
+		timer.Reset(wait)
+	}
+}
+
+// handleReauth 处理 reauth 上行帧：校验新 token 并续期，成功后回 reauth_ok。
+// 失败时只回 error 帧，不断开连接；旧 token 到期后由 watchAuth 断开。
+func (h *WSHandler) handleReauth(ctx context.Context, client *manager.Client, session *svc.Session, envelope *svc.Envelope) {
+	token, err := h.connectSvc.ParseReauth(envelope.Data)
+	if err == nil {
+		err = h.connectSvc.Reauth(ctx, session, token)
+	}
+	switch {
+	case err == nil:
+		h.sendAuthFrame(ctx, client, session, "reauth_ok")
+	case errors.Is(err, svc.ErrTokenRequired):
+		h.sendErrorFrame(ctx, client, consts.CodeConnectTokenRequired)
+	case errors.Is(err, svc.ErrTokenInvalid):
+		h.sendErrorFrame(ctx, client, consts.CodeInvalidToken)
+	default:
+		h.sendErrorFrame(ctx, client, consts.CodeConnectMessageFormatError)
+	}
+}
+
+// sendAuthFrame 下发携带当前 token 过期时间的 auth_expiring / reauth_ok 帧。
+func (h *WSHandler) sendAuthFrame(ctx context.Context, client *manager.Client, session *svc.Session, msgType string) {
+	frame, err := h.connectSvc.NewFrame(msgType, svc.NewAuthExpiryData(session, time.Now()))
+	if err != nil {
+		logger.Warn(ctx, "登录态帧序列化失败",
+			logger.String("type", msgType),
+			logger.ErrorField("error", err),
+		)
+		return
+	}
+	if !client.EnqueueFrame(frame) {
+		client.Close()
+	}
+}
+
+// minPositive 返回两个时长中较小的正值，wait <= 0 视为未设置。
+func minPositive(wait, candidate time.Duration) time.Duration {
+	if wait <= 0 {
+		return candidate
+	}
+	return min(wait, candidate)
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"ChatServer/apps/connect/internal/manager"
+	"ChatServer/apps/connect/internal/svc"
+	"ChatServer/consts"
+	rediskey "ChatServer/consts/redisKey"
+	"ChatServer/pkg/util"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signTestToken 签发指定有效期的 access token（util.GenerateToken 固定为 AccessExpire）。
+func signTestToken(t *testing.T, userUUID, deviceID string, ttl time.Duration) string {
+	t.Helper()
+	now := time.Now()
+	claims := util.CustomClaims{
+		UserUUID: userUUID,
+		DeviceID: deviceID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(util.JWTSecret))
+	require.NoError(t, err)
+	return token
+}
+
+// dialWithToken 使用给定 token 建立连接并读取 hello。
+func dialWithToken(t *testing.T, url, token, deviceID string) *callTestClient {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?token="+token+"&device_id="+deviceID, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	client := &callTestClient{t: t, conn: conn}
+	client.expect("hello")
+	return client
+}
+
+// expectClose 读取直到连接关闭并断言关闭码，期间收到的帧类型按序返回。
+func (c *callTestClient) expectClose(code int) []string {
+	c.t.Helper()
+	require.NoError(c.t, c.conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	var types []string
+	for {
+		var frame callTestFrame
+		err := c.conn.ReadJSON(&frame)
+		if err == nil {
+			types = append(types, frame.Type)
+			continue
+		}
+		var closeErr *websocket.CloseError
+		require.True(c.t, errors.As(err, &closeErr), "unexpected error: %v", err)
+		assert.Equal(c.t, code, closeErr.Code)
+		return types
+	}
+}
+
+func authExpiry(t *testing.T, frame callTestFrame) svc.AuthExpiryData {
+	t.Helper()
+	var data svc.AuthExpiryData
+	require.NoError(t, json.Unmarshal(frame.Data, &data))
+	return data
+}
+
+func TestAuthWatchExpiresConnection(t *testing.T) {
+	url := newTestWSServer(t, func(h *WSHandler) {
+		h.authCfg = svc.AuthWatchConfig{ExpiringLead: time.Hour}
+	})
+	client := dialWithToken(t, url, signTestToken(t, "alice", "a1", 2*time.Second), "a1")
+
+	// 剩余时间已不足 ExpiringLead，连接建立后立即提示且只提示一次。
+	data := authExpiry(t, client.expect("auth_expiring"))
+	assert.LessOrEqual(t, data.ExpiresIn, int64(2))
+	assert.Greater(t, data.ExpiresAt, time.Now().UnixMilli()-1000)
+
+	assert.Empty(t, client.expectClose(manager.CloseCodeAuthExpired))
+}
+
+func TestAuthWatchExpiringLead(t *testing.T) {
+	url := newTestWSServer(t, func(h *WSHandler) {
+		h.authCfg = svc.AuthWatchConfig{ExpiringLead: time.Second}
+	})
+	client := dialWithToken(t, url, signTestToken(t, "alice", "a1", 3*time.Second), "a1")
+
+	// 未到提示时间前不下发 auth_expiring。
+	client.send("heartbeat", nil)
+	client.expect("heartbeat_ack")
+
+	start := time.Now()
+	client.expect("auth_expiring")
+	assert.Greater(t, time.Since(start), 500*time.Millisecond)
+	assert.Empty(t, client.expectClose(manager.CloseCodeAuthExpired))
+}
+
+func TestAuthWatchReauthExtendsSession(t *testing.T) {
+	url := newTestWSServer(t, func(h *WSHandler) {
+		h.authCfg = svc.AuthWatchConfig{ExpiringLead: time.Hour}
+	})
+	client := dialWithToken(t, url, signTestToken(t, "alice", "a1", 2*time.Second), "a1")
+	client.expect("auth_expiring")
+
+	// 非法续期只回 error，不断开连接。
+	client.send("reauth", nil)
+	client.expectError(consts.CodeConnectTokenRequired)
+	client.send("reauth", svc.ReauthData{Token: signTestToken(t, "bob", "a1", time.Hour)})
+	client.expectError(consts.CodeInvalidToken)
+	client.send("reauth", svc.ReauthData{Token: signTestToken(t, "alice", "a2", time.Hour)})
+	client.expectError(consts.CodeInvalidToken)
+
+	token, err := util.GenerateToken("alice", "a1")
+	require.NoError(t, err)
+	client.send("reauth", svc.ReauthData{Token: token})
+	data := authExpiry(t, client.expect("reauth_ok"))
+	assert.Greater(t, data.ExpiresIn, int64(time.Hour/time.Second))
+
+	// 旧 token 过期后连接仍然存活，且新 token 未进入提示窗口前不再提示。
+	time.Sleep(2500 * time.Millisecond)
+	client.send("heartbeat", nil)
+	client.expect("heartbeat_ack")
+}
+
+func TestAuthWatchRevokedConnection(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	token, err := util.GenerateToken("alice", "a1")
+	require.NoError(t, err)
+	sum := md5.Sum([]byte(token))
+	key := rediskey.AccessTokenKey("alice", "a1")
+	require.NoError(t, rdb.Set(context.Background(), key, hex.EncodeToString(sum[:]), 0).Err())
+
+	url := newTestWSServer(t, func(h *WSHandler) {
+		h.connectSvc = svc.NewConnectService(rdb, nil, nil, svc.UplinkLimitConfig{})
+		h.authCfg = svc.AuthWatchConfig{ExpiringLead: time.Minute, RevokeCheckInterval: 100 * time.Millisecond}
+	})
+	client := dialWithToken(t, url, token, "a1")
+
+	// 登录态仍在时连接正常。
+	time.Sleep(300 * time.Millisecond)
+	client.send("heartbeat", nil)
+	client.expect("heartbeat_ack")
+
+	// 踢设备/登出删除 auth:at 后，下一次检查即断开。
+	mr.Del(key)
+	assert.Empty(t, client.expectClose(manager.CloseCodeAuthRevoked))
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"ChatServer/apps/connect/internal/manager"
+	"ChatServer/apps/connect/internal/svc"
+	"ChatServer/consts"
+	"ChatServer/pkg/logger"
+	"context"
+	"errors"
+)
+
+// SetCalls 启用通话信令中继，calls 为 nil 时 call_* 帧按不支持的消息类型处理。
+func (h *WSHandler) SetCalls(calls *svc.Calls) {
+	h.calls = calls
+}
+
+// handleCall 处理 call_* 通话信令帧，结果（call_invite_ok/call_end 等）由 svc.Calls 直接投递。
+// 通话不存在、信令非法或邀请被关系校验拒绝时回 error 帧，不断开连接。
+func (h *WSHandler) handleCall(ctx context.Context, client *manager.Client, session *svc.Session, envelope *svc.Envelope) {
+	err := h.calls.Handle(ctx, session, envelope.Type, envelope.Data)
+	switch {
+	case err == nil:
+	case errors.Is(err, svc.ErrCallNotFound):
+		h.sendErrorFrame(ctx, client, consts.CodeConnectCallNotFound)
+	case errors.Is(err, svc.ErrCallInvalid):
+		h.sendErrorFrame(ctx, client, consts.CodeConnectCallInvalid)
+	case errors.Is(err, svc.ErrCallNotFriend):
+		h.sendErrorFrame(ctx, client, consts.CodeConnectCallNotFriend)
+	case errors.Is(err, svc.ErrCallBlocked):
+		h.sendErrorFrame(ctx, client, consts.CodeConnectCallBlocked)
+	default:
+		logger.Warn(ctx, "处理通话信令失败",
+			logger.String("type", envelope.Type),
+			logger.ErrorField("error", err),
+		)
+		h.sendErrorFrame(ctx, client, consts.CodeInternalError)
+	}
+}
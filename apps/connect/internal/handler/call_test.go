@@ -0,0 +1,358 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ChatServer/apps/connect/internal/manager"
+	"ChatServer/apps/connect/internal/svc"
+	"ChatServer/consts"
+	rediskey "ChatServer/consts/redisKey"
+	"ChatServer/pkg/logger"
+	"ChatServer/pkg/util"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var connectCallHandlerLoggerOnce sync.Once
+
+func initConnectCallHandlerLogger() {
+	connectCallHandlerLoggerOnce.Do(func() {
+		logger.ReplaceGlobal(zap.NewNop())
+		gin.SetMode(gin.TestMode)
+	})
+}
+
+type fakeCallRecorder struct {
+	records chan svc.CallRecord
+}
+
+func (f *fakeCallRecorder) RecordCall(_ context.Context, record svc.CallRecord) error {
+	f.records <- record
+	return nil
+}
+
+func (f *fakeCallRecorder) next(t *testing.T) svc.CallRecord {
+	t.Helper()
+	select {
+	case record := <-f.records:
+		return record
+	case <-time.After(3 * time.Second):
+		t.Fatal("call record not written")
+		return svc.CallRecord{}
+	}
+}
+
+type callTestFrame struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+func (f callTestFrame) call(t *testing.T) svc.CallData {
+	t.Helper()
+	var data svc.CallData
+	require.NoError(t, json.Unmarshal(f.Data, &data))
+	return data
+}
+
+func (f callTestFrame) errorCode(t *testing.T) int {
+	t.Helper()
+	var data svc.ErrorData
+	require.NoError(t, json.Unmarshal(f.Data, &data))
+	return data.Code
+}
+
+type callTestClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+// newTestWSServer 启动单节点 /ws 服务（无 Redis、无上行限速），setup 用于在启动前调整 handler。
+func newTestWSServer(t *testing.T, setup func(h *WSHandler)) string {
+	t.Helper()
+	initConnectCallHandlerLogger()
+
+	connManager := manager.NewConnectionManager()
+	connectSvc := svc.NewConnectService(nil, nil, nil, svc.UplinkLimitConfig{})
+	wsHandler := NewWSHandler(connManager, connectSvc, manager.ClientOptions{}, svc.AuthWatchConfig{})
+	if setup != nil {
+		setup(wsHandler)
+	}
+
+	engine := gin.New()
+	engine.GET("/ws", wsHandler.ServeWS)
+	srv := httptest.NewServer(engine)
+	t.Cleanup(func() {
+		connManager.Shutdown()
+		srv.Close()
+	})
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+}
+
+// newCallTestServer 启动启用通话的 /ws 服务：通话状态存内存、信令直接投递本地连接。
+func newCallTestServer(t *testing.T, cfg svc.CallConfig) (string, *fakeCallRecorder) {
+	t.Helper()
+	recorder := &fakeCallRecorder{records: make(chan svc.CallRecord, 8)}
+	url := newTestWSServer(t, func(h *WSHandler) {
+		h.SetCalls(svc.NewCalls(nil, svc.NewLocalCallDelivery(h.connManager), recorder, nil, cfg))
+	})
+	return url, recorder
+}
+
+// dialTestWS 建立连接，query 为额外的握手参数。
+func dialTestWS(t *testing.T, url, userUUID, deviceID, query string) *callTestClient {
+	t.Helper()
+	token, err := util.GenerateToken(userUUID, deviceID)
+	require.NoError(t, err)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?token="+token+"&device_id="+deviceID+query, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return &callTestClient{t: t, conn: conn}
+}
+
+// dialCallClient 建立连接，读取 hello 后等待一次心跳往返，保证连接已注册到 manager。
+func dialCallClient(t *testing.T, url, userUUID, deviceID string) *callTestClient {
+	t.Helper()
+	client := dialTestWS(t, url, userUUID, deviceID, "")
+	client.expect("hello")
+	client.send("heartbeat", nil)
+	client.expect("heartbeat_ack")
+	return client
+}
+
+func (c *callTestClient) send(msgType string, data any) {
+	c.t.Helper()
+	frame := map[string]any{"type": msgType}
+	if data != nil {
+		frame["data"] = data
+	}
+	require.NoError(c.t, c.conn.WriteJSON(frame))
+}
+
+// expect 读取下一帧并断言类型。
+func (c *callTestClient) expect(msgType string) callTestFrame {
+	c.t.Helper()
+	require.NoError(c.t, c.conn.SetReadDeadline(time.Now().Add(3*time.Second)))
+	var frame callTestFrame
+	require.NoError(c.t, c.conn.ReadJSON(&frame))
+	require.Equal(c.t, msgType, frame.Type, "data: %s", frame.Data)
+	return frame
+}
+
+func (c *callTestClient) expectEnd(callID, reason string) {
+	c.t.Helper()
+	data := c.expect("call_end").call(c.t)
+	assert.Equal(c.t, callID, data.CallID)
+	assert.Equal(c.t, reason, data.Reason)
+}
+
+func (c *callTestClient) expectError(code int) {
+	c.t.Helper()
+	assert.Equal(c.t, code, c.expect("error").errorCode(c.t))
+}
+
+// invite 发起通话并返回服务端分配的 call_id。
+func (c *callTestClient) invite(to, media string) string {
+	c.t.Helper()
+	c.send(svc.CallTypeInvite, svc.CallData{ToUUID: to, Media: media})
+	data := c.expect("call_invite_ok").call(c.t)
+	require.NotEmpty(c.t, data.CallID)
+	return data.CallID
+}
+
+func TestCallFlowAnsweredOnOneDevice(t *testing.T) {
+	url, recorder := newCallTestServer(t, svc.CallConfig{})
+	alice := dialCallClient(t, url, "alice", "a1")
+	bob1 := dialCallClient(t, url, "bob", "b1")
+	bob2 := dialCallClient(t, url, "bob", "b2")
+
+	callID := alice.invite("bob", svc.CallMediaVideo)
+	for _, bob := range []*callTestClient{bob1, bob2} {
+		invite := bob.expect(svc.CallTypeInvite).call(t)
+		assert.Equal(t, callID, invite.CallID)
+		assert.Equal(t, "alice", invite.FromUUID)
+		assert.Equal(t, svc.CallMediaVideo, invite.Media)
+	}
+
+	bob1.send(svc.CallTypeRinging, svc.CallData{CallID: callID})
+	assert.Equal(t, "b1", alice.expect(svc.CallTypeRinging).call(t).FromDevice)
+
+	bob2.send(svc.CallTypeAccept, svc.CallData{CallID: callID})
+	assert.Equal(t, "b2", alice.expect(svc.CallTypeAccept).call(t).FromDevice)
+	bob1.expectEnd(callID, svc.CallEndAnsweredElsewhere)
+
+	// 接听后 SDP/ICE 只在主叫设备与接听设备之间转发。
+	alice.send(svc.CallTypeOffer, svc.CallData{CallID: callID, Payload: json.RawMessage(`{"sdp":"offer"}`)})
+	offer := bob2.expect(svc.CallTypeOffer).call(t)
+	assert.JSONEq(t, `{"sdp":"offer"}`, string(offer.Payload))
+	assert.Equal(t, "alice", offer.FromUUID)
+
+	bob2.send(svc.CallTypeAnswer, svc.CallData{CallID: callID, Payload: json.RawMessage(`{"sdp":"answer"}`)})
+	assert.JSONEq(t, `{"sdp":"answer"}`, string(alice.expect(svc.CallTypeAnswer).call(t).Payload))
+
+	bob2.send(svc.CallTypeICE, svc.CallData{CallID: callID, Payload: json.RawMessage(`{"candidate":"c1"}`)})
+	assert.JSONEq(t, `{"candidate":"c1"}`, string(alice.expect(svc.CallTypeICE).call(t).Payload))
+
+	bob1.send(svc.CallTypeICE, svc.CallData{CallID: callID, Payload: json.RawMessage(`{"candidate":"c2"}`)})
+	bob1.expectError(consts.CodeConnectCallInvalid)
+
+	alice.send(svc.CallTypeHangup, svc.CallData{CallID: callID})
+	alice.expectEnd(callID, svc.CallEndHangup)
+	bob2.expectEnd(callID, svc.CallEndHangup)
+
+	record := recorder.next(t)
+	assert.Equal(t, callID, record.CallID)
+	assert.Equal(t, "alice", record.CallerUUID)
+	assert.Equal(t, "a1", record.CallerDevice)
+	assert.Equal(t, "bob", record.CalleeUUID)
+	assert.Equal(t, svc.CallResultCompleted, record.Result)
+
+	bob2.send(svc.CallTypeHangup, svc.CallData{CallID: callID})
+	bob2.expectError(consts.CodeConnectCallNotFound)
+}
+
+func TestCallBusy(t *testing.T) {
+	url, recorder := newCallTestServer(t, svc.CallConfig{})
+	alice := dialCallClient(t, url, "alice", "a1")
+	bob := dialCallClient(t, url, "bob", "b1")
+	carol := dialCallClient(t, url, "carol", "c1")
+
+	callID := alice.invite("bob", "")
+	bob.expect(svc.CallTypeInvite)
+
+	carol.send(svc.CallTypeInvite, svc.CallData{ToUUID: "bob"})
+	busy := carol.expect("call_end").call(t)
+	assert.Equal(t, svc.CallEndBusy, busy.Reason)
+	assert.Equal(t, "bob", busy.ToUUID)
+
+	record := recorder.next(t)
+	assert.Equal(t, "carol", record.CallerUUID)
+	assert.Equal(t, svc.CallResultBusy, record.Result)
+
+	// 主叫自身已在通话中时不能再发起。
+	alice.send(svc.CallTypeInvite, svc.CallData{ToUUID: "carol"})
+	alice.expectError(consts.CodeConnectCallInvalid)
+
+	alice.send(svc.CallTypeCancel, svc.CallData{CallID: callID})
+	alice.expectEnd(callID, svc.CallEndCanceled)
+	bob.expectEnd(callID, svc.CallEndCanceled)
+	assert.Equal(t, svc.CallResultCanceled, recorder.next(t).Result)
+}
+
+func TestCallReject(t *testing.T) {
+	url, recorder := newCallTestServer(t, svc.CallConfig{})
+	alice := dialCallClient(t, url, "alice", "a1")
+	bob := dialCallClient(t, url, "bob", "b1")
+
+	callID := alice.invite("bob", svc.CallMediaAudio)
+	bob.expect(svc.CallTypeInvite)
+
+	// 只有被叫可以拒接。
+	alice.send(svc.CallTypeReject, svc.CallData{CallID: callID})
+	alice.expectError(consts.CodeConnectCallInvalid)
+
+	bob.send(svc.CallTypeReject, svc.CallData{CallID: callID})
+	alice.expectEnd(callID, svc.CallEndRejected)
+	bob.expectEnd(callID, svc.CallEndRejected)
+	assert.Equal(t, svc.CallResultRejected, recorder.next(t).Result)
+}
+
+func TestCallRingTimeout(t *testing.T) {
+	url, recorder := newCallTestServer(t, svc.CallConfig{RingTimeout: 200 * time.Millisecond})
+	alice := dialCallClient(t, url, "alice", "a1")
+	bob := dialCallClient(t, url, "bob", "b1")
+
+	callID := alice.invite("bob", svc.CallMediaAudio)
+	bob.expect(svc.CallTypeInvite)
+
+	alice.expectEnd(callID, svc.CallEndTimeout)
+	bob.expectEnd(callID, svc.CallEndTimeout)
+	assert.Equal(t, svc.CallResultMissed, recorder.next(t).Result)
+
+	bob.send(svc.CallTypeAccept, svc.CallData{CallID: callID})
+	bob.expectError(consts.CodeConnectCallNotFound)
+}
+
+func TestCallEndsWhenPeerDisconnects(t *testing.T) {
+	url, recorder := newCallTestServer(t, svc.CallConfig{DisconnectGrace: 100 * time.Millisecond})
+	alice := dialCallClient(t, url, "alice", "a1")
+	bob := dialCallClient(t, url, "bob", "b1")
+
+	callID := alice.invite("bob", svc.CallMediaAudio)
+	bob.expect(svc.CallTypeInvite)
+	bob.send(svc.CallTypeAccept, svc.CallData{CallID: callID})
+	alice.expect(svc.CallTypeAccept)
+
+	require.NoError(t, bob.conn.Close())
+	alice.expectEnd(callID, svc.CallEndDisconnected)
+	assert.Equal(t, svc.CallResultCompleted, recorder.next(t).Result)
+}
+
+func TestCallInviteRelationGuard(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	ctx := context.Background()
+
+	// 关系缓存：alice 与 bob、carol 互为好友；carol 拉黑了 alice；dave 不是好友。
+	for user, friends := range map[string][]string{
+		"alice": {"bob", "carol"},
+		"bob":   {"alice"},
+		"carol": {"alice"},
+	} {
+		for _, friend := range friends {
+			require.NoError(t, rdb.HSet(ctx, rediskey.FriendRelationKey(user), friend, "{}").Err())
+		}
+	}
+	for _, user := range []string{"alice", "bob"} {
+		require.NoError(t, rdb.ZAdd(ctx, rediskey.BlacklistRelationKey(user), redis.Z{Member: "__EMPTY__"}).Err())
+	}
+	require.NoError(t, rdb.ZAdd(ctx, rediskey.BlacklistRelationKey("carol"), redis.Z{Score: 1, Member: "alice"}).Err())
+
+	recorder := &fakeCallRecorder{records: make(chan svc.CallRecord, 8)}
+	url := newTestWSServer(t, func(h *WSHandler) {
+		guard := svc.NewRelationCallGuard(rdb, nil, nil)
+		h.SetCalls(svc.NewCalls(nil, svc.NewLocalCallDelivery(h.connManager), recorder, guard, svc.CallConfig{}))
+	})
+	alice := dialCallClient(t, url, "alice", "a1")
+	carol := dialCallClient(t, url, "carol", "c1")
+
+	// 被叫拉黑了主叫：拒绝，且不向被叫推送邀请、不写通话记录。
+	alice.send(svc.CallTypeInvite, svc.CallData{ToUUID: "carol"})
+	alice.expectError(consts.CodeConnectCallBlocked)
+	// 主叫拉黑了被叫：同样拒绝。
+	carol.send(svc.CallTypeInvite, svc.CallData{ToUUID: "alice"})
+	carol.expectError(consts.CodeConnectCallBlocked)
+	carol.send("heartbeat", nil)
+	carol.expect("heartbeat_ack")
+
+	alice.send(svc.CallTypeInvite, svc.CallData{ToUUID: "dave"})
+	alice.expectError(consts.CodeConnectCallNotFriend)
+
+	// 关系缓存未命中且无法回源时不放行。
+	require.NoError(t, rdb.Del(ctx, rediskey.FriendRelationKey("alice")).Err())
+	alice.send(svc.CallTypeInvite, svc.CallData{ToUUID: "bob"})
+	alice.expectError(consts.CodeInternalError)
+
+	require.NoError(t, rdb.HSet(ctx, rediskey.FriendRelationKey("alice"), "bob", "{}").Err())
+	bob := dialCallClient(t, url, "bob", "b1")
+	callID := alice.invite("bob", svc.CallMediaAudio)
+	assert.Equal(t, callID, bob.expect(svc.CallTypeInvite).call(t).CallID)
+	select {
+	case record := <-recorder.records:
+		t.Fatalf("unexpected call record: %+v", record)
+	default:
+	}
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// TokenSubprotocolPrefix 通过 Sec-WebSocket-Protocol 传递 access token 时使用的子协议前缀。
+// 浏览器 WebSocket API 无法设置自定义请求头，客户端可声明 ["lcchat.proto.v1", "lcchat.token.<access_token>"]：
+// 服务端只回显编码子协议，token 不会出现在 URL 与访问日志中。
+// 注意必须同时声明一个编码子协议，否则服务端不回显子协议，浏览器会判定握手失败。
+const TokenSubprotocolPrefix = "lcchat.token."
+
+// HandshakeConfig /ws、/sse 握手安全策略。
+type HandshakeConfig struct {
+	// AllowedOrigins Origin 白名单，为空时不校验：
+	// - "*" 放行全部；
+	// - "https://app.example.com" 精确匹配（端口需显式写出）；
+	// - "https://*.example.com" 匹配任意层级子域名（不含 example.com 本身）；
+	// - 省略 scheme（如 "*.example.com"）时匹配任意 scheme。
+	// 未携带 Origin 的请求（原生客户端）始终放行。
+	AllowedOrigins []string
+	// AllowQueryToken 是否接受 ?token= 传递的 access token。
+	AllowQueryToken bool
+}
+
+// DefaultHandshakeConfig 返回默认策略：不校验 Origin，允许 query 传 token（兼容旧客户端）。
+func DefaultHandshakeConfig() HandshakeConfig {
+	return HandshakeConfig{AllowQueryToken: true}
+}
+
+// SetHandshake 设置握手安全策略，需在开始接入前调用。
+func (h *WSHandler) SetHandshake(cfg HandshakeConfig) {
+	h.origins = newOriginPolicy(cfg.AllowedOrigins)
+	h.allowQueryToken = cfg.AllowQueryToken
+	h.upgrader.CheckOrigin = h.origins.allowRequest
+}
+
+// handshakeToken 按优先级读取握手 token：
+// Sec-WebSocket-Protocol（lcchat.token.*）> Authorization: Bearer > query token（允许时）。
+func (h *WSHandler) handshakeToken(r *http.Request) string {
+	for _, protocol := range websocket.Subprotocols(r) {
+		if token, ok := strings.CutPrefix(protocol, TokenSubprotocolPrefix); ok && token != "" {
+			return token
+		}
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && strings.TrimSpace(token) != "" {
+		return token
+	}
+	if h.allowQueryToken {
+		return r.URL.Query().Get("token")
+	}
+	return ""
+}
+
+// originPolicy Origin 白名单，nil 表示不校验。
+type originPolicy struct {
+	any      bool
+	patterns []originPattern
+}
+
+// originPattern 单条白名单规则，host 含端口；wildcard 时 host 为 ".example.com" 形式的后缀。
+type originPattern struct {
+	scheme   string
+	host     string
+	wildcard bool
+}
+
+// newOriginPolicy 解析白名单，列表为空时返回 nil（不校验）。
+func newOriginPolicy(allowed []string) *originPolicy {
+	if len(allowed) == 0 {
+		return nil
+	}
+	policy := &originPolicy{}
+	for _, raw := range allowed {
+		value := strings.ToLower(strings.TrimSpace(raw))
+		if value == "" {
+			continue
+		}
+		if value == "*" {
+			policy.any = true
+			continue
+		}
+		var pattern originPattern
+		if scheme, rest, ok := strings.Cut(value, "://"); ok {
+			pattern.scheme = scheme
+			value = rest
+		}
+		value = strings.TrimSuffix(value, "/")
+		if suffix, ok := strings.CutPrefix(value, "*."); ok {
+			pattern.wildcard = true
+			value = "." + suffix
+		}
+		pattern.host = value
+		policy.patterns = append(policy.patterns, pattern)
+	}
+	return policy
+}
+
+// allowRequest 供 websocket.Upgrader.CheckOrigin 使用。
+func (p *originPolicy) allowRequest(r *http.Request) bool {
+	return p.allow(r.Header.Get("Origin"))
+}
+
+// allow 判断 Origin 是否在白名单内；"null" 等非 URL 形式的 Origin 只能被同名规则精确匹配。
+func (p *originPolicy) allow(origin string) bool {
+	origin = strings.ToLower(strings.TrimSpace(origin))
+	if p == nil || p.any || origin == "" {
+		return true
+	}
+
+	var scheme, host string
+	if u, err := url.Parse(origin); err == nil && u.Scheme != "" && u.Host != "" {
+		scheme, host = u.Scheme, u.Host
+	}
+	for _, pattern := range p.patterns {
+		if host == "" {
+			if pattern.scheme == "" && !pattern.wildcard && pattern.host == origin {
+				return true
+			}
+			continue
+		}
+		if pattern.scheme != "" && pattern.scheme != scheme {
+			continue
+		}
+		if pattern.wildcard {
+			if len(host) > len(pattern.host) && strings.HasSuffix(host, pattern.host) {
+				return true
+			}
+			continue
+		}
+		if pattern.host == host {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,250 @@
+package handler
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"ChatServer/apps/connect/internal/codec"
+	"ChatServer/apps/connect/internal/manager"
+	"ChatServer/apps/connect/internal/svc"
+	"ChatServer/consts"
+	rediskey "ChatServer/consts/redisKey"
+	"ChatServer/pkg/connectroute"
+	"ChatServer/pkg/result"
+	"ChatServer/pkg/util"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOriginPolicy(t *testing.T) {
+	policy := newOriginPolicy([]string{
+		"https://app.example.com",
+		"https://*.chat.example.com",
+		"*.internal.test",
+		"http://localhost:5173",
+		"null",
+	})
+
+	cases := []struct {
+		origin string
+		allow  bool
+	}{
+		{"", true},
+		{"https://app.example.com", true},
+		{"HTTPS://APP.EXAMPLE.COM", true},
+		{"http://app.example.com", false},
+		{"https://app.example.com:8443", false},
+		{"https://evil.example.com", false},
+		{"https://web.chat.example.com", true},
+		{"https://a.b.chat.example.com", true},
+		{"https://chat.example.com", false},
+		{"https://evilchat.example.com", false},
+		{"http://web.chat.example.com", false},
+		{"http://dev.internal.test", true},
+		{"https://dev.internal.test", true},
+		{"http://localhost:5173", true},
+		{"http://localhost:3000", false},
+		{"null", true},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.allow, policy.allow(tc.origin), tc.origin)
+	}
+
+	assert.True(t, newOriginPolicy(nil).allow("https://any.example.com"))
+	assert.True(t, newOriginPolicy([]string{"*"}).allow("https://any.example.com"))
+	assert.False(t, newOriginPolicy([]string{"https://app.example.com"}).allow("null"))
+}
+
+func TestHandshakeRejections(t *testing.T) {
+	token, err := util.GenerateToken("alice", "a1")
+	require.NoError(t, err)
+	strict := HandshakeConfig{AllowedOrigins: []string{"https://*.example.com"}}
+
+	cases := []struct {
+		name         string
+		cfg          HandshakeConfig
+		query        url.Values
+		origin       string
+		subprotocols []string
+		status       int
+		code         int
+	}{
+		{
+			name:   "origin not allowed",
+			cfg:    strict,
+			query:  url.Values{"device_id": {"a1"}},
+			origin: "https://evil.test",
+			subprotocols: []string{
+				codec.SubprotocolJSON, TokenSubprotocolPrefix + token,
+			},
+			status: http.StatusForbidden,
+			code:   consts.CodeConnectOriginNotAllowed,
+		},
+		{
+			name:   "missing token",
+			cfg:    DefaultHandshakeConfig(),
+			query:  url.Values{"device_id": {"a1"}},
+			status: http.StatusBadRequest,
+			code:   consts.CodeConnectTokenRequired,
+		},
+		{
+			name:   "query token disabled",
+			cfg:    strict,
+			query:  url.Values{"token": {token}, "device_id": {"a1"}},
+			origin: "https://app.example.com",
+			status: http.StatusBadRequest,
+			code:   consts.CodeConnectTokenRequired,
+		},
+		{
+			name:         "invalid subprotocol token",
+			cfg:          strict,
+			query:        url.Values{"device_id": {"a1"}},
+			subprotocols: []string{codec.SubprotocolJSON, TokenSubprotocolPrefix + "not-a-jwt"},
+			status:       http.StatusUnauthorized,
+			code:         consts.CodeInvalidToken,
+		},
+		{
+			name:         "missing device_id",
+			cfg:          strict,
+			subprotocols: []string{codec.SubprotocolJSON, TokenSubprotocolPrefix + token},
+			status:       http.StatusBadRequest,
+			code:         consts.CodeConnectDeviceIDRequired,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			wsURL := newTestWSServer(t, func(h *WSHandler) { h.SetHandshake(tc.cfg) })
+			header := http.Header{}
+			if tc.origin != "" {
+				header.Set("Origin", tc.origin)
+			}
+			dialer := websocket.Dialer{Subprotocols: tc.subprotocols}
+
+			conn, resp, err := dialer.Dial(wsURL+"?"+tc.query.Encode(), header)
+			if conn != nil {
+				_ = conn.Close()
+			}
+			require.ErrorIs(t, err, websocket.ErrBadHandshake)
+			require.NotNil(t, resp)
+			defer resp.Body.Close()
+			assert.Equal(t, tc.status, resp.StatusCode)
+			var body result.Response
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, tc.code, body.Code)
+		})
+	}
+}
+
+func TestHandshakeTokenViaSubprotocol(t *testing.T) {
+	wsURL := newTestWSServer(t, func(h *WSHandler) {
+		h.SetHandshake(HandshakeConfig{AllowedOrigins: []string{"https://*.example.com"}})
+	})
+	token, err := util.GenerateToken("alice", "a1")
+	require.NoError(t, err)
+
+	header := http.Header{}
+	header.Set("Origin", "https://web.example.com")
+	dialer := websocket.Dialer{Subprotocols: []string{codec.SubprotocolJSON, TokenSubprotocolPrefix + token}}
+	conn, _, err := dialer.Dial(wsURL+"?device_id=a1", header)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	// 只回显编码子协议，token 不出现在响应中。
+	assert.Equal(t, codec.SubprotocolJSON, conn.Subprotocol())
+	client := &callTestClient{t: t, conn: conn}
+	client.expect("hello")
+}
+
+// newTicketTestServer 启动使用 miniredis 的 /ws 服务，并为 alice/a1 写入登录态。
+func newTicketTestServer(t *testing.T) (string, *redis.Client, connectroute.Ticket) {
+	t.Helper()
+	initConnectCallHandlerLogger()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	token, err := util.GenerateToken("alice", "a1")
+	require.NoError(t, err)
+	tokenHash := md5.Sum([]byte(token))
+	ticket := connectroute.Ticket{
+		UserUUID:  "alice",
+		DeviceID:  "a1",
+		ClientIP:  "127.0.0.1",
+		TokenHash: hex.EncodeToString(tokenHash[:]),
+	}
+	require.NoError(t, rdb.Set(context.Background(), rediskey.AccessTokenKey("alice", "a1"), ticket.TokenHash, time.Hour).Err())
+
+	connManager := manager.NewConnectionManager()
+	connectSvc := svc.NewConnectService(rdb, nil, nil, svc.UplinkLimitConfig{})
+	wsHandler := NewWSHandler(connManager, connectSvc, manager.ClientOptions{}, svc.AuthWatchConfig{})
+	engine := gin.New()
+	engine.GET("/ws", wsHandler.ServeWS)
+	srv := httptest.NewServer(engine)
+	t.Cleanup(func() {
+		connManager.Shutdown()
+		srv.Close()
+	})
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws", rdb, ticket
+}
+
+// dialTicket 使用票据握手，返回 HTTP 状态码（成功时为 101）。
+func dialTicket(t *testing.T, wsURL, ticket, deviceID string) int {
+	t.Helper()
+	conn, resp, err := websocket.DefaultDialer.Dial(wsURL+"?ticket="+ticket+"&device_id="+deviceID, nil)
+	if err != nil {
+		require.NotNil(t, resp, err)
+		return resp.StatusCode
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	client := &callTestClient{t: t, conn: conn}
+	client.expect("hello")
+	return resp.StatusCode
+}
+
+func TestHandshakeWithTicketIsSingleUse(t *testing.T) {
+	wsURL, rdb, ticket := newTicketTestServer(t)
+	ctx := context.Background()
+
+	id, err := connectroute.IssueTicket(ctx, rdb, ticket, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, dialTicket(t, wsURL, id, "a1"))
+	assert.Equal(t, http.StatusUnauthorized, dialTicket(t, wsURL, id, "a1"))
+}
+
+func TestHandshakeTicketRejections(t *testing.T) {
+	wsURL, rdb, ticket := newTicketTestServer(t)
+	ctx := context.Background()
+	issue := func(mutate func(*connectroute.Ticket)) string {
+		t.Helper()
+		issued := ticket
+		mutate(&issued)
+		id, err := connectroute.IssueTicket(ctx, rdb, issued, time.Minute)
+		require.NoError(t, err)
+		return id
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, dialTicket(t, wsURL, "unknown", "a1"), "unknown ticket")
+	assert.Equal(t, http.StatusUnauthorized, dialTicket(t, wsURL, issue(func(*connectroute.Ticket) {}), "a2"), "device mismatch")
+	assert.Equal(t, http.StatusUnauthorized, dialTicket(t, wsURL, issue(func(tk *connectroute.Ticket) { tk.ClientIP = "10.0.0.8" }), "a1"), "ip mismatch")
+	assert.Equal(t, http.StatusUnauthorized, dialTicket(t, wsURL, issue(func(tk *connectroute.Ticket) { tk.TokenHash = "stale" }), "a1"), "token refreshed")
+
+	// 登出/踢设备删除 auth:at 后，已签发的票据随之失效。
+	id := issue(func(*connectroute.Ticket) {})
+	require.NoError(t, rdb.Del(ctx, rediskey.AccessTokenKey("alice", "a1")).Err())
+	assert.Equal(t, http.StatusUnauthorized, dialTicket(t, wsURL, id, "a1"), "logged out")
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"ChatServer/apps/connect/internal/manager"
+	"ChatServer/apps/connect/internal/svc"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHelloCarriesPlatformHeartbeatPolicy(t *testing.T) {
+	url := newTestWSServer(t, func(h *WSHandler) {
+		h.SetHeartbeat(svc.HeartbeatConfig{
+			Interval:          30 * time.Second,
+			PlatformIntervals: map[string]time.Duration{"ios": 40 * time.Second},
+			MissedLimit:       3,
+		})
+	})
+
+	client := dialTestWS(t, url, "alice", "a1", "&platform=iOS")
+	var hello svc.HelloData
+	require.NoError(t, json.Unmarshal(client.expect("hello").Data, &hello))
+	assert.Equal(t, int64(40), hello.HeartbeatInterval)
+	assert.Equal(t, int64(120), hello.HeartbeatTimeout)
+	assert.Equal(t, int64(25), hello.PingInterval)
+	assert.Equal(t, "ios", hello.Platform)
+
+	client.send("heartbeat", nil)
+	var ack svc.HeartbeatAckData
+	require.NoError(t, json.Unmarshal(client.expect("heartbeat_ack").Data, &ack))
+	assert.Equal(t, int64(40), ack.HeartbeatInterval)
+
+	web := dialTestWS(t, url, "alice", "w1", "&platform=web")
+	require.NoError(t, json.Unmarshal(web.expect("hello").Data, &hello))
+	assert.Equal(t, int64(30), hello.HeartbeatInterval)
+}
+
+func TestMissedHeartbeatsCloseConnection(t *testing.T) {
+	url := newTestWSServer(t, func(h *WSHandler) {
+		h.SetHeartbeat(svc.HeartbeatConfig{Interval: 100 * time.Millisecond, MissedLimit: 2})
+	})
+
+	client := dialTestWS(t, url, "alice", "a1", "")
+	client.expect("hello")
+
+	// 协议层 Pong 由 gorilla 客户端自动回复，不能代替应用层心跳。
+	require.NoError(t, client.conn.SetReadDeadline(time.Now().Add(3*time.Second)))
+	_, _, err := client.conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, manager.CloseCodeHeartbeatTimeout, closeErr.Code)
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"ChatServer/apps/connect/internal/codec"
+	"ChatServer/apps/connect/internal/manager"
+	"ChatServer/apps/connect/internal/svc"
+	"ChatServer/consts"
+	"ChatServer/pkg/logger"
+	"ChatServer/pkg/result"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sseMaxUplinkSize SSE 上行单次请求体默认上限，与 WebSocket 上行单帧上限一致（ClientOptions.ReadLimit 可覆盖）。
+const sseMaxUplinkSize = 1 << 20
+
+// sseStreamBytes SSE 上行凭证随机字节数（base64url 编码后 43 个字符）。
+const sseStreamBytes = 32
+
+// sseStreamHeader SSE 上行携带流凭证的请求头，未携带时读取 ?stream= 参数。
+const sseStreamHeader = "X-SSE-Stream"
+
+// sseStream 本节点上的一条 SSE 下行流，上行请求据此找到连接与会话。
+type sseStream struct {
+	client  *manager.Client
+	session *svc.Session
+}
+
+// newSSEStreamID 生成 SSE 流的上行凭证。
+// 凭证只在 open 事件中下发一次，持有凭证即视为该流的所有者，上行无需再携带 access token。
+func newSSEStreamID() (string, error) {
+	raw := make([]byte, sseStreamBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// ServeSSE 处理 SSE 降级接入（GET /sse），用于拦截 WebSocket 升级的企业代理等受限网络。
+// 握手参数与 /ws 一致（token/device_id/resume/batch），鉴权、连接注册、补发、登录态跟踪与 /ws 共用；
+// 下行固定为 JSON 信封（SSE 只能承载文本），首个 open 事件下发上行凭证，上行通过 POST /sse/send 携带凭证发送。
+// 说明：SSE 无法在同一连接上行，负载均衡需按 device_id 或 Cookie 会话保持，让上行与下行落在同一节点。
+func (h *WSHandler) ServeSSE(c *gin.Context) {
+	session, ok := h.acceptHandshake(c)
+	if !ok {
+		return
+	}
+	ctx := newConnContext(c, session)
+
+	streamID, err := newSSEStreamID()
+	if err != nil {
+		logger.Error(ctx, "SSE 上行凭证生成失败",
+			logger.ErrorField("error", err),
+		)
+		h.writeHTTPError(c, http.StatusInternalServerError, consts.CodeInternalError)
+		return
+	}
+	transport, err := manager.NewSSETransport(c.Writer, c.Request.Context().Done(), streamID)
+	if err != nil {
+		logger.Warn(ctx, "SSE 流建立失败",
+			logger.ErrorField("error", err),
+		)
+		h.writeHTTPError(c, http.StatusInternalServerError, consts.CodeInternalError)
+		return
+	}
+
+	opts := h.clientOpts
+	opts.Codec = codec.JSON
+	opts.Compression = manager.CompressionConfig{}
+	opts.Batch = opts.Batch && session.Batch
+	opts.ClientIP = session.ClientIP
+	opts.Topics = svc.AutoTopics(session)
+	opts.Keepalive = keepaliveFor(opts.Keepalive, session)
+	client := manager.NewClientWithTransport(transport, session.UserUUID, session.DeviceID, opts)
+
+	stream := &sseStream{client: client, session: session}
+	h.sseStreams.Store(streamID, stream)
+	defer h.sseStreams.Delete(streamID)
+
+	h.serveClient(ctx, client, session)
+}
+
+// ServeSSESend 处理 SSE 上行（POST /sse/send，X-SSE-Stream 头或 ?stream= 携带 open 事件下发的凭证）。
+// 上行不校验 access token：凭证与下行流绑定，流的登录态由 reauth 与吊销检测维护，
+// access token 过期或轮换后上行仍可用（reauth 本身也经此上行）。
+// 请求体为一条与 WebSocket 上行相同的 JSON 信封（heartbeat/client_ack/reauth 等），
+// 处理结果（heartbeat_ack、error 等）经 SSE 下行流返回；HTTP 响应只表示是否已受理。
+func (h *WSHandler) ServeSSESend(c *gin.Context) {
+	if !h.origins.allowRequest(c.Request) {
+		h.writeHTTPError(c, http.StatusForbidden, consts.CodeConnectOriginNotAllowed)
+		return
+	}
+
+	streamID := c.GetHeader(sseStreamHeader)
+	if streamID == "" {
+		streamID = c.Query("stream")
+	}
+	value, ok := h.sseStreams.Load(streamID)
+	if streamID == "" || !ok {
+		h.writeHTTPError(c, http.StatusConflict, consts.CodeConnectStreamNotFound)
+		return
+	}
+	stream := value.(*sseStream)
+
+	limit := h.clientOpts.ReadLimit
+	if limit <= 0 {
+		limit = sseMaxUplinkSize
+	}
+	raw, err := io.ReadAll(io.LimitReader(c.Request.Body, limit+1))
+	if err != nil || int64(len(raw)) > limit {
+		h.writeHTTPError(c, http.StatusBadRequest, consts.CodeConnectMessageFormatError)
+		return
+	}
+
+	h.handleMessage(newConnContext(c, stream.session), stream.client, stream.session, raw)
+	result.Success(c, nil)
+}
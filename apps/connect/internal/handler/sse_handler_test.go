@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ChatServer/apps/connect/internal/codec"
+	"ChatServer/apps/connect/internal/manager"
+	"ChatServer/apps/connect/internal/svc"
+	"ChatServer/apps/connect/pb"
+	"ChatServer/pkg/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sseTestEvent 一个 SSE 事件，name 为空表示默认的 message 事件。
+type sseTestEvent struct {
+	name string
+	data string
+}
+
+type sseTestClient struct {
+	t      *testing.T
+	base   string
+	stream string
+	body   io.Closer
+	events chan sseTestEvent
+}
+
+// newSSETestServer 启动单节点 /sse、/sse/send 服务，返回服务根地址与连接管理器。
+func newSSETestServer(t *testing.T) (string, *manager.ConnectionManager) {
+	t.Helper()
+	initConnectCallHandlerLogger()
+
+	connManager := manager.NewConnectionManager()
+	connectSvc := svc.NewConnectService(nil, nil, nil, svc.UplinkLimitConfig{})
+	wsHandler := NewWSHandler(connManager, connectSvc, manager.ClientOptions{}, svc.AuthWatchConfig{})
+
+	engine := gin.New()
+	engine.GET("/sse", wsHandler.ServeSSE)
+	engine.POST("/sse/send", wsHandler.ServeSSESend)
+	srv := httptest.NewServer(engine)
+	t.Cleanup(func() {
+		srv.CloseClientConnections()
+		srv.Close()
+	})
+	return srv.URL, connManager
+}
+
+// dialSSE 建立 SSE 流并读取 open 事件中的上行凭证。
+func dialSSE(t *testing.T, base, userUUID, deviceID string) *sseTestClient {
+	t.Helper()
+	token, err := util.GenerateToken(userUUID, deviceID)
+	require.NoError(t, err)
+
+	resp, err := http.Get(base + "/sse?token=" + token + "&device_id=" + deviceID)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	client := &sseTestClient{t: t, base: base, body: resp.Body, events: make(chan sseTestEvent, 16)}
+	go func() {
+		defer close(client.events)
+		reader := bufio.NewReader(resp.Body)
+		var event sseTestEvent
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "":
+				if event.data != "" {
+					client.events <- event
+				}
+				event = sseTestEvent{}
+			case strings.HasPrefix(line, "event: "):
+				event.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				event.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+
+	open := client.next()
+	require.Equal(t, "open", open.name)
+	var data struct {
+		Stream string `json:"stream"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(open.data), &data))
+	require.NotEmpty(t, data.Stream)
+	client.stream = data.Stream
+	return client
+}
+
+// next 读取下一个事件（跳过保活注释行）。
+func (c *sseTestClient) next() sseTestEvent {
+	c.t.Helper()
+	select {
+	case event, ok := <-c.events:
+		require.True(c.t, ok, "sse stream closed")
+		return event
+	case <-time.After(3 * time.Second):
+		c.t.Fatal("sse event not received")
+		return sseTestEvent{}
+	}
+}
+
+// expect 读取下一个 message 事件并校验信封类型。
+func (c *sseTestClient) expect(msgType string) callTestFrame {
+	c.t.Helper()
+	event := c.next()
+	require.Empty(c.t, event.name, "data: %s", event.data)
+	var frame callTestFrame
+	require.NoError(c.t, json.Unmarshal([]byte(event.data), &frame))
+	require.Equal(c.t, msgType, frame.Type, "data: %s", frame.Data)
+	return frame
+}
+
+// send 以 stream 为凭证发送一条上行信封，返回 HTTP 状态码（不携带 access token）。
+func (c *sseTestClient) send(stream, msgType string) int {
+	c.t.Helper()
+	req, err := http.NewRequest(http.MethodPost, c.base+"/sse/send", strings.NewReader(`{"type":"`+msgType+`"}`))
+	require.NoError(c.t, err)
+	if stream != "" {
+		req.Header.Set(sseStreamHeader, stream)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(c.t, err)
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestSSESendBindsToStreamWithoutToken(t *testing.T) {
+	base, _ := newSSETestServer(t)
+	alice := dialSSE(t, base, "alice", "a1")
+	alice.expect("hello")
+
+	assert.Equal(t, http.StatusOK, alice.send(alice.stream, "heartbeat"))
+	alice.expect("heartbeat_ack")
+
+	// 凭证缺失或错误时不会落到任何流上。
+	assert.Equal(t, http.StatusConflict, alice.send("", "heartbeat"))
+	assert.Equal(t, http.StatusConflict, alice.send(alice.stream+"x", "heartbeat"))
+
+	// 每条流的凭证独立，上行只路由到凭证对应的流。
+	bob := dialSSE(t, base, "bob", "b1")
+	bob.expect("hello")
+	assert.NotEqual(t, alice.stream, bob.stream)
+	assert.Equal(t, http.StatusOK, alice.send(bob.stream, "heartbeat"))
+	bob.expect("heartbeat_ack")
+}
+
+// expectEnd 等待服务端结束 SSE 响应。
+func (c *sseTestClient) expectEnd() {
+	c.t.Helper()
+	for {
+		select {
+		case _, ok := <-c.events:
+			if !ok {
+				return
+			}
+		case <-time.After(3 * time.Second):
+			c.t.Fatal("sse stream not ended")
+			return
+		}
+	}
+}
+
+func TestSSERoundTripAndKick(t *testing.T) {
+	base, connManager := newSSETestServer(t)
+	alice := dialSSE(t, base, "alice", "a1")
+	alice.expect("hello")
+	// 上行经 /sse/send 处理，结果回到下行流；往返一次后连接已注册。
+	require.Equal(t, http.StatusOK, alice.send(alice.stream, "heartbeat"))
+	alice.expect("heartbeat_ack")
+	assert.Equal(t, map[string]int{manager.TransportSSE: 1}, connManager.TransportCounts())
+
+	// 下行推送以 message 事件送达。
+	require.True(t, connManager.SendToDevice("alice", "a1", codec.NewFrame(&pb.MessageEnvelope{Type: "message", ConvId: "c1", Seq: 1})))
+	event := alice.next()
+	require.Empty(t, event.name)
+	var pushed struct {
+		Type   string `json:"type"`
+		ConvID string `json:"conv_id"`
+		Seq    int64  `json:"seq"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(event.data), &pushed))
+	assert.Equal(t, "message", pushed.Type)
+	assert.Equal(t, "c1", pushed.ConvID)
+	assert.Equal(t, int64(1), pushed.Seq)
+
+	// 踢线：先下发 kickout，再以 close 事件携带关闭码，随后结束响应。
+	require.True(t, connManager.KickDevice("alice", "a1", codec.NewFrame(&pb.MessageEnvelope{Type: "kickout"}), "test"))
+	alice.expect("kickout")
+	closeEvent := alice.next()
+	require.Equal(t, "close", closeEvent.name)
+	var closed struct {
+		Code int `json:"code"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(closeEvent.data), &closed))
+	assert.Equal(t, manager.CloseCodeKicked, closed.Code)
+	alice.expectEnd()
+
+	// 流结束后凭证失效，上行与下行都不再落到旧连接。
+	require.Eventually(t, func() bool { return connManager.Count() == 0 }, 3*time.Second, 10*time.Millisecond)
+	assert.Equal(t, http.StatusConflict, alice.send(alice.stream, "heartbeat"))
+	assert.False(t, connManager.SendToDevice("alice", "a1", codec.NewFrame(&pb.MessageEnvelope{Type: "message"})))
+}
+
+func TestSSEClientDisconnectReleasesStream(t *testing.T) {
+	base, connManager := newSSETestServer(t)
+	alice := dialSSE(t, base, "alice", "a1")
+	alice.expect("hello")
+	require.Equal(t, http.StatusOK, alice.send(alice.stream, "heartbeat"))
+	alice.expect("heartbeat_ack")
+
+	require.NoError(t, alice.body.Close())
+	require.Eventually(t, func() bool { return connManager.Count() == 0 }, 3*time.Second, 10*time.Millisecond)
+	assert.Equal(t, http.StatusConflict, alice.send(alice.stream, "heartbeat"))
+}
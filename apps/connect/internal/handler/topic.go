@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"ChatServer/apps/connect/internal/manager"
+	"ChatServer/apps/connect/internal/svc"
+	"ChatServer/consts"
+	"ChatServer/pkg/logger"
+	"context"
+	"errors"
+)
+
+// handleTopics 处理 subscribe/unsubscribe 上行帧，成功后回 subscribe_ok/unsubscribe_ok（data.topics 为实际变更的主题）。
+// 主题非法或超过单连接上限时回 error 帧，不断开连接。
+func (h *WSHandler) handleTopics(ctx context.Context, client *manager.Client, envelope *svc.Envelope) {
+	topics, err := h.connectSvc.ParseTopics(envelope.Data)
+	if err != nil {
+		code := consts.CodeConnectMessageFormatError
+		if errors.Is(err, svc.ErrTopicInvalid) {
+			code = consts.CodeConnectTopicInvalid
+		}
+		h.sendErrorFrame(ctx, client, code)
+		return
+	}
+
+	var changed []string
+	if envelope.Type == "subscribe" {
+		changed, err = h.connManager.Subscribe(client, topics)
+		if errors.Is(err, manager.ErrTooManyTopics) {
+			h.sendErrorFrame(ctx, client, consts.CodeConnectTopicLimit)
+			return
+		}
+	} else {
+		changed = h.connManager.Unsubscribe(client, topics)
+	}
+
+	frame, err := h.connectSvc.NewFrame(envelope.Type+"_ok", svc.TopicsData{Topics: changed})
+	if err != nil {
+		logger.Warn(ctx, "主题订阅应答序列化失败",
+			logger.String("type", envelope.Type),
+			logger.ErrorField("error", err),
+		)
+		return
+	}
+	if !client.EnqueueFrame(frame) {
+		client.Close()
+	}
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"encoding/json"
+	"testing"
+
+	"ChatServer/apps/connect/internal/manager"
+	"ChatServer/apps/connect/internal/svc"
+	"ChatServer/consts"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribeFrames(t *testing.T) {
+	url := newTestWSServer(t, func(h *WSHandler) {
+		h.clientOpts = manager.ClientOptions{MaxTopics: 2}
+	})
+	client := dialCallClient(t, url, "alice", "a1")
+	topicsOf := func(frame callTestFrame) []string {
+		var data svc.TopicsData
+		require.NoError(t, json.Unmarshal(frame.Data, &data))
+		return data.Topics
+	}
+
+	// 保留主题与非法主题整体拒绝，连接保持。
+	client.send("subscribe", svc.TopicsData{Topics: []string{"news", "user:bob"}})
+	client.expectError(consts.CodeConnectTopicInvalid)
+	client.send("unsubscribe", svc.TopicsData{Topics: []string{"platform:ios"}})
+	client.expectError(consts.CodeConnectTopicInvalid)
+	client.send("subscribe", nil)
+	client.expectError(consts.CodeConnectTopicInvalid)
+	client.send("subscribe", map[string]any{"topics": "news"})
+	client.expectError(consts.CodeConnectMessageFormatError)
+
+	client.send("subscribe", svc.TopicsData{Topics: []string{"news", "live:1"}})
+	assert.Equal(t, []string{"news", "live:1"}, topicsOf(client.expect("subscribe_ok")))
+	client.send("subscribe", svc.TopicsData{Topics: []string{"sports"}})
+	client.expectError(consts.CodeConnectTopicLimit)
+
+	client.send("unsubscribe", svc.TopicsData{Topics: []string{"news", "missing"}})
+	assert.Equal(t, []string{"news"}, topicsOf(client.expect("unsubscribe_ok")))
+	client.send("subscribe", svc.TopicsData{Topics: []string{"sports"}})
+	assert.Equal(t, []string{"sports"}, topicsOf(client.expect("subscribe_ok")))
+}
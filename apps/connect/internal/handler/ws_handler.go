@@ -1,29 +1,45 @@
 package handler
 
 import (
+	"ChatServer/apps/connect/internal/codec"
 	"ChatServer/apps/connect/internal/manager"
 	"ChatServer/apps/connect/internal/svc"
 	"ChatServer/consts"
+	"ChatServer/pkg/connectroute"
 	"ChatServer/pkg/ctxmeta"
 	"ChatServer/pkg/logger"
 	"ChatServer/pkg/result"
 	"context"
 	"errors"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
 
-var wsUpgrader = websocket.Upgrader{
-	ReadBufferSize:  4096,
-	WriteBufferSize: 4096,
-	// 当前阶段默认放开来源校验，方便本地多端调试（Web/Electron/移动端模拟器）。
-	// 生产环境建议按域名白名单收紧校验策略。
-	CheckOrigin: func(_ *http.Request) bool {
-		return true
-	},
+// uplinkStrikeTimeout 记录上行超限断开次数（Redis）的超时。
+const uplinkStrikeTimeout = 3 * time.Second
+
+// newUpgrader 创建 WebSocket 升级器。
+// enableCompression 为 true 时与声明了 permessage-deflate 的客户端协商压缩，
+// 是否压缩单帧由连接的 CompressionConfig 阈值决定。
+func newUpgrader(enableCompression bool) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+		// 通过 Sec-WebSocket-Protocol 协商下行帧编码：
+		// - lcchat.proto.v1: protobuf MessageEnvelope 二进制帧；
+		// - lcchat.json.v1 或未声明: JSON 文本帧（兼容旧客户端）。
+		Subprotocols:      codec.Subprotocols(),
+		EnableCompression: enableCompression,
+		// 默认放开来源校验，方便本地多端调试（Web/Electron/移动端模拟器）；
+		// 生产环境通过 SetHandshake 配置 Origin 白名单。
+		CheckOrigin: func(_ *http.Request) bool {
+			return true
+		},
+	}
 }
 
 // WSHandler 负责处理 /ws 接入请求。
@@ -34,127 +50,324 @@ var wsUpgrader = websocket.Upgrader{
 type WSHandler struct {
 	connManager *manager.ConnectionManager
 	connectSvc  *svc.ConnectService
+	clientOpts  manager.ClientOptions
+	authCfg     svc.AuthWatchConfig
+	upgrader    *websocket.Upgrader
+	sseStreams  sync.Map // 上行凭证 -> *sseStream，供 SSE 上行定位连接
+	calls       *svc.Calls
+	heartbeat   svc.HeartbeatConfig
+	// origins 握手 Origin 白名单，nil 表示不校验。
+	origins         *originPolicy
+	allowQueryToken bool
 }
 
 // NewWSHandler 创建 WebSocket 入口处理器。
-func NewWSHandler(connManager *manager.ConnectionManager, connectSvc *svc.ConnectService) *WSHandler {
+// clientOpts 为每条连接的默认参数，其中 Codec 会被握手协商结果覆盖；
+// Batch 为 true 表示允许批量帧，最终由客户端 batch=1 参数决定是否启用。
+// authCfg 为连接存活期间的登录态检查策略。
+func NewWSHandler(connManager *manager.ConnectionManager, connectSvc *svc.ConnectService, clientOpts manager.ClientOptions, authCfg svc.AuthWatchConfig) *WSHandler {
 	return &WSHandler{
 		connManager: connManager,
 		connectSvc:  connectSvc,
+		clientOpts:  clientOpts,
+		authCfg:     authCfg,
+		upgrader:    newUpgrader(clientOpts.Compression.Enabled),
+		heartbeat:   svc.DefaultHeartbeatConfig(),
+
+		allowQueryToken: DefaultHandshakeConfig().AllowQueryToken,
 	}
 }
 
+// SetHeartbeat 设置按平台区分的应用层心跳策略，未设置时使用 svc.DefaultHeartbeatConfig。
+func (h *WSHandler) SetHeartbeat(cfg svc.HeartbeatConfig) {
+	h.heartbeat = cfg
+}
+
 // ServeWS 处理 WebSocket 握手与接入。
 // 执行流程：
-// 1. 从 query 中读取 token/device_id，并获取 client_ip。
+// 0. 节点排空中直接返回 503，客户端应重连其他节点。
+// 1. 校验 Origin 白名单；读取 token（子协议/Authorization/query）或一次性 ticket，以及 query 中的 device_id/resume/batch/platform/app_version，并获取 client_ip。
 // 2. 调用 connectSvc.Authenticate 做鉴权。
 // 3. 构建连接级 context（注入 trace/user/device/ip）。
 // 4. 完成协议升级并进入连接处理主循环。
 func (h *WSHandler) ServeWS(c *gin.Context) {
-	token := c.Query("token")
-	deviceID := c.Query("device_id")
+	session, ok := h.acceptHandshake(c)
+	if !ok {
+		return
+	}
+	connCtx := newConnContext(c, session)
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		logger.Warn(connCtx, "WebSocket 升级失败",
+			logger.ErrorField("error", err),
+		)
+		return
+	}
+
+	h.handleConnection(connCtx, conn, session)
+}
+
+// acceptHandshake WebSocket 与 SSE 共用的握手校验：排空检查、鉴权、解析 resume/batch/platform/app_version。
+// 未携带 platform/app_version 时从设备信息缓存补全，并按平台计算心跳策略。
+// 返回 false 时已写出 HTTP 错误响应。
+func (h *WSHandler) acceptHandshake(c *gin.Context) (*svc.Session, bool) {
+	if h.connManager.Draining() {
+		h.writeHTTPError(c, http.StatusServiceUnavailable, consts.CodeConnectDraining)
+		return nil, false
+	}
+
+	session, ok := h.authenticate(c)
+	if !ok {
+		return nil, false
+	}
+	// resume 为 conv_id:seq 列表，非法项忽略，不拒绝握手；携带空值表示本地没有任何会话游标。
+	if raw, ok := c.GetQuery("resume"); ok {
+		session.Resume = true
+		session.ResumeCursors = connectroute.ParseResumeCursors(raw)
+	}
+	session.Batch = c.Query("batch") == "1"
+	session.Platform = c.Query("platform")
+	session.AppVersion = c.Query("app_version")
+	h.connectSvc.ResolveDevice(c.Request.Context(), session)
+	session.Heartbeat = h.heartbeat.PolicyFor(session.Platform)
+	return session, true
+}
+
+// authenticate 校验 Origin 与 token（或 ticket）/device_id，失败时写出 HTTP 错误响应。
+func (h *WSHandler) authenticate(c *gin.Context) (*svc.Session, bool) {
+	if !h.origins.allowRequest(c.Request) {
+		h.writeHTTPError(c, http.StatusForbidden, consts.CodeConnectOriginNotAllowed)
+		return nil, false
+	}
+
 	clientIP := ctxmeta.ClientIPFromGin(c)
 	if clientIP == "" {
 		clientIP = c.ClientIP()
 	}
 
-	session, err := h.connectSvc.Authenticate(c.Request.Context(), token, deviceID, clientIP)
+	session, err := h.connectSvc.Authenticate(c.Request.Context(), h.handshakeToken(c.Request), c.Query("ticket"), c.Query("device_id"), clientIP)
 	if err != nil {
 		h.writeAuthError(c, err)
-		return
+		return nil, false
 	}
+	return session, true
+}
 
+// newConnContext 构建连接级 context（注入 trace/user/device/ip），与 HTTP 请求生命周期解耦。
+func newConnContext(c *gin.Context, session *svc.Session) context.Context {
 	connCtx := context.Background()
 	if traceID := ctxmeta.TraceIDFromGin(c); traceID != "" {
 		connCtx = ctxmeta.WithTraceID(connCtx, traceID)
 	}
 	connCtx = ctxmeta.WithUserUUID(connCtx, session.UserUUID)
 	connCtx = ctxmeta.WithDeviceID(connCtx, session.DeviceID)
-	connCtx = ctxmeta.WithClientIP(connCtx, session.ClientIP)
+	return ctxmeta.WithClientIP(connCtx, session.ClientIP)
+}
 
-	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		logger.Warn(connCtx, "WebSocket 升级失败",
-			logger.ErrorField("error", err),
-		)
-		return
-	}
+// handleConnection 按握手协商结果创建 WebSocket 连接并进入生命周期主循环。
+func (h *WSHandler) handleConnection(ctx context.Context, conn *websocket.Conn, session *svc.Session) {
+	opts := h.clientOpts
+	opts.Codec = codec.ForSubprotocol(conn.Subprotocol())
+	opts.Batch = opts.Batch && session.Batch
+	opts.ClientIP = session.ClientIP
+	opts.Topics = svc.AutoTopics(session)
+	opts.Keepalive = keepaliveFor(opts.Keepalive, session)
+	h.serveClient(ctx, manager.NewClient(conn, session.UserUUID, session.DeviceID, opts), session)
+}
 
-	h.handleConnection(connCtx, conn, session)
+// keepaliveFor 在默认保活参数上叠加连接的应用层心跳策略。
+func keepaliveFor(base manager.KeepaliveConfig, session *svc.Session) manager.KeepaliveConfig {
+	base.HeartbeatInterval = session.Heartbeat.Interval
+	base.HeartbeatTimeout = session.Heartbeat.Timeout
+	return base
 }
 
-// handleConnection 承载单个连接的完整生命周期。
+// serveClient 承载单个连接的完整生命周期（WebSocket 与 SSE 共用）。
 // 关键语义：
+// - 第一帧为 hello，下发推荐心跳间隔与超时；
 // - 同设备重复连接时，用新连接替换旧连接；
+// - 携带 resume 时先进入补发状态再注册，补发完成前实时推送暂存并按 (conv_id, seq) 去重；
+// - 连接存活期间跟踪 token 过期与吊销（见 watchAuth）；
 // - 连接建立/断开分别触发 OnConnect/OnDisconnect；
 // - 日志里保留 user_uuid/device_id 便于排障。
-func (h *WSHandler) handleConnection(ctx context.Context, conn *websocket.Conn, session *svc.Session) {
-	client := manager.NewClient(conn, session.UserUUID, session.DeviceID)
+func (h *WSHandler) serveClient(ctx context.Context, client *manager.Client, session *svc.Session) {
+	resuming := session.Resume
+	if resuming {
+		client.BeginResume()
+	}
+	// hello 在注册前入队，保证先于任何推送下发。
+	h.sendHello(ctx, client, session)
 	replaced := h.connManager.Register(client)
 	if replaced != nil {
 		replaced.Close()
 	}
 
 	h.connectSvc.OnConnect(ctx, session)
-	logger.Info(ctx, "WebSocket 连接已建立",
+	logger.Info(ctx, "客户端连接已建立",
 		logger.String("user_uuid", session.UserUUID),
 		logger.String("device_id", session.DeviceID),
 		logger.String("client_ip", session.ClientIP),
+		logger.String("transport", client.Transport()),
+		logger.String("subprotocol", client.Codec().Subprotocol()),
+		logger.Bool("batch", client.Batch()),
 		logger.Int("online_count", h.connManager.Count()),
 	)
 
+	if resuming {
+		// 补发量可能超过写队列容量，需在写协程启动后异步进行。
+		go h.resumeSession(ctx, client, session)
+	}
+	go h.watchAuth(ctx, client, session)
+
 	client.Run(ctx, func(raw []byte) {
 		h.handleMessage(ctx, client, session, raw)
 	}, func() {
 		h.connManager.Unregister(client)
 		h.connectSvc.OnDisconnect(ctx, session)
-		logger.Info(ctx, "WebSocket 连接已断开",
+		if h.calls != nil {
+			h.calls.OnDisconnect(session)
+		}
+		logger.Info(ctx, "客户端连接已断开",
 			logger.String("user_uuid", session.UserUUID),
 			logger.String("device_id", session.DeviceID),
+			logger.String("transport", client.Transport()),
 			logger.Int("online_count", h.connManager.Count()),
 		)
 	})
 }
 
 // handleMessage 处理客户端上行帧。
+// 先按帧类型执行上行限速（超限回 error 帧，持续超限断开并累计封禁次数），再按类型分发。
 // 当前支持：
 // - heartbeat: 更新活跃时间并返回 heartbeat_ack；
-// - message: 预留消息链路（当前仅回 message_ack 占位）。
+// - message: 预留消息链路（当前仅回 message_ack 占位）；
+// - client_ack: 确认 ack_required 下行消息，停止对应 (conv_id, seq) 的重传；
+// - reauth: 携带新的 access token 续期连接登录态；
+// - subscribe/unsubscribe: 显式订阅/退订主题；
+// - call_*: 1:1 通话信令（启用通话时）。
 func (h *WSHandler) handleMessage(ctx context.Context, client *manager.Client, session *svc.Session, raw []byte) {
-	envelope, err := h.connectSvc.ParseEnvelope(raw)
+	envelope, err := h.connectSvc.ParseEnvelope(client.Codec(), raw)
 	if err != nil {
 		h.sendErrorFrame(ctx, client, consts.CodeConnectMessageFormatError)
 		return
 	}
+	switch h.connectSvc.CheckUplink(session, envelope.Type) {
+	case svc.UplinkReject:
+		h.sendErrorFrame(ctx, client, consts.CodeConnectRateLimited)
+		return
+	case svc.UplinkClose:
+		h.closeRateLimited(ctx, client, session, envelope.Type)
+		return
+	}
 
 	switch envelope.Type {
 	case "heartbeat":
 		h.connectSvc.OnHeartbeat(ctx, session)
-		ack, marshalErr := h.connectSvc.MarshalEnvelope("heartbeat_ack", nil)
-		if marshalErr != nil {
+		h.connManager.Heartbeat(client)
+		if h.calls != nil {
+			h.calls.Touch(ctx, session)
+		}
+		ack, frameErr := h.connectSvc.NewFrame("heartbeat_ack", svc.HeartbeatAckData{
+			HeartbeatInterval: int64(session.Heartbeat.Interval / time.Second),
+		})
+		if frameErr != nil {
 			logger.Warn(ctx, "心跳应答序列化失败",
-				logger.ErrorField("error", marshalErr),
+				logger.ErrorField("error", frameErr),
 			)
 			return
 		}
-		if !client.Enqueue(ack) {
+		if !client.EnqueueFrame(ack) {
 			client.Close()
 		}
 	case "message":
 		// TODO: 接入 msg 服务进行消息路由与持久化，并返回投递结果回执。
-		ack, marshalErr := h.connectSvc.MarshalEnvelope("message_ack", nil)
-		if marshalErr == nil && !client.Enqueue(ack) {
+		ack, frameErr := h.connectSvc.NewFrame("message_ack", nil)
+		if frameErr == nil && !client.EnqueueFrame(ack) {
 			client.Close()
 		}
+	case "client_ack":
+		acks, parseErr := h.connectSvc.ParseClientAck(envelope.Data)
+		if parseErr != nil {
+			h.sendErrorFrame(ctx, client, consts.CodeConnectMessageFormatError)
+			return
+		}
+		for _, ack := range acks {
+			client.Ack(ack.ConvID, ack.Seq)
+		}
+	case "reauth":
+		h.handleReauth(ctx, client, session, envelope)
+	case "subscribe", "unsubscribe":
+		h.handleTopics(ctx, client, envelope)
 	default:
+		if h.calls != nil && svc.IsCallType(envelope.Type) {
+			h.handleCall(ctx, client, session, envelope)
+			return
+		}
 		h.sendErrorFrame(ctx, client, consts.CodeConnectMessageTypeNotSupport)
 	}
 }
 
+// closeRateLimited 持续超限时以 CloseCodeRateLimited 断开，并累计断开次数（达到阈值时临时封禁）。
+func (h *WSHandler) closeRateLimited(ctx context.Context, client *manager.Client, session *svc.Session, msgType string) {
+	strikeCtx, cancel := context.WithTimeout(ctx, uplinkStrikeTimeout)
+	banned := h.connectSvc.StrikeUplink(strikeCtx, session)
+	cancel()
+
+	logger.Warn(ctx, "上行消息持续超限，断开连接",
+		logger.String("msg_type", msgType),
+		logger.Bool("banned", banned),
+	)
+	client.CloseWithCode(manager.CloseCodeRateLimited, "rate limited")
+}
+
+// sendHello 下发 hello 帧（推荐心跳间隔、心跳超时与 Ping 周期）。
+func (h *WSHandler) sendHello(ctx context.Context, client *manager.Client, session *svc.Session) {
+	frame, err := h.connectSvc.NewFrame("hello", svc.NewHelloData(session, client.Keepalive().PingPeriod))
+	if err != nil {
+		logger.Warn(ctx, "hello 帧序列化失败",
+			logger.ErrorField("error", err),
+		)
+		return
+	}
+	client.EnqueueFrame(frame)
+}
+
+// resumeSession 补发断线期间的消息。
+// 补发完成后下发 resume_ok；缓冲无法覆盖缺口时下发 resync，由客户端按各会话本地 seq 全量拉取。
+func (h *WSHandler) resumeSession(ctx context.Context, client *manager.Client, session *svc.Session) {
+	frames, reason := h.connectSvc.LoadResume(ctx, session)
+	replayed := client.EndResume(frames)
+
+	msgType := "resume_ok"
+	if reason != "" {
+		msgType = "resync"
+	}
+	frame, err := h.connectSvc.NewFrame(msgType, svc.ResumeData{
+		Replayed: replayed,
+		Reason:   string(reason),
+	})
+	if err != nil {
+		logger.Warn(ctx, "补发结果帧序列化失败",
+			logger.ErrorField("error", err),
+		)
+		return
+	}
+	if !client.EnqueueFrame(frame) {
+		client.Close()
+		return
+	}
+	logger.Info(ctx, "WebSocket 断线补发完成",
+		logger.Int("resume_convs", len(session.ResumeCursors)),
+		logger.Int("replayed", replayed),
+		logger.String("resync_reason", string(reason)),
+	)
+}
+
 // sendErrorFrame 发送 ws 协议层错误帧。
 // 发送失败通常表示连接不可写，此时主动关闭连接避免资源泄漏。
 func (h *WSHandler) sendErrorFrame(ctx context.Context, client *manager.Client, code int) {
-	payload, err := h.connectSvc.MarshalEnvelope("error", svc.ErrorData{
+	frame, err := h.connectSvc.NewFrame("error", svc.ErrorData{
 		Code:    code,
 		Message: consts.GetMessage(code),
 	})
@@ -165,7 +378,7 @@ func (h *WSHandler) sendErrorFrame(ctx context.Context, client *manager.Client,
 		)
 		return
 	}
-	if !client.Enqueue(payload) {
+	if !client.EnqueueFrame(frame) {
 		client.Close()
 	}
 }
@@ -180,6 +393,8 @@ func (h *WSHandler) writeAuthError(c *gin.Context, err error) {
 		h.writeHTTPError(c, http.StatusBadRequest, consts.CodeConnectDeviceIDRequired)
 	case errors.Is(err, svc.ErrTokenInvalid):
 		h.writeHTTPError(c, http.StatusUnauthorized, consts.CodeInvalidToken)
+	case errors.Is(err, svc.ErrBanned):
+		h.writeHTTPError(c, http.StatusTooManyRequests, consts.CodeConnectBanned)
 	default:
 		h.writeHTTPError(c, http.StatusInternalServerError, consts.CodeInternalError)
 	}
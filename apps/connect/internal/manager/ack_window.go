@@ -0,0 +1,174 @@
+package manager
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// defaultAckWindowSize 单连接最多等待回执的消息数。
+	defaultAckWindowSize = 128
+	// defaultAckTimeout 首次等待回执的超时时间，之后按指数退避。
+	defaultAckTimeout = 5 * time.Second
+	// defaultAckMaxBackoff 重传间隔上限。
+	defaultAckMaxBackoff = 30 * time.Second
+	// defaultAckMaxRetries 最大重传次数，超过后断开连接让客户端重连并按 seq 拉取。
+	defaultAckMaxRetries = 3
+	// ackCheckInterval 写协程检查超时回执的周期。
+	ackCheckInterval = time.Second
+)
+
+// AckConfig 定义 ack_required 消息的回执与重传策略。
+// WindowSize <= 0 表示关闭回执跟踪（ack_required 消息按普通消息下发）。
+type AckConfig struct {
+	// WindowSize 单连接待回执窗口上限，窗口满时视为慢客户端并断开连接。
+	WindowSize int
+	// Timeout 首次等待回执的超时时间。
+	Timeout time.Duration
+	// MaxBackoff 重传间隔上限。
+	MaxBackoff time.Duration
+	// MaxRetries 最大重传次数。
+	MaxRetries int
+}
+
+// DefaultAckConfig 返回默认回执配置。
+func DefaultAckConfig() AckConfig {
+	return AckConfig{
+		WindowSize: defaultAckWindowSize,
+		Timeout:    defaultAckTimeout,
+		MaxBackoff: defaultAckMaxBackoff,
+		MaxRetries: defaultAckMaxRetries,
+	}
+}
+
+func (c AckConfig) normalize() AckConfig {
+	if c.Timeout <= 0 {
+		c.Timeout = defaultAckTimeout
+	}
+	if c.MaxBackoff < c.Timeout {
+		c.MaxBackoff = max(defaultAckMaxBackoff, c.Timeout)
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = defaultAckMaxRetries
+	}
+	return c
+}
+
+// pendingAck 一条待回执消息。
+// sentAt 为零值表示帧仍在写队列中尚未写出，此时不计超时。
+type pendingAck struct {
+	payload  []byte
+	sentAt   time.Time
+	deadline time.Time
+	retries  int
+}
+
+// ackWindow 单连接的待回执窗口（按 (conv_id, seq) 索引）。
+type ackWindow struct {
+	cfg AckConfig
+
+	mu      sync.Mutex
+	pending map[msgKey]*pendingAck
+}
+
+func newAckWindow(cfg AckConfig) *ackWindow {
+	if cfg.WindowSize <= 0 {
+		return nil
+	}
+	return &ackWindow{
+		cfg:     cfg.normalize(),
+		pending: make(map[msgKey]*pendingAck, cfg.WindowSize),
+	}
+}
+
+// track 在入队前登记待回执消息，超时计时从 sent 标记写出时开始。
+// 返回 false 表示窗口已满；同一 key 重复登记时覆盖（视为业务侧重新推送）。
+func (w *ackWindow) track(key msgKey, payload []byte) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.pending[key]; !ok {
+		if len(w.pending) >= w.cfg.WindowSize {
+			return false
+		}
+		ackPending.Inc()
+	}
+	w.pending[key] = &pendingAck{payload: payload}
+	return true
+}
+
+// sent 标记消息已首次写出并开始计时；已确认或已在计时的消息忽略。
+func (w *ackWindow) sent(key msgKey, now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entry, ok := w.pending[key]
+	if !ok || !entry.sentAt.IsZero() {
+		return
+	}
+	entry.sentAt = now
+	entry.deadline = now.Add(w.cfg.Timeout)
+}
+
+// ack 确认回执，返回从首次写出到确认的耗时（尚未写出时为 0）。
+func (w *ackWindow) ack(key msgKey, now time.Time) (time.Duration, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entry, ok := w.pending[key]
+	if !ok {
+		return 0, false
+	}
+	delete(w.pending, key)
+	ackPending.Dec()
+	if entry.sentAt.IsZero() {
+		return 0, true
+	}
+	return now.Sub(entry.sentAt), true
+}
+
+// due 收集已超时需要重传的消息，并推进其重传次数与下一次截止时间。
+// 仍在写队列中的消息不参与超时判断。
+// exhausted=true 表示存在超过最大重传次数的消息，调用方应断开连接。
+func (w *ackWindow) due(now time.Time) (payloads [][]byte, exhausted bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, entry := range w.pending {
+		if entry.sentAt.IsZero() || now.Before(entry.deadline) {
+			continue
+		}
+		if entry.retries >= w.cfg.MaxRetries {
+			return nil, true
+		}
+		entry.retries++
+		entry.deadline = now.Add(w.backoff(entry.retries))
+		payloads = append(payloads, entry.payload)
+	}
+	return payloads, false
+}
+
+// backoff 第 n 次重传后的等待时间：Timeout * 2^n，上限 MaxBackoff。
+func (w *ackWindow) backoff(retries int) time.Duration {
+	wait := w.cfg.Timeout
+	for i := 0; i < retries && wait < w.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, w.cfg.MaxBackoff)
+}
+
+// size 返回当前待回执数量。
+func (w *ackWindow) size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.pending)
+}
+
+// release 连接关闭时清空窗口。
+func (w *ackWindow) release() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ackPending.Sub(float64(len(w.pending)))
+	w.pending = make(map[msgKey]*pendingAck)
+}
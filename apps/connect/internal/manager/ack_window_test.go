@@ -0,0 +1,124 @@
+package manager
+
+import (
+	"ChatServer/apps/connect/internal/codec"
+	"ChatServer/apps/connect/pb"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testAckWindow(size int) *ackWindow {
+	return newAckWindow(AckConfig{WindowSize: size, Timeout: time.Second, MaxBackoff: 4 * time.Second, MaxRetries: 2})
+}
+
+func TestAckWindowTrackAndAck(t *testing.T) {
+	w := testAckWindow(4)
+	now := time.Now()
+	a5 := msgKey{convID: "convA", seq: 5}
+	b5 := msgKey{convID: "convB", seq: 5}
+
+	require.True(t, w.track(a5, []byte("a5")))
+	require.True(t, w.track(b5, []byte("b5")))
+	assert.Equal(t, 2, w.size())
+
+	// 同一 seq 不同会话互不影响。
+	w.sent(a5, now)
+	latency, ok := w.ack(a5, now.Add(300*time.Millisecond))
+	assert.True(t, ok)
+	assert.Equal(t, 300*time.Millisecond, latency)
+	_, ok = w.ack(a5, now)
+	assert.False(t, ok)
+	assert.Equal(t, 1, w.size())
+
+	// 尚未写出即被确认时耗时记为 0。
+	latency, ok = w.ack(b5, now)
+	assert.True(t, ok)
+	assert.Zero(t, latency)
+	assert.Zero(t, w.size())
+}
+
+func TestAckWindowFull(t *testing.T) {
+	w := testAckWindow(2)
+	require.True(t, w.track(msgKey{convID: "c", seq: 1}, nil))
+	require.True(t, w.track(msgKey{convID: "c", seq: 2}, nil))
+	assert.False(t, w.track(msgKey{convID: "c", seq: 3}, nil))
+	// 重复登记视为覆盖，不占用新位置。
+	assert.True(t, w.track(msgKey{convID: "c", seq: 2}, []byte("again")))
+
+	w.ack(msgKey{convID: "c", seq: 1}, time.Now())
+	assert.True(t, w.track(msgKey{convID: "c", seq: 3}, nil))
+}
+
+func TestAckWindowDue(t *testing.T) {
+	w := testAckWindow(4)
+	now := time.Now()
+	key := msgKey{convID: "c", seq: 1}
+	require.True(t, w.track(key, []byte("m1")))
+
+	// 仍在写队列中的消息不计超时。
+	payloads, exhausted := w.due(now.Add(time.Hour))
+	assert.Empty(t, payloads)
+	assert.False(t, exhausted)
+
+	w.sent(key, now)
+	// 重复标记不重置计时。
+	w.sent(key, now.Add(500*time.Millisecond))
+
+	payloads, _ = w.due(now.Add(999 * time.Millisecond))
+	assert.Empty(t, payloads)
+
+	// 第 1 次重传后等待 2s，第 2 次后等待 4s，之后超过 MaxRetries 断开。
+	at := now.Add(time.Second)
+	payloads, exhausted = w.due(at)
+	assert.Equal(t, [][]byte{[]byte("m1")}, payloads)
+	assert.False(t, exhausted)
+
+	payloads, _ = w.due(at.Add(time.Second))
+	assert.Empty(t, payloads)
+	at = at.Add(2 * time.Second)
+	payloads, _ = w.due(at)
+	assert.Len(t, payloads, 1)
+
+	at = at.Add(4 * time.Second)
+	payloads, exhausted = w.due(at)
+	assert.Empty(t, payloads)
+	assert.True(t, exhausted)
+}
+
+func TestAckWindowBackoff(t *testing.T) {
+	w := newAckWindow(AckConfig{WindowSize: 1, Timeout: time.Second, MaxBackoff: 5 * time.Second})
+	assert.Equal(t, time.Second, w.backoff(0))
+	assert.Equal(t, 2*time.Second, w.backoff(1))
+	assert.Equal(t, 4*time.Second, w.backoff(2))
+	assert.Equal(t, 5*time.Second, w.backoff(3))
+	assert.Equal(t, 5*time.Second, w.backoff(10))
+}
+
+func TestClientAckTimerStartsOnWrite(t *testing.T) {
+	transport := newFakeTransport()
+	c := NewClientWithTransport(transport, "alice", "a1", ClientOptions{Ack: DefaultAckConfig()})
+	ackFrame := func(convID string, seq int64) *codec.Frame {
+		return codec.NewFrame(&pb.MessageEnvelope{Type: "message", ConvId: convID, Seq: seq, AckRequired: true})
+	}
+
+	require.True(t, c.EnqueueFrame(ackFrame("convA", 5)))
+	require.True(t, c.EnqueueFrame(ackFrame("convB", 5)))
+	assert.Equal(t, 2, c.PendingAcks())
+
+	// 排队期间不会超时重传。
+	payloads, _ := c.acks.due(time.Now().Add(time.Hour))
+	assert.Empty(t, payloads)
+
+	require.NoError(t, c.writeBatch())
+	assert.Len(t, transport.written, 2)
+	payloads, _ = c.acks.due(time.Now().Add(time.Hour))
+	assert.Len(t, payloads, 2)
+
+	assert.True(t, c.Ack("convA", 5))
+	assert.False(t, c.Ack("convA", 5))
+	assert.True(t, c.Ack("convB", 5))
+	assert.Zero(t, c.PendingAcks())
+}
@@ -1,59 +1,187 @@
 package manager
 
 import (
+	"ChatServer/apps/connect/internal/codec"
+	"ChatServer/apps/connect/pb"
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
 const (
+	// defaultSendQueueSize 默认单连接写队列容量。
 	defaultSendQueueSize = 64
 	// wsWriteTimeout 单次写操作超时，避免慢连接长期阻塞写协程。
 	wsWriteTimeout = 5 * time.Second
-	// wsPongWait 读取超时窗口：若该时间内未收到任何数据或 Pong，判定连接失活。
-	wsPongWait = 60 * time.Second
-	// wsPingPeriod 主动 Ping 周期，通常小于 pongWait，确保超时窗口持续被刷新。
-	wsPingPeriod = wsPongWait * 9 / 10
 	// wsMaxMessageSize 限制单条上行消息大小，防止超大包导致内存风险。
 	wsMaxMessageSize = 1 << 20 // 1MB
 	// wsBatchDrainLimit 单次唤醒最多额外清空的排队消息数。
-	// 目的：在高峰期减少 goroutine 调度与锁竞争开销。
+	// 目的：在高峰期减少 goroutine 调度与锁竞争开销；开启批量帧时同时是单个 batch 的最大信封数 - 1。
 	wsBatchDrainLimit = 16
 )
 
+// 应用自定义 WebSocket 关闭码（4000-4999 为应用私有区间）。
+const (
+	// CloseCodeAckTimeout ack_required 消息重传耗尽或待回执窗口溢出，客户端应重连后按 seq 拉取。
+	CloseCodeAckTimeout = 4001
+	// CloseCodeSlowConsumer 写队列已满且消息类型策略为 disconnect，客户端应重连后按 seq 拉取。
+	CloseCodeSlowConsumer = 4002
+	// CloseCodeAuthExpired access token 已过期且未 reauth，客户端应刷新 token 后重连。
+	CloseCodeAuthExpired = 4003
+	// CloseCodeAuthRevoked 登录态已被吊销（踢设备/登出/改密），客户端应回到登录页。
+	CloseCodeAuthRevoked = 4004
+	// CloseCodeKicked 被业务侧主动踢下线（踢设备/登出/注销/重置密码），关闭前会下发 kickout 帧。
+	CloseCodeKicked = 4005
+	// CloseCodeRateLimited 上行帧持续超出限速被临时断开，客户端应退避后重连。
+	CloseCodeRateLimited = 4006
+	// CloseCodeHeartbeatTimeout 超过心跳超时未收到应用层 heartbeat，客户端应立即重连。
+	CloseCodeHeartbeatTimeout = 4007
+)
+
 // MessageHandler 定义上行消息回调。
-// 参数 raw 为客户端原始二进制载荷（通常是 JSON 编码后的字节）。
+// 参数 raw 为客户端原始帧载荷，按连接协商的 codec 解码（JSON 或 protobuf）。
 type MessageHandler func(raw []byte)
 
 // CloseHandler 定义连接关闭回调。
 // 用于在 read/write 循环退出后执行清理逻辑（例如从 manager 注销）。
 type CloseHandler func()
 
-// Client 封装单条 WebSocket 连接。
+// Client 封装单条客户端长连接（WebSocket 或 SSE 降级通道）。
 // 设计要点：
-// - send 队列用于削峰，避免业务 goroutine 直接阻塞在网络写；
+// - send 队列用于削峰，避免业务 goroutine 直接阻塞在网络写，队列满时按消息类型执行慢消费策略；
 // - done 用于统一关闭信号，读写循环都监听该信号退出；
-// - once 保证 Close 幂等，避免重复 close channel/panic。
+// - once 保证 Close 幂等，避免重复 close channel/panic；
+// - codec 为握手协商出的帧编码（JSON 文本帧 / protobuf 二进制帧）；
+// - acks 跟踪 ack_required 消息的回执，超时由写协程重传；
+// - resume 在断线重连补发期间暂存实时推送，保证补发与实时推送不重复；
+// - compress/batch 控制下行压缩与积压帧打包；
+// - topics 为主题订阅状态，autoTopics 为握手时计算出的自动主题（见 topic.go）；
+// - keepalive 为保活策略，lastHeartbeat 为最近一次应用层心跳（见 keepalive.go）；
+// - transport 为底层传输（WebSocket 或 SSE），读写都经由它完成。
 type Client struct {
-	conn     *websocket.Conn
-	userUUID string
-	deviceID string
-	send     chan []byte
-	done     chan struct{}
-	once     sync.Once
+	transport Transport
+	userUUID  string
+	deviceID  string
+	codec     codec.Codec
+	acks      *ackWindow
+	resume    resumeState
+	slow      SlowConsumerConfig
+	compress  CompressionConfig
+	batch     bool
+	send      *sendQueue
+	kick      atomic.Pointer[closeRequest]
+	done      chan struct{}
+	once      sync.Once
+
+	clientIP    string
+	connectedAt time.Time
+
+	autoTopics []string
+	topics     clientTopics
+
+	keepalive     KeepaliveConfig
+	lastHeartbeat atomic.Int64
+}
+
+// closeRequest 写队列清空后执行的关闭请求。
+type closeRequest struct {
+	code   int
+	reason string
 }
 
-// NewClient 创建连接包装对象。
-func NewClient(conn *websocket.Conn, userUUID, deviceID string) *Client {
-	return &Client{
-		conn:     conn,
-		userUUID: userUUID,
-		deviceID: deviceID,
-		send:     make(chan []byte, defaultSendQueueSize),
-		done:     make(chan struct{}),
+// ClientOptions 定义单条连接的可选参数。
+type ClientOptions struct {
+	// Codec 握手协商的帧编码，为 nil 时使用 JSON。
+	Codec codec.Codec
+	// Ack ack_required 消息的回执策略，WindowSize <= 0 时不跟踪回执。
+	Ack AckConfig
+	// SlowConsumer 写队列容量与慢消费策略，零值等价于 64 容量 + 全部 spill。
+	SlowConsumer SlowConsumerConfig
+	// Compression permessage-deflate 压缩策略，仅对握手时协商了压缩扩展的连接生效。
+	Compression CompressionConfig
+	// Batch 是否把积压的多条帧打包为一条 type=batch 帧下发，需客户端握手时声明支持。
+	Batch bool
+	// ClientIP 客户端真实 IP，仅用于运维查询。
+	ClientIP string
+	// ReadLimit 单条上行消息大小上限（字节），<=0 时使用 1MB。
+	ReadLimit int64
+	// Topics 握手时自动加入的主题（平台/版本/用户），注册时写入主题索引。
+	Topics []string
+	// MaxTopics 单连接可显式订阅的主题数上限，<=0 时使用 32。
+	MaxTopics int
+	// Keepalive 协议层 Ping/Pong 与应用层心跳超时策略，零值等价于 25s Ping + 40s 读超时、不检查心跳。
+	Keepalive KeepaliveConfig
+}
+
+// ClientInfo 连接运维快照，供管理接口查询。
+type ClientInfo struct {
+	UserUUID    string    `json:"user_uuid"`
+	DeviceID    string    `json:"device_id"`
+	ClientIP    string    `json:"client_ip"`
+	Transport   string    `json:"transport"`
+	Subprotocol string    `json:"subprotocol"`
+	Batch       bool      `json:"batch"`
+	ConnectedAt time.Time `json:"connected_at"`
+	QueueDepth  int       `json:"queue_depth"`
+	PendingAcks int       `json:"pending_acks"`
+	Topics      []string  `json:"topics"`
+}
+
+// NewClient 创建 WebSocket 连接包装对象。
+func NewClient(conn *websocket.Conn, userUUID, deviceID string, opts ClientOptions) *Client {
+	if opts.Codec == nil {
+		opts.Codec = codec.JSON
 	}
+	opts.Compression = opts.Compression.normalize()
+	if opts.Compression.Enabled {
+		// 级别已校验，未协商压缩的连接上设置无副作用。
+		_ = conn.SetCompressionLevel(opts.Compression.Level)
+	}
+	readLimit := opts.ReadLimit
+	if readLimit <= 0 {
+		readLimit = wsMaxMessageSize
+	}
+	pongWait := opts.Keepalive.normalize().PongWait
+	return NewClientWithTransport(newWSTransport(conn, opts.Codec.MessageType(), readLimit, pongWait), userUUID, deviceID, opts)
+}
+
+// NewClientWithTransport 基于任意传输创建连接包装对象（如 SSE）。
+func NewClientWithTransport(transport Transport, userUUID, deviceID string, opts ClientOptions) *Client {
+	if opts.Codec == nil {
+		opts.Codec = codec.JSON
+	}
+	opts.Compression = opts.Compression.normalize()
+	if opts.MaxTopics <= 0 {
+		opts.MaxTopics = defaultMaxTopics
+	}
+	opts.Keepalive = opts.Keepalive.normalize()
+	observeKeepalive(transport.Name(), opts.Keepalive)
+
+	client := &Client{
+		transport: transport,
+		userUUID:  userUUID,
+		deviceID:  deviceID,
+		codec:     opts.Codec,
+		acks:      newAckWindow(opts.Ack),
+		slow:      opts.SlowConsumer,
+		compress:  opts.Compression,
+		batch:     opts.Batch,
+		send:      newSendQueue(opts.SlowConsumer.QueueSize),
+		done:      make(chan struct{}),
+
+		clientIP:    opts.ClientIP,
+		connectedAt: time.Now(),
+
+		autoTopics: opts.Topics,
+		topics:     clientTopics{topics: make(map[string]bool), max: opts.MaxTopics},
+
+		keepalive: opts.Keepalive,
+	}
+	client.touchHeartbeat(client.connectedAt)
+	return client
 }
 
 func (c *Client) UserUUID() string {
@@ -64,29 +192,162 @@ func (c *Client) DeviceID() string {
 	return c.deviceID
 }
 
+// Info 返回连接当前的运维快照（写队列深度、待回执数为读取时刻的值）。
+func (c *Client) Info() ClientInfo {
+	return ClientInfo{
+		UserUUID:    c.userUUID,
+		DeviceID:    c.deviceID,
+		ClientIP:    c.clientIP,
+		Transport:   c.transport.Name(),
+		Subprotocol: c.codec.Subprotocol(),
+		Batch:       c.batch,
+		ConnectedAt: c.connectedAt,
+		QueueDepth:  c.send.size(),
+		PendingAcks: c.PendingAcks(),
+		Topics:      c.Topics(),
+	}
+}
+
+// Transport 返回连接的传输方式名称（ws/sse）。
+func (c *Client) Transport() string {
+	return c.transport.Name()
+}
+
+// Batch 返回连接是否启用批量帧。
+func (c *Client) Batch() bool {
+	return c.batch
+}
+
+// Codec 返回连接协商的帧编码。
+func (c *Client) Codec() codec.Codec {
+	return c.codec
+}
+
 // Done 返回连接关闭信号通道。
 // 外部可通过监听该通道感知连接生命周期结束。
 func (c *Client) Done() <-chan struct{} {
 	return c.done
 }
 
-// Enqueue 将待发送消息投递到写队列。
+// Enqueue 将待发送消息投递到写队列（按默认慢消费策略处理队列满）。
 // 返回值语义：
 // - true：已成功入队；
-// - false：连接已关闭或队列已满（调用方可选择断开连接或丢弃消息）。
+// - false：连接已关闭或被慢消费策略拒绝（调用方可选择断开连接或丢弃消息）。
 func (c *Client) Enqueue(msg []byte) bool {
+	return c.enqueue(msg, nil, 0, nil)
+}
+
+// enqueue 投递到写队列。
+// wait > 0 时队列满会等待空位（用于补发，不触发慢消费策略）；
+// wait <= 0 时队列满按 envelope 类型对应的慢消费策略处理。
+// ack 非空表示该帧已登记到待回执窗口，写出时开始计时。
+func (c *Client) enqueue(msg []byte, envelope *pb.MessageEnvelope, wait time.Duration, ack *msgKey) bool {
 	if len(msg) == 0 {
 		return true
 	}
-	cloned := append([]byte(nil), msg...)
 	select {
 	case <-c.done:
 		return false
-	case c.send <- cloned:
-		return true
 	default:
+	}
+
+	frame := queuedFrame{
+		payload: append([]byte(nil), msg...),
+		msgType: envelope.GetType(),
+		convID:  msgKeyOf(envelope).convID,
+		ack:     ack,
+	}
+	if wait > 0 {
+		return c.enqueueWait(frame, wait)
+	}
+
+	frame.policy = c.slow.policyFor(frame.msgType)
+	decision := c.send.push(frame)
+	if decision != decisionEnqueued {
+		sendQueueDecisionTotal.WithLabelValues(string(frame.policy), string(decision)).Inc()
+	}
+	if decision == decisionDisconnected {
+		c.CloseWithCode(CloseCodeSlowConsumer, "slow consumer")
+	}
+	return decision.accepted()
+}
+
+// enqueueWait 队列满时最多等待 wait 直到写协程腾出空位。
+func (c *Client) enqueueWait(frame queuedFrame, wait time.Duration) bool {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		if c.send.tryPush(frame) {
+			return true
+		}
+		select {
+		case <-c.done:
+			return false
+		case <-timer.C:
+			return false
+		case <-c.send.space:
+		}
+	}
+}
+
+// EnqueueFrame 按连接协商的编码序列化下行帧并投递到写队列。
+// ack_required 且带 seq 的帧会按 (conv_id, seq) 登记到待回执窗口，写出后超时未收到 client_ack 时重传；
+// 窗口已满说明客户端消费过慢，直接断开连接让其重连后按 seq 拉取。
+// 补发进行中时，带 seq 的帧先暂存，补发结束后去重下发；补发结束后的宽限期内已补发过的帧直接视为已送达。
+// 编码失败与入队失败同样返回 false。
+func (c *Client) EnqueueFrame(frame *codec.Frame) bool {
+	if held, ok := c.holdForResume(frame); held {
+		return ok
+	}
+	if c.resumeDelivered(frame) {
+		return true
+	}
+	return c.deliverFrame(frame, 0)
+}
+
+// deliverFrame 编码、登记回执并入队，wait 为写队列满时的最长等待时间。
+func (c *Client) deliverFrame(frame *codec.Frame, wait time.Duration) bool {
+	msg, err := frame.Encode(c.codec)
+	if err != nil {
+		return false
+	}
+
+	envelope := frame.Envelope()
+	if c.acks == nil || !envelope.GetAckRequired() || envelope.GetSeq() <= 0 {
+		return c.enqueue(msg, envelope, wait, nil)
+	}
+
+	key := msgKeyOf(envelope)
+	if !c.acks.track(key, msg) {
+		ackDisconnectTotal.WithLabelValues("window_full").Inc()
+		c.CloseWithCode(CloseCodeAckTimeout, "ack window full")
+		return false
+	}
+	if !c.enqueue(msg, envelope, wait, &key) {
+		c.acks.ack(key, time.Now())
+		return false
+	}
+	return true
+}
+
+// Ack 处理客户端回执，返回 false 表示该 (conv_id, seq) 不在待回执窗口中（已确认或未跟踪）。
+func (c *Client) Ack(convID string, seq int64) bool {
+	if c.acks == nil {
 		return false
 	}
+	latency, ok := c.acks.ack(msgKey{convID: convID, seq: seq}, time.Now())
+	if ok {
+		ackLatency.Observe(latency.Seconds())
+	}
+	return ok
+}
+
+// PendingAcks 返回当前待回执消息数。
+func (c *Client) PendingAcks() int {
+	if c.acks == nil {
+		return 0
+	}
+	return c.acks.size()
 }
 
 // Run 启动读写循环并阻塞等待 readLoop 结束。
@@ -102,11 +363,8 @@ func (c *Client) Run(ctx context.Context, onMessage MessageHandler, onClose Clos
 		}
 	}()
 
-	c.conn.SetReadLimit(wsMaxMessageSize)
-	_ = c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
-	c.conn.SetPongHandler(func(string) error {
-		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
-	})
+	transportConnections.WithLabelValues(c.transport.Name()).Inc()
+	defer transportConnections.WithLabelValues(c.transport.Name()).Dec()
 
 	go c.writeLoop(ctx)
 	c.readLoop(onMessage)
@@ -115,11 +373,14 @@ func (c *Client) Run(ctx context.Context, onMessage MessageHandler, onClose Clos
 // Close 幂等关闭连接。
 // 关闭顺序：
 // 1. 关闭 done 信号，通知读写循环退出；
-// 2. 关闭底层 websocket 连接释放网络资源。
+// 2. 关闭底层连接释放网络资源。
 func (c *Client) Close() {
 	c.once.Do(func() {
 		close(c.done)
-		_ = c.conn.Close()
+		_ = c.transport.Close()
+		if c.acks != nil {
+			c.acks.release()
+		}
 	})
 }
 
@@ -127,9 +388,38 @@ func (c *Client) Close() {
 // 用于优雅停机场景：客户端收到 GoingAway 后知道服务端正在维护，
 // 可立即尝试重连到其他节点，而不是当作异常断线处理。
 func (c *Client) CloseGracefully() {
-	deadline := time.Now().Add(wsWriteTimeout)
-	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
-	_ = c.conn.WriteControl(websocket.CloseMessage, msg, deadline)
+	c.CloseWithCode(websocket.CloseGoingAway, "server shutting down")
+}
+
+// CloseGracefullyWithHint 先下发提示帧（如携带备用接入地址的 reconnect），再以 CloseGoingAway 关闭连接。
+// 用于节点排空：客户端按提示帧重连到其他节点；hint 为 nil 时等价于 CloseGracefully。
+func (c *Client) CloseGracefullyWithHint(hint *codec.Frame) {
+	c.closeAfterFrame(hint, websocket.CloseGoingAway, "server draining")
+}
+
+// Kick 下发最后一帧（如 kickout）后以 CloseCodeKicked 关闭连接。
+func (c *Client) Kick(frame *codec.Frame, reason string) {
+	c.closeAfterFrame(frame, CloseCodeKicked, reason)
+}
+
+// closeAfterFrame 下发最后一帧后以指定关闭码关闭连接。
+// 最后一帧进入写队列，由写协程写出已排队的消息后关闭；
+// 写协程阻塞或入队失败时最迟 wsWriteTimeout 后强制关闭。
+func (c *Client) closeAfterFrame(frame *codec.Frame, code int, reason string) {
+	if frame == nil || !c.deliverFrame(frame, wsWriteTimeout) {
+		c.CloseWithCode(code, reason)
+		return
+	}
+	c.kick.Store(&closeRequest{code: code, reason: reason})
+	signal(c.send.notify)
+	time.AfterFunc(wsWriteTimeout, func() {
+		c.CloseWithCode(code, reason)
+	})
+}
+
+// CloseWithCode 发送指定关闭码的 Close 帧后关闭连接，便于客户端区分断开原因。
+func (c *Client) CloseWithCode(code int, reason string) {
+	_ = c.transport.WriteClose(code, reason)
 	c.Close()
 }
 
@@ -138,8 +428,11 @@ func (c *Client) CloseGracefully() {
 // 退出依赖连接关闭（Close）或网络读错误。
 func (c *Client) readLoop(onMessage MessageHandler) {
 	for {
-		_, raw, err := c.conn.ReadMessage()
+		raw, err := c.transport.ReadMessage()
 		if err != nil {
+			if isTimeout(err) {
+				keepaliveTimeoutTotal.WithLabelValues("pong").Inc()
+			}
 			return
 		}
 
@@ -150,11 +443,27 @@ func (c *Client) readLoop(onMessage MessageHandler) {
 }
 
 // writeLoop 持续从 send 队列取消息写入客户端。
-// 同时按固定周期发送 Ping 保活，收到 Pong 后由读协程刷新读超时。
+// 同时按 PingPeriod 发送 Ping 保活，收到 Pong 后由读协程刷新读超时；
+// 配置了心跳超时时，周期检查应用层心跳，超时以 CloseCodeHeartbeatTimeout 断开；
+// 开启回执跟踪时，按 ackCheckInterval 重传超时未确认的消息。
 func (c *Client) writeLoop(ctx context.Context) {
-	ticker := time.NewTicker(wsPingPeriod)
+	ticker := time.NewTicker(c.keepalive.PingPeriod)
 	defer ticker.Stop()
 
+	var heartbeatTick <-chan time.Time
+	if c.keepalive.HeartbeatTimeout > 0 {
+		heartbeatTicker := time.NewTicker(c.keepalive.heartbeatCheckPeriod())
+		defer heartbeatTicker.Stop()
+		heartbeatTick = heartbeatTicker.C
+	}
+
+	var ackTick <-chan time.Time
+	if c.acks != nil {
+		ackTicker := time.NewTicker(ackCheckInterval)
+		defer ackTicker.Stop()
+		ackTick = ackTicker.C
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -163,58 +472,137 @@ func (c *Client) writeLoop(ctx context.Context) {
 			return
 		case <-c.done:
 			return
-		case msg := <-c.send:
-			if err := c.writeBatch(msg); err != nil {
+		case <-c.send.notify:
+			if err := c.writeBatch(); err != nil {
 				c.Close()
 				return
 			}
+			if req := c.kick.Load(); req != nil && c.send.size() == 0 {
+				c.CloseWithCode(req.code, req.reason)
+				return
+			}
 		case <-ticker.C:
 			if err := c.writePing(); err != nil {
 				c.Close()
 				return
 			}
+		case now := <-heartbeatTick:
+			if c.heartbeatExpired(now) {
+				keepaliveTimeoutTotal.WithLabelValues("heartbeat").Inc()
+				c.CloseWithCode(CloseCodeHeartbeatTimeout, "heartbeat timeout")
+				return
+			}
+		case now := <-ackTick:
+			if err := c.retransmit(now); err != nil {
+				c.Close()
+				return
+			}
 		}
 	}
 }
 
-// writeBatch 先发送当前消息，再尽量清空队列中已积压的消息。
-// 说明：每条业务消息仍保持独立 WebSocket 帧语义，避免破坏上层协议解析。
-func (c *Client) writeBatch(first []byte) error {
-	if err := c.writeFrame(first); err != nil {
-		return err
+// retransmit 重传超时未回执的消息；超过最大重传次数时以 CloseCodeAckTimeout 断开连接。
+// 在写协程内直接写出，不占用 send 队列。
+func (c *Client) retransmit(now time.Time) error {
+	payloads, exhausted := c.acks.due(now)
+	if exhausted {
+		ackDisconnectTotal.WithLabelValues("timeout").Inc()
+		c.CloseWithCode(CloseCodeAckTimeout, "ack timeout")
+		return nil
+	}
+	for _, payload := range payloads {
+		if err := c.writeFrame(payload); err != nil {
+			return err
+		}
+		ackRetransmitTotal.Inc()
 	}
+	return nil
+}
 
-	for i := 0; i < wsBatchDrainLimit; i++ {
-		select {
-		case msg := <-c.send:
-			if err := c.writeFrame(msg); err != nil {
+// writeBatch 单次唤醒最多发送 1+wsBatchDrainLimit 条积压消息。
+// 说明：
+// - 默认每条业务消息保持独立 WebSocket 帧语义，避免破坏上层协议解析；
+// - 客户端声明支持批量帧时，多条积压消息打包为一条 type=batch 帧，减少帧头与压缩上下文开销；
+// 本轮未清空时重新发出唤醒信号，让 Ping/重传有机会穿插执行。
+func (c *Client) writeBatch() error {
+	if c.batch {
+		if err := c.writePacked(); err != nil {
+			return err
+		}
+	} else {
+		for i := 0; i <= wsBatchDrainLimit; i++ {
+			frame, ok := c.send.pop()
+			if !ok {
+				return nil
+			}
+			if err := c.writeFrame(frame.payload); err != nil {
 				return err
 			}
-		default:
-			return nil
+			c.markSent(frame)
 		}
 	}
+	if c.send.size() > 0 {
+		signal(c.send.notify)
+	}
 	return nil
 }
 
-// writeFrame 使用 NextWriter 发送单条文本帧。
-// 与直接 WriteMessage 相比，可为后续更细粒度写优化保留扩展点。
-func (c *Client) writeFrame(msg []byte) error {
-	_ = c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
-	writer, err := c.conn.NextWriter(websocket.TextMessage)
+// writePacked 取出最多 1+wsBatchDrainLimit 条积压消息并合并为一帧写出，仅一条时原样下发。
+func (c *Client) writePacked() error {
+	frames := make([]queuedFrame, 0, wsBatchDrainLimit+1)
+	for len(frames) <= wsBatchDrainLimit {
+		frame, ok := c.send.pop()
+		if !ok {
+			break
+		}
+		frames = append(frames, frame)
+	}
+	switch len(frames) {
+	case 0:
+		return nil
+	case 1:
+		if err := c.writeFrame(frames[0].payload); err != nil {
+			return err
+		}
+		c.markSent(frames...)
+		return nil
+	}
+
+	payloads := make([][]byte, len(frames))
+	for i, frame := range frames {
+		payloads[i] = frame.payload
+	}
+	msg, err := c.codec.EncodeBatch(payloads)
 	if err != nil {
 		return err
 	}
-	if _, err = writer.Write(msg); err != nil {
-		_ = writer.Close()
+	writeBatchSize.Observe(float64(len(payloads)))
+	if err := c.writeFrame(msg); err != nil {
 		return err
 	}
-	return writer.Close()
+	c.markSent(frames...)
+	return nil
+}
+
+// markSent 帧写出后为其中待回执的消息开始超时计时。
+func (c *Client) markSent(frames ...queuedFrame) {
+	if c.acks == nil {
+		return
+	}
+	now := time.Now()
+	for _, frame := range frames {
+		if frame.ack != nil {
+			c.acks.sent(*frame.ack, now)
+		}
+	}
+}
+
+// writeFrame 发送单条数据帧，载荷达到压缩阈值时请求传输层压缩。
+func (c *Client) writeFrame(msg []byte) error {
+	return c.transport.WriteMessage(msg, c.compress.shouldCompress(len(msg)))
 }
 
-// writePing 发送协议层 Ping 保活包。
+// writePing 发送保活包。
 func (c *Client) writePing() error {
-	deadline := time.Now().Add(wsWriteTimeout)
-	_ = c.conn.SetWriteDeadline(deadline)
-	return c.conn.WriteControl(websocket.PingMessage, nil, deadline)
+	return c.transport.WritePing()
 }
@@ -0,0 +1,168 @@
+package manager
+
+import (
+	"ChatServer/apps/connect/internal/codec"
+	"ChatServer/apps/connect/pb"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// benchBurst 单次突发的下行帧数，对应 writeBatch 一次唤醒的最大处理量。
+const benchBurst = wsBatchDrainLimit + 1
+
+// countingConn 统计客户端从网络读取的字节数（即线上实际传输的下行字节）。
+type countingConn struct {
+	net.Conn
+	read *atomic.Int64
+}
+
+func (c countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+// benchFrames 构造一组群聊消息帧：同一会话、不同发送者与内容，贴近群聊突发下行。
+func benchFrames(n int) []*codec.Frame {
+	frames := make([]*codec.Frame, n)
+	for i := range frames {
+		data, _ := json.Marshal(map[string]any{
+			"conversation_id": "group_7f3c2a9e-1d4b-4c8e-9a61-3b2d5e8f0c14",
+			"msg_id":          fmt.Sprintf("msg_%08d", i),
+			"sender_uuid":     fmt.Sprintf("u_5b1e0f42-8c7d-4a3e-b9d6-%012x", i*7919),
+			"sender_nickname": fmt.Sprintf("周末爬山小分队-%02d", i),
+			"content_type":    "text",
+			"content":         fmt.Sprintf("第 %d 条：今晚 %d 点老地方集合，记得带水和头灯，路线 %x。", i, 18+i%4, i*104729),
+			"mentions":        []string{},
+		})
+		frames[i] = codec.NewFrame(&pb.MessageEnvelope{
+			Type:     "message",
+			Data:     data,
+			Seq:      int64(i + 1),
+			ServerTs: time.Now().UnixMilli(),
+			TraceId:  fmt.Sprintf("trace-%016x", i),
+		})
+	}
+	return frames
+}
+
+// newBenchClient 建立一条真实 WebSocket 连接，返回服务端 Client 与客户端读取字节计数。
+// 客户端在后台持续读取并丢弃下行帧，Close 后等待读取结束。
+func newBenchClient(b *testing.B, frameCodec codec.Codec, compression CompressionConfig, batch bool) (*Client, *atomic.Int64, func()) {
+	b.Helper()
+
+	upgrader := websocket.Upgrader{
+		Subprotocols:      codec.Subprotocols(),
+		EnableCompression: compression.Enabled,
+	}
+	conns := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			b.Errorf("upgrade: %v", err)
+			return
+		}
+		conns <- conn
+	}))
+
+	read := &atomic.Int64{}
+	dialer := websocket.Dialer{
+		Subprotocols:      []string{frameCodec.Subprotocol()},
+		EnableCompression: compression.Enabled,
+		NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return countingConn{Conn: conn, read: read}, nil
+		},
+	}
+	peer, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		server.Close()
+		b.Fatalf("dial: %v", err)
+	}
+
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		for {
+			if _, _, err := peer.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	client := NewClient(<-conns, "bench-user", "bench-device", ClientOptions{
+		Codec:        frameCodec,
+		SlowConsumer: SlowConsumerConfig{QueueSize: benchBurst},
+		Compression:  compression,
+		Batch:        batch,
+	})
+	closeFn := func() {
+		client.CloseWithCode(websocket.CloseNormalClosure, "")
+		<-readDone
+		_ = peer.Close()
+		server.Close()
+	}
+	return client, read, closeFn
+}
+
+// BenchmarkWriteBurst 对比不同压缩/批量组合下一次突发（17 条群消息）的线上字节数与 CPU 开销。
+// 结果中 wire_B/op 为客户端实际读到的字节数（含 WebSocket 帧头），ns/op 为服务端编码+压缩+写出耗时。
+// deflate 不设阈值（每帧都压缩），deflate-threshold 使用默认阈值，单条小帧不压缩、打包后的 batch 帧压缩。
+//
+//	go test ./apps/connect/internal/manager -run '^$' -bench WriteBurst -benchmem
+func BenchmarkWriteBurst(b *testing.B) {
+	frames := benchFrames(benchBurst)
+	deflate := CompressionConfig{Enabled: true, Level: defaultCompressionLevel}
+	cases := []struct {
+		name        string
+		compression CompressionConfig
+		batch       bool
+	}{
+		{name: "plain"},
+		{name: "deflate", compression: deflate},
+		{name: "deflate-threshold", compression: DefaultCompressionConfig()},
+		{name: "batch", batch: true},
+		{name: "deflate+batch", compression: deflate, batch: true},
+		{name: "deflate-threshold+batch", compression: DefaultCompressionConfig(), batch: true},
+	}
+
+	for _, frameCodec := range []codec.Codec{codec.JSON, codec.Proto} {
+		for _, tc := range cases {
+			b.Run(frameCodec.Subprotocol()+"/"+tc.name, func(b *testing.B) {
+				client, read, closeFn := newBenchClient(b, frameCodec, tc.compression, tc.batch)
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					for _, frame := range frames {
+						if !client.EnqueueFrame(frame) {
+							b.Fatal("enqueue failed")
+						}
+					}
+					for client.send.size() > 0 {
+						if err := client.writeBatch(); err != nil {
+							b.Fatalf("write batch: %v", err)
+						}
+					}
+				}
+				b.StopTimer()
+
+				closeFn()
+				b.ReportMetric(float64(read.Load())/float64(b.N), "wire_B/op")
+			})
+		}
+	}
+}
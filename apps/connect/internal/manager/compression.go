@@ -0,0 +1,42 @@
+package manager
+
+import "compress/flate"
+
+const (
+	// defaultCompressionLevel 默认压缩级别，与 gorilla 默认一致（BestSpeed），兼顾 CPU 与压缩率。
+	defaultCompressionLevel = flate.BestSpeed
+	// defaultCompressionThreshold 默认压缩阈值：小于该字节数的帧压缩收益低于帧头与 CPU 开销。
+	defaultCompressionThreshold = 512
+)
+
+// CompressionConfig 定义 permessage-deflate 下行压缩策略。
+// 压缩需客户端在握手时声明 permessage-deflate 扩展，未协商的连接始终不压缩。
+type CompressionConfig struct {
+	// Enabled 是否在握手时协商 permessage-deflate。
+	Enabled bool
+	// Level flate 压缩级别（-2~9，HuffmanOnly~BestCompression），非法值回退为 BestSpeed。
+	Level int
+	// Threshold 压缩阈值（字节），帧载荷小于该值时不压缩；<= 0 表示全部压缩。
+	Threshold int
+}
+
+// DefaultCompressionConfig 返回默认压缩配置。
+func DefaultCompressionConfig() CompressionConfig {
+	return CompressionConfig{
+		Enabled:   true,
+		Level:     defaultCompressionLevel,
+		Threshold: defaultCompressionThreshold,
+	}
+}
+
+func (c CompressionConfig) normalize() CompressionConfig {
+	if c.Level < flate.HuffmanOnly || c.Level > flate.BestCompression {
+		c.Level = defaultCompressionLevel
+	}
+	return c
+}
+
+// shouldCompress 判断指定大小的帧是否压缩。
+func (c CompressionConfig) shouldCompress(size int) bool {
+	return c.Enabled && size >= c.Threshold
+}
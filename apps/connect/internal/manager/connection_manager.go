@@ -1,6 +1,7 @@
 package manager
 
 import (
+	"ChatServer/apps/connect/internal/codec"
 	"hash/fnv"
 	"sync"
 	"sync/atomic"
@@ -18,12 +19,41 @@ type userBucket struct {
 	byUser map[string]map[string]*Client
 }
 
+// RouteHook 连接索引变化时的回调，用于同步集群路由注册表。
+// 回调在分桶锁之外执行，实现方需自行控制耗时（例如设置 Redis 超时）。
+type RouteHook interface {
+	// OnRegister 设备连接登记到本节点
+	OnRegister(userUUID, deviceID string)
+	// OnUnregister 设备连接从本节点移除（替换场景不会触发）
+	OnUnregister(userUUID, deviceID string)
+	// OnHeartbeat 设备连接心跳，用于续期路由
+	OnHeartbeat(userUUID, deviceID string)
+}
+
+// PresenceHook 用户在本节点的在线设备数在 0 与非 0 之间切换时的回调，用于好友在线状态推送。
+// 回调在分桶锁之外同步执行，实现方不应阻塞（例如只登记防抖定时器）。
+// Shutdown 批量断开时不触发 OnUserOffline，由其他节点上的重连自然收敛。
+type PresenceHook interface {
+	// OnUserOnline 用户第一个设备连接到本节点
+	OnUserOnline(userUUID string)
+	// OnUserOffline 用户最后一个设备从本节点断开
+	OnUserOffline(userUUID string)
+}
+
 // ConnectionManager 管理所有在线 WebSocket 连接。
-// 维护按用户分桶索引：
-// - byUser(user_uuid -> device_id -> client) 用于设备定位与按用户广播。
+// 维护两类分桶索引：
+// - byUser(user_uuid -> device_id -> client) 用于设备定位与按用户广播；
+// - byTopic(topic -> client 集合) 用于按主题广播（见 topic.go）。
+// 设置 RouteHook 后，注册/注销/心跳会同步到集群路由注册表；
+// 设置 PresenceHook 后，用户首个设备上线/最后一个设备下线时回调；
+// Drain 期间拒绝新握手并按速率逐步关闭已有连接（见 drain.go）。
 type ConnectionManager struct {
-	userBuckets []userBucket
-	shutdown    atomic.Bool
+	userBuckets  []userBucket
+	topicBuckets []topicBucket
+	shutdown     atomic.Bool
+	drain        atomic.Pointer[drainState]
+	routeHook    RouteHook
+	presenceHook PresenceHook
 }
 
 // NewConnectionManager 创建连接管理器实例。
@@ -40,18 +70,32 @@ func NewConnectionManagerWithBuckets(bucketCount int) *ConnectionManager {
 	}
 
 	m := &ConnectionManager{
-		userBuckets: make([]userBucket, bucketCount),
+		userBuckets:  make([]userBucket, bucketCount),
+		topicBuckets: make([]topicBucket, bucketCount),
 	}
 
 	for i := 0; i < bucketCount; i++ {
 		m.userBuckets[i] = userBucket{
 			byUser: make(map[string]map[string]*Client),
 		}
+		m.topicBuckets[i] = topicBucket{
+			byTopic: make(map[string]map[*Client]struct{}),
+		}
 	}
 
 	return m
 }
 
+// SetRouteHook 设置集群路由回调，需在开始接入连接前调用。
+func (m *ConnectionManager) SetRouteHook(hook RouteHook) {
+	m.routeHook = hook
+}
+
+// SetPresenceHook 设置在线状态回调，需在开始接入连接前调用。
+func (m *ConnectionManager) SetPresenceHook(hook PresenceHook) {
+	m.presenceHook = hook
+}
+
 // Register 注册一个设备连接。
 // 返回值 replaced 表示被新连接替换掉的旧连接（如果存在）。
 // 调用方通常应主动关闭 replaced，确保同设备最多一个活跃连接。
@@ -66,10 +110,10 @@ func (m *ConnectionManager) Register(client *Client) (replaced *Client) {
 	userBucket := m.userBucketFor(userUUID)
 
 	userBucket.mu.Lock()
-	defer userBucket.mu.Unlock()
 
 	// 加锁后再次判断，避免 Shutdown 与 Register 并发交错。
 	if m.shutdown.Load() {
+		userBucket.mu.Unlock()
 		return nil
 	}
 
@@ -78,11 +122,20 @@ func (m *ConnectionManager) Register(client *Client) (replaced *Client) {
 		userConns = make(map[string]*Client)
 		userBucket.byUser[userUUID] = userConns
 	}
+	first := len(userConns) == 0
 	if old, ok := userConns[deviceID]; ok && old != client {
 		replaced = old
 	}
 	userConns[deviceID] = client
+	userBucket.mu.Unlock()
 
+	m.joinAutoTopics(client, client.autoTopics)
+	if m.routeHook != nil {
+		m.routeHook.OnRegister(userUUID, deviceID)
+	}
+	if first && m.presenceHook != nil {
+		m.presenceHook.OnUserOnline(userUUID)
+	}
 	return replaced
 }
 
@@ -96,25 +149,51 @@ func (m *ConnectionManager) Unregister(client *Client) {
 	userUUID := client.UserUUID()
 	deviceID := client.DeviceID()
 
+	// 主题订阅按连接维度清理，被替换/被踢的连接也要退出。
+	m.leaveTopics(client)
+
 	userBucket := m.userBucketFor(userUUID)
 
 	userBucket.mu.Lock()
-	defer userBucket.mu.Unlock()
-
+	removed := false
+	last := false
 	if userConns, ok := userBucket.byUser[userUUID]; ok {
 		// 防御并发替换：仅当指针一致时才删除，避免误删新连接。
 		if existed, ok := userConns[deviceID]; ok && existed == client {
 			delete(userConns, deviceID)
+			removed = true
 		}
 		if len(userConns) == 0 {
 			delete(userBucket.byUser, userUUID)
+			last = removed
 		}
 	}
+	userBucket.mu.Unlock()
+
+	if removed && m.routeHook != nil {
+		m.routeHook.OnUnregister(userUUID, deviceID)
+	}
+	if last && m.presenceHook != nil {
+		m.presenceHook.OnUserOffline(userUUID)
+	}
+}
+
+// Heartbeat 记录连接的应用层心跳（用于心跳超时检查），并续期集群路由。
+func (m *ConnectionManager) Heartbeat(client *Client) {
+	if client == nil {
+		return
+	}
+	client.touchHeartbeat(time.Now())
+	if m.routeHook == nil {
+		return
+	}
+	m.routeHook.OnHeartbeat(client.UserUUID(), client.DeviceID())
 }
 
 // SendToDevice 向指定用户的指定设备发送消息。
+// 帧按目标连接协商的编码序列化。
 // 返回 false 表示目标连接不存在或写队列不可用。
-func (m *ConnectionManager) SendToDevice(userUUID, deviceID string, msg []byte) bool {
+func (m *ConnectionManager) SendToDevice(userUUID, deviceID string, frame *codec.Frame) bool {
 	userBucket := m.userBucketFor(userUUID)
 
 	userBucket.mu.RLock()
@@ -126,12 +205,13 @@ func (m *ConnectionManager) SendToDevice(userUUID, deviceID string, msg []byte)
 	if client == nil {
 		return false
 	}
-	return client.Enqueue(msg)
+	return client.EnqueueFrame(frame)
 }
 
 // SendToUser 向用户的所有在线设备广播消息。
+// 各设备按自身协商的编码序列化，同一 frame 每种编码只序列化一次。
 // 返回成功入队的设备数量，可用于统计下行投递率。
-func (m *ConnectionManager) SendToUser(userUUID string, msg []byte) int {
+func (m *ConnectionManager) SendToUser(userUUID string, frame *codec.Frame) int {
 	userBucket := m.userBucketFor(userUUID)
 
 	userBucket.mu.RLock()
@@ -148,7 +228,7 @@ func (m *ConnectionManager) SendToUser(userUUID string, msg []byte) int {
 
 	sent := 0
 	for _, client := range clients {
-		if client.Enqueue(msg) {
+		if client.EnqueueFrame(frame) {
 			sent++
 		}
 	}
@@ -169,6 +249,47 @@ func (m *ConnectionManager) Count() int {
 	return total
 }
 
+// BucketCounts 返回每个分桶当前的连接数，下标即分桶索引，用于观察分桶是否倾斜。
+func (m *ConnectionManager) BucketCounts() []int {
+	counts := make([]int, len(m.userBuckets))
+	for i := range m.userBuckets {
+		b := &m.userBuckets[i]
+		b.mu.RLock()
+		for _, userConns := range b.byUser {
+			counts[i] += len(userConns)
+		}
+		b.mu.RUnlock()
+	}
+	return counts
+}
+
+// TransportCounts 返回按传输方式（ws/sse）统计的连接数。
+func (m *ConnectionManager) TransportCounts() map[string]int {
+	counts := make(map[string]int, 2)
+	for _, client := range m.snapshot() {
+		counts[client.Transport()]++
+	}
+	return counts
+}
+
+// UserClients 返回指定用户在本节点各设备连接的运维快照。
+func (m *ConnectionManager) UserClients(userUUID string) []ClientInfo {
+	userBucket := m.userBucketFor(userUUID)
+
+	userBucket.mu.RLock()
+	clients := make([]*Client, 0, len(userBucket.byUser[userUUID]))
+	for _, client := range userBucket.byUser[userUUID] {
+		clients = append(clients, client)
+	}
+	userBucket.mu.RUnlock()
+
+	infos := make([]ClientInfo, 0, len(clients))
+	for _, client := range clients {
+		infos = append(infos, client.Info())
+	}
+	return infos
+}
+
 // GetOnlineDevices 返回指定用户当前在线的设备 ID 列表。
 // 如果用户完全离线，返回空切片。
 func (m *ConnectionManager) GetOnlineDevices(userUUID string) []string {
@@ -190,8 +311,16 @@ func (m *ConnectionManager) GetOnlineDevices(userUUID string) []string {
 }
 
 // KickDevice 强制断开指定用户的指定设备连接。
+// frame 为关闭前下发的最后一帧（kickout），为 nil 时直接关闭；关闭码为 CloseCodeKicked。
 // 返回 true 表示连接存在且已被关闭；false 表示目标不在线。
-func (m *ConnectionManager) KickDevice(userUUID, deviceID string) bool {
+func (m *ConnectionManager) KickDevice(userUUID, deviceID string, frame *codec.Frame, reason string) bool {
+	return m.KickDeviceBefore(userUUID, deviceID, frame, reason, time.Time{})
+}
+
+// KickDeviceBefore 与 KickDevice 相同，但只断开 issuedBefore 之前建立的连接：
+// 踢线指令重试或延迟到达时，同一设备重新登录建立的新连接不受影响。issuedBefore 为零值表示不限制。
+// 目标连接建立时间不早于 issuedBefore 时返回 false。
+func (m *ConnectionManager) KickDeviceBefore(userUUID, deviceID string, frame *codec.Frame, reason string, issuedBefore time.Time) bool {
 	userBucket := m.userBucketFor(userUUID)
 
 	userBucket.mu.Lock()
@@ -201,17 +330,24 @@ func (m *ConnectionManager) KickDevice(userUUID, deviceID string) bool {
 		return false
 	}
 	client, ok := userConns[deviceID]
-	if !ok {
+	if !ok || (!issuedBefore.IsZero() && !client.connectedAt.Before(issuedBefore)) {
 		userBucket.mu.Unlock()
 		return false
 	}
 	delete(userConns, deviceID)
-	if len(userConns) == 0 {
+	last := len(userConns) == 0
+	if last {
 		delete(userBucket.byUser, userUUID)
 	}
 	userBucket.mu.Unlock()
 
-	client.CloseGracefully()
+	if m.routeHook != nil {
+		m.routeHook.OnUnregister(userUUID, deviceID)
+	}
+	if last && m.presenceHook != nil {
+		m.presenceHook.OnUserOffline(userUUID)
+	}
+	client.Kick(frame, reason)
 	return true
 }
 
@@ -222,10 +358,15 @@ func (m *ConnectionManager) KickDevice(userUUID, deviceID string) bool {
 // 3. 向所有连接发送 CloseGoingAway 帧，通知客户端服务端正在维护；
 // 4. 等待 1 秒让客户端处理关闭帧；
 // 5. 强制关闭仍未断开的连接。
+// 说明：Shutdown 不逐个注销路由，由调用方停止路由注册表（删除节点存活 Key）后交给路由方清理。
 func (m *ConnectionManager) Shutdown() {
 	if !m.shutdown.CompareAndSwap(false, true) {
 		return
 	}
+	// 停止进行中的排空，剩余连接由下面统一关闭。
+	if state := m.drain.Load(); state != nil {
+		state.cancel()
+	}
 
 	clients := make([]*Client, 0)
 	for i := range m.userBuckets {
@@ -239,6 +380,12 @@ func (m *ConnectionManager) Shutdown() {
 		b.byUser = make(map[string]map[string]*Client)
 		b.mu.Unlock()
 	}
+	for i := range m.topicBuckets {
+		b := &m.topicBuckets[i]
+		b.mu.Lock()
+		b.byTopic = make(map[string]map[*Client]struct{})
+		b.mu.Unlock()
+	}
 
 	// 先发送 CloseGoingAway 帧，让客户端感知到优雅关闭。
 	for _, client := range clients {
@@ -0,0 +1,36 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKickDeviceBeforeSkipsNewerConnection(t *testing.T) {
+	m := NewConnectionManager()
+	old := NewClientWithTransport(newFakeTransport(), "alice", "a1", ClientOptions{})
+	m.Register(old)
+
+	// 踢线指令签发后同一设备重新登录：过期的踢线不能断开新连接。
+	issuedAt := old.connectedAt.Add(time.Millisecond)
+	fresh := NewClientWithTransport(newFakeTransport(), "alice", "a1", ClientOptions{})
+	fresh.connectedAt = issuedAt.Add(time.Second)
+	m.Register(fresh)
+
+	assert.False(t, m.KickDeviceBefore("alice", "a1", nil, "logout", issuedAt))
+	assert.Equal(t, []string{"a1"}, m.GetOnlineDevices("alice"))
+
+	// 签发时间晚于连接建立时间时正常断开。
+	assert.True(t, m.KickDeviceBefore("alice", "a1", nil, "logout", fresh.connectedAt.Add(time.Millisecond)))
+	assert.Empty(t, m.GetOnlineDevices("alice"))
+}
+
+func TestKickDeviceWithoutIssuedBefore(t *testing.T) {
+	m := NewConnectionManager()
+	m.Register(NewClientWithTransport(newFakeTransport(), "alice", "a1", ClientOptions{}))
+
+	assert.False(t, m.KickDevice("alice", "a2", nil, "logout"))
+	assert.True(t, m.KickDeviceBefore("alice", "a1", nil, "logout", time.Time{}))
+	assert.False(t, m.KickDevice("alice", "a1", nil, "logout"))
+}
@@ -0,0 +1,158 @@
+package manager
+
+import (
+	"ChatServer/apps/connect/internal/codec"
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultDrainRate 默认每秒关闭的连接数。
+	defaultDrainRate = 100
+	// MaxDrainRate 每秒关闭连接数上限：过高的速率等同于一次性断开，失去分批的意义。
+	MaxDrainRate = 10000
+)
+
+var (
+	// ErrDraining 节点已在排空中。
+	ErrDraining = errors.New("connection manager is draining")
+	// ErrShutdown 节点已停机。
+	ErrShutdown = errors.New("connection manager is shut down")
+)
+
+// DrainStatus 排空进度快照。
+type DrainStatus struct {
+	Draining  bool      `json:"draining"`
+	Endpoint  string    `json:"endpoint,omitempty"`
+	Rate      int       `json:"rate,omitempty"`
+	StartedAt time.Time `json:"started_at,omitempty"`
+	// Closed 已发出 reconnect 提示并关闭的连接数。
+	Closed int64 `json:"closed"`
+	// Remaining 本节点仍在线的连接数。
+	Remaining int `json:"remaining"`
+	// Done 已有连接全部处理完毕（节点仍拒绝新握手，直到停机或 StopDrain）。
+	Done bool `json:"done"`
+}
+
+// drainState 一次排空的运行状态。
+type drainState struct {
+	endpoint  string
+	rate      int
+	startedAt time.Time
+	closed    atomic.Int64
+	done      atomic.Bool
+	cancel    context.CancelFunc
+}
+
+// Draining 是否处于排空状态，排空期间握手入口应拒绝新连接。
+func (m *ConnectionManager) Draining() bool {
+	return m.drain.Load() != nil
+}
+
+// StartDrain 排空本节点：立即拒绝新握手，并在后台按 rate（每秒连接数，<=0 使用默认 100，超过 MaxDrainRate 按上限）逐个关闭已有连接。
+// 每条连接先收到 hint（如携带备用接入地址 endpoint 的 reconnect 帧），再以 CloseGoingAway 关闭，
+// 客户端据此立即重连其他节点，而不是当作异常断线退避重试。与 Shutdown 的区别是按速率分批，避免重连风暴。
+// 返回的通道在已有连接全部处理完毕（nil）或被 StopDrain/Shutdown 中止（context.Canceled）时收到结果。
+func (m *ConnectionManager) StartDrain(endpoint string, hint *codec.Frame, rate int) (<-chan error, error) {
+	if m.shutdown.Load() {
+		return nil, ErrShutdown
+	}
+	if rate <= 0 {
+		rate = defaultDrainRate
+	}
+	rate = min(rate, MaxDrainRate)
+	ctx, cancel := context.WithCancel(context.Background())
+	state := &drainState{
+		endpoint:  endpoint,
+		rate:      rate,
+		startedAt: time.Now(),
+		cancel:    cancel,
+	}
+	if !m.drain.CompareAndSwap(nil, state) {
+		cancel()
+		return nil, ErrDraining
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer cancel()
+		done <- m.drainLoop(ctx, state, hint)
+	}()
+	return done, nil
+}
+
+// drainLoop 按速率关闭连接。
+// 握手入口在标记排空后才开始拒绝，快照之间可能混入少量新连接，循环到没有未处理的连接为止。
+func (m *ConnectionManager) drainLoop(ctx context.Context, state *drainState, hint *codec.Frame) error {
+	ticker := time.NewTicker(time.Second / time.Duration(state.rate))
+	defer ticker.Stop()
+
+	handled := make(map[*Client]struct{})
+	for {
+		pending := make([]*Client, 0)
+		for _, client := range m.snapshot() {
+			if _, ok := handled[client]; !ok {
+				pending = append(pending, client)
+			}
+		}
+		if len(pending) == 0 {
+			state.done.Store(true)
+			return nil
+		}
+		for _, client := range pending {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+			handled[client] = struct{}{}
+			client.CloseGracefullyWithHint(hint)
+			state.closed.Add(1)
+		}
+	}
+}
+
+// StopDrain 停止排空并恢复接受握手，已关闭的连接不受影响。
+// 返回 false 表示当前不在排空状态。
+func (m *ConnectionManager) StopDrain() bool {
+	state := m.drain.Swap(nil)
+	if state == nil {
+		return false
+	}
+	state.cancel()
+	return true
+}
+
+// DrainStatus 返回排空进度。
+func (m *ConnectionManager) DrainStatus() DrainStatus {
+	status := DrainStatus{Remaining: m.Count()}
+	state := m.drain.Load()
+	if state == nil {
+		return status
+	}
+	status.Draining = true
+	status.Endpoint = state.endpoint
+	status.Rate = state.rate
+	status.StartedAt = state.startedAt
+	status.Closed = state.closed.Load()
+	status.Done = state.done.Load()
+	return status
+}
+
+// snapshot 返回当前全部在线连接。
+func (m *ConnectionManager) snapshot() []*Client {
+	clients := make([]*Client, 0)
+	for i := range m.userBuckets {
+		b := &m.userBuckets[i]
+		b.mu.RLock()
+		for _, userConns := range b.byUser {
+			for _, client := range userConns {
+				clients = append(clients, client)
+			}
+		}
+		b.mu.RUnlock()
+	}
+	return clients
+}
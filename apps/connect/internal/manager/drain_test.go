@@ -0,0 +1,95 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrainClosesConnectionsAndRejectsRestart(t *testing.T) {
+	m := NewConnectionManager()
+	transports := make([]*fakeTransport, 3)
+	for i, deviceID := range []string{"a1", "a2", "b1"} {
+		transports[i] = newFakeTransport()
+		userUUID := "alice"
+		if deviceID == "b1" {
+			userUUID = "bob"
+		}
+		m.Register(NewClientWithTransport(transports[i], userUUID, deviceID, ClientOptions{}))
+	}
+
+	done, err := m.StartDrain("wss://backup", nil, 1000)
+	require.NoError(t, err)
+	assert.True(t, m.Draining())
+	_, err = m.StartDrain("", nil, 0)
+	assert.ErrorIs(t, err, ErrDraining)
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("drain did not finish")
+	}
+	status := m.DrainStatus()
+	assert.True(t, status.Done)
+	assert.Equal(t, int64(3), status.Closed)
+	assert.Equal(t, "wss://backup", status.Endpoint)
+	for _, transport := range transports {
+		assert.Equal(t, []int{websocket.CloseGoingAway}, transport.closes)
+	}
+
+	// 排空完成后仍拒绝握手，直到 StopDrain。
+	assert.True(t, m.Draining())
+	assert.True(t, m.StopDrain())
+	assert.False(t, m.Draining())
+	assert.False(t, m.StopDrain())
+}
+
+func TestDrainRateBounds(t *testing.T) {
+	cases := []struct {
+		rate int
+		want int
+	}{
+		{0, defaultDrainRate},
+		{-5, defaultDrainRate},
+		{500, 500},
+		{MaxDrainRate + 1, MaxDrainRate},
+		// 超大速率不能让 time.Second/rate 变为 0 导致 NewTicker panic。
+		{int(time.Second) * 2, MaxDrainRate},
+	}
+	for _, tc := range cases {
+		m := NewConnectionManager()
+		done, err := m.StartDrain("", nil, tc.rate)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, m.DrainStatus().Rate, tc.rate)
+		require.NoError(t, <-done)
+		m.StopDrain()
+	}
+}
+
+func TestStopDrainCancelsInProgress(t *testing.T) {
+	m := NewConnectionManager()
+	for _, deviceID := range []string{"a1", "a2", "a3"} {
+		m.Register(NewClientWithTransport(newFakeTransport(), "alice", deviceID, ClientOptions{}))
+	}
+
+	// 每秒 1 条：第一条关闭前停止排空。
+	done, err := m.StartDrain("", nil, 1)
+	require.NoError(t, err)
+	assert.True(t, m.StopDrain())
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("drain not canceled")
+	}
+	assert.Equal(t, 3, m.Count())
+
+	m.Shutdown()
+	_, err = m.StartDrain("", nil, 0)
+	assert.ErrorIs(t, err, ErrShutdown)
+}
@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+const (
+	// defaultPingPeriod 默认协议层 Ping 周期，需小于运营商 NAT 的空闲回收时间（常见 30s~5min）。
+	defaultPingPeriod = 25 * time.Second
+	// defaultPongWait 默认读超时窗口，超过该时间未收到 Pong 判定为半开连接。
+	defaultPongWait = 40 * time.Second
+	// minHeartbeatCheckPeriod 应用层心跳检查的最小周期。
+	minHeartbeatCheckPeriod = time.Second
+)
+
+// KeepaliveConfig 定义单连接的保活策略，分两层独立检测：
+// - 协议层：服务端按 PingPeriod 发 Ping，PongWait 内未收到 Pong（或任何上行数据帧）时读超时断开，
+// 检测 TCP 半开；SSE 没有 Pong，Ping 仅用于保持代理连接活跃；
+// - 应用层：客户端按 HeartbeatInterval 上行 heartbeat，超过 HeartbeatTimeout 未收到时以
+// CloseCodeHeartbeatTimeout 断开，检测客户端进程挂起、App 被冻结等 Pong 仍由系统回复的情况。
+type KeepaliveConfig struct {
+	// PingPeriod 协议层 Ping 周期，<=0 时使用 25s。
+	PingPeriod time.Duration
+	// PongWait 读超时窗口，<=PingPeriod 时使用 PingPeriod+15s（默认 40s）。
+	PongWait time.Duration
+	// HeartbeatInterval 下发给客户端的推荐心跳间隔，仅用于指标与检查周期。
+	HeartbeatInterval time.Duration
+	// HeartbeatTimeout 应用层心跳超时，<=0 表示不检查。
+	HeartbeatTimeout time.Duration
+}
+
+// DefaultKeepaliveConfig 返回默认保活配置（不检查应用层心跳）。
+func DefaultKeepaliveConfig() KeepaliveConfig {
+	return KeepaliveConfig{
+		PingPeriod: defaultPingPeriod,
+		PongWait:   defaultPongWait,
+	}
+}
+
+func (c KeepaliveConfig) normalize() KeepaliveConfig {
+	if c.PingPeriod <= 0 {
+		c.PingPeriod = defaultPingPeriod
+	}
+	if c.PongWait <= c.PingPeriod {
+		c.PongWait = c.PingPeriod + defaultPongWait - defaultPingPeriod
+	}
+	return c
+}
+
+// heartbeatCheckPeriod 应用层心跳检查周期：推荐间隔与超时的 1/4 取小，超时后最多延迟一个周期断开。
+func (c KeepaliveConfig) heartbeatCheckPeriod() time.Duration {
+	period := c.HeartbeatTimeout / 4
+	if c.HeartbeatInterval > 0 && c.HeartbeatInterval < period {
+		period = c.HeartbeatInterval
+	}
+	return max(period, minHeartbeatCheckPeriod)
+}
+
+// Keepalive 返回连接生效的保活策略。
+func (c *Client) Keepalive() KeepaliveConfig {
+	return c.keepalive
+}
+
+// touchHeartbeat 记录最近一次应用层心跳时间。
+func (c *Client) touchHeartbeat(now time.Time) {
+	c.lastHeartbeat.Store(now.UnixNano())
+}
+
+// heartbeatExpired 判断应用层心跳是否已超时。
+func (c *Client) heartbeatExpired(now time.Time) bool {
+	if c.keepalive.HeartbeatTimeout <= 0 {
+		return false
+	}
+	last := time.Unix(0, c.lastHeartbeat.Load())
+	return now.Sub(last) > c.keepalive.HeartbeatTimeout
+}
+
+// observeKeepalive 记录连接生效的保活参数。
+func observeKeepalive(transport string, cfg KeepaliveConfig) {
+	keepaliveInterval.WithLabelValues(transport, "ping_period").Observe(cfg.PingPeriod.Seconds())
+	if transport == TransportWebSocket {
+		keepaliveInterval.WithLabelValues(transport, "pong_wait").Observe(cfg.PongWait.Seconds())
+	}
+	if cfg.HeartbeatInterval > 0 {
+		keepaliveInterval.WithLabelValues(transport, "heartbeat_interval").Observe(cfg.HeartbeatInterval.Seconds())
+	}
+	if cfg.HeartbeatTimeout > 0 {
+		keepaliveInterval.WithLabelValues(transport, "heartbeat_timeout").Observe(cfg.HeartbeatTimeout.Seconds())
+	}
+}
+
+// isTimeout 判断读错误是否为读超时（未在 PongWait 内收到任何上行）。
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,71 @@
+package manager
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// connect 下行投递相关指标，注册到默认 Registry，由 /metrics 统一暴露。
+var (
+	// ackPending 所有连接当前待回执消息总数。
+	ackPending = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "connect",
+		Name:      "ack_pending",
+		Help:      "Current number of ack_required frames waiting for client ack.",
+	})
+	// ackRetransmitTotal ack_required 消息重传次数。
+	ackRetransmitTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "connect",
+		Name:      "ack_retransmit_total",
+		Help:      "Total number of ack_required frames retransmitted.",
+	})
+	// ackLatency 下发到收到客户端回执的耗时。
+	ackLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "connect",
+		Name:      "ack_latency_seconds",
+		Help:      "Latency between first delivery and client ack.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	})
+	// ackDisconnectTotal 因回执异常断开的连接数（reason: timeout/window_full）。
+	ackDisconnectTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "connect",
+		Name:      "ack_disconnect_total",
+		Help:      "Total number of connections closed due to ack failures.",
+	}, []string{"reason"})
+	// sendQueueDecisionTotal 写队列满或合并时的慢消费策略决策次数。
+	sendQueueDecisionTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "connect",
+		Name:      "send_queue_decision_total",
+		Help:      "Total number of slow consumer policy decisions on the per-client send queue.",
+	}, []string{"policy", "decision"})
+	// writeBatchSize 批量帧打包的信封数。
+	writeBatchSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "connect",
+		Name:      "write_batch_size",
+		Help:      "Number of envelopes packed into a single batch frame.",
+		Buckets:   []float64{2, 4, 8, 12, 17},
+	})
+	// transportConnections 按传输方式（ws/sse）统计的当前连接数。
+	transportConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "connect",
+		Name:      "transport_connections",
+		Help:      "Current number of active connections by transport.",
+	}, []string{"transport"})
+	// keepaliveInterval 新建连接生效的保活参数（kind: ping_period/pong_wait/heartbeat_interval/heartbeat_timeout）。
+	keepaliveInterval = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "connect",
+		Name:      "keepalive_interval_seconds",
+		Help:      "Effective keepalive intervals of new connections.",
+		Buckets:   []float64{10, 15, 20, 25, 30, 40, 45, 60, 90, 120, 180, 300},
+	}, []string{"transport", "kind"})
+	// keepaliveTimeoutTotal 保活超时断开的连接数（kind: pong 读超时/heartbeat 应用层心跳超时）。
+	keepaliveTimeoutTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "connect",
+		Name:      "keepalive_timeout_total",
+		Help:      "Total number of connections closed due to keepalive timeouts.",
+	}, []string{"kind"})
+)
+
+func init() {
+	prometheus.MustRegister(ackPending, ackRetransmitTotal, ackLatency, ackDisconnectTotal, sendQueueDecisionTotal, writeBatchSize, transportConnections,
+		keepaliveInterval, keepaliveTimeoutTotal)
+}
@@ -0,0 +1,149 @@
+package manager
+
+import (
+	"ChatServer/apps/connect/internal/codec"
+	"ChatServer/apps/connect/pb"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// resumeHoldLimit 补发期间最多暂存的实时推送数，超出后按队列满处理。
+	resumeHoldLimit = 256
+	// resumeEnqueueWait 补发写入队列时的最长等待时间（补发量可能超过队列容量）。
+	resumeEnqueueWait = wsWriteTimeout
+	// resumeDedupGrace 补发结束后继续按 (conv_id, seq) 去重的时长：
+	// 补发读取缓冲前已写入缓冲的推送，可能晚于补发结束才经路由到达本连接。
+	resumeDedupGrace = 30 * time.Second
+)
+
+// msgKey 定位一条下行消息：seq 为会话内序号，需与 conv_id 组合才能唯一。
+type msgKey struct {
+	convID string
+	seq    int64
+}
+
+// msgKeyOf 返回信封的 (conv_id, seq)。
+func msgKeyOf(envelope *pb.MessageEnvelope) msgKey {
+	return msgKey{convID: envelope.GetConvId(), seq: envelope.GetSeq()}
+}
+
+// resumeState 断线重连补发状态。
+// 补发期间带 seq 的实时推送先暂存；补发结束后按 (conv_id, seq) 去重再下发，
+// 避免同一条消息既从补发缓冲读到、又被实时推送送达。
+// 补发结束后的 resumeDedupGrace 内保留已下发集合，迟到的实时推送同样去重。
+type resumeState struct {
+	active atomic.Bool
+	// dedupUntil 宽限期截止时间（UnixNano），0 表示不在宽限期。
+	dedupUntil atomic.Int64
+
+	mu        sync.Mutex
+	held      []*codec.Frame
+	delivered map[msgKey]struct{}
+}
+
+// BeginResume 进入补发状态，需在连接注册到 ConnectionManager 之前调用，
+// 确保注册后到达的实时推送都会被暂存。
+func (c *Client) BeginResume() {
+	c.resume.mu.Lock()
+	c.resume.held = nil
+	c.resume.delivered = make(map[msgKey]struct{})
+	c.resume.dedupUntil.Store(0)
+	c.resume.mu.Unlock()
+	c.resume.active.Store(true)
+}
+
+// EndResume 下发补发消息（同一会话内按 seq 升序），再去重下发补发期间暂存的实时推送，
+// 最后退出补发状态并进入去重宽限期。
+// 返回成功下发的补发消息数。
+func (c *Client) EndResume(replay []*codec.Frame) int {
+	if !c.resume.active.Load() {
+		return 0
+	}
+
+	replayed := 0
+	for _, frame := range replay {
+		if !c.markResumeDelivered(frame) {
+			continue
+		}
+		if c.deliverFrame(frame, resumeEnqueueWait) {
+			replayed++
+		}
+	}
+
+	for {
+		c.resume.mu.Lock()
+		held := c.resume.held
+		c.resume.held = nil
+		if len(held) == 0 {
+			c.resume.dedupUntil.Store(time.Now().Add(resumeDedupGrace).UnixNano())
+			c.resume.active.Store(false)
+			c.resume.mu.Unlock()
+			return replayed
+		}
+		c.resume.mu.Unlock()
+
+		for _, frame := range held {
+			if c.markResumeDelivered(frame) {
+				c.deliverFrame(frame, resumeEnqueueWait)
+			}
+		}
+	}
+}
+
+// holdForResume 补发期间暂存带 seq 的实时推送。
+// held=false 表示未处于补发状态（或帧不带 seq），调用方按正常流程下发。
+func (c *Client) holdForResume(frame *codec.Frame) (held bool, ok bool) {
+	if !c.resume.active.Load() || frame.Envelope().GetSeq() <= 0 {
+		return false, false
+	}
+
+	c.resume.mu.Lock()
+	defer c.resume.mu.Unlock()
+	if !c.resume.active.Load() {
+		return false, false
+	}
+	if len(c.resume.held) >= resumeHoldLimit {
+		return true, false
+	}
+	c.resume.held = append(c.resume.held, frame)
+	return true, true
+}
+
+// resumeDelivered 判断补发结束后到达的实时推送是否已随补发下发过（仅宽限期内），宽限期过后释放已下发集合。
+func (c *Client) resumeDelivered(frame *codec.Frame) bool {
+	if c.resume.dedupUntil.Load() == 0 || frame.Envelope().GetSeq() <= 0 {
+		return false
+	}
+
+	c.resume.mu.Lock()
+	defer c.resume.mu.Unlock()
+	until := c.resume.dedupUntil.Load()
+	if until == 0 {
+		return false
+	}
+	if time.Now().UnixNano() >= until {
+		c.resume.delivered = nil
+		c.resume.dedupUntil.Store(0)
+		return false
+	}
+	_, ok := c.resume.delivered[msgKeyOf(frame.Envelope())]
+	return ok
+}
+
+// markResumeDelivered 记录补发期间已下发的 (conv_id, seq)，返回 false 表示该消息已下发过。
+func (c *Client) markResumeDelivered(frame *codec.Frame) bool {
+	key := msgKeyOf(frame.Envelope())
+	if key.seq <= 0 {
+		return true
+	}
+
+	c.resume.mu.Lock()
+	defer c.resume.mu.Unlock()
+	if _, ok := c.resume.delivered[key]; ok {
+		return false
+	}
+	c.resume.delivered[key] = struct{}{}
+	return true
+}
@@ -0,0 +1,168 @@
+package manager
+
+import (
+	"ChatServer/apps/connect/internal/codec"
+	"ChatServer/apps/connect/pb"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTransport 记录写出的帧，ReadMessage 阻塞到 Close。
+type fakeTransport struct {
+	mu      sync.Mutex
+	written [][]byte
+	closes  []int
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func newFakeTransport() *fakeTransport {
+	return &fakeTransport{closed: make(chan struct{})}
+}
+
+func (t *fakeTransport) Name() string { return "fake" }
+
+func (t *fakeTransport) ReadMessage() ([]byte, error) {
+	<-t.closed
+	return nil, errors.New("closed")
+}
+
+func (t *fakeTransport) WriteMessage(msg []byte, _ bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.written = append(t.written, append([]byte(nil), msg...))
+	return nil
+}
+
+func (t *fakeTransport) WritePing() error { return nil }
+
+func (t *fakeTransport) WriteClose(code int, _ string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closes = append(t.closes, code)
+	return nil
+}
+
+func (t *fakeTransport) Close() error {
+	t.once.Do(func() { close(t.closed) })
+	return nil
+}
+
+// testEnvelope 解码 JSON 下行帧中测试关心的字段。
+type testEnvelope struct {
+	Type   string `json:"type"`
+	ConvID string `json:"conv_id"`
+	Seq    int64  `json:"seq"`
+}
+
+func (e testEnvelope) key() string {
+	return fmt.Sprintf("%s/%s/%d", e.Type, e.ConvID, e.Seq)
+}
+
+func seqFrame(convID string, seq int64) *codec.Frame {
+	return codec.NewFrame(&pb.MessageEnvelope{Type: "message", ConvId: convID, Seq: seq})
+}
+
+// drainQueue 取出写队列中的全部帧并按 JSON 解码。
+func drainQueue(t *testing.T, c *Client) []string {
+	t.Helper()
+	var keys []string
+	for {
+		frame, ok := c.send.pop()
+		if !ok {
+			return keys
+		}
+		var envelope testEnvelope
+		require.NoError(t, json.Unmarshal(frame.payload, &envelope))
+		keys = append(keys, envelope.key())
+	}
+}
+
+func TestResumeDedupsLivePushesByConversation(t *testing.T) {
+	c := NewClientWithTransport(newFakeTransport(), "alice", "a1", ClientOptions{})
+	c.BeginResume()
+
+	// 补发期间到达的实时推送：带 seq 的暂存，不带 seq 的直接入队。
+	assert.True(t, c.EnqueueFrame(seqFrame("convA", 5)))
+	assert.True(t, c.EnqueueFrame(seqFrame("convB", 5)))
+	assert.True(t, c.EnqueueFrame(seqFrame("convA", 6)))
+	assert.True(t, c.EnqueueFrame(codec.NewFrame(&pb.MessageEnvelope{Type: "typing"})))
+
+	replayed := c.EndResume([]*codec.Frame{seqFrame("convA", 4), seqFrame("convA", 5), seqFrame("convB", 5)})
+	assert.Equal(t, 3, replayed)
+
+	assert.Equal(t, []string{
+		"typing//0",
+		"message/convA/4", "message/convA/5", "message/convB/5",
+		"message/convA/6",
+	}, drainQueue(t, c))
+
+	// 补发结束后的实时推送不再暂存，直接下发。
+	assert.True(t, c.EnqueueFrame(seqFrame("convA", 7)))
+	assert.Equal(t, []string{"message/convA/7"}, drainQueue(t, c))
+}
+
+func TestResumeDedupsLatePushesAfterEnd(t *testing.T) {
+	c := NewClientWithTransport(newFakeTransport(), "alice", "a1", ClientOptions{})
+	c.BeginResume()
+
+	// convA/5 在读取补发缓冲前已写入缓冲，因此随补发下发；
+	// 它的实时推送在补发结束后才到达，不能再下发一次。
+	assert.Equal(t, 2, c.EndResume([]*codec.Frame{seqFrame("convA", 4), seqFrame("convA", 5)}))
+	assert.True(t, c.EnqueueFrame(seqFrame("convA", 5)))
+	assert.True(t, c.EnqueueFrame(seqFrame("convB", 5)))
+	assert.True(t, c.EnqueueFrame(seqFrame("convA", 6)))
+	assert.Equal(t, []string{
+		"message/convA/4", "message/convA/5",
+		"message/convB/5", "message/convA/6",
+	}, drainQueue(t, c))
+
+	// 宽限期过后释放已下发集合，不再去重。
+	c.resume.dedupUntil.Store(time.Now().Add(-time.Second).UnixNano())
+	assert.True(t, c.EnqueueFrame(seqFrame("convA", 5)))
+	assert.Equal(t, []string{"message/convA/5"}, drainQueue(t, c))
+	assert.Nil(t, c.resume.delivered)
+}
+
+func TestResumeLivePushesRacingWithEnd(t *testing.T) {
+	c := NewClientWithTransport(newFakeTransport(), "alice", "a1", ClientOptions{
+		SlowConsumer: SlowConsumerConfig{QueueSize: 1024},
+	})
+	c.BeginResume()
+
+	replay := make([]*codec.Frame, 0, 100)
+	for seq := int64(1); seq <= 100; seq++ {
+		replay = append(replay, seqFrame("convA", seq))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(conv string) {
+			defer wg.Done()
+			for seq := int64(1); seq <= 50; seq++ {
+				assert.True(t, c.EnqueueFrame(seqFrame(conv, seq)))
+			}
+		}(fmt.Sprintf("conv%d", i))
+	}
+	replayed := c.EndResume(replay)
+	wg.Wait()
+	assert.Equal(t, 100, replayed)
+
+	// 与补发结束并发的实时推送既不丢失也不重复。
+	counts := make(map[string]int)
+	for _, key := range drainQueue(t, c) {
+		counts[key]++
+	}
+	assert.Len(t, counts, 100+8*50)
+	for key, n := range counts {
+		assert.Equal(t, 1, n, key)
+	}
+}
@@ -0,0 +1,266 @@
+package manager
+
+import (
+	"fmt"
+	"sync"
+)
+
+// SlowConsumerPolicy 定义写队列已满时对新帧的处理策略。
+type SlowConsumerPolicy string
+
+const (
+	// PolicyDropOldest 丢弃队列中最旧的可丢弃帧（同样配置为 drop_oldest/coalesce 的类型）腾出空间；
+	// 队列中没有可丢弃帧时丢弃新帧。适用于 typing、presence 等非关键帧。
+	PolicyDropOldest SlowConsumerPolicy = "drop_oldest"
+	// PolicyCoalesce 队列已满时，若队列中已有同类型、同会话的帧则用新帧原位替换（最新状态覆盖旧状态），
+	// 否则按 drop_oldest 处理；队列未满时正常入队。
+	// 适用于未读数等按会话的快照型帧：生产方需保证帧内容是完整快照而非增量。
+	PolicyCoalesce SlowConsumerPolicy = "coalesce"
+	// PolicySpill 拒绝入队并返回未送达：推送方的 connectroute.Router/Direct 据此上报离线通知链路（UndeliveredSink），
+	// 带 seq 的帧在转发前已写入补发缓冲，重连后按游标补发。
+	PolicySpill SlowConsumerPolicy = "spill"
+	// PolicyDisconnect 断开连接，客户端重连后按 seq 拉取。
+	PolicyDisconnect SlowConsumerPolicy = "disconnect"
+)
+
+// ParseSlowConsumerPolicy 解析策略名，未知值返回 false。
+func ParseSlowConsumerPolicy(value string) (SlowConsumerPolicy, bool) {
+	switch policy := SlowConsumerPolicy(value); policy {
+	case PolicyDropOldest, PolicyCoalesce, PolicySpill, PolicyDisconnect:
+		return policy, true
+	default:
+		return "", false
+	}
+}
+
+// SlowConsumerConfig 定义单连接写队列容量与按消息类型的慢消费策略。
+type SlowConsumerConfig struct {
+	// QueueSize 写队列容量（帧数）。
+	QueueSize int
+	// Policies 消息类型 -> 策略，未配置的类型使用 DefaultPolicy。
+	Policies map[string]SlowConsumerPolicy
+	// DefaultPolicy 默认策略，为空时使用 spill（与旧行为一致：返回未送达）。
+	DefaultPolicy SlowConsumerPolicy
+}
+
+// DefaultSlowConsumerConfig 返回默认慢消费配置：
+// typing/presence 挤掉旧帧，unread_count 合并，其余类型返回未送达交给离线链路。
+func DefaultSlowConsumerConfig() SlowConsumerConfig {
+	return SlowConsumerConfig{
+		QueueSize: defaultSendQueueSize,
+		Policies: map[string]SlowConsumerPolicy{
+			"typing":       PolicyDropOldest,
+			"presence":     PolicyDropOldest,
+			"unread_count": PolicyCoalesce,
+		},
+		DefaultPolicy: PolicySpill,
+	}
+}
+
+// NewSlowConsumerConfig 从字符串配置构建慢消费配置，未知策略名返回错误。
+func NewSlowConsumerConfig(queueSize int, policies map[string]string, defaultPolicy string) (SlowConsumerConfig, error) {
+	cfg := SlowConsumerConfig{
+		QueueSize:     queueSize,
+		Policies:      make(map[string]SlowConsumerPolicy, len(policies)),
+		DefaultPolicy: PolicySpill,
+	}
+	if defaultPolicy != "" {
+		policy, ok := ParseSlowConsumerPolicy(defaultPolicy)
+		if !ok {
+			return SlowConsumerConfig{}, fmt.Errorf("unknown slow consumer policy %q", defaultPolicy)
+		}
+		cfg.DefaultPolicy = policy
+	}
+	for msgType, value := range policies {
+		policy, ok := ParseSlowConsumerPolicy(value)
+		if !ok {
+			return SlowConsumerConfig{}, fmt.Errorf("unknown slow consumer policy %q for type %q", value, msgType)
+		}
+		cfg.Policies[msgType] = policy
+	}
+	return cfg, nil
+}
+
+// policyFor 返回消息类型对应的策略。
+func (c SlowConsumerConfig) policyFor(msgType string) SlowConsumerPolicy {
+	if policy, ok := c.Policies[msgType]; ok {
+		return policy
+	}
+	if c.DefaultPolicy == "" {
+		return PolicySpill
+	}
+	return c.DefaultPolicy
+}
+
+// queuedFrame 写队列中的一帧。
+// ack 非空表示该帧已登记到待回执窗口，写出后由写协程开始回执计时。
+type queuedFrame struct {
+	payload []byte
+	msgType string
+	convID  string
+	policy  SlowConsumerPolicy
+	ack     *msgKey
+}
+
+// droppable 队列满时可被新帧挤掉的帧。
+// 待回执的帧不会被挤掉，否则其窗口条目永远不会开始计时。
+func (f queuedFrame) droppable() bool {
+	return f.ack == nil && (f.policy == PolicyDropOldest || f.policy == PolicyCoalesce)
+}
+
+// coalescable 可与同类型、同会话帧原位合并的帧，待回执的帧不参与合并。
+func (f queuedFrame) coalescable() bool {
+	return f.ack == nil && f.policy == PolicyCoalesce
+}
+
+// coalescesWith 判断 f 是否可被 frame 原位替换。
+func (f queuedFrame) coalescesWith(frame queuedFrame) bool {
+	return f.coalescable() && f.msgType == frame.msgType && f.convID == frame.convID
+}
+
+// queueDecision 入队结果。
+type queueDecision string
+
+const (
+	decisionEnqueued      queueDecision = "enqueued"
+	decisionDroppedOldest queueDecision = "dropped_oldest"
+	decisionDroppedNew    queueDecision = "dropped_new"
+	decisionCoalesced     queueDecision = "coalesced"
+	decisionSpilled       queueDecision = "spilled"
+	decisionDisconnected  queueDecision = "disconnected"
+)
+
+// accepted 判断入队结果是否意味着新帧已在队列中。
+func (d queueDecision) accepted() bool {
+	return d == decisionEnqueued || d == decisionDroppedOldest || d == decisionCoalesced
+}
+
+// sendQueue 有界写队列。
+// 与 channel 相比支持按策略挤掉旧帧、原位合并同类型帧；
+// notify 用于唤醒写协程，space 用于唤醒等待空位的补发流程。
+type sendQueue struct {
+	mu       sync.Mutex
+	items    []queuedFrame // 有效帧为 items[head:]
+	head     int
+	capacity int
+
+	notify chan struct{}
+	space  chan struct{}
+}
+
+func newSendQueue(capacity int) *sendQueue {
+	if capacity <= 0 {
+		capacity = defaultSendQueueSize
+	}
+	return &sendQueue{
+		items:    make([]queuedFrame, 0, capacity),
+		capacity: capacity,
+		notify:   make(chan struct{}, 1),
+		space:    make(chan struct{}, 1),
+	}
+}
+
+// push 入队；队列已满时按帧自身策略决定结果。
+// 返回 decisionEnqueued/decisionDroppedOldest/decisionCoalesced 表示新帧已入队。
+func (q *sendQueue) push(frame queuedFrame) queueDecision {
+	q.mu.Lock()
+	decision := q.pushLocked(frame)
+	q.mu.Unlock()
+
+	if decision.accepted() {
+		signal(q.notify)
+	}
+	return decision
+}
+
+func (q *sendQueue) pushLocked(frame queuedFrame) queueDecision {
+	if q.appendLocked(frame) {
+		return decisionEnqueued
+	}
+
+	switch frame.policy {
+	case PolicyDropOldest, PolicyCoalesce:
+		if frame.coalescable() {
+			for i := q.head; i < len(q.items); i++ {
+				if q.items[i].coalescesWith(frame) {
+					q.items[i] = frame
+					return decisionCoalesced
+				}
+			}
+		}
+		for i := q.head; i < len(q.items); i++ {
+			if q.items[i].droppable() {
+				copy(q.items[i:], q.items[i+1:])
+				q.items[len(q.items)-1] = frame
+				return decisionDroppedOldest
+			}
+		}
+		return decisionDroppedNew
+	case PolicyDisconnect:
+		return decisionDisconnected
+	default:
+		return decisionSpilled
+	}
+}
+
+// tryPush 仅在有空位时入队（不触发策略），用于可等待的补发流程。
+func (q *sendQueue) tryPush(frame queuedFrame) bool {
+	q.mu.Lock()
+	ok := q.appendLocked(frame)
+	q.mu.Unlock()
+
+	if ok {
+		signal(q.notify)
+	}
+	return ok
+}
+
+// appendLocked 有空位时追加到队尾；底层数组尾部用尽时先把有效帧搬回头部，复用同一块内存。
+func (q *sendQueue) appendLocked(frame queuedFrame) bool {
+	if len(q.items)-q.head >= q.capacity {
+		return false
+	}
+	if len(q.items) == cap(q.items) && q.head > 0 {
+		n := copy(q.items, q.items[q.head:])
+		clear(q.items[n:])
+		q.items = q.items[:n]
+		q.head = 0
+	}
+	q.items = append(q.items, frame)
+	return true
+}
+
+// pop 取出队首帧。
+func (q *sendQueue) pop() (queuedFrame, bool) {
+	q.mu.Lock()
+	if q.head == len(q.items) {
+		q.mu.Unlock()
+		return queuedFrame{}, false
+	}
+	frame := q.items[q.head]
+	q.items[q.head] = queuedFrame{}
+	q.head++
+	if q.head == len(q.items) {
+		q.items = q.items[:0]
+		q.head = 0
+	}
+	q.mu.Unlock()
+
+	signal(q.space)
+	return frame, true
+}
+
+// size 返回当前排队帧数。
+func (q *sendQueue) size() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items) - q.head
+}
+
+// signal 非阻塞唤醒（通道容量为 1，重复信号合并）。
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,145 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testQueuedFrame(payload, msgType string, policy SlowConsumerPolicy) queuedFrame {
+	return queuedFrame{payload: []byte(payload), msgType: msgType, policy: policy}
+}
+
+// popAll 依次取出队列中的全部帧。
+func popAll(q *sendQueue) []string {
+	var payloads []string
+	for {
+		frame, ok := q.pop()
+		if !ok {
+			return payloads
+		}
+		payloads = append(payloads, string(frame.payload))
+	}
+}
+
+func testConvFrame(payload, msgType, convID string, policy SlowConsumerPolicy) queuedFrame {
+	frame := testQueuedFrame(payload, msgType, policy)
+	frame.convID = convID
+	return frame
+}
+
+func TestSendQueueCoalesce(t *testing.T) {
+	q := newSendQueue(4)
+
+	// 队列未满时快照帧正常入队，不合并。
+	assert.Equal(t, decisionEnqueued, q.push(testConvFrame("a1", "unread_count", "a", PolicyCoalesce)))
+	assert.Equal(t, decisionEnqueued, q.push(testQueuedFrame("m1", "message", PolicySpill)))
+	assert.Equal(t, decisionEnqueued, q.push(testConvFrame("a2", "unread_count", "a", PolicyCoalesce)))
+	assert.Equal(t, decisionEnqueued, q.push(testConvFrame("r1", "read_receipt", "a", PolicyCoalesce)))
+
+	// 队列满：同类型、同会话的帧原位替换（替换第一条），保持原有位置。
+	assert.Equal(t, decisionCoalesced, q.push(testConvFrame("a3", "unread_count", "a", PolicyCoalesce)))
+	assert.Equal(t, 4, q.size())
+	assert.Equal(t, []string{"a3", "m1", "a2", "r1"}, popAll(q))
+}
+
+func TestSendQueueCoalescePerConversation(t *testing.T) {
+	q := newSendQueue(3)
+
+	require.Equal(t, decisionEnqueued, q.push(testConvFrame("a1", "unread_count", "a", PolicyCoalesce)))
+	require.Equal(t, decisionEnqueued, q.push(testQueuedFrame("m1", "message", PolicySpill)))
+	require.Equal(t, decisionEnqueued, q.push(testConvFrame("b1", "unread_count", "b", PolicyCoalesce)))
+
+	// 会话 b 的新快照只覆盖 b，会话 a 的快照保留。
+	assert.Equal(t, decisionCoalesced, q.push(testConvFrame("b2", "unread_count", "b", PolicyCoalesce)))
+	assert.Equal(t, []string{"a1", "m1", "b2"}, popAll(q))
+
+	// 队列中没有同会话的帧时按 drop_oldest 挤掉最旧的可丢弃帧。
+	require.Equal(t, decisionEnqueued, q.push(testConvFrame("a1", "unread_count", "a", PolicyCoalesce)))
+	require.Equal(t, decisionEnqueued, q.push(testQueuedFrame("m1", "message", PolicySpill)))
+	require.Equal(t, decisionEnqueued, q.push(testConvFrame("b1", "unread_count", "b", PolicyCoalesce)))
+	assert.Equal(t, decisionDroppedOldest, q.push(testConvFrame("c1", "unread_count", "c", PolicyCoalesce)))
+	assert.Equal(t, []string{"m1", "b1", "c1"}, popAll(q))
+}
+
+func TestSendQueueDropOldest(t *testing.T) {
+	q := newSendQueue(3)
+
+	require.Equal(t, decisionEnqueued, q.push(testQueuedFrame("m1", "message", PolicySpill)))
+	require.Equal(t, decisionEnqueued, q.push(testQueuedFrame("t1", "typing", PolicyDropOldest)))
+	require.Equal(t, decisionEnqueued, q.push(testQueuedFrame("t2", "typing", PolicyDropOldest)))
+
+	// 队列满：挤掉最旧的可丢弃帧，关键帧保持原位，新帧追加到队尾。
+	assert.Equal(t, decisionDroppedOldest, q.push(testQueuedFrame("p1", "presence", PolicyDropOldest)))
+	assert.Equal(t, []string{"m1", "t2", "p1"}, popAll(q))
+
+	// 队列中没有可丢弃帧时丢弃新帧。
+	for _, payload := range []string{"m1", "m2", "m3"} {
+		require.Equal(t, decisionEnqueued, q.push(testQueuedFrame(payload, "message", PolicySpill)))
+	}
+	assert.Equal(t, decisionDroppedNew, q.push(testQueuedFrame("t3", "typing", PolicyDropOldest)))
+	assert.Equal(t, decisionDroppedNew, q.push(testQueuedFrame("u1", "unread_count", PolicyCoalesce)))
+	assert.Equal(t, []string{"m1", "m2", "m3"}, popAll(q))
+}
+
+func TestSendQueueFullPolicies(t *testing.T) {
+	q := newSendQueue(1)
+	require.Equal(t, decisionEnqueued, q.push(testQueuedFrame("t1", "typing", PolicyDropOldest)))
+
+	// spill/disconnect 不挤占可丢弃帧，由调用方处理。
+	assert.Equal(t, decisionSpilled, q.push(testQueuedFrame("m1", "message", PolicySpill)))
+	assert.Equal(t, decisionDisconnected, q.push(testQueuedFrame("m2", "message", PolicyDisconnect)))
+	assert.False(t, q.tryPush(testQueuedFrame("m3", "message", PolicySpill)))
+
+	assert.Equal(t, []string{"t1"}, popAll(q))
+	assert.True(t, q.tryPush(testQueuedFrame("m3", "message", PolicySpill)))
+	assert.Equal(t, []string{"m3"}, popAll(q))
+}
+
+func TestSendQueueCompaction(t *testing.T) {
+	q := newSendQueue(4)
+	backing := cap(q.items)
+
+	// 交替入队出队使 head 前移，尾部用尽后应把有效帧搬回头部而不是扩容。
+	next := 0
+	var popped []string
+	for round := 0; round < 10; round++ {
+		for q.size() < 3 {
+			require.Equal(t, decisionEnqueued, q.push(testQueuedFrame(string(rune('a'+next%26)), "message", PolicySpill)))
+			next++
+		}
+		frame, ok := q.pop()
+		require.True(t, ok)
+		popped = append(popped, string(frame.payload))
+		assert.LessOrEqual(t, len(q.items)-q.head, q.capacity)
+		assert.Equal(t, backing, cap(q.items))
+	}
+	popped = append(popped, popAll(q)...)
+
+	// 整体保持 FIFO。
+	require.Len(t, popped, next)
+	for i, payload := range popped {
+		assert.Equal(t, string(rune('a'+i%26)), payload)
+	}
+	assert.Equal(t, 0, q.head)
+	assert.Empty(t, q.items)
+}
+
+func TestSlowConsumerConfigPolicies(t *testing.T) {
+	cfg, err := NewSlowConsumerConfig(8, map[string]string{"typing": "drop_oldest", "unread_count": "coalesce"}, "")
+	require.NoError(t, err)
+	assert.Equal(t, PolicyDropOldest, cfg.policyFor("typing"))
+	assert.Equal(t, PolicyCoalesce, cfg.policyFor("unread_count"))
+	assert.Equal(t, PolicySpill, cfg.policyFor("message"))
+
+	cfg, err = NewSlowConsumerConfig(8, nil, "disconnect")
+	require.NoError(t, err)
+	assert.Equal(t, PolicyDisconnect, cfg.policyFor("message"))
+	assert.Equal(t, PolicySpill, SlowConsumerConfig{}.policyFor("message"))
+
+	_, err = NewSlowConsumerConfig(8, nil, "block")
+	assert.Error(t, err)
+	_, err = NewSlowConsumerConfig(8, map[string]string{"typing": "block"}, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,144 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// errSSEClosed SSE 流已关闭（客户端断开或服务端关闭）。
+var errSSEClosed = errors.New("sse stream closed")
+
+// sseTransport Server-Sent Events 传输，用于拦截 WebSocket 升级的受限网络。
+// 下行为 text/event-stream：首个事件为 open（data 为 {"stream"}，上行凭证），
+// 之后每条帧一个 message 事件（data 为 JSON 信封，仅支持 JSON 编码）；
+// 保活为注释行；关闭通知为 close 事件（data 为 {"code","reason"}）。
+// 上行走独立的 HTTP 请求，由 handler 定位到该连接后按 WebSocket 上行同样处理，因此 ReadMessage 只等待关闭。
+type sseTransport struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	done    <-chan struct{}
+	closed  chan struct{}
+	closeMu sync.Once
+}
+
+// sseOpenData 定义 event: open 的 data 结构。
+type sseOpenData struct {
+	Stream string `json:"stream"`
+}
+
+// sseCloseData 定义 event: close 的 data 结构。
+type sseCloseData struct {
+	Code   int    `json:"code"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// NewSSETransport 在 HTTP 响应上建立 SSE 流，写出响应头与 open 事件。
+// done 为请求 context 的 Done（客户端断开时关闭）；stream 为该流的上行凭证，客户端上行时携带以定位本流。
+// 说明：清除连接级读写超时，否则 HTTP Server 的 ReadTimeout/WriteTimeout 会掐断长连接。
+func NewSSETransport(w http.ResponseWriter, done <-chan struct{}, stream string) (Transport, error) {
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	if err := rc.SetWriteDeadline(time.Now().Add(wsWriteTimeout)); err != nil {
+		return nil, err
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// 关闭 Nginx 等反向代理的响应缓冲，否则事件会被攒批下发。
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	t := &sseTransport{
+		w:      w,
+		rc:     rc,
+		done:   done,
+		closed: make(chan struct{}),
+	}
+	open, err := json.Marshal(sseOpenData{Stream: stream})
+	if err != nil {
+		return nil, err
+	}
+	if err := t.write("event: open\ndata: ", open, "\n\n"); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *sseTransport) Name() string {
+	return TransportSSE
+}
+
+// ReadMessage 阻塞到客户端断开或服务端关闭。
+func (t *sseTransport) ReadMessage() ([]byte, error) {
+	select {
+	case <-t.done:
+	case <-t.closed:
+	}
+	return nil, errSSEClosed
+}
+
+// WriteMessage 写出一个 message 事件；SSE 无压缩协商，compress 被忽略。
+// JSON 编码的信封不含换行，可直接作为单行 data。
+func (t *sseTransport) WriteMessage(msg []byte, _ bool) error {
+	return t.write("data: ", msg, "\n\n")
+}
+
+// WritePing 写出注释行保活，同时让代理感知连接仍在活动。
+func (t *sseTransport) WritePing() error {
+	return t.write(": ping", nil, "\n\n")
+}
+
+// WriteClose 写出 close 事件，客户端据 code 区分断开原因（与 WebSocket 关闭码一致）。
+func (t *sseTransport) WriteClose(code int, reason string) error {
+	data, err := json.Marshal(sseCloseData{Code: code, Reason: reason})
+	if err != nil {
+		return err
+	}
+	return t.write("event: close\ndata: ", data, "\n\n")
+}
+
+// Close 结束 SSE 流，handler 返回后 HTTP 响应随之结束。
+// 持写锁关闭，保证 handler 返回（ResponseWriter 失效）后不会再有写入。
+func (t *sseTransport) Close() error {
+	t.closeMu.Do(func() {
+		t.mu.Lock()
+		close(t.closed)
+		t.mu.Unlock()
+	})
+	return nil
+}
+
+func (t *sseTransport) write(prefix string, body []byte, suffix string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	select {
+	case <-t.closed:
+		return errSSEClosed
+	case <-t.done:
+		return errSSEClosed
+	default:
+	}
+
+	_ = t.rc.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if _, err := t.w.Write([]byte(prefix)); err != nil {
+		return err
+	}
+	if len(body) > 0 {
+		if _, err := t.w.Write(body); err != nil {
+			return err
+		}
+	}
+	if _, err := t.w.Write([]byte(suffix)); err != nil {
+		return err
+	}
+	return t.rc.Flush()
+}
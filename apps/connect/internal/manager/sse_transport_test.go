@@ -0,0 +1,74 @@
+package manager
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deadlineRecorder 为 ResponseRecorder 补上读写超时设置，满足 http.ResponseController。
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (deadlineRecorder) SetReadDeadline(time.Time) error  { return nil }
+func (deadlineRecorder) SetWriteDeadline(time.Time) error { return nil }
+
+func TestSSETransportEvents(t *testing.T) {
+	rec := deadlineRecorder{httptest.NewRecorder()}
+	transport, err := NewSSETransport(rec, make(chan struct{}), "s1")
+	require.NoError(t, err)
+	assert.Equal(t, TransportSSE, transport.Name())
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+
+	require.NoError(t, transport.WriteMessage([]byte(`{"type":"message"}`), true))
+	require.NoError(t, transport.WritePing())
+	require.NoError(t, transport.WriteClose(CloseCodeKicked, "kicked"))
+	assert.Equal(t, "event: open\ndata: {\"stream\":\"s1\"}\n\n"+
+		"data: {\"type\":\"message\"}\n\n"+
+		": ping\n\n"+
+		"event: close\ndata: {\"code\":4005,\"reason\":\"kicked\"}\n\n", rec.Body.String())
+}
+
+func TestSSETransportWriteAfterClose(t *testing.T) {
+	rec := deadlineRecorder{httptest.NewRecorder()}
+	transport, err := NewSSETransport(rec, make(chan struct{}), "s1")
+	require.NoError(t, err)
+	opened := rec.Body.String()
+
+	read := make(chan error, 1)
+	go func() {
+		_, err := transport.ReadMessage()
+		read <- err
+	}()
+
+	require.NoError(t, transport.Close())
+	require.NoError(t, transport.Close())
+	select {
+	case err := <-read:
+		assert.ErrorIs(t, err, errSSEClosed)
+	case <-time.After(time.Second):
+		t.Fatal("ReadMessage not released by Close")
+	}
+
+	// 关闭后 handler 可能已返回，任何写入都不能再触达 ResponseWriter。
+	assert.ErrorIs(t, transport.WriteMessage([]byte(`{}`), false), errSSEClosed)
+	assert.ErrorIs(t, transport.WritePing(), errSSEClosed)
+	assert.ErrorIs(t, transport.WriteClose(CloseCodeKicked, ""), errSSEClosed)
+	assert.Equal(t, opened, rec.Body.String())
+}
+
+func TestSSETransportClientGone(t *testing.T) {
+	rec := deadlineRecorder{httptest.NewRecorder()}
+	done := make(chan struct{})
+	transport, err := NewSSETransport(rec, done, "s1")
+	require.NoError(t, err)
+
+	close(done)
+	_, err = transport.ReadMessage()
+	assert.ErrorIs(t, err, errSSEClosed)
+	assert.ErrorIs(t, transport.WriteMessage([]byte(`{}`), false), errSSEClosed)
+}
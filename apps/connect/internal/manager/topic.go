@@ -0,0 +1,185 @@
+package manager
+
+import (
+	"ChatServer/apps/connect/internal/codec"
+	"errors"
+	"slices"
+	"sync"
+)
+
+// defaultMaxTopics 单连接默认可显式订阅的主题数上限（不含握手自动加入的主题）。
+const defaultMaxTopics = 32
+
+// ErrTooManyTopics 显式订阅的主题数超过单连接上限。
+var ErrTooManyTopics = errors.New("too many topics")
+
+// topicBucket 主题索引分桶：topic -> 订阅连接集合。
+type topicBucket struct {
+	mu      sync.RWMutex
+	byTopic map[string]map[*Client]struct{}
+}
+
+// clientTopics 连接的主题订阅状态，auto 为握手时自动加入的主题（不可退订、不计入上限）。
+// closed 后不再接受订阅，保证连接注销后不会残留在主题索引中。
+type clientTopics struct {
+	mu       sync.Mutex
+	topics   map[string]bool
+	explicit int
+	max      int
+	closed   bool
+}
+
+// Topics 返回连接当前订阅的全部主题（含自动加入的主题），按名称排序。
+func (c *Client) Topics() []string {
+	c.topics.mu.Lock()
+	topics := make([]string, 0, len(c.topics.topics))
+	for topic := range c.topics.topics {
+		topics = append(topics, topic)
+	}
+	c.topics.mu.Unlock()
+
+	slices.Sort(topics)
+	return topics
+}
+
+// Subscribe 为连接显式订阅主题，返回本次新增的主题（已订阅的忽略）。
+// 订阅后总数超过单连接上限时整体拒绝并返回 ErrTooManyTopics；连接已注销时不做任何处理。
+func (m *ConnectionManager) Subscribe(client *Client, topics []string) ([]string, error) {
+	state := &client.topics
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if state.closed {
+		return nil, nil
+	}
+	added := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		if _, ok := state.topics[topic]; !ok && !slices.Contains(added, topic) {
+			added = append(added, topic)
+		}
+	}
+	if state.explicit+len(added) > state.max {
+		return nil, ErrTooManyTopics
+	}
+	for _, topic := range added {
+		state.topics[topic] = false
+		m.indexTopic(topic, client)
+	}
+	state.explicit += len(added)
+	return added, nil
+}
+
+// Unsubscribe 退订连接显式订阅的主题，返回实际退订的主题（自动加入的主题不可退订）。
+func (m *ConnectionManager) Unsubscribe(client *Client, topics []string) []string {
+	state := &client.topics
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	removed := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		if auto, ok := state.topics[topic]; !ok || auto {
+			continue
+		}
+		delete(state.topics, topic)
+		m.unindexTopic(topic, client)
+		removed = append(removed, topic)
+	}
+	state.explicit -= len(removed)
+	return removed
+}
+
+// PublishToTopic 向本节点订阅了主题的连接投递消息，返回成功入队的连接数。
+// 同一 frame 每种编码只序列化一次。
+func (m *ConnectionManager) PublishToTopic(topic string, frame *codec.Frame) int {
+	bucket := m.topicBucketFor(topic)
+
+	bucket.mu.RLock()
+	subscribers := bucket.byTopic[topic]
+	clients := make([]*Client, 0, len(subscribers))
+	for client := range subscribers {
+		clients = append(clients, client)
+	}
+	bucket.mu.RUnlock()
+
+	return enqueueAll(clients, frame)
+}
+
+// Broadcast 向本节点全部在线连接投递消息（系统广播），返回成功入队的连接数。
+// 全部连接本身就是一个隐式主题，直接遍历用户索引，不额外维护主题索引。
+func (m *ConnectionManager) Broadcast(frame *codec.Frame) int {
+	return enqueueAll(m.snapshot(), frame)
+}
+
+// joinAutoTopics 连接注册时加入握手计算出的自动主题。
+func (m *ConnectionManager) joinAutoTopics(client *Client, topics []string) {
+	state := &client.topics
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if state.closed {
+		return
+	}
+	for _, topic := range topics {
+		if _, ok := state.topics[topic]; ok {
+			continue
+		}
+		state.topics[topic] = true
+		m.indexTopic(topic, client)
+	}
+}
+
+// leaveTopics 连接注销时退出全部主题，之后不再接受订阅。
+func (m *ConnectionManager) leaveTopics(client *Client) {
+	state := &client.topics
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.closed = true
+	for topic := range state.topics {
+		m.unindexTopic(topic, client)
+	}
+	state.topics = make(map[string]bool)
+	state.explicit = 0
+}
+
+// indexTopic 把连接加入主题索引，调用方需持有连接的订阅锁。
+func (m *ConnectionManager) indexTopic(topic string, client *Client) {
+	bucket := m.topicBucketFor(topic)
+	bucket.mu.Lock()
+	subscribers, ok := bucket.byTopic[topic]
+	if !ok {
+		subscribers = make(map[*Client]struct{})
+		bucket.byTopic[topic] = subscribers
+	}
+	subscribers[client] = struct{}{}
+	bucket.mu.Unlock()
+}
+
+// unindexTopic 把连接移出主题索引，主题无订阅者时回收，调用方需持有连接的订阅锁。
+func (m *ConnectionManager) unindexTopic(topic string, client *Client) {
+	bucket := m.topicBucketFor(topic)
+	bucket.mu.Lock()
+	if subscribers, ok := bucket.byTopic[topic]; ok {
+		delete(subscribers, client)
+		if len(subscribers) == 0 {
+			delete(bucket.byTopic, topic)
+		}
+	}
+	bucket.mu.Unlock()
+}
+
+// topicBucketFor 根据主题名定位所属分桶。
+func (m *ConnectionManager) topicBucketFor(topic string) *topicBucket {
+	return &m.topicBuckets[m.bucketIndex(topic)]
+}
+
+// enqueueAll 向一组连接投递同一帧，返回成功入队数。
+func enqueueAll(clients []*Client, frame *codec.Frame) int {
+	sent := 0
+	for _, client := range clients {
+		if client.EnqueueFrame(frame) {
+			sent++
+		}
+	}
+	return sent
+}
@@ -0,0 +1,83 @@
+package manager
+
+import (
+	"ChatServer/apps/connect/internal/codec"
+	"ChatServer/apps/connect/pb"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribeLimits(t *testing.T) {
+	m := NewConnectionManager()
+	client := NewClientWithTransport(newFakeTransport(), "alice", "a1", ClientOptions{
+		MaxTopics: 3,
+		Topics:    []string{"user:alice", "platform:ios"},
+	})
+	m.Register(client)
+
+	tests := []struct {
+		name    string
+		topics  []string
+		added   []string
+		wantErr error
+	}{
+		// 自动主题不计入上限，重复主题只算一次。
+		{name: "dedupe", topics: []string{"a", "b", "a"}, added: []string{"a", "b"}},
+		{name: "already_subscribed", topics: []string{"a", "user:alice"}, added: []string{}},
+		// 超出上限时整体拒绝，不部分订阅。
+		{name: "over_limit", topics: []string{"c", "d"}, wantErr: ErrTooManyTopics},
+		{name: "fill_limit", topics: []string{"c", "b"}, added: []string{"c"}},
+		{name: "full", topics: []string{"d"}, wantErr: ErrTooManyTopics},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, err := m.Subscribe(client, tt.topics)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, added)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.added, added)
+		})
+	}
+	assert.Equal(t, []string{"a", "b", "c", "platform:ios", "user:alice"}, client.Topics())
+
+	// 自动主题不可退订；退订显式主题后释放名额。
+	assert.Equal(t, []string{"a"}, m.Unsubscribe(client, []string{"a", "user:alice", "missing"}))
+	added, err := m.Subscribe(client, []string{"d"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d"}, added)
+
+	frame := codec.NewFrame(&pb.MessageEnvelope{Type: "notice"})
+	assert.Equal(t, 1, m.PublishToTopic("d", frame))
+	assert.Equal(t, 1, m.PublishToTopic("user:alice", frame))
+	assert.Zero(t, m.PublishToTopic("a", frame))
+
+	// 注销后退出全部主题，且不再接受订阅。
+	m.Unregister(client)
+	assert.Zero(t, m.PublishToTopic("d", frame))
+	assert.Zero(t, m.PublishToTopic("user:alice", frame))
+	added, err = m.Subscribe(client, []string{"e"})
+	assert.NoError(t, err)
+	assert.Empty(t, added)
+	assert.Zero(t, m.PublishToTopic("e", frame))
+}
+
+func TestSubscribeDefaultLimit(t *testing.T) {
+	m := NewConnectionManager()
+	client := NewClientWithTransport(newFakeTransport(), "alice", "a1", ClientOptions{})
+	m.Register(client)
+
+	topics := make([]string, defaultMaxTopics+1)
+	for i := range topics {
+		topics[i] = string(rune('a'+i%26)) + string(rune('a'+i/26))
+	}
+	_, err := m.Subscribe(client, topics)
+	assert.ErrorIs(t, err, ErrTooManyTopics)
+	added, err := m.Subscribe(client, topics[:defaultMaxTopics])
+	require.NoError(t, err)
+	assert.Len(t, added, defaultMaxTopics)
+}
@@ -0,0 +1,93 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 传输方式名称，用于指标标签与运维查询。
+const (
+	TransportWebSocket = "ws"
+	TransportSSE       = "sse"
+)
+
+// Transport 单条连接的底层传输。
+// Client 只依赖该接口完成读写，WebSocket 与 SSE（受限网络下的降级通道）共用同一套
+// 写队列、慢消费、回执、补发与踢线逻辑，推送方无需关心设备使用哪种传输。
+type Transport interface {
+	// Name 传输方式名称（ws/sse）。
+	Name() string
+	// ReadMessage 阻塞读取下一条上行帧；没有独立上行通道的传输阻塞到连接关闭后返回错误。
+	ReadMessage() ([]byte, error)
+	// WriteMessage 写出一条已编码的下行帧，compress 表示该帧达到压缩阈值。
+	WriteMessage(msg []byte, compress bool) error
+	// WritePing 写出保活包。
+	WritePing() error
+	// WriteClose 尽力写出关闭通知（关闭码 + 原因），不关闭连接。
+	WriteClose(code int, reason string) error
+	// Close 关闭底层连接，需幂等并打断阻塞中的 ReadMessage。
+	Close() error
+}
+
+// wsTransport WebSocket 传输。
+type wsTransport struct {
+	conn        *websocket.Conn
+	messageType int
+	pongWait    time.Duration
+}
+
+// newWSTransport 包装 WebSocket 连接，设置上行大小限制与读超时；收到 Pong 或任何上行帧都会续期读超时。
+func newWSTransport(conn *websocket.Conn, messageType int, readLimit int64, pongWait time.Duration) *wsTransport {
+	conn.SetReadLimit(readLimit)
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	return &wsTransport{conn: conn, messageType: messageType, pongWait: pongWait}
+}
+
+func (t *wsTransport) Name() string {
+	return TransportWebSocket
+}
+
+func (t *wsTransport) ReadMessage() ([]byte, error) {
+	_, raw, err := t.conn.ReadMessage()
+	if err == nil {
+		_ = t.conn.SetReadDeadline(time.Now().Add(t.pongWait))
+	}
+	return raw, err
+}
+
+// WriteMessage 使用 NextWriter 发送单条数据帧，帧类型由协商的 codec 决定。
+// compress 为 true 时按帧开启 permessage-deflate（未协商压缩的连接不生效）。
+func (t *wsTransport) WriteMessage(msg []byte, compress bool) error {
+	_ = t.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	t.conn.EnableWriteCompression(compress)
+	writer, err := t.conn.NextWriter(t.messageType)
+	if err != nil {
+		return err
+	}
+	if _, err = writer.Write(msg); err != nil {
+		_ = writer.Close()
+		return err
+	}
+	return writer.Close()
+}
+
+// WritePing 发送协议层 Ping 保活包。
+func (t *wsTransport) WritePing() error {
+	deadline := time.Now().Add(wsWriteTimeout)
+	_ = t.conn.SetWriteDeadline(deadline)
+	return t.conn.WriteControl(websocket.PingMessage, nil, deadline)
+}
+
+// WriteClose 发送 Close 帧，便于客户端按关闭码区分断开原因。
+func (t *wsTransport) WriteClose(code int, reason string) error {
+	deadline := time.Now().Add(wsWriteTimeout)
+	return t.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+}
+
+func (t *wsTransport) Close() error {
+	return t.conn.Close()
+}
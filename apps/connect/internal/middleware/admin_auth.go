@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"ChatServer/consts"
+	"ChatServer/pkg/ctxmeta"
+	"ChatServer/pkg/logger"
+	"ChatServer/pkg/result"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuthMiddleware 校验运维接口的静态令牌（Authorization: Bearer <token>）。
+// 令牌按常量时间比较，避免通过响应耗时逐字节猜测；token 为空时拒绝全部请求。
+func AdminAuthMiddleware(token string) gin.HandlerFunc {
+	expected := []byte(token)
+	return func(c *gin.Context) {
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if len(expected) > 0 && subtle.ConstantTimeCompare([]byte(provided), expected) == 1 {
+			c.Next()
+			return
+		}
+
+		logger.Warn(ctxmeta.BuildContextFromGin(c), "connect 运维接口鉴权失败",
+			logger.String("ip", ctxmeta.ClientIPFromGin(c)),
+			logger.String("path", c.Request.URL.Path),
+			logger.String("method", c.Request.Method),
+		)
+
+		c.Set("business_code", consts.CodeUnauthorized)
+		c.JSON(http.StatusUnauthorized, result.Response{
+			Code:      consts.CodeUnauthorized,
+			Message:   consts.GetMessage(consts.CodeUnauthorized),
+			Data:      nil,
+			TraceId:   ctxmeta.TraceIDFromGin(c),
+			Timestamp: time.Now().Unix(),
+		})
+		c.Abort()
+	}
+}
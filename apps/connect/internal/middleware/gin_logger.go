@@ -40,7 +40,7 @@ func GinLogger() gin.HandlerFunc {
 			logger.Info(ctx, "WebSocket 握手成功",
 				logger.String("method", method),
 				logger.String("path", path),
-				logger.String("query", query),
+				logger.Query("query", query),
 				logger.String("ip", ip),
 				logger.Int("status", status),
 				logger.Duration("cost", cost),
@@ -49,7 +49,7 @@ func GinLogger() gin.HandlerFunc {
 			logger.Error(ctx, "Connect HTTP 请求失败",
 				logger.String("method", method),
 				logger.String("path", path),
-				logger.String("query", query),
+				logger.Query("query", query),
 				logger.String("ip", ip),
 				logger.Int("status", status),
 				logger.Duration("cost", cost),
@@ -59,7 +59,7 @@ func GinLogger() gin.HandlerFunc {
 			logger.Warn(ctx, "Connect HTTP 慢请求",
 				logger.String("method", method),
 				logger.String("path", path),
-				logger.String("query", query),
+				logger.Query("query", query),
 				logger.String("ip", ip),
 				logger.Int("status", status),
 				logger.Duration("cost", cost),
@@ -5,6 +5,7 @@ import (
 	"ChatServer/pkg/ctxmeta"
 	"ChatServer/pkg/logger"
 	"ChatServer/pkg/result"
+	"context"
 	"net/http"
 	"os"
 	"strconv"
@@ -24,6 +25,11 @@ const (
 	wsHandshakeEvictBatch = 256
 )
 
+// BanChecker 查询 IP 是否处于临时封禁期（如上行滥用封禁），实现方需自行处理存储异常（建议放行）。
+type BanChecker interface {
+	IPBanned(ctx context.Context, ip string) bool
+}
+
 // WSHandshakeRateLimitConfig 定义 /ws 握手限流配置。
 type WSHandshakeRateLimitConfig struct {
 	// Rate 表示每秒允许的握手请求数。
@@ -36,6 +42,8 @@ type WSHandshakeRateLimitConfig struct {
 	CleanupInterval time.Duration
 	// MaxEntries 表示可保留的 IP 桶上限，防止内存无限增长。
 	MaxEntries int
+	// Ban 可选的 IP 封禁查询，被封禁的 IP 直接拒绝握手（不消耗令牌）。
+	Ban BanChecker
 }
 
 // DefaultWSHandshakeRateLimitConfig 返回默认握手限流参数。
@@ -157,7 +165,8 @@ func (l *handshakeLimiter) evictCount() int {
 }
 
 // WSHandshakeRateLimitMiddleware 仅用于 /ws 握手请求限流。
-// 注意：它只限制“建连频率”，不干预 WebSocket 长连接内的消息收发。
+// 注意：它只限制“建连频率”，不干预 WebSocket 长连接内的消息收发（上行限速见 svc.UplinkLimitConfig）；
+// 配置 Ban 时同时拦截被封禁的 IP。
 func WSHandshakeRateLimitMiddleware(cfg WSHandshakeRateLimitConfig) gin.HandlerFunc {
 	if cfg.Rate <= 0 || cfg.Burst <= 0 {
 		return func(c *gin.Context) { c.Next() }
@@ -183,6 +192,23 @@ func WSHandshakeRateLimitMiddleware(cfg WSHandshakeRateLimitConfig) gin.HandlerF
 			return
 		}
 
+		if cfg.Ban != nil && cfg.Ban.IPBanned(c.Request.Context(), ip) {
+			logger.Warn(ctxmeta.BuildContextFromGin(c), "握手请求来自封禁 IP",
+				logger.String("ip", ip),
+				logger.String("path", c.Request.URL.Path),
+			)
+			c.Set("business_code", consts.CodeConnectBanned)
+			c.JSON(http.StatusTooManyRequests, result.Response{
+				Code:      consts.CodeConnectBanned,
+				Message:   consts.GetMessage(consts.CodeConnectBanned),
+				Data:      nil,
+				TraceId:   ctxmeta.TraceIDFromGin(c),
+				Timestamp: time.Now().Unix(),
+			})
+			c.Abort()
+			return
+		}
+
 		now := time.Now()
 		if limiter.allow(ip, now) {
 			c.Next()
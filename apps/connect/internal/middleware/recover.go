@@ -52,7 +52,7 @@ func RecoverMiddleware(stack bool) gin.HandlerFunc {
 						logger.Any("error", recovered),
 						logger.String("method", c.Request.Method),
 						logger.String("path", c.Request.URL.Path),
-						logger.String("query", c.Request.URL.RawQuery),
+						logger.Query("query", c.Request.URL.RawQuery),
 						logger.String("ip", c.ClientIP()),
 						logger.String("user-agent", c.Request.UserAgent()),
 						logger.String("request", string(httpRequest)),
@@ -63,7 +63,7 @@ func RecoverMiddleware(stack bool) gin.HandlerFunc {
 						logger.Any("error", recovered),
 						logger.String("method", c.Request.Method),
 						logger.String("path", c.Request.URL.Path),
-						logger.String("query", c.Request.URL.RawQuery),
+						logger.Query("query", c.Request.URL.RawQuery),
 						logger.String("ip", c.ClientIP()),
 						logger.String("user-agent", c.Request.UserAgent()),
 						logger.String("request", string(httpRequest)),
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"ChatServer/apps/connect/internal/handler"
+	"ChatServer/apps/connect/internal/middleware"
+	"ChatServer/pkg/util"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewAdmin 构建运维 HTTP 服务，与 /ws 接入端口隔离，所有路由需携带运维令牌。
+// 路由职责：
+// - GET    /admin/connections:                           连接总数与各分桶连接数。
+// - GET    /admin/users/:user_uuid/connections:          用户在本节点的设备连接详情。
+// - DELETE /admin/users/:user_uuid/connections:          断开用户在本节点的全部连接。
+// - DELETE /admin/users/:user_uuid/connections/:device_id: 断开指定设备连接。
+// - GET    /admin/drain:                                 排空进度。
+// - POST   /admin/drain:                                 开始排空（body 可选 {"endpoint","rate"}）。
+// - DELETE /admin/drain:                                 停止排空并恢复接入。
+func NewAdmin(addr, token string, adminHandler *handler.AdminHandler) *Server {
+	r := gin.New()
+	r.Use(util.TraceLogger())
+	r.Use(middleware.ClientIPMiddleware())
+	r.Use(middleware.GinLogger())
+	r.Use(middleware.RecoverMiddleware(true))
+
+	admin := r.Group("/admin", middleware.AdminAuthMiddleware(token))
+	admin.GET("/connections", adminHandler.Connections)
+	admin.GET("/users/:user_uuid/connections", adminHandler.UserConnections)
+	admin.DELETE("/users/:user_uuid/connections", adminHandler.CloseConnections)
+	admin.DELETE("/users/:user_uuid/connections/:device_id", adminHandler.CloseConnections)
+	admin.GET("/drain", adminHandler.DrainStatus)
+	admin.POST("/drain", adminHandler.StartDrain)
+	admin.DELETE("/drain", adminHandler.StopDrain)
+
+	return &Server{
+		httpServer: &http.Server{
+			Addr:              addr,
+			Handler:           r,
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       10 * time.Second,
+			WriteTimeout:      10 * time.Second,
+			IdleTimeout:       60 * time.Second,
+		},
+	}
+}
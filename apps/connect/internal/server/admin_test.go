@@ -0,0 +1,165 @@
+package server
+
+import (
+	"ChatServer/apps/connect/internal/handler"
+	"ChatServer/apps/connect/internal/manager"
+	"ChatServer/apps/connect/internal/svc"
+	"ChatServer/consts"
+	"ChatServer/pkg/logger"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testAdminToken = "admin-secret"
+
+var connectAdminTestOnce sync.Once
+
+func initConnectAdminTest() {
+	connectAdminTestOnce.Do(func() {
+		logger.ReplaceGlobal(zap.NewNop())
+		gin.SetMode(gin.TestMode)
+	})
+}
+
+// nopTransport 不做任何 IO 的传输层，ReadMessage 阻塞到 Close。
+type nopTransport struct {
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (t *nopTransport) Name() string { return "ws" }
+
+func (t *nopTransport) ReadMessage() ([]byte, error) {
+	<-t.closed
+	return nil, errors.New("closed")
+}
+
+func (t *nopTransport) WriteMessage([]byte, bool) error { return nil }
+func (t *nopTransport) WritePing() error                { return nil }
+func (t *nopTransport) WriteClose(int, string) error    { return nil }
+
+func (t *nopTransport) Close() error {
+	t.once.Do(func() { close(t.closed) })
+	return nil
+}
+
+type adminTestResponse struct {
+	Code int             `json:"code"`
+	Data json.RawMessage `json:"data"`
+}
+
+func newAdminTestServer(t *testing.T, token string) (http.Handler, *manager.ConnectionManager) {
+	t.Helper()
+	initConnectAdminTest()
+	connManager := manager.NewConnectionManager()
+	t.Cleanup(func() { connManager.StopDrain() })
+	connectSvc := svc.NewConnectService(nil, nil, nil, svc.UplinkLimitConfig{})
+	admin := NewAdmin("", token, handler.NewAdminHandler(connManager, connectSvc, handler.DrainConfig{Rate: 1000}))
+	return admin.httpServer.Handler, connManager
+}
+
+func adminRequest(t *testing.T, h http.Handler, method, path, token, body string) (int, adminTestResponse) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var resp adminTestResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec.Code, resp
+}
+
+func registerAdminTestClient(m *manager.ConnectionManager, userUUID, deviceID string) {
+	m.Register(manager.NewClientWithTransport(&nopTransport{closed: make(chan struct{})}, userUUID, deviceID, manager.ClientOptions{}))
+}
+
+func TestAdminAuth(t *testing.T) {
+	h, _ := newAdminTestServer(t, testAdminToken)
+
+	for _, token := range []string{"", "wrong", testAdminToken + "x"} {
+		status, resp := adminRequest(t, h, http.MethodGet, "/admin/connections", token, "")
+		assert.Equal(t, http.StatusUnauthorized, status, token)
+		assert.Equal(t, consts.CodeUnauthorized, resp.Code, token)
+	}
+	status, resp := adminRequest(t, h, http.MethodGet, "/admin/connections", testAdminToken, "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, consts.CodeSuccess, resp.Code)
+
+	// 未配置令牌时拒绝全部请求。
+	open, _ := newAdminTestServer(t, "")
+	status, _ = adminRequest(t, open, http.MethodGet, "/admin/connections", "", "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestAdminConnectionsAndClose(t *testing.T) {
+	h, connManager := newAdminTestServer(t, testAdminToken)
+	registerAdminTestClient(connManager, "alice", "a1")
+	registerAdminTestClient(connManager, "alice", "a2")
+	registerAdminTestClient(connManager, "bob", "b1")
+
+	_, resp := adminRequest(t, h, http.MethodGet, "/admin/connections", testAdminToken, "")
+	var connections struct {
+		Total      int            `json:"total"`
+		Transports map[string]int `json:"transports"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Data, &connections))
+	assert.Equal(t, 3, connections.Total)
+	assert.Equal(t, map[string]int{"ws": 3}, connections.Transports)
+
+	_, resp = adminRequest(t, h, http.MethodDelete, "/admin/users/alice/connections/a2", testAdminToken, "")
+	var closed struct {
+		Closed []string `json:"closed"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Data, &closed))
+	assert.Equal(t, []string{"a2"}, closed.Closed)
+
+	_, resp = adminRequest(t, h, http.MethodDelete, "/admin/users/alice/connections", testAdminToken, "")
+	require.NoError(t, json.Unmarshal(resp.Data, &closed))
+	assert.Equal(t, []string{"a1"}, closed.Closed)
+	assert.Empty(t, connManager.GetOnlineDevices("alice"))
+	assert.Equal(t, []string{"b1"}, connManager.GetOnlineDevices("bob"))
+}
+
+func TestAdminDrain(t *testing.T) {
+	h, connManager := newAdminTestServer(t, testAdminToken)
+
+	for _, body := range []string{`{"rate":-1}`, `{"rate":10001}`, `{"rate":"fast"}`} {
+		_, resp := adminRequest(t, h, http.MethodPost, "/admin/drain", testAdminToken, body)
+		assert.Equal(t, consts.CodeParamError, resp.Code, body)
+	}
+	assert.False(t, connManager.Draining())
+
+	_, resp := adminRequest(t, h, http.MethodPost, "/admin/drain", testAdminToken, `{"endpoint":"wss://backup"}`)
+	require.Equal(t, consts.CodeSuccess, resp.Code)
+	var status manager.DrainStatus
+	require.NoError(t, json.Unmarshal(resp.Data, &status))
+	assert.True(t, status.Draining)
+	assert.Equal(t, "wss://backup", status.Endpoint)
+	assert.Equal(t, 1000, status.Rate)
+
+	// 重复调用幂等，返回进行中的排空。
+	_, resp = adminRequest(t, h, http.MethodPost, "/admin/drain", testAdminToken, `{"rate":10000}`)
+	require.NoError(t, json.Unmarshal(resp.Data, &status))
+	assert.Equal(t, 1000, status.Rate)
+
+	_, resp = adminRequest(t, h, http.MethodDelete, "/admin/drain", testAdminToken, "")
+	require.NoError(t, json.Unmarshal(resp.Data, &status))
+	assert.False(t, status.Draining)
+	assert.False(t, connManager.Draining())
+}
@@ -4,14 +4,15 @@ import (
 	"ChatServer/apps/connect/internal/handler"
 	"ChatServer/apps/connect/internal/manager"
 	"ChatServer/apps/connect/internal/middleware"
+	"ChatServer/pkg/grpcx"
 	"ChatServer/pkg/util"
 	"context"
-	"fmt"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // Config 定义 connect HTTP 服务的运行参数。
@@ -22,6 +23,8 @@ type Config struct {
 	ReadTimeout       time.Duration
 	WriteTimeout      time.Duration
 	IdleTimeout       time.Duration
+	// HandshakeBan 握手入口的 IP 封禁查询，可为 nil。
+	HandshakeBan middleware.BanChecker
 }
 
 // DefaultConfig 返回 connect 服务的默认配置。
@@ -49,8 +52,10 @@ type Server struct {
 // New 构建 Gin 路由并包装成 HTTP Server。
 // 路由职责：
 // - GET /health:   健康检查，返回在线连接数，供容器/探针调用。
-// - GET /metrics:  暴露 Prometheus 文本格式指标（online_connections gauge）。
+// - GET /metrics:  暴露 Prometheus 指标（在线连接数、回执重传、gRPC 调用等）。
 // - GET /ws:       WebSocket 接入入口。
+// - GET /sse:      SSE 降级接入入口（WebSocket 升级被代理拦截时使用）。
+// - POST /sse/send: SSE 连接的上行入口。
 func New(cfg Config, wsHandler *handler.WSHandler, connManager *manager.ConnectionManager) *Server {
 	ginMode := os.Getenv("GIN_MODE")
 	if ginMode == "" {
@@ -66,6 +71,7 @@ func New(cfg Config, wsHandler *handler.WSHandler, connManager *manager.Connecti
 	r.Use(middleware.CORSMiddleware(middleware.DefaultCORSConfig()))
 
 	wsRateLimitCfg := middleware.DefaultWSHandshakeRateLimitConfig()
+	wsRateLimitCfg.Ban = cfg.HandshakeBan
 
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -74,15 +80,20 @@ func New(cfg Config, wsHandler *handler.WSHandler, connManager *manager.Connecti
 		})
 	})
 
-	r.GET("/metrics", func(c *gin.Context) {
-		c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
-		c.String(http.StatusOK,
-			fmt.Sprintf("# HELP connect_online_connections Current number of active WebSocket connections.\n"+
-				"# TYPE connect_online_connections gauge\n"+
-				"connect_online_connections %d\n", connManager.Count()))
-	})
+	// online_connections 以 GaugeFunc 注册到默认 Registry，与回执、gRPC 等指标统一由 /metrics 暴露。
+	_ = prometheus.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "connect",
+		Name:      "online_connections",
+		Help:      "Current number of active WebSocket connections.",
+	}, func() float64 {
+		return float64(connManager.Count())
+	}))
+	r.GET("/metrics", gin.WrapH(grpcx.DefaultHandler()))
 
-	r.GET("/ws", middleware.WSHandshakeRateLimitMiddleware(wsRateLimitCfg), wsHandler.ServeWS)
+	handshakeLimit := middleware.WSHandshakeRateLimitMiddleware(wsRateLimitCfg)
+	r.GET("/ws", handshakeLimit, wsHandler.ServeWS)
+	r.GET("/sse", handshakeLimit, wsHandler.ServeSSE)
+	r.POST("/sse/send", wsHandler.ServeSSESend)
 
 	return &Server{
 		httpServer: &http.Server{
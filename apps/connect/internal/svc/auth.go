@@ -8,8 +8,10 @@ import (
 	"encoding/hex"
 	"errors"
 	"strings"
+	"time"
 
 	rediskey "ChatServer/consts/redisKey"
+	"ChatServer/pkg/connectroute"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -23,34 +25,149 @@ var (
 	ErrTokenInvalid = errors.New("token is invalid")
 )
 
-// Authenticate 校验 WebSocket 握手参数与登录态。
+const (
+	// defaultAuthExpiringLead 默认提前下发 auth_expiring 的时间。
+	defaultAuthExpiringLead = 5 * time.Minute
+	// defaultAuthRevokeCheckInterval 默认吊销检查周期。
+	defaultAuthRevokeCheckInterval = time.Minute
+)
+
+// AuthWatchConfig 定义连接存活期间的登录态检查策略。
+type AuthWatchConfig struct {
+	// ExpiringLead 距 access token 过期多久时下发 auth_expiring，提示客户端 reauth。
+	ExpiringLead time.Duration
+	// RevokeCheckInterval 检查 auth:at 是否已被删除（踢设备/登出）的周期，<=0 表示不检查。
+	RevokeCheckInterval time.Duration
+}
+
+// DefaultAuthWatchConfig 返回默认登录态检查配置。
+func DefaultAuthWatchConfig() AuthWatchConfig {
+	return AuthWatchConfig{
+		ExpiringLead:        defaultAuthExpiringLead,
+		RevokeCheckInterval: defaultAuthRevokeCheckInterval,
+	}
+}
+
+// sessionAuth 连接当前生效的 access token 状态，reauth 时整体替换。
+type sessionAuth struct {
+	tokenHash string
+	expiresAt time.Time
+}
+
+// ExpiresAt 返回当前 access token 的过期时间，零值表示 token 未携带过期时间。
+func (s *Session) ExpiresAt() time.Time {
+	if auth := s.auth.Load(); auth != nil {
+		return auth.expiresAt
+	}
+	return time.Time{}
+}
+
+// Authenticate 校验 WebSocket 握手参数与登录态，并记录 token 过期时间供连接存活期间检查。
+// token 与 ticket 二选一（同时携带时以 token 为准），ticket 为 gateway 签发的一次性接入票据。
 // 校验流程：
-// 1. 校验 token/device_id 是否为空；
-// 2. 解析 JWT，校验 claims 基本字段；
-// 3. 强校验 claims.DeviceID 与 query.device_id 一致；
-// 4. 若 Redis 可用，校验 auth:at:{user_uuid}:{device_id} 中存储的 token md5。
+// 1. 校验 token/ticket、device_id 是否为空；
+// 2. token：解析 JWT，强校验 claims.DeviceID 与 query.device_id 一致，Redis 可用时校验 auth:at 中存储的 token md5；
+// ticket：从 Redis 兑换票据（兑换即失效），校验设备、客户端 IP 与 auth:at 中的 token md5；
+// 3. 用户处于上行滥用封禁期时拒绝（ErrBanned）。
 //
 // 降级策略（Fail-Open）：
-// - 当 Redis 异常不可用时，不直接拒绝连接，而是退化为仅 JWT 校验；
-// - 这样可提升可用性，但会降低"被踢立即失效"的严格性。
-func (s *ConnectService) Authenticate(ctx context.Context, token, deviceID, clientIP string) (*Session, error) {
+// - 当 Redis 异常不可用时，token 不直接拒绝连接，而是退化为仅 JWT 校验；
+// - 这样可提升可用性，但会降低"被踢立即失效"的严格性；
+// - ticket 只存在于 Redis，Redis 不可用时无法兑换，按非法处理。
+func (s *ConnectService) Authenticate(ctx context.Context, token, ticket, deviceID, clientIP string) (*Session, error) {
 	token = strings.TrimSpace(token)
+	ticket = strings.TrimSpace(ticket)
 	deviceID = strings.TrimSpace(deviceID)
 	clientIP = strings.TrimSpace(clientIP)
 
-	if token == "" {
+	if token == "" && ticket == "" {
 		return nil, ErrTokenRequired
 	}
 	if deviceID == "" {
 		return nil, ErrDeviceIDRequired
 	}
 
+	var (
+		userUUID string
+		auth     *sessionAuth
+		err      error
+	)
+	if token != "" {
+		var claims *util.CustomClaims
+		claims, auth, err = s.verifyToken(ctx, token, deviceID)
+		if claims != nil {
+			userUUID = claims.UserUUID
+		}
+	} else {
+		userUUID, auth, err = s.redeemTicket(ctx, ticket, deviceID, clientIP)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.userBanned(ctx, userUUID) {
+		return nil, ErrBanned
+	}
+
+	session := &Session{
+		UserUUID: userUUID,
+		DeviceID: deviceID,
+		ClientIP: clientIP,
+	}
+	session.auth.Store(auth)
+	return session, nil
+}
+
+// Reauth 在连接存活期间用新的 access token 续期登录态。
+// 新 token 必须属于同一用户、同一设备，校验规则与握手一致。
+func (s *ConnectService) Reauth(ctx context.Context, session *Session, token string) error {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return ErrTokenRequired
+	}
+
+	claims, auth, err := s.verifyToken(ctx, token, session.DeviceID)
+	if err != nil {
+		return err
+	}
+	if claims.UserUUID != session.UserUUID {
+		return ErrTokenInvalid
+	}
+	session.auth.Store(auth)
+	return nil
+}
+
+// CheckRevoked 检查连接的登录态是否已被吊销（踢设备、登出、改密等会删除 auth:at）。
+// 说明：
+// - 仅以 auth:at 不存在判定吊销；哈希不一致说明客户端已通过 HTTP 刷新 token，
+// 旧 token 在过期前仍视为有效，客户端应尽快 reauth；
+// - Redis 不可用或读取失败时按未吊销处理（与握手的 fail-open 策略一致）。
+func (s *ConnectService) CheckRevoked(ctx context.Context, session *Session) bool {
+	if s.redisClient == nil {
+		return false
+	}
+	key := rediskey.AccessTokenKey(session.UserUUID, session.DeviceID)
+	err := s.redisClient.Get(ctx, key).Err()
+	switch {
+	case err == redis.Nil:
+		return true
+	case err != nil:
+		logger.Warn(ctx, "连接登录态检查读取 Redis 失败，跳过本次检查",
+			logger.String("user_uuid", session.UserUUID),
+			logger.String("device_id", session.DeviceID),
+			logger.ErrorField("error", err),
+		)
+	}
+	return false
+}
+
+// verifyToken 解析 JWT 并校验设备绑定与 Redis 中的 token 哈希。
+func (s *ConnectService) verifyToken(ctx context.Context, token, deviceID string) (*util.CustomClaims, *sessionAuth, error) {
 	claims, err := util.ParseToken(token)
 	if err != nil {
-		return nil, ErrTokenInvalid
+		return nil, nil, ErrTokenInvalid
 	}
 	if claims.UserUUID == "" || claims.DeviceID == "" || claims.DeviceID != deviceID {
-		return nil, ErrTokenInvalid
+		return nil, nil, ErrTokenInvalid
 	}
 
 	// 与 user/auth 存储规则保持一致：
@@ -60,7 +177,7 @@ func (s *ConnectService) Authenticate(ctx context.Context, token, deviceID, clie
 		storedHash, getErr := s.redisClient.Get(ctx, key).Result()
 		switch {
 		case getErr == redis.Nil:
-			return nil, ErrTokenInvalid
+			return nil, nil, ErrTokenInvalid
 		case getErr != nil:
 			// Redis 短暂故障时采用 fail-open，优先保证连接服务可用性。
 			logger.Warn(ctx, "连接鉴权读取 Redis 失败，降级为仅 JWT 校验",
@@ -70,16 +187,58 @@ func (s *ConnectService) Authenticate(ctx context.Context, token, deviceID, clie
 			)
 		default:
 			if storedHash != md5Hex(token) {
-				return nil, ErrTokenInvalid
+				return nil, nil, ErrTokenInvalid
 			}
 		}
 	}
 
-	return &Session{
-		UserUUID: claims.UserUUID,
-		DeviceID: claims.DeviceID,
-		ClientIP: clientIP,
-	}, nil
+	auth := &sessionAuth{tokenHash: md5Hex(token)}
+	if claims.ExpiresAt != nil {
+		auth.expiresAt = claims.ExpiresAt.Time
+	}
+	return claims, auth, nil
+}
+
+// redeemTicket 兑换一次性接入票据，校验设备、客户端 IP 与登录态（auth:at 中的 token md5）。
+func (s *ConnectService) redeemTicket(ctx context.Context, id, deviceID, clientIP string) (string, *sessionAuth, error) {
+	if s.redisClient == nil {
+		return "", nil, ErrTokenInvalid
+	}
+	ticket, err := connectroute.RedeemTicket(ctx, s.redisClient, id)
+	if err != nil {
+		if !errors.Is(err, connectroute.ErrTicketNotFound) {
+			logger.Warn(ctx, "兑换接入票据失败",
+				logger.ErrorField("error", err),
+			)
+		}
+		return "", nil, ErrTokenInvalid
+	}
+	if ticket.UserUUID == "" || ticket.DeviceID != deviceID {
+		return "", nil, ErrTokenInvalid
+	}
+	if ticket.ClientIP != "" && ticket.ClientIP != clientIP {
+		return "", nil, ErrTokenInvalid
+	}
+
+	storedHash, getErr := s.redisClient.Get(ctx, rediskey.AccessTokenKey(ticket.UserUUID, ticket.DeviceID)).Result()
+	switch {
+	case getErr == redis.Nil:
+		return "", nil, ErrTokenInvalid
+	case getErr != nil:
+		logger.Warn(ctx, "票据兑换后读取登录态失败，跳过 token 哈希校验",
+			logger.String("user_uuid", ticket.UserUUID),
+			logger.String("device_id", ticket.DeviceID),
+			logger.ErrorField("error", getErr),
+		)
+	case storedHash != ticket.TokenHash:
+		return "", nil, ErrTokenInvalid
+	}
+
+	auth := &sessionAuth{tokenHash: ticket.TokenHash}
+	if ticket.ExpiresAt > 0 {
+		auth.expiresAt = time.Unix(ticket.ExpiresAt, 0)
+	}
+	return ticket.UserUUID, auth, nil
 }
 
 // md5Hex 返回字符串的 MD5 十六进制摘要。
@@ -0,0 +1,634 @@
+package svc
+
+import (
+	"ChatServer/apps/connect/pb"
+	"ChatServer/pkg/logger"
+	"ChatServer/pkg/util"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 通话信令帧类型（上行）。
+// call_invite/call_ringing/call_accept/call_offer/call_answer/call_ice 同时也是转发给对端的下行帧类型。
+const (
+	CallTypeInvite  = "call_invite"
+	CallTypeRinging = "call_ringing"
+	CallTypeAccept  = "call_accept"
+	CallTypeReject  = "call_reject"
+	CallTypeCancel  = "call_cancel"
+	CallTypeHangup  = "call_hangup"
+	CallTypeOffer   = "call_offer"
+	CallTypeAnswer  = "call_answer"
+	CallTypeICE     = "call_ice"
+
+	// callTypeInviteOK 发起成功，下发给主叫设备（data.call_id 为服务端分配的通话 ID）。
+	callTypeInviteOK = "call_invite_ok"
+	// callTypeEnd 通话结束，下发给主被叫（data.reason 为结束原因）。
+	callTypeEnd = "call_end"
+)
+
+// 通话结束原因（call_end 帧的 data.reason）。
+const (
+	CallEndBusy              = "busy"               // 被叫忙线
+	CallEndRejected          = "rejected"           // 被叫拒接
+	CallEndCanceled          = "canceled"           // 主叫取消
+	CallEndTimeout           = "timeout"            // 振铃超时无人接听
+	CallEndHangup            = "hangup"             // 通话中任一方挂断
+	CallEndDisconnected      = "disconnected"       // 参与设备断线且宽限期内未重连
+	CallEndAnsweredElsewhere = "answered_elsewhere" // 被叫已在其他设备接听（仅下发给被叫的其他设备）
+)
+
+// 媒体类型。
+const (
+	CallMediaAudio = "audio"
+	CallMediaVideo = "video"
+)
+
+// callOpTimeout 定时器触发的结算、通话记录写入的超时。
+const callOpTimeout = 5 * time.Second
+
+// CallState 通话状态。
+type CallState string
+
+const (
+	// CallStateRinging 已发起、等待被叫接听。
+	CallStateRinging CallState = "ringing"
+	// CallStateActive 已接听，主被叫设备已确定，可以交换 SDP/ICE。
+	CallStateActive CallState = "active"
+)
+
+// Call 一次 1:1 通话的服务端状态。
+// 振铃阶段被叫设备未确定（邀请推送到被叫全部在线设备），接听后锁定为接听设备。
+type Call struct {
+	ID           string    `json:"id"`
+	Media        string    `json:"media"`
+	CallerUUID   string    `json:"caller_uuid"`
+	CallerDevice string    `json:"caller_device"`
+	CalleeUUID   string    `json:"callee_uuid"`
+	CalleeDevice string    `json:"callee_device,omitempty"`
+	State        CallState `json:"state"`
+	// CreatedAt/AnsweredAt 毫秒时间戳。
+	CreatedAt  int64 `json:"created_at"`
+	AnsweredAt int64 `json:"answered_at,omitempty"`
+}
+
+// isParticipant 设备是否为通话参与方（振铃阶段被叫的任一设备都算）。
+func (c *Call) isParticipant(userUUID, deviceID string) bool {
+	if userUUID == c.CallerUUID {
+		return deviceID == c.CallerDevice
+	}
+	if userUUID == c.CalleeUUID {
+		return c.CalleeDevice == "" || deviceID == c.CalleeDevice
+	}
+	return false
+}
+
+// peer 返回通话中对端的用户与设备，仅 active 状态有意义。
+func (c *Call) peer(userUUID string) (string, string) {
+	if userUUID == c.CallerUUID {
+		return c.CalleeUUID, c.CalleeDevice
+	}
+	return c.CallerUUID, c.CallerDevice
+}
+
+// CallData 定义 call_* 上下行帧的 data 结构。
+// 上行：call_invite 携带 to_uuid/media；其余携带 call_id，call_offer/call_answer/call_ice 另带 payload（SDP/ICE 候选，服务端不解析）。
+// 下行：服务端补充 from_uuid/from_device 标识发送方，call_end 携带 reason。
+type CallData struct {
+	CallID     string          `json:"call_id,omitempty"`
+	ToUUID     string          `json:"to_uuid,omitempty"`
+	FromUUID   string          `json:"from_uuid,omitempty"`
+	FromDevice string          `json:"from_device,omitempty"`
+	Media      string          `json:"media,omitempty"`
+	Reason     string          `json:"reason,omitempty"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+}
+
+// CallConfig 通话信令配置。
+type CallConfig struct {
+	// RingTimeout 振铃超时，超时未接听按 timeout 结束（记录为未接）。
+	RingTimeout time.Duration
+	// DisconnectGrace 参与设备断线后的宽限期，期内重连不结束通话。
+	DisconnectGrace time.Duration
+	// StateTTL 通话状态 TTL，由参与设备心跳续期；节点宕机未能结算时由 TTL 兜底释放占线。
+	StateTTL time.Duration
+}
+
+// DefaultCallConfig 返回默认配置：振铃 45s，断线宽限 15s，状态 TTL 120s。
+func DefaultCallConfig() CallConfig {
+	return CallConfig{
+		RingTimeout:     45 * time.Second,
+		DisconnectGrace: 15 * time.Second,
+		StateTTL:        120 * time.Second,
+	}
+}
+
+// Calls 1:1 音视频通话信令中继（WebRTC），服务端只维护状态机并转发信令，不参与媒体流。
+// 状态机：
+//
+//	invite -> ringing --accept--> active --hangup/disconnect--> 结束
+//	             |--reject/cancel/hangup/timeout/disconnect--> 结束
+//
+// - 只能呼叫好友，双方任一方拉黑对方时拒绝邀请（见 CallGuard）；
+// - 主叫或被叫任一设备已在通话中时，新的邀请以 busy 结束（主叫自身在通话中视为非法信令）；
+// - 邀请推送到被叫全部在线设备，首个 accept 的设备锁定通话，其余设备收到 call_end(answered_elsewhere)；
+// - 结束时通知主被叫并经 CallRecorder 在单聊会话写入一条通话记录消息。
+type Calls struct {
+	store    callStore
+	delivery CallDelivery
+	recorder CallRecorder
+	guard    CallGuard
+	cfg      CallConfig
+
+	mu sync.Mutex
+	// local 本节点上参与通话的设备：user_uuid/device_id -> call_id，心跳时续期、断线时判断是否需要结束通话。
+	local map[string]string
+}
+
+// NewCalls 创建通话信令中继，delivery 为 nil 时返回 nil（不支持通话）。
+// rdb 不为 nil 时通话状态存 Redis（集群模式），否则存本节点内存；recorder 为 nil 时不写通话记录；
+// guard 为 nil 时不校验好友与黑名单关系（仅用于本地调试）。
+func NewCalls(rdb *redis.Client, delivery CallDelivery, recorder CallRecorder, guard CallGuard, cfg CallConfig) *Calls {
+	if delivery == nil {
+		return nil
+	}
+	defaults := DefaultCallConfig()
+	if cfg.RingTimeout <= 0 {
+		cfg.RingTimeout = defaults.RingTimeout
+	}
+	if cfg.DisconnectGrace <= 0 {
+		cfg.DisconnectGrace = defaults.DisconnectGrace
+	}
+	if cfg.StateTTL <= 0 {
+		cfg.StateTTL = defaults.StateTTL
+	}
+	// 振铃期间只有主叫心跳续期，TTL 必须覆盖振铃超时，保证超时结算时状态仍在。
+	if minTTL := cfg.RingTimeout + callOpTimeout; cfg.StateTTL < minTTL {
+		cfg.StateTTL = minTTL
+	}
+
+	var store callStore
+	if rdb != nil {
+		store = &redisCallStore{rdb: rdb}
+	} else {
+		store = newMemoryCallStore()
+	}
+	return &Calls{
+		store:    store,
+		delivery: delivery,
+		recorder: recorder,
+		guard:    guard,
+		cfg:      cfg,
+		local:    make(map[string]string),
+	}
+}
+
+// IsCallType 是否为通话信令上行帧类型。
+func IsCallType(msgType string) bool {
+	switch msgType {
+	case CallTypeInvite, CallTypeRinging, CallTypeAccept, CallTypeReject, CallTypeCancel,
+		CallTypeHangup, CallTypeOffer, CallTypeAnswer, CallTypeICE:
+		return true
+	}
+	return false
+}
+
+// Handle 处理通话信令上行帧。
+// 返回 ErrCallNotFound 表示通话不存在或已结束；ErrCallInvalid 表示参数缺失、角色或状态不符；
+// ErrCallNotFriend/ErrCallBlocked 表示邀请被关系校验拒绝；其他错误为关系查询、存储或投递失败。
+func (c *Calls) Handle(ctx context.Context, session *Session, msgType string, raw json.RawMessage) error {
+	if len(raw) == 0 {
+		return ErrCallInvalid
+	}
+	var data CallData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("%w: %v", ErrCallInvalid, err)
+	}
+	if msgType != CallTypeInvite && data.CallID == "" {
+		return ErrCallInvalid
+	}
+
+	switch msgType {
+	case CallTypeInvite:
+		return c.invite(ctx, session, data)
+	case CallTypeRinging:
+		return c.ringing(ctx, session, data)
+	case CallTypeAccept:
+		return c.accept(ctx, session, data)
+	case CallTypeReject:
+		return c.end(ctx, data.CallID, CallEndRejected, func(call *Call) error {
+			if call.State != CallStateRinging || session.UserUUID != call.CalleeUUID {
+				return ErrCallInvalid
+			}
+			return nil
+		})
+	case CallTypeCancel:
+		return c.end(ctx, data.CallID, CallEndCanceled, func(call *Call) error {
+			if call.State != CallStateRinging || session.UserUUID != call.CallerUUID || session.DeviceID != call.CallerDevice {
+				return ErrCallInvalid
+			}
+			return nil
+		})
+	case CallTypeHangup:
+		return c.hangup(ctx, session, data)
+	case CallTypeOffer, CallTypeAnswer, CallTypeICE:
+		return c.relay(ctx, session, msgType, data)
+	default:
+		return ErrCallInvalid
+	}
+}
+
+// Touch 参与通话的设备心跳时续期通话状态，通话已结束时清理本地登记。
+func (c *Calls) Touch(ctx context.Context, session *Session) {
+	key := callDeviceKey(session.UserUUID, session.DeviceID)
+	c.mu.Lock()
+	callID, ok := c.local[key]
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	_, err := c.store.update(ctx, callID, c.cfg.StateTTL, nil)
+	if errors.Is(err, ErrCallNotFound) {
+		c.untrack(key, callID)
+		return
+	}
+	if err != nil {
+		logger.Warn(ctx, "通话状态续期失败",
+			logger.String("call_id", callID),
+			logger.ErrorField("error", err),
+		)
+	}
+}
+
+// OnDisconnect 参与通话的设备断线后，宽限期内未重连（同设备不在线）则以 disconnected 结束通话。
+func (c *Calls) OnDisconnect(session *Session) {
+	userUUID, deviceID := session.UserUUID, session.DeviceID
+	key := callDeviceKey(userUUID, deviceID)
+	c.mu.Lock()
+	callID, ok := c.local[key]
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	time.AfterFunc(c.cfg.DisconnectGrace, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), callOpTimeout)
+		defer cancel()
+
+		devices, err := c.delivery.Devices(ctx, userUUID)
+		if err != nil {
+			logger.Warn(ctx, "查询通话设备在线状态失败",
+				logger.String("call_id", callID),
+				logger.ErrorField("error", err),
+			)
+			return
+		}
+		for _, online := range devices {
+			if online == deviceID {
+				return
+			}
+		}
+		err = c.end(ctx, callID, CallEndDisconnected, func(call *Call) error {
+			// 被叫振铃期间的某个设备掉线不影响其他设备继续振铃。
+			if !call.isParticipant(userUUID, deviceID) || (userUUID == call.CalleeUUID && call.State == CallStateRinging) {
+				return ErrCallInvalid
+			}
+			return nil
+		})
+		if errors.Is(err, ErrCallNotFound) || errors.Is(err, ErrCallInvalid) {
+			c.untrack(key, callID)
+			return
+		}
+		if err != nil {
+			logger.Warn(ctx, "结束断线通话失败",
+				logger.String("call_id", callID),
+				logger.ErrorField("error", err),
+			)
+		}
+	})
+}
+
+// invite 发起通话：校验好友与黑名单关系，登记状态并标记占线，回 call_invite_ok 给主叫设备，推送 call_invite 到被叫全部在线设备。
+func (c *Calls) invite(ctx context.Context, session *Session, data CallData) error {
+	to := strings.TrimSpace(data.ToUUID)
+	media := data.Media
+	if media == "" {
+		media = CallMediaAudio
+	}
+	if to == "" || to == session.UserUUID || (media != CallMediaAudio && media != CallMediaVideo) {
+		return ErrCallInvalid
+	}
+	if c.guard != nil {
+		if err := c.guard.CanCall(ctx, session.UserUUID, to); err != nil {
+			return err
+		}
+	}
+
+	call := &Call{
+		ID:           util.NewUUID(),
+		Media:        media,
+		CallerUUID:   session.UserUUID,
+		CallerDevice: session.DeviceID,
+		CalleeUUID:   to,
+		State:        CallStateRinging,
+		CreatedAt:    time.Now().UnixMilli(),
+	}
+	busyUser, err := c.store.create(ctx, call, c.cfg.StateTTL)
+	if err != nil {
+		return err
+	}
+	if busyUser == session.UserUUID {
+		return ErrCallInvalid
+	}
+	if busyUser != "" {
+		c.pushToDevice(ctx, session.UserUUID, session.DeviceID, callTypeEnd, CallData{
+			CallID: call.ID,
+			ToUUID: to,
+			Media:  media,
+			Reason: CallEndBusy,
+		})
+		c.record(call, CallResultBusy, 0)
+		return nil
+	}
+
+	c.track(callDeviceKey(session.UserUUID, session.DeviceID), call.ID)
+	c.pushToDevice(ctx, session.UserUUID, session.DeviceID, callTypeInviteOK, CallData{
+		CallID: call.ID,
+		ToUUID: to,
+		Media:  media,
+	})
+	c.pushToUser(ctx, to, CallTypeInvite, CallData{
+		CallID:     call.ID,
+		FromUUID:   session.UserUUID,
+		FromDevice: session.DeviceID,
+		Media:      media,
+	})
+
+	time.AfterFunc(c.cfg.RingTimeout, func() {
+		timeoutCtx, cancel := context.WithTimeout(context.Background(), callOpTimeout)
+		defer cancel()
+		err := c.end(timeoutCtx, call.ID, CallEndTimeout, func(current *Call) error {
+			if current.State != CallStateRinging {
+				return ErrCallInvalid
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, ErrCallNotFound) && !errors.Is(err, ErrCallInvalid) {
+			logger.Warn(timeoutCtx, "结束振铃超时通话失败",
+				logger.String("call_id", call.ID),
+				logger.ErrorField("error", err),
+			)
+		}
+	})
+	return nil
+}
+
+// ringing 被叫设备已开始振铃，转发给主叫设备。
+func (c *Calls) ringing(ctx context.Context, session *Session, data CallData) error {
+	call, err := c.store.get(ctx, data.CallID)
+	if err != nil {
+		return err
+	}
+	if call.State != CallStateRinging || session.UserUUID != call.CalleeUUID {
+		return ErrCallInvalid
+	}
+	c.pushToDevice(ctx, call.CallerUUID, call.CallerDevice, CallTypeRinging, CallData{
+		CallID:     call.ID,
+		FromUUID:   session.UserUUID,
+		FromDevice: session.DeviceID,
+	})
+	return nil
+}
+
+// accept 被叫接听：锁定接听设备并转入 active，通知主叫设备与被叫的其他设备。
+func (c *Calls) accept(ctx context.Context, session *Session, data CallData) error {
+	call, err := c.store.update(ctx, data.CallID, c.cfg.StateTTL, func(call *Call) error {
+		if call.State != CallStateRinging || session.UserUUID != call.CalleeUUID {
+			return ErrCallInvalid
+		}
+		call.State = CallStateActive
+		call.CalleeDevice = session.DeviceID
+		call.AnsweredAt = time.Now().UnixMilli()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	c.track(callDeviceKey(session.UserUUID, session.DeviceID), call.ID)
+	c.pushToDevice(ctx, call.CallerUUID, call.CallerDevice, CallTypeAccept, CallData{
+		CallID:     call.ID,
+		FromUUID:   session.UserUUID,
+		FromDevice: session.DeviceID,
+	})
+
+	devices, err := c.delivery.Devices(ctx, call.CalleeUUID)
+	if err != nil {
+		logger.Warn(ctx, "查询被叫在线设备失败，其他设备将振铃至超时",
+			logger.String("call_id", call.ID),
+			logger.ErrorField("error", err),
+		)
+		return nil
+	}
+	for _, deviceID := range devices {
+		if deviceID == session.DeviceID {
+			continue
+		}
+		c.pushToDevice(ctx, call.CalleeUUID, deviceID, callTypeEnd, CallData{
+			CallID: call.ID,
+			Reason: CallEndAnsweredElsewhere,
+		})
+	}
+	return nil
+}
+
+// hangup 参与设备挂断：通话中按 hangup 结束；振铃中主叫挂断视为取消，被叫挂断视为拒接。
+func (c *Calls) hangup(ctx context.Context, session *Session, data CallData) error {
+	call, err := c.store.get(ctx, data.CallID)
+	if err != nil {
+		return err
+	}
+	if !call.isParticipant(session.UserUUID, session.DeviceID) {
+		return ErrCallInvalid
+	}
+	reason := CallEndHangup
+	if call.State == CallStateRinging {
+		reason = CallEndRejected
+		if session.UserUUID == call.CallerUUID {
+			reason = CallEndCanceled
+		}
+	}
+	state := call.State
+	return c.end(ctx, call.ID, reason, func(current *Call) error {
+		if current.State != state || !current.isParticipant(session.UserUUID, session.DeviceID) {
+			return ErrCallInvalid
+		}
+		return nil
+	})
+}
+
+// relay 转发 SDP offer/answer 与 ICE 候选给对端设备，仅 active 状态的参与设备可发送。
+func (c *Calls) relay(ctx context.Context, session *Session, msgType string, data CallData) error {
+	if len(data.Payload) == 0 {
+		return ErrCallInvalid
+	}
+	call, err := c.store.get(ctx, data.CallID)
+	if err != nil {
+		return err
+	}
+	if call.State != CallStateActive || !call.isParticipant(session.UserUUID, session.DeviceID) {
+		return ErrCallInvalid
+	}
+	peerUUID, peerDevice := call.peer(session.UserUUID)
+	c.pushToDevice(ctx, peerUUID, peerDevice, msgType, CallData{
+		CallID:     call.ID,
+		FromUUID:   session.UserUUID,
+		FromDevice: session.DeviceID,
+		Payload:    data.Payload,
+	})
+	return nil
+}
+
+// end 结束通话：cond 通过后删除状态并释放占线，通知主被叫（振铃中通知被叫全部设备），异步写入通话记录。
+func (c *Calls) end(ctx context.Context, callID, reason string, cond func(call *Call) error) error {
+	call, err := c.store.remove(ctx, callID, cond)
+	if err != nil {
+		return err
+	}
+	c.untrack(callDeviceKey(call.CallerUUID, call.CallerDevice), call.ID)
+	if call.CalleeDevice != "" {
+		c.untrack(callDeviceKey(call.CalleeUUID, call.CalleeDevice), call.ID)
+	}
+
+	endData := CallData{CallID: call.ID, Reason: reason}
+	c.pushToDevice(ctx, call.CallerUUID, call.CallerDevice, callTypeEnd, endData)
+	if call.CalleeDevice != "" {
+		c.pushToDevice(ctx, call.CalleeUUID, call.CalleeDevice, callTypeEnd, endData)
+	} else {
+		c.pushToUser(ctx, call.CalleeUUID, callTypeEnd, endData)
+	}
+
+	result, duration := callResult(call, reason, time.Now().UnixMilli())
+	c.record(call, result, duration)
+	logger.Info(ctx, "通话结束",
+		logger.String("call_id", call.ID),
+		logger.String("reason", reason),
+		logger.String("result", result),
+		logger.Int64("duration", duration),
+	)
+	return nil
+}
+
+// callResult 把结束原因映射为通话记录结果：接通过的一律为 completed（含时长），振铃超时为 missed。
+func callResult(call *Call, reason string, now int64) (string, int64) {
+	if call.State == CallStateActive {
+		return CallResultCompleted, max((now-call.AnsweredAt)/1000, 0)
+	}
+	switch reason {
+	case CallEndTimeout:
+		return CallResultMissed, 0
+	case CallEndRejected:
+		return CallResultRejected, 0
+	default:
+		return CallResultCanceled, 0
+	}
+}
+
+// record 异步写入通话记录，失败只记日志。
+func (c *Calls) record(call *Call, result string, duration int64) {
+	if c.recorder == nil {
+		return
+	}
+	record := CallRecord{
+		CallID:       call.ID,
+		CallerUUID:   call.CallerUUID,
+		CallerDevice: call.CallerDevice,
+		CalleeUUID:   call.CalleeUUID,
+		Media:        call.Media,
+		Result:       result,
+		Duration:     duration,
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), callOpTimeout)
+		defer cancel()
+		if err := c.recorder.RecordCall(ctx, record); err != nil {
+			logger.Warn(ctx, "写入通话记录失败",
+				logger.String("call_id", record.CallID),
+				logger.String("result", record.Result),
+				logger.ErrorField("error", err),
+			)
+		}
+	}()
+}
+
+// pushToDevice 投递信令到指定设备，失败只记日志（对端离线由断线宽限与振铃超时兜底）。
+func (c *Calls) pushToDevice(ctx context.Context, userUUID, deviceID, msgType string, data CallData) {
+	envelope, err := newCallEnvelope(msgType, data)
+	if err == nil {
+		_, err = c.delivery.PushToDevice(ctx, userUUID, deviceID, envelope)
+	}
+	if err != nil {
+		logger.Warn(ctx, "投递通话信令失败",
+			logger.String("type", msgType),
+			logger.String("call_id", data.CallID),
+			logger.String("target_uuid", userUUID),
+			logger.String("target_device", deviceID),
+			logger.ErrorField("error", err),
+		)
+	}
+}
+
+// pushToUser 投递信令到用户全部在线设备。
+func (c *Calls) pushToUser(ctx context.Context, userUUID, msgType string, data CallData) {
+	envelope, err := newCallEnvelope(msgType, data)
+	if err == nil {
+		_, err = c.delivery.PushToUser(ctx, userUUID, envelope)
+	}
+	if err != nil {
+		logger.Warn(ctx, "投递通话信令失败",
+			logger.String("type", msgType),
+			logger.String("call_id", data.CallID),
+			logger.String("target_uuid", userUUID),
+			logger.ErrorField("error", err),
+		)
+	}
+}
+
+func (c *Calls) track(key, callID string) {
+	c.mu.Lock()
+	c.local[key] = callID
+	c.mu.Unlock()
+}
+
+// untrack 清理本地登记，仅当仍指向该通话时删除（设备可能已发起新通话）。
+func (c *Calls) untrack(key, callID string) {
+	c.mu.Lock()
+	if c.local[key] == callID {
+		delete(c.local, key)
+	}
+	c.mu.Unlock()
+}
+
+func callDeviceKey(userUUID, deviceID string) string {
+	return userUUID + "/" + deviceID
+}
+
+func newCallEnvelope(msgType string, data CallData) (*pb.MessageEnvelope, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.MessageEnvelope{
+		Type:     msgType,
+		Data:     payload,
+		ServerTs: time.Now().UnixMilli(),
+	}, nil
+}
@@ -0,0 +1,94 @@
+package svc
+
+import (
+	"ChatServer/apps/connect/internal/codec"
+	"ChatServer/apps/connect/pb"
+	"ChatServer/pkg/connectroute"
+	"context"
+)
+
+// CallDelivery 通话信令投递能力。
+// 集群模式使用 connectroute.Router（主被叫可以在不同节点），单节点模式直接投递本地连接。
+type CallDelivery interface {
+	// PushToDevice 投递到用户的指定设备，返回是否入队。
+	PushToDevice(ctx context.Context, userUUID, deviceID string, envelope *pb.MessageEnvelope) (bool, error)
+	// PushToUser 投递到用户全部在线设备，返回入队设备数。
+	PushToUser(ctx context.Context, userUUID string, envelope *pb.MessageEnvelope) (int, error)
+	// Devices 返回用户当前在线的设备 ID。
+	Devices(ctx context.Context, userUUID string) ([]string, error)
+}
+
+// LocalSender 本节点连接投递能力（manager.ConnectionManager 满足该接口）。
+type LocalSender interface {
+	SendToDevice(userUUID, deviceID string, frame *codec.Frame) bool
+	SendToUser(userUUID string, frame *codec.Frame) int
+	GetOnlineDevices(userUUID string) []string
+}
+
+// NewLocalCallDelivery 单节点模式的信令投递。
+func NewLocalCallDelivery(sender LocalSender) CallDelivery {
+	return localCallDelivery{sender: sender}
+}
+
+type localCallDelivery struct {
+	sender LocalSender
+}
+
+func (d localCallDelivery) PushToDevice(_ context.Context, userUUID, deviceID string, envelope *pb.MessageEnvelope) (bool, error) {
+	return d.sender.SendToDevice(userUUID, deviceID, codec.NewFrame(envelope)), nil
+}
+
+func (d localCallDelivery) PushToUser(_ context.Context, userUUID string, envelope *pb.MessageEnvelope) (int, error) {
+	return d.sender.SendToUser(userUUID, codec.NewFrame(envelope)), nil
+}
+
+func (d localCallDelivery) Devices(_ context.Context, userUUID string) ([]string, error) {
+	return d.sender.GetOnlineDevices(userUUID), nil
+}
+
+// NewRouterCallDelivery 集群模式的信令投递，router 为 nil 时返回 nil。
+func NewRouterCallDelivery(router *connectroute.Router) CallDelivery {
+	if router == nil {
+		return nil
+	}
+	return routerCallDelivery{router: router}
+}
+
+type routerCallDelivery struct {
+	router *connectroute.Router
+}
+
+func (d routerCallDelivery) PushToDevice(ctx context.Context, userUUID, deviceID string, envelope *pb.MessageEnvelope) (bool, error) {
+	resp, err := d.router.PushToDevice(ctx, &pb.PushToDeviceRequest{
+		UserUuid: userUUID,
+		DeviceId: deviceID,
+		Message:  envelope,
+	})
+	if err != nil {
+		return false, err
+	}
+	return resp.Delivered, nil
+}
+
+func (d routerCallDelivery) PushToUser(ctx context.Context, userUUID string, envelope *pb.MessageEnvelope) (int, error) {
+	resp, err := d.router.PushToUser(ctx, &pb.PushToUserRequest{
+		UserUuid: userUUID,
+		Message:  envelope,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(resp.DeliveredCount), nil
+}
+
+func (d routerCallDelivery) Devices(ctx context.Context, userUUID string) ([]string, error) {
+	routes, err := d.router.Lookup(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	devices := make([]string, 0, len(routes))
+	for deviceID := range routes {
+		devices = append(devices, deviceID)
+	}
+	return devices, nil
+}
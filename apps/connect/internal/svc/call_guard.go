@@ -0,0 +1,97 @@
+package svc
+
+import (
+	userpb "ChatServer/apps/user/pb"
+	"context"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrCallNotFriend 表示被叫不是主叫的好友。
+	ErrCallNotFriend = errors.New("callee is not a friend")
+	// ErrCallBlocked 表示主被叫任一方拉黑了对方。
+	ErrCallBlocked = errors.New("call blocked by blacklist")
+	// errRelationUnknown 关系缓存未命中且无法回源 user 服务。
+	errRelationUnknown = errors.New("relation unknown")
+)
+
+// CallGuard 发起通话前的关系校验。
+type CallGuard interface {
+	// CanCall 返回 nil 表示允许主叫呼叫被叫；ErrCallNotFriend/ErrCallBlocked 表示拒绝，其他错误为查询失败。
+	CanCall(ctx context.Context, callerUUID, calleeUUID string) error
+}
+
+// NewRelationCallGuard 基于关系缓存的通话校验：被叫必须是主叫的好友，且双方均未拉黑对方。
+// 优先读 user 服务维护的 Redis 关系缓存（与好友在线状态推送同源），未命中时回源 user 服务 RPC；
+// 无法回源时拒绝通话（查询失败）。rdb 与两个 client 均为 nil 时返回 nil（不校验）。
+func NewRelationCallGuard(rdb *redis.Client, friends userpb.FriendServiceClient, blacklist userpb.BlacklistServiceClient) CallGuard {
+	if rdb == nil && friends == nil && blacklist == nil {
+		return nil
+	}
+	return relationCallGuard{rdb: rdb, friends: friends, blacklist: blacklist}
+}
+
+type relationCallGuard struct {
+	rdb       *redis.Client
+	friends   userpb.FriendServiceClient
+	blacklist userpb.BlacklistServiceClient
+}
+
+func (g relationCallGuard) CanCall(ctx context.Context, callerUUID, calleeUUID string) error {
+	friend, err := g.isFriend(ctx, callerUUID, calleeUUID)
+	if err != nil {
+		return err
+	}
+	if !friend {
+		return ErrCallNotFriend
+	}
+	// 被叫拉黑主叫（被叫意愿优先），或主叫拉黑了被叫，都不允许发起。
+	for _, pair := range [][2]string{{calleeUUID, callerUUID}, {callerUUID, calleeUUID}} {
+		blocked, err := g.isBlocked(ctx, pair[0], pair[1])
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return ErrCallBlocked
+		}
+	}
+	return nil
+}
+
+// isFriend 先查主叫的好友缓存，未命中时回源 CheckIsFriend。
+func (g relationCallGuard) isFriend(ctx context.Context, userUUID, peerUUID string) (bool, error) {
+	if g.rdb != nil {
+		hit, friend, err := relationCache{rdb: g.rdb}.isFriend(ctx, userUUID, peerUUID)
+		if err == nil && hit {
+			return friend, nil
+		}
+	}
+	if g.friends == nil {
+		return false, errRelationUnknown
+	}
+	resp, err := g.friends.CheckIsFriend(ctx, &userpb.CheckIsFriendRequest{UserUuid: userUUID, PeerUuid: peerUUID})
+	if err != nil {
+		return false, err
+	}
+	return resp.GetIsFriend(), nil
+}
+
+// isBlocked 先查 userUUID 的黑名单缓存，未命中时回源 CheckIsBlacklist。
+func (g relationCallGuard) isBlocked(ctx context.Context, userUUID, peerUUID string) (bool, error) {
+	if g.rdb != nil {
+		hit, blocked, err := relationCache{rdb: g.rdb}.isBlocked(ctx, userUUID, peerUUID)
+		if err == nil && hit {
+			return blocked, nil
+		}
+	}
+	if g.blacklist == nil {
+		return false, errRelationUnknown
+	}
+	resp, err := g.blacklist.CheckIsBlacklist(ctx, &userpb.CheckIsBlacklistRequest{UserUuid: userUUID, TargetUuid: peerUUID})
+	if err != nil {
+		return false, err
+	}
+	return resp.GetIsBlacklist(), nil
+}
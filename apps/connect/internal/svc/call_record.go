@@ -0,0 +1,77 @@
+package svc
+
+import (
+	msgpb "ChatServer/apps/msg/pb"
+	"ChatServer/consts"
+	"context"
+	"encoding/json"
+)
+
+// 通话结果（写入通话记录消息的 result 字段）。
+const (
+	CallResultCompleted = "completed" // 已接通并正常结束
+	CallResultMissed    = "missed"    // 振铃超时未接听
+	CallResultRejected  = "rejected"  // 被叫拒接
+	CallResultCanceled  = "canceled"  // 主叫在接听前取消（含主叫掉线）
+	CallResultBusy      = "busy"      // 被叫忙线
+)
+
+// CallRecord 一次通话的结果，结束时写入主被叫的单聊会话。
+type CallRecord struct {
+	CallID       string
+	CallerUUID   string
+	CallerDevice string
+	CalleeUUID   string
+	Media        string
+	Result       string
+	// Duration 通话时长（秒），仅 completed 有值。
+	Duration int64
+}
+
+// CallRecorder 通话记录写入能力，实现方需保证同一 call_id 只落一条消息。
+type CallRecorder interface {
+	RecordCall(ctx context.Context, record CallRecord) error
+}
+
+// callRecordContent 通话记录消息的 content 结构（msg_type=consts.MsgTypeCall）。
+type callRecordContent struct {
+	CallID   string `json:"call_id"`
+	Media    string `json:"media"`
+	Result   string `json:"result"`
+	Duration int64  `json:"duration,omitempty"`
+}
+
+// NewMsgCallRecorder 经 msg 服务 SendMessage 写入通话记录，client 为 nil 时返回 nil（不写记录）。
+// 消息以主叫身份发送到单聊会话，client_msg_id 固定为 call-{call_id}，重复写入由 msg 服务幂等去重。
+func NewMsgCallRecorder(client msgpb.MsgServiceClient) CallRecorder {
+	if client == nil {
+		return nil
+	}
+	return msgCallRecorder{client: client}
+}
+
+type msgCallRecorder struct {
+	client msgpb.MsgServiceClient
+}
+
+func (r msgCallRecorder) RecordCall(ctx context.Context, record CallRecord) error {
+	content, err := json.Marshal(callRecordContent{
+		CallID:   record.CallID,
+		Media:    record.Media,
+		Result:   record.Result,
+		Duration: record.Duration,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.client.SendMessage(ctx, &msgpb.SendMessageRequest{
+		FromUuid:    record.CallerUUID,
+		DeviceId:    record.CallerDevice,
+		ConvType:    msgpb.ConvType_CONV_TYPE_P2P,
+		TargetUuid:  record.CalleeUUID,
+		ClientMsgId: "call-" + record.CallID,
+		MsgType:     consts.MsgTypeCall,
+		Content:     string(content),
+	})
+	return err
+}
@@ -0,0 +1,289 @@
+package svc
+
+import (
+	rediskey "ChatServer/consts/redisKey"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// callStoreRetries Redis 乐观锁（WATCH）冲突时的重试次数。
+const callStoreRetries = 3
+
+var (
+	// ErrCallNotFound 表示通话不存在或已结束。
+	ErrCallNotFound = errors.New("call not found")
+	// ErrCallInvalid 表示通话信令非法：参数缺失、发送方角色或通话状态不符。
+	ErrCallInvalid = errors.New("call signal is invalid")
+)
+
+// callStore 通话状态存储。
+// 单节点模式使用内存实现；集群模式使用 Redis 实现，主被叫可以连在不同节点上。
+// 所有状态迁移都必须原子：同一通话只能被接听一次、只能结束一次。
+type callStore interface {
+	// create 登记通话并标记主被叫占线，任一方已有通话时不登记并返回占线的用户（主叫优先）。
+	create(ctx context.Context, call *Call, ttl time.Duration) (busyUser string, err error)
+	// get 读取通话。
+	get(ctx context.Context, callID string) (*Call, error)
+	// update 读取-修改-写回并续期，fn 返回错误时放弃修改；fn 为 nil 时仅续期。
+	update(ctx context.Context, callID string, ttl time.Duration, fn func(call *Call) error) (*Call, error)
+	// remove 删除通话并释放占线标记，cond 返回错误时放弃删除；返回删除前的通话。
+	remove(ctx context.Context, callID string, cond func(call *Call) error) (*Call, error)
+}
+
+// memoryCallStore 单节点内存实现，过期时间在访问时检查。
+type memoryCallStore struct {
+	mu      sync.Mutex
+	calls   map[string]*memoryCall
+	byUser  map[string]string
+	nowFunc func() time.Time
+}
+
+type memoryCall struct {
+	call      Call
+	expiresAt time.Time
+}
+
+func newMemoryCallStore() *memoryCallStore {
+	return &memoryCallStore{
+		calls:   make(map[string]*memoryCall),
+		byUser:  make(map[string]string),
+		nowFunc: time.Now,
+	}
+}
+
+func (s *memoryCallStore) create(_ context.Context, call *Call, ttl time.Duration) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, userUUID := range []string{call.CallerUUID, call.CalleeUUID} {
+		if callID, ok := s.byUser[userUUID]; ok && s.lookup(callID) != nil {
+			return userUUID, nil
+		}
+	}
+	s.calls[call.ID] = &memoryCall{call: *call, expiresAt: s.nowFunc().Add(ttl)}
+	s.byUser[call.CallerUUID] = call.ID
+	s.byUser[call.CalleeUUID] = call.ID
+	return "", nil
+}
+
+func (s *memoryCallStore) get(_ context.Context, callID string) (*Call, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.lookup(callID)
+	if entry == nil {
+		return nil, ErrCallNotFound
+	}
+	call := entry.call
+	return &call, nil
+}
+
+func (s *memoryCallStore) update(_ context.Context, callID string, ttl time.Duration, fn func(call *Call) error) (*Call, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.lookup(callID)
+	if entry == nil {
+		return nil, ErrCallNotFound
+	}
+	call := entry.call
+	if fn != nil {
+		if err := fn(&call); err != nil {
+			return nil, err
+		}
+	}
+	entry.call = call
+	entry.expiresAt = s.nowFunc().Add(ttl)
+	return &call, nil
+}
+
+func (s *memoryCallStore) remove(_ context.Context, callID string, cond func(call *Call) error) (*Call, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.lookup(callID)
+	if entry == nil {
+		return nil, ErrCallNotFound
+	}
+	call := entry.call
+	if cond != nil {
+		if err := cond(&call); err != nil {
+			return nil, err
+		}
+	}
+	s.drop(callID)
+	return &call, nil
+}
+
+// lookup 返回未过期的通话，已过期的顺手清理，调用方需持有锁。
+func (s *memoryCallStore) lookup(callID string) *memoryCall {
+	entry, ok := s.calls[callID]
+	if !ok {
+		return nil
+	}
+	if s.nowFunc().After(entry.expiresAt) {
+		s.drop(callID)
+		return nil
+	}
+	return entry
+}
+
+// drop 删除通话及仍指向它的占线标记，调用方需持有锁。
+func (s *memoryCallStore) drop(callID string) {
+	entry, ok := s.calls[callID]
+	if !ok {
+		return
+	}
+	delete(s.calls, callID)
+	for _, userUUID := range []string{entry.call.CallerUUID, entry.call.CalleeUUID} {
+		if s.byUser[userUUID] == callID {
+			delete(s.byUser, userUUID)
+		}
+	}
+}
+
+// redisCallStore 集群实现：
+// - connect:call:{call_id} 通话 JSON；
+// - connect:call:user:{uuid} 占线标记（值为 call_id）；
+// 三个 Key 使用相同 TTL，由参与方心跳续期；状态迁移通过 WATCH 乐观锁保证原子。
+type redisCallStore struct {
+	rdb *redis.Client
+}
+
+func (s *redisCallStore) create(ctx context.Context, call *Call, ttl time.Duration) (string, error) {
+	data, err := json.Marshal(call)
+	if err != nil {
+		return "", err
+	}
+	callerKey := rediskey.ConnectCallUserKey(call.CallerUUID)
+	calleeKey := rediskey.ConnectCallUserKey(call.CalleeUUID)
+
+	var busyUser string
+	err = s.watch(ctx, func(tx *redis.Tx) error {
+		owners, err := tx.MGet(ctx, callerKey, calleeKey).Result()
+		if err != nil {
+			return err
+		}
+		busyUser = ""
+		for i, userUUID := range []string{call.CallerUUID, call.CalleeUUID} {
+			if owners[i] != nil {
+				busyUser = userUUID
+				return nil
+			}
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, rediskey.ConnectCallKey(call.ID), data, ttl)
+			pipe.Set(ctx, callerKey, call.ID, ttl)
+			pipe.Set(ctx, calleeKey, call.ID, ttl)
+			return nil
+		})
+		return err
+	}, callerKey, calleeKey)
+	return busyUser, err
+}
+
+func (s *redisCallStore) get(ctx context.Context, callID string) (*Call, error) {
+	return readCall(ctx, s.rdb, callID)
+}
+
+func (s *redisCallStore) update(ctx context.Context, callID string, ttl time.Duration, fn func(call *Call) error) (*Call, error) {
+	key := rediskey.ConnectCallKey(callID)
+	var out *Call
+	err := s.watch(ctx, func(tx *redis.Tx) error {
+		call, err := readCall(ctx, tx, callID)
+		if err != nil {
+			return err
+		}
+		if fn != nil {
+			if err := fn(call); err != nil {
+				return err
+			}
+		}
+		data, err := json.Marshal(call)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, ttl)
+			pipe.Expire(ctx, rediskey.ConnectCallUserKey(call.CallerUUID), ttl)
+			pipe.Expire(ctx, rediskey.ConnectCallUserKey(call.CalleeUUID), ttl)
+			return nil
+		})
+		out = call
+		return err
+	}, key)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *redisCallStore) remove(ctx context.Context, callID string, cond func(call *Call) error) (*Call, error) {
+	key := rediskey.ConnectCallKey(callID)
+	var out *Call
+	err := s.watch(ctx, func(tx *redis.Tx) error {
+		call, err := readCall(ctx, tx, callID)
+		if err != nil {
+			return err
+		}
+		if cond != nil {
+			if err := cond(call); err != nil {
+				return err
+			}
+		}
+		userKeys := []string{rediskey.ConnectCallUserKey(call.CallerUUID), rediskey.ConnectCallUserKey(call.CalleeUUID)}
+		owners, err := tx.MGet(ctx, userKeys...).Result()
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, key)
+			for i, userKey := range userKeys {
+				// 占线标记已过期并被新通话占用时保留。
+				if owner, _ := owners[i].(string); owner == callID {
+					pipe.Del(ctx, userKey)
+				}
+			}
+			return nil
+		})
+		out = call
+		return err
+	}, key)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// watch 执行 WATCH 事务，乐观锁冲突时重试。
+func (s *redisCallStore) watch(ctx context.Context, fn func(tx *redis.Tx) error, keys ...string) error {
+	var err error
+	for i := 0; i < callStoreRetries; i++ {
+		err = s.rdb.Watch(ctx, fn, keys...)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return err
+}
+
+// readCall 读取并解析通话 JSON，Key 不存在时返回 ErrCallNotFound。
+func readCall(ctx context.Context, cmd redis.Cmdable, callID string) (*Call, error) {
+	raw, err := cmd.Get(ctx, rediskey.ConnectCallKey(callID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCallNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var call Call
+	if err := json.Unmarshal(raw, &call); err != nil {
+		return nil, err
+	}
+	return &call, nil
+}
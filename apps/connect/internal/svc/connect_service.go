@@ -1,12 +1,19 @@
 package svc
 
 import (
+	"ChatServer/apps/connect/internal/codec"
+	"ChatServer/apps/connect/pb"
 	userpb "ChatServer/apps/user/pb"
+	"ChatServer/pkg/connectroute"
 	"ChatServer/pkg/deviceactive"
+	"ChatServer/pkg/logger"
+	"context"
 	"encoding/json"
 	"errors"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -17,6 +24,22 @@ type Session struct {
 	UserUUID string
 	DeviceID string
 	ClientIP string
+	// Resume 客户端重连时请求补发（握手携带 resume 参数）。
+	Resume bool
+	// ResumeCursors 客户端各会话最后收到的 seq（conv_id -> seq），未出现的会话视为从未收到。
+	ResumeCursors map[string]int64
+	// Batch 客户端声明支持 type=batch 批量帧。
+	Batch bool
+	// Platform/AppVersion 客户端握手时声明的平台与 App 版本，仅用于计算自动订阅的主题。
+	Platform   string
+	AppVersion string
+	// Heartbeat 按平台计算的应用层心跳策略，随 hello 帧下发。
+	Heartbeat HeartbeatPolicy
+
+	// auth 当前生效的 access token 状态，reauth 时原子替换。
+	auth atomic.Pointer[sessionAuth]
+	// uplink 上行限速状态，OnConnect 时登记、OnDisconnect 时释放。
+	uplink atomic.Pointer[sessionUplink]
 }
 
 // Envelope 定义 WebSocket 通用消息包格式。
@@ -34,22 +57,77 @@ type ErrorData struct {
 	Message string `json:"message"`
 }
 
+// ClientAckData 定义 type=client_ack 时的 data 结构。
+// seq 为会话内序号，需与 conv_id 组合定位消息（无会话的消息 conv_id 留空）。
+// 同一会话单条确认使用 seq，批量确认使用 seqs，两者可同时出现；跨会话批量确认使用 acks。
+type ClientAckData struct {
+	ConvID string          `json:"conv_id,omitempty"`
+	Seq    int64           `json:"seq,omitempty"`
+	Seqs   []int64         `json:"seqs,omitempty"`
+	Acks   []ClientAckItem `json:"acks,omitempty"`
+}
+
+// ClientAckItem 一条待确认消息的 (conv_id, seq)。
+type ClientAckItem struct {
+	ConvID string `json:"conv_id,omitempty"`
+	Seq    int64  `json:"seq"`
+}
+
+// ReauthData 定义 type=reauth 上行帧的 data 结构。
+type ReauthData struct {
+	Token string `json:"token"`
+}
+
+// AuthExpiryData 定义 type=auth_expiring / type=reauth_ok 时的 data 结构。
+// expires_at 为毫秒时间戳，expires_in 为剩余秒数。
+type AuthExpiryData struct {
+	ExpiresAt int64 `json:"expires_at"`
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+// ResumeData 定义 type=resume_ok / type=resync 时的 data 结构。
+// resync 表示补发缓冲无法完整覆盖缺口，客户端需要按各会话本地 seq 全量拉取。
+type ResumeData struct {
+	Replayed int    `json:"replayed"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// KickoutData 定义 type=kickout 时的 data 结构。
+type KickoutData struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// ReconnectData 定义 type=reconnect 时的 data 结构。
+// 节点排空时下发，endpoint 为建议重连的备用接入地址（为空时客户端按原地址重连，由负载均衡分配）。
+type ReconnectData struct {
+	Reason   string `json:"reason"`
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// ResyncOutboxUnavailable 补发缓冲不可用（未启用 Redis 或读取失败）。
+const ResyncOutboxUnavailable connectroute.ResyncReason = "outbox_unavailable"
+
 // ConnectService 承载 connect 的核心业务逻辑。
 type ConnectService struct {
 	redisClient      *redis.Client
 	userDeviceClient userpb.DeviceServiceClient // 可为 nil，降级时跳过 RPC
 	activeSyncer     *deviceactive.Syncer
+	outbox           *connectroute.Outbox  // 下行补发缓冲，Redis 不可用时为 nil
+	uplink           *uplinkLimiter        // 上行限速，未配置限速时为 nil
 	statusQueue      chan deviceStatusTask // 设备状态 RPC 任务队列
 	statusWg         sync.WaitGroup        // 等待工作协程退出
 }
 
 // NewConnectService 创建业务服务实例。
 // userDeviceClient 可为 nil：此时设备状态 RPC 会被跳过（降级运行）。
-func NewConnectService(redisClient *redis.Client, userDeviceClient userpb.DeviceServiceClient, activeSyncer *deviceactive.Syncer) *ConnectService {
+// uplinkCfg 为上行限速与滥用封禁策略，限速为空时不限速。
+func NewConnectService(redisClient *redis.Client, userDeviceClient userpb.DeviceServiceClient, activeSyncer *deviceactive.Syncer, uplinkCfg UplinkLimitConfig) *ConnectService {
 	s := &ConnectService{
 		redisClient:      redisClient,
 		userDeviceClient: userDeviceClient,
 		activeSyncer:     activeSyncer,
+		outbox:           connectroute.NewOutbox(redisClient, connectroute.OutboxConfig{}),
+		uplink:           newUplinkLimiter(uplinkCfg),
 	}
 
 	// 仅在 userDeviceClient 可用时启动工作协程。
@@ -75,28 +153,114 @@ func (s *ConnectService) ShutdownStatusWorkers() {
 	}
 }
 
-// ParseEnvelope 解析客户端上行帧。
-// 若 type 缺失或 JSON 不合法，会返回错误交由 handler 返回 error 帧。
-func (s *ConnectService) ParseEnvelope(raw []byte) (*Envelope, error) {
-	var envelope Envelope
-	if err := json.Unmarshal(raw, &envelope); err != nil {
+// ParseEnvelope 按连接协商的编码解析客户端上行帧。
+// 若 type 缺失或载荷不合法，会返回错误交由 handler 返回 error 帧。
+// 说明：protobuf 连接的上行 data 约定同样为 JSON 字节，便于按 Type 复用解析逻辑。
+func (s *ConnectService) ParseEnvelope(frameCodec codec.Codec, raw []byte) (*Envelope, error) {
+	decoded, err := frameCodec.Decode(raw)
+	if err != nil {
 		return nil, err
 	}
-	envelope.Type = strings.TrimSpace(envelope.Type)
-	if envelope.Type == "" {
+	msgType := strings.TrimSpace(decoded.Type)
+	if msgType == "" {
 		return nil, errors.New("type is required")
 	}
-	return &envelope, nil
+	return &Envelope{
+		Type: msgType,
+		Data: decoded.Data,
+	}, nil
 }
 
-// MarshalEnvelope 组装并序列化下行帧。
-// 约定：data=nil 时省略 data 字段，避免无意义空对象。
-func (s *ConnectService) MarshalEnvelope(msgType string, data any) ([]byte, error) {
-	envelope := map[string]any{
-		"type": msgType,
+// NewFrame 组装下行帧。
+// 约定：data=nil 时省略 data 字段，避免无意义空对象；data 以 JSON 编码后放入信封。
+// 实际的帧编码（JSON/protobuf）在入队时按连接协商结果决定。
+func (s *ConnectService) NewFrame(msgType string, data any) (*codec.Frame, error) {
+	envelope := &pb.MessageEnvelope{
+		Type:     msgType,
+		ServerTs: time.Now().UnixMilli(),
 	}
 	if data != nil {
-		envelope["data"] = data
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		envelope.Data = payload
+	}
+	return codec.NewFrame(envelope), nil
+}
+
+// ParseClientAck 解析 client_ack 上行帧，返回需要确认的 (conv_id, seq) 列表。
+func (s *ConnectService) ParseClientAck(data json.RawMessage) ([]ClientAckItem, error) {
+	if len(data) == 0 {
+		return nil, errors.New("data is required")
+	}
+	var ack ClientAckData
+	if err := json.Unmarshal(data, &ack); err != nil {
+		return nil, err
+	}
+	items := make([]ClientAckItem, 0, len(ack.Seqs)+len(ack.Acks)+1)
+	if ack.Seq > 0 {
+		items = append(items, ClientAckItem{ConvID: ack.ConvID, Seq: ack.Seq})
+	}
+	for _, seq := range ack.Seqs {
+		if seq > 0 {
+			items = append(items, ClientAckItem{ConvID: ack.ConvID, Seq: seq})
+		}
+	}
+	for _, item := range ack.Acks {
+		if item.Seq > 0 {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return nil, errors.New("seq is required")
+	}
+	return items, nil
+}
+
+// ParseReauth 解析 reauth 上行帧，返回新的 access token。
+func (s *ConnectService) ParseReauth(data json.RawMessage) (string, error) {
+	if len(data) == 0 {
+		return "", ErrTokenRequired
+	}
+	var reauth ReauthData
+	if err := json.Unmarshal(data, &reauth); err != nil {
+		return "", err
+	}
+	return reauth.Token, nil
+}
+
+// NewAuthExpiryData 按 session 当前 token 过期时间构建 auth_expiring / reauth_ok 的 data。
+func NewAuthExpiryData(session *Session, now time.Time) AuthExpiryData {
+	expiresAt := session.ExpiresAt()
+	if expiresAt.IsZero() {
+		return AuthExpiryData{}
+	}
+	return AuthExpiryData{
+		ExpiresAt: expiresAt.UnixMilli(),
+		ExpiresIn: int64(max(expiresAt.Sub(now), 0) / time.Second),
+	}
+}
+
+// LoadResume 从补发缓冲读取 session.ResumeCursors 之后的消息。
+// reason 非空时客户端需要全量按 seq 拉取，可补发的部分仍会返回。
+func (s *ConnectService) LoadResume(ctx context.Context, session *Session) ([]*codec.Frame, connectroute.ResyncReason) {
+	if s.outbox == nil {
+		return nil, ResyncOutboxUnavailable
+	}
+
+	envelopes, reason, err := s.outbox.Since(ctx, session.UserUUID, session.DeviceID, session.ResumeCursors)
+	if err != nil {
+		logger.Warn(ctx, "读取下行补发缓冲失败",
+			logger.Int("resume_convs", len(session.ResumeCursors)),
+			logger.ErrorField("error", err),
+		)
+		return nil, ResyncOutboxUnavailable
+	}
+
+	frames := make([]*codec.Frame, 0, len(envelopes))
+	for _, envelope := range envelopes {
+		frames = append(frames, codec.NewFrame(envelope))
 	}
-	return json.Marshal(envelope)
+	return frames, reason
 }
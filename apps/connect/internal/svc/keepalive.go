@@ -0,0 +1,119 @@
+package svc
+
+import (
+	rediskey "ChatServer/consts/redisKey"
+	"ChatServer/pkg/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// deviceLookupTimeout 握手时读取设备信息缓存的超时。
+const deviceLookupTimeout = time.Second
+
+// HeartbeatConfig 应用层心跳策略。
+// 推荐间隔按平台区分：移动端在运营商 NAT 后，间隔需小于 NAT 空闲回收时间，又不能过短耗电；
+// 桌面端与 Web 可以放宽。连续 MissedLimit 个间隔未收到 heartbeat 时断开连接。
+type HeartbeatConfig struct {
+	// Interval 未单独配置的平台使用的推荐心跳间隔。
+	Interval time.Duration
+	// PlatformIntervals 按平台（小写，如 ios/android/web）覆盖的推荐心跳间隔。
+	PlatformIntervals map[string]time.Duration
+	// MissedLimit 允许连续错过的心跳次数，<=0 表示不检查应用层心跳。
+	MissedLimit int
+}
+
+// DefaultHeartbeatConfig 返回默认配置：推荐间隔 30s，连续错过 3 次断开。
+func DefaultHeartbeatConfig() HeartbeatConfig {
+	return HeartbeatConfig{
+		Interval:    30 * time.Second,
+		MissedLimit: 3,
+	}
+}
+
+// HeartbeatPolicy 单连接生效的心跳策略，Timeout 为 0 表示不检查。
+type HeartbeatPolicy struct {
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+// PolicyFor 按平台计算心跳策略，平台大小写不敏感，未配置的平台使用默认间隔。
+func (c HeartbeatConfig) PolicyFor(platform string) HeartbeatPolicy {
+	interval := c.Interval
+	if interval <= 0 {
+		interval = DefaultHeartbeatConfig().Interval
+	}
+	if platformInterval, ok := c.PlatformIntervals[strings.ToLower(strings.TrimSpace(platform))]; ok && platformInterval > 0 {
+		interval = platformInterval
+	}
+	policy := HeartbeatPolicy{Interval: interval}
+	if c.MissedLimit > 0 {
+		policy.Timeout = interval * time.Duration(c.MissedLimit)
+	}
+	return policy
+}
+
+// HelloData 定义 type=hello 下行帧的 data 结构，连接建立后的第一帧。
+// heartbeat_interval 为推荐的心跳间隔（秒），heartbeat_timeout 为超过多久未收到心跳断开（秒，0 表示不检查），
+// ping_interval 为服务端协议层 Ping 周期（秒），platform 为服务端识别出的平台。
+type HelloData struct {
+	HeartbeatInterval int64  `json:"heartbeat_interval"`
+	HeartbeatTimeout  int64  `json:"heartbeat_timeout"`
+	PingInterval      int64  `json:"ping_interval"`
+	Platform          string `json:"platform,omitempty"`
+}
+
+// NewHelloData 构造 hello 帧数据。
+func NewHelloData(session *Session, pingPeriod time.Duration) HelloData {
+	return HelloData{
+		HeartbeatInterval: int64(session.Heartbeat.Interval / time.Second),
+		HeartbeatTimeout:  int64(session.Heartbeat.Timeout / time.Second),
+		PingInterval:      int64(pingPeriod / time.Second),
+		Platform:          strings.ToLower(session.Platform),
+	}
+}
+
+// HeartbeatAckData 定义 type=heartbeat_ack 的 data 结构，携带当前推荐的心跳间隔（秒），客户端据此调整。
+type HeartbeatAckData struct {
+	HeartbeatInterval int64 `json:"heartbeat_interval"`
+}
+
+// deviceCacheItem 设备信息缓存（user:devices:{user_uuid} 的 field 值，由 user 服务登录时写入）。
+type deviceCacheItem struct {
+	Platform   string `json:"platform"`
+	AppVersion string `json:"appVersion"`
+}
+
+// ResolveDevice 握手未携带 platform/app_version 时，从设备信息缓存（DeviceSession）补全。
+// 缓存未命中或 Redis 不可用时保持为空，按默认心跳策略处理。
+func (s *ConnectService) ResolveDevice(ctx context.Context, session *Session) {
+	if s.redisClient == nil || (session.Platform != "" && session.AppVersion != "") {
+		return
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, deviceLookupTimeout)
+	defer cancel()
+
+	raw, err := s.redisClient.HGet(lookupCtx, rediskey.DeviceInfoKey(session.UserUUID), session.DeviceID).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			logger.Warn(ctx, "读取设备信息缓存失败，使用默认心跳策略",
+				logger.ErrorField("error", err),
+			)
+		}
+		return
+	}
+	var item deviceCacheItem
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return
+	}
+	if session.Platform == "" {
+		session.Platform = item.Platform
+	}
+	if session.AppVersion == "" {
+		session.AppVersion = item.AppVersion
+	}
+}
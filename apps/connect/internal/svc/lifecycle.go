@@ -29,9 +29,13 @@ type deviceStatusTask struct {
 
 // OnConnect 在连接建立后触发。
 // 行为：
-// 1. 立即触发活跃时间同步（不受节流限制）；
-// 2. 异步调用 user-service RPC 将 DeviceSession.status 置为在线。
+// 1. 登记上行限速状态；
+// 2. 立即触发活跃时间同步（不受节流限制）；
+// 3. 异步调用 user-service RPC 将 DeviceSession.status 置为在线。
 func (s *ConnectService) OnConnect(ctx context.Context, session *Session) {
+	if s.uplink != nil {
+		session.uplink.Store(s.uplink.attach(session.UserUUID))
+	}
 	if s.activeSyncer != nil {
 		// 连接建立时强制刷新：先删除节流记录再 touch，确保本次会入缓冲 map。
 		s.activeSyncer.Delete(session.UserUUID, session.DeviceID)
@@ -51,9 +55,12 @@ func (s *ConnectService) OnHeartbeat(ctx context.Context, session *Session) {
 
 // OnDisconnect 在连接断开后触发。
 // 行为：
-// 1. 清理本地节流缓存，避免内存泄漏；
+// 1. 清理本地节流缓存与上行限速状态，避免内存泄漏；
 // 2. 异步调用 user-service RPC 将 DeviceSession.status 置为离线。
 func (s *ConnectService) OnDisconnect(ctx context.Context, session *Session) {
+	if uplink := session.uplink.Swap(nil); uplink != nil && s.uplink != nil {
+		s.uplink.detach(session.UserUUID, uplink)
+	}
 	if s.activeSyncer != nil {
 		s.activeSyncer.Delete(session.UserUUID, session.DeviceID)
 	}
@@ -0,0 +1,236 @@
+package svc
+
+import (
+	"ChatServer/apps/connect/pb"
+	rediskey "ChatServer/consts/redisKey"
+	"ChatServer/pkg/fanout"
+	"ChatServer/pkg/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// presenceFrameType 好友在线状态变化下行帧类型。
+	presenceFrameType = "presence"
+	// presenceOpTimeout 单次在线状态结算（查路由、读缓存、推送）的超时。
+	presenceOpTimeout = 5 * time.Second
+)
+
+// PresenceConfig 好友在线状态推送配置。
+// 上线与下线使用不同的延迟（迟滞）：上线延迟短，保证好友尽快看到绿点；
+// 下线宽限长，移动端切网/闪断在宽限内重连时不会产生 离线->在线 两次推送。
+type PresenceConfig struct {
+	// OnlineDelay 首个设备上线后延迟多久结算并推送上线。
+	OnlineDelay time.Duration
+	// OfflineGrace 最后一个设备下线后等待多久仍未重连才推送下线。
+	OfflineGrace time.Duration
+}
+
+// DefaultPresenceConfig 返回默认配置：上线 2s，下线宽限 30s。
+func DefaultPresenceConfig() PresenceConfig {
+	return PresenceConfig{
+		OnlineDelay:  2 * time.Second,
+		OfflineGrace: 30 * time.Second,
+	}
+}
+
+// PresenceData 定义 type=presence 下行帧的 data 结构，ts 为毫秒时间戳。
+type PresenceData struct {
+	UserUUID string `json:"user_uuid"`
+	Online   bool   `json:"online"`
+	Ts       int64  `json:"ts"`
+}
+
+// presenceRouter 集群路由能力：查询用户全局在线设备、按用户批量推送（connectroute.Router 满足该接口）。
+type presenceRouter interface {
+	fanout.Broadcaster
+	Lookup(ctx context.Context, userUUID string) (map[string]string, error)
+}
+
+// Presence 好友在线状态推送（实现 manager.PresenceHook）。
+// 流程：
+// 1. 用户在本节点的首个设备上线/最后一个设备下线时按用户登记防抖定时器，新事件覆盖未触发的旧事件；
+// 2. 定时器触发后经集群路由表结算用户的全局在线状态（其他节点仍有设备时视为在线）；
+// 3. 与 Redis 中最近一次推送的状态比较（SET GET），未变化则不推送，记录缺失视为未知，多节点同时结算时只有一个节点推送；
+// 4. 从好友关系缓存取好友列表，过滤双方黑名单（ZSet）后经 Router 推送给在线好友。
+// 好友关系缓存未命中时跳过推送（客户端仍可通过 BatchGetOnlineStatus 拉取）；黑名单读取失败时不推送。
+type Presence struct {
+	rdb    *redis.Client
+	router presenceRouter
+	cfg    PresenceConfig
+
+	mu      sync.Mutex
+	timers  map[string]*time.Timer
+	stopped bool
+}
+
+// NewPresence 创建在线状态推送，rdb 或 router 为 nil 时返回 nil（不推送在线状态）。
+func NewPresence(rdb *redis.Client, router presenceRouter, cfg PresenceConfig) *Presence {
+	if rdb == nil || router == nil {
+		return nil
+	}
+	defaults := DefaultPresenceConfig()
+	if cfg.OnlineDelay <= 0 {
+		cfg.OnlineDelay = defaults.OnlineDelay
+	}
+	if cfg.OfflineGrace <= 0 {
+		cfg.OfflineGrace = defaults.OfflineGrace
+	}
+	return &Presence{
+		rdb:    rdb,
+		router: router,
+		cfg:    cfg,
+		timers: make(map[string]*time.Timer),
+	}
+}
+
+// OnUserOnline 用户首个设备连接到本节点。
+func (p *Presence) OnUserOnline(userUUID string) {
+	p.schedule(userUUID, p.cfg.OnlineDelay)
+}
+
+// OnUserOffline 用户最后一个设备从本节点断开。
+func (p *Presence) OnUserOffline(userUUID string) {
+	p.schedule(userUUID, p.cfg.OfflineGrace)
+}
+
+// Stop 取消所有未触发的结算，停机时调用。
+func (p *Presence) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stopped = true
+	for userUUID, timer := range p.timers {
+		timer.Stop()
+		delete(p.timers, userUUID)
+	}
+}
+
+// schedule 登记（或覆盖）用户的结算定时器。
+func (p *Presence) schedule(userUUID string, delay time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopped {
+		return
+	}
+	if timer, ok := p.timers[userUUID]; ok {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		p.mu.Lock()
+		// 已被新事件覆盖或已停止时放弃本次结算。
+		if p.timers[userUUID] != timer {
+			p.mu.Unlock()
+			return
+		}
+		delete(p.timers, userUUID)
+		p.mu.Unlock()
+
+		p.settle(userUUID)
+	})
+	p.timers[userUUID] = timer
+}
+
+// settle 结算用户全局在线状态，状态变化时推送给好友。
+func (p *Presence) settle(userUUID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), presenceOpTimeout)
+	defer cancel()
+
+	devices, err := p.router.Lookup(ctx, userUUID)
+	if err != nil {
+		p.logWarn(ctx, "查询用户在线路由失败，跳过在线状态推送", userUUID, err)
+		return
+	}
+	online := len(devices) > 0
+
+	value := "0"
+	if online {
+		value = "1"
+	}
+	prev, err := p.rdb.SetArgs(ctx, rediskey.ConnectPresenceKey(userUUID), value, redis.SetArgs{
+		Get: true,
+		TTL: rediskey.ConnectPresenceTTL,
+	}).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		p.logWarn(ctx, "写入在线状态失败，跳过在线状态推送", userUUID, err)
+		return
+	}
+	// 状态未变化时不推送。记录缺失（从未推送或已过期，例如连续在线超过 TTL）视为未知，
+	// 照常推送：重复推送同一状态对客户端无副作用，漏推下线则会让好友一直看到绿点。
+	if prev == value {
+		return
+	}
+
+	p.publish(ctx, userUUID, online)
+}
+
+// publish 推送在线状态给未拉黑双方的好友，离线好友由 Router 按路由自然跳过。
+func (p *Presence) publish(ctx context.Context, userUUID string, online bool) {
+	targets, err := p.audience(ctx, userUUID)
+	if err != nil {
+		p.logWarn(ctx, "解析在线状态推送对象失败", userUUID, err)
+		return
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	data, err := json.Marshal(PresenceData{UserUUID: userUUID, Online: online, Ts: now})
+	if err != nil {
+		p.logWarn(ctx, "序列化在线状态失败", userUUID, err)
+		return
+	}
+	result := fanout.BroadcastInChunks(ctx, p.router, targets, &pb.MessageEnvelope{
+		Type:     presenceFrameType,
+		Data:     data,
+		ServerTs: now,
+	}, 0)
+	if result.Err != nil {
+		p.logWarn(ctx, "推送在线状态失败", userUUID, result.Err)
+		return
+	}
+	logger.Debug(ctx, "推送好友在线状态",
+		logger.String("user_uuid", userUUID),
+		logger.Bool("online", online),
+		logger.Int("friend_count", len(targets)),
+		logger.Int("delivered", result.TotalDelivered),
+	)
+}
+
+// audience 从好友关系缓存解析推送对象，剔除：
+// - 把用户拉黑的好友（对方黑名单，推送目标的意愿优先）；
+// - 被用户拉黑的好友（不向被拉黑者暴露自己的在线状态）。
+func (p *Presence) audience(ctx context.Context, userUUID string) ([]string, error) {
+	cache := relationCache{rdb: p.rdb}
+	hit, friends, err := cache.friends(ctx, userUUID)
+	if err != nil || !hit || len(friends) == 0 {
+		return nil, err
+	}
+
+	blocked, err := cache.blockedEither(ctx, userUUID, friends)
+	if err != nil {
+		return nil, err
+	}
+	targets := friends[:0]
+	for i, friendUUID := range friends {
+		if !blocked[i] {
+			targets = append(targets, friendUUID)
+		}
+	}
+	return targets, nil
+}
+
+func (p *Presence) logWarn(ctx context.Context, msg, userUUID string, err error) {
+	logger.Warn(ctx, msg,
+		logger.String("user_uuid", userUUID),
+		logger.ErrorField("error", err),
+	)
+}
@@ -0,0 +1,171 @@
+package svc
+
+import (
+	"ChatServer/apps/connect/pb"
+	rediskey "ChatServer/consts/redisKey"
+	"ChatServer/pkg/logger"
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+var connectPresenceLoggerOnce sync.Once
+
+func initConnectPresenceLogger() {
+	connectPresenceLoggerOnce.Do(func() {
+		logger.ReplaceGlobal(zap.NewNop())
+	})
+}
+
+// fakePresenceRouter 按内存中的在线表应答 Lookup，并记录推送。
+type fakePresenceRouter struct {
+	mu     sync.Mutex
+	online map[string]bool
+	pushes []presencePush
+}
+
+type presencePush struct {
+	targets []string
+	data    PresenceData
+}
+
+func (r *fakePresenceRouter) setOnline(userUUID string, online bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.online[userUUID] = online
+}
+
+func (r *fakePresenceRouter) Lookup(_ context.Context, userUUID string) (map[string]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.online[userUUID] {
+		return map[string]string{"d1": "node-1"}, nil
+	}
+	return map[string]string{}, nil
+}
+
+func (r *fakePresenceRouter) BroadcastToUsers(_ context.Context, in *pb.BroadcastToUsersRequest, _ ...grpc.CallOption) (*pb.BroadcastToUsersResponse, error) {
+	var data PresenceData
+	if err := json.Unmarshal(in.GetMessage().GetData(), &data); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pushes = append(r.pushes, presencePush{targets: append([]string(nil), in.GetUserUuids()...), data: data})
+	return &pb.BroadcastToUsersResponse{SuccessCount: int32(len(in.GetUserUuids()))}, nil
+}
+
+func (r *fakePresenceRouter) history() []presencePush {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]presencePush(nil), r.pushes...)
+}
+
+func newTestPresence(t *testing.T, cfg PresenceConfig) (*Presence, *fakePresenceRouter, *redis.Client) {
+	t.Helper()
+	initConnectPresenceLogger()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	router := &fakePresenceRouter{online: make(map[string]bool)}
+	presence := NewPresence(rdb, router, cfg)
+	t.Cleanup(presence.Stop)
+	return presence, router, rdb
+}
+
+func seedFriends(t *testing.T, rdb *redis.Client, userUUID string, friends ...string) {
+	t.Helper()
+	values := []any{friendCacheEmptyField, "1"}
+	for _, friendUUID := range friends {
+		values = append(values, friendUUID, "{}")
+	}
+	require.NoError(t, rdb.HSet(context.Background(), rediskey.FriendRelationKey(userUUID), values...).Err())
+}
+
+func seedBlacklist(t *testing.T, rdb *redis.Client, userUUID string, targets ...string) {
+	t.Helper()
+	members := []redis.Z{{Score: 0, Member: friendCacheEmptyField}}
+	for _, targetUUID := range targets {
+		members = append(members, redis.Z{Score: float64(time.Now().Unix()), Member: targetUUID})
+	}
+	require.NoError(t, rdb.ZAdd(context.Background(), rediskey.BlacklistRelationKey(userUUID), members...).Err())
+}
+
+func TestPresenceHysteresis(t *testing.T) {
+	presence, router, rdb := newTestPresence(t, PresenceConfig{
+		OnlineDelay:  10 * time.Millisecond,
+		OfflineGrace: 150 * time.Millisecond,
+	})
+	seedFriends(t, rdb, "alice", "bob")
+
+	router.setOnline("alice", true)
+	presence.OnUserOnline("alice")
+	require.Eventually(t, func() bool { return len(router.history()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.True(t, router.history()[0].data.Online)
+
+	// 宽限内重连：覆盖未触发的下线结算，不产生任何推送。
+	router.setOnline("alice", false)
+	presence.OnUserOffline("alice")
+	time.Sleep(30 * time.Millisecond)
+	router.setOnline("alice", true)
+	presence.OnUserOnline("alice")
+	time.Sleep(250 * time.Millisecond)
+	assert.Len(t, router.history(), 1)
+
+	// 超过宽限仍未重连：推送下线。
+	router.setOnline("alice", false)
+	presence.OnUserOffline("alice")
+	require.Eventually(t, func() bool { return len(router.history()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.False(t, router.history()[1].data.Online)
+}
+
+func TestPresenceSettleSkipsUnchangedState(t *testing.T) {
+	presence, router, rdb := newTestPresence(t, PresenceConfig{})
+	seedFriends(t, rdb, "alice", "bob")
+
+	router.setOnline("alice", true)
+	presence.settle("alice")
+	presence.settle("alice")
+	require.Len(t, router.history(), 1)
+
+	// 记录过期（连续在线超过 TTL）后下线仍要推送。
+	require.NoError(t, rdb.Del(context.Background(), rediskey.ConnectPresenceKey("alice")).Err())
+	router.setOnline("alice", false)
+	presence.settle("alice")
+	pushes := router.history()
+	require.Len(t, pushes, 2)
+	assert.False(t, pushes[1].data.Online)
+	assert.Equal(t, "alice", pushes[1].data.UserUUID)
+}
+
+func TestPresenceAudienceFiltersBlacklist(t *testing.T) {
+	presence, router, rdb := newTestPresence(t, PresenceConfig{})
+	seedFriends(t, rdb, "alice", "bob", "carol", "dave")
+	seedBlacklist(t, rdb, "alice", "carol")
+	seedBlacklist(t, rdb, "dave", "alice")
+	seedBlacklist(t, rdb, "bob")
+
+	router.setOnline("alice", true)
+	presence.settle("alice")
+	pushes := router.history()
+	require.Len(t, pushes, 1)
+	assert.Equal(t, []string{"bob"}, pushes[0].targets)
+}
+
+func TestPresenceAudienceCacheMiss(t *testing.T) {
+	presence, router, _ := newTestPresence(t, PresenceConfig{})
+
+	router.setOnline("alice", true)
+	presence.settle("alice")
+	assert.Empty(t, router.history())
+}
@@ -0,0 +1,88 @@
+package svc
+
+import (
+	rediskey "ChatServer/consts/redisKey"
+	"context"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// friendCacheEmptyField 关系缓存空值占位（与 user 服务一致）。
+const friendCacheEmptyField = "__EMPTY__"
+
+// relationCache 只读访问 user 服务维护的关系缓存（不回源、不重建）：
+// - 好友：Hash user:relation:friend:{uuid}，field 为好友 UUID，无好友时只有 __EMPTY__ 占位；
+// - 黑名单：ZSet user:relation:blacklist:{uuid}，member 为被拉黑的 UUID，无拉黑时只有 __EMPTY__ 占位。
+// 缓存 Key 不存在表示未命中（未知），由调用方决定回源或放弃。
+type relationCache struct {
+	rdb *redis.Client
+}
+
+// isFriend 返回 userUUID 的好友缓存是否命中，以及 peerUUID 是否在其中。
+func (r relationCache) isFriend(ctx context.Context, userUUID, peerUUID string) (hit bool, friend bool, err error) {
+	key := rediskey.FriendRelationKey(userUUID)
+	pipe := r.rdb.Pipeline()
+	exists := pipe.Exists(ctx, key)
+	member := pipe.HExists(ctx, key, peerUUID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, false, err
+	}
+	if exists.Val() == 0 {
+		return false, false, nil
+	}
+	return true, member.Val(), nil
+}
+
+// isBlocked 返回 userUUID 的黑名单缓存是否命中，以及 userUUID 是否拉黑了 peerUUID。
+func (r relationCache) isBlocked(ctx context.Context, userUUID, peerUUID string) (hit bool, blocked bool, err error) {
+	key := rediskey.BlacklistRelationKey(userUUID)
+	pipe := r.rdb.Pipeline()
+	exists := pipe.Exists(ctx, key)
+	score := pipe.ZScore(ctx, key, peerUUID)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return false, false, err
+	}
+	if exists.Val() == 0 {
+		return false, false, nil
+	}
+	return true, score.Err() == nil, nil
+}
+
+// friends 返回 userUUID 的好友缓存是否命中，以及好友 UUID 列表（已剔除空值占位）。
+func (r relationCache) friends(ctx context.Context, userUUID string) (hit bool, friends []string, err error) {
+	fields, err := r.rdb.HKeys(ctx, rediskey.FriendRelationKey(userUUID)).Result()
+	if err != nil {
+		return false, nil, err
+	}
+	if len(fields) == 0 {
+		return false, nil, nil
+	}
+	friends = make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field != friendCacheEmptyField {
+			friends = append(friends, field)
+		}
+	}
+	return true, friends, nil
+}
+
+// blockedEither 批量判断 userUUID 与每个 peer 之间是否存在任一方向的拉黑，结果与 peers 一一对应。
+// 黑名单缓存未命中按未拉黑处理，适用于尽力而为的场景（如在线状态推送）。
+func (r relationCache) blockedEither(ctx context.Context, userUUID string, peers []string) ([]bool, error) {
+	pipe := r.rdb.Pipeline()
+	blocking := make([]*redis.FloatCmd, len(peers))
+	blockedBy := make([]*redis.FloatCmd, len(peers))
+	for i, peerUUID := range peers {
+		blocking[i] = pipe.ZScore(ctx, rediskey.BlacklistRelationKey(userUUID), peerUUID)
+		blockedBy[i] = pipe.ZScore(ctx, rediskey.BlacklistRelationKey(peerUUID), userUUID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	blocked := make([]bool, len(peers))
+	for i := range peers {
+		blocked[i] = blocking[i].Err() == nil || blockedBy[i].Err() == nil
+	}
+	return blocked, nil
+}
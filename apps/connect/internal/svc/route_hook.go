@@ -0,0 +1,52 @@
+package svc
+
+import (
+	"ChatServer/pkg/connectroute"
+	"ChatServer/pkg/logger"
+	"context"
+)
+
+// RouteHook 将连接索引变化同步到集群路由注册表（实现 manager.RouteHook）。
+// 路由写入失败只记录日志：本节点连接仍可用，仅跨节点投递会暂时找不到该设备，
+// 下一次心跳会补写路由。
+type RouteHook struct {
+	registry *connectroute.Registry
+}
+
+// NewRouteHook 创建路由回调，registry 为 nil 时返回 nil（单节点模式）。
+func NewRouteHook(registry *connectroute.Registry) *RouteHook {
+	if registry == nil {
+		return nil
+	}
+	return &RouteHook{registry: registry}
+}
+
+// OnRegister 登记设备路由。
+func (h *RouteHook) OnRegister(userUUID, deviceID string) {
+	if err := h.registry.Register(context.Background(), userUUID, deviceID); err != nil {
+		h.logError("登记连接路由失败", userUUID, deviceID, err)
+	}
+}
+
+// OnUnregister 注销设备路由。
+func (h *RouteHook) OnUnregister(userUUID, deviceID string) {
+	if err := h.registry.Unregister(context.Background(), userUUID, deviceID); err != nil {
+		h.logError("注销连接路由失败", userUUID, deviceID, err)
+	}
+}
+
+// OnHeartbeat 续期设备路由。
+func (h *RouteHook) OnHeartbeat(userUUID, deviceID string) {
+	if err := h.registry.Refresh(context.Background(), userUUID, deviceID); err != nil {
+		h.logError("续期连接路由失败", userUUID, deviceID, err)
+	}
+}
+
+func (h *RouteHook) logError(msg, userUUID, deviceID string, err error) {
+	logger.Warn(context.Background(), msg,
+		logger.String("user_uuid", userUUID),
+		logger.String("device_id", deviceID),
+		logger.String("node_id", h.registry.Node().ID),
+		logger.ErrorField("error", err),
+	)
+}
@@ -0,0 +1,49 @@
+package svc
+
+import (
+	"ChatServer/pkg/connectroute"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// maxTopicsPerFrame 单个 subscribe/unsubscribe 帧最多携带的主题数。
+const maxTopicsPerFrame = 32
+
+// ErrTopicInvalid 表示订阅的主题为空、为保留主题或格式不合法。
+var ErrTopicInvalid = errors.New("topic is invalid")
+
+// TopicsData 定义 type=subscribe / type=unsubscribe 上行帧，以及 subscribe_ok / unsubscribe_ok 下行帧的 data 结构。
+// 下行帧中 topics 为本次实际新增/退订的主题。
+type TopicsData struct {
+	Topics []string `json:"topics"`
+}
+
+// AutoTopics 计算连接握手时自动加入的主题（用户、平台、App 版本），全员主题 all 隐式生效无需加入。
+func AutoTopics(session *Session) []string {
+	return connectroute.AutoTopics(session.UserUUID, session.Platform, session.AppVersion)
+}
+
+// ParseTopics 解析 subscribe/unsubscribe 上行帧中的主题列表。
+// 任一主题为保留主题（all/platform:/app:/user:）或格式非法时整体拒绝（ErrTopicInvalid）。
+func (s *ConnectService) ParseTopics(data json.RawMessage) ([]string, error) {
+	if len(data) == 0 {
+		return nil, ErrTopicInvalid
+	}
+	var payload TopicsData
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+	if len(payload.Topics) == 0 || len(payload.Topics) > maxTopicsPerFrame {
+		return nil, ErrTopicInvalid
+	}
+	topics := make([]string, 0, len(payload.Topics))
+	for _, topic := range payload.Topics {
+		topic = strings.TrimSpace(topic)
+		if !connectroute.ValidSubscribeTopic(topic) {
+			return nil, ErrTopicInvalid
+		}
+		topics = append(topics, topic)
+	}
+	return topics, nil
+}
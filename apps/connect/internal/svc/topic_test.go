@@ -0,0 +1,62 @@
+package svc
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTopics(t *testing.T) {
+	s := &ConnectService{}
+	tooMany := make([]string, maxTopicsPerFrame+1)
+	for i := range tooMany {
+		tooMany[i] = "t" + strconv.Itoa(i)
+	}
+	encode := func(topics []string) json.RawMessage {
+		raw, err := json.Marshal(TopicsData{Topics: topics})
+		require.NoError(t, err)
+		return raw
+	}
+
+	tests := []struct {
+		name    string
+		data    json.RawMessage
+		want    []string
+		wantErr error
+	}{
+		{name: "trimmed", data: encode([]string{" live:1 ", "news"}), want: []string{"live:1", "news"}},
+		{name: "frame_limit", data: encode(tooMany[:maxTopicsPerFrame]), want: tooMany[:maxTopicsPerFrame]},
+		{name: "too_many", data: encode(tooMany), wantErr: ErrTopicInvalid},
+		{name: "empty_list", data: encode(nil), wantErr: ErrTopicInvalid},
+		{name: "no_data", wantErr: ErrTopicInvalid},
+		{name: "reserved_all", data: encode([]string{"news", "all"}), wantErr: ErrTopicInvalid},
+		{name: "reserved_user", data: encode([]string{"user:bob"}), wantErr: ErrTopicInvalid},
+		{name: "reserved_platform", data: encode([]string{"platform:ios"}), wantErr: ErrTopicInvalid},
+		{name: "reserved_app", data: encode([]string{"app:ios:2"}), wantErr: ErrTopicInvalid},
+		{name: "blank", data: encode([]string{"  "}), wantErr: ErrTopicInvalid},
+		{name: "too_long", data: encode([]string{strings.Repeat("a", 129)}), wantErr: ErrTopicInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topics, err := s.ParseTopics(tt.data)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, topics)
+		})
+	}
+
+	_, err := s.ParseTopics(json.RawMessage(`{"topics":"news"}`))
+	assert.Error(t, err)
+}
+
+func TestSessionAutoTopics(t *testing.T) {
+	session := &Session{UserUUID: "alice", Platform: "android", AppVersion: "v2.3"}
+	assert.Equal(t, []string{"user:alice", "platform:android", "app:android:2", "app:android:2.3"}, AutoTopics(session))
+}
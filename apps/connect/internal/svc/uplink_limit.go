@@ -0,0 +1,270 @@
+package svc
+
+import (
+	rediskey "ChatServer/consts/redisKey"
+	"ChatServer/pkg/logger"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// uplinkDefaultType 未单独配置限速的帧类型共用的配置键。
+const uplinkDefaultType = "default"
+
+// ErrBanned 表示用户或 IP 因上行滥用处于临时封禁期。
+var ErrBanned = errors.New("connection is temporarily banned")
+
+// RateSpec 令牌桶参数：每秒补充 Rate 个令牌，桶容量 Burst。
+type RateSpec struct {
+	Rate  float64
+	Burst int
+}
+
+// UplinkLimitConfig 上行限速与滥用升级策略。
+// 限速按帧类型配置（heartbeat/message/typing...），未配置的类型使用 "default"，均未配置时不限速。
+// 升级策略：
+// 1. 超限帧被丢弃并回 error 帧；
+// 2. ViolationWindow 内超限次数达到 CloseAfter 时临时断开（关闭码 4006）；
+// 3. BanWindow 内被临时断开次数达到 BanAfter 时写入 Redis 临时封禁（BanDuration），
+// 封禁期间握手直接拒绝（BanByIP 时同时封禁 IP，握手限流中间件也会拦截）。
+type UplinkLimitConfig struct {
+	// ConnLimits 单连接按帧类型的限速。
+	ConnLimits map[string]RateSpec
+	// UserLimits 单用户（本节点上该用户全部连接合计）按帧类型的限速。
+	UserLimits map[string]RateSpec
+	// ViolationWindow 超限计数窗口。
+	ViolationWindow time.Duration
+	// CloseAfter 窗口内超限次数达到该值时断开连接（<=0 不断开）。
+	CloseAfter int
+	// BanAfter BanWindow 内被断开次数达到该值时封禁（<=0 不封禁）。
+	BanAfter int
+	// BanWindow 断开次数计数窗口。
+	BanWindow time.Duration
+	// BanDuration 封禁时长。
+	BanDuration time.Duration
+	// BanByIP 封禁时是否同时封禁客户端 IP（NAT 出口下可能误伤同出口用户，默认关闭）。
+	BanByIP bool
+}
+
+// UplinkVerdict 上行帧限速判定结果。
+type UplinkVerdict int
+
+const (
+	// UplinkAllow 放行。
+	UplinkAllow UplinkVerdict = iota
+	// UplinkReject 丢弃该帧并回 error 帧。
+	UplinkReject
+	// UplinkClose 超限次数过多，临时断开连接。
+	UplinkClose
+)
+
+// uplinkLimiter 管理按用户共享的令牌桶，单连接令牌桶挂在 Session 上。
+type uplinkLimiter struct {
+	cfg UplinkLimitConfig
+
+	mu    sync.Mutex
+	users map[string]*userUplink
+}
+
+// userUplink 用户级令牌桶，按本节点连接数引用计数，最后一条连接断开时回收。
+type userUplink struct {
+	limiters map[string]*rate.Limiter
+	refs     int
+}
+
+// sessionUplink 单连接的限速状态。
+type sessionUplink struct {
+	conn map[string]*rate.Limiter
+	user *userUplink
+
+	mu          sync.Mutex
+	windowStart time.Time
+	violations  int
+	// closing 已判定断开，断开生效前读到的后续帧只丢弃，不重复累计断开次数。
+	closing bool
+}
+
+// newUplinkLimiter 创建上行限速器，未配置任何限速时返回 nil（不限速）。
+func newUplinkLimiter(cfg UplinkLimitConfig) *uplinkLimiter {
+	if len(cfg.ConnLimits) == 0 && len(cfg.UserLimits) == 0 {
+		return nil
+	}
+	if cfg.ViolationWindow <= 0 {
+		cfg.ViolationWindow = time.Minute
+	}
+	if cfg.BanWindow <= 0 {
+		cfg.BanWindow = 10 * time.Minute
+	}
+	if cfg.BanDuration <= 0 {
+		cfg.BanDuration = 10 * time.Minute
+	}
+	return &uplinkLimiter{
+		cfg:   cfg,
+		users: make(map[string]*userUplink),
+	}
+}
+
+// newLimiters 按配置创建一组令牌桶，Rate/Burst 非法的项忽略。
+func newLimiters(specs map[string]RateSpec) map[string]*rate.Limiter {
+	limiters := make(map[string]*rate.Limiter, len(specs))
+	for msgType, spec := range specs {
+		if spec.Rate <= 0 || spec.Burst <= 0 {
+			continue
+		}
+		limiters[msgType] = rate.NewLimiter(rate.Limit(spec.Rate), spec.Burst)
+	}
+	return limiters
+}
+
+// limiterFor 返回帧类型对应的令牌桶，未单独配置时使用 default。
+func limiterFor(limiters map[string]*rate.Limiter, msgType string) *rate.Limiter {
+	if limiter, ok := limiters[msgType]; ok {
+		return limiter
+	}
+	return limiters[uplinkDefaultType]
+}
+
+// attach 为连接创建限速状态并引用用户级令牌桶。
+func (l *uplinkLimiter) attach(userUUID string) *sessionUplink {
+	l.mu.Lock()
+	user, ok := l.users[userUUID]
+	if !ok {
+		user = &userUplink{limiters: newLimiters(l.cfg.UserLimits)}
+		l.users[userUUID] = user
+	}
+	user.refs++
+	l.mu.Unlock()
+
+	return &sessionUplink{
+		conn: newLimiters(l.cfg.ConnLimits),
+		user: user,
+	}
+}
+
+// detach 释放用户级令牌桶引用。
+func (l *uplinkLimiter) detach(userUUID string, uplink *sessionUplink) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if user, ok := l.users[userUUID]; ok && user == uplink.user {
+		user.refs--
+		if user.refs <= 0 {
+			delete(l.users, userUUID)
+		}
+	}
+}
+
+// allow 按连接级、用户级令牌桶判定，超限时累计违规次数决定是否升级为断开。
+func (l *uplinkLimiter) allow(uplink *sessionUplink, msgType string, now time.Time) UplinkVerdict {
+	connLimiter := limiterFor(uplink.conn, msgType)
+	userLimiter := limiterFor(uplink.user.limiters, msgType)
+	if (connLimiter == nil || connLimiter.AllowN(now, 1)) && (userLimiter == nil || userLimiter.AllowN(now, 1)) {
+		return UplinkAllow
+	}
+
+	uplink.mu.Lock()
+	if now.Sub(uplink.windowStart) > l.cfg.ViolationWindow {
+		uplink.windowStart = now
+		uplink.violations = 0
+	}
+	uplink.violations++
+	verdict := UplinkReject
+	if l.cfg.CloseAfter > 0 && uplink.violations >= l.cfg.CloseAfter && !uplink.closing {
+		uplink.closing = true
+		verdict = UplinkClose
+	}
+	uplink.mu.Unlock()
+
+	return verdict
+}
+
+// CheckUplink 判定上行帧是否放行，未启用限速或连接未登记时总是放行。
+func (s *ConnectService) CheckUplink(session *Session, msgType string) UplinkVerdict {
+	uplink := session.uplink.Load()
+	if s.uplink == nil || uplink == nil {
+		return UplinkAllow
+	}
+	return s.uplink.allow(uplink, msgType, time.Now())
+}
+
+// StrikeUplink 记录一次因上行超限导致的临时断开，BanWindow 内达到 BanAfter 次时写入临时封禁。
+// 返回 true 表示本次触发了封禁。Redis 不可用时只断开、不封禁。
+func (s *ConnectService) StrikeUplink(ctx context.Context, session *Session) bool {
+	if s.uplink == nil || s.uplink.cfg.BanAfter <= 0 || s.redisClient == nil {
+		return false
+	}
+	cfg := s.uplink.cfg
+
+	strikeKey := rediskey.ConnectUplinkStrikeKey(session.UserUUID)
+	strikes, err := s.redisClient.Incr(ctx, strikeKey).Result()
+	if err != nil {
+		logger.Warn(ctx, "记录上行超限断开次数失败",
+			logger.String("user_uuid", session.UserUUID),
+			logger.ErrorField("error", err),
+		)
+		return false
+	}
+	if strikes == 1 {
+		_ = s.redisClient.Expire(ctx, strikeKey, cfg.BanWindow).Err()
+	}
+	if strikes < int64(cfg.BanAfter) {
+		return false
+	}
+
+	pipe := s.redisClient.TxPipeline()
+	pipe.Set(ctx, rediskey.ConnectUplinkBanUserKey(session.UserUUID), session.DeviceID, cfg.BanDuration)
+	if cfg.BanByIP && session.ClientIP != "" {
+		pipe.Set(ctx, rediskey.ConnectUplinkBanIPKey(session.ClientIP), session.UserUUID, cfg.BanDuration)
+	}
+	pipe.Del(ctx, strikeKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.Warn(ctx, "写入上行滥用封禁失败",
+			logger.String("user_uuid", session.UserUUID),
+			logger.ErrorField("error", err),
+		)
+		return false
+	}
+	logger.Warn(ctx, "上行滥用，临时封禁用户",
+		logger.String("user_uuid", session.UserUUID),
+		logger.String("device_id", session.DeviceID),
+		logger.String("client_ip", session.ClientIP),
+		logger.Bool("ban_ip", cfg.BanByIP),
+		logger.Duration("ban_duration", cfg.BanDuration),
+	)
+	return true
+}
+
+// IPBanned 检查 IP 是否处于上行滥用封禁期，供握手限流中间件使用。
+// 未开启按 IP 封禁或 Redis 异常时放行（Fail-Open）。
+func (s *ConnectService) IPBanned(ctx context.Context, ip string) bool {
+	if s.uplink == nil || !s.uplink.cfg.BanByIP {
+		return false
+	}
+	return s.banned(ctx, rediskey.ConnectUplinkBanIPKey(ip))
+}
+
+// userBanned 检查用户是否处于上行滥用封禁期。
+func (s *ConnectService) userBanned(ctx context.Context, userUUID string) bool {
+	if s.uplink == nil || s.uplink.cfg.BanAfter <= 0 {
+		return false
+	}
+	return s.banned(ctx, rediskey.ConnectUplinkBanUserKey(userUUID))
+}
+
+func (s *ConnectService) banned(ctx context.Context, key string) bool {
+	if s.redisClient == nil {
+		return false
+	}
+	n, err := s.redisClient.Exists(ctx, key).Result()
+	if err != nil {
+		logger.Warn(ctx, "查询上行滥用封禁失败，降级放行",
+			logger.String("key", key),
+			logger.ErrorField("error", err),
+		)
+		return false
+	}
+	return n > 0
+}
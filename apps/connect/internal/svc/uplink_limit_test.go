@@ -0,0 +1,163 @@
+package svc
+
+import (
+	rediskey "ChatServer/consts/redisKey"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUplinkLimiterDisabled(t *testing.T) {
+	assert.Nil(t, newUplinkLimiter(UplinkLimitConfig{}))
+
+	s := NewConnectService(nil, nil, nil, UplinkLimitConfig{})
+	session := &Session{UserUUID: "alice", DeviceID: "a1"}
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, UplinkAllow, s.CheckUplink(session, "message"))
+	}
+}
+
+func TestUplinkLimiterAllow(t *testing.T) {
+	l := newUplinkLimiter(UplinkLimitConfig{
+		ConnLimits: map[string]RateSpec{
+			"message":         {Rate: 1, Burst: 2},
+			uplinkDefaultType: {Rate: 1, Burst: 1},
+			"broken":          {Rate: 0, Burst: 5},
+		},
+		ViolationWindow: time.Second,
+		CloseAfter:      3,
+	})
+	uplink := l.attach("alice")
+	now := time.Now()
+
+	// 按类型独立计数，未配置的类型使用 default，非法配置项同样落到 default。
+	assert.Equal(t, UplinkAllow, l.allow(uplink, "message", now))
+	assert.Equal(t, UplinkAllow, l.allow(uplink, "message", now))
+	assert.Equal(t, UplinkAllow, l.allow(uplink, "typing", now))
+	assert.Equal(t, UplinkReject, l.allow(uplink, "message", now))
+	assert.Equal(t, UplinkReject, l.allow(uplink, "broken", now))
+
+	// 窗口内第 CloseAfter 次超限升级为断开，之后只丢弃不重复断开。
+	assert.Equal(t, UplinkClose, l.allow(uplink, "typing", now))
+	assert.Equal(t, UplinkReject, l.allow(uplink, "typing", now))
+
+	// 令牌补充后放行。
+	assert.Equal(t, UplinkAllow, l.allow(uplink, "message", now.Add(time.Second)))
+}
+
+func TestUplinkLimiterViolationWindow(t *testing.T) {
+	l := newUplinkLimiter(UplinkLimitConfig{
+		ConnLimits:      map[string]RateSpec{uplinkDefaultType: {Rate: 0.001, Burst: 1}},
+		ViolationWindow: time.Second,
+		CloseAfter:      2,
+	})
+	uplink := l.attach("alice")
+	now := time.Now()
+
+	require.Equal(t, UplinkAllow, l.allow(uplink, "message", now))
+	assert.Equal(t, UplinkReject, l.allow(uplink, "message", now))
+	// 超出窗口后违规计数重新开始。
+	assert.Equal(t, UplinkReject, l.allow(uplink, "message", now.Add(2*time.Second)))
+	assert.Equal(t, UplinkClose, l.allow(uplink, "message", now.Add(2*time.Second)))
+}
+
+func TestUplinkLimiterSharedUserBucket(t *testing.T) {
+	l := newUplinkLimiter(UplinkLimitConfig{
+		UserLimits: map[string]RateSpec{"message": {Rate: 1, Burst: 2}},
+	})
+	a1 := l.attach("alice")
+	a2 := l.attach("alice")
+	bob := l.attach("bob")
+	now := time.Now()
+
+	// 同一用户的多条连接共用用户级令牌桶。
+	assert.Equal(t, UplinkAllow, l.allow(a1, "message", now))
+	assert.Equal(t, UplinkAllow, l.allow(a2, "message", now))
+	assert.Equal(t, UplinkReject, l.allow(a1, "message", now))
+	assert.Equal(t, UplinkAllow, l.allow(bob, "message", now))
+	// 未配置用户级限速的类型不限。
+	assert.Equal(t, UplinkAllow, l.allow(a1, "typing", now))
+
+	// 最后一条连接断开后回收，重新连接拿到新桶。
+	l.detach("alice", a1)
+	require.Contains(t, l.users, "alice")
+	l.detach("alice", a2)
+	assert.NotContains(t, l.users, "alice")
+	assert.Equal(t, UplinkAllow, l.allow(l.attach("alice"), "message", now))
+}
+
+func newUplinkTestService(t *testing.T, cfg UplinkLimitConfig) (*ConnectService, *miniredis.Miniredis) {
+	t.Helper()
+	initConnectPresenceLogger()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	return NewConnectService(rdb, nil, nil, cfg), mr
+}
+
+func TestStrikeUplinkBans(t *testing.T) {
+	ctx := context.Background()
+	s, mr := newUplinkTestService(t, UplinkLimitConfig{
+		ConnLimits:  map[string]RateSpec{uplinkDefaultType: {Rate: 1, Burst: 1}},
+		BanAfter:    2,
+		BanWindow:   time.Minute,
+		BanDuration: 5 * time.Minute,
+		BanByIP:     true,
+	})
+	session := &Session{UserUUID: "alice", DeviceID: "a1", ClientIP: "10.0.0.1"}
+	strikeKey := rediskey.ConnectUplinkStrikeKey("alice")
+
+	assert.False(t, s.StrikeUplink(ctx, session))
+	assert.Equal(t, time.Minute, mr.TTL(strikeKey))
+	assert.False(t, s.userBanned(ctx, "alice"))
+	assert.False(t, s.IPBanned(ctx, "10.0.0.1"))
+
+	assert.True(t, s.StrikeUplink(ctx, session))
+	assert.False(t, mr.Exists(strikeKey))
+	assert.Equal(t, 5*time.Minute, mr.TTL(rediskey.ConnectUplinkBanUserKey("alice")))
+	assert.True(t, s.userBanned(ctx, "alice"))
+	assert.True(t, s.IPBanned(ctx, "10.0.0.1"))
+	assert.False(t, s.IPBanned(ctx, "10.0.0.2"))
+
+	// 封禁到期后恢复。
+	mr.FastForward(5 * time.Minute)
+	assert.False(t, s.userBanned(ctx, "alice"))
+	assert.False(t, s.IPBanned(ctx, "10.0.0.1"))
+}
+
+func TestStrikeUplinkWithoutIPBan(t *testing.T) {
+	ctx := context.Background()
+	s, mr := newUplinkTestService(t, UplinkLimitConfig{
+		ConnLimits: map[string]RateSpec{uplinkDefaultType: {Rate: 1, Burst: 1}},
+		BanAfter:   1,
+	})
+	session := &Session{UserUUID: "alice", DeviceID: "a1", ClientIP: "10.0.0.1"}
+
+	assert.True(t, s.StrikeUplink(ctx, session))
+	assert.True(t, s.userBanned(ctx, "alice"))
+	// 未开启 BanByIP 时不写、也不检查 IP 封禁。
+	assert.False(t, mr.Exists(rediskey.ConnectUplinkBanIPKey("10.0.0.1")))
+	require.NoError(t, mr.Set(rediskey.ConnectUplinkBanIPKey("10.0.0.1"), "bob"))
+	assert.False(t, s.IPBanned(ctx, "10.0.0.1"))
+}
+
+func TestStrikeUplinkRedisDown(t *testing.T) {
+	ctx := context.Background()
+	s, mr := newUplinkTestService(t, UplinkLimitConfig{
+		ConnLimits: map[string]RateSpec{uplinkDefaultType: {Rate: 1, Burst: 1}},
+		BanAfter:   1,
+		BanByIP:    true,
+	})
+	session := &Session{UserUUID: "alice", DeviceID: "a1", ClientIP: "10.0.0.1"}
+
+	// Redis 不可用时只断开不封禁，封禁检查降级放行。
+	mr.SetError("ERR redis unavailable")
+	assert.False(t, s.StrikeUplink(ctx, session))
+	assert.False(t, s.userBanned(ctx, "alice"))
+	assert.False(t, s.IPBanned(ctx, "10.0.0.1"))
+}
@@ -212,6 +212,13 @@ func main() {
 	deviceService := service.NewDeviceService(userClient)
 	logger.Info(ctx, "设备服务初始化完成")
 
+	connectTicketCfg := config.DefaultConnectTicketConfig()
+	connectService := service.NewConnectService(redisClient, connectTicketCfg)
+	logger.Info(ctx, "长连接接入服务初始化完成",
+		logger.Duration("ticket_ttl", connectTicketCfg.TTL),
+		logger.Bool("ticket_bind_ip", connectTicketCfg.BindIP),
+	)
+
 	// 7. 初始化 Handler 层（依赖注入）
 	authHandler := v1.NewAuthHandler(authService)
 	logger.Info(ctx, "认证处理器初始化完成")
@@ -228,6 +235,9 @@ func main() {
 	deviceHandler := v1.NewDeviceHandler(deviceService)
 	logger.Info(ctx, "设备处理器初始化完成")
 
+	connectHandler := v1.NewConnectHandler(connectService)
+	logger.Info(ctx, "长连接接入处理器初始化完成")
+
 	// 8. 初始化路由（依赖注入）
 	// Gin 模式设置: ReleaseMode/DebugMode/TestMode
 	ginMode := os.Getenv("GIN_MODE")
@@ -235,7 +245,7 @@ func main() {
 		ginMode = gin.ReleaseMode
 	}
 	gin.SetMode(ginMode)
-	r := router.InitRouter(authHandler, userHandler, friendHandler, blacklistHandler, deviceHandler)
+	r := router.InitRouter(authHandler, userHandler, friendHandler, blacklistHandler, deviceHandler, connectHandler)
 	logger.Info(ctx, "路由初始化完成")
 
 	// 9. 配置服务器
@@ -0,0 +1,14 @@
+package dto
+
+// ==================== 长连接接入相关 DTO ====================
+
+// IssueConnectTicketRequest 签发 WebSocket 接入票据请求 DTO（无请求体）
+type IssueConnectTicketRequest struct {
+	AccessToken string `json:"-"` // 当前请求携带的 access token（由 handler 从 Authorization 头提取）
+}
+
+// IssueConnectTicketResponse 签发 WebSocket 接入票据响应 DTO
+type IssueConnectTicketResponse struct {
+	Ticket    string `json:"ticket"`    // 一次性票据，建连时以 /ws?ticket=...&device_id=... 携带
+	ExpiresIn int64  `json:"expiresIn"` // 票据有效期(秒)
+}
@@ -72,6 +72,59 @@ type OnlineStatusItem struct {
 	LastSeenAt string `json:"lastSeenAt"` // 最后活跃时间（RFC3339）
 }
 
+// RegisterPushTokenRequest 登记推送 token 请求 DTO（作用于当前设备）
+type RegisterPushTokenRequest struct {
+	Provider string `json:"provider" binding:"omitempty,max=32"`    // 推送通道(apns/fcm/huawei/xiaomi/oppo/vivo)，为空按设备平台推断
+	Token    string `json:"token" binding:"required,min=1,max=255"` // 推送通道下发的设备 token
+}
+
+// RegisterPushTokenResponse 登记推送 token 响应 DTO
+type RegisterPushTokenResponse struct {
+	Provider string `json:"provider"` // 实际使用的推送通道
+}
+
+// UnregisterPushTokenResponse 清除推送 token 响应 DTO
+type UnregisterPushTokenResponse struct{}
+
+// NotificationSettings 设备通知偏好 DTO
+type NotificationSettings struct {
+	DeviceID          string `json:"deviceId"`          // 设备ID
+	PushEnabled       bool   `json:"pushEnabled"`       // 是否接收系统推送
+	QuietHoursEnabled bool   `json:"quietHoursEnabled"` // 是否开启免打扰时段
+	QuietStart        int32  `json:"quietStart"`        // 免打扰开始(当天分钟数)
+	QuietEnd          int32  `json:"quietEnd"`          // 免打扰结束(当天分钟数，小于开始表示跨零点)
+	Timezone          string `json:"timezone"`          // IANA 时区，为空使用服务端时区
+	ShowPreview       bool   `json:"showPreview"`       // 是否展示消息预览
+	Sound             bool   `json:"sound"`             // 是否播放提示音
+}
+
+// GetNotificationSettingsRequest 获取通知偏好请求 DTO
+type GetNotificationSettingsRequest struct {
+	DeviceID string `form:"deviceId" json:"deviceId" binding:"omitempty,max=64"` // 设备ID，为空表示当前设备
+}
+
+// GetNotificationSettingsResponse 获取通知偏好响应 DTO
+type GetNotificationSettingsResponse struct {
+	Settings *NotificationSettings `json:"settings"` // 通知偏好
+}
+
+// UpdateNotificationSettingsRequest 更新通知偏好请求 DTO（未携带的字段保持不变）
+type UpdateNotificationSettingsRequest struct {
+	DeviceID          string  `json:"deviceId" binding:"omitempty,max=64"`           // 设备ID，为空表示当前设备
+	PushEnabled       *bool   `json:"pushEnabled"`                                   // 是否接收系统推送
+	QuietHoursEnabled *bool   `json:"quietHoursEnabled"`                             // 是否开启免打扰时段
+	QuietStart        *int32  `json:"quietStart" binding:"omitempty,min=0,max=1439"` // 免打扰开始(当天分钟数)
+	QuietEnd          *int32  `json:"quietEnd" binding:"omitempty,min=0,max=1439"`   // 免打扰结束(当天分钟数)
+	Timezone          *string `json:"timezone" binding:"omitempty,max=64"`           // IANA 时区
+	ShowPreview       *bool   `json:"showPreview"`                                   // 是否展示消息预览
+	Sound             *bool   `json:"sound"`                                         // 是否播放提示音
+}
+
+// UpdateNotificationSettingsResponse 更新通知偏好响应 DTO
+type UpdateNotificationSettingsResponse struct {
+	Settings *NotificationSettings `json:"settings"` // 更新后的通知偏好
+}
+
 // ==================== 设备服务 DTO 转换函数 ====================
 
 // ConvertToProtoGetOnlineStatusRequest 将 DTO 转换为 Protobuf 请求
@@ -104,6 +157,61 @@ func ConvertToProtoKickDeviceRequest(dto *KickDeviceRequest) *userpb.KickDeviceR
 	}
 }
 
+// ConvertToProtoRegisterPushTokenRequest 将 DTO 转换为 Protobuf 请求
+func ConvertToProtoRegisterPushTokenRequest(dto *RegisterPushTokenRequest) *userpb.RegisterPushTokenRequest {
+	if dto == nil {
+		return nil
+	}
+	return &userpb.RegisterPushTokenRequest{
+		Provider: dto.Provider,
+		Token:    dto.Token,
+	}
+}
+
+// ConvertToProtoGetNotificationSettingsRequest 将 DTO 转换为 Protobuf 请求
+func ConvertToProtoGetNotificationSettingsRequest(dto *GetNotificationSettingsRequest) *userpb.GetNotificationSettingsRequest {
+	if dto == nil {
+		return nil
+	}
+	return &userpb.GetNotificationSettingsRequest{
+		DeviceId: dto.DeviceID,
+	}
+}
+
+// ConvertToProtoUpdateNotificationSettingsRequest 将 DTO 转换为 Protobuf 请求
+func ConvertToProtoUpdateNotificationSettingsRequest(dto *UpdateNotificationSettingsRequest) *userpb.UpdateNotificationSettingsRequest {
+	if dto == nil {
+		return nil
+	}
+	return &userpb.UpdateNotificationSettingsRequest{
+		DeviceId:          dto.DeviceID,
+		PushEnabled:       dto.PushEnabled,
+		QuietHoursEnabled: dto.QuietHoursEnabled,
+		QuietStart:        dto.QuietStart,
+		QuietEnd:          dto.QuietEnd,
+		Timezone:          dto.Timezone,
+		ShowPreview:       dto.ShowPreview,
+		Sound:             dto.Sound,
+	}
+}
+
+// ConvertNotificationSettingsFromProto 将 Protobuf 通知偏好转换为 DTO
+func ConvertNotificationSettingsFromProto(pb *userpb.NotificationSettings) *NotificationSettings {
+	if pb == nil {
+		return nil
+	}
+	return &NotificationSettings{
+		DeviceID:          pb.DeviceId,
+		PushEnabled:       pb.PushEnabled,
+		QuietHoursEnabled: pb.QuietHoursEnabled,
+		QuietStart:        pb.QuietStart,
+		QuietEnd:          pb.QuietEnd,
+		Timezone:          pb.Timezone,
+		ShowPreview:       pb.ShowPreview,
+		Sound:             pb.Sound,
+	}
+}
+
 // ConvertDeviceItemFromProto 将 Protobuf 设备项转换为 DTO
 func ConvertDeviceItemFromProto(pb *userpb.DeviceItem) *DeviceItem {
 	if pb == nil {
@@ -220,3 +328,33 @@ func ConvertBatchGetOnlineStatusResponseFromProto(pb *userpb.BatchGetOnlineStatu
 		Users: users,
 	}
 }
+
+// ConvertRegisterPushTokenResponseFromProto 将 Protobuf 登记推送 token 响应转换为 DTO
+func ConvertRegisterPushTokenResponseFromProto(pb *userpb.RegisterPushTokenResponse) *RegisterPushTokenResponse {
+	if pb == nil {
+		return nil
+	}
+	return &RegisterPushTokenResponse{
+		Provider: pb.Provider,
+	}
+}
+
+// ConvertGetNotificationSettingsResponseFromProto 将 Protobuf 获取通知偏好响应转换为 DTO
+func ConvertGetNotificationSettingsResponseFromProto(pb *userpb.GetNotificationSettingsResponse) *GetNotificationSettingsResponse {
+	if pb == nil {
+		return nil
+	}
+	return &GetNotificationSettingsResponse{
+		Settings: ConvertNotificationSettingsFromProto(pb.Settings),
+	}
+}
+
+// ConvertUpdateNotificationSettingsResponseFromProto 将 Protobuf 更新通知偏好响应转换为 DTO
+func ConvertUpdateNotificationSettingsResponseFromProto(pb *userpb.UpdateNotificationSettingsResponse) *UpdateNotificationSettingsResponse {
+	if pb == nil {
+		return nil
+	}
+	return &UpdateNotificationSettingsResponse{
+		Settings: ConvertNotificationSettingsFromProto(pb.Settings),
+	}
+}
@@ -31,7 +31,7 @@ func GinLogger() gin.HandlerFunc {
 		logger.Info(ctx, "请求开始",
 			logger.String("method", c.Request.Method),
 			logger.String("path", path),
-			logger.String("query", query),
+			logger.Query("query", query),
 			logger.String("ip", clientIP),
 		)
 
@@ -46,7 +46,7 @@ func GinLogger() gin.HandlerFunc {
 				logger.Int("status", status),
 				logger.String("method", c.Request.Method),
 				logger.String("path", path),
-				logger.String("query", query),
+				logger.Query("query", query),
 				logger.String("ip", c.ClientIP()),
 				logger.String("user-agent", c.Request.UserAgent()),
 				logger.String("errors", c.Errors.ByType(gin.ErrorTypePrivate).String()),
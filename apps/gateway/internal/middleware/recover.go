@@ -59,7 +59,7 @@ func GinRecovery(stack bool) gin.HandlerFunc {
 						logger.Any("error", err),
 						logger.String("method", c.Request.Method),
 						logger.String("path", c.Request.URL.Path),
-						logger.String("query", c.Request.URL.RawQuery),
+						logger.Query("query", c.Request.URL.RawQuery),
 						logger.String("ip", c.ClientIP()),
 						logger.String("user-agent", c.Request.UserAgent()),
 						logger.String("request", string(httpRequest)),
@@ -70,7 +70,7 @@ func GinRecovery(stack bool) gin.HandlerFunc {
 						logger.Any("error", err),
 						logger.String("method", c.Request.Method),
 						logger.String("path", c.Request.URL.Path),
-						logger.String("query", c.Request.URL.RawQuery),
+						logger.Query("query", c.Request.URL.RawQuery),
 						logger.String("ip", c.ClientIP()),
 						logger.String("user-agent", c.Request.UserAgent()),
 						logger.String("request", string(httpRequest)),
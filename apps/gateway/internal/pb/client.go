@@ -352,6 +352,34 @@ func (c *userServiceClientImpl) BatchGetOnlineStatus(ctx context.Context, req *u
 	})
 }
 
+// RegisterPushToken 登记当前设备的推送 token
+func (c *userServiceClientImpl) RegisterPushToken(ctx context.Context, req *userpb.RegisterPushTokenRequest) (*userpb.RegisterPushTokenResponse, error) {
+	return ExecuteWithBreaker(c.breaker, "RegisterPushToken", func() (*userpb.RegisterPushTokenResponse, error) {
+		return c.deviceClient.RegisterPushToken(ctx, req)
+	})
+}
+
+// UnregisterPushToken 清除当前设备的推送 token
+func (c *userServiceClientImpl) UnregisterPushToken(ctx context.Context, req *userpb.UnregisterPushTokenRequest) (*userpb.UnregisterPushTokenResponse, error) {
+	return ExecuteWithBreaker(c.breaker, "UnregisterPushToken", func() (*userpb.UnregisterPushTokenResponse, error) {
+		return c.deviceClient.UnregisterPushToken(ctx, req)
+	})
+}
+
+// GetNotificationSettings 获取设备通知偏好
+func (c *userServiceClientImpl) GetNotificationSettings(ctx context.Context, req *userpb.GetNotificationSettingsRequest) (*userpb.GetNotificationSettingsResponse, error) {
+	return ExecuteWithBreaker(c.breaker, "GetNotificationSettings", func() (*userpb.GetNotificationSettingsResponse, error) {
+		return c.deviceClient.GetNotificationSettings(ctx, req)
+	})
+}
+
+// UpdateNotificationSettings 更新设备通知偏好
+func (c *userServiceClientImpl) UpdateNotificationSettings(ctx context.Context, req *userpb.UpdateNotificationSettingsRequest) (*userpb.UpdateNotificationSettingsResponse, error) {
+	return ExecuteWithBreaker(c.breaker, "UpdateNotificationSettings", func() (*userpb.UpdateNotificationSettingsResponse, error) {
+		return c.deviceClient.UpdateNotificationSettings(ctx, req)
+	})
+}
+
 // ==================== 通用工具函数 ====================
 // CreateConnection 通用的 gRPC 连接创建函数
 // addr: 服务地址，格式为 "host:port"
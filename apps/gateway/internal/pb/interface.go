@@ -141,4 +141,16 @@ type UserServiceClient interface {
 
 	// BatchGetOnlineStatus 批量获取在线状态
 	BatchGetOnlineStatus(ctx context.Context, req *userpb.BatchGetOnlineStatusRequest) (*userpb.BatchGetOnlineStatusResponse, error)
+
+	// RegisterPushToken 登记当前设备的推送 token
+	RegisterPushToken(ctx context.Context, req *userpb.RegisterPushTokenRequest) (*userpb.RegisterPushTokenResponse, error)
+
+	// UnregisterPushToken 清除当前设备的推送 token
+	UnregisterPushToken(ctx context.Context, req *userpb.UnregisterPushTokenRequest) (*userpb.UnregisterPushTokenResponse, error)
+
+	// GetNotificationSettings 获取设备通知偏好
+	GetNotificationSettings(ctx context.Context, req *userpb.GetNotificationSettingsRequest) (*userpb.GetNotificationSettingsResponse, error)
+
+	// UpdateNotificationSettings 更新设备通知偏好
+	UpdateNotificationSettings(ctx context.Context, req *userpb.UpdateNotificationSettingsRequest) (*userpb.UpdateNotificationSettingsResponse, error)
 }
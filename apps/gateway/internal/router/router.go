@@ -16,7 +16,8 @@ import (
 // friendHandler: 好友处理器（依赖注入）
 // blacklistHandler: 黑名单处理器（依赖注入）
 // deviceHandler: 设备处理器（依赖注入）
-func InitRouter(authHandler *v1.AuthHandler, userHandler *v1.UserHandler, friendHandler *v1.FriendHandler, blacklistHandler *v1.BlacklistHandler, deviceHandler *v1.DeviceHandler) *gin.Engine {
+// connectHandler: 长连接接入处理器（依赖注入）
+func InitRouter(authHandler *v1.AuthHandler, userHandler *v1.UserHandler, friendHandler *v1.FriendHandler, blacklistHandler *v1.BlacklistHandler, deviceHandler *v1.DeviceHandler, connectHandler *v1.ConnectHandler) *gin.Engine {
 	r := gin.New()
 
 	// 恢复中间件
@@ -101,6 +102,10 @@ func InitRouter(authHandler *v1.AuthHandler, userHandler *v1.UserHandler, friend
 				user.DELETE("/devices/:deviceId", deviceHandler.KickDevice)
 				user.GET("/online-status/:userUuid", deviceHandler.GetOnlineStatus)
 				user.POST("/batch-online-status", deviceHandler.BatchGetOnlineStatus)
+				user.POST("/push-token", deviceHandler.RegisterPushToken)
+				user.DELETE("/push-token", deviceHandler.UnregisterPushToken)
+				user.GET("/notification-settings", deviceHandler.GetNotificationSettings)
+				user.PUT("/notification-settings", deviceHandler.UpdateNotificationSettings)
 
 				// 敏感操作使用更严格的限流
 				user.POST("/change-password",
@@ -139,6 +144,14 @@ func InitRouter(authHandler *v1.AuthHandler, userHandler *v1.UserHandler, friend
 				blacklist.DELETE("/:userUuid", blacklistHandler.RemoveBlacklist)
 				blacklist.POST("/check", blacklistHandler.CheckIsBlacklist)
 			}
+
+			connect := auth.Group("/connect")
+			{
+				// 重连风暴时客户端会频繁申请票据，单独限流
+				connect.POST("/ticket",
+					middleware.UserRateLimitMiddlewareWithConfig(5.0, 10),
+					connectHandler.IssueTicket)
+			}
 		}
 	}
 
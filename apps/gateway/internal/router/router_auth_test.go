@@ -142,7 +142,7 @@ func buildAuthTestRouter(authSvc service.AuthService) *gin.Engine {
 	friendHandler := v1.NewFriendHandler(nil)
 	blacklistHandler := v1.NewBlacklistHandler(nil)
 	deviceHandler := v1.NewDeviceHandler(nil)
-	return InitRouter(authHandler, userHandler, friendHandler, blacklistHandler, deviceHandler)
+	return InitRouter(authHandler, userHandler, friendHandler, blacklistHandler, deviceHandler, v1.NewConnectHandler(nil))
 }
 
 func TestRouterAuthPublicRoutesSuccess(t *testing.T) {
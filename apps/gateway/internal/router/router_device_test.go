@@ -30,6 +30,10 @@ type fakeRouterDeviceService struct {
 	kickDeviceFn           func(context.Context, *dto.KickDeviceRequest) (*dto.KickDeviceResponse, error)
 	getOnlineStatusFn      func(context.Context, *dto.GetOnlineStatusRequest) (*dto.GetOnlineStatusResponse, error)
 	batchGetOnlineStatusFn func(context.Context, *dto.BatchGetOnlineStatusRequest) (*dto.BatchGetOnlineStatusResponse, error)
+	registerPushTokenFn    func(context.Context, *dto.RegisterPushTokenRequest) (*dto.RegisterPushTokenResponse, error)
+	unregisterPushTokenFn  func(context.Context) (*dto.UnregisterPushTokenResponse, error)
+	getNotifySettingsFn    func(context.Context, *dto.GetNotificationSettingsRequest) (*dto.GetNotificationSettingsResponse, error)
+	updateNotifySettingsFn func(context.Context, *dto.UpdateNotificationSettingsRequest) (*dto.UpdateNotificationSettingsResponse, error)
 }
 
 var _ service.DeviceService = (*fakeRouterDeviceService)(nil)
@@ -62,6 +66,34 @@ func (f *fakeRouterDeviceService) BatchGetOnlineStatus(ctx context.Context, req
 	return f.batchGetOnlineStatusFn(ctx, req)
 }
 
+func (f *fakeRouterDeviceService) RegisterPushToken(ctx context.Context, req *dto.RegisterPushTokenRequest) (*dto.RegisterPushTokenResponse, error) {
+	if f.registerPushTokenFn == nil {
+		return &dto.RegisterPushTokenResponse{}, nil
+	}
+	return f.registerPushTokenFn(ctx, req)
+}
+
+func (f *fakeRouterDeviceService) UnregisterPushToken(ctx context.Context) (*dto.UnregisterPushTokenResponse, error) {
+	if f.unregisterPushTokenFn == nil {
+		return &dto.UnregisterPushTokenResponse{}, nil
+	}
+	return f.unregisterPushTokenFn(ctx)
+}
+
+func (f *fakeRouterDeviceService) GetNotificationSettings(ctx context.Context, req *dto.GetNotificationSettingsRequest) (*dto.GetNotificationSettingsResponse, error) {
+	if f.getNotifySettingsFn == nil {
+		return &dto.GetNotificationSettingsResponse{}, nil
+	}
+	return f.getNotifySettingsFn(ctx, req)
+}
+
+func (f *fakeRouterDeviceService) UpdateNotificationSettings(ctx context.Context, req *dto.UpdateNotificationSettingsRequest) (*dto.UpdateNotificationSettingsResponse, error) {
+	if f.updateNotifySettingsFn == nil {
+		return &dto.UpdateNotificationSettingsResponse{}, nil
+	}
+	return f.updateNotifySettingsFn(ctx, req)
+}
+
 type routerDeviceResultBody struct {
 	Code int `json:"code"`
 }
@@ -110,7 +142,7 @@ func buildDeviceTestRouter(deviceSvc service.DeviceService) *gin.Engine {
 	friendHandler := v1.NewFriendHandler(nil)
 	blacklistHandler := v1.NewBlacklistHandler(nil)
 	deviceHandler := v1.NewDeviceHandler(deviceSvc)
-	return InitRouter(authHandler, userHandler, friendHandler, blacklistHandler, deviceHandler)
+	return InitRouter(authHandler, userHandler, friendHandler, blacklistHandler, deviceHandler, v1.NewConnectHandler(nil))
 }
 
 func TestRouterDeviceUnauthorized(t *testing.T) {
@@ -182,6 +214,55 @@ func TestRouterDeviceRoutesAndSuccess(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:   "register_push_token",
+			method: http.MethodPost,
+			target: "/api/v1/auth/user/push-token",
+			body:   `{"provider":"apns","token":"tok-1"}`,
+			setup: func(s *fakeRouterDeviceService, called *bool) {
+				s.registerPushTokenFn = func(_ context.Context, req *dto.RegisterPushTokenRequest) (*dto.RegisterPushTokenResponse, error) {
+					*called = true
+					require.Equal(t, "tok-1", req.Token)
+					return &dto.RegisterPushTokenResponse{Provider: "apns"}, nil
+				}
+			},
+		},
+		{
+			name:   "unregister_push_token",
+			method: http.MethodDelete,
+			target: "/api/v1/auth/user/push-token",
+			setup: func(s *fakeRouterDeviceService, called *bool) {
+				s.unregisterPushTokenFn = func(_ context.Context) (*dto.UnregisterPushTokenResponse, error) {
+					*called = true
+					return &dto.UnregisterPushTokenResponse{}, nil
+				}
+			},
+		},
+		{
+			name:   "get_notification_settings",
+			method: http.MethodGet,
+			target: "/api/v1/auth/user/notification-settings?deviceId=d2",
+			setup: func(s *fakeRouterDeviceService, called *bool) {
+				s.getNotifySettingsFn = func(_ context.Context, req *dto.GetNotificationSettingsRequest) (*dto.GetNotificationSettingsResponse, error) {
+					*called = true
+					require.Equal(t, "d2", req.DeviceID)
+					return &dto.GetNotificationSettingsResponse{}, nil
+				}
+			},
+		},
+		{
+			name:   "update_notification_settings",
+			method: http.MethodPut,
+			target: "/api/v1/auth/user/notification-settings",
+			body:   `{"quietHoursEnabled":true}`,
+			setup: func(s *fakeRouterDeviceService, called *bool) {
+				s.updateNotifySettingsFn = func(_ context.Context, req *dto.UpdateNotificationSettingsRequest) (*dto.UpdateNotificationSettingsResponse, error) {
+					*called = true
+					require.NotNil(t, req.QuietHoursEnabled)
+					return &dto.UpdateNotificationSettingsResponse{}, nil
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -168,7 +168,7 @@ func buildRouterUserTestRouter(userSvc service.UserService) *gin.Engine {
 	friendHandler := v1.NewFriendHandler(nil)
 	blacklistHandler := v1.NewBlacklistHandler(nil)
 	deviceHandler := v1.NewDeviceHandler(nil)
-	return InitRouter(authHandler, userHandler, friendHandler, blacklistHandler, deviceHandler)
+	return InitRouter(authHandler, userHandler, friendHandler, blacklistHandler, deviceHandler, v1.NewConnectHandler(nil))
 }
 
 func TestRouterUserUnauthorized(t *testing.T) {
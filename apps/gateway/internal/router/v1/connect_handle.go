@@ -0,0 +1,54 @@
+package v1
+
+import (
+	"ChatServer/apps/gateway/internal/dto"
+	"ChatServer/apps/gateway/internal/middleware"
+	"ChatServer/apps/gateway/internal/service"
+	"ChatServer/apps/gateway/internal/utils"
+	"ChatServer/consts"
+	"ChatServer/pkg/logger"
+	"ChatServer/pkg/result"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConnectHandler 长连接接入处理器
+type ConnectHandler struct {
+	connectService service.ConnectService
+}
+
+// NewConnectHandler 创建长连接接入处理器
+func NewConnectHandler(connectService service.ConnectService) *ConnectHandler {
+	return &ConnectHandler{
+		connectService: connectService,
+	}
+}
+
+// IssueTicket 签发 WebSocket 一次性接入票据
+// @Summary 签发长连接接入票据
+// @Description 签发短时有效、只能使用一次的票据，建连时以 /ws?ticket=...&device_id=... 代替 access token
+// @Tags 长连接接口
+// @Produce json
+// @Success 200 {object} dto.IssueConnectTicketResponse
+// @Router /api/v1/auth/connect/ticket [post]
+func (h *ConnectHandler) IssueTicket(c *gin.Context) {
+	ctx := middleware.NewContextWithGin(c)
+
+	// JWT 中间件已校验 Authorization 格式
+	accessToken := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	resp, err := h.connectService.IssueTicket(ctx, &dto.IssueConnectTicketRequest{AccessToken: accessToken})
+	if err != nil {
+		if consts.IsNonServerError(utils.ExtractErrorCode(err)) {
+			result.Fail(c, nil, utils.ExtractErrorCode(err))
+			return
+		}
+		logger.Error(ctx, "签发接入票据服务内部错误",
+			logger.ErrorField("error", err),
+		)
+		result.Fail(c, nil, consts.CodeInternalError)
+		return
+	}
+
+	result.Success(c, resp)
+}
@@ -157,3 +157,137 @@ func (h *DeviceHandler) BatchGetOnlineStatus(c *gin.Context) {
 
 	result.Success(c, resp)
 }
+
+// RegisterPushToken 登记推送 token
+// @Summary 登记推送 token
+// @Description 为当前设备登记系统推送 token（APNs/FCM/厂商通道），用户离线时新消息经系统推送下发
+// @Tags 设备接口
+// @Accept json
+// @Produce json
+// @Param request body dto.RegisterPushTokenRequest true "登记推送 token 请求"
+// @Success 200 {object} dto.RegisterPushTokenResponse
+// @Router /api/v1/auth/user/push-token [post]
+func (h *DeviceHandler) RegisterPushToken(c *gin.Context) {
+	ctx := middleware.NewContextWithGin(c)
+
+	var req dto.RegisterPushTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		result.Fail(c, nil, consts.CodeParamError)
+		return
+	}
+
+	resp, err := h.deviceService.RegisterPushToken(ctx, &req)
+	if err != nil {
+		if consts.IsNonServerError(utils.ExtractErrorCode(err)) {
+			result.Fail(c, nil, utils.ExtractErrorCode(err))
+			return
+		}
+		logger.Error(ctx, "登记推送 token 服务内部错误",
+			logger.ErrorField("error", err),
+		)
+		result.Fail(c, nil, consts.CodeInternalError)
+		return
+	}
+
+	result.Success(c, resp)
+}
+
+// UnregisterPushToken 清除推送 token
+// @Summary 清除推送 token
+// @Description 清除当前设备的推送 token，关闭系统推送
+// @Tags 设备接口
+// @Produce json
+// @Success 200 {object} dto.UnregisterPushTokenResponse
+// @Router /api/v1/auth/user/push-token [delete]
+func (h *DeviceHandler) UnregisterPushToken(c *gin.Context) {
+	ctx := middleware.NewContextWithGin(c)
+
+	resp, err := h.deviceService.UnregisterPushToken(ctx)
+	if err != nil {
+		if consts.IsNonServerError(utils.ExtractErrorCode(err)) {
+			result.Fail(c, nil, utils.ExtractErrorCode(err))
+			return
+		}
+		logger.Error(ctx, "清除推送 token 服务内部错误",
+			logger.ErrorField("error", err),
+		)
+		result.Fail(c, nil, consts.CodeInternalError)
+		return
+	}
+
+	result.Success(c, resp)
+}
+
+// GetNotificationSettings 获取通知偏好
+// @Summary 获取通知偏好
+// @Description 获取设备的通知偏好（推送开关、免打扰时段、消息预览、提示音），未设置过返回默认值
+// @Tags 设备接口
+// @Produce json
+// @Param deviceId query string false "设备ID，为空表示当前设备"
+// @Success 200 {object} dto.GetNotificationSettingsResponse
+// @Router /api/v1/auth/user/notification-settings [get]
+func (h *DeviceHandler) GetNotificationSettings(c *gin.Context) {
+	ctx := middleware.NewContextWithGin(c)
+
+	var req dto.GetNotificationSettingsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		result.Fail(c, nil, consts.CodeParamError)
+		return
+	}
+
+	resp, err := h.deviceService.GetNotificationSettings(ctx, &req)
+	if err != nil {
+		if consts.IsNonServerError(utils.ExtractErrorCode(err)) {
+			result.Fail(c, nil, utils.ExtractErrorCode(err))
+			return
+		}
+		logger.Error(ctx, "获取通知偏好服务内部错误",
+			logger.ErrorField("error", err),
+		)
+		result.Fail(c, nil, consts.CodeInternalError)
+		return
+	}
+
+	result.Success(c, resp)
+}
+
+// UpdateNotificationSettings 更新通知偏好
+// @Summary 更新通知偏好
+// @Description 更新设备的通知偏好，只更新请求中携带的字段
+// @Tags 设备接口
+// @Accept json
+// @Produce json
+// @Param request body dto.UpdateNotificationSettingsRequest true "更新通知偏好请求"
+// @Success 200 {object} dto.UpdateNotificationSettingsResponse
+// @Router /api/v1/auth/user/notification-settings [put]
+func (h *DeviceHandler) UpdateNotificationSettings(c *gin.Context) {
+	ctx := middleware.NewContextWithGin(c)
+
+	var req dto.UpdateNotificationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		result.Fail(c, nil, consts.CodeParamError)
+		return
+	}
+
+	// 至少提供一个字段
+	if req.PushEnabled == nil && req.QuietHoursEnabled == nil && req.QuietStart == nil && req.QuietEnd == nil &&
+		req.Timezone == nil && req.ShowPreview == nil && req.Sound == nil {
+		result.Fail(c, nil, consts.CodeParamError)
+		return
+	}
+
+	resp, err := h.deviceService.UpdateNotificationSettings(ctx, &req)
+	if err != nil {
+		if consts.IsNonServerError(utils.ExtractErrorCode(err)) {
+			result.Fail(c, nil, utils.ExtractErrorCode(err))
+			return
+		}
+		logger.Error(ctx, "更新通知偏好服务内部错误",
+			logger.ErrorField("error", err),
+		)
+		result.Fail(c, nil, consts.CodeInternalError)
+		return
+	}
+
+	result.Success(c, resp)
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"sync"
 	"testing"
 
@@ -28,6 +29,10 @@ type fakeDeviceHTTPService struct {
 	kickDeviceFn           func(context.Context, *dto.KickDeviceRequest) (*dto.KickDeviceResponse, error)
 	getOnlineStatusFn      func(context.Context, *dto.GetOnlineStatusRequest) (*dto.GetOnlineStatusResponse, error)
 	batchGetOnlineStatusFn func(context.Context, *dto.BatchGetOnlineStatusRequest) (*dto.BatchGetOnlineStatusResponse, error)
+	registerPushTokenFn    func(context.Context, *dto.RegisterPushTokenRequest) (*dto.RegisterPushTokenResponse, error)
+	unregisterPushTokenFn  func(context.Context) (*dto.UnregisterPushTokenResponse, error)
+	getNotifySettingsFn    func(context.Context, *dto.GetNotificationSettingsRequest) (*dto.GetNotificationSettingsResponse, error)
+	updateNotifySettingsFn func(context.Context, *dto.UpdateNotificationSettingsRequest) (*dto.UpdateNotificationSettingsResponse, error)
 }
 
 var _ service.DeviceService = (*fakeDeviceHTTPService)(nil)
@@ -60,6 +65,34 @@ func (f *fakeDeviceHTTPService) BatchGetOnlineStatus(ctx context.Context, req *d
 	return f.batchGetOnlineStatusFn(ctx, req)
 }
 
+func (f *fakeDeviceHTTPService) RegisterPushToken(ctx context.Context, req *dto.RegisterPushTokenRequest) (*dto.RegisterPushTokenResponse, error) {
+	if f.registerPushTokenFn == nil {
+		return &dto.RegisterPushTokenResponse{}, nil
+	}
+	return f.registerPushTokenFn(ctx, req)
+}
+
+func (f *fakeDeviceHTTPService) UnregisterPushToken(ctx context.Context) (*dto.UnregisterPushTokenResponse, error) {
+	if f.unregisterPushTokenFn == nil {
+		return &dto.UnregisterPushTokenResponse{}, nil
+	}
+	return f.unregisterPushTokenFn(ctx)
+}
+
+func (f *fakeDeviceHTTPService) GetNotificationSettings(ctx context.Context, req *dto.GetNotificationSettingsRequest) (*dto.GetNotificationSettingsResponse, error) {
+	if f.getNotifySettingsFn == nil {
+		return &dto.GetNotificationSettingsResponse{}, nil
+	}
+	return f.getNotifySettingsFn(ctx, req)
+}
+
+func (f *fakeDeviceHTTPService) UpdateNotificationSettings(ctx context.Context, req *dto.UpdateNotificationSettingsRequest) (*dto.UpdateNotificationSettingsResponse, error) {
+	if f.updateNotifySettingsFn == nil {
+		return &dto.UpdateNotificationSettingsResponse{}, nil
+	}
+	return f.updateNotifySettingsFn(ctx, req)
+}
+
 type deviceHandlerResultBody struct {
 	Code int `json:"code"`
 }
@@ -432,3 +465,146 @@ func TestDeviceHandlerBatchGetOnlineStatus(t *testing.T) {
 		}
 	})
 }
+
+func TestDeviceHandlerRegisterPushToken(t *testing.T) {
+	initGatewayDeviceHandlerLogger()
+
+	t.Run("invalid_body", func(t *testing.T) {
+		for _, body := range []string{"{", `{"provider":"apns"}`, `{"token":""}`} {
+			called := false
+			h := NewDeviceHandler(&fakeDeviceHTTPService{
+				registerPushTokenFn: func(_ context.Context, _ *dto.RegisterPushTokenRequest) (*dto.RegisterPushTokenResponse, error) {
+					called = true
+					return &dto.RegisterPushTokenResponse{}, nil
+				},
+			})
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = newDeviceJSONRequest(t, http.MethodPost, "/api/v1/auth/user/push-token", body)
+
+			h.RegisterPushToken(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, consts.CodeParamError, decodeDeviceHandlerCode(t, w))
+			assert.False(t, called)
+		}
+	})
+
+	t.Run("success_and_error_mapping", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			err        error
+			wantStatus int
+			wantCode   int
+		}{
+			{name: "success", wantStatus: http.StatusOK, wantCode: consts.CodeSuccess},
+			{name: "business_error", err: status.Error(codes.FailedPrecondition, strconv.Itoa(consts.CodePlatformNotSupport)), wantStatus: http.StatusOK, wantCode: consts.CodePlatformNotSupport},
+			{name: "internal_error", err: errors.New("internal"), wantStatus: http.StatusInternalServerError, wantCode: consts.CodeInternalError},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				h := NewDeviceHandler(&fakeDeviceHTTPService{
+					registerPushTokenFn: func(_ context.Context, req *dto.RegisterPushTokenRequest) (*dto.RegisterPushTokenResponse, error) {
+						require.Equal(t, "tok-1", req.Token)
+						if tt.err != nil {
+							return nil, tt.err
+						}
+						return &dto.RegisterPushTokenResponse{Provider: "apns"}, nil
+					},
+				})
+				w := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(w)
+				c.Request = newDeviceJSONRequest(t, http.MethodPost, "/api/v1/auth/user/push-token", `{"token":"tok-1"}`)
+
+				h.RegisterPushToken(c)
+
+				assert.Equal(t, tt.wantStatus, w.Code)
+				assert.Equal(t, tt.wantCode, decodeDeviceHandlerCode(t, w))
+			})
+		}
+	})
+}
+
+func TestDeviceHandlerUpdateNotificationSettings(t *testing.T) {
+	initGatewayDeviceHandlerLogger()
+
+	t.Run("invalid_body", func(t *testing.T) {
+		for _, body := range []string{"{", `{}`, `{"deviceId":"d1"}`, `{"quietStart":1440}`, `{"quietEnd":-1}`} {
+			called := false
+			h := NewDeviceHandler(&fakeDeviceHTTPService{
+				updateNotifySettingsFn: func(_ context.Context, _ *dto.UpdateNotificationSettingsRequest) (*dto.UpdateNotificationSettingsResponse, error) {
+					called = true
+					return &dto.UpdateNotificationSettingsResponse{}, nil
+				},
+			})
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = newDeviceJSONRequest(t, http.MethodPut, "/api/v1/auth/user/notification-settings", body)
+
+			h.UpdateNotificationSettings(c)
+
+			assert.Equal(t, http.StatusOK, w.Code, body)
+			assert.Equal(t, consts.CodeParamError, decodeDeviceHandlerCode(t, w), body)
+			assert.False(t, called, body)
+		}
+	})
+
+	t.Run("false_values_are_forwarded", func(t *testing.T) {
+		h := NewDeviceHandler(&fakeDeviceHTTPService{
+			updateNotifySettingsFn: func(_ context.Context, req *dto.UpdateNotificationSettingsRequest) (*dto.UpdateNotificationSettingsResponse, error) {
+				require.NotNil(t, req.PushEnabled)
+				assert.False(t, *req.PushEnabled)
+				require.NotNil(t, req.QuietStart)
+				assert.Equal(t, int32(0), *req.QuietStart)
+				assert.Nil(t, req.Sound)
+				return &dto.UpdateNotificationSettingsResponse{}, nil
+			},
+		})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = newDeviceJSONRequest(t, http.MethodPut, "/api/v1/auth/user/notification-settings", `{"pushEnabled":false,"quietStart":0}`)
+
+		h.UpdateNotificationSettings(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, consts.CodeSuccess, decodeDeviceHandlerCode(t, w))
+	})
+}
+
+func TestDeviceHandlerGetNotificationSettings(t *testing.T) {
+	initGatewayDeviceHandlerLogger()
+
+	t.Run("query_device_id", func(t *testing.T) {
+		h := NewDeviceHandler(&fakeDeviceHTTPService{
+			getNotifySettingsFn: func(_ context.Context, req *dto.GetNotificationSettingsRequest) (*dto.GetNotificationSettingsResponse, error) {
+				require.Equal(t, "d2", req.DeviceID)
+				return &dto.GetNotificationSettingsResponse{}, nil
+			},
+		})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = newDeviceJSONRequest(t, http.MethodGet, "/api/v1/auth/user/notification-settings?deviceId=d2", "")
+
+		h.GetNotificationSettings(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, consts.CodeSuccess, decodeDeviceHandlerCode(t, w))
+	})
+
+	t.Run("business_error", func(t *testing.T) {
+		h := NewDeviceHandler(&fakeDeviceHTTPService{
+			getNotifySettingsFn: func(_ context.Context, _ *dto.GetNotificationSettingsRequest) (*dto.GetNotificationSettingsResponse, error) {
+				return nil, status.Error(codes.NotFound, strconv.Itoa(consts.CodeDeviceNotFound))
+			},
+		})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = newDeviceJSONRequest(t, http.MethodGet, "/api/v1/auth/user/notification-settings", "")
+
+		h.GetNotificationSettings(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, consts.CodeDeviceNotFound, decodeDeviceHandlerCode(t, w))
+	})
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"ChatServer/apps/gateway/internal/dto"
+	"ChatServer/config"
+	"ChatServer/consts"
+	rediskey "ChatServer/consts/redisKey"
+	"ChatServer/pkg/connectroute"
+	"ChatServer/pkg/logger"
+	"ChatServer/pkg/util"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ConnectServiceImpl 长连接接入服务实现
+type ConnectServiceImpl struct {
+	redisClient *redis.Client
+	ticketCfg   config.ConnectTicketConfig
+}
+
+// NewConnectService 创建长连接接入服务实例
+// redisClient: 票据存储（与 connect 共用的 Redis），为 nil 时签发接口返回服务不可用
+// ticketCfg: 票据有效期与 IP 绑定策略
+func NewConnectService(redisClient *redis.Client, ticketCfg config.ConnectTicketConfig) ConnectService {
+	if ticketCfg.TTL <= 0 {
+		ticketCfg.TTL = rediskey.ConnectTicketTTL
+	}
+	return &ConnectServiceImpl{
+		redisClient: redisClient,
+		ticketCfg:   ticketCfg,
+	}
+}
+
+// IssueTicket 签发一次性接入票据
+// 票据绑定 user_uuid、device_id、（可选）客户端 IP 与当前 access token 的哈希：
+// 客户端不必把长期有效的 access token 放进 /ws URL，泄露的 URL 在票据兑换或过期后即失效。
+func (s *ConnectServiceImpl) IssueTicket(ctx context.Context, req *dto.IssueConnectTicketRequest) (*dto.IssueConnectTicketResponse, error) {
+	// 1. 从 context 中获取当前用户与设备（JWT 中间件注入）
+	userUUID := util.GetUserUUIDFromContext(ctx)
+	deviceID := util.GetDeviceIDFromContext(ctx)
+	if userUUID == "" || deviceID == "" || req.AccessToken == "" {
+		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+	if s.redisClient == nil {
+		return nil, status.Error(codes.Unavailable, strconv.Itoa(consts.CodeServiceUnavailable))
+	}
+
+	// 2. 记录 access token 过期时间，connect 据此下发 auth_expiring
+	claims, err := util.ParseToken(req.AccessToken)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+	sum := md5.Sum([]byte(req.AccessToken))
+	ticket := connectroute.Ticket{
+		UserUUID:  userUUID,
+		DeviceID:  deviceID,
+		TokenHash: hex.EncodeToString(sum[:]),
+	}
+	if claims.ExpiresAt != nil {
+		ticket.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	if s.ticketCfg.BindIP {
+		ticket.ClientIP = util.GetClientIPFromContext(ctx)
+	}
+
+	// 3. 写入 Redis
+	id, err := connectroute.IssueTicket(ctx, s.redisClient, ticket, s.ticketCfg.TTL)
+	if err != nil {
+		logger.Error(ctx, "签发接入票据失败",
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	return &dto.IssueConnectTicketResponse{
+		Ticket:    id,
+		ExpiresIn: int64(s.ticketCfg.TTL.Seconds()),
+	}, nil
+}
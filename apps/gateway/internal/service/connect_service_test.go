@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"ChatServer/apps/gateway/internal/dto"
+	"ChatServer/apps/gateway/internal/utils"
+	"ChatServer/config"
+	"ChatServer/consts"
+	"ChatServer/pkg/connectroute"
+	"ChatServer/pkg/ctxmeta"
+	"ChatServer/pkg/util"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newConnectTicketTestContext() context.Context {
+	ctx := ctxmeta.WithUserUUID(context.Background(), "u1")
+	ctx = ctxmeta.WithDeviceID(ctx, "d1")
+	return ctxmeta.WithClientIP(ctx, "203.0.113.7")
+}
+
+func TestGatewayConnectServiceIssueTicket(t *testing.T) {
+	initGatewayDeviceServiceTestLogger()
+
+	token, err := util.GenerateToken("u1", "d1")
+	require.NoError(t, err)
+
+	t.Run("success_bound_to_device_and_ip", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		defer rdb.Close()
+		svc := NewConnectService(rdb, config.ConnectTicketConfig{TTL: 20 * time.Second, BindIP: true})
+
+		resp, err := svc.IssueTicket(newConnectTicketTestContext(), &dto.IssueConnectTicketRequest{AccessToken: token})
+		require.NoError(t, err)
+		require.NotEmpty(t, resp.Ticket)
+		assert.Equal(t, int64(20), resp.ExpiresIn)
+
+		ticket, err := connectroute.RedeemTicket(context.Background(), rdb, resp.Ticket)
+		require.NoError(t, err)
+		sum := md5.Sum([]byte(token))
+		assert.Equal(t, "u1", ticket.UserUUID)
+		assert.Equal(t, "d1", ticket.DeviceID)
+		assert.Equal(t, "203.0.113.7", ticket.ClientIP)
+		assert.Equal(t, hex.EncodeToString(sum[:]), ticket.TokenHash)
+		assert.Positive(t, ticket.ExpiresAt)
+
+		_, err = connectroute.RedeemTicket(context.Background(), rdb, resp.Ticket)
+		assert.ErrorIs(t, err, connectroute.ErrTicketNotFound)
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		defer rdb.Close()
+		svc := NewConnectService(rdb, config.ConnectTicketConfig{})
+
+		_, err := svc.IssueTicket(context.Background(), &dto.IssueConnectTicketRequest{AccessToken: token})
+		assert.Equal(t, consts.CodeUnauthorized, utils.ExtractErrorCode(err))
+	})
+
+	t.Run("redis_unavailable", func(t *testing.T) {
+		svc := NewConnectService(nil, config.ConnectTicketConfig{})
+
+		_, err := svc.IssueTicket(newConnectTicketTestContext(), &dto.IssueConnectTicketRequest{AccessToken: token})
+		assert.Equal(t, consts.CodeServiceUnavailable, utils.ExtractErrorCode(err))
+	})
+}
@@ -110,3 +110,90 @@ func (s *DeviceServiceImpl) BatchGetOnlineStatus(ctx context.Context, req *dto.B
 
 	return dto.ConvertBatchGetOnlineStatusResponseFromProto(grpcResp), nil
 }
+
+// RegisterPushToken 登记当前设备的推送 token
+func (s *DeviceServiceImpl) RegisterPushToken(ctx context.Context, req *dto.RegisterPushTokenRequest) (*dto.RegisterPushTokenResponse, error) {
+	startTime := time.Now()
+
+	grpcReq := dto.ConvertToProtoRegisterPushTokenRequest(req)
+	grpcResp, err := s.userClient.RegisterPushToken(ctx, grpcReq)
+	if err != nil {
+		code := utils.ExtractErrorCode(err)
+		if code >= 30000 {
+			logger.Error(ctx, "调用用户服务 gRPC 失败",
+				logger.ErrorField("error", err),
+				logger.Int("business_code", code),
+				logger.String("business_message", consts.GetMessage(code)),
+				logger.Duration("duration", time.Since(startTime)),
+			)
+		}
+		return nil, err
+	}
+
+	return dto.ConvertRegisterPushTokenResponseFromProto(grpcResp), nil
+}
+
+// UnregisterPushToken 清除当前设备的推送 token
+func (s *DeviceServiceImpl) UnregisterPushToken(ctx context.Context) (*dto.UnregisterPushTokenResponse, error) {
+	startTime := time.Now()
+
+	_, err := s.userClient.UnregisterPushToken(ctx, &userpb.UnregisterPushTokenRequest{})
+	if err != nil {
+		code := utils.ExtractErrorCode(err)
+		if code >= 30000 {
+			logger.Error(ctx, "调用用户服务 gRPC 失败",
+				logger.ErrorField("error", err),
+				logger.Int("business_code", code),
+				logger.String("business_message", consts.GetMessage(code)),
+				logger.Duration("duration", time.Since(startTime)),
+			)
+		}
+		return nil, err
+	}
+
+	return &dto.UnregisterPushTokenResponse{}, nil
+}
+
+// GetNotificationSettings 获取设备通知偏好
+func (s *DeviceServiceImpl) GetNotificationSettings(ctx context.Context, req *dto.GetNotificationSettingsRequest) (*dto.GetNotificationSettingsResponse, error) {
+	startTime := time.Now()
+
+	grpcReq := dto.ConvertToProtoGetNotificationSettingsRequest(req)
+	grpcResp, err := s.userClient.GetNotificationSettings(ctx, grpcReq)
+	if err != nil {
+		code := utils.ExtractErrorCode(err)
+		if code >= 30000 {
+			logger.Error(ctx, "调用用户服务 gRPC 失败",
+				logger.ErrorField("error", err),
+				logger.Int("business_code", code),
+				logger.String("business_message", consts.GetMessage(code)),
+				logger.Duration("duration", time.Since(startTime)),
+			)
+		}
+		return nil, err
+	}
+
+	return dto.ConvertGetNotificationSettingsResponseFromProto(grpcResp), nil
+}
+
+// UpdateNotificationSettings 更新设备通知偏好
+func (s *DeviceServiceImpl) UpdateNotificationSettings(ctx context.Context, req *dto.UpdateNotificationSettingsRequest) (*dto.UpdateNotificationSettingsResponse, error) {
+	startTime := time.Now()
+
+	grpcReq := dto.ConvertToProtoUpdateNotificationSettingsRequest(req)
+	grpcResp, err := s.userClient.UpdateNotificationSettings(ctx, grpcReq)
+	if err != nil {
+		code := utils.ExtractErrorCode(err)
+		if code >= 30000 {
+			logger.Error(ctx, "调用用户服务 gRPC 失败",
+				logger.ErrorField("error", err),
+				logger.Int("business_code", code),
+				logger.String("business_message", consts.GetMessage(code)),
+				logger.Duration("duration", time.Since(startTime)),
+			)
+		}
+		return nil, err
+	}
+
+	return dto.ConvertUpdateNotificationSettingsResponseFromProto(grpcResp), nil
+}
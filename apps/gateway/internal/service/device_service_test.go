@@ -29,10 +29,14 @@ func initGatewayDeviceServiceTestLogger() {
 type fakeGatewayDeviceClient struct {
 	gatewaypb.UserServiceClient
 
-	getDeviceListFn       func(context.Context, *userpb.GetDeviceListRequest) (*userpb.GetDeviceListResponse, error)
-	kickDeviceFn          func(context.Context, *userpb.KickDeviceRequest) (*userpb.KickDeviceResponse, error)
-	getOnlineStatusFn     func(context.Context, *userpb.GetOnlineStatusRequest) (*userpb.GetOnlineStatusResponse, error)
+	getDeviceListFn        func(context.Context, *userpb.GetDeviceListRequest) (*userpb.GetDeviceListResponse, error)
+	kickDeviceFn           func(context.Context, *userpb.KickDeviceRequest) (*userpb.KickDeviceResponse, error)
+	getOnlineStatusFn      func(context.Context, *userpb.GetOnlineStatusRequest) (*userpb.GetOnlineStatusResponse, error)
 	batchGetOnlineStatusFn func(context.Context, *userpb.BatchGetOnlineStatusRequest) (*userpb.BatchGetOnlineStatusResponse, error)
+	registerPushTokenFn    func(context.Context, *userpb.RegisterPushTokenRequest) (*userpb.RegisterPushTokenResponse, error)
+	unregisterPushTokenFn  func(context.Context, *userpb.UnregisterPushTokenRequest) (*userpb.UnregisterPushTokenResponse, error)
+	getNotifySettingsFn    func(context.Context, *userpb.GetNotificationSettingsRequest) (*userpb.GetNotificationSettingsResponse, error)
+	updateNotifySettingsFn func(context.Context, *userpb.UpdateNotificationSettingsRequest) (*userpb.UpdateNotificationSettingsResponse, error)
 }
 
 func (f *fakeGatewayDeviceClient) GetDeviceList(ctx context.Context, req *userpb.GetDeviceListRequest) (*userpb.GetDeviceListResponse, error) {
@@ -63,6 +67,34 @@ func (f *fakeGatewayDeviceClient) BatchGetOnlineStatus(ctx context.Context, req
 	return f.batchGetOnlineStatusFn(ctx, req)
 }
 
+func (f *fakeGatewayDeviceClient) RegisterPushToken(ctx context.Context, req *userpb.RegisterPushTokenRequest) (*userpb.RegisterPushTokenResponse, error) {
+	if f.registerPushTokenFn == nil {
+		return &userpb.RegisterPushTokenResponse{}, nil
+	}
+	return f.registerPushTokenFn(ctx, req)
+}
+
+func (f *fakeGatewayDeviceClient) UnregisterPushToken(ctx context.Context, req *userpb.UnregisterPushTokenRequest) (*userpb.UnregisterPushTokenResponse, error) {
+	if f.unregisterPushTokenFn == nil {
+		return &userpb.UnregisterPushTokenResponse{}, nil
+	}
+	return f.unregisterPushTokenFn(ctx, req)
+}
+
+func (f *fakeGatewayDeviceClient) GetNotificationSettings(ctx context.Context, req *userpb.GetNotificationSettingsRequest) (*userpb.GetNotificationSettingsResponse, error) {
+	if f.getNotifySettingsFn == nil {
+		return &userpb.GetNotificationSettingsResponse{}, nil
+	}
+	return f.getNotifySettingsFn(ctx, req)
+}
+
+func (f *fakeGatewayDeviceClient) UpdateNotificationSettings(ctx context.Context, req *userpb.UpdateNotificationSettingsRequest) (*userpb.UpdateNotificationSettingsResponse, error) {
+	if f.updateNotifySettingsFn == nil {
+		return &userpb.UpdateNotificationSettingsResponse{}, nil
+	}
+	return f.updateNotifySettingsFn(ctx, req)
+}
+
 func TestGatewayDeviceServiceGetDeviceList(t *testing.T) {
 	initGatewayDeviceServiceTestLogger()
 
@@ -230,3 +262,112 @@ func TestGatewayDeviceServiceBatchGetOnlineStatus(t *testing.T) {
 		require.ErrorIs(t, err, wantErr)
 	})
 }
+
+func TestGatewayDeviceServicePushToken(t *testing.T) {
+	initGatewayDeviceServiceTestLogger()
+
+	t.Run("register_success_mapping", func(t *testing.T) {
+		svc := NewDeviceService(&fakeGatewayDeviceClient{
+			registerPushTokenFn: func(_ context.Context, req *userpb.RegisterPushTokenRequest) (*userpb.RegisterPushTokenResponse, error) {
+				require.Equal(t, "huawei", req.Provider)
+				require.Equal(t, "tok-1", req.Token)
+				return &userpb.RegisterPushTokenResponse{Provider: "huawei"}, nil
+			},
+		})
+		resp, err := svc.RegisterPushToken(context.Background(), &dto.RegisterPushTokenRequest{Provider: "huawei", Token: "tok-1"})
+		require.NoError(t, err)
+		assert.Equal(t, "huawei", resp.Provider)
+	})
+
+	t.Run("downstream_error_passthrough", func(t *testing.T) {
+		wantErr := errors.New("grpc failed")
+		svc := NewDeviceService(&fakeGatewayDeviceClient{
+			registerPushTokenFn: func(_ context.Context, _ *userpb.RegisterPushTokenRequest) (*userpb.RegisterPushTokenResponse, error) {
+				return nil, wantErr
+			},
+			unregisterPushTokenFn: func(_ context.Context, _ *userpb.UnregisterPushTokenRequest) (*userpb.UnregisterPushTokenResponse, error) {
+				return nil, wantErr
+			},
+		})
+		resp, err := svc.RegisterPushToken(context.Background(), &dto.RegisterPushTokenRequest{Token: "tok-1"})
+		require.Nil(t, resp)
+		require.ErrorIs(t, err, wantErr)
+
+		unregResp, err := svc.UnregisterPushToken(context.Background())
+		require.Nil(t, unregResp)
+		require.ErrorIs(t, err, wantErr)
+	})
+}
+
+func TestGatewayDeviceServiceNotificationSettings(t *testing.T) {
+	initGatewayDeviceServiceTestLogger()
+
+	t.Run("get_success_mapping", func(t *testing.T) {
+		svc := NewDeviceService(&fakeGatewayDeviceClient{
+			getNotifySettingsFn: func(_ context.Context, req *userpb.GetNotificationSettingsRequest) (*userpb.GetNotificationSettingsResponse, error) {
+				require.Equal(t, "d2", req.DeviceId)
+				return &userpb.GetNotificationSettingsResponse{Settings: &userpb.NotificationSettings{
+					DeviceId:          "d2",
+					PushEnabled:       true,
+					QuietHoursEnabled: true,
+					QuietStart:        1320,
+					QuietEnd:          480,
+					Timezone:          "Asia/Shanghai",
+					Sound:             true,
+				}}, nil
+			},
+		})
+		resp, err := svc.GetNotificationSettings(context.Background(), &dto.GetNotificationSettingsRequest{DeviceID: "d2"})
+		require.NoError(t, err)
+		assert.Equal(t, &dto.NotificationSettings{
+			DeviceID:          "d2",
+			PushEnabled:       true,
+			QuietHoursEnabled: true,
+			QuietStart:        1320,
+			QuietEnd:          480,
+			Timezone:          "Asia/Shanghai",
+			ShowPreview:       false,
+			Sound:             true,
+		}, resp.Settings)
+	})
+
+	t.Run("update_passes_only_present_fields", func(t *testing.T) {
+		showPreview := false
+		quietEnd := int32(420)
+		svc := NewDeviceService(&fakeGatewayDeviceClient{
+			updateNotifySettingsFn: func(_ context.Context, req *userpb.UpdateNotificationSettingsRequest) (*userpb.UpdateNotificationSettingsResponse, error) {
+				assert.Nil(t, req.PushEnabled)
+				assert.Nil(t, req.Sound)
+				require.NotNil(t, req.ShowPreview)
+				assert.False(t, *req.ShowPreview)
+				require.NotNil(t, req.QuietEnd)
+				assert.Equal(t, int32(420), *req.QuietEnd)
+				return &userpb.UpdateNotificationSettingsResponse{Settings: &userpb.NotificationSettings{DeviceId: "d1", QuietEnd: 420}}, nil
+			},
+		})
+		resp, err := svc.UpdateNotificationSettings(context.Background(), &dto.UpdateNotificationSettingsRequest{ShowPreview: &showPreview, QuietEnd: &quietEnd})
+		require.NoError(t, err)
+		assert.Equal(t, "d1", resp.Settings.DeviceID)
+		assert.Equal(t, int32(420), resp.Settings.QuietEnd)
+	})
+
+	t.Run("downstream_error_passthrough", func(t *testing.T) {
+		wantErr := errors.New("grpc failed")
+		svc := NewDeviceService(&fakeGatewayDeviceClient{
+			getNotifySettingsFn: func(_ context.Context, _ *userpb.GetNotificationSettingsRequest) (*userpb.GetNotificationSettingsResponse, error) {
+				return nil, wantErr
+			},
+			updateNotifySettingsFn: func(_ context.Context, _ *userpb.UpdateNotificationSettingsRequest) (*userpb.UpdateNotificationSettingsResponse, error) {
+				return nil, wantErr
+			},
+		})
+		getResp, err := svc.GetNotificationSettings(context.Background(), &dto.GetNotificationSettingsRequest{})
+		require.Nil(t, getResp)
+		require.ErrorIs(t, err, wantErr)
+
+		sound := true
+		updateResp, err := svc.UpdateNotificationSettings(context.Background(), &dto.UpdateNotificationSettingsRequest{Sound: &sound})
+		require.Nil(t, updateResp)
+		require.ErrorIs(t, err, wantErr)
+	})
+}
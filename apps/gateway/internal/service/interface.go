@@ -138,6 +138,26 @@ type DeviceService interface {
 
 	// BatchGetOnlineStatus 批量获取在线状态
 	BatchGetOnlineStatus(ctx context.Context, req *dto.BatchGetOnlineStatusRequest) (*dto.BatchGetOnlineStatusResponse, error)
+
+	// RegisterPushToken 登记当前设备的推送 token
+	RegisterPushToken(ctx context.Context, req *dto.RegisterPushTokenRequest) (*dto.RegisterPushTokenResponse, error)
+
+	// UnregisterPushToken 清除当前设备的推送 token
+	UnregisterPushToken(ctx context.Context) (*dto.UnregisterPushTokenResponse, error)
+
+	// GetNotificationSettings 获取设备通知偏好
+	GetNotificationSettings(ctx context.Context, req *dto.GetNotificationSettingsRequest) (*dto.GetNotificationSettingsResponse, error)
+
+	// UpdateNotificationSettings 更新设备通知偏好
+	UpdateNotificationSettings(ctx context.Context, req *dto.UpdateNotificationSettingsRequest) (*dto.UpdateNotificationSettingsResponse, error)
+}
+
+// ConnectService 长连接接入服务接口
+// 职责：
+//   - 签发 connect 握手使用的一次性接入票据（存 Redis，由 connect 兑换）
+type ConnectService interface {
+	// IssueTicket 为当前登录设备签发一次性接入票据
+	IssueTicket(ctx context.Context, req *dto.IssueConnectTicketRequest) (*dto.IssueConnectTicketResponse, error)
 }
 
 // UserService 用户服务接口
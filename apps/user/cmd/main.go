@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"time"
 
+	connectpb "ChatServer/apps/connect/pb"
 	"ChatServer/apps/user/internal/handler"
 	"ChatServer/apps/user/internal/repository"
 	"ChatServer/apps/user/internal/service"
@@ -15,16 +16,20 @@ import (
 	userpb "ChatServer/apps/user/pb"
 	"ChatServer/config"
 	"ChatServer/pkg/async"
+	"ChatServer/pkg/connectroute"
 	"ChatServer/pkg/ctxmeta"
 	pkgdeviceactive "ChatServer/pkg/deviceactive"
 	"ChatServer/pkg/grpcx"
 	"ChatServer/pkg/kafka"
 	"ChatServer/pkg/logger"
+	pkgminio "ChatServer/pkg/minio"
 	"ChatServer/pkg/mysql"
+	"ChatServer/pkg/pushnotify"
 	pkgredis "ChatServer/pkg/redis"
 	"ChatServer/pkg/util"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
 )
 
@@ -148,6 +153,60 @@ func main() {
 		logger.Duration("flush_interval", deviceActiveCfg.FlushInterval),
 	)
 
+	// 4.6 初始化 MinIO 对象存储（用于生成九宫格群头像）
+	minioCfg := config.DefaultMinIOConfig()
+	minioClient, err := pkgminio.Build(minioCfg)
+	if err != nil {
+		// MinIO 初始化失败不阻塞启动，但群头像不会自动生成
+		logger.Warn(ctx, "初始化 MinIO 失败，群头像自动生成不可用",
+			logger.ErrorField("error", err),
+		)
+		minioClient = nil
+	} else {
+		pkgminio.ReplaceGlobal(minioClient)
+		logger.Info(ctx, "MinIO 初始化成功",
+			logger.String("endpoint", minioCfg.Endpoint),
+			logger.String("bucket", minioCfg.BucketName),
+		)
+	}
+
+	// 4.7 初始化 connect-service gRPC 客户端（用于向在线连接下发群公告等通知）
+	// 降级策略：连接创建失败时照常启动，仅跳过实时下发。
+	connectGRPCAddr := os.Getenv("CONNECT_GRPC_ADDR")
+	if connectGRPCAddr == "" {
+		connectGRPCAddr = ":9091"
+	}
+	var connectClient connectpb.ConnectServiceClient
+	connectGRPCConn, err := grpc.NewClient(
+		connectGRPCAddr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		logger.Warn(ctx, "connect-service gRPC 连接创建失败，降级为无实时下发模式",
+			logger.String("addr", connectGRPCAddr),
+			logger.ErrorField("error", err),
+		)
+	} else {
+		defer connectGRPCConn.Close()
+		connectClient = connectpb.NewConnectServiceClient(connectGRPCConn)
+		logger.Info(ctx, "connect-service gRPC 客户端初始化成功",
+			logger.String("addr", connectGRPCAddr),
+		)
+	}
+
+	// 4.8 离线通知：推送时用户/设备无在线连接的消息写入 Kafka，由本服务的通知消费者下发系统推送。
+	// Kafka 仅在 Redis 可用时启用，否则在进程内处理（见 5.1）；未启用时未送达推送直接丢弃。
+	pushNotifyCfg := config.DefaultPushNotifyConfig()
+	var pushNotifyProducer *kafka.Producer
+	if pushNotifyCfg.Enabled && kafkaProducer != nil {
+		pushNotifyProducer = kafka.NewProducer(config.DefaultKafkaConfig().Brokers, pushNotifyCfg.Topic)
+		defer func() {
+			if err := pushNotifyProducer.Close(); err != nil {
+				logger.Error(ctx, "关闭离线通知 Kafka Producer 失败", logger.ErrorField("error", err))
+			}
+		}()
+	}
+
 	// 5. 组装依赖 - Repository 层
 	authRepo := repository.NewAuthRepository(db, redisClient)
 	userRepo := repository.NewUserRepository(db, redisClient)
@@ -155,13 +214,95 @@ func main() {
 	applyRepo := repository.NewApplyRepository(db, redisClient)
 	blacklistRepo := repository.NewBlacklistRepository(db, redisClient)
 	deviceRepo := repository.NewDeviceRepository(db, redisClient)
+	groupRepo := repository.NewGroupRepository(db, redisClient)
+	notificationRepo := repository.NewNotificationRepository(db, redisClient)
+
+	// 5.1 离线通知：按免打扰/设备平台/会话折叠规则经推送通道下发。
+	// 有 Kafka 时未送达推送写入 Kafka 由本服务的通知消费者处理；无 Kafka（单节点模式）时在进程内直接处理。
+	// 未接入 APNs/FCM 等真实通道时可配置本地桩（PUSH_NOTIFY_STUB_URL / PUSH_NOTIFY_STUB_FILE）联调。
+	var undeliveredSink connectroute.UndeliveredSink
+	if pushNotifyCfg.Enabled {
+		var stub pushnotify.Provider
+		switch {
+		case pushNotifyCfg.StubURL != "":
+			stub = pushnotify.NewHTTPProvider(pushNotifyCfg.StubURL)
+		case pushNotifyCfg.StubFile != "":
+			stub = pushnotify.NewFileProvider(pushNotifyCfg.StubFile)
+		default:
+			logger.Warn(ctx, "离线通知未配置推送通道，通知将被丢弃")
+		}
+		notificationService := service.NewNotificationService(
+			deviceRepo,
+			notificationRepo,
+			userRepo,
+			pushnotify.NewDispatcher(stub),
+			service.NotificationConfig{
+				Platforms:      pushNotifyCfg.Platforms,
+				CollapseWindow: pushNotifyCfg.CollapseWindow,
+			},
+		)
+		if pushNotifyProducer != nil {
+			undeliveredSink = pushnotify.NewKafkaSink(pushNotifyProducer, pushNotifyCfg.EnvelopeTypes)
+			pushNotifyConsumer := kafka.NewConsumer(config.DefaultKafkaConfig().Brokers, pushNotifyCfg.Topic, pushNotifyCfg.GroupID)
+			defer func() {
+				if err := pushNotifyConsumer.Close(); err != nil {
+					logger.Error(ctx, "关闭离线通知消费者失败", logger.ErrorField("error", err))
+				}
+			}()
+			go func() {
+				logger.Info(ctx, "离线通知消费者启动中",
+					logger.String("topic", pushNotifyCfg.Topic),
+					logger.String("group_id", pushNotifyCfg.GroupID),
+				)
+				if err := pushNotifyConsumer.Start(ctx, pushnotify.NewKafkaHandler(notificationService.HandleUndelivered)); err != nil {
+					logger.Error(ctx, "离线通知消费者运行错误", logger.ErrorField("error", err))
+				}
+			}()
+		} else {
+			undeliveredSink = pushnotify.NewLocalSink(notificationService.HandleUndelivered, pushNotifyCfg.EnvelopeTypes)
+			logger.Info(ctx, "离线通知未使用 Kafka，未送达推送在进程内处理")
+		}
+	}
+
+	// 5.2 多节点部署时通过 Redis 路由表定位连接所在 connect 节点，
+	// 无 Redis 时保持直连 CONNECT_GRPC_ADDR（单节点模式），按节点投递结果上报未送达推送。
+	// 带 seq 的推送同时写入下行补发缓冲，供客户端断线重连后补发。
+	routeCfg := config.DefaultConnectRouteConfig()
+	connectOutbox := connectroute.NewOutbox(redisClient, connectroute.OutboxConfig{
+		MaxEntries: routeCfg.OutboxMaxEntries,
+		TTL:        routeCfg.OutboxTTL,
+	})
+	if connectRouter := connectroute.NewRouter(redisClient, connectroute.RouterConfig{
+		Outbox:      connectOutbox,
+		Undelivered: undeliveredSink,
+	}); connectRouter != nil {
+		defer connectRouter.Close()
+		connectClient = connectRouter
+		logger.Info(ctx, "connect 集群路由客户端初始化成功")
+	} else {
+		connectClient = connectroute.NewDirect(connectClient, undeliveredSink)
+	}
+	// 重试队列中的踢线任务同样经由路由客户端执行。
+	if redisConsumer != nil && connectClient != nil {
+		redisConsumer.SetConnectClient(connectClient)
+	}
 
 	// 6. 组装依赖 - Service 层
-	authService := service.NewAuthService(authRepo, deviceRepo)
-	userService := service.NewUserService(userRepo, authRepo, deviceRepo)
+	connectKicker := service.NewConnectKicker(connectClient)
+	authService := service.NewAuthService(authRepo, deviceRepo, connectKicker)
+	userService := service.NewUserService(userRepo, authRepo, deviceRepo, connectKicker)
 	friendService := service.NewFriendService(friendRepo, applyRepo, blacklistRepo)
 	blacklistService := service.NewBlacklistService(blacklistRepo)
-	deviceService := service.NewDeviceService(deviceRepo)
+	deviceService := service.NewDeviceService(deviceRepo, connectKicker)
+	groupAvatarGenerator := service.NewGroupAvatarGenerator(
+		groupRepo,
+		userRepo,
+		service.NewMinIOGroupAvatarStorage(minioClient),
+		0,
+	)
+	defer groupAvatarGenerator.Stop()
+	connectPusher := service.NewConnectPusher(connectClient)
+	groupService := service.NewGroupService(groupRepo, userRepo, groupAvatarGenerator, connectPusher)
 
 	// 7. 组装依赖 - Handler 层
 	authHandler := handler.NewAuthHandler(authService)
@@ -169,6 +310,7 @@ func main() {
 	friendHandler := handler.NewFriendHandler(friendService)
 	blacklistHandler := handler.NewBlacklistHandler(blacklistService)
 	deviceHandler := handler.NewDeviceHandler(deviceService)
+	groupHandler := handler.NewGroupHandler(groupService)
 
 	// 8. 初始化小组件
 	util.InitSnowflake(1) // 雪花算法
@@ -218,6 +360,7 @@ func main() {
 		userpb.RegisterFriendServiceServer(s, friendHandler)
 		userpb.RegisterBlacklistServiceServer(s, blacklistHandler)
 		userpb.RegisterDeviceServiceServer(s, deviceHandler)
+		userpb.RegisterGroupServiceServer(s, groupHandler)
 
 		if hs != nil {
 			if setter, ok := hs.(interface {
@@ -49,3 +49,23 @@ func (h *DeviceHandler) UpdateDeviceActive(ctx context.Context, req *pb.UpdateDe
 func (h *DeviceHandler) UpdateDeviceStatus(ctx context.Context, req *pb.UpdateDeviceStatusRequest) (*pb.UpdateDeviceStatusResponse, error) {
 	return &pb.UpdateDeviceStatusResponse{}, h.deviceService.UpdateDeviceStatus(ctx, req)
 }
+
+// RegisterPushToken 登记推送 token
+func (h *DeviceHandler) RegisterPushToken(ctx context.Context, req *pb.RegisterPushTokenRequest) (*pb.RegisterPushTokenResponse, error) {
+	return h.deviceService.RegisterPushToken(ctx, req)
+}
+
+// UnregisterPushToken 清除推送 token
+func (h *DeviceHandler) UnregisterPushToken(ctx context.Context, req *pb.UnregisterPushTokenRequest) (*pb.UnregisterPushTokenResponse, error) {
+	return &pb.UnregisterPushTokenResponse{}, h.deviceService.UnregisterPushToken(ctx, req)
+}
+
+// UpdateNotificationSettings 更新设备通知偏好
+func (h *DeviceHandler) UpdateNotificationSettings(ctx context.Context, req *pb.UpdateNotificationSettingsRequest) (*pb.UpdateNotificationSettingsResponse, error) {
+	return h.deviceService.UpdateNotificationSettings(ctx, req)
+}
+
+// GetNotificationSettings 获取设备通知偏好
+func (h *DeviceHandler) GetNotificationSettings(ctx context.Context, req *pb.GetNotificationSettingsRequest) (*pb.GetNotificationSettingsResponse, error) {
+	return h.deviceService.GetNotificationSettings(ctx, req)
+}
@@ -19,6 +19,10 @@ type fakeDeviceHandlerService struct {
 	batchGetOnlineStatusFn func(context.Context, *pb.BatchGetOnlineStatusRequest) (*pb.BatchGetOnlineStatusResponse, error)
 	updateDeviceActiveFn   func(context.Context, *pb.UpdateDeviceActiveRequest) error
 	updateDeviceStatusFn   func(context.Context, *pb.UpdateDeviceStatusRequest) error
+	registerPushTokenFn    func(context.Context, *pb.RegisterPushTokenRequest) (*pb.RegisterPushTokenResponse, error)
+	unregisterPushTokenFn  func(context.Context, *pb.UnregisterPushTokenRequest) error
+	updateNotifySettingsFn func(context.Context, *pb.UpdateNotificationSettingsRequest) (*pb.UpdateNotificationSettingsResponse, error)
+	getNotifySettingsFn    func(context.Context, *pb.GetNotificationSettingsRequest) (*pb.GetNotificationSettingsResponse, error)
 }
 
 var _ service.IDeviceService = (*fakeDeviceHandlerService)(nil)
@@ -65,6 +69,34 @@ func (f *fakeDeviceHandlerService) UpdateDeviceActive(ctx context.Context, req *
 	return f.updateDeviceActiveFn(ctx, req)
 }
 
+func (f *fakeDeviceHandlerService) RegisterPushToken(ctx context.Context, req *pb.RegisterPushTokenRequest) (*pb.RegisterPushTokenResponse, error) {
+	if f.registerPushTokenFn == nil {
+		return &pb.RegisterPushTokenResponse{}, nil
+	}
+	return f.registerPushTokenFn(ctx, req)
+}
+
+func (f *fakeDeviceHandlerService) UnregisterPushToken(ctx context.Context, req *pb.UnregisterPushTokenRequest) error {
+	if f.unregisterPushTokenFn == nil {
+		return nil
+	}
+	return f.unregisterPushTokenFn(ctx, req)
+}
+
+func (f *fakeDeviceHandlerService) UpdateNotificationSettings(ctx context.Context, req *pb.UpdateNotificationSettingsRequest) (*pb.UpdateNotificationSettingsResponse, error) {
+	if f.updateNotifySettingsFn == nil {
+		return &pb.UpdateNotificationSettingsResponse{}, nil
+	}
+	return f.updateNotifySettingsFn(ctx, req)
+}
+
+func (f *fakeDeviceHandlerService) GetNotificationSettings(ctx context.Context, req *pb.GetNotificationSettingsRequest) (*pb.GetNotificationSettingsResponse, error) {
+	if f.getNotifySettingsFn == nil {
+		return &pb.GetNotificationSettingsResponse{}, nil
+	}
+	return f.getNotifySettingsFn(ctx, req)
+}
+
 func TestUserDeviceHandlerGetDeviceList(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		want := &pb.GetDeviceListResponse{Devices: []*pb.DeviceItem{{DeviceId: "d1"}}}
@@ -255,3 +287,79 @@ func TestUserDeviceHandlerUpdateDeviceActive(t *testing.T) {
 		assert.IsType(t, &pb.UpdateDeviceActiveResponse{}, resp)
 	})
 }
+
+func TestUserDeviceHandlerRegisterPushToken(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		h := NewDeviceHandler(&fakeDeviceHandlerService{
+			registerPushTokenFn: func(_ context.Context, req *pb.RegisterPushTokenRequest) (*pb.RegisterPushTokenResponse, error) {
+				require.Equal(t, "tok-1", req.Token)
+				return &pb.RegisterPushTokenResponse{Provider: "apns"}, nil
+			},
+		})
+		resp, err := h.RegisterPushToken(context.Background(), &pb.RegisterPushTokenRequest{Token: "tok-1"})
+		require.NoError(t, err)
+		assert.Equal(t, "apns", resp.Provider)
+	})
+
+	t.Run("error_passthrough", func(t *testing.T) {
+		wantErr := errors.New("register failed")
+		h := NewDeviceHandler(&fakeDeviceHandlerService{
+			registerPushTokenFn: func(_ context.Context, _ *pb.RegisterPushTokenRequest) (*pb.RegisterPushTokenResponse, error) {
+				return nil, wantErr
+			},
+		})
+		resp, err := h.RegisterPushToken(context.Background(), &pb.RegisterPushTokenRequest{Token: "tok-1"})
+		require.ErrorIs(t, err, wantErr)
+		assert.Nil(t, resp)
+	})
+}
+
+func TestUserDeviceHandlerUnregisterPushToken(t *testing.T) {
+	t.Run("success_empty_response_contract", func(t *testing.T) {
+		h := NewDeviceHandler(&fakeDeviceHandlerService{})
+		resp, err := h.UnregisterPushToken(context.Background(), &pb.UnregisterPushTokenRequest{})
+		require.NoError(t, err)
+		assert.IsType(t, &pb.UnregisterPushTokenResponse{}, resp)
+	})
+
+	t.Run("error_passthrough", func(t *testing.T) {
+		wantErr := errors.New("unregister failed")
+		h := NewDeviceHandler(&fakeDeviceHandlerService{
+			unregisterPushTokenFn: func(_ context.Context, _ *pb.UnregisterPushTokenRequest) error {
+				return wantErr
+			},
+		})
+		resp, err := h.UnregisterPushToken(context.Background(), &pb.UnregisterPushTokenRequest{})
+		require.ErrorIs(t, err, wantErr)
+		assert.IsType(t, &pb.UnregisterPushTokenResponse{}, resp)
+	})
+}
+
+func TestUserDeviceHandlerNotificationSettings(t *testing.T) {
+	t.Run("update_passthrough", func(t *testing.T) {
+		want := &pb.UpdateNotificationSettingsResponse{Settings: &pb.NotificationSettings{DeviceId: "d1", Sound: false}}
+		h := NewDeviceHandler(&fakeDeviceHandlerService{
+			updateNotifySettingsFn: func(_ context.Context, req *pb.UpdateNotificationSettingsRequest) (*pb.UpdateNotificationSettingsResponse, error) {
+				require.NotNil(t, req.Sound)
+				assert.False(t, *req.Sound)
+				return want, nil
+			},
+		})
+		sound := false
+		resp, err := h.UpdateNotificationSettings(context.Background(), &pb.UpdateNotificationSettingsRequest{Sound: &sound})
+		require.NoError(t, err)
+		assert.Same(t, want, resp)
+	})
+
+	t.Run("get_error_passthrough", func(t *testing.T) {
+		wantErr := errors.New("get failed")
+		h := NewDeviceHandler(&fakeDeviceHandlerService{
+			getNotifySettingsFn: func(_ context.Context, _ *pb.GetNotificationSettingsRequest) (*pb.GetNotificationSettingsResponse, error) {
+				return nil, wantErr
+			},
+		})
+		resp, err := h.GetNotificationSettings(context.Background(), &pb.GetNotificationSettingsRequest{DeviceId: "d1"})
+		require.ErrorIs(t, err, wantErr)
+		assert.Nil(t, resp)
+	})
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"ChatServer/apps/user/internal/service"
+	pb "ChatServer/apps/user/pb"
+	"context"
+)
+
+// GroupHandler 群组服务Handler
+type GroupHandler struct {
+	pb.UnimplementedGroupServiceServer
+
+	groupService service.IGroupService
+}
+
+// NewGroupHandler 创建群组Handler实例
+func NewGroupHandler(groupService service.IGroupService) *GroupHandler {
+	return &GroupHandler{
+		groupService: groupService,
+	}
+}
+
+// SetGroupCard 设置群名片
+func (h *GroupHandler) SetGroupCard(ctx context.Context, req *pb.SetGroupCardRequest) (*pb.SetGroupCardResponse, error) {
+	return &pb.SetGroupCardResponse{}, h.groupService.SetGroupCard(ctx, req)
+}
+
+// UpdateGroupSettings 更新本人的群设置
+func (h *GroupHandler) UpdateGroupSettings(ctx context.Context, req *pb.UpdateGroupSettingsRequest) (*pb.UpdateGroupSettingsResponse, error) {
+	return &pb.UpdateGroupSettingsResponse{}, h.groupService.UpdateGroupSettings(ctx, req)
+}
+
+// GetGroupSettings 获取本人的群设置
+func (h *GroupHandler) GetGroupSettings(ctx context.Context, req *pb.GetGroupSettingsRequest) (*pb.GetGroupSettingsResponse, error) {
+	return h.groupService.GetGroupSettings(ctx, req)
+}
+
+// BatchGetMemberDisplayName 批量获取群成员展示名（内部调用）
+func (h *GroupHandler) BatchGetMemberDisplayName(ctx context.Context, req *pb.BatchGetMemberDisplayNameRequest) (*pb.BatchGetMemberDisplayNameResponse, error) {
+	return h.groupService.BatchGetMemberDisplayName(ctx, req)
+}
+
+// CreateGroupInvite 创建群邀请
+func (h *GroupHandler) CreateGroupInvite(ctx context.Context, req *pb.CreateGroupInviteRequest) (*pb.CreateGroupInviteResponse, error) {
+	return h.groupService.CreateGroupInvite(ctx, req)
+}
+
+// RevokeGroupInvite 撤销群邀请
+func (h *GroupHandler) RevokeGroupInvite(ctx context.Context, req *pb.RevokeGroupInviteRequest) (*pb.RevokeGroupInviteResponse, error) {
+	return &pb.RevokeGroupInviteResponse{}, h.groupService.RevokeGroupInvite(ctx, req)
+}
+
+// PreviewGroupInvite 预览群邀请
+func (h *GroupHandler) PreviewGroupInvite(ctx context.Context, req *pb.PreviewGroupInviteRequest) (*pb.PreviewGroupInviteResponse, error) {
+	return h.groupService.PreviewGroupInvite(ctx, req)
+}
+
+// JoinGroupByInvite 通过群邀请入群
+func (h *GroupHandler) JoinGroupByInvite(ctx context.Context, req *pb.JoinGroupByInviteRequest) (*pb.JoinGroupByInviteResponse, error) {
+	return h.groupService.JoinGroupByInvite(ctx, req)
+}
+
+// ListGroupJoinRequests 获取待审核的入群申请
+func (h *GroupHandler) ListGroupJoinRequests(ctx context.Context, req *pb.ListGroupJoinRequestsRequest) (*pb.ListGroupJoinRequestsResponse, error) {
+	return h.groupService.ListGroupJoinRequests(ctx, req)
+}
+
+// HandleGroupJoinRequest 处理入群申请
+func (h *GroupHandler) HandleGroupJoinRequest(ctx context.Context, req *pb.HandleGroupJoinRequestRequest) (*pb.HandleGroupJoinRequestResponse, error) {
+	return &pb.HandleGroupJoinRequestResponse{}, h.groupService.HandleGroupJoinRequest(ctx, req)
+}
+
+// SetGroupAvatar 设置群头像
+func (h *GroupHandler) SetGroupAvatar(ctx context.Context, req *pb.SetGroupAvatarRequest) (*pb.SetGroupAvatarResponse, error) {
+	return &pb.SetGroupAvatarResponse{}, h.groupService.SetGroupAvatar(ctx, req)
+}
+
+// PostGroupAnnouncement 发布群公告
+func (h *GroupHandler) PostGroupAnnouncement(ctx context.Context, req *pb.PostGroupAnnouncementRequest) (*pb.PostGroupAnnouncementResponse, error) {
+	return h.groupService.PostGroupAnnouncement(ctx, req)
+}
+
+// EditGroupAnnouncement 编辑群公告
+func (h *GroupHandler) EditGroupAnnouncement(ctx context.Context, req *pb.EditGroupAnnouncementRequest) (*pb.EditGroupAnnouncementResponse, error) {
+	return &pb.EditGroupAnnouncementResponse{}, h.groupService.EditGroupAnnouncement(ctx, req)
+}
+
+// PinGroupAnnouncement 置顶/取消置顶群公告
+func (h *GroupHandler) PinGroupAnnouncement(ctx context.Context, req *pb.PinGroupAnnouncementRequest) (*pb.PinGroupAnnouncementResponse, error) {
+	return &pb.PinGroupAnnouncementResponse{}, h.groupService.PinGroupAnnouncement(ctx, req)
+}
+
+// DeleteGroupAnnouncement 删除群公告
+func (h *GroupHandler) DeleteGroupAnnouncement(ctx context.Context, req *pb.DeleteGroupAnnouncementRequest) (*pb.DeleteGroupAnnouncementResponse, error) {
+	return &pb.DeleteGroupAnnouncementResponse{}, h.groupService.DeleteGroupAnnouncement(ctx, req)
+}
+
+// ListGroupAnnouncements 获取群公告列表
+func (h *GroupHandler) ListGroupAnnouncements(ctx context.Context, req *pb.ListGroupAnnouncementsRequest) (*pb.ListGroupAnnouncementsResponse, error) {
+	return h.groupService.ListGroupAnnouncements(ctx, req)
+}
+
+// AckGroupAnnouncement 确认群公告
+func (h *GroupHandler) AckGroupAnnouncement(ctx context.Context, req *pb.AckGroupAnnouncementRequest) (*pb.AckGroupAnnouncementResponse, error) {
+	return &pb.AckGroupAnnouncementResponse{}, h.groupService.AckGroupAnnouncement(ctx, req)
+}
+
+// GetGroupAnnouncementAcks 查询公告确认情况
+func (h *GroupHandler) GetGroupAnnouncementAcks(ctx context.Context, req *pb.GetGroupAnnouncementAcksRequest) (*pb.GetGroupAnnouncementAcksResponse, error) {
+	return h.groupService.GetGroupAnnouncementAcks(ctx, req)
+}
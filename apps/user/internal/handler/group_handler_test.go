@@ -0,0 +1,332 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"ChatServer/apps/user/internal/service"
+	pb "ChatServer/apps/user/pb"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeGroupHandlerService struct {
+	setGroupCardFn              func(context.Context, *pb.SetGroupCardRequest) error
+	updateGroupSettingsFn       func(context.Context, *pb.UpdateGroupSettingsRequest) error
+	getGroupSettingsFn          func(context.Context, *pb.GetGroupSettingsRequest) (*pb.GetGroupSettingsResponse, error)
+	batchGetMemberDisplayNameFn func(context.Context, *pb.BatchGetMemberDisplayNameRequest) (*pb.BatchGetMemberDisplayNameResponse, error)
+	createGroupInviteFn         func(context.Context, *pb.CreateGroupInviteRequest) (*pb.CreateGroupInviteResponse, error)
+	revokeGroupInviteFn         func(context.Context, *pb.RevokeGroupInviteRequest) error
+	previewGroupInviteFn        func(context.Context, *pb.PreviewGroupInviteRequest) (*pb.PreviewGroupInviteResponse, error)
+	joinGroupByInviteFn         func(context.Context, *pb.JoinGroupByInviteRequest) (*pb.JoinGroupByInviteResponse, error)
+	listGroupJoinRequestsFn     func(context.Context, *pb.ListGroupJoinRequestsRequest) (*pb.ListGroupJoinRequestsResponse, error)
+	handleGroupJoinRequestFn    func(context.Context, *pb.HandleGroupJoinRequestRequest) error
+	setGroupAvatarFn            func(context.Context, *pb.SetGroupAvatarRequest) error
+	postGroupAnnouncementFn     func(context.Context, *pb.PostGroupAnnouncementRequest) (*pb.PostGroupAnnouncementResponse, error)
+	editGroupAnnouncementFn     func(context.Context, *pb.EditGroupAnnouncementRequest) error
+	pinGroupAnnouncementFn      func(context.Context, *pb.PinGroupAnnouncementRequest) error
+	deleteGroupAnnouncementFn   func(context.Context, *pb.DeleteGroupAnnouncementRequest) error
+	listGroupAnnouncementsFn    func(context.Context, *pb.ListGroupAnnouncementsRequest) (*pb.ListGroupAnnouncementsResponse, error)
+	ackGroupAnnouncementFn      func(context.Context, *pb.AckGroupAnnouncementRequest) error
+	getGroupAnnouncementAcksFn  func(context.Context, *pb.GetGroupAnnouncementAcksRequest) (*pb.GetGroupAnnouncementAcksResponse, error)
+}
+
+var _ service.IGroupService = (*fakeGroupHandlerService)(nil)
+
+func (f *fakeGroupHandlerService) SetGroupCard(ctx context.Context, req *pb.SetGroupCardRequest) error {
+	if f.setGroupCardFn == nil {
+		return nil
+	}
+	return f.setGroupCardFn(ctx, req)
+}
+
+func (f *fakeGroupHandlerService) UpdateGroupSettings(ctx context.Context, req *pb.UpdateGroupSettingsRequest) error {
+	if f.updateGroupSettingsFn == nil {
+		return nil
+	}
+	return f.updateGroupSettingsFn(ctx, req)
+}
+
+func (f *fakeGroupHandlerService) GetGroupSettings(ctx context.Context, req *pb.GetGroupSettingsRequest) (*pb.GetGroupSettingsResponse, error) {
+	if f.getGroupSettingsFn == nil {
+		return &pb.GetGroupSettingsResponse{}, nil
+	}
+	return f.getGroupSettingsFn(ctx, req)
+}
+
+func (f *fakeGroupHandlerService) BatchGetMemberDisplayName(ctx context.Context, req *pb.BatchGetMemberDisplayNameRequest) (*pb.BatchGetMemberDisplayNameResponse, error) {
+	if f.batchGetMemberDisplayNameFn == nil {
+		return &pb.BatchGetMemberDisplayNameResponse{}, nil
+	}
+	return f.batchGetMemberDisplayNameFn(ctx, req)
+}
+
+func (f *fakeGroupHandlerService) CreateGroupInvite(ctx context.Context, req *pb.CreateGroupInviteRequest) (*pb.CreateGroupInviteResponse, error) {
+	if f.createGroupInviteFn == nil {
+		return &pb.CreateGroupInviteResponse{}, nil
+	}
+	return f.createGroupInviteFn(ctx, req)
+}
+
+func (f *fakeGroupHandlerService) RevokeGroupInvite(ctx context.Context, req *pb.RevokeGroupInviteRequest) error {
+	if f.revokeGroupInviteFn == nil {
+		return nil
+	}
+	return f.revokeGroupInviteFn(ctx, req)
+}
+
+func (f *fakeGroupHandlerService) PreviewGroupInvite(ctx context.Context, req *pb.PreviewGroupInviteRequest) (*pb.PreviewGroupInviteResponse, error) {
+	if f.previewGroupInviteFn == nil {
+		return &pb.PreviewGroupInviteResponse{}, nil
+	}
+	return f.previewGroupInviteFn(ctx, req)
+}
+
+func (f *fakeGroupHandlerService) JoinGroupByInvite(ctx context.Context, req *pb.JoinGroupByInviteRequest) (*pb.JoinGroupByInviteResponse, error) {
+	if f.joinGroupByInviteFn == nil {
+		return &pb.JoinGroupByInviteResponse{}, nil
+	}
+	return f.joinGroupByInviteFn(ctx, req)
+}
+
+func (f *fakeGroupHandlerService) ListGroupJoinRequests(ctx context.Context, req *pb.ListGroupJoinRequestsRequest) (*pb.ListGroupJoinRequestsResponse, error) {
+	if f.listGroupJoinRequestsFn == nil {
+		return &pb.ListGroupJoinRequestsResponse{}, nil
+	}
+	return f.listGroupJoinRequestsFn(ctx, req)
+}
+
+func (f *fakeGroupHandlerService) HandleGroupJoinRequest(ctx context.Context, req *pb.HandleGroupJoinRequestRequest) error {
+	if f.handleGroupJoinRequestFn == nil {
+		return nil
+	}
+	return f.handleGroupJoinRequestFn(ctx, req)
+}
+
+func (f *fakeGroupHandlerService) SetGroupAvatar(ctx context.Context, req *pb.SetGroupAvatarRequest) error {
+	if f.setGroupAvatarFn == nil {
+		return nil
+	}
+	return f.setGroupAvatarFn(ctx, req)
+}
+
+func (f *fakeGroupHandlerService) PostGroupAnnouncement(ctx context.Context, req *pb.PostGroupAnnouncementRequest) (*pb.PostGroupAnnouncementResponse, error) {
+	if f.postGroupAnnouncementFn == nil {
+		return &pb.PostGroupAnnouncementResponse{}, nil
+	}
+	return f.postGroupAnnouncementFn(ctx, req)
+}
+
+func (f *fakeGroupHandlerService) EditGroupAnnouncement(ctx context.Context, req *pb.EditGroupAnnouncementRequest) error {
+	if f.editGroupAnnouncementFn == nil {
+		return nil
+	}
+	return f.editGroupAnnouncementFn(ctx, req)
+}
+
+func (f *fakeGroupHandlerService) PinGroupAnnouncement(ctx context.Context, req *pb.PinGroupAnnouncementRequest) error {
+	if f.pinGroupAnnouncementFn == nil {
+		return nil
+	}
+	return f.pinGroupAnnouncementFn(ctx, req)
+}
+
+func (f *fakeGroupHandlerService) DeleteGroupAnnouncement(ctx context.Context, req *pb.DeleteGroupAnnouncementRequest) error {
+	if f.deleteGroupAnnouncementFn == nil {
+		return nil
+	}
+	return f.deleteGroupAnnouncementFn(ctx, req)
+}
+
+func (f *fakeGroupHandlerService) ListGroupAnnouncements(ctx context.Context, req *pb.ListGroupAnnouncementsRequest) (*pb.ListGroupAnnouncementsResponse, error) {
+	if f.listGroupAnnouncementsFn == nil {
+		return &pb.ListGroupAnnouncementsResponse{}, nil
+	}
+	return f.listGroupAnnouncementsFn(ctx, req)
+}
+
+func (f *fakeGroupHandlerService) AckGroupAnnouncement(ctx context.Context, req *pb.AckGroupAnnouncementRequest) error {
+	if f.ackGroupAnnouncementFn == nil {
+		return nil
+	}
+	return f.ackGroupAnnouncementFn(ctx, req)
+}
+
+func (f *fakeGroupHandlerService) GetGroupAnnouncementAcks(ctx context.Context, req *pb.GetGroupAnnouncementAcksRequest) (*pb.GetGroupAnnouncementAcksResponse, error) {
+	if f.getGroupAnnouncementAcksFn == nil {
+		return &pb.GetGroupAnnouncementAcksResponse{}, nil
+	}
+	return f.getGroupAnnouncementAcksFn(ctx, req)
+}
+
+func TestUserGroupHandlerSetGroupCard(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		h := NewGroupHandler(&fakeGroupHandlerService{})
+		resp, err := h.SetGroupCard(context.Background(), &pb.SetGroupCardRequest{GroupUuid: "g1", Card: "c"})
+		require.NoError(t, err)
+		assert.NotNil(t, resp)
+	})
+
+	t.Run("error_passthrough", func(t *testing.T) {
+		wantErr := errors.New("set failed")
+		h := NewGroupHandler(&fakeGroupHandlerService{
+			setGroupCardFn: func(context.Context, *pb.SetGroupCardRequest) error {
+				return wantErr
+			},
+		})
+		_, err := h.SetGroupCard(context.Background(), &pb.SetGroupCardRequest{GroupUuid: "g1"})
+		require.ErrorIs(t, err, wantErr)
+	})
+}
+
+func TestUserGroupHandlerSettings(t *testing.T) {
+	t.Run("update_error_passthrough", func(t *testing.T) {
+		wantErr := errors.New("update failed")
+		h := NewGroupHandler(&fakeGroupHandlerService{
+			updateGroupSettingsFn: func(context.Context, *pb.UpdateGroupSettingsRequest) error {
+				return wantErr
+			},
+		})
+		_, err := h.UpdateGroupSettings(context.Background(), &pb.UpdateGroupSettingsRequest{GroupUuid: "g1"})
+		require.ErrorIs(t, err, wantErr)
+	})
+
+	t.Run("get_success", func(t *testing.T) {
+		want := &pb.GetGroupSettingsResponse{Settings: &pb.GroupSettings{GroupUuid: "g1", MuteNotify: true}}
+		h := NewGroupHandler(&fakeGroupHandlerService{
+			getGroupSettingsFn: func(context.Context, *pb.GetGroupSettingsRequest) (*pb.GetGroupSettingsResponse, error) {
+				return want, nil
+			},
+		})
+		resp, err := h.GetGroupSettings(context.Background(), &pb.GetGroupSettingsRequest{GroupUuid: "g1"})
+		require.NoError(t, err)
+		assert.Equal(t, want, resp)
+	})
+}
+
+func TestUserGroupHandlerBatchGetMemberDisplayName(t *testing.T) {
+	want := &pb.BatchGetMemberDisplayNameResponse{Members: []*pb.MemberDisplayName{{UserUuid: "u1", DisplayName: "card"}}}
+	h := NewGroupHandler(&fakeGroupHandlerService{
+		batchGetMemberDisplayNameFn: func(context.Context, *pb.BatchGetMemberDisplayNameRequest) (*pb.BatchGetMemberDisplayNameResponse, error) {
+			return want, nil
+		},
+	})
+	resp, err := h.BatchGetMemberDisplayName(context.Background(), &pb.BatchGetMemberDisplayNameRequest{GroupUuid: "g1", UserUuids: []string{"u1"}})
+	require.NoError(t, err)
+	assert.Equal(t, want, resp)
+}
+
+func TestUserGroupHandlerInvite(t *testing.T) {
+	t.Run("create_success", func(t *testing.T) {
+		want := &pb.CreateGroupInviteResponse{Token: "t1", InviteUrl: "https://www.LCchat.top/g/t1"}
+		h := NewGroupHandler(&fakeGroupHandlerService{
+			createGroupInviteFn: func(context.Context, *pb.CreateGroupInviteRequest) (*pb.CreateGroupInviteResponse, error) {
+				return want, nil
+			},
+		})
+		resp, err := h.CreateGroupInvite(context.Background(), &pb.CreateGroupInviteRequest{GroupUuid: "g1"})
+		require.NoError(t, err)
+		assert.Equal(t, want, resp)
+	})
+
+	t.Run("revoke_error_passthrough", func(t *testing.T) {
+		wantErr := errors.New("revoke failed")
+		h := NewGroupHandler(&fakeGroupHandlerService{
+			revokeGroupInviteFn: func(context.Context, *pb.RevokeGroupInviteRequest) error {
+				return wantErr
+			},
+		})
+		_, err := h.RevokeGroupInvite(context.Background(), &pb.RevokeGroupInviteRequest{Token: "t1"})
+		require.ErrorIs(t, err, wantErr)
+	})
+
+	t.Run("join_success", func(t *testing.T) {
+		want := &pb.JoinGroupByInviteResponse{GroupUuid: "g1", Pending: true}
+		h := NewGroupHandler(&fakeGroupHandlerService{
+			joinGroupByInviteFn: func(context.Context, *pb.JoinGroupByInviteRequest) (*pb.JoinGroupByInviteResponse, error) {
+				return want, nil
+			},
+		})
+		resp, err := h.JoinGroupByInvite(context.Background(), &pb.JoinGroupByInviteRequest{Token: "t1"})
+		require.NoError(t, err)
+		assert.Equal(t, want, resp)
+	})
+
+	t.Run("handle_join_request_error_passthrough", func(t *testing.T) {
+		wantErr := errors.New("handle failed")
+		h := NewGroupHandler(&fakeGroupHandlerService{
+			handleGroupJoinRequestFn: func(context.Context, *pb.HandleGroupJoinRequestRequest) error {
+				return wantErr
+			},
+		})
+		_, err := h.HandleGroupJoinRequest(context.Background(), &pb.HandleGroupJoinRequestRequest{GroupUuid: "g1", UserUuid: "u2"})
+		require.ErrorIs(t, err, wantErr)
+	})
+}
+
+func TestUserGroupHandlerSetGroupAvatar(t *testing.T) {
+	t.Run("success_passthrough", func(t *testing.T) {
+		var got *pb.SetGroupAvatarRequest
+		h := NewGroupHandler(&fakeGroupHandlerService{
+			setGroupAvatarFn: func(_ context.Context, req *pb.SetGroupAvatarRequest) error {
+				got = req
+				return nil
+			},
+		})
+		req := &pb.SetGroupAvatarRequest{GroupUuid: "g1", Avatar: "https://cdn/a.png"}
+		resp, err := h.SetGroupAvatar(context.Background(), req)
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+		assert.Equal(t, req, got)
+	})
+
+	t.Run("error_passthrough", func(t *testing.T) {
+		wantErr := errors.New("set avatar failed")
+		h := NewGroupHandler(&fakeGroupHandlerService{
+			setGroupAvatarFn: func(context.Context, *pb.SetGroupAvatarRequest) error {
+				return wantErr
+			},
+		})
+		_, err := h.SetGroupAvatar(context.Background(), &pb.SetGroupAvatarRequest{GroupUuid: "g1"})
+		require.ErrorIs(t, err, wantErr)
+	})
+}
+
+func TestUserGroupHandlerAnnouncements(t *testing.T) {
+	t.Run("post_success_passthrough", func(t *testing.T) {
+		want := &pb.PostGroupAnnouncementResponse{Announcement: &pb.GroupAnnouncement{AnnouncementUuid: "a1"}}
+		h := NewGroupHandler(&fakeGroupHandlerService{
+			postGroupAnnouncementFn: func(context.Context, *pb.PostGroupAnnouncementRequest) (*pb.PostGroupAnnouncementResponse, error) {
+				return want, nil
+			},
+		})
+		resp, err := h.PostGroupAnnouncement(context.Background(), &pb.PostGroupAnnouncementRequest{GroupUuid: "g1", Content: "hi"})
+		require.NoError(t, err)
+		assert.Equal(t, want, resp)
+	})
+
+	t.Run("ack_error_passthrough", func(t *testing.T) {
+		wantErr := errors.New("ack failed")
+		h := NewGroupHandler(&fakeGroupHandlerService{
+			ackGroupAnnouncementFn: func(context.Context, *pb.AckGroupAnnouncementRequest) error {
+				return wantErr
+			},
+		})
+		_, err := h.AckGroupAnnouncement(context.Background(), &pb.AckGroupAnnouncementRequest{GroupUuid: "g1", AnnouncementUuid: "a1"})
+		require.ErrorIs(t, err, wantErr)
+	})
+
+	t.Run("get_acks_success_passthrough", func(t *testing.T) {
+		want := &pb.GetGroupAnnouncementAcksResponse{UnackedUserUuids: []string{"u2"}}
+		h := NewGroupHandler(&fakeGroupHandlerService{
+			getGroupAnnouncementAcksFn: func(context.Context, *pb.GetGroupAnnouncementAcksRequest) (*pb.GetGroupAnnouncementAcksResponse, error) {
+				return want, nil
+			},
+		})
+		resp, err := h.GetGroupAnnouncementAcks(context.Background(), &pb.GetGroupAnnouncementAcksRequest{GroupUuid: "g1", AnnouncementUuid: "a1"})
+		require.NoError(t, err)
+		assert.Equal(t, want, resp)
+	})
+}
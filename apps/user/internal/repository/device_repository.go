@@ -9,11 +9,13 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // deviceRepositoryImpl 设备会话数据访问层实现
@@ -568,6 +570,98 @@ func (r *deviceRepositoryImpl) UpdateOnlineStatus(ctx context.Context, userUUID,
 	return nil
 }
 
+// UpdatePushToken 登记或清除设备的推送 token（token 为空表示清除）。
+// 同一 token 只能归属一个设备会话：登记时先清除其他会话上的同值 token（换号登录/重装后 token 复用）。
+func (r *deviceRepositoryImpl) UpdatePushToken(ctx context.Context, userUUID, deviceID, provider, token string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if token != "" {
+			if err := tx.Model(&model.DeviceSession{}).
+				Where("push_token = ? AND NOT (user_uuid = ? AND device_id = ?)", token, userUUID, deviceID).
+				Updates(map[string]interface{}{
+					"push_provider": "",
+					"push_token":    "",
+				}).Error; err != nil {
+				return err
+			}
+		}
+
+		result := tx.Model(&model.DeviceSession{}).
+			Where("user_uuid = ? AND device_id = ? AND deleted_at IS NULL", userUUID, deviceID).
+			Updates(map[string]interface{}{
+				"push_provider": provider,
+				"push_token":    token,
+				"updated_at":    time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return err
+		}
+		return WrapDBError(err)
+	}
+	return nil
+}
+
+// GetPushTargets 获取用户已登记推送 token 且未登出/未被踢的设备会话
+func (r *deviceRepositoryImpl) GetPushTargets(ctx context.Context, userUUID string) ([]*model.DeviceSession, error) {
+	var sessions []*model.DeviceSession
+	err := r.db.WithContext(ctx).
+		Where("user_uuid = ? AND push_token <> '' AND status IN ?", userUUID,
+			[]int8{model.DeviceStatusOnline, model.DeviceStatusOffline}).
+		Find(&sessions).Error
+	if err != nil {
+		return nil, WrapDBError(err)
+	}
+	return sessions, nil
+}
+
+// GetNotificationSetting 获取设备通知偏好，未设置过返回 ErrRecordNotFound
+func (r *deviceRepositoryImpl) GetNotificationSetting(ctx context.Context, userUUID, deviceID string) (*model.DeviceNotificationSetting, error) {
+	var setting model.DeviceNotificationSetting
+	err := r.db.WithContext(ctx).
+		Where("user_uuid = ? AND device_id = ?", userUUID, deviceID).
+		First(&setting).Error
+	if err != nil {
+		return nil, WrapDBError(err)
+	}
+	return &setting, nil
+}
+
+// ListNotificationSettings 获取用户所有设备的通知偏好（key 为 device_id）
+func (r *deviceRepositoryImpl) ListNotificationSettings(ctx context.Context, userUUID string) (map[string]*model.DeviceNotificationSetting, error) {
+	var settings []*model.DeviceNotificationSetting
+	if err := r.db.WithContext(ctx).Where("user_uuid = ?", userUUID).Find(&settings).Error; err != nil {
+		return nil, WrapDBError(err)
+	}
+	result := make(map[string]*model.DeviceNotificationSetting, len(settings))
+	for _, setting := range settings {
+		result[setting.DeviceId] = setting
+	}
+	return result, nil
+}
+
+// UpsertNotificationSetting 写入设备通知偏好（Insert On Duplicate Key Update，整行覆盖）
+func (r *deviceRepositoryImpl) UpsertNotificationSetting(ctx context.Context, setting *model.DeviceNotificationSetting) error {
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_uuid"}, {Name: "device_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"push_enabled", "quiet_hours_enabled", "quiet_start", "quiet_end",
+			"timezone", "show_preview", "sound", "updated_at",
+		}),
+	}).Create(setting).Error
+	if err != nil {
+		return WrapDBError(err)
+	}
+	return nil
+}
+
 // UpdateLastSeen 更新最后活跃时间
 func (r *deviceRepositoryImpl) UpdateLastSeen(ctx context.Context, userUUID, deviceID string) error {
 	return nil // TODO: 更新最后活跃时间
@@ -31,6 +31,15 @@ var (
 
 	// ErrApplyNotFound 申请不存在或已处理
 	ErrApplyNotFound = errors.New("apply not found or already processed")
+
+	// ErrGroupInviteExhausted 群邀请使用次数已达上限
+	ErrGroupInviteExhausted = errors.New("group invite exhausted")
+
+	// ErrGroupFull 群成员已达上限
+	ErrGroupFull = errors.New("group is full")
+
+	// ErrGroupApplyPending 已有待审核的入群申请
+	ErrGroupApplyPending = errors.New("group join request pending")
 )
 
 // ==================== 核心包装函数 ====================
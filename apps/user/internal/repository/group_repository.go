@@ -0,0 +1,574 @@
+package repository
+
+import (
+	"ChatServer/consts/redisKey"
+	"ChatServer/model"
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// groupRepositoryImpl 群组数据访问层实现
+type groupRepositoryImpl struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+// NewGroupRepository 创建群组仓储实例
+func NewGroupRepository(db *gorm.DB, redisClient *redis.Client) IGroupRepository {
+	return &groupRepositoryImpl{db: db, redisClient: redisClient}
+}
+
+// GetGroup 根据UUID查询群组信息
+// 群组不存在时返回 ErrRecordNotFound。
+func (r *groupRepositoryImpl) GetGroup(ctx context.Context, groupUUID string) (*model.GroupInfo, error) {
+	var group model.GroupInfo
+	err := r.db.WithContext(ctx).
+		Where("uuid = ? AND deleted_at IS NULL", groupUUID).
+		First(&group).Error
+	if err != nil {
+		return nil, WrapDBError(err)
+	}
+	return &group, nil
+}
+
+// GetMember 查询正常状态的群成员
+// 非成员（含已退出、被踢出、待审核）返回 ErrRecordNotFound。
+func (r *groupRepositoryImpl) GetMember(ctx context.Context, groupUUID, userUUID string) (*model.GroupMember, error) {
+	var member model.GroupMember
+	err := r.db.WithContext(ctx).
+		Where("group_uuid = ? AND user_uuid = ? AND status = ? AND deleted_at IS NULL",
+			groupUUID, userUUID, model.GroupMemberStatusNormal).
+		First(&member).Error
+	if err != nil {
+		return nil, WrapDBError(err)
+	}
+	return &member, nil
+}
+
+// BatchGetMembers 批量查询正常状态的群成员
+// 非成员不包含在结果中。
+func (r *groupRepositoryImpl) BatchGetMembers(ctx context.Context, groupUUID string, userUUIDs []string) ([]*model.GroupMember, error) {
+	if len(userUUIDs) == 0 {
+		return []*model.GroupMember{}, nil
+	}
+
+	var members []*model.GroupMember
+	err := r.db.WithContext(ctx).
+		Where("group_uuid = ? AND user_uuid IN ? AND status = ? AND deleted_at IS NULL",
+			groupUUID, userUUIDs, model.GroupMemberStatusNormal).
+		Find(&members).Error
+	if err != nil {
+		return nil, WrapDBError(err)
+	}
+	return members, nil
+}
+
+// UpdateMemberCard 更新群名片
+func (r *groupRepositoryImpl) UpdateMemberCard(ctx context.Context, groupUUID, userUUID, card string) error {
+	result := r.db.WithContext(ctx).
+		Model(&model.GroupMember{}).
+		Where("group_uuid = ? AND user_uuid = ? AND status = ? AND deleted_at IS NULL",
+			groupUUID, userUUID, model.GroupMemberStatusNormal).
+		Updates(map[string]interface{}{
+			"remark":     card,
+			"updated_at": time.Now(),
+		})
+
+	if result.Error != nil {
+		return WrapDBError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpdateMemberSettings 更新成员个人群设置
+// 仅更新 update 中非 nil 的字段。
+func (r *groupRepositoryImpl) UpdateMemberSettings(ctx context.Context, groupUUID, userUUID string, update GroupMemberSettingsUpdate) error {
+	updates := map[string]interface{}{
+		"updated_at": time.Now(),
+	}
+	if update.SaveToContacts != nil {
+		updates["save_to_contacts"] = *update.SaveToContacts
+	}
+	if update.HideMemberNickname != nil {
+		updates["hide_member_nickname"] = *update.HideMemberNickname
+	}
+	if update.MuteNotify != nil {
+		updates["mute_notify"] = *update.MuteNotify
+	}
+
+	result := r.db.WithContext(ctx).
+		Model(&model.GroupMember{}).
+		Where("group_uuid = ? AND user_uuid = ? AND status = ? AND deleted_at IS NULL",
+			groupUUID, userUUID, model.GroupMemberStatusNormal).
+		Updates(updates)
+
+	if result.Error != nil {
+		return WrapDBError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// AddMember 添加群成员
+// 在同一事务内锁定 (group_uuid, user_uuid) 的历史记录：
+//   - 无记录：直接插入
+//   - 已退出/被踢出/待审核/软删除：复用该记录并重置成员个人数据
+//   - 已是正常成员：返回 ErrDuplicateKey
+//
+// member.Status 为正常时同步累加 group_info.member_cnt。
+func (r *groupRepositoryImpl) AddMember(ctx context.Context, member *model.GroupMember) error {
+	now := time.Now()
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing model.GroupMember
+		err := tx.Unscoped().
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("group_uuid = ? AND user_uuid = ?", member.GroupUuid, member.UserUuid).
+			First(&existing).Error
+
+		switch {
+		case err == nil:
+			if !existing.DeletedAt.Valid && existing.Status == model.GroupMemberStatusNormal {
+				return ErrDuplicateKey
+			}
+			if !existing.DeletedAt.Valid && existing.Status == model.GroupMemberStatusPending &&
+				member.Status == model.GroupMemberStatusPending {
+				return ErrGroupApplyPending
+			}
+			if err := tx.Unscoped().
+				Model(&model.GroupMember{}).
+				Where("id = ?", existing.Id).
+				Updates(map[string]interface{}{
+					"role":                 member.Role,
+					"remark":               member.Remark,
+					"status":               member.Status,
+					"mute_until":           nil,
+					"inviter_uuid":         member.Inviter,
+					"save_to_contacts":     false,
+					"hide_member_nickname": false,
+					"mute_notify":          false,
+					"joined_at":            now,
+					"updated_at":           now,
+					"deleted_at":           nil, // 恢复软删除
+				}).Error; err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := tx.Create(member).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		if member.Status != model.GroupMemberStatusNormal {
+			return nil
+		}
+		return incrGroupMemberCount(tx, member.GroupUuid)
+	})
+	if errors.Is(err, ErrDuplicateKey) || errors.Is(err, ErrGroupApplyPending) || errors.Is(err, ErrGroupFull) {
+		return err
+	}
+	return WrapDBError(err)
+}
+
+// incrGroupMemberCount 在事务内累加群人数，已达 model.GroupMaxMembers 时返回 ErrGroupFull（事务随之回滚）。
+// 条件更新持有群组行锁，并发入群不会突破上限。
+func incrGroupMemberCount(tx *gorm.DB, groupUUID string) error {
+	result := tx.Model(&model.GroupInfo{}).
+		Where("uuid = ? AND member_cnt < ?", groupUUID, model.GroupMaxMembers).
+		UpdateColumn("member_cnt", gorm.Expr("member_cnt + ?", 1))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrGroupFull
+	}
+	return nil
+}
+
+// GetPendingMember 查询待审核的入群申请
+func (r *groupRepositoryImpl) GetPendingMember(ctx context.Context, groupUUID, userUUID string) (*model.GroupMember, error) {
+	var member model.GroupMember
+	err := r.db.WithContext(ctx).
+		Where("group_uuid = ? AND user_uuid = ? AND status = ? AND deleted_at IS NULL",
+			groupUUID, userUUID, model.GroupMemberStatusPending).
+		First(&member).Error
+	if err != nil {
+		return nil, WrapDBError(err)
+	}
+	return &member, nil
+}
+
+// ListPendingMembers 查询待审核的入群申请（按申请时间倒序）
+func (r *groupRepositoryImpl) ListPendingMembers(ctx context.Context, groupUUID string) ([]*model.GroupMember, error) {
+	var members []*model.GroupMember
+	err := r.db.WithContext(ctx).
+		Where("group_uuid = ? AND status = ? AND deleted_at IS NULL", groupUUID, model.GroupMemberStatusPending).
+		Order("updated_at DESC").
+		Find(&members).Error
+	if err != nil {
+		return nil, WrapDBError(err)
+	}
+	return members, nil
+}
+
+// ApprovePendingMember 通过入群申请
+// 申请不存在（或已处理）时返回 ErrRecordNotFound，群人数已达上限时返回 ErrGroupFull（申请保持待审核）。
+func (r *groupRepositoryImpl) ApprovePendingMember(ctx context.Context, groupUUID, userUUID string) error {
+	now := time.Now()
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.GroupMember{}).
+			Where("group_uuid = ? AND user_uuid = ? AND status = ? AND deleted_at IS NULL",
+				groupUUID, userUUID, model.GroupMemberStatusPending).
+			Updates(map[string]interface{}{
+				"status":     model.GroupMemberStatusNormal,
+				"joined_at":  now,
+				"updated_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+		return incrGroupMemberCount(tx, groupUUID)
+	})
+	if errors.Is(err, ErrRecordNotFound) || errors.Is(err, ErrGroupFull) {
+		return err
+	}
+	return WrapDBError(err)
+}
+
+// RejectPendingMember 拒绝入群申请
+// 申请不存在（或已处理）时返回 ErrRecordNotFound。
+func (r *groupRepositoryImpl) RejectPendingMember(ctx context.Context, groupUUID, userUUID string) error {
+	result := r.db.WithContext(ctx).
+		Model(&model.GroupMember{}).
+		Where("group_uuid = ? AND user_uuid = ? AND status = ? AND deleted_at IS NULL",
+			groupUUID, userUUID, model.GroupMemberStatusPending).
+		Updates(map[string]interface{}{
+			"status":     model.GroupMemberStatusQuit,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return WrapDBError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListAvatarMembers 按入群顺序查询前 limit 个正常成员
+func (r *groupRepositoryImpl) ListAvatarMembers(ctx context.Context, groupUUID string, limit int) ([]*model.GroupMember, error) {
+	var members []*model.GroupMember
+	err := r.db.WithContext(ctx).
+		Where("group_uuid = ? AND status = ? AND deleted_at IS NULL", groupUUID, model.GroupMemberStatusNormal).
+		Order("joined_at ASC, id ASC").
+		Limit(limit).
+		Find(&members).Error
+	if err != nil {
+		return nil, WrapDBError(err)
+	}
+	return members, nil
+}
+
+// UpdateGeneratedAvatar 写入自动生成的群头像
+// 条件更新 avatar_custom = 0，避免与群主设置自定义头像并发时覆盖自定义头像。
+func (r *groupRepositoryImpl) UpdateGeneratedAvatar(ctx context.Context, groupUUID, avatar string) error {
+	result := r.db.WithContext(ctx).
+		Model(&model.GroupInfo{}).
+		Where("uuid = ? AND avatar_custom = ? AND deleted_at IS NULL", groupUUID, false).
+		Updates(map[string]interface{}{
+			"avatar":     avatar,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return WrapDBError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpdateCustomAvatar 设置群头像及其是否为自定义头像
+func (r *groupRepositoryImpl) UpdateCustomAvatar(ctx context.Context, groupUUID, avatar string, custom bool) error {
+	result := r.db.WithContext(ctx).
+		Model(&model.GroupInfo{}).
+		Where("uuid = ? AND deleted_at IS NULL", groupUUID).
+		Updates(map[string]interface{}{
+			"avatar":        avatar,
+			"avatar_custom": custom,
+			"updated_at":    time.Now(),
+		})
+	if result.Error != nil {
+		return WrapDBError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListMemberUUIDs 查询群内全部正常成员的UUID
+func (r *groupRepositoryImpl) ListMemberUUIDs(ctx context.Context, groupUUID string) ([]string, error) {
+	var userUUIDs []string
+	err := r.db.WithContext(ctx).
+		Model(&model.GroupMember{}).
+		Where("group_uuid = ? AND status = ? AND deleted_at IS NULL", groupUUID, model.GroupMemberStatusNormal).
+		Pluck("user_uuid", &userUUIDs).Error
+	if err != nil {
+		return nil, WrapDBError(err)
+	}
+	return userUUIDs, nil
+}
+
+// ==================== 群公告 ====================
+
+// CreateAnnouncement 创建群公告
+func (r *groupRepositoryImpl) CreateAnnouncement(ctx context.Context, announcement *model.GroupAnnouncement) error {
+	if err := r.db.WithContext(ctx).Create(announcement).Error; err != nil {
+		return WrapDBError(err)
+	}
+	return nil
+}
+
+// GetAnnouncement 查询群公告
+func (r *groupRepositoryImpl) GetAnnouncement(ctx context.Context, groupUUID, announcementUUID string) (*model.GroupAnnouncement, error) {
+	var announcement model.GroupAnnouncement
+	err := r.db.WithContext(ctx).
+		Where("uuid = ? AND group_uuid = ? AND deleted_at IS NULL", announcementUUID, groupUUID).
+		First(&announcement).Error
+	if err != nil {
+		return nil, WrapDBError(err)
+	}
+	return &announcement, nil
+}
+
+// ListAnnouncements 查询群公告列表
+func (r *groupRepositoryImpl) ListAnnouncements(ctx context.Context, groupUUID string, limit int) ([]*model.GroupAnnouncement, error) {
+	var announcements []*model.GroupAnnouncement
+	err := r.db.WithContext(ctx).
+		Where("group_uuid = ? AND deleted_at IS NULL", groupUUID).
+		Order("pinned DESC, created_at DESC, id DESC").
+		Limit(limit).
+		Find(&announcements).Error
+	if err != nil {
+		return nil, WrapDBError(err)
+	}
+	return announcements, nil
+}
+
+// UpdateAnnouncement 编辑群公告内容
+// 内容变化后旧的确认不再有效，同一事务内清空确认记录；仅修改是否需要确认时保留已有确认。
+func (r *groupRepositoryImpl) UpdateAnnouncement(ctx context.Context, announcementUUID, editorUUID, content string, requireAck bool) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current model.GroupAnnouncement
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("uuid = ? AND deleted_at IS NULL", announcementUUID).
+			First(&current).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.GroupAnnouncement{}).
+			Where("id = ?", current.Id).
+			Updates(map[string]interface{}{
+				"content":     content,
+				"editor_uuid": editorUUID,
+				"require_ack": requireAck,
+				"updated_at":  time.Now(),
+			}).Error; err != nil {
+			return err
+		}
+		if current.Content == content {
+			return nil
+		}
+		return tx.Where("announcement_uuid = ?", announcementUUID).
+			Delete(&model.GroupAnnouncementAck{}).Error
+	})
+	if err != nil {
+		return WrapDBError(err)
+	}
+	return nil
+}
+
+// SetAnnouncementPinned 置顶/取消置顶群公告
+func (r *groupRepositoryImpl) SetAnnouncementPinned(ctx context.Context, announcementUUID string, pinned bool) error {
+	result := r.db.WithContext(ctx).
+		Model(&model.GroupAnnouncement{}).
+		Where("uuid = ? AND deleted_at IS NULL", announcementUUID).
+		Updates(map[string]interface{}{
+			"pinned":     pinned,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return WrapDBError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteAnnouncement 删除群公告（软删除）
+func (r *groupRepositoryImpl) DeleteAnnouncement(ctx context.Context, announcementUUID string) error {
+	result := r.db.WithContext(ctx).
+		Where("uuid = ?", announcementUUID).
+		Delete(&model.GroupAnnouncement{})
+	if result.Error != nil {
+		return WrapDBError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// AckAnnouncement 确认群公告
+// 依赖 uidx_announcement_user 唯一索引，重复确认不更新确认时间。
+func (r *groupRepositoryImpl) AckAnnouncement(ctx context.Context, announcementUUID, userUUID string) error {
+	err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.GroupAnnouncementAck{
+			AnnouncementUuid: announcementUUID,
+			UserUuid:         userUUID,
+		}).Error
+	if err != nil {
+		return WrapDBError(err)
+	}
+	return nil
+}
+
+// ListAnnouncementAcks 查询公告的全部确认记录
+func (r *groupRepositoryImpl) ListAnnouncementAcks(ctx context.Context, announcementUUID string) ([]*model.GroupAnnouncementAck, error) {
+	var acks []*model.GroupAnnouncementAck
+	err := r.db.WithContext(ctx).
+		Where("announcement_uuid = ?", announcementUUID).
+		Order("acked_at ASC, id ASC").
+		Find(&acks).Error
+	if err != nil {
+		return nil, WrapDBError(err)
+	}
+	return acks, nil
+}
+
+// ListAckedAnnouncementUUIDs 查询用户已确认的公告UUID
+func (r *groupRepositoryImpl) ListAckedAnnouncementUUIDs(ctx context.Context, userUUID string, announcementUUIDs []string) (map[string]bool, error) {
+	acked := make(map[string]bool, len(announcementUUIDs))
+	if len(announcementUUIDs) == 0 {
+		return acked, nil
+	}
+
+	var uuids []string
+	err := r.db.WithContext(ctx).
+		Model(&model.GroupAnnouncementAck{}).
+		Where("user_uuid = ? AND announcement_uuid IN ?", userUUID, announcementUUIDs).
+		Pluck("announcement_uuid", &uuids).Error
+	if err != nil {
+		return nil, WrapDBError(err)
+	}
+	for _, uuid := range uuids {
+		acked[uuid] = true
+	}
+	return acked, nil
+}
+
+// ==================== Redis 群邀请管理 ====================
+
+// SaveGroupInvite 保存群邀请
+// 使用 Hash 存储，字段：group_uuid / creator_uuid / max_uses / used / require_approval / expire_at，
+// 并以 EXPIREAT 对齐邀请的过期时间。
+func (r *groupRepositoryImpl) SaveGroupInvite(ctx context.Context, invite *GroupInvite) error {
+	key := rediskey.GroupInviteKey(invite.Token)
+	requireApproval := "0"
+	if invite.RequireApproval {
+		requireApproval = "1"
+	}
+
+	_, err := r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, map[string]interface{}{
+			"group_uuid":       invite.GroupUUID,
+			"creator_uuid":     invite.CreatorUUID,
+			"max_uses":         invite.MaxUses,
+			"used":             invite.UsedCount,
+			"require_approval": requireApproval,
+			"expire_at":        invite.ExpireAt.UnixMilli(),
+		})
+		pipe.ExpireAt(ctx, key, invite.ExpireAt)
+		return nil
+	})
+	if err != nil {
+		return WrapRedisError(err)
+	}
+	return nil
+}
+
+// GetGroupInvite 获取群邀请
+func (r *groupRepositoryImpl) GetGroupInvite(ctx context.Context, token string) (*GroupInvite, error) {
+	values, err := r.redisClient.HGetAll(ctx, rediskey.GroupInviteKey(token)).Result()
+	if err != nil {
+		return nil, WrapRedisError(err)
+	}
+	if len(values) == 0 || values["group_uuid"] == "" {
+		return nil, ErrRedisNil
+	}
+
+	maxUses, _ := strconv.Atoi(values["max_uses"])
+	used, _ := strconv.Atoi(values["used"])
+	expireAtMs, _ := strconv.ParseInt(values["expire_at"], 10, 64)
+
+	return &GroupInvite{
+		Token:           token,
+		GroupUUID:       values["group_uuid"],
+		CreatorUUID:     values["creator_uuid"],
+		MaxUses:         maxUses,
+		UsedCount:       used,
+		RequireApproval: values["require_approval"] == "1",
+		ExpireAt:        time.UnixMilli(expireAtMs),
+	}, nil
+}
+
+// ConsumeGroupInvite 原子占用一次使用次数
+func (r *groupRepositoryImpl) ConsumeGroupInvite(ctx context.Context, token string) error {
+	luaScript := redis.NewScript(luaConsumeGroupInvite)
+	result, err := luaScript.Run(ctx, r.redisClient, []string{rediskey.GroupInviteKey(token)}).Int()
+	if err != nil {
+		return WrapRedisError(err)
+	}
+	switch result {
+	case -1:
+		return ErrRedisNil
+	case -2:
+		return ErrGroupInviteExhausted
+	}
+	return nil
+}
+
+// ReleaseGroupInvite 归还一次使用次数
+func (r *groupRepositoryImpl) ReleaseGroupInvite(ctx context.Context, token string) error {
+	luaScript := redis.NewScript(luaReleaseGroupInvite)
+	if err := luaScript.Run(ctx, r.redisClient, []string{rediskey.GroupInviteKey(token)}).Err(); err != nil {
+		return WrapRedisError(err)
+	}
+	return nil
+}
+
+// DeleteGroupInvite 删除群邀请
+func (r *groupRepositoryImpl) DeleteGroupInvite(ctx context.Context, token string) error {
+	if err := r.redisClient.Del(ctx, rediskey.GroupInviteKey(token)).Err(); err != nil {
+		return WrapRedisError(err)
+	}
+	return nil
+}
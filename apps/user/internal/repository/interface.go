@@ -12,6 +12,25 @@ type DeviceActiveItem struct {
 	DeviceID string
 }
 
+// GroupMemberSettingsUpdate 成员个人群设置的部分更新项
+// 字段为 nil 表示不修改。
+type GroupMemberSettingsUpdate struct {
+	SaveToContacts     *bool
+	HideMemberNickname *bool
+	MuteNotify         *bool
+}
+
+// GroupInvite 群邀请（二维码 / 分享链接），仅存储于 Redis，过期即失效
+type GroupInvite struct {
+	Token           string
+	GroupUUID       string
+	CreatorUUID     string    // 链接创建人，入群后记为 GroupMember.Inviter
+	MaxUses         int       // 最大使用次数，0 表示不限
+	UsedCount       int       // 已使用次数
+	RequireApproval bool      // 创建时要求入群审核
+	ExpireAt        time.Time // 过期时间
+}
+
 // ==================== 认证相关 Repository ====================
 
 // IAuthRepository 认证相关数据访问接口
@@ -273,6 +292,21 @@ type IDeviceRepository interface {
 	// BatchGetOnlineStatus 批量获取用户在线状态
 	BatchGetOnlineStatus(ctx context.Context, userUUIDs []string) (map[string][]*model.DeviceSession, error)
 
+	// UpdatePushToken 登记或清除设备的推送 token（token 为空表示清除），设备不存在返回 ErrRecordNotFound
+	UpdatePushToken(ctx context.Context, userUUID, deviceID, provider, token string) error
+
+	// GetPushTargets 获取用户已登记推送 token 且未登出/未被踢的设备会话
+	GetPushTargets(ctx context.Context, userUUID string) ([]*model.DeviceSession, error)
+
+	// GetNotificationSetting 获取设备通知偏好，未设置过返回 ErrRecordNotFound
+	GetNotificationSetting(ctx context.Context, userUUID, deviceID string) (*model.DeviceNotificationSetting, error)
+
+	// ListNotificationSettings 获取用户所有设备的通知偏好（key 为 device_id，未设置过的设备不在结果中）
+	ListNotificationSettings(ctx context.Context, userUUID string) (map[string]*model.DeviceNotificationSetting, error)
+
+	// UpsertNotificationSetting 写入设备通知偏好（整行覆盖）
+	UpsertNotificationSetting(ctx context.Context, setting *model.DeviceNotificationSetting) error
+
 	// UpdateToken 更新Token
 	UpdateToken(ctx context.Context, userUUID, deviceID, token, refreshToken string, expireAt *time.Time) error
 
@@ -296,3 +330,114 @@ type IDeviceRepository interface {
 	// DeleteTokens 删除设备的所有 Token（用于踢出设备）
 	DeleteTokens(ctx context.Context, userUUID, deviceID string) error
 }
+
+// ==================== 离线通知 Repository ====================
+
+// INotificationRepository 离线通知数据访问接口
+type INotificationRepository interface {
+	// GetConversation 查询用户的会话（仅免打扰判断所需字段），会话不存在返回 ErrRecordNotFound
+	GetConversation(ctx context.Context, ownerUUID, convID string) (*model.Conversation, error)
+
+	// IsGroupNotifyMuted 查询群成员是否开启了群消息免打扰，非成员视为未开启
+	IsGroupNotifyMuted(ctx context.Context, groupUUID, userUUID string) (bool, error)
+
+	// IncrCollapseCount 递增会话折叠计数，返回窗口内第几条事件（1 表示窗口内第一条）
+	IncrCollapseCount(ctx context.Context, userUUID, collapseID string, window time.Duration) (int64, error)
+}
+
+// ==================== 群组 Repository ====================
+
+// IGroupRepository 群组数据访问接口
+type IGroupRepository interface {
+	// GetGroup 根据UUID查询群组信息
+	GetGroup(ctx context.Context, groupUUID string) (*model.GroupInfo, error)
+
+	// GetMember 查询正常状态的群成员
+	GetMember(ctx context.Context, groupUUID, userUUID string) (*model.GroupMember, error)
+
+	// BatchGetMembers 批量查询正常状态的群成员
+	BatchGetMembers(ctx context.Context, groupUUID string, userUUIDs []string) ([]*model.GroupMember, error)
+
+	// UpdateMemberCard 更新群名片
+	UpdateMemberCard(ctx context.Context, groupUUID, userUUID, card string) error
+
+	// UpdateMemberSettings 更新成员个人群设置
+	UpdateMemberSettings(ctx context.Context, groupUUID, userUUID string, update GroupMemberSettingsUpdate) error
+
+	// GetPendingMember 查询待审核的入群申请，不存在返回 ErrRecordNotFound
+	GetPendingMember(ctx context.Context, groupUUID, userUUID string) (*model.GroupMember, error)
+
+	// AddMember 添加群成员（Status 为正常时同步累加群人数）
+	// 已退出/被踢出/待审核的历史记录会被复用；已是正常成员时返回 ErrDuplicateKey，
+	// 重复提交待审核申请时返回 ErrGroupApplyPending，群人数已达 model.GroupMaxMembers 时返回 ErrGroupFull。
+	AddMember(ctx context.Context, member *model.GroupMember) error
+
+	// ListPendingMembers 查询待审核的入群申请
+	ListPendingMembers(ctx context.Context, groupUUID string) ([]*model.GroupMember, error)
+
+	// ApprovePendingMember 通过入群申请（待审核 -> 正常，并累加群人数），群人数已达上限时返回 ErrGroupFull
+	ApprovePendingMember(ctx context.Context, groupUUID, userUUID string) error
+
+	// RejectPendingMember 拒绝入群申请（待审核 -> 已退出）
+	RejectPendingMember(ctx context.Context, groupUUID, userUUID string) error
+
+	// ListAvatarMembers 按入群顺序查询前 limit 个正常成员（用于生成九宫格群头像）
+	ListAvatarMembers(ctx context.Context, groupUUID string, limit int) ([]*model.GroupMember, error)
+
+	// UpdateGeneratedAvatar 写入自动生成的群头像
+	// 仅在群头像不是自定义头像时生效，否则返回 ErrRecordNotFound。
+	UpdateGeneratedAvatar(ctx context.Context, groupUUID, avatar string) error
+
+	// UpdateCustomAvatar 设置群头像及其是否为自定义头像
+	UpdateCustomAvatar(ctx context.Context, groupUUID, avatar string, custom bool) error
+
+	// ListMemberUUIDs 查询群内全部正常成员的UUID
+	ListMemberUUIDs(ctx context.Context, groupUUID string) ([]string, error)
+
+	// ==================== 群公告 ====================
+
+	// CreateAnnouncement 创建群公告
+	CreateAnnouncement(ctx context.Context, announcement *model.GroupAnnouncement) error
+
+	// GetAnnouncement 查询群公告，不存在或已删除返回 ErrRecordNotFound
+	GetAnnouncement(ctx context.Context, groupUUID, announcementUUID string) (*model.GroupAnnouncement, error)
+
+	// ListAnnouncements 查询群公告列表（置顶优先，再按发布时间倒序）
+	ListAnnouncements(ctx context.Context, groupUUID string, limit int) ([]*model.GroupAnnouncement, error)
+
+	// UpdateAnnouncement 编辑群公告，内容变化时清空已有确认记录
+	UpdateAnnouncement(ctx context.Context, announcementUUID, editorUUID, content string, requireAck bool) error
+
+	// SetAnnouncementPinned 置顶/取消置顶群公告
+	SetAnnouncementPinned(ctx context.Context, announcementUUID string, pinned bool) error
+
+	// DeleteAnnouncement 删除群公告（软删除）
+	DeleteAnnouncement(ctx context.Context, announcementUUID string) error
+
+	// AckAnnouncement 确认群公告（重复确认幂等）
+	AckAnnouncement(ctx context.Context, announcementUUID, userUUID string) error
+
+	// ListAnnouncementAcks 查询公告的全部确认记录
+	ListAnnouncementAcks(ctx context.Context, announcementUUID string) ([]*model.GroupAnnouncementAck, error)
+
+	// ListAckedAnnouncementUUIDs 查询用户已确认的公告UUID（在给定范围内）
+	ListAckedAnnouncementUUIDs(ctx context.Context, userUUID string, announcementUUIDs []string) (map[string]bool, error)
+
+	// ==================== Redis 群邀请管理 ====================
+
+	// SaveGroupInvite 保存群邀请，按 ExpireAt 设置过期
+	SaveGroupInvite(ctx context.Context, invite *GroupInvite) error
+
+	// GetGroupInvite 获取群邀请，不存在或已过期返回 ErrRedisNil
+	GetGroupInvite(ctx context.Context, token string) (*GroupInvite, error)
+
+	// ConsumeGroupInvite 原子占用一次使用次数
+	// 不存在返回 ErrRedisNil，次数已用完返回 ErrGroupInviteExhausted。
+	ConsumeGroupInvite(ctx context.Context, token string) error
+
+	// ReleaseGroupInvite 归还一次使用次数（入群失败时回滚）
+	ReleaseGroupInvite(ctx context.Context, token string) error
+
+	// DeleteGroupInvite 删除群邀请（撤销）
+	DeleteGroupInvite(ctx context.Context, token string) error
+}
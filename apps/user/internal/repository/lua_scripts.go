@@ -117,5 +117,31 @@ if redis.call('EXISTS', KEYS[1]) == 1 then
 	return 1
 end
 return 0
+`
+
+	// luaConsumeGroupInvite 原子占用一次群邀请使用次数
+	// KEYS[1]: 群邀请 Hash
+	// 返回: -1 表示邀请不存在，-2 表示次数已用完，否则为占用后的已使用次数
+	luaConsumeGroupInvite = `
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -1
+end
+local maxUses = tonumber(redis.call('HGET', KEYS[1], 'max_uses') or '0')
+local used = tonumber(redis.call('HGET', KEYS[1], 'used') or '0')
+if maxUses > 0 and used >= maxUses then
+	return -2
+end
+return redis.call('HINCRBY', KEYS[1], 'used', 1)
+`
+
+	// luaReleaseGroupInvite 归还一次群邀请使用次数（入群失败时回滚）
+	// KEYS[1]: 群邀请 Hash
+	// 返回: 1 表示归还成功，0 表示 key 不存在或无可归还次数
+	luaReleaseGroupInvite = `
+if redis.call('EXISTS', KEYS[1]) == 1 and tonumber(redis.call('HGET', KEYS[1], 'used') or '0') > 0 then
+	redis.call('HINCRBY', KEYS[1], 'used', -1)
+	return 1
+end
+return 0
 `
 )
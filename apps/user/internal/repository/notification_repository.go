@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"ChatServer/consts/redisKey"
+	"ChatServer/model"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// notificationRepositoryImpl 离线通知数据访问层实现
+type notificationRepositoryImpl struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+// NewNotificationRepository 创建离线通知仓储实例
+func NewNotificationRepository(db *gorm.DB, redisClient *redis.Client) INotificationRepository {
+	return &notificationRepositoryImpl{db: db, redisClient: redisClient}
+}
+
+// GetConversation 查询用户的会话（仅免打扰判断所需字段）
+// 会话不存在时返回 ErrRecordNotFound。
+func (r *notificationRepositoryImpl) GetConversation(ctx context.Context, ownerUUID, convID string) (*model.Conversation, error) {
+	var conv model.Conversation
+	err := r.db.WithContext(ctx).
+		Select("conv_id", "type", "target_uuid", "mute").
+		Where("owner_uuid = ? AND conv_id = ? AND status = 0", ownerUUID, convID).
+		First(&conv).Error
+	if err != nil {
+		return nil, WrapDBError(err)
+	}
+	return &conv, nil
+}
+
+// IsGroupNotifyMuted 查询群成员是否开启了群消息免打扰（UpdateMemberSettings 写入），非成员视为未开启
+func (r *notificationRepositoryImpl) IsGroupNotifyMuted(ctx context.Context, groupUUID, userUUID string) (bool, error) {
+	var member model.GroupMember
+	err := r.db.WithContext(ctx).
+		Select("mute_notify").
+		Where("group_uuid = ? AND user_uuid = ? AND status = ? AND deleted_at IS NULL",
+			groupUUID, userUUID, model.GroupMemberStatusNormal).
+		First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, WrapDBError(err)
+	}
+	return member.MuteNotify, nil
+}
+
+// IncrCollapseCount 递增会话折叠计数，返回窗口内第几条事件（1 表示窗口内第一条）。
+// Redis 不可用时始终返回 1（不折叠）。
+func (r *notificationRepositoryImpl) IncrCollapseCount(ctx context.Context, userUUID, collapseID string, window time.Duration) (int64, error) {
+	if r.redisClient == nil {
+		return 1, nil
+	}
+	seconds := int64(window.Seconds())
+	if seconds <= 0 {
+		seconds = int64(rediskey.PushNotifyCollapseTTL.Seconds())
+	}
+	count, err := r.redisClient.Eval(ctx, luaIncrementWithExpire,
+		[]string{rediskey.PushNotifyCollapseKey(userUUID, collapseID)}, seconds).Int64()
+	if err != nil {
+		return 1, WrapRedisError(err)
+	}
+	return count, nil
+}
@@ -64,16 +64,20 @@ func getRequiredDeviceID(ctx context.Context) (string, error) {
 type authServiceImpl struct {
 	authRepo   repository.IAuthRepository
 	deviceRepo repository.IDeviceRepository
+	kicker     ConnectKicker
 }
 
 // NewAuthService 创建认证服务实例
+// kicker 可为 nil，此时登出/重置密码不主动断开 WebSocket 连接
 func NewAuthService(
 	authRepo repository.IAuthRepository,
 	deviceRepo repository.IDeviceRepository,
+	kicker ConnectKicker,
 ) AuthService {
 	return &authServiceImpl{
 		authRepo:   authRepo,
 		deviceRepo: deviceRepo,
+		kicker:     kicker,
 	}
 }
 
@@ -659,8 +663,10 @@ func (s *authServiceImpl) RefreshToken(ctx context.Context, req *pb.RefreshToken
 // Logout 用户登出
 // 业务流程：
 //  1. 从 context 中获取 user_uuid（由 JWT 中间件解析）
-//  2. 删除 Redis 中的 Access Token 和 Refresh Token
-//  3. 返回成功
+//  2. 删除 Redis 中的 Access Token 和 Refresh Token，注销设备会话并记录最后活跃时间
+//  3. 清除该设备的推送 token
+//  4. 断开该设备的 WebSocket 连接
+//  5. 返回成功
 //
 // 错误码映射：
 //   - codes.Internal: 系统内部错误
@@ -691,7 +697,7 @@ func (s *authServiceImpl) Logout(ctx context.Context, req *pb.LogoutRequest) err
 		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
 	}
 
-	// 3. 登出语义为注销设备会话（status=2），设备不存在视为幂等成功。
+	// 登出语义为注销设备会话（status=2），设备不存在视为幂等成功。
 	if err := s.deviceRepo.UpdateOnlineStatus(ctx, userUUID, req.DeviceId, model.DeviceStatusLoggedOut); err != nil {
 		if !errors.Is(err, repository.ErrRecordNotFound) {
 			logger.Error(ctx, "更新设备注销状态失败",
@@ -707,7 +713,7 @@ func (s *authServiceImpl) Logout(ctx context.Context, req *pb.LogoutRequest) err
 		)
 	}
 
-	// 4. 写入最后活跃时间（尽力而为，不阻塞登出）。
+	// 写入最后活跃时间（尽力而为，不阻塞登出）。
 	if err := s.deviceRepo.SetActiveTimestamp(ctx, userUUID, req.DeviceId, time.Now().Unix()); err != nil {
 		logger.Warn(ctx, "写入登出活跃时间失败",
 			logger.String("user_uuid", userUUID),
@@ -716,6 +722,14 @@ func (s *authServiceImpl) Logout(ctx context.Context, req *pb.LogoutRequest) err
 		)
 	}
 
+	// 3. 清除推送 token，登出后该设备不再接收离线推送（尽力而为）。
+	clearPushToken(ctx, s.deviceRepo, userUUID, req.DeviceId)
+
+	// 4. 断开该设备的 WebSocket 连接（异步，失败走重试队列）
+	if s.kicker != nil {
+		s.kicker.KickDevice(ctx, userUUID, req.DeviceId, KickReasonLogout)
+	}
+
 	// 5. 登出成功
 	logger.Info(ctx, "用户登出成功",
 		logger.String("user_uuid", userUUID),
@@ -733,6 +747,7 @@ func (s *authServiceImpl) Logout(ctx context.Context, req *pb.LogoutRequest) err
 //  4. 生成新密码哈希
 //  5. 更新密码
 //  6. 删除验证码
+//  7. 断开用户全部设备的 WebSocket 连接
 //
 // 错误码映射：
 //   - codes.NotFound: 用户不存在
@@ -810,7 +825,12 @@ func (s *authServiceImpl) ResetPassword(ctx context.Context, req *pb.ResetPasswo
 		// 删除失败不影响重置密码流程，只记录警告日志
 	}
 
-	// 7. 重置成功
+	// 7. 断开用户全部设备的 WebSocket 连接（异步，失败走重试队列）
+	if s.kicker != nil {
+		s.kicker.KickUser(ctx, user.Uuid, KickReasonPasswordReset)
+	}
+
+	// 8. 重置成功
 	logger.Info(ctx, "用户密码重置成功",
 		logger.String("email", utils.MaskEmail(req.Email)),
 	)
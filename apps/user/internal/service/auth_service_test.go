@@ -113,6 +113,7 @@ type fakeAuthDeviceRepo struct {
 	touchDeviceInfoFn    func(ctx context.Context, userUUID string) error
 	deleteTokensFn       func(ctx context.Context, userUUID, deviceID string) error
 	updateOnlineStatusFn func(ctx context.Context, userUUID, deviceID string, status int8) error
+	updatePushTokenFn    func(ctx context.Context, userUUID, deviceID, provider, token string) error
 }
 
 var _ repository.IDeviceRepository = (*fakeAuthDeviceRepo)(nil)
@@ -173,6 +174,13 @@ func (f *fakeAuthDeviceRepo) UpdateOnlineStatus(ctx context.Context, userUUID, d
 	return f.updateOnlineStatusFn(ctx, userUUID, deviceID, status)
 }
 
+func (f *fakeAuthDeviceRepo) UpdatePushToken(ctx context.Context, userUUID, deviceID, provider, token string) error {
+	if f.updatePushTokenFn == nil {
+		return nil
+	}
+	return f.updatePushTokenFn(ctx, userUUID, deviceID, provider, token)
+}
+
 func requireAuthStatusCode(t *testing.T, err error, wantCode codes.Code, wantBizCode int) {
 	t.Helper()
 	require.Error(t, err)
@@ -201,7 +209,7 @@ func TestUserAuthServiceRegister(t *testing.T) {
 				return false, repository.ErrRedisNil
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.Register(context.Background(), &pb.RegisterRequest{
 			Email:      "a@test.com",
@@ -219,7 +227,7 @@ func TestUserAuthServiceRegister(t *testing.T) {
 				return false, nil
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.Register(context.Background(), &pb.RegisterRequest{
 			Email:      "a@test.com",
@@ -236,7 +244,7 @@ func TestUserAuthServiceRegister(t *testing.T) {
 				return false, errors.New("redis error")
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.Register(context.Background(), &pb.RegisterRequest{
 			Email:      "a@test.com",
@@ -256,7 +264,7 @@ func TestUserAuthServiceRegister(t *testing.T) {
 				return nil, repository.ErrDuplicateKey
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.Register(context.Background(), &pb.RegisterRequest{
 			Email:      "a@test.com",
@@ -284,7 +292,7 @@ func TestUserAuthServiceRegister(t *testing.T) {
 				}, nil
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.Register(context.Background(), &pb.RegisterRequest{
 			Email:      "a@test.com",
@@ -318,7 +326,7 @@ func TestUserAuthServiceLogin(t *testing.T) {
 				return nil, repository.ErrRecordNotFound
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.Login(context.Background(), &pb.LoginRequest{
 			Account:  "a@test.com",
@@ -336,7 +344,7 @@ func TestUserAuthServiceLogin(t *testing.T) {
 				return &u, nil
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.Login(context.Background(), &pb.LoginRequest{
 			Account:    "a@test.com",
@@ -354,7 +362,7 @@ func TestUserAuthServiceLogin(t *testing.T) {
 				return &u, nil
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.Login(context.Background(), &pb.LoginRequest{
 			Account:    "a@test.com",
@@ -372,7 +380,7 @@ func TestUserAuthServiceLogin(t *testing.T) {
 				return &u, nil
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.Login(context.Background(), &pb.LoginRequest{
 			Account:    "a@test.com",
@@ -395,7 +403,7 @@ func TestUserAuthServiceLogin(t *testing.T) {
 				return errors.New("redis write error")
 			},
 		}
-		svc := NewAuthService(repo, deviceRepo)
+		svc := NewAuthService(repo, deviceRepo, nil)
 
 		ctx := context.WithValue(context.Background(), "device_id", "d1")
 		resp, err := svc.Login(ctx, &pb.LoginRequest{
@@ -419,7 +427,7 @@ func TestUserAuthServiceLogin(t *testing.T) {
 				return errors.New("redis write error")
 			},
 		}
-		svc := NewAuthService(repo, deviceRepo)
+		svc := NewAuthService(repo, deviceRepo, nil)
 
 		ctx := context.WithValue(context.Background(), "device_id", "d1")
 		resp, err := svc.Login(ctx, &pb.LoginRequest{
@@ -454,7 +462,7 @@ func TestUserAuthServiceLogin(t *testing.T) {
 				return errors.New("redis temporary error")
 			},
 		}
-		svc := NewAuthService(repo, deviceRepo)
+		svc := NewAuthService(repo, deviceRepo, nil)
 
 		ctx := context.WithValue(context.Background(), "device_id", "d1")
 		resp, err := svc.Login(ctx, &pb.LoginRequest{
@@ -494,7 +502,7 @@ func TestUserAuthServiceLoginByCode(t *testing.T) {
 				return nil, repository.ErrRecordNotFound
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.LoginByCode(context.Background(), &pb.LoginByCodeRequest{
 			Email:      "a@test.com",
@@ -512,7 +520,7 @@ func TestUserAuthServiceLoginByCode(t *testing.T) {
 				return &u, nil
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.LoginByCode(context.Background(), &pb.LoginByCodeRequest{
 			Email:      "a@test.com",
@@ -533,7 +541,7 @@ func TestUserAuthServiceLoginByCode(t *testing.T) {
 				return false, repository.ErrRedisNil
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 		ctx := context.WithValue(context.Background(), "device_id", "d1")
 
 		resp, err := svc.LoginByCode(ctx, &pb.LoginByCodeRequest{
@@ -555,7 +563,7 @@ func TestUserAuthServiceLoginByCode(t *testing.T) {
 				return false, nil
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 		ctx := context.WithValue(context.Background(), "device_id", "d1")
 
 		resp, err := svc.LoginByCode(ctx, &pb.LoginByCodeRequest{
@@ -573,7 +581,7 @@ func TestUserAuthServiceLoginByCode(t *testing.T) {
 				return &u, nil
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.LoginByCode(context.Background(), &pb.LoginByCodeRequest{
 			Email:      "a@test.com",
@@ -599,7 +607,7 @@ func TestUserAuthServiceLoginByCode(t *testing.T) {
 				return errors.New("redis error")
 			},
 		}
-		svc := NewAuthService(repo, deviceRepo)
+		svc := NewAuthService(repo, deviceRepo, nil)
 
 		ctx := context.WithValue(context.Background(), "device_id", "d1")
 		resp, err := svc.LoginByCode(ctx, &pb.LoginByCodeRequest{
@@ -633,7 +641,7 @@ func TestUserAuthServiceLoginByCode(t *testing.T) {
 				return errors.New("redis temporary error")
 			},
 		}
-		svc := NewAuthService(repo, deviceRepo)
+		svc := NewAuthService(repo, deviceRepo, nil)
 
 		ctx := context.WithValue(context.Background(), "device_id", "d1")
 		resp, err := svc.LoginByCode(ctx, &pb.LoginByCodeRequest{
@@ -659,7 +667,7 @@ func TestUserAuthServiceSendVerifyCode(t *testing.T) {
 	util.SetEmailConfig(util.EmailConfig{})
 
 	t.Run("invalid_email", func(t *testing.T) {
-		svc := NewAuthService(&fakeAuthRepo{}, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(&fakeAuthRepo{}, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.SendVerifyCode(context.Background(), &pb.SendVerifyCodeRequest{
 			Email: "invalid",
@@ -675,7 +683,7 @@ func TestUserAuthServiceSendVerifyCode(t *testing.T) {
 				return true, nil
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.SendVerifyCode(context.Background(), &pb.SendVerifyCodeRequest{
 			Email: "a@test.com",
@@ -691,7 +699,7 @@ func TestUserAuthServiceSendVerifyCode(t *testing.T) {
 				return false, errors.New("redis error")
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.SendVerifyCode(context.Background(), &pb.SendVerifyCodeRequest{
 			Email: "a@test.com",
@@ -710,7 +718,7 @@ func TestUserAuthServiceSendVerifyCode(t *testing.T) {
 				return errors.New("redis error")
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.SendVerifyCode(context.Background(), &pb.SendVerifyCodeRequest{
 			Email: "a@test.com",
@@ -732,7 +740,7 @@ func TestUserAuthServiceSendVerifyCode(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.SendVerifyCode(context.Background(), &pb.SendVerifyCodeRequest{
 			Email: "a@test.com",
@@ -752,7 +760,7 @@ func TestUserAuthServiceVerifyCode(t *testing.T) {
 				return false, repository.ErrRedisNil
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.VerifyCode(context.Background(), &pb.VerifyCodeRequest{
 			Email:      "a@test.com",
@@ -769,7 +777,7 @@ func TestUserAuthServiceVerifyCode(t *testing.T) {
 				return false, errors.New("redis error")
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.VerifyCode(context.Background(), &pb.VerifyCodeRequest{
 			Email:      "a@test.com",
@@ -786,7 +794,7 @@ func TestUserAuthServiceVerifyCode(t *testing.T) {
 				return true, nil
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.VerifyCode(context.Background(), &pb.VerifyCodeRequest{
 			Email:      "a@test.com",
@@ -804,7 +812,7 @@ func TestUserAuthServiceVerifyCode(t *testing.T) {
 				return false, nil
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		resp, err := svc.VerifyCode(context.Background(), &pb.VerifyCodeRequest{
 			Email:      "a@test.com",
@@ -821,14 +829,14 @@ func TestUserAuthServiceRefreshToken(t *testing.T) {
 	initUserAuthTestLogger()
 
 	t.Run("missing_user_uuid", func(t *testing.T) {
-		svc := NewAuthService(&fakeAuthRepo{}, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(&fakeAuthRepo{}, &fakeAuthDeviceRepo{}, nil)
 		resp, err := svc.RefreshToken(context.Background(), &pb.RefreshTokenRequest{RefreshToken: "rtk"})
 		require.Nil(t, resp)
 		requireAuthStatusCode(t, err, codes.InvalidArgument, consts.CodeInvalidToken)
 	})
 
 	t.Run("missing_device_id", func(t *testing.T) {
-		svc := NewAuthService(&fakeAuthRepo{}, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(&fakeAuthRepo{}, &fakeAuthDeviceRepo{}, nil)
 		ctx := context.WithValue(context.Background(), "user_uuid", "u1")
 		resp, err := svc.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: "rtk"})
 		require.Nil(t, resp)
@@ -841,7 +849,7 @@ func TestUserAuthServiceRefreshToken(t *testing.T) {
 				return "", repository.ErrRedisNil
 			},
 		}
-		svc := NewAuthService(&fakeAuthRepo{}, deviceRepo)
+		svc := NewAuthService(&fakeAuthRepo{}, deviceRepo, nil)
 		ctx := context.WithValue(context.Background(), "user_uuid", "u1")
 		ctx = context.WithValue(ctx, "device_id", "d1")
 
@@ -856,7 +864,7 @@ func TestUserAuthServiceRefreshToken(t *testing.T) {
 				return "stored-token", nil
 			},
 		}
-		svc := NewAuthService(&fakeAuthRepo{}, deviceRepo)
+		svc := NewAuthService(&fakeAuthRepo{}, deviceRepo, nil)
 		ctx := context.WithValue(context.Background(), "user_uuid", "u1")
 		ctx = context.WithValue(ctx, "device_id", "d1")
 
@@ -874,7 +882,7 @@ func TestUserAuthServiceRefreshToken(t *testing.T) {
 				return errors.New("redis error")
 			},
 		}
-		svc := NewAuthService(&fakeAuthRepo{}, deviceRepo)
+		svc := NewAuthService(&fakeAuthRepo{}, deviceRepo, nil)
 		ctx := context.WithValue(context.Background(), "user_uuid", "u1")
 		ctx = context.WithValue(ctx, "device_id", "d1")
 
@@ -897,7 +905,7 @@ func TestUserAuthServiceRefreshToken(t *testing.T) {
 				return errors.New("redis warning")
 			},
 		}
-		svc := NewAuthService(&fakeAuthRepo{}, deviceRepo)
+		svc := NewAuthService(&fakeAuthRepo{}, deviceRepo, nil)
 		ctx := context.WithValue(context.Background(), "user_uuid", "u1")
 		ctx = context.WithValue(ctx, "device_id", "d1")
 
@@ -914,19 +922,19 @@ func TestUserAuthServiceLogout(t *testing.T) {
 	initUserAuthTestLogger()
 
 	t.Run("nil_request", func(t *testing.T) {
-		svc := NewAuthService(&fakeAuthRepo{}, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(&fakeAuthRepo{}, &fakeAuthDeviceRepo{}, nil)
 		err := svc.Logout(context.Background(), nil)
 		requireAuthStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
 	})
 
 	t.Run("empty_device_id", func(t *testing.T) {
-		svc := NewAuthService(&fakeAuthRepo{}, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(&fakeAuthRepo{}, &fakeAuthDeviceRepo{}, nil)
 		err := svc.Logout(context.Background(), &pb.LogoutRequest{})
 		requireAuthStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
 	})
 
 	t.Run("missing_user_uuid_context", func(t *testing.T) {
-		svc := NewAuthService(&fakeAuthRepo{}, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(&fakeAuthRepo{}, &fakeAuthDeviceRepo{}, nil)
 		err := svc.Logout(context.Background(), &pb.LogoutRequest{DeviceId: "d1"})
 		requireAuthStatusCode(t, err, codes.Internal, consts.CodeInternalError)
 	})
@@ -937,7 +945,7 @@ func TestUserAuthServiceLogout(t *testing.T) {
 				return errors.New("redis error")
 			},
 		}
-		svc := NewAuthService(&fakeAuthRepo{}, deviceRepo)
+		svc := NewAuthService(&fakeAuthRepo{}, deviceRepo, nil)
 		ctx := context.WithValue(context.Background(), "user_uuid", "u1")
 
 		err := svc.Logout(ctx, &pb.LogoutRequest{DeviceId: "d1"})
@@ -950,7 +958,7 @@ func TestUserAuthServiceLogout(t *testing.T) {
 				return repository.ErrRecordNotFound
 			},
 		}
-		svc := NewAuthService(&fakeAuthRepo{}, deviceRepo)
+		svc := NewAuthService(&fakeAuthRepo{}, deviceRepo, nil)
 		ctx := context.WithValue(context.Background(), "user_uuid", "u1")
 
 		err := svc.Logout(ctx, &pb.LogoutRequest{DeviceId: "d1"})
@@ -963,11 +971,37 @@ func TestUserAuthServiceLogout(t *testing.T) {
 				return errors.New("redis warning")
 			},
 		}
-		svc := NewAuthService(&fakeAuthRepo{}, deviceRepo)
+		svc := NewAuthService(&fakeAuthRepo{}, deviceRepo, nil)
+		ctx := context.WithValue(context.Background(), "user_uuid", "u1")
+
+		err := svc.Logout(ctx, &pb.LogoutRequest{DeviceId: "d1"})
+		require.NoError(t, err)
+	})
+
+	t.Run("clears_push_token_failed_not_blocking", func(t *testing.T) {
+		var cleared []string
+		deviceRepo := &fakeAuthDeviceRepo{
+			updatePushTokenFn: func(_ context.Context, userUUID, deviceID, provider, token string) error {
+				cleared = append(cleared, userUUID+"/"+deviceID+"/"+provider+"/"+token)
+				return errors.New("db failed")
+			},
+		}
+		svc := NewAuthService(&fakeAuthRepo{}, deviceRepo, nil)
+		ctx := context.WithValue(context.Background(), "user_uuid", "u1")
+
+		err := svc.Logout(ctx, &pb.LogoutRequest{DeviceId: "d1"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"u1/d1//"}, cleared)
+	})
+
+	t.Run("kicks_connection", func(t *testing.T) {
+		kicker := &fakeConnectKicker{}
+		svc := NewAuthService(&fakeAuthRepo{}, &fakeAuthDeviceRepo{}, kicker)
 		ctx := context.WithValue(context.Background(), "user_uuid", "u1")
 
 		err := svc.Logout(ctx, &pb.LogoutRequest{DeviceId: "d1"})
 		require.NoError(t, err)
+		assert.Equal(t, []string{"u1/d1/" + KickReasonLogout}, kicker.devices)
 	})
 }
 
@@ -982,7 +1016,7 @@ func TestUserAuthServiceResetPassword(t *testing.T) {
 				return nil, repository.ErrRecordNotFound
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		err := svc.ResetPassword(context.Background(), &pb.ResetPasswordRequest{
 			Email:       "a@test.com",
@@ -1002,7 +1036,7 @@ func TestUserAuthServiceResetPassword(t *testing.T) {
 				return false, repository.ErrRedisNil
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		err := svc.ResetPassword(context.Background(), &pb.ResetPasswordRequest{
 			Email:       "a@test.com",
@@ -1022,7 +1056,7 @@ func TestUserAuthServiceResetPassword(t *testing.T) {
 				return false, nil
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		err := svc.ResetPassword(context.Background(), &pb.ResetPasswordRequest{
 			Email:       "a@test.com",
@@ -1041,7 +1075,7 @@ func TestUserAuthServiceResetPassword(t *testing.T) {
 				return true, nil
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		err := svc.ResetPassword(context.Background(), &pb.ResetPasswordRequest{
 			Email:       "a@test.com",
@@ -1063,7 +1097,7 @@ func TestUserAuthServiceResetPassword(t *testing.T) {
 				return errors.New("db error")
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		err := svc.ResetPassword(context.Background(), &pb.ResetPasswordRequest{
 			Email:       "a@test.com",
@@ -1092,7 +1126,7 @@ func TestUserAuthServiceResetPassword(t *testing.T) {
 				return errors.New("delete error")
 			},
 		}
-		svc := NewAuthService(repo, &fakeAuthDeviceRepo{})
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, nil)
 
 		err := svc.ResetPassword(context.Background(), &pb.ResetPasswordRequest{
 			Email:       "a@test.com",
@@ -1102,4 +1136,27 @@ func TestUserAuthServiceResetPassword(t *testing.T) {
 		require.NoError(t, err)
 		assert.True(t, deleteCalled)
 	})
+
+	t.Run("success_kicks_all_devices", func(t *testing.T) {
+		repo := &fakeAuthRepo{
+			getByEmailFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
+				return &model.UserInfo{Uuid: "u1", Password: oldHashed}, nil
+			},
+			verifyVerifyCodeFn: func(_ context.Context, _, _ string, _ int32) (bool, error) {
+				return true, nil
+			},
+			updatePasswordFn: func(_ context.Context, _, _ string) error { return nil },
+		}
+		kicker := &fakeConnectKicker{}
+		svc := NewAuthService(repo, &fakeAuthDeviceRepo{}, kicker)
+
+		err := svc.ResetPassword(context.Background(), &pb.ResetPasswordRequest{
+			Email:       "a@test.com",
+			VerifyCode:  "123456",
+			NewPassword: "newpass123",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"u1/" + KickReasonPasswordReset}, kicker.users)
+		assert.Empty(t, kicker.devices)
+	})
 }
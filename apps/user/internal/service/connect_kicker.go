@@ -0,0 +1,88 @@
+package service
+
+import (
+	connectpb "ChatServer/apps/connect/pb"
+	"ChatServer/apps/user/mq"
+	"ChatServer/pkg/async"
+	"ChatServer/pkg/logger"
+	"context"
+	"time"
+)
+
+// 踢线原因，随 kickout 帧下发给客户端
+const (
+	KickReasonDeviceKicked   = "device_kicked"
+	KickReasonLogout         = "logout"
+	KickReasonAccountDeleted = "account_deleted"
+	KickReasonPasswordReset  = "password_reset"
+)
+
+const (
+	// connectKickTimeout 单次踢线调用超时
+	connectKickTimeout = 3 * time.Second
+	// connectKickTaskTimeout 异步任务总超时（含失败后写入重试队列）
+	connectKickTaskTimeout = 5 * time.Second
+)
+
+// ConnectKicker 通过 connect 服务断开设备的 WebSocket 连接
+// 调用均为异步尽力而为：connect 不可达时写入 Kafka 重试队列，不影响主流程
+type ConnectKicker interface {
+	// KickDevice 断开指定设备的连接
+	KickDevice(ctx context.Context, userUUID, deviceID, reason string)
+	// KickUser 断开用户全部在线设备的连接
+	KickUser(ctx context.Context, userUUID, reason string)
+}
+
+// grpcConnectKicker 基于 connect gRPC 客户端（或集群路由客户端）的踢线实现
+type grpcConnectKicker struct {
+	client connectpb.ConnectServiceClient
+}
+
+// NewConnectKicker 创建 connect 踢线器，client 为 nil 时返回 nil（不踢线）
+func NewConnectKicker(client connectpb.ConnectServiceClient) ConnectKicker {
+	if client == nil {
+		return nil
+	}
+	return &grpcConnectKicker{client: client}
+}
+
+// KickDevice 异步断开指定设备
+func (k *grpcConnectKicker) KickDevice(ctx context.Context, userUUID, deviceID, reason string) {
+	k.kick(ctx, mq.ConnectKick{UserUUID: userUUID, DeviceID: deviceID, Reason: reason, IssuedAt: time.Now().UnixMilli()}, "ConnectKicker.KickDevice")
+}
+
+// KickUser 异步断开用户全部在线设备
+func (k *grpcConnectKicker) KickUser(ctx context.Context, userUUID, reason string) {
+	k.kick(ctx, mq.ConnectKick{UserUUID: userUUID, Reason: reason, IssuedAt: time.Now().UnixMilli()}, "ConnectKicker.KickUser")
+}
+
+// kick 调用 connect 踢线，失败时发送到 Kafka 重试队列
+func (k *grpcConnectKicker) kick(ctx context.Context, kick mq.ConnectKick, source string) {
+	async.RunSafe(ctx, func(runCtx context.Context) {
+		callCtx, cancel := context.WithTimeout(runCtx, connectKickTimeout)
+		err := mq.ExecuteConnectKick(callCtx, k.client, kick)
+		cancel()
+		if err == nil {
+			return
+		}
+
+		logger.Warn(runCtx, "connect 踢线失败，发送到重试队列",
+			logger.String("user_uuid", kick.UserUUID),
+			logger.String("device_id", kick.DeviceID),
+			logger.String("reason", kick.Reason),
+			logger.ErrorField("error", err),
+		)
+		task := mq.BuildConnectKickTask(kick.UserUUID, kick.DeviceID, kick.Reason, kick.IssuedAt).
+			WithContext(runCtx).
+			WithError(err).
+			WithSource(source)
+		if kafkaErr := mq.SendRedisTask(runCtx, task); kafkaErr != nil {
+			logger.Error(runCtx, "发送踢线重试任务到 Kafka 失败，放弃处理",
+				logger.String("user_uuid", kick.UserUUID),
+				logger.String("device_id", kick.DeviceID),
+				logger.ErrorField("kafka_error", kafkaErr),
+				logger.ErrorField("original_error", err),
+			)
+		}
+	}, connectKickTaskTimeout)
+}
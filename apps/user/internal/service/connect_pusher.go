@@ -0,0 +1,32 @@
+package service
+
+import (
+	connectpb "ChatServer/apps/connect/pb"
+	"ChatServer/pkg/fanout"
+	"context"
+)
+
+// ConnectPusher 通过 connect 服务向用户的在线连接下发消息
+type ConnectPusher interface {
+	// BroadcastToUsers 向多个用户的所有在线设备下发同一条消息，内部按 1000 人分批
+	BroadcastToUsers(ctx context.Context, userUUIDs []string, envelope *connectpb.MessageEnvelope) error
+}
+
+// grpcConnectPusher 基于 connect gRPC 客户端的下发实现
+type grpcConnectPusher struct {
+	client connectpb.ConnectServiceClient
+}
+
+// NewConnectPusher 创建 connect 下发器，client 为 nil 时返回 nil（不下发）
+func NewConnectPusher(client connectpb.ConnectServiceClient) ConnectPusher {
+	if client == nil {
+		return nil
+	}
+	return &grpcConnectPusher{client: client}
+}
+
+// BroadcastToUsers 分批调用 connect.BroadcastToUsers
+func (p *grpcConnectPusher) BroadcastToUsers(ctx context.Context, userUUIDs []string, envelope *connectpb.MessageEnvelope) error {
+	result := fanout.BroadcastInChunks(ctx, p.client, userUUIDs, envelope, fanout.MaxBroadcastUsers)
+	return result.Err
+}
@@ -7,11 +7,13 @@ import (
 	"ChatServer/model"
 	pkgdeviceactive "ChatServer/pkg/deviceactive"
 	"ChatServer/pkg/logger"
+	"ChatServer/pkg/pushnotify"
 	"ChatServer/pkg/util"
 	"context"
 	"errors"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"google.golang.org/grpc/codes"
@@ -21,12 +23,15 @@ import (
 // deviceServiceImpl 设备会话服务实现
 type deviceServiceImpl struct {
 	deviceRepo repository.IDeviceRepository
+	kicker     ConnectKicker
 }
 
 // NewDeviceService 创建设备服务实例
-func NewDeviceService(deviceRepo repository.IDeviceRepository) DeviceService {
+// kicker 可为 nil，此时踢设备只吊销 Token，不主动断开 WebSocket 连接
+func NewDeviceService(deviceRepo repository.IDeviceRepository, kicker ConnectKicker) DeviceService {
 	return &deviceServiceImpl{
 		deviceRepo: deviceRepo,
+		kicker:     kicker,
 	}
 }
 
@@ -157,6 +162,16 @@ func (s *deviceServiceImpl) KickDevice(ctx context.Context, req *pb.KickDeviceRe
 		}
 	}
 
+	// 被踢设备不再接收离线推送
+	if session.PushToken != "" {
+		clearPushToken(ctx, s.deviceRepo, userUUID, req.DeviceId)
+	}
+
+	// 立即断开被踢设备的 WebSocket 连接（异步，失败走重试队列）
+	if s.kicker != nil {
+		s.kicker.KickDevice(ctx, userUUID, req.DeviceId, KickReasonDeviceKicked)
+	}
+
 	logger.Info(ctx, "踢出设备成功",
 		logger.String("user_uuid", userUUID),
 		logger.String("device_id", req.DeviceId),
@@ -446,3 +461,253 @@ func (s *deviceServiceImpl) UpdateDeviceStatus(ctx context.Context, req *pb.Upda
 
 	return nil
 }
+
+// RegisterPushToken 为当前设备登记推送 token。
+// provider 为空时按设备平台推断；Web/桌面端等不支持系统推送的平台拒绝登记。
+func (s *deviceServiceImpl) RegisterPushToken(ctx context.Context, req *pb.RegisterPushTokenRequest) (*pb.RegisterPushTokenResponse, error) {
+	userUUID := util.GetUserUUIDFromContext(ctx)
+	deviceID := util.GetDeviceIDFromContext(ctx)
+	if userUUID == "" || deviceID == "" {
+		logger.Warn(ctx, "登记推送 token 失败：user_uuid 或 device_id 为空")
+		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	token := ""
+	if req != nil {
+		token = strings.TrimSpace(req.Token)
+	}
+	if token == "" || len(token) > 255 {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	session, err := s.deviceRepo.GetByDeviceID(ctx, userUUID, deviceID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, strconv.Itoa(consts.CodeDeviceNotFound))
+		}
+		logger.Error(ctx, "登记推送 token 失败：查询设备会话失败",
+			logger.String("user_uuid", userUUID),
+			logger.String("device_id", deviceID),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	// 只有移动端支持系统推送；provider 缺省时使用平台默认通道。
+	defaultProvider := pushnotify.DefaultProvider(session.Platform)
+	if defaultProvider == "" {
+		return nil, status.Error(codes.FailedPrecondition, strconv.Itoa(consts.CodePlatformNotSupport))
+	}
+	provider := strings.ToLower(strings.TrimSpace(req.Provider))
+	if provider == "" {
+		provider = defaultProvider
+	}
+	if !pushnotify.ValidProvider(provider) {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	if err := s.deviceRepo.UpdatePushToken(ctx, userUUID, deviceID, provider, token); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, strconv.Itoa(consts.CodeDeviceNotFound))
+		}
+		logger.Error(ctx, "登记推送 token 失败：写入设备会话失败",
+			logger.String("user_uuid", userUUID),
+			logger.String("device_id", deviceID),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	logger.Info(ctx, "登记推送 token 成功",
+		logger.String("user_uuid", userUUID),
+		logger.String("device_id", deviceID),
+		logger.String("provider", provider),
+	)
+	return &pb.RegisterPushTokenResponse{Provider: provider}, nil
+}
+
+// UnregisterPushToken 清除当前设备的推送 token。
+// 幂等语义：设备会话不存在或未登记 token 时视为成功。
+func (s *deviceServiceImpl) UnregisterPushToken(ctx context.Context, req *pb.UnregisterPushTokenRequest) error {
+	userUUID := util.GetUserUUIDFromContext(ctx)
+	deviceID := util.GetDeviceIDFromContext(ctx)
+	if userUUID == "" || deviceID == "" {
+		logger.Warn(ctx, "清除推送 token 失败：user_uuid 或 device_id 为空")
+		return status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	if err := s.deviceRepo.UpdatePushToken(ctx, userUUID, deviceID, "", ""); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil
+		}
+		logger.Error(ctx, "清除推送 token 失败",
+			logger.String("user_uuid", userUUID),
+			logger.String("device_id", deviceID),
+			logger.ErrorField("error", err),
+		)
+		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+	return nil
+}
+
+// UpdateNotificationSettings 更新设备通知偏好，只更新请求中携带的字段
+func (s *deviceServiceImpl) UpdateNotificationSettings(ctx context.Context, req *pb.UpdateNotificationSettingsRequest) (*pb.UpdateNotificationSettingsResponse, error) {
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+	setting, err := s.loadNotificationSetting(ctx, req.DeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.PushEnabled != nil {
+		setting.PushEnabled = *req.PushEnabled
+	}
+	if req.QuietHoursEnabled != nil {
+		setting.QuietHoursEnabled = *req.QuietHoursEnabled
+	}
+	if req.QuietStart != nil {
+		if *req.QuietStart < 0 || *req.QuietStart >= minutesPerDay {
+			return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+		}
+		setting.QuietStart = int16(*req.QuietStart)
+	}
+	if req.QuietEnd != nil {
+		if *req.QuietEnd < 0 || *req.QuietEnd >= minutesPerDay {
+			return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+		}
+		setting.QuietEnd = int16(*req.QuietEnd)
+	}
+	if req.Timezone != nil {
+		timezone := strings.TrimSpace(*req.Timezone)
+		if timezone != "" {
+			if _, err := time.LoadLocation(timezone); err != nil {
+				return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+			}
+		}
+		setting.Timezone = timezone
+	}
+	if req.ShowPreview != nil {
+		setting.ShowPreview = *req.ShowPreview
+	}
+	if req.Sound != nil {
+		setting.Sound = *req.Sound
+	}
+	// 开始与结束相同的时段没有意义（无法区分"全天"与"不生效"）
+	if setting.QuietHoursEnabled && setting.QuietStart == setting.QuietEnd {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	if err := s.deviceRepo.UpsertNotificationSetting(ctx, setting); err != nil {
+		logger.Error(ctx, "更新通知偏好失败",
+			logger.String("user_uuid", setting.UserUuid),
+			logger.String("device_id", setting.DeviceId),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	logger.Info(ctx, "更新通知偏好成功",
+		logger.String("user_uuid", setting.UserUuid),
+		logger.String("device_id", setting.DeviceId),
+		logger.Bool("push_enabled", setting.PushEnabled),
+		logger.Bool("quiet_hours_enabled", setting.QuietHoursEnabled),
+	)
+	return &pb.UpdateNotificationSettingsResponse{Settings: notificationSettingsToProto(setting)}, nil
+}
+
+// GetNotificationSettings 获取设备通知偏好，未设置过的设备返回默认值
+func (s *deviceServiceImpl) GetNotificationSettings(ctx context.Context, req *pb.GetNotificationSettingsRequest) (*pb.GetNotificationSettingsResponse, error) {
+	deviceID := ""
+	if req != nil {
+		deviceID = req.DeviceId
+	}
+	setting, err := s.loadNotificationSetting(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetNotificationSettingsResponse{Settings: notificationSettingsToProto(setting)}, nil
+}
+
+// loadNotificationSetting 校验设备归属并读取通知偏好，deviceID 为空时取当前设备
+func (s *deviceServiceImpl) loadNotificationSetting(ctx context.Context, deviceID string) (*model.DeviceNotificationSetting, error) {
+	userUUID := util.GetUserUUIDFromContext(ctx)
+	if userUUID == "" {
+		logger.Warn(ctx, "读取通知偏好失败：user_uuid 为空")
+		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+	if deviceID == "" {
+		deviceID = util.GetDeviceIDFromContext(ctx)
+	}
+	if deviceID == "" {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	if _, err := s.deviceRepo.GetByDeviceID(ctx, userUUID, deviceID); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, strconv.Itoa(consts.CodeDeviceNotFound))
+		}
+		logger.Error(ctx, "读取通知偏好失败：查询设备会话失败",
+			logger.String("user_uuid", userUUID),
+			logger.String("device_id", deviceID),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	setting, err := s.deviceRepo.GetNotificationSetting(ctx, userUUID, deviceID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return defaultNotificationSetting(userUUID, deviceID), nil
+		}
+		logger.Error(ctx, "读取通知偏好失败",
+			logger.String("user_uuid", userUUID),
+			logger.String("device_id", deviceID),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+	return setting, nil
+}
+
+// minutesPerDay 免打扰时段取值上界（当天分钟数）
+const minutesPerDay = 24 * 60
+
+// defaultNotificationSetting 未设置过通知偏好的设备使用的默认值
+func defaultNotificationSetting(userUUID, deviceID string) *model.DeviceNotificationSetting {
+	return &model.DeviceNotificationSetting{
+		UserUuid:    userUUID,
+		DeviceId:    deviceID,
+		PushEnabled: true,
+		QuietStart:  model.DefaultQuietStart,
+		QuietEnd:    model.DefaultQuietEnd,
+		ShowPreview: true,
+		Sound:       true,
+	}
+}
+
+// notificationSettingsToProto 转换通知偏好为 proto
+func notificationSettingsToProto(setting *model.DeviceNotificationSetting) *pb.NotificationSettings {
+	return &pb.NotificationSettings{
+		DeviceId:          setting.DeviceId,
+		PushEnabled:       setting.PushEnabled,
+		QuietHoursEnabled: setting.QuietHoursEnabled,
+		QuietStart:        int32(setting.QuietStart),
+		QuietEnd:          int32(setting.QuietEnd),
+		Timezone:          setting.Timezone,
+		ShowPreview:       setting.ShowPreview,
+		Sound:             setting.Sound,
+	}
+}
+
+// clearPushToken 清除设备推送 token（设备被踢出/登出时调用）。
+// 尽力而为：失败只记录日志，离线通知链路只推送在线/离线状态的设备，残留 token 不会被使用。
+func clearPushToken(ctx context.Context, deviceRepo repository.IDeviceRepository, userUUID, deviceID string) {
+	if err := deviceRepo.UpdatePushToken(ctx, userUUID, deviceID, "", ""); err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
+		logger.Warn(ctx, "清除推送 token 失败",
+			logger.String("user_uuid", userUUID),
+			logger.String("device_id", deviceID),
+			logger.ErrorField("error", err),
+		)
+	}
+}
@@ -75,6 +75,11 @@ type fakeDeviceRepository struct {
 	verifyAccessTokenFn    func(context.Context, string, string, string) (bool, error)
 	getRefreshTokenFn      func(context.Context, string, string) (string, error)
 	deleteTokensFn         func(context.Context, string, string) error
+	updatePushTokenFn      func(context.Context, string, string, string, string) error
+	getPushTargetsFn       func(context.Context, string) ([]*model.DeviceSession, error)
+	getNotifySettingFn     func(context.Context, string, string) (*model.DeviceNotificationSetting, error)
+	listNotifySettingsFn   func(context.Context, string) (map[string]*model.DeviceNotificationSetting, error)
+	upsertNotifySettingFn  func(context.Context, *model.DeviceNotificationSetting) error
 }
 
 func (f *fakeDeviceRepository) Create(ctx context.Context, session *model.DeviceSession) error {
@@ -231,11 +236,60 @@ func (f *fakeDeviceRepository) DeleteTokens(ctx context.Context, userUUID, devic
 	return f.deleteTokensFn(ctx, userUUID, deviceID)
 }
 
+func (f *fakeDeviceRepository) UpdatePushToken(ctx context.Context, userUUID, deviceID, provider, token string) error {
+	if f.updatePushTokenFn == nil {
+		return nil
+	}
+	return f.updatePushTokenFn(ctx, userUUID, deviceID, provider, token)
+}
+
+func (f *fakeDeviceRepository) GetPushTargets(ctx context.Context, userUUID string) ([]*model.DeviceSession, error) {
+	if f.getPushTargetsFn == nil {
+		return nil, nil
+	}
+	return f.getPushTargetsFn(ctx, userUUID)
+}
+
+func (f *fakeDeviceRepository) GetNotificationSetting(ctx context.Context, userUUID, deviceID string) (*model.DeviceNotificationSetting, error) {
+	if f.getNotifySettingFn == nil {
+		return nil, repository.ErrRecordNotFound
+	}
+	return f.getNotifySettingFn(ctx, userUUID, deviceID)
+}
+
+func (f *fakeDeviceRepository) ListNotificationSettings(ctx context.Context, userUUID string) (map[string]*model.DeviceNotificationSetting, error) {
+	if f.listNotifySettingsFn == nil {
+		return map[string]*model.DeviceNotificationSetting{}, nil
+	}
+	return f.listNotifySettingsFn(ctx, userUUID)
+}
+
+func (f *fakeDeviceRepository) UpsertNotificationSetting(ctx context.Context, setting *model.DeviceNotificationSetting) error {
+	if f.upsertNotifySettingFn == nil {
+		return nil
+	}
+	return f.upsertNotifySettingFn(ctx, setting)
+}
+
+// fakeConnectKicker 记录踢线调用
+type fakeConnectKicker struct {
+	devices []string // user_uuid/device_id/reason
+	users   []string // user_uuid/reason
+}
+
+func (f *fakeConnectKicker) KickDevice(_ context.Context, userUUID, deviceID, reason string) {
+	f.devices = append(f.devices, userUUID+"/"+deviceID+"/"+reason)
+}
+
+func (f *fakeConnectKicker) KickUser(_ context.Context, userUUID, reason string) {
+	f.users = append(f.users, userUUID+"/"+reason)
+}
+
 func TestUserDeviceServiceGetDeviceList(t *testing.T) {
 	initUserDeviceTestLogger()
 
 	t.Run("unauthenticated", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil)
 		resp, err := svc.GetDeviceList(context.Background(), &pb.GetDeviceListRequest{})
 		require.Nil(t, resp)
 		requireDeviceStatusCode(t, err, codes.Unauthenticated, consts.CodeUnauthorized)
@@ -247,7 +301,7 @@ func TestUserDeviceServiceGetDeviceList(t *testing.T) {
 				assert.Equal(t, []string{"u1"}, userUUIDs)
 				return nil, errors.New("redis failed")
 			},
-		})
+		}, nil)
 		resp, err := svc.GetDeviceList(withDeviceContext("u1", "d1"), &pb.GetDeviceListRequest{})
 		require.Nil(t, resp)
 		requireDeviceStatusCode(t, err, codes.Internal, consts.CodeInternalError)
@@ -274,7 +328,7 @@ func TestUserDeviceServiceGetDeviceList(t *testing.T) {
 					"d2": nowSec - 30,
 				}, nil
 			},
-		})
+		}, nil)
 
 		resp, err := svc.GetDeviceList(withDeviceContext("u1", "d2"), &pb.GetDeviceListRequest{})
 		require.NoError(t, err)
@@ -302,7 +356,7 @@ func TestUserDeviceServiceGetDeviceList(t *testing.T) {
 			getActiveTimestampsFn: func(_ context.Context, _ string, _ []string) (map[string]int64, error) {
 				return nil, errors.New("active redis down")
 			},
-		})
+		}, nil)
 
 		resp, err := svc.GetDeviceList(withDeviceContext("u1", "d1"), &pb.GetDeviceListRequest{})
 		require.NoError(t, err)
@@ -317,13 +371,13 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 	initUserDeviceTestLogger()
 
 	t.Run("unauthenticated", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil)
 		err := svc.KickDevice(context.Background(), &pb.KickDeviceRequest{DeviceId: "d1"})
 		requireDeviceStatusCode(t, err, codes.Unauthenticated, consts.CodeUnauthorized)
 	})
 
 	t.Run("invalid_request", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil)
 
 		err := svc.KickDevice(withDeviceContext("u1", "d2"), nil)
 		requireDeviceStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
@@ -333,7 +387,7 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 	})
 
 	t.Run("cannot_kick_current_device", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil)
 		err := svc.KickDevice(withDeviceContext("u1", "d1"), &pb.KickDeviceRequest{DeviceId: "d1"})
 		requireDeviceStatusCode(t, err, codes.FailedPrecondition, consts.CodeCannotKickCurrent)
 	})
@@ -343,7 +397,7 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 			getByDeviceIDFn: func(_ context.Context, _, _ string) (*model.DeviceSession, error) {
 				return nil, repository.ErrRecordNotFound
 			},
-		})
+		}, nil)
 		err := svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"})
 		requireDeviceStatusCode(t, err, codes.NotFound, consts.CodeDeviceNotFound)
 
@@ -351,7 +405,7 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 			getByDeviceIDFn: func(_ context.Context, _, _ string) (*model.DeviceSession, error) {
 				return nil, errors.New("db failed")
 			},
-		})
+		}, nil)
 		err = svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"})
 		requireDeviceStatusCode(t, err, codes.Internal, consts.CodeInternalError)
 
@@ -359,7 +413,7 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 			getByDeviceIDFn: func(_ context.Context, _, _ string) (*model.DeviceSession, error) {
 				return nil, nil
 			},
-		})
+		}, nil)
 		err = svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"})
 		requireDeviceStatusCode(t, err, codes.NotFound, consts.CodeDeviceNotFound)
 	})
@@ -374,7 +428,7 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 			deleteTokensFn: func(_ context.Context, _, _ string) error {
 				return errors.New("redis failed")
 			},
-		})
+		}, nil)
 		err := svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"})
 		requireDeviceStatusCode(t, err, codes.Internal, consts.CodeInternalError)
 
@@ -386,7 +440,7 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 			updateOnlineStatusFn: func(_ context.Context, _, _ string, _ int8) error {
 				return repository.ErrRecordNotFound
 			},
-		})
+		}, nil)
 		err = svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"})
 		requireDeviceStatusCode(t, err, codes.NotFound, consts.CodeDeviceNotFound)
 
@@ -398,7 +452,7 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 			updateOnlineStatusFn: func(_ context.Context, _, _ string, _ int8) error {
 				return errors.New("db failed")
 			},
-		})
+		}, nil)
 		err = svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"})
 		requireDeviceStatusCode(t, err, codes.Internal, consts.CodeInternalError)
 	})
@@ -421,7 +475,7 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 				assert.Equal(t, model.DeviceStatusKicked, status)
 				return nil
 			},
-		})
+		}, nil)
 		require.NoError(t, svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"}))
 		assert.Equal(t, 1, updateCalls)
 
@@ -435,17 +489,57 @@ func TestUserDeviceServiceKickDevice(t *testing.T) {
 				updateCalls++
 				return nil
 			},
-		})
+		}, nil)
 		require.NoError(t, svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"}))
 		assert.Equal(t, 0, updateCalls)
 	})
+
+	t.Run("clears_push_token", func(t *testing.T) {
+		var cleared []string
+		svc := NewDeviceService(&fakeDeviceRepository{
+			getByDeviceIDFn: func(_ context.Context, _, _ string) (*model.DeviceSession, error) {
+				return &model.DeviceSession{UserUuid: "u1", DeviceId: "d1", Status: model.DeviceStatusOffline, PushToken: "tok-1"}, nil
+			},
+			updatePushTokenFn: func(_ context.Context, userUUID, deviceID, provider, token string) error {
+				cleared = append(cleared, userUUID+"/"+deviceID+"/"+provider+"/"+token)
+				return errors.New("db failed")
+			},
+		}, nil)
+		// 清除失败不影响踢出结果
+		require.NoError(t, svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"}))
+		assert.Equal(t, []string{"u1/d1//"}, cleared)
+	})
+
+	t.Run("kicks_connection_after_token_revoked", func(t *testing.T) {
+		kicker := &fakeConnectKicker{}
+		svc := NewDeviceService(&fakeDeviceRepository{
+			getByDeviceIDFn: func(_ context.Context, _, _ string) (*model.DeviceSession, error) {
+				return &model.DeviceSession{UserUuid: "u1", DeviceId: "d1", Status: model.DeviceStatusOnline}, nil
+			},
+		}, kicker)
+		require.NoError(t, svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"}))
+		assert.Equal(t, []string{"u1/d1/" + KickReasonDeviceKicked}, kicker.devices)
+
+		kicker = &fakeConnectKicker{}
+		svc = NewDeviceService(&fakeDeviceRepository{
+			getByDeviceIDFn: func(_ context.Context, _, _ string) (*model.DeviceSession, error) {
+				return &model.DeviceSession{UserUuid: "u1", DeviceId: "d1", Status: model.DeviceStatusOnline}, nil
+			},
+			deleteTokensFn: func(_ context.Context, _, _ string) error {
+				return errors.New("redis failed")
+			},
+		}, kicker)
+		err := svc.KickDevice(withDeviceContext("u1", "d9"), &pb.KickDeviceRequest{DeviceId: "d1"})
+		requireDeviceStatusCode(t, err, codes.Internal, consts.CodeInternalError)
+		assert.Empty(t, kicker.devices)
+	})
 }
 
 func TestUserDeviceServiceGetOnlineStatus(t *testing.T) {
 	initUserDeviceTestLogger()
 
 	t.Run("invalid_request", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil)
 
 		resp, err := svc.GetOnlineStatus(context.Background(), nil)
 		require.Nil(t, resp)
@@ -462,7 +556,7 @@ func TestUserDeviceServiceGetOnlineStatus(t *testing.T) {
 				assert.Equal(t, []string{"u1"}, userUUIDs)
 				return nil, errors.New("db failed")
 			},
-		})
+		}, nil)
 		resp, err := svc.GetOnlineStatus(context.Background(), &pb.GetOnlineStatusRequest{UserUuid: "u1"})
 		require.Nil(t, resp)
 		requireDeviceStatusCode(t, err, codes.Internal, consts.CodeInternalError)
@@ -473,7 +567,7 @@ func TestUserDeviceServiceGetOnlineStatus(t *testing.T) {
 			batchGetOnlineStatusFn: func(_ context.Context, _ []string) (map[string][]*model.DeviceSession, error) {
 				return map[string][]*model.DeviceSession{}, nil
 			},
-		})
+		}, nil)
 		resp, err := svc.GetOnlineStatus(context.Background(), &pb.GetOnlineStatusRequest{UserUuid: "u1"})
 		require.NoError(t, err)
 		require.NotNil(t, resp)
@@ -495,7 +589,7 @@ func TestUserDeviceServiceGetOnlineStatus(t *testing.T) {
 			getActiveTimestampsFn: func(_ context.Context, _ string, _ []string) (map[string]int64, error) {
 				return nil, errors.New("redis failed")
 			},
-		})
+		}, nil)
 		resp, err := svc.GetOnlineStatus(context.Background(), &pb.GetOnlineStatusRequest{UserUuid: "u1"})
 		require.NoError(t, err)
 		require.NotNil(t, resp)
@@ -528,7 +622,7 @@ func TestUserDeviceServiceGetOnlineStatus(t *testing.T) {
 				assert.Equal(t, []string{"u1"}, userUUIDs)
 				return map[string]int64{"u1": now - 10}, nil
 			},
-		})
+		}, nil)
 
 		resp, err := svc.GetOnlineStatus(context.Background(), &pb.GetOnlineStatusRequest{UserUuid: "u1"})
 		require.NoError(t, err)
@@ -544,7 +638,7 @@ func TestUserDeviceServiceBatchGetOnlineStatus(t *testing.T) {
 	initUserDeviceTestLogger()
 
 	t.Run("invalid_request", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil)
 
 		resp, err := svc.BatchGetOnlineStatus(context.Background(), nil)
 		require.Nil(t, resp)
@@ -573,7 +667,7 @@ func TestUserDeviceServiceBatchGetOnlineStatus(t *testing.T) {
 				assert.Equal(t, []string{"u1", "u2"}, userUUIDs)
 				return nil, errors.New("db failed")
 			},
-		})
+		}, nil)
 
 		resp, err := svc.BatchGetOnlineStatus(context.Background(), &pb.BatchGetOnlineStatusRequest{UserUuids: []string{"u1", "u2"}})
 		require.Nil(t, resp)
@@ -610,7 +704,7 @@ func TestUserDeviceServiceBatchGetOnlineStatus(t *testing.T) {
 					"u1": now - 10,
 				}, nil
 			},
-		})
+		}, nil)
 
 		req := &pb.BatchGetOnlineStatusRequest{UserUuids: []string{"u1", "u1", "u2", "u3"}}
 		resp, err := svc.BatchGetOnlineStatus(context.Background(), req)
@@ -644,13 +738,13 @@ func TestUserDeviceServiceUpdateDeviceStatus(t *testing.T) {
 	initUserDeviceTestLogger()
 
 	t.Run("nil_request", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil)
 		err := svc.UpdateDeviceStatus(context.Background(), nil)
 		requireDeviceStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
 	})
 
 	t.Run("empty_user_uuid", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil)
 		err := svc.UpdateDeviceStatus(context.Background(), &pb.UpdateDeviceStatusRequest{
 			UserUuid: "",
 			DeviceId: "d1",
@@ -660,7 +754,7 @@ func TestUserDeviceServiceUpdateDeviceStatus(t *testing.T) {
 	})
 
 	t.Run("empty_device_id", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil)
 		err := svc.UpdateDeviceStatus(context.Background(), &pb.UpdateDeviceStatusRequest{
 			UserUuid: "u1",
 			DeviceId: "",
@@ -670,7 +764,7 @@ func TestUserDeviceServiceUpdateDeviceStatus(t *testing.T) {
 	})
 
 	t.Run("invalid_status_kicked", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil)
 		err := svc.UpdateDeviceStatus(context.Background(), &pb.UpdateDeviceStatusRequest{
 			UserUuid: "u1",
 			DeviceId: "d1",
@@ -680,7 +774,7 @@ func TestUserDeviceServiceUpdateDeviceStatus(t *testing.T) {
 	})
 
 	t.Run("invalid_status_logged_out", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil)
 		err := svc.UpdateDeviceStatus(context.Background(), &pb.UpdateDeviceStatusRequest{
 			UserUuid: "u1",
 			DeviceId: "d1",
@@ -702,7 +796,7 @@ func TestUserDeviceServiceUpdateDeviceStatus(t *testing.T) {
 				captured.status = status
 				return nil
 			},
-		})
+		}, nil)
 		err := svc.UpdateDeviceStatus(context.Background(), &pb.UpdateDeviceStatusRequest{
 			UserUuid: "u1",
 			DeviceId: "d1",
@@ -721,7 +815,7 @@ func TestUserDeviceServiceUpdateDeviceStatus(t *testing.T) {
 				capturedStatus = status
 				return nil
 			},
-		})
+		}, nil)
 		err := svc.UpdateDeviceStatus(context.Background(), &pb.UpdateDeviceStatusRequest{
 			UserUuid: "u1",
 			DeviceId: "d1",
@@ -736,7 +830,7 @@ func TestUserDeviceServiceUpdateDeviceStatus(t *testing.T) {
 			updateOnlineStatusFn: func(_ context.Context, _, _ string, _ int8) error {
 				return repository.ErrRecordNotFound
 			},
-		})
+		}, nil)
 		// 设备不存在时应返回成功（幂等语义）
 		err := svc.UpdateDeviceStatus(context.Background(), &pb.UpdateDeviceStatusRequest{
 			UserUuid: "u1",
@@ -751,7 +845,7 @@ func TestUserDeviceServiceUpdateDeviceStatus(t *testing.T) {
 			updateOnlineStatusFn: func(_ context.Context, _, _ string, _ int8) error {
 				return errors.New("db write failed")
 			},
-		})
+		}, nil)
 		err := svc.UpdateDeviceStatus(context.Background(), &pb.UpdateDeviceStatusRequest{
 			UserUuid: "u1",
 			DeviceId: "d1",
@@ -765,19 +859,19 @@ func TestUserDeviceServiceUpdateDeviceActive(t *testing.T) {
 	initUserDeviceTestLogger()
 
 	t.Run("nil_request", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil)
 		err := svc.UpdateDeviceActive(context.Background(), nil)
 		requireDeviceStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
 	})
 
 	t.Run("empty_items", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil)
 		err := svc.UpdateDeviceActive(context.Background(), &pb.UpdateDeviceActiveRequest{Items: []*pb.UpdateDeviceActiveItem{}})
 		requireDeviceStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
 	})
 
 	t.Run("invalid_item", func(t *testing.T) {
-		svc := NewDeviceService(&fakeDeviceRepository{})
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil)
 		err := svc.UpdateDeviceActive(context.Background(), &pb.UpdateDeviceActiveRequest{
 			Items: []*pb.UpdateDeviceActiveItem{
 				{UserUuid: "u1", DeviceId: "d1"},
@@ -796,7 +890,7 @@ func TestUserDeviceServiceUpdateDeviceActive(t *testing.T) {
 				assert.Greater(t, ts, int64(0))
 				return errors.New("redis write failed")
 			},
-		})
+		}, nil)
 		err := svc.UpdateDeviceActive(context.Background(), &pb.UpdateDeviceActiveRequest{
 			Items: []*pb.UpdateDeviceActiveItem{
 				{UserUuid: "u1", DeviceId: "d1"},
@@ -819,7 +913,7 @@ func TestUserDeviceServiceUpdateDeviceActive(t *testing.T) {
 				assert.True(t, got["u2:d3"])
 				return nil
 			},
-		})
+		}, nil)
 		err := svc.UpdateDeviceActive(context.Background(), &pb.UpdateDeviceActiveRequest{
 			Items: []*pb.UpdateDeviceActiveItem{
 				{UserUuid: "u1", DeviceId: "d1"},
@@ -830,3 +924,249 @@ func TestUserDeviceServiceUpdateDeviceActive(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestUserDeviceServiceRegisterPushToken(t *testing.T) {
+	initUserDeviceTestLogger()
+
+	sessionWithPlatform := func(platform string) func(context.Context, string, string) (*model.DeviceSession, error) {
+		return func(_ context.Context, userUUID, deviceID string) (*model.DeviceSession, error) {
+			return &model.DeviceSession{UserUuid: userUUID, DeviceId: deviceID, Platform: platform}, nil
+		}
+	}
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil)
+		_, err := svc.RegisterPushToken(withDeviceContext("u1", ""), &pb.RegisterPushTokenRequest{Token: "tok"})
+		requireDeviceStatusCode(t, err, codes.Unauthenticated, consts.CodeUnauthorized)
+	})
+
+	t.Run("invalid_request", func(t *testing.T) {
+		svc := NewDeviceService(&fakeDeviceRepository{getByDeviceIDFn: sessionWithPlatform("iOS")}, nil)
+
+		_, err := svc.RegisterPushToken(withDeviceContext("u1", "d1"), nil)
+		requireDeviceStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
+
+		_, err = svc.RegisterPushToken(withDeviceContext("u1", "d1"), &pb.RegisterPushTokenRequest{Token: "  "})
+		requireDeviceStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
+
+		_, err = svc.RegisterPushToken(withDeviceContext("u1", "d1"), &pb.RegisterPushTokenRequest{Provider: "pigeon", Token: "tok"})
+		requireDeviceStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
+	})
+
+	t.Run("device_not_found", func(t *testing.T) {
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil)
+		_, err := svc.RegisterPushToken(withDeviceContext("u1", "d1"), &pb.RegisterPushTokenRequest{Token: "tok"})
+		requireDeviceStatusCode(t, err, codes.NotFound, consts.CodeDeviceNotFound)
+	})
+
+	t.Run("platform_not_support", func(t *testing.T) {
+		svc := NewDeviceService(&fakeDeviceRepository{getByDeviceIDFn: sessionWithPlatform("Web")}, nil)
+		_, err := svc.RegisterPushToken(withDeviceContext("u1", "d1"), &pb.RegisterPushTokenRequest{Token: "tok"})
+		requireDeviceStatusCode(t, err, codes.FailedPrecondition, consts.CodePlatformNotSupport)
+	})
+
+	t.Run("update_error", func(t *testing.T) {
+		svc := NewDeviceService(&fakeDeviceRepository{
+			getByDeviceIDFn: sessionWithPlatform("Android"),
+			updatePushTokenFn: func(_ context.Context, _, _, _, _ string) error {
+				return errors.New("db failed")
+			},
+		}, nil)
+		_, err := svc.RegisterPushToken(withDeviceContext("u1", "d1"), &pb.RegisterPushTokenRequest{Token: "tok"})
+		requireDeviceStatusCode(t, err, codes.Internal, consts.CodeInternalError)
+	})
+
+	t.Run("success_default_and_explicit_provider", func(t *testing.T) {
+		var saved []string
+		repo := &fakeDeviceRepository{
+			getByDeviceIDFn: sessionWithPlatform("Android"),
+			updatePushTokenFn: func(_ context.Context, userUUID, deviceID, provider, token string) error {
+				saved = append(saved, userUUID+"/"+deviceID+"/"+provider+"/"+token)
+				return nil
+			},
+		}
+		svc := NewDeviceService(repo, nil)
+
+		resp, err := svc.RegisterPushToken(withDeviceContext("u1", "d1"), &pb.RegisterPushTokenRequest{Token: " tok-1 "})
+		require.NoError(t, err)
+		assert.Equal(t, "fcm", resp.Provider)
+
+		resp, err = svc.RegisterPushToken(withDeviceContext("u1", "d1"), &pb.RegisterPushTokenRequest{Provider: "Huawei", Token: "tok-2"})
+		require.NoError(t, err)
+		assert.Equal(t, "huawei", resp.Provider)
+
+		assert.Equal(t, []string{"u1/d1/fcm/tok-1", "u1/d1/huawei/tok-2"}, saved)
+	})
+}
+
+func TestUserDeviceServiceUnregisterPushToken(t *testing.T) {
+	initUserDeviceTestLogger()
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil)
+		err := svc.UnregisterPushToken(context.Background(), &pb.UnregisterPushTokenRequest{})
+		requireDeviceStatusCode(t, err, codes.Unauthenticated, consts.CodeUnauthorized)
+	})
+
+	t.Run("idempotent_when_device_missing", func(t *testing.T) {
+		svc := NewDeviceService(&fakeDeviceRepository{
+			updatePushTokenFn: func(_ context.Context, _, _, provider, token string) error {
+				require.Empty(t, provider)
+				require.Empty(t, token)
+				return repository.ErrRecordNotFound
+			},
+		}, nil)
+		require.NoError(t, svc.UnregisterPushToken(withDeviceContext("u1", "d1"), &pb.UnregisterPushTokenRequest{}))
+	})
+
+	t.Run("update_error", func(t *testing.T) {
+		svc := NewDeviceService(&fakeDeviceRepository{
+			updatePushTokenFn: func(_ context.Context, _, _, _, _ string) error {
+				return errors.New("db failed")
+			},
+		}, nil)
+		err := svc.UnregisterPushToken(withDeviceContext("u1", "d1"), &pb.UnregisterPushTokenRequest{})
+		requireDeviceStatusCode(t, err, codes.Internal, consts.CodeInternalError)
+	})
+}
+
+func TestUserDeviceServiceGetNotificationSettings(t *testing.T) {
+	initUserDeviceTestLogger()
+
+	ownDevice := func(_ context.Context, userUUID, deviceID string) (*model.DeviceSession, error) {
+		return &model.DeviceSession{UserUuid: userUUID, DeviceId: deviceID}, nil
+	}
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil)
+		_, err := svc.GetNotificationSettings(context.Background(), &pb.GetNotificationSettingsRequest{})
+		requireDeviceStatusCode(t, err, codes.Unauthenticated, consts.CodeUnauthorized)
+	})
+
+	t.Run("device_not_owned", func(t *testing.T) {
+		svc := NewDeviceService(&fakeDeviceRepository{}, nil)
+		_, err := svc.GetNotificationSettings(withDeviceContext("u1", "d1"), &pb.GetNotificationSettingsRequest{DeviceId: "other"})
+		requireDeviceStatusCode(t, err, codes.NotFound, consts.CodeDeviceNotFound)
+	})
+
+	t.Run("defaults_for_current_device", func(t *testing.T) {
+		svc := NewDeviceService(&fakeDeviceRepository{getByDeviceIDFn: ownDevice}, nil)
+		resp, err := svc.GetNotificationSettings(withDeviceContext("u1", "d1"), &pb.GetNotificationSettingsRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, &pb.NotificationSettings{
+			DeviceId:    "d1",
+			PushEnabled: true,
+			QuietStart:  22 * 60,
+			QuietEnd:    8 * 60,
+			ShowPreview: true,
+			Sound:       true,
+		}, resp.Settings)
+	})
+
+	t.Run("stored_and_error", func(t *testing.T) {
+		svc := NewDeviceService(&fakeDeviceRepository{
+			getByDeviceIDFn: ownDevice,
+			getNotifySettingFn: func(_ context.Context, userUUID, deviceID string) (*model.DeviceNotificationSetting, error) {
+				require.Equal(t, "u1", userUUID)
+				require.Equal(t, "d2", deviceID)
+				return &model.DeviceNotificationSetting{DeviceId: "d2", QuietHoursEnabled: true, QuietStart: 60, QuietEnd: 120, Timezone: "UTC"}, nil
+			},
+		}, nil)
+		resp, err := svc.GetNotificationSettings(withDeviceContext("u1", "d1"), &pb.GetNotificationSettingsRequest{DeviceId: "d2"})
+		require.NoError(t, err)
+		assert.False(t, resp.Settings.PushEnabled)
+		assert.True(t, resp.Settings.QuietHoursEnabled)
+		assert.Equal(t, int32(60), resp.Settings.QuietStart)
+		assert.Equal(t, "UTC", resp.Settings.Timezone)
+
+		svc = NewDeviceService(&fakeDeviceRepository{
+			getByDeviceIDFn: ownDevice,
+			getNotifySettingFn: func(_ context.Context, _, _ string) (*model.DeviceNotificationSetting, error) {
+				return nil, errors.New("db failed")
+			},
+		}, nil)
+		_, err = svc.GetNotificationSettings(withDeviceContext("u1", "d1"), &pb.GetNotificationSettingsRequest{})
+		requireDeviceStatusCode(t, err, codes.Internal, consts.CodeInternalError)
+	})
+}
+
+func TestUserDeviceServiceUpdateNotificationSettings(t *testing.T) {
+	initUserDeviceTestLogger()
+
+	ownDevice := func(_ context.Context, userUUID, deviceID string) (*model.DeviceSession, error) {
+		return &model.DeviceSession{UserUuid: userUUID, DeviceId: deviceID}, nil
+	}
+	boolPtr := func(v bool) *bool { return &v }
+	int32Ptr := func(v int32) *int32 { return &v }
+	stringPtr := func(v string) *string { return &v }
+
+	t.Run("invalid_request", func(t *testing.T) {
+		var upserts int
+		svc := NewDeviceService(&fakeDeviceRepository{
+			getByDeviceIDFn: ownDevice,
+			upsertNotifySettingFn: func(_ context.Context, _ *model.DeviceNotificationSetting) error {
+				upserts++
+				return nil
+			},
+		}, nil)
+		ctx := withDeviceContext("u1", "d1")
+
+		for _, req := range []*pb.UpdateNotificationSettingsRequest{
+			nil,
+			{QuietStart: int32Ptr(-1)},
+			{QuietEnd: int32Ptr(1440)},
+			{Timezone: stringPtr("Mars/Olympus")},
+			{QuietHoursEnabled: boolPtr(true), QuietStart: int32Ptr(60), QuietEnd: int32Ptr(60)},
+		} {
+			_, err := svc.UpdateNotificationSettings(ctx, req)
+			requireDeviceStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
+		}
+		assert.Zero(t, upserts)
+	})
+
+	t.Run("partial_update_keeps_other_fields", func(t *testing.T) {
+		var saved *model.DeviceNotificationSetting
+		svc := NewDeviceService(&fakeDeviceRepository{
+			getByDeviceIDFn: ownDevice,
+			getNotifySettingFn: func(_ context.Context, userUUID, deviceID string) (*model.DeviceNotificationSetting, error) {
+				return &model.DeviceNotificationSetting{UserUuid: userUUID, DeviceId: deviceID, PushEnabled: true, QuietStart: 1320, QuietEnd: 480, ShowPreview: true, Sound: true}, nil
+			},
+			upsertNotifySettingFn: func(_ context.Context, setting *model.DeviceNotificationSetting) error {
+				saved = setting
+				return nil
+			},
+		}, nil)
+
+		resp, err := svc.UpdateNotificationSettings(withDeviceContext("u1", "d1"), &pb.UpdateNotificationSettingsRequest{
+			QuietHoursEnabled: boolPtr(true),
+			QuietEnd:          int32Ptr(420),
+			Timezone:          stringPtr(" Asia/Shanghai "),
+			ShowPreview:       boolPtr(false),
+		})
+		require.NoError(t, err)
+		require.NotNil(t, saved)
+		assert.Equal(t, "u1", saved.UserUuid)
+		assert.Equal(t, "d1", saved.DeviceId)
+		assert.Equal(t, &pb.NotificationSettings{
+			DeviceId:          "d1",
+			PushEnabled:       true,
+			QuietHoursEnabled: true,
+			QuietStart:        1320,
+			QuietEnd:          420,
+			Timezone:          "Asia/Shanghai",
+			ShowPreview:       false,
+			Sound:             true,
+		}, resp.Settings)
+	})
+
+	t.Run("upsert_error", func(t *testing.T) {
+		svc := NewDeviceService(&fakeDeviceRepository{
+			getByDeviceIDFn: ownDevice,
+			upsertNotifySettingFn: func(_ context.Context, _ *model.DeviceNotificationSetting) error {
+				return errors.New("db failed")
+			},
+		}, nil)
+		_, err := svc.UpdateNotificationSettings(withDeviceContext("u1", "d1"), &pb.UpdateNotificationSettingsRequest{Sound: boolPtr(false)})
+		requireDeviceStatusCode(t, err, codes.Internal, consts.CodeInternalError)
+	})
+}
@@ -0,0 +1,507 @@
+package service
+
+import (
+	connectpb "ChatServer/apps/connect/pb"
+	"ChatServer/apps/user/internal/repository"
+	pb "ChatServer/apps/user/pb"
+	"ChatServer/consts"
+	"ChatServer/model"
+	"ChatServer/pkg/async"
+	"ChatServer/pkg/ctxmeta"
+	"ChatServer/pkg/logger"
+	"ChatServer/pkg/util"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// groupAnnouncementMaxLen 群公告最大字符数，与 group_announcement.content 列宽一致
+	groupAnnouncementMaxLen = 2000
+	// groupAnnouncementListLimit 群公告列表最多返回条数
+	groupAnnouncementListLimit = 100
+	// groupAnnouncementPushTimeout 新公告推送超时
+	groupAnnouncementPushTimeout = 10 * time.Second
+
+	// EnvelopeTypeGroupAnnouncement 新群公告下行消息类型，客户端据此在群会话顶部展示横幅
+	EnvelopeTypeGroupAnnouncement = "group_announcement"
+)
+
+// groupAnnouncementPayload type=group_announcement 的 data 结构
+type groupAnnouncementPayload struct {
+	GroupUUID        string `json:"group_uuid"`
+	AnnouncementUUID string `json:"announcement_uuid"`
+	AuthorUUID       string `json:"author_uuid"`
+	AuthorName       string `json:"author_name,omitempty"` // 发布者群内展示名（群名片优先），查询失败时为空
+	Content          string `json:"content"`
+	Pinned           bool   `json:"pinned"`
+	RequireAck       bool   `json:"require_ack"`
+	CreatedAt        int64  `json:"created_at"`
+}
+
+// PostGroupAnnouncement 发布群公告
+// 业务流程：
+//  1. 从context中获取当前用户UUID
+//  2. 校验公告长度
+//  3. 校验当前用户为群主或管理员
+//  4. 写入公告
+//  5. 异步向群成员推送 group_announcement 下行消息
+//
+// 错误码映射：
+//   - codes.InvalidArgument: 参数错误、公告过长
+//   - codes.NotFound: 群组不存在
+//   - codes.FailedPrecondition: 群组已解散
+//   - codes.PermissionDenied: 不是群成员、不是群主或管理员
+//   - codes.Internal: 系统内部错误
+func (s *groupServiceImpl) PostGroupAnnouncement(ctx context.Context, req *pb.PostGroupAnnouncementRequest) (*pb.PostGroupAnnouncementResponse, error) {
+	// 1. 从context中获取当前用户UUID
+	currentUserUUID := util.GetUserUUIDFromContext(ctx)
+	if currentUserUUID == "" {
+		logger.Error(ctx, "获取用户UUID失败")
+		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	// 2. 参数校验
+	if req == nil || req.GroupUuid == "" || req.Content == "" {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+	if utf8.RuneCountInString(req.Content) > groupAnnouncementMaxLen {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeGroupNoticeTooLong))
+	}
+
+	// 3. 权限校验
+	if err := s.requireGroupManager(ctx, req.GroupUuid, currentUserUUID); err != nil {
+		return nil, err
+	}
+
+	// 4. 写入公告
+	announcement := &model.GroupAnnouncement{
+		Uuid:       util.GenIDString(),
+		GroupUuid:  req.GroupUuid,
+		AuthorUuid: currentUserUUID,
+		Content:    req.Content,
+		Pinned:     req.Pinned,
+		RequireAck: req.RequireAck,
+	}
+	if err := s.groupRepo.CreateAnnouncement(ctx, announcement); err != nil {
+		logger.Error(ctx, "发布群公告失败",
+			logger.String("group_uuid", req.GroupUuid),
+			logger.String("author_uuid", currentUserUUID),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	logger.Info(ctx, "发布群公告成功",
+		logger.String("group_uuid", req.GroupUuid),
+		logger.String("announcement_uuid", announcement.Uuid),
+		logger.String("author_uuid", currentUserUUID),
+		logger.Bool("require_ack", req.RequireAck),
+	)
+
+	// 5. 推送新公告
+	s.pushGroupAnnouncement(ctx, announcement)
+
+	return &pb.PostGroupAnnouncementResponse{
+		Announcement: toGroupAnnouncementPB(announcement, false),
+	}, nil
+}
+
+// EditGroupAnnouncement 编辑群公告
+// 仅群主或管理员可编辑；内容变化后已有确认记录失效，成员需重新确认。
+func (s *groupServiceImpl) EditGroupAnnouncement(ctx context.Context, req *pb.EditGroupAnnouncementRequest) error {
+	// 1. 从context中获取当前用户UUID
+	currentUserUUID := util.GetUserUUIDFromContext(ctx)
+	if currentUserUUID == "" {
+		logger.Error(ctx, "获取用户UUID失败")
+		return status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	// 2. 参数校验
+	if req == nil || req.GroupUuid == "" || req.AnnouncementUuid == "" || req.Content == "" {
+		return status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+	if utf8.RuneCountInString(req.Content) > groupAnnouncementMaxLen {
+		return status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeGroupNoticeTooLong))
+	}
+
+	// 3. 权限校验 + 公告存在性
+	if err := s.requireGroupManager(ctx, req.GroupUuid, currentUserUUID); err != nil {
+		return err
+	}
+	if _, err := s.getGroupAnnouncement(ctx, req.GroupUuid, req.AnnouncementUuid); err != nil {
+		return err
+	}
+
+	// 4. 更新公告
+	if err := s.groupRepo.UpdateAnnouncement(ctx, req.AnnouncementUuid, currentUserUUID, req.Content, req.RequireAck); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return status.Error(codes.NotFound, strconv.Itoa(consts.CodeGroupAnnouncementNotFound))
+		}
+		logger.Error(ctx, "编辑群公告失败",
+			logger.String("group_uuid", req.GroupUuid),
+			logger.String("announcement_uuid", req.AnnouncementUuid),
+			logger.ErrorField("error", err),
+		)
+		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	logger.Info(ctx, "编辑群公告成功",
+		logger.String("group_uuid", req.GroupUuid),
+		logger.String("announcement_uuid", req.AnnouncementUuid),
+		logger.String("editor_uuid", currentUserUUID),
+	)
+	return nil
+}
+
+// PinGroupAnnouncement 置顶/取消置顶群公告
+// 仅群主或管理员可操作。
+func (s *groupServiceImpl) PinGroupAnnouncement(ctx context.Context, req *pb.PinGroupAnnouncementRequest) error {
+	// 1. 从context中获取当前用户UUID
+	currentUserUUID := util.GetUserUUIDFromContext(ctx)
+	if currentUserUUID == "" {
+		logger.Error(ctx, "获取用户UUID失败")
+		return status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	// 2. 参数校验
+	if req == nil || req.GroupUuid == "" || req.AnnouncementUuid == "" {
+		return status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	// 3. 权限校验 + 公告存在性
+	if err := s.requireGroupManager(ctx, req.GroupUuid, currentUserUUID); err != nil {
+		return err
+	}
+	if _, err := s.getGroupAnnouncement(ctx, req.GroupUuid, req.AnnouncementUuid); err != nil {
+		return err
+	}
+
+	// 4. 更新置顶状态
+	if err := s.groupRepo.SetAnnouncementPinned(ctx, req.AnnouncementUuid, req.Pinned); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return status.Error(codes.NotFound, strconv.Itoa(consts.CodeGroupAnnouncementNotFound))
+		}
+		logger.Error(ctx, "置顶群公告失败",
+			logger.String("group_uuid", req.GroupUuid),
+			logger.String("announcement_uuid", req.AnnouncementUuid),
+			logger.Bool("pinned", req.Pinned),
+			logger.ErrorField("error", err),
+		)
+		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+	return nil
+}
+
+// DeleteGroupAnnouncement 删除群公告
+// 仅群主或管理员可删除。
+func (s *groupServiceImpl) DeleteGroupAnnouncement(ctx context.Context, req *pb.DeleteGroupAnnouncementRequest) error {
+	// 1. 从context中获取当前用户UUID
+	currentUserUUID := util.GetUserUUIDFromContext(ctx)
+	if currentUserUUID == "" {
+		logger.Error(ctx, "获取用户UUID失败")
+		return status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	// 2. 参数校验
+	if req == nil || req.GroupUuid == "" || req.AnnouncementUuid == "" {
+		return status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	// 3. 权限校验 + 公告存在性
+	if err := s.requireGroupManager(ctx, req.GroupUuid, currentUserUUID); err != nil {
+		return err
+	}
+	if _, err := s.getGroupAnnouncement(ctx, req.GroupUuid, req.AnnouncementUuid); err != nil {
+		return err
+	}
+
+	// 4. 删除公告
+	if err := s.groupRepo.DeleteAnnouncement(ctx, req.AnnouncementUuid); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return status.Error(codes.NotFound, strconv.Itoa(consts.CodeGroupAnnouncementNotFound))
+		}
+		logger.Error(ctx, "删除群公告失败",
+			logger.String("group_uuid", req.GroupUuid),
+			logger.String("announcement_uuid", req.AnnouncementUuid),
+			logger.ErrorField("error", err),
+		)
+		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	logger.Info(ctx, "删除群公告成功",
+		logger.String("group_uuid", req.GroupUuid),
+		logger.String("announcement_uuid", req.AnnouncementUuid),
+		logger.String("operator_uuid", currentUserUUID),
+	)
+	return nil
+}
+
+// ListGroupAnnouncements 获取群公告列表
+// 群成员可查看；置顶公告在前，其余按发布时间倒序，并标注当前用户是否已确认。
+func (s *groupServiceImpl) ListGroupAnnouncements(ctx context.Context, req *pb.ListGroupAnnouncementsRequest) (*pb.ListGroupAnnouncementsResponse, error) {
+	// 1. 从context中获取当前用户UUID
+	currentUserUUID := util.GetUserUUIDFromContext(ctx)
+	if currentUserUUID == "" {
+		logger.Error(ctx, "获取用户UUID失败")
+		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	// 2. 参数校验
+	if req == nil || req.GroupUuid == "" {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	// 3. 校验成员身份
+	if _, err := s.getActiveMember(ctx, req.GroupUuid, currentUserUUID); err != nil {
+		return nil, err
+	}
+
+	// 4. 查询公告与确认状态
+	announcements, err := s.groupRepo.ListAnnouncements(ctx, req.GroupUuid, groupAnnouncementListLimit)
+	if err != nil {
+		logger.Error(ctx, "查询群公告失败",
+			logger.String("group_uuid", req.GroupUuid),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	ackUUIDs := make([]string, 0, len(announcements))
+	for _, announcement := range announcements {
+		if announcement != nil && announcement.RequireAck {
+			ackUUIDs = append(ackUUIDs, announcement.Uuid)
+		}
+	}
+	acked, err := s.groupRepo.ListAckedAnnouncementUUIDs(ctx, currentUserUUID, ackUUIDs)
+	if err != nil {
+		logger.Error(ctx, "查询群公告确认状态失败",
+			logger.String("group_uuid", req.GroupUuid),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	items := make([]*pb.GroupAnnouncement, 0, len(announcements))
+	for _, announcement := range announcements {
+		if announcement == nil {
+			continue
+		}
+		items = append(items, toGroupAnnouncementPB(announcement, acked[announcement.Uuid]))
+	}
+
+	return &pb.ListGroupAnnouncementsResponse{Announcements: items}, nil
+}
+
+// AckGroupAnnouncement 确认群公告
+// 群成员可确认；公告未要求确认时直接成功，重复确认幂等。
+func (s *groupServiceImpl) AckGroupAnnouncement(ctx context.Context, req *pb.AckGroupAnnouncementRequest) error {
+	// 1. 从context中获取当前用户UUID
+	currentUserUUID := util.GetUserUUIDFromContext(ctx)
+	if currentUserUUID == "" {
+		logger.Error(ctx, "获取用户UUID失败")
+		return status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	// 2. 参数校验
+	if req == nil || req.GroupUuid == "" || req.AnnouncementUuid == "" {
+		return status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	// 3. 校验成员身份 + 公告存在性
+	if _, err := s.getActiveMember(ctx, req.GroupUuid, currentUserUUID); err != nil {
+		return err
+	}
+	announcement, err := s.getGroupAnnouncement(ctx, req.GroupUuid, req.AnnouncementUuid)
+	if err != nil {
+		return err
+	}
+	if !announcement.RequireAck {
+		return nil
+	}
+
+	// 4. 写入确认记录
+	if err := s.groupRepo.AckAnnouncement(ctx, req.AnnouncementUuid, currentUserUUID); err != nil {
+		logger.Error(ctx, "确认群公告失败",
+			logger.String("group_uuid", req.GroupUuid),
+			logger.String("announcement_uuid", req.AnnouncementUuid),
+			logger.String("user_uuid", currentUserUUID),
+			logger.ErrorField("error", err),
+		)
+		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+	return nil
+}
+
+// GetGroupAnnouncementAcks 查询公告确认情况
+// 仅群主可查询；未确认列表基于当前正常成员计算（已退群成员不计入）。
+func (s *groupServiceImpl) GetGroupAnnouncementAcks(ctx context.Context, req *pb.GetGroupAnnouncementAcksRequest) (*pb.GetGroupAnnouncementAcksResponse, error) {
+	// 1. 从context中获取当前用户UUID
+	currentUserUUID := util.GetUserUUIDFromContext(ctx)
+	if currentUserUUID == "" {
+		logger.Error(ctx, "获取用户UUID失败")
+		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	// 2. 参数校验
+	if req == nil || req.GroupUuid == "" || req.AnnouncementUuid == "" {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	// 3. 权限校验：仅群主
+	member, err := s.getActiveMember(ctx, req.GroupUuid, currentUserUUID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role != model.GroupRoleOwner {
+		return nil, status.Error(codes.PermissionDenied, strconv.Itoa(consts.CodeNoPermission))
+	}
+	if _, err := s.getGroupAnnouncement(ctx, req.GroupUuid, req.AnnouncementUuid); err != nil {
+		return nil, err
+	}
+
+	// 4. 查询确认记录与当前成员
+	acks, err := s.groupRepo.ListAnnouncementAcks(ctx, req.AnnouncementUuid)
+	if err != nil {
+		logger.Error(ctx, "查询群公告确认记录失败",
+			logger.String("announcement_uuid", req.AnnouncementUuid),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+	memberUUIDs, err := s.groupRepo.ListMemberUUIDs(ctx, req.GroupUuid)
+	if err != nil {
+		logger.Error(ctx, "查询群成员失败",
+			logger.String("group_uuid", req.GroupUuid),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	// 5. 组装结果：已确认（仅当前成员）+ 未确认
+	isMember := make(map[string]bool, len(memberUUIDs))
+	for _, userUUID := range memberUUIDs {
+		isMember[userUUID] = true
+	}
+	ackedSet := make(map[string]bool, len(acks))
+	ackedItems := make([]*pb.GroupAnnouncementAckItem, 0, len(acks))
+	for _, ack := range acks {
+		if ack == nil || !isMember[ack.UserUuid] {
+			continue
+		}
+		ackedSet[ack.UserUuid] = true
+		ackedItems = append(ackedItems, &pb.GroupAnnouncementAckItem{
+			UserUuid: ack.UserUuid,
+			AckedAt:  util.TimeToUnixMilli(ack.AckedAt),
+		})
+	}
+	unacked := make([]string, 0, len(memberUUIDs)-len(ackedItems))
+	for _, userUUID := range memberUUIDs {
+		if !ackedSet[userUUID] {
+			unacked = append(unacked, userUUID)
+		}
+	}
+
+	return &pb.GetGroupAnnouncementAcksResponse{
+		Acked:            ackedItems,
+		UnackedUserUuids: unacked,
+	}, nil
+}
+
+// getGroupAnnouncement 查询群公告，不存在时返回 CodeGroupAnnouncementNotFound
+func (s *groupServiceImpl) getGroupAnnouncement(ctx context.Context, groupUUID, announcementUUID string) (*model.GroupAnnouncement, error) {
+	announcement, err := s.groupRepo.GetAnnouncement(ctx, groupUUID, announcementUUID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, strconv.Itoa(consts.CodeGroupAnnouncementNotFound))
+		}
+		logger.Error(ctx, "查询群公告失败",
+			logger.String("group_uuid", groupUUID),
+			logger.String("announcement_uuid", announcementUUID),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+	return announcement, nil
+}
+
+// pushGroupAnnouncement 异步向群成员推送新公告（未配置 connect 时跳过）
+func (s *groupServiceImpl) pushGroupAnnouncement(ctx context.Context, announcement *model.GroupAnnouncement) {
+	if s.pusher == nil {
+		return
+	}
+
+	async.RunSafe(ctx, func(runCtx context.Context) {
+		data, err := json.Marshal(groupAnnouncementPayload{
+			GroupUUID:        announcement.GroupUuid,
+			AnnouncementUUID: announcement.Uuid,
+			AuthorUUID:       announcement.AuthorUuid,
+			AuthorName:       s.announcementAuthorName(runCtx, announcement),
+			Content:          announcement.Content,
+			Pinned:           announcement.Pinned,
+			RequireAck:       announcement.RequireAck,
+			CreatedAt:        util.TimeToUnixMilli(announcement.CreatedAt),
+		})
+		if err != nil {
+			logger.Error(runCtx, "序列化群公告推送失败", logger.ErrorField("error", err))
+			return
+		}
+
+		memberUUIDs, err := s.groupRepo.ListMemberUUIDs(runCtx, announcement.GroupUuid)
+		if err != nil {
+			logger.Warn(runCtx, "查询群成员失败，跳过群公告推送",
+				logger.String("group_uuid", announcement.GroupUuid),
+				logger.ErrorField("error", err),
+			)
+			return
+		}
+
+		envelope := &connectpb.MessageEnvelope{
+			Type:     EnvelopeTypeGroupAnnouncement,
+			Data:     data,
+			ServerTs: time.Now().UnixMilli(),
+			TraceId:  ctxmeta.TraceID(runCtx),
+		}
+		if err := s.pusher.BroadcastToUsers(runCtx, memberUUIDs, envelope); err != nil {
+			logger.Warn(runCtx, "推送群公告失败",
+				logger.String("group_uuid", announcement.GroupUuid),
+				logger.String("announcement_uuid", announcement.Uuid),
+				logger.Int("members", len(memberUUIDs)),
+				logger.ErrorField("error", err),
+			)
+		}
+	}, groupAnnouncementPushTimeout)
+}
+
+// announcementAuthorName 查询公告发布者的群内展示名，失败时返回空（BatchGetMemberDisplayName 已记录日志）
+func (s *groupServiceImpl) announcementAuthorName(ctx context.Context, announcement *model.GroupAnnouncement) string {
+	resp, err := s.BatchGetMemberDisplayName(ctx, &pb.BatchGetMemberDisplayNameRequest{
+		GroupUuid: announcement.GroupUuid,
+		UserUuids: []string{announcement.AuthorUuid},
+	})
+	if err != nil || len(resp.Members) == 0 {
+		return ""
+	}
+	return resp.Members[0].DisplayName
+}
+
+// toGroupAnnouncementPB 转换群公告为 protobuf 结构
+func toGroupAnnouncementPB(announcement *model.GroupAnnouncement, acked bool) *pb.GroupAnnouncement {
+	return &pb.GroupAnnouncement{
+		AnnouncementUuid: announcement.Uuid,
+		GroupUuid:        announcement.GroupUuid,
+		AuthorUuid:       announcement.AuthorUuid,
+		Content:          announcement.Content,
+		Pinned:           announcement.Pinned,
+		RequireAck:       announcement.RequireAck,
+		Acked:            acked,
+		CreatedAt:        util.TimeToUnixMilli(announcement.CreatedAt),
+		UpdatedAt:        util.TimeToUnixMilli(announcement.UpdatedAt),
+	}
+}
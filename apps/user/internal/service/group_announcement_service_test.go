@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	connectpb "ChatServer/apps/connect/pb"
+	"ChatServer/apps/user/internal/repository"
+	pb "ChatServer/apps/user/pb"
+	"ChatServer/config"
+	"ChatServer/consts"
+	"ChatServer/model"
+	"ChatServer/pkg/async"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+var userGroupAsyncOnce sync.Once
+
+func initUserGroupTestAsync(t *testing.T) {
+	t.Helper()
+	userGroupAsyncOnce.Do(func() {
+		require.NoError(t, async.Init(config.DefaultAsyncConfig()))
+	})
+}
+
+type fakeConnectPusher struct {
+	calls chan fakeConnectPush
+	err   error
+}
+
+type fakeConnectPush struct {
+	userUUIDs []string
+	envelope  *connectpb.MessageEnvelope
+}
+
+func newFakeConnectPusher() *fakeConnectPusher {
+	return &fakeConnectPusher{calls: make(chan fakeConnectPush, 4)}
+}
+
+func (f *fakeConnectPusher) BroadcastToUsers(_ context.Context, userUUIDs []string, envelope *connectpb.MessageEnvelope) error {
+	f.calls <- fakeConnectPush{userUUIDs: userUUIDs, envelope: envelope}
+	return f.err
+}
+
+func announcementMemberFn(role int8) func(context.Context, string, string) (*model.GroupMember, error) {
+	return func(_ context.Context, groupUUID, userUUID string) (*model.GroupMember, error) {
+		return &model.GroupMember{GroupUuid: groupUUID, UserUuid: userUUID, Role: role}, nil
+	}
+}
+
+func TestUserGroupServicePostGroupAnnouncement(t *testing.T) {
+	initUserGroupTestLogger()
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{}, &fakeUserSvcRepo{}, nil, nil)
+		_, err := svc.PostGroupAnnouncement(context.Background(), &pb.PostGroupAnnouncementRequest{GroupUuid: "g1", Content: "hi"})
+		requireGroupStatusCode(t, err, codes.Unauthenticated, consts.CodeUnauthorized)
+	})
+
+	t.Run("content_too_long", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{}, &fakeUserSvcRepo{}, nil, nil)
+		content := make([]rune, groupAnnouncementMaxLen+1)
+		for i := range content {
+			content[i] = '告'
+		}
+		_, err := svc.PostGroupAnnouncement(withGroupUserUUID("u1"), &pb.PostGroupAnnouncementRequest{GroupUuid: "g1", Content: string(content)})
+		requireGroupStatusCode(t, err, codes.InvalidArgument, consts.CodeGroupNoticeTooLong)
+	})
+
+	t.Run("member_no_permission", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getMemberFn: announcementMemberFn(model.GroupRoleMember),
+		}, &fakeUserSvcRepo{}, nil, nil)
+		_, err := svc.PostGroupAnnouncement(withGroupUserUUID("u1"), &pb.PostGroupAnnouncementRequest{GroupUuid: "g1", Content: "hi"})
+		requireGroupStatusCode(t, err, codes.PermissionDenied, consts.CodeNoPermission)
+	})
+
+	t.Run("success_pushes_envelope", func(t *testing.T) {
+		initUserGroupTestAsync(t)
+		pusher := newFakeConnectPusher()
+		var created *model.GroupAnnouncement
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getMemberFn: announcementMemberFn(model.GroupRoleAdmin),
+			createAnnouncementFn: func(_ context.Context, announcement *model.GroupAnnouncement) error {
+				created = announcement
+				return nil
+			},
+			listMemberUUIDsFn: func(context.Context, string) ([]string, error) {
+				return []string{"u1", "u2", "u3"}, nil
+			},
+			batchGetMembersFn: func(_ context.Context, groupUUID string, userUUIDs []string) ([]*model.GroupMember, error) {
+				return []*model.GroupMember{{GroupUuid: groupUUID, UserUuid: userUUIDs[0], Remark: "行政小王"}}, nil
+			},
+		}, avatarUsersRepo(nil), nil, pusher)
+
+		resp, err := svc.PostGroupAnnouncement(withGroupUserUUID("u1"), &pb.PostGroupAnnouncementRequest{
+			GroupUuid:  "g1",
+			Content:    "周五团建",
+			Pinned:     true,
+			RequireAck: true,
+		})
+		require.NoError(t, err)
+		require.NotNil(t, created)
+		assert.NotEmpty(t, created.Uuid)
+		assert.Equal(t, "u1", created.AuthorUuid)
+		assert.True(t, created.Pinned)
+		assert.True(t, created.RequireAck)
+		assert.Equal(t, created.Uuid, resp.Announcement.AnnouncementUuid)
+
+		select {
+		case push := <-pusher.calls:
+			assert.Equal(t, []string{"u1", "u2", "u3"}, push.userUUIDs)
+			assert.Equal(t, EnvelopeTypeGroupAnnouncement, push.envelope.Type)
+			var payload groupAnnouncementPayload
+			require.NoError(t, json.Unmarshal(push.envelope.Data, &payload))
+			assert.Equal(t, "g1", payload.GroupUUID)
+			assert.Equal(t, created.Uuid, payload.AnnouncementUUID)
+			assert.Equal(t, "周五团建", payload.Content)
+			assert.Equal(t, "行政小王", payload.AuthorName)
+			assert.True(t, payload.RequireAck)
+		case <-time.After(time.Second):
+			t.Fatal("announcement not pushed")
+		}
+	})
+
+	t.Run("create_error", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getMemberFn: announcementMemberFn(model.GroupRoleOwner),
+			createAnnouncementFn: func(context.Context, *model.GroupAnnouncement) error {
+				return errors.New("db down")
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		_, err := svc.PostGroupAnnouncement(withGroupUserUUID("u1"), &pb.PostGroupAnnouncementRequest{GroupUuid: "g1", Content: "hi"})
+		requireGroupStatusCode(t, err, codes.Internal, consts.CodeInternalError)
+	})
+}
+
+func TestUserGroupServiceManageGroupAnnouncement(t *testing.T) {
+	initUserGroupTestLogger()
+
+	t.Run("edit_not_found", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getMemberFn: announcementMemberFn(model.GroupRoleOwner),
+			getAnnouncementFn: func(context.Context, string, string) (*model.GroupAnnouncement, error) {
+				return nil, repository.ErrRecordNotFound
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.EditGroupAnnouncement(withGroupUserUUID("u1"), &pb.EditGroupAnnouncementRequest{GroupUuid: "g1", AnnouncementUuid: "a1", Content: "new"})
+		requireGroupStatusCode(t, err, codes.NotFound, consts.CodeGroupAnnouncementNotFound)
+	})
+
+	t.Run("edit_success", func(t *testing.T) {
+		var gotEditor, gotContent string
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getMemberFn: announcementMemberFn(model.GroupRoleAdmin),
+			updateAnnouncementFn: func(_ context.Context, _ string, editorUUID, content string, _ bool) error {
+				gotEditor, gotContent = editorUUID, content
+				return nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.EditGroupAnnouncement(withGroupUserUUID("u1"), &pb.EditGroupAnnouncementRequest{GroupUuid: "g1", AnnouncementUuid: "a1", Content: "new"})
+		require.NoError(t, err)
+		assert.Equal(t, "u1", gotEditor)
+		assert.Equal(t, "new", gotContent)
+	})
+
+	t.Run("pin_member_no_permission", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getMemberFn: announcementMemberFn(model.GroupRoleMember),
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.PinGroupAnnouncement(withGroupUserUUID("u1"), &pb.PinGroupAnnouncementRequest{GroupUuid: "g1", AnnouncementUuid: "a1", Pinned: true})
+		requireGroupStatusCode(t, err, codes.PermissionDenied, consts.CodeNoPermission)
+	})
+
+	t.Run("pin_success", func(t *testing.T) {
+		var gotPinned bool
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getMemberFn: announcementMemberFn(model.GroupRoleOwner),
+			setAnnouncementPinnedFn: func(_ context.Context, _ string, pinned bool) error {
+				gotPinned = pinned
+				return nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.PinGroupAnnouncement(withGroupUserUUID("u1"), &pb.PinGroupAnnouncementRequest{GroupUuid: "g1", AnnouncementUuid: "a1", Pinned: true})
+		require.NoError(t, err)
+		assert.True(t, gotPinned)
+	})
+
+	t.Run("delete_success", func(t *testing.T) {
+		deleted := ""
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getMemberFn: announcementMemberFn(model.GroupRoleOwner),
+			deleteAnnouncementFn: func(_ context.Context, announcementUUID string) error {
+				deleted = announcementUUID
+				return nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.DeleteGroupAnnouncement(withGroupUserUUID("u1"), &pb.DeleteGroupAnnouncementRequest{GroupUuid: "g1", AnnouncementUuid: "a1"})
+		require.NoError(t, err)
+		assert.Equal(t, "a1", deleted)
+	})
+}
+
+func TestUserGroupServiceListAndAckGroupAnnouncements(t *testing.T) {
+	initUserGroupTestLogger()
+
+	t.Run("list_marks_acked", func(t *testing.T) {
+		now := time.Now()
+		svc := NewGroupService(&fakeGroupRepoForService{
+			listAnnouncementsFn: func(context.Context, string, int) ([]*model.GroupAnnouncement, error) {
+				return []*model.GroupAnnouncement{
+					{Uuid: "a1", GroupUuid: "g1", Content: "pinned", Pinned: true, RequireAck: true, CreatedAt: now},
+					{Uuid: "a2", GroupUuid: "g1", Content: "plain", CreatedAt: now},
+				}, nil
+			},
+			listAckedAnnouncementsFn: func(_ context.Context, userUUID string, uuids []string) (map[string]bool, error) {
+				assert.Equal(t, "u2", userUUID)
+				assert.Equal(t, []string{"a1"}, uuids)
+				return map[string]bool{"a1": true}, nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+
+		resp, err := svc.ListGroupAnnouncements(withGroupUserUUID("u2"), &pb.ListGroupAnnouncementsRequest{GroupUuid: "g1"})
+		require.NoError(t, err)
+		require.Len(t, resp.Announcements, 2)
+		assert.True(t, resp.Announcements[0].Acked)
+		assert.False(t, resp.Announcements[1].Acked)
+	})
+
+	t.Run("ack_not_required_is_noop", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			ackAnnouncementFn: func(context.Context, string, string) error {
+				t.Fatal("should not record ack for announcement without require_ack")
+				return nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.AckGroupAnnouncement(withGroupUserUUID("u2"), &pb.AckGroupAnnouncementRequest{GroupUuid: "g1", AnnouncementUuid: "a1"})
+		require.NoError(t, err)
+	})
+
+	t.Run("ack_success", func(t *testing.T) {
+		var gotUser string
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getAnnouncementFn: func(_ context.Context, groupUUID, announcementUUID string) (*model.GroupAnnouncement, error) {
+				return &model.GroupAnnouncement{Uuid: announcementUUID, GroupUuid: groupUUID, RequireAck: true}, nil
+			},
+			ackAnnouncementFn: func(_ context.Context, _ string, userUUID string) error {
+				gotUser = userUUID
+				return nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.AckGroupAnnouncement(withGroupUserUUID("u2"), &pb.AckGroupAnnouncementRequest{GroupUuid: "g1", AnnouncementUuid: "a1"})
+		require.NoError(t, err)
+		assert.Equal(t, "u2", gotUser)
+	})
+}
+
+func TestUserGroupServiceGetGroupAnnouncementAcks(t *testing.T) {
+	initUserGroupTestLogger()
+
+	t.Run("admin_no_permission", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getMemberFn: announcementMemberFn(model.GroupRoleAdmin),
+		}, &fakeUserSvcRepo{}, nil, nil)
+		_, err := svc.GetGroupAnnouncementAcks(withGroupUserUUID("u1"), &pb.GetGroupAnnouncementAcksRequest{GroupUuid: "g1", AnnouncementUuid: "a1"})
+		requireGroupStatusCode(t, err, codes.PermissionDenied, consts.CodeNoPermission)
+	})
+
+	t.Run("owner_sees_acked_and_unacked", func(t *testing.T) {
+		ackedAt := time.UnixMilli(1700000000000)
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getMemberFn: announcementMemberFn(model.GroupRoleOwner),
+			listAnnouncementAcksFn: func(context.Context, string) ([]*model.GroupAnnouncementAck, error) {
+				return []*model.GroupAnnouncementAck{
+					{AnnouncementUuid: "a1", UserUuid: "u2", AckedAt: ackedAt},
+					{AnnouncementUuid: "a1", UserUuid: "left", AckedAt: ackedAt},
+				}, nil
+			},
+			listMemberUUIDsFn: func(context.Context, string) ([]string, error) {
+				return []string{"u1", "u2", "u3"}, nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+
+		resp, err := svc.GetGroupAnnouncementAcks(withGroupUserUUID("u1"), &pb.GetGroupAnnouncementAcksRequest{GroupUuid: "g1", AnnouncementUuid: "a1"})
+		require.NoError(t, err)
+		require.Len(t, resp.Acked, 1)
+		assert.Equal(t, "u2", resp.Acked[0].UserUuid)
+		assert.Equal(t, int64(1700000000000), resp.Acked[0].AckedAt)
+		assert.Equal(t, []string{"u1", "u3"}, resp.UnackedUserUuids)
+	})
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"ChatServer/apps/user/internal/repository"
+	"ChatServer/pkg/async"
+	"ChatServer/pkg/imaging"
+	"ChatServer/pkg/logger"
+	pkgminio "ChatServer/pkg/minio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // 注册 GIF 解码器
+	_ "image/jpeg" // 注册 JPEG 解码器
+	"image/png"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultGroupAvatarDebounce 群头像生成防抖窗口：窗口内的多次成员变动只生成一次
+	defaultGroupAvatarDebounce = 3 * time.Second
+	// groupAvatarGenerateTimeout 单次生成（下载 + 拼图 + 上传）超时
+	groupAvatarGenerateTimeout = 30 * time.Second
+	// groupAvatarPathPrefix 群头像在对象存储中的路径前缀
+	groupAvatarPathPrefix = "group_avatars"
+	// groupAvatarTileMaxBytes 单个成员头像最多读取的字节数，超出视为异常文件
+	groupAvatarTileMaxBytes = 4 << 20
+	// groupAvatarTileMaxPixels 单个成员头像解码前允许的最大像素数，防止小文件解压出超大位图
+	groupAvatarTileMaxPixels = 4096 * 4096
+)
+
+// GroupAvatarStorage 群头像对象存储
+type GroupAvatarStorage interface {
+	// Fetch 下载头像图片；地址不属于本存储时返回 (nil, nil)，由调用方使用占位图。
+	Fetch(ctx context.Context, url string) (io.ReadCloser, error)
+	// Upload 上传 PNG 图片，返回访问 URL
+	Upload(ctx context.Context, pathPrefix, fileName string, data []byte) (string, error)
+}
+
+// GroupAvatarRefresher 群头像刷新触发器
+// 群创建或前 9 名成员可能发生变化时调用，实现方负责异步与防抖。
+type GroupAvatarRefresher interface {
+	Trigger(ctx context.Context, groupUUID string)
+}
+
+// minioGroupAvatarStorage 基于 MinIO 的群头像存储
+type minioGroupAvatarStorage struct {
+	client *pkgminio.MinIOClient
+}
+
+// NewMinIOGroupAvatarStorage 创建基于 MinIO 的群头像存储，client 为 nil 时返回 nil
+func NewMinIOGroupAvatarStorage(client *pkgminio.MinIOClient) GroupAvatarStorage {
+	if client == nil {
+		return nil
+	}
+	return &minioGroupAvatarStorage{client: client}
+}
+
+// Fetch 从 MinIO 下载头像
+func (s *minioGroupAvatarStorage) Fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	objectName, ok := s.client.ObjectNameFromURL(url)
+	if !ok {
+		return nil, nil
+	}
+	reader, _, err := s.client.Download(ctx, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+// Upload 上传群头像到 MinIO
+func (s *minioGroupAvatarStorage) Upload(ctx context.Context, pathPrefix, fileName string, data []byte) (string, error) {
+	result, err := s.client.Upload(ctx, bytes.NewReader(data), int64(len(data)), pkgminio.UploadOptions{
+		PathPrefix:  pathPrefix,
+		FileName:    fileName,
+		ContentType: "image/png",
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.URL, nil
+}
+
+// GroupAvatarGenerator 九宫格群头像生成器
+// 按群维度防抖：窗口期内重复触发只会重置计时器，到期后在协程池中异步生成。
+// 群主设置过自定义头像（GroupInfo.AvatarCustom）的群不会被覆盖。
+type GroupAvatarGenerator struct {
+	groupRepo repository.IGroupRepository
+	userRepo  repository.IUserRepository
+	storage   GroupAvatarStorage
+	debounce  time.Duration
+
+	// run 异步执行生成任务，默认提交到 async 协程池
+	run func(ctx context.Context, task func(ctx context.Context))
+
+	mu      sync.Mutex
+	timers  map[string]*time.Timer
+	stopped bool
+}
+
+// NewGroupAvatarGenerator 创建群头像生成器
+// storage 为 nil 时生成器处于禁用状态，Trigger 为空操作。
+func NewGroupAvatarGenerator(
+	groupRepo repository.IGroupRepository,
+	userRepo repository.IUserRepository,
+	storage GroupAvatarStorage,
+	debounce time.Duration,
+) *GroupAvatarGenerator {
+	if debounce <= 0 {
+		debounce = defaultGroupAvatarDebounce
+	}
+	return &GroupAvatarGenerator{
+		groupRepo: groupRepo,
+		userRepo:  userRepo,
+		storage:   storage,
+		debounce:  debounce,
+		run: func(ctx context.Context, task func(ctx context.Context)) {
+			async.RunSafe(ctx, task, groupAvatarGenerateTimeout)
+		},
+		timers: make(map[string]*time.Timer),
+	}
+}
+
+// Trigger 触发群头像重新生成（异步、防抖）
+func (g *GroupAvatarGenerator) Trigger(ctx context.Context, groupUUID string) {
+	if g == nil || g.storage == nil || groupUUID == "" {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stopped {
+		return
+	}
+
+	if timer, ok := g.timers[groupUUID]; ok {
+		timer.Reset(g.debounce)
+		return
+	}
+	g.timers[groupUUID] = time.AfterFunc(g.debounce, func() {
+		g.mu.Lock()
+		delete(g.timers, groupUUID)
+		stopped := g.stopped
+		g.mu.Unlock()
+		if stopped {
+			return
+		}
+
+		g.run(ctx, func(runCtx context.Context) {
+			if err := g.Generate(runCtx, groupUUID); err != nil {
+				logger.Warn(runCtx, "生成群头像失败",
+					logger.String("group_uuid", groupUUID),
+					logger.ErrorField("error", err),
+				)
+			}
+		})
+	})
+}
+
+// Stop 停止生成器，丢弃尚未到期的任务
+func (g *GroupAvatarGenerator) Stop() {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.stopped = true
+	for groupUUID, timer := range g.timers {
+		timer.Stop()
+		delete(g.timers, groupUUID)
+	}
+}
+
+// Generate 同步生成并保存群头像
+// 业务流程：
+//  1. 查询群组，自定义头像直接跳过
+//  2. 按入群顺序取前 9 名成员及其头像
+//  3. 根据头像列表计算签名作为文件名，与当前头像一致时跳过（前 9 名未变化）
+//  4. 下载头像并拼成九宫格，上传后条件写回 group_info.avatar
+func (g *GroupAvatarGenerator) Generate(ctx context.Context, groupUUID string) error {
+	// 1. 查询群组
+	group, err := g.groupRepo.GetGroup(ctx, groupUUID)
+	if err != nil {
+		return err
+	}
+	if group.AvatarCustom {
+		return nil
+	}
+
+	// 2. 前 9 名成员头像
+	members, err := g.groupRepo.ListAvatarMembers(ctx, groupUUID, imaging.MaxGridTiles)
+	if err != nil {
+		return err
+	}
+	if len(members) == 0 {
+		return nil
+	}
+
+	memberUUIDs := make([]string, 0, len(members))
+	for _, member := range members {
+		memberUUIDs = append(memberUUIDs, member.UserUuid)
+	}
+	users, err := g.userRepo.BatchGetByUUIDs(ctx, memberUUIDs)
+	if err != nil {
+		return err
+	}
+	avatarMap := make(map[string]string, len(users))
+	for _, user := range users {
+		if user != nil {
+			avatarMap[user.Uuid] = user.Avatar
+		}
+	}
+	avatarURLs := make([]string, 0, len(memberUUIDs))
+	for _, userUUID := range memberUUIDs {
+		avatarURLs = append(avatarURLs, avatarMap[userUUID])
+	}
+
+	// 3. 前 9 名头像未变化时跳过
+	pathPrefix := fmt.Sprintf("%s/%s", groupAvatarPathPrefix, groupUUID)
+	fileName := groupAvatarSignature(avatarURLs) + ".png"
+	if strings.HasSuffix(group.Avatar, "/"+pathPrefix+"/"+fileName) {
+		return nil
+	}
+
+	// 4. 下载、拼图、上传
+	tiles := make([]image.Image, 0, len(avatarURLs))
+	for _, url := range avatarURLs {
+		tiles = append(tiles, g.fetchTile(ctx, url))
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, imaging.ComposeGrid(tiles, imaging.DefaultGridOptions())); err != nil {
+		return err
+	}
+
+	avatarURL, err := g.storage.Upload(ctx, pathPrefix, fileName, buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	if err := g.groupRepo.UpdateGeneratedAvatar(ctx, groupUUID, avatarURL); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			// 生成期间群主设置了自定义头像，放弃写回
+			return nil
+		}
+		return err
+	}
+
+	logger.Info(ctx, "群头像生成成功",
+		logger.String("group_uuid", groupUUID),
+		logger.Int("members", len(avatarURLs)),
+		logger.String("avatar", avatarURL),
+	)
+	return nil
+}
+
+// fetchTile 下载并解码单个成员头像，失败时返回 nil（使用占位色）。
+// 最多读取 groupAvatarTileMaxBytes，并先用 DecodeConfig 校验尺寸再完整解码。
+func (g *GroupAvatarGenerator) fetchTile(ctx context.Context, url string) image.Image {
+	if url == "" {
+		return nil
+	}
+	reader, err := g.storage.Fetch(ctx, url)
+	if err != nil {
+		logger.Warn(ctx, "下载成员头像失败",
+			logger.String("url", url),
+			logger.ErrorField("error", err),
+		)
+		return nil
+	}
+	if reader == nil {
+		return nil
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, groupAvatarTileMaxBytes+1))
+	if err != nil {
+		logger.Warn(ctx, "下载成员头像失败",
+			logger.String("url", url),
+			logger.ErrorField("error", err),
+		)
+		return nil
+	}
+	if len(data) > groupAvatarTileMaxBytes {
+		logger.Warn(ctx, "成员头像文件过大",
+			logger.String("url", url),
+			logger.Int("limit", groupAvatarTileMaxBytes),
+		)
+		return nil
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		logger.Warn(ctx, "解码成员头像失败",
+			logger.String("url", url),
+			logger.ErrorField("error", err),
+		)
+		return nil
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > groupAvatarTileMaxPixels {
+		logger.Warn(ctx, "成员头像尺寸超限",
+			logger.String("url", url),
+			logger.Int("width", cfg.Width),
+			logger.Int("height", cfg.Height),
+		)
+		return nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		logger.Warn(ctx, "解码成员头像失败",
+			logger.String("url", url),
+			logger.ErrorField("error", err),
+		)
+		return nil
+	}
+	return img
+}
+
+// groupAvatarSignature 根据有序头像列表计算签名
+func groupAvatarSignature(avatarURLs []string) string {
+	sum := sha1.Sum([]byte(strings.Join(avatarURLs, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,377 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ChatServer/apps/user/internal/repository"
+	pb "ChatServer/apps/user/pb"
+	"ChatServer/consts"
+	"ChatServer/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+type fakeGroupAvatarStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	fetchFn func(context.Context, string) (io.ReadCloser, error)
+	uploads []string
+}
+
+func (f *fakeGroupAvatarStorage) Fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	if f.fetchFn != nil {
+		return f.fetchFn(ctx, url)
+	}
+	data, ok := f.objects[url]
+	if !ok {
+		return nil, nil
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (f *fakeGroupAvatarStorage) Upload(_ context.Context, pathPrefix, fileName string, data []byte) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	url := "http://minio/bucket/" + pathPrefix + "/" + fileName
+	f.uploads = append(f.uploads, url)
+	if f.objects == nil {
+		f.objects = make(map[string][]byte)
+	}
+	f.objects[url] = data
+	return url, nil
+}
+
+type fakeGroupAvatarRefresher struct {
+	triggered []string
+}
+
+func (f *fakeGroupAvatarRefresher) Trigger(_ context.Context, groupUUID string) {
+	f.triggered = append(f.triggered, groupUUID)
+}
+
+func solidPNG(t *testing.T, c color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func avatarMembersFn(userUUIDs ...string) func(context.Context, string, int) ([]*model.GroupMember, error) {
+	return func(_ context.Context, groupUUID string, limit int) ([]*model.GroupMember, error) {
+		members := make([]*model.GroupMember, 0, len(userUUIDs))
+		for _, userUUID := range userUUIDs {
+			if len(members) >= limit {
+				break
+			}
+			members = append(members, &model.GroupMember{GroupUuid: groupUUID, UserUuid: userUUID})
+		}
+		return members, nil
+	}
+}
+
+func avatarUsersRepo(avatars map[string]string) *fakeUserSvcRepo {
+	return &fakeUserSvcRepo{
+		batchGetByUUIDsFn: func(_ context.Context, uuids []string) ([]*model.UserInfo, error) {
+			users := make([]*model.UserInfo, 0, len(uuids))
+			for _, uuid := range uuids {
+				users = append(users, &model.UserInfo{Uuid: uuid, Avatar: avatars[uuid]})
+			}
+			return users, nil
+		},
+	}
+}
+
+func TestUserGroupAvatarGenerate(t *testing.T) {
+	initUserGroupTestLogger()
+
+	t.Run("compose_upload_and_update", func(t *testing.T) {
+		storage := &fakeGroupAvatarStorage{objects: map[string][]byte{
+			"http://minio/bucket/avatars/u1.png": solidPNG(t, color.RGBA{R: 255, A: 255}),
+			"http://minio/bucket/avatars/u2.png": solidPNG(t, color.RGBA{G: 255, A: 255}),
+		}}
+		var updated string
+		repo := &fakeGroupRepoForService{
+			listAvatarMembersFn: avatarMembersFn("u1", "u2", "u3"),
+			updateGeneratedAvatarFn: func(_ context.Context, groupUUID, avatar string) error {
+				assert.Equal(t, "g1", groupUUID)
+				updated = avatar
+				return nil
+			},
+		}
+		users := avatarUsersRepo(map[string]string{
+			"u1": "http://minio/bucket/avatars/u1.png",
+			"u2": "http://minio/bucket/avatars/u2.png",
+			"u3": "https://external.example.com/u3.png",
+		})
+
+		gen := NewGroupAvatarGenerator(repo, users, storage, time.Millisecond)
+		require.NoError(t, gen.Generate(context.Background(), "g1"))
+
+		require.Len(t, storage.uploads, 1)
+		assert.Equal(t, storage.uploads[0], updated)
+		assert.True(t, strings.HasPrefix(updated, "http://minio/bucket/group_avatars/g1/"))
+		assert.True(t, strings.HasSuffix(updated, ".png"))
+
+		img, err := png.Decode(bytes.NewReader(storage.objects[updated]))
+		require.NoError(t, err)
+		assert.Equal(t, 240, img.Bounds().Dx())
+		assert.Equal(t, 240, img.Bounds().Dy())
+	})
+
+	t.Run("custom_avatar_skipped", func(t *testing.T) {
+		storage := &fakeGroupAvatarStorage{}
+		repo := &fakeGroupRepoForService{
+			getGroupFn: func(_ context.Context, groupUUID string) (*model.GroupInfo, error) {
+				return &model.GroupInfo{Uuid: groupUUID, Avatar: "https://cdn/custom.png", AvatarCustom: true}, nil
+			},
+			listAvatarMembersFn: func(context.Context, string, int) ([]*model.GroupMember, error) {
+				t.Fatal("should not list members for custom avatar")
+				return nil, nil
+			},
+		}
+
+		gen := NewGroupAvatarGenerator(repo, &fakeUserSvcRepo{}, storage, time.Millisecond)
+		require.NoError(t, gen.Generate(context.Background(), "g1"))
+		assert.Empty(t, storage.uploads)
+	})
+
+	t.Run("unchanged_members_skipped", func(t *testing.T) {
+		storage := &fakeGroupAvatarStorage{}
+		current := ""
+		repo := &fakeGroupRepoForService{
+			getGroupFn: func(_ context.Context, groupUUID string) (*model.GroupInfo, error) {
+				return &model.GroupInfo{Uuid: groupUUID, Avatar: current}, nil
+			},
+			listAvatarMembersFn: avatarMembersFn("u1", "u2"),
+			updateGeneratedAvatarFn: func(_ context.Context, _ string, avatar string) error {
+				current = avatar
+				return nil
+			},
+		}
+		users := avatarUsersRepo(map[string]string{"u1": "a1", "u2": "a2"})
+
+		gen := NewGroupAvatarGenerator(repo, users, storage, time.Millisecond)
+		require.NoError(t, gen.Generate(context.Background(), "g1"))
+		require.NoError(t, gen.Generate(context.Background(), "g1"))
+		assert.Len(t, storage.uploads, 1)
+	})
+
+	t.Run("fetch_error_uses_placeholder", func(t *testing.T) {
+		storage := &fakeGroupAvatarStorage{
+			fetchFn: func(context.Context, string) (io.ReadCloser, error) {
+				return nil, errors.New("minio down")
+			},
+		}
+		repo := &fakeGroupRepoForService{listAvatarMembersFn: avatarMembersFn("u1")}
+		users := avatarUsersRepo(map[string]string{"u1": "http://minio/bucket/avatars/u1.png"})
+
+		gen := NewGroupAvatarGenerator(repo, users, storage, time.Millisecond)
+		require.NoError(t, gen.Generate(context.Background(), "g1"))
+		assert.Len(t, storage.uploads, 1)
+	})
+
+	t.Run("custom_set_during_generation_ignored", func(t *testing.T) {
+		repo := &fakeGroupRepoForService{
+			listAvatarMembersFn: avatarMembersFn("u1"),
+			updateGeneratedAvatarFn: func(context.Context, string, string) error {
+				return repository.ErrRecordNotFound
+			},
+		}
+
+		gen := NewGroupAvatarGenerator(repo, avatarUsersRepo(nil), &fakeGroupAvatarStorage{}, time.Millisecond)
+		require.NoError(t, gen.Generate(context.Background(), "g1"))
+	})
+
+	t.Run("group_not_found", func(t *testing.T) {
+		repo := &fakeGroupRepoForService{
+			getGroupFn: func(context.Context, string) (*model.GroupInfo, error) {
+				return nil, repository.ErrRecordNotFound
+			},
+		}
+
+		gen := NewGroupAvatarGenerator(repo, &fakeUserSvcRepo{}, &fakeGroupAvatarStorage{}, time.Millisecond)
+		require.ErrorIs(t, gen.Generate(context.Background(), "g1"), repository.ErrRecordNotFound)
+	})
+}
+
+// countingReader 无限输出零字节并记录已读取的字节数
+type countingReader struct {
+	read int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	clear(p)
+	r.read += len(p)
+	return len(p), nil
+}
+
+func TestUserGroupAvatarFetchTileLimits(t *testing.T) {
+	initUserGroupTestLogger()
+
+	var huge bytes.Buffer
+	require.NoError(t, png.Encode(&huge, image.NewGray(image.Rect(0, 0, 4097, 4097))))
+	endless := &countingReader{}
+	storage := &fakeGroupAvatarStorage{
+		objects: map[string][]byte{
+			"ok":   solidPNG(t, color.RGBA{B: 255, A: 255}),
+			"huge": huge.Bytes(),
+			"junk": []byte("not an image"),
+		},
+	}
+	gen := NewGroupAvatarGenerator(&fakeGroupRepoForService{}, &fakeUserSvcRepo{}, storage, time.Millisecond)
+	ctx := context.Background()
+
+	tile := gen.fetchTile(ctx, "ok")
+	require.NotNil(t, tile)
+	assert.Equal(t, 16, tile.Bounds().Dx())
+
+	// 压缩后很小但像素数超限的图片在完整解码前被拒绝。
+	assert.Less(t, huge.Len(), groupAvatarTileMaxBytes)
+	assert.Nil(t, gen.fetchTile(ctx, "huge"))
+	assert.Nil(t, gen.fetchTile(ctx, "junk"))
+
+	// 超大文件最多读取上限 + 1 字节。
+	storage.fetchFn = func(context.Context, string) (io.ReadCloser, error) {
+		return io.NopCloser(endless), nil
+	}
+	assert.Nil(t, gen.fetchTile(ctx, "endless"))
+	assert.Equal(t, groupAvatarTileMaxBytes+1, endless.read)
+}
+
+func TestUserGroupAvatarTriggerDebounce(t *testing.T) {
+	initUserGroupTestLogger()
+
+	var mu sync.Mutex
+	calls := 0
+	repo := &fakeGroupRepoForService{
+		getGroupFn: func(_ context.Context, groupUUID string) (*model.GroupInfo, error) {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+			return &model.GroupInfo{Uuid: groupUUID, AvatarCustom: true}, nil
+		},
+	}
+
+	done := make(chan struct{}, 4)
+	gen := NewGroupAvatarGenerator(repo, &fakeUserSvcRepo{}, &fakeGroupAvatarStorage{}, 20*time.Millisecond)
+	gen.run = func(ctx context.Context, task func(ctx context.Context)) {
+		task(ctx)
+		done <- struct{}{}
+	}
+	defer gen.Stop()
+
+	for i := 0; i < 5; i++ {
+		gen.Trigger(context.Background(), "g1")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("debounced generation not executed")
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, calls)
+}
+
+func TestUserGroupAvatarTriggerDisabled(t *testing.T) {
+	var gen *GroupAvatarGenerator
+	assert.NotPanics(t, func() { gen.Trigger(context.Background(), "g1") })
+
+	gen = NewGroupAvatarGenerator(&fakeGroupRepoForService{}, &fakeUserSvcRepo{}, nil, 0)
+	gen.Trigger(context.Background(), "g1")
+	assert.Empty(t, gen.timers)
+}
+
+func TestUserGroupServiceSetGroupAvatar(t *testing.T) {
+	initUserGroupTestLogger()
+
+	ownerRepo := func(update func(context.Context, string, string, bool) error) *fakeGroupRepoForService {
+		return &fakeGroupRepoForService{
+			getGroupFn: func(_ context.Context, groupUUID string) (*model.GroupInfo, error) {
+				return &model.GroupInfo{Uuid: groupUUID, Avatar: "http://minio/bucket/group_avatars/g1/old.png", AvatarCustom: true}, nil
+			},
+			getMemberFn: func(_ context.Context, groupUUID, userUUID string) (*model.GroupMember, error) {
+				return &model.GroupMember{GroupUuid: groupUUID, UserUuid: userUUID, Role: model.GroupRoleOwner}, nil
+			},
+			updateCustomAvatarFn: update,
+		}
+	}
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.SetGroupAvatar(context.Background(), &pb.SetGroupAvatarRequest{GroupUuid: "g1"})
+		requireGroupStatusCode(t, err, codes.Unauthenticated, consts.CodeUnauthorized)
+	})
+
+	t.Run("not_owner", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getMemberFn: func(_ context.Context, groupUUID, userUUID string) (*model.GroupMember, error) {
+				return &model.GroupMember{GroupUuid: groupUUID, UserUuid: userUUID, Role: model.GroupRoleAdmin}, nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.SetGroupAvatar(withGroupUserUUID("u1"), &pb.SetGroupAvatarRequest{GroupUuid: "g1", Avatar: "https://cdn/a.png"})
+		requireGroupStatusCode(t, err, codes.PermissionDenied, consts.CodeNoPermission)
+	})
+
+	t.Run("set_custom", func(t *testing.T) {
+		refresher := &fakeGroupAvatarRefresher{}
+		var gotAvatar string
+		var gotCustom bool
+		svc := NewGroupService(ownerRepo(func(_ context.Context, _ string, avatar string, custom bool) error {
+			gotAvatar, gotCustom = avatar, custom
+			return nil
+		}), &fakeUserSvcRepo{}, refresher, nil)
+
+		err := svc.SetGroupAvatar(withGroupUserUUID("u1"), &pb.SetGroupAvatarRequest{GroupUuid: "g1", Avatar: "https://cdn/a.png"})
+		require.NoError(t, err)
+		assert.Equal(t, "https://cdn/a.png", gotAvatar)
+		assert.True(t, gotCustom)
+		assert.Empty(t, refresher.triggered)
+	})
+
+	t.Run("reset_to_generated", func(t *testing.T) {
+		refresher := &fakeGroupAvatarRefresher{}
+		var gotAvatar string
+		gotCustom := true
+		svc := NewGroupService(ownerRepo(func(_ context.Context, _ string, avatar string, custom bool) error {
+			gotAvatar, gotCustom = avatar, custom
+			return nil
+		}), &fakeUserSvcRepo{}, refresher, nil)
+
+		err := svc.SetGroupAvatar(withGroupUserUUID("u1"), &pb.SetGroupAvatarRequest{GroupUuid: "g1"})
+		require.NoError(t, err)
+		assert.Equal(t, "http://minio/bucket/group_avatars/g1/old.png", gotAvatar)
+		assert.False(t, gotCustom)
+		assert.Equal(t, []string{"g1"}, refresher.triggered)
+	})
+
+	t.Run("update_error", func(t *testing.T) {
+		svc := NewGroupService(ownerRepo(func(context.Context, string, string, bool) error {
+			return errors.New("db down")
+		}), &fakeUserSvcRepo{}, nil, nil)
+
+		err := svc.SetGroupAvatar(withGroupUserUUID("u1"), &pb.SetGroupAvatarRequest{GroupUuid: "g1", Avatar: "https://cdn/a.png"})
+		requireGroupStatusCode(t, err, codes.Internal, consts.CodeInternalError)
+	})
+}
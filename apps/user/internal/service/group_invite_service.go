@@ -0,0 +1,495 @@
+package service
+
+import (
+	"ChatServer/apps/user/internal/repository"
+	pb "ChatServer/apps/user/pb"
+	"ChatServer/consts"
+	"ChatServer/consts/redisKey"
+	"ChatServer/model"
+	"ChatServer/pkg/logger"
+	"ChatServer/pkg/util"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// groupInviteURLFormat 群邀请链接格式，同时作为二维码内容
+const groupInviteURLFormat = "https://www.LCchat.top/g/%s"
+
+// CreateGroupInvite 创建群邀请（二维码 / 分享链接）
+// 业务流程：
+//  1. 从context中获取当前用户UUID（链接创建人）
+//  2. 校验群组状态与成员身份
+//  3. 生成随机 token，按有效期写入 Redis
+//  4. 返回分享链接与过期时间
+//
+// 邀请 token 不可预测（随机 UUID），避免被枚举；入群时创建人记为 GroupMember.Inviter。
+func (s *groupServiceImpl) CreateGroupInvite(ctx context.Context, req *pb.CreateGroupInviteRequest) (*pb.CreateGroupInviteResponse, error) {
+	// 1. 从context中获取当前用户UUID
+	currentUserUUID := util.GetUserUUIDFromContext(ctx)
+	if currentUserUUID == "" {
+		logger.Error(ctx, "获取用户UUID失败")
+		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	// 2. 参数校验
+	if req == nil || req.GroupUuid == "" || req.ExpireSeconds < 0 || req.MaxUses < 0 {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+	ttl := rediskey.GroupInviteDefaultTTL
+	if req.ExpireSeconds > 0 {
+		ttl = time.Duration(req.ExpireSeconds) * time.Second
+	}
+	if ttl > rediskey.GroupInviteMaxTTL {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	// 3. 校验群组状态与成员身份
+	if _, err := s.getActiveMember(ctx, req.GroupUuid, currentUserUUID); err != nil {
+		return nil, err
+	}
+
+	// 4. 生成邀请并写入 Redis
+	invite := &repository.GroupInvite{
+		Token:           strings.ReplaceAll(util.NewUUID(), "-", ""),
+		GroupUUID:       req.GroupUuid,
+		CreatorUUID:     currentUserUUID,
+		MaxUses:         int(req.MaxUses),
+		RequireApproval: req.RequireApproval,
+		ExpireAt:        time.Now().Add(ttl),
+	}
+	if err := s.groupRepo.SaveGroupInvite(ctx, invite); err != nil {
+		logger.Error(ctx, "保存群邀请失败",
+			logger.String("group_uuid", req.GroupUuid),
+			logger.String("user_uuid", currentUserUUID),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	inviteURL := fmt.Sprintf(groupInviteURLFormat, invite.Token)
+
+	logger.Info(ctx, "创建群邀请成功",
+		logger.String("group_uuid", req.GroupUuid),
+		logger.String("user_uuid", currentUserUUID),
+		logger.Int("max_uses", invite.MaxUses),
+		logger.Duration("ttl", ttl),
+	)
+
+	return &pb.CreateGroupInviteResponse{
+		Token:     invite.Token,
+		InviteUrl: inviteURL,
+		ExpireAt:  invite.ExpireAt.Format(time.RFC3339),
+	}, nil
+}
+
+// RevokeGroupInvite 撤销群邀请
+// 仅链接创建人、群主或管理员可撤销。
+func (s *groupServiceImpl) RevokeGroupInvite(ctx context.Context, req *pb.RevokeGroupInviteRequest) error {
+	// 1. 从context中获取当前用户UUID
+	currentUserUUID := util.GetUserUUIDFromContext(ctx)
+	if currentUserUUID == "" {
+		logger.Error(ctx, "获取用户UUID失败")
+		return status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	// 2. 参数校验
+	if req == nil || req.Token == "" {
+		return status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	// 3. 查询邀请
+	invite, err := s.getGroupInvite(ctx, req.Token)
+	if err != nil {
+		return err
+	}
+
+	// 4. 权限校验：非创建人需为群主或管理员
+	if invite.CreatorUUID != currentUserUUID {
+		member, err := s.getActiveMember(ctx, invite.GroupUUID, currentUserUUID)
+		if err != nil {
+			return err
+		}
+		if !isGroupManager(member.Role) {
+			return status.Error(codes.PermissionDenied, strconv.Itoa(consts.CodeNoPermission))
+		}
+	}
+
+	// 5. 删除邀请
+	if err := s.groupRepo.DeleteGroupInvite(ctx, req.Token); err != nil {
+		logger.Error(ctx, "撤销群邀请失败",
+			logger.String("group_uuid", invite.GroupUUID),
+			logger.String("user_uuid", currentUserUUID),
+			logger.ErrorField("error", err),
+		)
+		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	logger.Info(ctx, "撤销群邀请成功",
+		logger.String("group_uuid", invite.GroupUUID),
+		logger.String("user_uuid", currentUserUUID),
+	)
+
+	return nil
+}
+
+// PreviewGroupInvite 预览群邀请对应的群信息
+// 扫码/打开链接后、入群前调用，返回群名称、头像、人数及是否需要审核。
+func (s *groupServiceImpl) PreviewGroupInvite(ctx context.Context, req *pb.PreviewGroupInviteRequest) (*pb.PreviewGroupInviteResponse, error) {
+	// 1. 从context中获取当前用户UUID
+	currentUserUUID := util.GetUserUUIDFromContext(ctx)
+	if currentUserUUID == "" {
+		logger.Error(ctx, "获取用户UUID失败")
+		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	// 2. 参数校验
+	if req == nil || req.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	// 3. 查询邀请与群组
+	invite, err := s.getGroupInvite(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+	if invite.MaxUses > 0 && invite.UsedCount >= invite.MaxUses {
+		return nil, status.Error(codes.ResourceExhausted, strconv.Itoa(consts.CodeGroupInviteExhausted))
+	}
+	group, err := s.getActiveGroup(ctx, invite.GroupUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	needApproval, err := s.inviteNeedApproval(ctx, invite, group)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. 当前用户是否已在群内
+	isMember := true
+	if _, err := s.groupRepo.GetMember(ctx, group.Uuid, currentUserUUID); err != nil {
+		if !errors.Is(err, repository.ErrRecordNotFound) {
+			logger.Error(ctx, "查询群成员失败",
+				logger.String("group_uuid", group.Uuid),
+				logger.String("user_uuid", currentUserUUID),
+				logger.ErrorField("error", err),
+			)
+			return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		}
+		isMember = false
+	}
+
+	return &pb.PreviewGroupInviteResponse{
+		GroupUuid:    group.Uuid,
+		Name:         group.Name,
+		Avatar:       group.Avatar,
+		MemberCount:  int32(group.MemberCnt),
+		InviterUuid:  invite.CreatorUUID,
+		NeedApproval: needApproval,
+		IsMember:     isMember,
+		ExpireAt:     invite.ExpireAt.Format(time.RFC3339),
+	}, nil
+}
+
+// JoinGroupByInvite 通过群邀请入群
+// 业务流程：
+//  1. 校验邀请有效、群组未解散、当前用户不在群内
+//  2. 判断是否需要审核（邀请要求审核，或群设置为需审核且创建人不是群主/管理员）
+//  3. 已有待审核申请或群已满员时直接拒绝，不占用次数
+//  4. 原子占用一次使用次数
+//  5. 写入成员记录（直接入群或待审核），Inviter 记为链接创建人；
+//     群人数上限在写入事务内再次校验，失败时归还使用次数
+//
+// 错误码映射：
+//   - codes.NotFound: 邀请已失效、群组不存在
+//   - codes.ResourceExhausted: 邀请使用次数已达上限
+//   - codes.FailedPrecondition: 群组已解散、群成员已满
+//   - codes.AlreadyExists: 已经是群成员、入群申请待审核
+//   - codes.Internal: 系统内部错误
+func (s *groupServiceImpl) JoinGroupByInvite(ctx context.Context, req *pb.JoinGroupByInviteRequest) (*pb.JoinGroupByInviteResponse, error) {
+	// 1. 从context中获取当前用户UUID
+	currentUserUUID := util.GetUserUUIDFromContext(ctx)
+	if currentUserUUID == "" {
+		logger.Error(ctx, "获取用户UUID失败")
+		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	// 2. 参数校验
+	if req == nil || req.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	// 3. 查询邀请与群组
+	invite, err := s.getGroupInvite(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+	group, err := s.getActiveGroup(ctx, invite.GroupUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. 已在群内直接拒绝，避免白白占用次数
+	if _, err := s.groupRepo.GetMember(ctx, group.Uuid, currentUserUUID); err == nil {
+		return nil, status.Error(codes.AlreadyExists, strconv.Itoa(consts.CodeAlreadyGroupMember))
+	} else if !errors.Is(err, repository.ErrRecordNotFound) {
+		logger.Error(ctx, "查询群成员失败",
+			logger.String("group_uuid", group.Uuid),
+			logger.String("user_uuid", currentUserUUID),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	needApproval, err := s.inviteNeedApproval(ctx, invite, group)
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. 重复申请与满员提前拒绝（满员以写入事务内的校验为准）
+	if needApproval {
+		if _, err := s.groupRepo.GetPendingMember(ctx, group.Uuid, currentUserUUID); err == nil {
+			return nil, status.Error(codes.AlreadyExists, strconv.Itoa(consts.CodeGroupApplyPending))
+		} else if !errors.Is(err, repository.ErrRecordNotFound) {
+			logger.Error(ctx, "查询入群申请失败",
+				logger.String("group_uuid", group.Uuid),
+				logger.String("user_uuid", currentUserUUID),
+				logger.ErrorField("error", err),
+			)
+			return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+		}
+	} else if group.MemberCnt >= model.GroupMaxMembers {
+		return nil, status.Error(codes.FailedPrecondition, strconv.Itoa(consts.CodeGroupFull))
+	}
+
+	// 6. 占用一次使用次数
+	if err := s.groupRepo.ConsumeGroupInvite(ctx, req.Token); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRedisNil):
+			return nil, status.Error(codes.NotFound, strconv.Itoa(consts.CodeGroupInviteInvalid))
+		case errors.Is(err, repository.ErrGroupInviteExhausted):
+			return nil, status.Error(codes.ResourceExhausted, strconv.Itoa(consts.CodeGroupInviteExhausted))
+		}
+		logger.Error(ctx, "占用群邀请次数失败",
+			logger.String("group_uuid", group.Uuid),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	// 7. 写入成员记录
+	memberStatus := model.GroupMemberStatusNormal
+	if needApproval {
+		memberStatus = model.GroupMemberStatusPending
+	}
+	member := &model.GroupMember{
+		GroupUuid: group.Uuid,
+		UserUuid:  currentUserUUID,
+		Role:      model.GroupRoleMember,
+		Status:    memberStatus,
+		Inviter:   invite.CreatorUUID,
+	}
+	if err := s.groupRepo.AddMember(ctx, member); err != nil {
+		if releaseErr := s.groupRepo.ReleaseGroupInvite(ctx, req.Token); releaseErr != nil {
+			logger.Warn(ctx, "归还群邀请次数失败",
+				logger.String("group_uuid", group.Uuid),
+				logger.ErrorField("error", releaseErr),
+			)
+		}
+		switch {
+		case errors.Is(err, repository.ErrDuplicateKey):
+			return nil, status.Error(codes.AlreadyExists, strconv.Itoa(consts.CodeAlreadyGroupMember))
+		case errors.Is(err, repository.ErrGroupApplyPending):
+			return nil, status.Error(codes.AlreadyExists, strconv.Itoa(consts.CodeGroupApplyPending))
+		case errors.Is(err, repository.ErrGroupFull):
+			return nil, status.Error(codes.FailedPrecondition, strconv.Itoa(consts.CodeGroupFull))
+		}
+		logger.Error(ctx, "通过邀请入群失败",
+			logger.String("group_uuid", group.Uuid),
+			logger.String("user_uuid", currentUserUUID),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	logger.Info(ctx, "通过邀请入群成功",
+		logger.String("group_uuid", group.Uuid),
+		logger.String("user_uuid", currentUserUUID),
+		logger.String("inviter_uuid", invite.CreatorUUID),
+		logger.Bool("pending", needApproval),
+	)
+
+	// 8. 直接入群时前 9 名成员可能变化，刷新群头像
+	if !needApproval {
+		s.refreshGroupAvatar(ctx, group.Uuid)
+	}
+
+	return &pb.JoinGroupByInviteResponse{
+		GroupUuid: group.Uuid,
+		Pending:   needApproval,
+	}, nil
+}
+
+// ListGroupJoinRequests 获取待审核的入群申请
+// 仅群主或管理员可查看。
+func (s *groupServiceImpl) ListGroupJoinRequests(ctx context.Context, req *pb.ListGroupJoinRequestsRequest) (*pb.ListGroupJoinRequestsResponse, error) {
+	// 1. 从context中获取当前用户UUID
+	currentUserUUID := util.GetUserUUIDFromContext(ctx)
+	if currentUserUUID == "" {
+		logger.Error(ctx, "获取用户UUID失败")
+		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	// 2. 参数校验
+	if req == nil || req.GroupUuid == "" {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	// 3. 权限校验
+	if err := s.requireGroupManager(ctx, req.GroupUuid, currentUserUUID); err != nil {
+		return nil, err
+	}
+
+	// 4. 查询待审核成员
+	members, err := s.groupRepo.ListPendingMembers(ctx, req.GroupUuid)
+	if err != nil {
+		logger.Error(ctx, "查询入群申请失败",
+			logger.String("group_uuid", req.GroupUuid),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	items := make([]*pb.GroupJoinRequestItem, 0, len(members))
+	for _, member := range members {
+		if member == nil {
+			continue
+		}
+		items = append(items, &pb.GroupJoinRequestItem{
+			UserUuid:    member.UserUuid,
+			InviterUuid: member.Inviter,
+			AppliedAt:   util.TimeToUnixMilli(member.UpdatedAt),
+		})
+	}
+
+	return &pb.ListGroupJoinRequestsResponse{Requests: items}, nil
+}
+
+// HandleGroupJoinRequest 处理入群申请
+// 仅群主或管理员可处理；申请不存在或已处理时返回 CodeGroupApplyNotFound，群成员已满时通过申请返回 CodeGroupFull。
+func (s *groupServiceImpl) HandleGroupJoinRequest(ctx context.Context, req *pb.HandleGroupJoinRequestRequest) error {
+	// 1. 从context中获取当前用户UUID
+	currentUserUUID := util.GetUserUUIDFromContext(ctx)
+	if currentUserUUID == "" {
+		logger.Error(ctx, "获取用户UUID失败")
+		return status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	// 2. 参数校验
+	if req == nil || req.GroupUuid == "" || req.UserUuid == "" {
+		return status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	// 3. 权限校验
+	if err := s.requireGroupManager(ctx, req.GroupUuid, currentUserUUID); err != nil {
+		return err
+	}
+
+	// 4. 通过 / 拒绝
+	var err error
+	if req.Approve {
+		err = s.groupRepo.ApprovePendingMember(ctx, req.GroupUuid, req.UserUuid)
+	} else {
+		err = s.groupRepo.RejectPendingMember(ctx, req.GroupUuid, req.UserUuid)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRecordNotFound):
+			return status.Error(codes.NotFound, strconv.Itoa(consts.CodeGroupApplyNotFound))
+		case errors.Is(err, repository.ErrGroupFull):
+			return status.Error(codes.FailedPrecondition, strconv.Itoa(consts.CodeGroupFull))
+		}
+		logger.Error(ctx, "处理入群申请失败",
+			logger.String("group_uuid", req.GroupUuid),
+			logger.String("applicant_uuid", req.UserUuid),
+			logger.Bool("approve", req.Approve),
+			logger.ErrorField("error", err),
+		)
+		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	logger.Info(ctx, "处理入群申请成功",
+		logger.String("group_uuid", req.GroupUuid),
+		logger.String("operator_uuid", currentUserUUID),
+		logger.String("applicant_uuid", req.UserUuid),
+		logger.Bool("approve", req.Approve),
+	)
+
+	if req.Approve {
+		s.refreshGroupAvatar(ctx, req.GroupUuid)
+	}
+
+	return nil
+}
+
+// getGroupInvite 查询群邀请，不存在或已过期时返回 CodeGroupInviteInvalid
+func (s *groupServiceImpl) getGroupInvite(ctx context.Context, token string) (*repository.GroupInvite, error) {
+	invite, err := s.groupRepo.GetGroupInvite(ctx, token)
+	if err != nil {
+		if errors.Is(err, repository.ErrRedisNil) {
+			return nil, status.Error(codes.NotFound, strconv.Itoa(consts.CodeGroupInviteInvalid))
+		}
+		logger.Error(ctx, "查询群邀请失败", logger.ErrorField("error", err))
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+	if !invite.ExpireAt.IsZero() && time.Now().After(invite.ExpireAt) {
+		return nil, status.Error(codes.NotFound, strconv.Itoa(consts.CodeGroupInviteInvalid))
+	}
+	return invite, nil
+}
+
+// inviteNeedApproval 判断通过该邀请入群是否需要审核
+// 创建人已不在群内时邀请视为失效。
+func (s *groupServiceImpl) inviteNeedApproval(ctx context.Context, invite *repository.GroupInvite, group *model.GroupInfo) (bool, error) {
+	creator, err := s.groupRepo.GetMember(ctx, invite.GroupUUID, invite.CreatorUUID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return false, status.Error(codes.NotFound, strconv.Itoa(consts.CodeGroupInviteInvalid))
+		}
+		logger.Error(ctx, "查询邀请创建人失败",
+			logger.String("group_uuid", invite.GroupUUID),
+			logger.String("creator_uuid", invite.CreatorUUID),
+			logger.ErrorField("error", err),
+		)
+		return false, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	if invite.RequireApproval {
+		return true, nil
+	}
+	return group.AddMode == model.GroupAddModeApproval && !isGroupManager(creator.Role), nil
+}
+
+// requireGroupManager 校验当前用户为群主或管理员
+func (s *groupServiceImpl) requireGroupManager(ctx context.Context, groupUUID, userUUID string) error {
+	member, err := s.getActiveMember(ctx, groupUUID, userUUID)
+	if err != nil {
+		return err
+	}
+	if !isGroupManager(member.Role) {
+		return status.Error(codes.PermissionDenied, strconv.Itoa(consts.CodeNoPermission))
+	}
+	return nil
+}
+
+// isGroupManager 是否为群主或管理员
+func isGroupManager(role int8) bool {
+	return role == model.GroupRoleOwner || role == model.GroupRoleAdmin
+}
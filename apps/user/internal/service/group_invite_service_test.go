@@ -0,0 +1,389 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"ChatServer/apps/user/internal/repository"
+	pb "ChatServer/apps/user/pb"
+	"ChatServer/consts"
+	"ChatServer/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func newTestGroupInvite() *repository.GroupInvite {
+	return &repository.GroupInvite{
+		Token:       "t1",
+		GroupUUID:   "g1",
+		CreatorUUID: "creator",
+		ExpireAt:    time.Now().Add(time.Hour),
+	}
+}
+
+func TestUserGroupServiceCreateGroupInvite(t *testing.T) {
+	initUserGroupTestLogger()
+
+	t.Run("expire_too_long", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{}, &fakeUserSvcRepo{}, nil, nil)
+		_, err := svc.CreateGroupInvite(withGroupUserUUID("u1"), &pb.CreateGroupInviteRequest{
+			GroupUuid:     "g1",
+			ExpireSeconds: int64((31 * 24 * time.Hour).Seconds()),
+		})
+		requireGroupStatusCode(t, err, codes.InvalidArgument, consts.CodeParamError)
+	})
+
+	t.Run("not_member", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getMemberFn: func(context.Context, string, string) (*model.GroupMember, error) {
+				return nil, repository.ErrRecordNotFound
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		_, err := svc.CreateGroupInvite(withGroupUserUUID("u1"), &pb.CreateGroupInviteRequest{GroupUuid: "g1"})
+		requireGroupStatusCode(t, err, codes.PermissionDenied, consts.CodeNotGroupMember)
+	})
+
+	t.Run("success_default_ttl", func(t *testing.T) {
+		var saved *repository.GroupInvite
+		svc := NewGroupService(&fakeGroupRepoForService{
+			saveGroupInviteFn: func(_ context.Context, invite *repository.GroupInvite) error {
+				saved = invite
+				return nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+
+		resp, err := svc.CreateGroupInvite(withGroupUserUUID("u1"), &pb.CreateGroupInviteRequest{
+			GroupUuid:       "g1",
+			MaxUses:         5,
+			RequireApproval: true,
+		})
+		require.NoError(t, err)
+		require.NotNil(t, saved)
+		assert.Equal(t, "g1", saved.GroupUUID)
+		assert.Equal(t, "u1", saved.CreatorUUID)
+		assert.Equal(t, 5, saved.MaxUses)
+		assert.True(t, saved.RequireApproval)
+		assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), saved.ExpireAt, time.Minute)
+		assert.Equal(t, saved.Token, resp.Token)
+		assert.True(t, strings.HasSuffix(resp.InviteUrl, "/g/"+saved.Token))
+		assert.NotContains(t, saved.Token, "-")
+	})
+}
+
+func TestUserGroupServiceRevokeGroupInvite(t *testing.T) {
+	initUserGroupTestLogger()
+
+	t.Run("invalid_token", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.RevokeGroupInvite(withGroupUserUUID("u1"), &pb.RevokeGroupInviteRequest{Token: "t1"})
+		requireGroupStatusCode(t, err, codes.NotFound, consts.CodeGroupInviteInvalid)
+	})
+
+	t.Run("normal_member_cannot_revoke_others", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getGroupInviteFn: func(context.Context, string) (*repository.GroupInvite, error) {
+				return newTestGroupInvite(), nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.RevokeGroupInvite(withGroupUserUUID("u1"), &pb.RevokeGroupInviteRequest{Token: "t1"})
+		requireGroupStatusCode(t, err, codes.PermissionDenied, consts.CodeNoPermission)
+	})
+
+	t.Run("creator_revokes", func(t *testing.T) {
+		deleted := ""
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getGroupInviteFn: func(context.Context, string) (*repository.GroupInvite, error) {
+				return newTestGroupInvite(), nil
+			},
+			deleteGroupInviteFn: func(_ context.Context, token string) error {
+				deleted = token
+				return nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.RevokeGroupInvite(withGroupUserUUID("creator"), &pb.RevokeGroupInviteRequest{Token: "t1"})
+		require.NoError(t, err)
+		assert.Equal(t, "t1", deleted)
+	})
+}
+
+func TestUserGroupServicePreviewGroupInvite(t *testing.T) {
+	initUserGroupTestLogger()
+
+	t.Run("exhausted", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getGroupInviteFn: func(context.Context, string) (*repository.GroupInvite, error) {
+				invite := newTestGroupInvite()
+				invite.MaxUses, invite.UsedCount = 2, 2
+				return invite, nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		_, err := svc.PreviewGroupInvite(withGroupUserUUID("u1"), &pb.PreviewGroupInviteRequest{Token: "t1"})
+		requireGroupStatusCode(t, err, codes.ResourceExhausted, consts.CodeGroupInviteExhausted)
+	})
+
+	t.Run("approval_group_normal_creator", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getGroupInviteFn: func(context.Context, string) (*repository.GroupInvite, error) {
+				return newTestGroupInvite(), nil
+			},
+			getGroupFn: func(context.Context, string) (*model.GroupInfo, error) {
+				return &model.GroupInfo{Uuid: "g1", Name: "群", Avatar: "a.png", MemberCnt: 3, AddMode: model.GroupAddModeApproval}, nil
+			},
+			getMemberFn: func(_ context.Context, _ string, userUUID string) (*model.GroupMember, error) {
+				if userUUID == "creator" {
+					return &model.GroupMember{UserUuid: userUUID, Role: model.GroupRoleMember}, nil
+				}
+				return nil, repository.ErrRecordNotFound
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+
+		resp, err := svc.PreviewGroupInvite(withGroupUserUUID("u1"), &pb.PreviewGroupInviteRequest{Token: "t1"})
+		require.NoError(t, err)
+		assert.Equal(t, "群", resp.Name)
+		assert.Equal(t, "a.png", resp.Avatar)
+		assert.Equal(t, int32(3), resp.MemberCount)
+		assert.Equal(t, "creator", resp.InviterUuid)
+		assert.True(t, resp.NeedApproval)
+		assert.False(t, resp.IsMember)
+	})
+
+	t.Run("approval_group_admin_creator", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getGroupInviteFn: func(context.Context, string) (*repository.GroupInvite, error) {
+				return newTestGroupInvite(), nil
+			},
+			getGroupFn: func(context.Context, string) (*model.GroupInfo, error) {
+				return &model.GroupInfo{Uuid: "g1", AddMode: model.GroupAddModeApproval}, nil
+			},
+			getMemberFn: func(_ context.Context, _ string, userUUID string) (*model.GroupMember, error) {
+				return &model.GroupMember{UserUuid: userUUID, Role: model.GroupRoleAdmin}, nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+
+		resp, err := svc.PreviewGroupInvite(withGroupUserUUID("u1"), &pb.PreviewGroupInviteRequest{Token: "t1"})
+		require.NoError(t, err)
+		assert.False(t, resp.NeedApproval)
+		assert.True(t, resp.IsMember)
+	})
+
+	t.Run("creator_left_group", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getGroupInviteFn: func(context.Context, string) (*repository.GroupInvite, error) {
+				return newTestGroupInvite(), nil
+			},
+			getMemberFn: func(context.Context, string, string) (*model.GroupMember, error) {
+				return nil, repository.ErrRecordNotFound
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		_, err := svc.PreviewGroupInvite(withGroupUserUUID("u1"), &pb.PreviewGroupInviteRequest{Token: "t1"})
+		requireGroupStatusCode(t, err, codes.NotFound, consts.CodeGroupInviteInvalid)
+	})
+}
+
+func TestUserGroupServiceJoinGroupByInvite(t *testing.T) {
+	initUserGroupTestLogger()
+
+	creatorOnly := func(_ context.Context, _ string, userUUID string) (*model.GroupMember, error) {
+		if userUUID == "creator" {
+			return &model.GroupMember{UserUuid: userUUID, Role: model.GroupRoleMember}, nil
+		}
+		return nil, repository.ErrRecordNotFound
+	}
+
+	t.Run("already_member", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getGroupInviteFn: func(context.Context, string) (*repository.GroupInvite, error) {
+				return newTestGroupInvite(), nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		_, err := svc.JoinGroupByInvite(withGroupUserUUID("u1"), &pb.JoinGroupByInviteRequest{Token: "t1"})
+		requireGroupStatusCode(t, err, codes.AlreadyExists, consts.CodeAlreadyGroupMember)
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getGroupInviteFn: func(context.Context, string) (*repository.GroupInvite, error) {
+				return newTestGroupInvite(), nil
+			},
+			getMemberFn: creatorOnly,
+			consumeGroupInviteFn: func(context.Context, string) error {
+				return repository.ErrGroupInviteExhausted
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		_, err := svc.JoinGroupByInvite(withGroupUserUUID("u1"), &pb.JoinGroupByInviteRequest{Token: "t1"})
+		requireGroupStatusCode(t, err, codes.ResourceExhausted, consts.CodeGroupInviteExhausted)
+	})
+
+	t.Run("direct_join_records_inviter", func(t *testing.T) {
+		var added *model.GroupMember
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getGroupInviteFn: func(context.Context, string) (*repository.GroupInvite, error) {
+				return newTestGroupInvite(), nil
+			},
+			getMemberFn: creatorOnly,
+			addMemberFn: func(_ context.Context, member *model.GroupMember) error {
+				added = member
+				return nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		resp, err := svc.JoinGroupByInvite(withGroupUserUUID("u1"), &pb.JoinGroupByInviteRequest{Token: "t1"})
+		require.NoError(t, err)
+		assert.False(t, resp.Pending)
+		require.NotNil(t, added)
+		assert.Equal(t, "creator", added.Inviter)
+		assert.Equal(t, model.GroupMemberStatusNormal, added.Status)
+	})
+
+	t.Run("approval_required_goes_pending", func(t *testing.T) {
+		var added *model.GroupMember
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getGroupInviteFn: func(context.Context, string) (*repository.GroupInvite, error) {
+				invite := newTestGroupInvite()
+				invite.RequireApproval = true
+				return invite, nil
+			},
+			getMemberFn: creatorOnly,
+			addMemberFn: func(_ context.Context, member *model.GroupMember) error {
+				added = member
+				return nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		resp, err := svc.JoinGroupByInvite(withGroupUserUUID("u1"), &pb.JoinGroupByInviteRequest{Token: "t1"})
+		require.NoError(t, err)
+		assert.True(t, resp.Pending)
+		require.NotNil(t, added)
+		assert.Equal(t, model.GroupMemberStatusPending, added.Status)
+	})
+
+	t.Run("pending_request_does_not_consume", func(t *testing.T) {
+		consumed := false
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getGroupInviteFn: func(context.Context, string) (*repository.GroupInvite, error) {
+				invite := newTestGroupInvite()
+				invite.RequireApproval = true
+				return invite, nil
+			},
+			getMemberFn: creatorOnly,
+			getPendingMemberFn: func(_ context.Context, groupUUID, userUUID string) (*model.GroupMember, error) {
+				return &model.GroupMember{GroupUuid: groupUUID, UserUuid: userUUID, Status: model.GroupMemberStatusPending}, nil
+			},
+			consumeGroupInviteFn: func(context.Context, string) error {
+				consumed = true
+				return nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		_, err := svc.JoinGroupByInvite(withGroupUserUUID("u1"), &pb.JoinGroupByInviteRequest{Token: "t1"})
+		requireGroupStatusCode(t, err, codes.AlreadyExists, consts.CodeGroupApplyPending)
+		assert.False(t, consumed)
+	})
+
+	t.Run("full_group_does_not_consume", func(t *testing.T) {
+		consumed := false
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getGroupFn: func(_ context.Context, groupUUID string) (*model.GroupInfo, error) {
+				return &model.GroupInfo{Uuid: groupUUID, OwnerUuid: "creator", MemberCnt: model.GroupMaxMembers}, nil
+			},
+			getGroupInviteFn: func(context.Context, string) (*repository.GroupInvite, error) {
+				return newTestGroupInvite(), nil
+			},
+			getMemberFn: creatorOnly,
+			consumeGroupInviteFn: func(context.Context, string) error {
+				consumed = true
+				return nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		_, err := svc.JoinGroupByInvite(withGroupUserUUID("u1"), &pb.JoinGroupByInviteRequest{Token: "t1"})
+		requireGroupStatusCode(t, err, codes.FailedPrecondition, consts.CodeGroupFull)
+		assert.False(t, consumed)
+	})
+
+	t.Run("full_in_transaction_releases_use", func(t *testing.T) {
+		released := false
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getGroupInviteFn: func(context.Context, string) (*repository.GroupInvite, error) {
+				return newTestGroupInvite(), nil
+			},
+			getMemberFn: creatorOnly,
+			addMemberFn: func(context.Context, *model.GroupMember) error {
+				return repository.ErrGroupFull
+			},
+			releaseGroupInviteFn: func(context.Context, string) error {
+				released = true
+				return nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		_, err := svc.JoinGroupByInvite(withGroupUserUUID("u1"), &pb.JoinGroupByInviteRequest{Token: "t1"})
+		requireGroupStatusCode(t, err, codes.FailedPrecondition, consts.CodeGroupFull)
+		assert.True(t, released)
+	})
+
+	t.Run("add_failed_releases_use", func(t *testing.T) {
+		released := false
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getGroupInviteFn: func(context.Context, string) (*repository.GroupInvite, error) {
+				return newTestGroupInvite(), nil
+			},
+			getMemberFn: creatorOnly,
+			addMemberFn: func(context.Context, *model.GroupMember) error {
+				return errors.New("db down")
+			},
+			releaseGroupInviteFn: func(context.Context, string) error {
+				released = true
+				return nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		_, err := svc.JoinGroupByInvite(withGroupUserUUID("u1"), &pb.JoinGroupByInviteRequest{Token: "t1"})
+		requireGroupStatusCode(t, err, codes.Internal, consts.CodeInternalError)
+		assert.True(t, released)
+	})
+}
+
+func TestUserGroupServiceHandleGroupJoinRequest(t *testing.T) {
+	initUserGroupTestLogger()
+
+	t.Run("normal_member_denied", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.HandleGroupJoinRequest(withGroupUserUUID("u1"), &pb.HandleGroupJoinRequestRequest{
+			GroupUuid: "g1", UserUuid: "u2", Approve: true,
+		})
+		requireGroupStatusCode(t, err, codes.PermissionDenied, consts.CodeNoPermission)
+	})
+
+	t.Run("request_not_found", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getMemberFn: func(_ context.Context, _ string, userUUID string) (*model.GroupMember, error) {
+				return &model.GroupMember{UserUuid: userUUID, Role: model.GroupRoleOwner}, nil
+			},
+			rejectPendingFn: func(context.Context, string, string) error {
+				return repository.ErrRecordNotFound
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.HandleGroupJoinRequest(withGroupUserUUID("u1"), &pb.HandleGroupJoinRequestRequest{
+			GroupUuid: "g1", UserUuid: "u2", Approve: false,
+		})
+		requireGroupStatusCode(t, err, codes.NotFound, consts.CodeGroupApplyNotFound)
+	})
+
+	t.Run("approve_success", func(t *testing.T) {
+		approved := ""
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getMemberFn: func(_ context.Context, _ string, userUUID string) (*model.GroupMember, error) {
+				return &model.GroupMember{UserUuid: userUUID, Role: model.GroupRoleAdmin}, nil
+			},
+			approvePendingFn: func(_ context.Context, _ string, userUUID string) error {
+				approved = userUUID
+				return nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.HandleGroupJoinRequest(withGroupUserUUID("u1"), &pb.HandleGroupJoinRequestRequest{
+			GroupUuid: "g1", UserUuid: "u2", Approve: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "u2", approved)
+	})
+}
@@ -0,0 +1,383 @@
+package service
+
+import (
+	"ChatServer/apps/user/internal/repository"
+	pb "ChatServer/apps/user/pb"
+	"ChatServer/consts"
+	"ChatServer/model"
+	"ChatServer/pkg/logger"
+	"ChatServer/pkg/util"
+	"context"
+	"errors"
+	"strconv"
+	"unicode/utf8"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// groupCardMaxLen 群名片最大字符数，与 group_member.remark 列宽一致
+const groupCardMaxLen = 64
+
+// groupServiceImpl 群组服务实现
+type groupServiceImpl struct {
+	groupRepo       repository.IGroupRepository
+	userRepo        repository.IUserRepository
+	avatarRefresher GroupAvatarRefresher
+	pusher          ConnectPusher
+}
+
+// NewGroupService 创建群组服务实例
+// avatarRefresher 可为 nil，此时成员变动不会触发群头像重新生成；
+// pusher 可为 nil，此时群公告等通知不会实时下发。
+func NewGroupService(
+	groupRepo repository.IGroupRepository,
+	userRepo repository.IUserRepository,
+	avatarRefresher GroupAvatarRefresher,
+	pusher ConnectPusher,
+) GroupService {
+	return &groupServiceImpl{
+		groupRepo:       groupRepo,
+		userRepo:        userRepo,
+		avatarRefresher: avatarRefresher,
+		pusher:          pusher,
+	}
+}
+
+// SetGroupCard 设置群名片
+// 业务流程：
+//  1. 从context中获取当前用户UUID
+//  2. 校验群名片长度
+//  3. 校验群组状态与成员身份
+//  4. 更新 group_member.remark
+//
+// 错误码映射：
+//   - codes.InvalidArgument: 参数错误、群名片过长
+//   - codes.NotFound: 群组不存在
+//   - codes.FailedPrecondition: 群组已解散
+//   - codes.PermissionDenied: 不是群成员
+//   - codes.Internal: 系统内部错误
+func (s *groupServiceImpl) SetGroupCard(ctx context.Context, req *pb.SetGroupCardRequest) error {
+	// 1. 从context中获取当前用户UUID
+	currentUserUUID := util.GetUserUUIDFromContext(ctx)
+	if currentUserUUID == "" {
+		logger.Error(ctx, "获取用户UUID失败")
+		return status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	// 2. 参数校验
+	if req == nil || req.GroupUuid == "" {
+		return status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+	if utf8.RuneCountInString(req.Card) > groupCardMaxLen {
+		return status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeGroupCardTooLong))
+	}
+
+	// 3. 校验群组状态与成员身份
+	if _, err := s.getActiveMember(ctx, req.GroupUuid, currentUserUUID); err != nil {
+		return err
+	}
+
+	// 4. 更新群名片
+	if err := s.groupRepo.UpdateMemberCard(ctx, req.GroupUuid, currentUserUUID, req.Card); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return status.Error(codes.PermissionDenied, strconv.Itoa(consts.CodeNotGroupMember))
+		}
+		logger.Error(ctx, "设置群名片失败",
+			logger.String("group_uuid", req.GroupUuid),
+			logger.String("user_uuid", currentUserUUID),
+			logger.ErrorField("error", err),
+		)
+		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	logger.Info(ctx, "设置群名片成功",
+		logger.String("group_uuid", req.GroupUuid),
+		logger.String("user_uuid", currentUserUUID),
+	)
+
+	return nil
+}
+
+// UpdateGroupSettings 更新本人的群设置
+// 仅更新请求中显式携带的字段；未携带任何字段时直接返回成功。
+func (s *groupServiceImpl) UpdateGroupSettings(ctx context.Context, req *pb.UpdateGroupSettingsRequest) error {
+	// 1. 从context中获取当前用户UUID
+	currentUserUUID := util.GetUserUUIDFromContext(ctx)
+	if currentUserUUID == "" {
+		logger.Error(ctx, "获取用户UUID失败")
+		return status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	// 2. 参数校验
+	if req == nil || req.GroupUuid == "" {
+		return status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	update := repository.GroupMemberSettingsUpdate{
+		SaveToContacts:     req.SaveToContacts,
+		HideMemberNickname: req.HideMemberNickname,
+		MuteNotify:         req.MuteNotify,
+	}
+
+	// 3. 校验群组状态与成员身份
+	if _, err := s.getActiveMember(ctx, req.GroupUuid, currentUserUUID); err != nil {
+		return err
+	}
+	if update.SaveToContacts == nil && update.HideMemberNickname == nil && update.MuteNotify == nil {
+		return nil
+	}
+
+	// 4. 更新群设置
+	if err := s.groupRepo.UpdateMemberSettings(ctx, req.GroupUuid, currentUserUUID, update); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return status.Error(codes.PermissionDenied, strconv.Itoa(consts.CodeNotGroupMember))
+		}
+		logger.Error(ctx, "更新群设置失败",
+			logger.String("group_uuid", req.GroupUuid),
+			logger.String("user_uuid", currentUserUUID),
+			logger.ErrorField("error", err),
+		)
+		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	logger.Info(ctx, "更新群设置成功",
+		logger.String("group_uuid", req.GroupUuid),
+		logger.String("user_uuid", currentUserUUID),
+	)
+
+	return nil
+}
+
+// GetGroupSettings 获取本人的群设置
+func (s *groupServiceImpl) GetGroupSettings(ctx context.Context, req *pb.GetGroupSettingsRequest) (*pb.GetGroupSettingsResponse, error) {
+	// 1. 从context中获取当前用户UUID
+	currentUserUUID := util.GetUserUUIDFromContext(ctx)
+	if currentUserUUID == "" {
+		logger.Error(ctx, "获取用户UUID失败")
+		return nil, status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	// 2. 参数校验
+	if req == nil || req.GroupUuid == "" {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	// 3. 查询成员记录
+	member, err := s.getActiveMember(ctx, req.GroupUuid, currentUserUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetGroupSettingsResponse{
+		Settings: &pb.GroupSettings{
+			GroupUuid:          member.GroupUuid,
+			GroupCard:          member.Remark,
+			SaveToContacts:     member.SaveToContacts,
+			HideMemberNickname: member.HideMemberNickname,
+			MuteNotify:         member.MuteNotify,
+		},
+	}, nil
+}
+
+// BatchGetMemberDisplayName 批量获取群成员展示名（内部调用）
+// 展示名优先取群名片，为空时回退为全局昵称；非群成员不包含在结果中。
+// 结果按请求中 user_uuids 的顺序返回。
+func (s *groupServiceImpl) BatchGetMemberDisplayName(ctx context.Context, req *pb.BatchGetMemberDisplayNameRequest) (*pb.BatchGetMemberDisplayNameResponse, error) {
+	if req == nil || req.GroupUuid == "" {
+		return nil, status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+	if len(req.UserUuids) == 0 {
+		return &pb.BatchGetMemberDisplayNameResponse{Members: []*pb.MemberDisplayName{}}, nil
+	}
+
+	// 1. 查询群成员（拿到群名片）
+	members, err := s.groupRepo.BatchGetMembers(ctx, req.GroupUuid, req.UserUuids)
+	if err != nil {
+		logger.Error(ctx, "批量查询群成员失败",
+			logger.String("group_uuid", req.GroupUuid),
+			logger.Int("count", len(req.UserUuids)),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+	if len(members) == 0 {
+		return &pb.BatchGetMemberDisplayNameResponse{Members: []*pb.MemberDisplayName{}}, nil
+	}
+
+	memberMap := make(map[string]*model.GroupMember, len(members))
+	memberUUIDs := make([]string, 0, len(members))
+	for _, member := range members {
+		if member == nil {
+			continue
+		}
+		memberMap[member.UserUuid] = member
+		memberUUIDs = append(memberUUIDs, member.UserUuid)
+	}
+
+	// 2. 查询全局昵称（群名片为空时回退）
+	users, err := s.userRepo.BatchGetByUUIDs(ctx, memberUUIDs)
+	if err != nil {
+		logger.Error(ctx, "批量查询用户信息失败",
+			logger.String("group_uuid", req.GroupUuid),
+			logger.Int("count", len(memberUUIDs)),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+	nicknameMap := make(map[string]string, len(users))
+	for _, user := range users {
+		if user == nil {
+			continue
+		}
+		nicknameMap[user.Uuid] = user.Nickname
+	}
+
+	// 3. 按请求顺序组装结果
+	items := make([]*pb.MemberDisplayName, 0, len(memberMap))
+	seen := make(map[string]struct{}, len(memberMap))
+	for _, userUUID := range req.UserUuids {
+		member, ok := memberMap[userUUID]
+		if !ok {
+			continue
+		}
+		if _, dup := seen[userUUID]; dup {
+			continue
+		}
+		seen[userUUID] = struct{}{}
+
+		nickname := nicknameMap[userUUID]
+		items = append(items, &pb.MemberDisplayName{
+			UserUuid:    userUUID,
+			DisplayName: memberDisplayName(member.Remark, nickname),
+			GroupCard:   member.Remark,
+			Nickname:    nickname,
+		})
+	}
+
+	return &pb.BatchGetMemberDisplayNameResponse{Members: items}, nil
+}
+
+// SetGroupAvatar 设置群头像
+// 业务流程：
+//  1. 从context中获取当前用户UUID
+//  2. 校验当前用户为群主
+//  3. avatar 非空：写入自定义头像，之后成员变动不再覆盖
+//  4. avatar 为空：清除自定义标记并触发九宫格头像重新生成
+//
+// 错误码映射：
+//   - codes.InvalidArgument: 参数错误
+//   - codes.NotFound: 群组不存在
+//   - codes.FailedPrecondition: 群组已解散
+//   - codes.PermissionDenied: 不是群成员、不是群主
+//   - codes.Internal: 系统内部错误
+func (s *groupServiceImpl) SetGroupAvatar(ctx context.Context, req *pb.SetGroupAvatarRequest) error {
+	// 1. 从context中获取当前用户UUID
+	currentUserUUID := util.GetUserUUIDFromContext(ctx)
+	if currentUserUUID == "" {
+		logger.Error(ctx, "获取用户UUID失败")
+		return status.Error(codes.Unauthenticated, strconv.Itoa(consts.CodeUnauthorized))
+	}
+
+	// 2. 参数校验
+	if req == nil || req.GroupUuid == "" {
+		return status.Error(codes.InvalidArgument, strconv.Itoa(consts.CodeParamError))
+	}
+
+	// 3. 权限校验：仅群主
+	member, err := s.getActiveMember(ctx, req.GroupUuid, currentUserUUID)
+	if err != nil {
+		return err
+	}
+	if member.Role != model.GroupRoleOwner {
+		return status.Error(codes.PermissionDenied, strconv.Itoa(consts.CodeNoPermission))
+	}
+
+	// 4. 更新头像；恢复自动生成时保留当前头像，待生成完成后替换
+	avatar := req.Avatar
+	custom := avatar != ""
+	if !custom {
+		group, err := s.getActiveGroup(ctx, req.GroupUuid)
+		if err != nil {
+			return err
+		}
+		avatar = group.Avatar
+	}
+	if err := s.groupRepo.UpdateCustomAvatar(ctx, req.GroupUuid, avatar, custom); err != nil {
+		logger.Error(ctx, "设置群头像失败",
+			logger.String("group_uuid", req.GroupUuid),
+			logger.Bool("custom", custom),
+			logger.ErrorField("error", err),
+		)
+		return status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+
+	logger.Info(ctx, "设置群头像成功",
+		logger.String("group_uuid", req.GroupUuid),
+		logger.String("operator_uuid", currentUserUUID),
+		logger.Bool("custom", custom),
+	)
+
+	if !custom {
+		s.refreshGroupAvatar(ctx, req.GroupUuid)
+	}
+	return nil
+}
+
+// refreshGroupAvatar 触发群头像重新生成（未配置生成器时为空操作）
+func (s *groupServiceImpl) refreshGroupAvatar(ctx context.Context, groupUUID string) {
+	if s.avatarRefresher == nil {
+		return
+	}
+	s.avatarRefresher.Trigger(ctx, groupUUID)
+}
+
+// getActiveGroup 查询群组并校验未解散
+// 返回的错误已转换为 gRPC status。
+func (s *groupServiceImpl) getActiveGroup(ctx context.Context, groupUUID string) (*model.GroupInfo, error) {
+	group, err := s.groupRepo.GetGroup(ctx, groupUUID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, strconv.Itoa(consts.CodeGroupNotFound))
+		}
+		logger.Error(ctx, "查询群组失败",
+			logger.String("group_uuid", groupUUID),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+	if group.Status == model.GroupStatusDismissed {
+		return nil, status.Error(codes.FailedPrecondition, strconv.Itoa(consts.CodeGroupAlreadyDismiss))
+	}
+	return group, nil
+}
+
+// getActiveMember 校验群组可用且当前用户为正常状态的成员
+// 返回的错误已转换为 gRPC status。
+func (s *groupServiceImpl) getActiveMember(ctx context.Context, groupUUID, userUUID string) (*model.GroupMember, error) {
+	if _, err := s.getActiveGroup(ctx, groupUUID); err != nil {
+		return nil, err
+	}
+
+	member, err := s.groupRepo.GetMember(ctx, groupUUID, userUUID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, status.Error(codes.PermissionDenied, strconv.Itoa(consts.CodeNotGroupMember))
+		}
+		logger.Error(ctx, "查询群成员失败",
+			logger.String("group_uuid", groupUUID),
+			logger.String("user_uuid", userUUID),
+			logger.ErrorField("error", err),
+		)
+		return nil, status.Error(codes.Internal, strconv.Itoa(consts.CodeInternalError))
+	}
+	return member, nil
+}
+
+// memberDisplayName 计算群内展示名：群名片优先，否则回退为全局昵称
+func memberDisplayName(groupCard, nickname string) string {
+	if groupCard != "" {
+		return groupCard
+	}
+	return nickname
+}
@@ -0,0 +1,469 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"ChatServer/apps/user/internal/repository"
+	pb "ChatServer/apps/user/pb"
+	"ChatServer/consts"
+	"ChatServer/model"
+	"ChatServer/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+var userGroupLoggerOnce sync.Once
+
+func initUserGroupTestLogger() {
+	userGroupLoggerOnce.Do(func() {
+		logger.ReplaceGlobal(zap.NewNop())
+	})
+}
+
+type fakeGroupRepoForService struct {
+	getGroupFn               func(context.Context, string) (*model.GroupInfo, error)
+	getMemberFn              func(context.Context, string, string) (*model.GroupMember, error)
+	getPendingMemberFn       func(context.Context, string, string) (*model.GroupMember, error)
+	batchGetMembersFn        func(context.Context, string, []string) ([]*model.GroupMember, error)
+	updateMemberCardFn       func(context.Context, string, string, string) error
+	updateMemberSettingsFn   func(context.Context, string, string, repository.GroupMemberSettingsUpdate) error
+	addMemberFn              func(context.Context, *model.GroupMember) error
+	listPendingMembersFn     func(context.Context, string) ([]*model.GroupMember, error)
+	approvePendingFn         func(context.Context, string, string) error
+	rejectPendingFn          func(context.Context, string, string) error
+	saveGroupInviteFn        func(context.Context, *repository.GroupInvite) error
+	getGroupInviteFn         func(context.Context, string) (*repository.GroupInvite, error)
+	consumeGroupInviteFn     func(context.Context, string) error
+	releaseGroupInviteFn     func(context.Context, string) error
+	deleteGroupInviteFn      func(context.Context, string) error
+	listAvatarMembersFn      func(context.Context, string, int) ([]*model.GroupMember, error)
+	updateGeneratedAvatarFn  func(context.Context, string, string) error
+	updateCustomAvatarFn     func(context.Context, string, string, bool) error
+	listMemberUUIDsFn        func(context.Context, string) ([]string, error)
+	createAnnouncementFn     func(context.Context, *model.GroupAnnouncement) error
+	getAnnouncementFn        func(context.Context, string, string) (*model.GroupAnnouncement, error)
+	listAnnouncementsFn      func(context.Context, string, int) ([]*model.GroupAnnouncement, error)
+	updateAnnouncementFn     func(context.Context, string, string, string, bool) error
+	setAnnouncementPinnedFn  func(context.Context, string, bool) error
+	deleteAnnouncementFn     func(context.Context, string) error
+	ackAnnouncementFn        func(context.Context, string, string) error
+	listAnnouncementAcksFn   func(context.Context, string) ([]*model.GroupAnnouncementAck, error)
+	listAckedAnnouncementsFn func(context.Context, string, []string) (map[string]bool, error)
+}
+
+func (f *fakeGroupRepoForService) GetGroup(ctx context.Context, groupUUID string) (*model.GroupInfo, error) {
+	if f.getGroupFn == nil {
+		return &model.GroupInfo{Uuid: groupUUID}, nil
+	}
+	return f.getGroupFn(ctx, groupUUID)
+}
+
+func (f *fakeGroupRepoForService) GetMember(ctx context.Context, groupUUID, userUUID string) (*model.GroupMember, error) {
+	if f.getMemberFn == nil {
+		return &model.GroupMember{GroupUuid: groupUUID, UserUuid: userUUID}, nil
+	}
+	return f.getMemberFn(ctx, groupUUID, userUUID)
+}
+
+func (f *fakeGroupRepoForService) GetPendingMember(ctx context.Context, groupUUID, userUUID string) (*model.GroupMember, error) {
+	if f.getPendingMemberFn == nil {
+		return nil, repository.ErrRecordNotFound
+	}
+	return f.getPendingMemberFn(ctx, groupUUID, userUUID)
+}
+
+func (f *fakeGroupRepoForService) BatchGetMembers(ctx context.Context, groupUUID string, userUUIDs []string) ([]*model.GroupMember, error) {
+	if f.batchGetMembersFn == nil {
+		return nil, nil
+	}
+	return f.batchGetMembersFn(ctx, groupUUID, userUUIDs)
+}
+
+func (f *fakeGroupRepoForService) UpdateMemberCard(ctx context.Context, groupUUID, userUUID, card string) error {
+	if f.updateMemberCardFn == nil {
+		return nil
+	}
+	return f.updateMemberCardFn(ctx, groupUUID, userUUID, card)
+}
+
+func (f *fakeGroupRepoForService) UpdateMemberSettings(ctx context.Context, groupUUID, userUUID string, update repository.GroupMemberSettingsUpdate) error {
+	if f.updateMemberSettingsFn == nil {
+		return nil
+	}
+	return f.updateMemberSettingsFn(ctx, groupUUID, userUUID, update)
+}
+
+func (f *fakeGroupRepoForService) AddMember(ctx context.Context, member *model.GroupMember) error {
+	if f.addMemberFn == nil {
+		return nil
+	}
+	return f.addMemberFn(ctx, member)
+}
+
+func (f *fakeGroupRepoForService) ListPendingMembers(ctx context.Context, groupUUID string) ([]*model.GroupMember, error) {
+	if f.listPendingMembersFn == nil {
+		return nil, nil
+	}
+	return f.listPendingMembersFn(ctx, groupUUID)
+}
+
+func (f *fakeGroupRepoForService) ApprovePendingMember(ctx context.Context, groupUUID, userUUID string) error {
+	if f.approvePendingFn == nil {
+		return nil
+	}
+	return f.approvePendingFn(ctx, groupUUID, userUUID)
+}
+
+func (f *fakeGroupRepoForService) RejectPendingMember(ctx context.Context, groupUUID, userUUID string) error {
+	if f.rejectPendingFn == nil {
+		return nil
+	}
+	return f.rejectPendingFn(ctx, groupUUID, userUUID)
+}
+
+func (f *fakeGroupRepoForService) SaveGroupInvite(ctx context.Context, invite *repository.GroupInvite) error {
+	if f.saveGroupInviteFn == nil {
+		return nil
+	}
+	return f.saveGroupInviteFn(ctx, invite)
+}
+
+func (f *fakeGroupRepoForService) GetGroupInvite(ctx context.Context, token string) (*repository.GroupInvite, error) {
+	if f.getGroupInviteFn == nil {
+		return nil, repository.ErrRedisNil
+	}
+	return f.getGroupInviteFn(ctx, token)
+}
+
+func (f *fakeGroupRepoForService) ConsumeGroupInvite(ctx context.Context, token string) error {
+	if f.consumeGroupInviteFn == nil {
+		return nil
+	}
+	return f.consumeGroupInviteFn(ctx, token)
+}
+
+func (f *fakeGroupRepoForService) ReleaseGroupInvite(ctx context.Context, token string) error {
+	if f.releaseGroupInviteFn == nil {
+		return nil
+	}
+	return f.releaseGroupInviteFn(ctx, token)
+}
+
+func (f *fakeGroupRepoForService) DeleteGroupInvite(ctx context.Context, token string) error {
+	if f.deleteGroupInviteFn == nil {
+		return nil
+	}
+	return f.deleteGroupInviteFn(ctx, token)
+}
+
+func (f *fakeGroupRepoForService) ListAvatarMembers(ctx context.Context, groupUUID string, limit int) ([]*model.GroupMember, error) {
+	if f.listAvatarMembersFn == nil {
+		return nil, nil
+	}
+	return f.listAvatarMembersFn(ctx, groupUUID, limit)
+}
+
+func (f *fakeGroupRepoForService) UpdateGeneratedAvatar(ctx context.Context, groupUUID, avatar string) error {
+	if f.updateGeneratedAvatarFn == nil {
+		return nil
+	}
+	return f.updateGeneratedAvatarFn(ctx, groupUUID, avatar)
+}
+
+func (f *fakeGroupRepoForService) UpdateCustomAvatar(ctx context.Context, groupUUID, avatar string, custom bool) error {
+	if f.updateCustomAvatarFn == nil {
+		return nil
+	}
+	return f.updateCustomAvatarFn(ctx, groupUUID, avatar, custom)
+}
+
+func (f *fakeGroupRepoForService) ListMemberUUIDs(ctx context.Context, groupUUID string) ([]string, error) {
+	if f.listMemberUUIDsFn == nil {
+		return nil, nil
+	}
+	return f.listMemberUUIDsFn(ctx, groupUUID)
+}
+
+func (f *fakeGroupRepoForService) CreateAnnouncement(ctx context.Context, announcement *model.GroupAnnouncement) error {
+	if f.createAnnouncementFn == nil {
+		return nil
+	}
+	return f.createAnnouncementFn(ctx, announcement)
+}
+
+func (f *fakeGroupRepoForService) GetAnnouncement(ctx context.Context, groupUUID, announcementUUID string) (*model.GroupAnnouncement, error) {
+	if f.getAnnouncementFn == nil {
+		return &model.GroupAnnouncement{Uuid: announcementUUID, GroupUuid: groupUUID}, nil
+	}
+	return f.getAnnouncementFn(ctx, groupUUID, announcementUUID)
+}
+
+func (f *fakeGroupRepoForService) ListAnnouncements(ctx context.Context, groupUUID string, limit int) ([]*model.GroupAnnouncement, error) {
+	if f.listAnnouncementsFn == nil {
+		return nil, nil
+	}
+	return f.listAnnouncementsFn(ctx, groupUUID, limit)
+}
+
+func (f *fakeGroupRepoForService) UpdateAnnouncement(ctx context.Context, announcementUUID, editorUUID, content string, requireAck bool) error {
+	if f.updateAnnouncementFn == nil {
+		return nil
+	}
+	return f.updateAnnouncementFn(ctx, announcementUUID, editorUUID, content, requireAck)
+}
+
+func (f *fakeGroupRepoForService) SetAnnouncementPinned(ctx context.Context, announcementUUID string, pinned bool) error {
+	if f.setAnnouncementPinnedFn == nil {
+		return nil
+	}
+	return f.setAnnouncementPinnedFn(ctx, announcementUUID, pinned)
+}
+
+func (f *fakeGroupRepoForService) DeleteAnnouncement(ctx context.Context, announcementUUID string) error {
+	if f.deleteAnnouncementFn == nil {
+		return nil
+	}
+	return f.deleteAnnouncementFn(ctx, announcementUUID)
+}
+
+func (f *fakeGroupRepoForService) AckAnnouncement(ctx context.Context, announcementUUID, userUUID string) error {
+	if f.ackAnnouncementFn == nil {
+		return nil
+	}
+	return f.ackAnnouncementFn(ctx, announcementUUID, userUUID)
+}
+
+func (f *fakeGroupRepoForService) ListAnnouncementAcks(ctx context.Context, announcementUUID string) ([]*model.GroupAnnouncementAck, error) {
+	if f.listAnnouncementAcksFn == nil {
+		return nil, nil
+	}
+	return f.listAnnouncementAcksFn(ctx, announcementUUID)
+}
+
+func (f *fakeGroupRepoForService) ListAckedAnnouncementUUIDs(ctx context.Context, userUUID string, announcementUUIDs []string) (map[string]bool, error) {
+	if f.listAckedAnnouncementsFn == nil {
+		return map[string]bool{}, nil
+	}
+	return f.listAckedAnnouncementsFn(ctx, userUUID, announcementUUIDs)
+}
+
+func withGroupUserUUID(userUUID string) context.Context {
+	return context.WithValue(context.Background(), "user_uuid", userUUID)
+}
+
+func requireGroupStatusCode(t *testing.T, err error, wantGRPC codes.Code, wantBizCode int) {
+	t.Helper()
+	require.Error(t, err)
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, wantGRPC, st.Code())
+	gotBiz, convErr := strconv.Atoi(st.Message())
+	require.NoError(t, convErr)
+	require.Equal(t, wantBizCode, gotBiz)
+}
+
+func TestUserGroupServiceSetGroupCard(t *testing.T) {
+	initUserGroupTestLogger()
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.SetGroupCard(context.Background(), &pb.SetGroupCardRequest{GroupUuid: "g1", Card: "c"})
+		requireGroupStatusCode(t, err, codes.Unauthenticated, consts.CodeUnauthorized)
+	})
+
+	t.Run("card_too_long", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.SetGroupCard(withGroupUserUUID("u1"), &pb.SetGroupCardRequest{
+			GroupUuid: "g1",
+			Card:      strings.Repeat("名", groupCardMaxLen+1),
+		})
+		requireGroupStatusCode(t, err, codes.InvalidArgument, consts.CodeGroupCardTooLong)
+	})
+
+	t.Run("group_not_found", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getGroupFn: func(context.Context, string) (*model.GroupInfo, error) {
+				return nil, repository.ErrRecordNotFound
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.SetGroupCard(withGroupUserUUID("u1"), &pb.SetGroupCardRequest{GroupUuid: "g1", Card: "c"})
+		requireGroupStatusCode(t, err, codes.NotFound, consts.CodeGroupNotFound)
+	})
+
+	t.Run("group_dismissed", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getGroupFn: func(context.Context, string) (*model.GroupInfo, error) {
+				return &model.GroupInfo{Uuid: "g1", Status: model.GroupStatusDismissed}, nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.SetGroupCard(withGroupUserUUID("u1"), &pb.SetGroupCardRequest{GroupUuid: "g1", Card: "c"})
+		requireGroupStatusCode(t, err, codes.FailedPrecondition, consts.CodeGroupAlreadyDismiss)
+	})
+
+	t.Run("not_member", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			getMemberFn: func(context.Context, string, string) (*model.GroupMember, error) {
+				return nil, repository.ErrRecordNotFound
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.SetGroupCard(withGroupUserUUID("u1"), &pb.SetGroupCardRequest{GroupUuid: "g1", Card: "c"})
+		requireGroupStatusCode(t, err, codes.PermissionDenied, consts.CodeNotGroupMember)
+	})
+
+	t.Run("repo_error", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			updateMemberCardFn: func(context.Context, string, string, string) error {
+				return errors.New("db down")
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.SetGroupCard(withGroupUserUUID("u1"), &pb.SetGroupCardRequest{GroupUuid: "g1", Card: "c"})
+		requireGroupStatusCode(t, err, codes.Internal, consts.CodeInternalError)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		var gotGroup, gotUser, gotCard string
+		svc := NewGroupService(&fakeGroupRepoForService{
+			updateMemberCardFn: func(_ context.Context, groupUUID, userUUID, card string) error {
+				gotGroup, gotUser, gotCard = groupUUID, userUUID, card
+				return nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.SetGroupCard(withGroupUserUUID("u1"), &pb.SetGroupCardRequest{GroupUuid: "g1", Card: "小明"})
+		require.NoError(t, err)
+		assert.Equal(t, "g1", gotGroup)
+		assert.Equal(t, "u1", gotUser)
+		assert.Equal(t, "小明", gotCard)
+	})
+}
+
+func TestUserGroupServiceUpdateGroupSettings(t *testing.T) {
+	initUserGroupTestLogger()
+
+	t.Run("empty_update_skips_repo", func(t *testing.T) {
+		called := false
+		svc := NewGroupService(&fakeGroupRepoForService{
+			updateMemberSettingsFn: func(context.Context, string, string, repository.GroupMemberSettingsUpdate) error {
+				called = true
+				return nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.UpdateGroupSettings(withGroupUserUUID("u1"), &pb.UpdateGroupSettingsRequest{GroupUuid: "g1"})
+		require.NoError(t, err)
+		assert.False(t, called)
+	})
+
+	t.Run("partial_update", func(t *testing.T) {
+		var got repository.GroupMemberSettingsUpdate
+		svc := NewGroupService(&fakeGroupRepoForService{
+			updateMemberSettingsFn: func(_ context.Context, _, _ string, update repository.GroupMemberSettingsUpdate) error {
+				got = update
+				return nil
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.UpdateGroupSettings(withGroupUserUUID("u1"), &pb.UpdateGroupSettingsRequest{
+			GroupUuid:  "g1",
+			MuteNotify: proto.Bool(true),
+		})
+		require.NoError(t, err)
+		require.NotNil(t, got.MuteNotify)
+		assert.True(t, *got.MuteNotify)
+		assert.Nil(t, got.SaveToContacts)
+		assert.Nil(t, got.HideMemberNickname)
+	})
+
+	t.Run("member_gone", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			updateMemberSettingsFn: func(context.Context, string, string, repository.GroupMemberSettingsUpdate) error {
+				return repository.ErrRecordNotFound
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		err := svc.UpdateGroupSettings(withGroupUserUUID("u1"), &pb.UpdateGroupSettingsRequest{
+			GroupUuid:      "g1",
+			SaveToContacts: proto.Bool(true),
+		})
+		requireGroupStatusCode(t, err, codes.PermissionDenied, consts.CodeNotGroupMember)
+	})
+}
+
+func TestUserGroupServiceGetGroupSettings(t *testing.T) {
+	initUserGroupTestLogger()
+
+	svc := NewGroupService(&fakeGroupRepoForService{
+		getMemberFn: func(_ context.Context, groupUUID, userUUID string) (*model.GroupMember, error) {
+			return &model.GroupMember{
+				GroupUuid:          groupUUID,
+				UserUuid:           userUUID,
+				Remark:             "card",
+				SaveToContacts:     true,
+				HideMemberNickname: false,
+				MuteNotify:         true,
+			}, nil
+		},
+	}, &fakeUserSvcRepo{}, nil, nil)
+
+	resp, err := svc.GetGroupSettings(withGroupUserUUID("u1"), &pb.GetGroupSettingsRequest{GroupUuid: "g1"})
+	require.NoError(t, err)
+	require.NotNil(t, resp.Settings)
+	assert.Equal(t, "g1", resp.Settings.GroupUuid)
+	assert.Equal(t, "card", resp.Settings.GroupCard)
+	assert.True(t, resp.Settings.SaveToContacts)
+	assert.False(t, resp.Settings.HideMemberNickname)
+	assert.True(t, resp.Settings.MuteNotify)
+}
+
+func TestUserGroupServiceBatchGetMemberDisplayName(t *testing.T) {
+	initUserGroupTestLogger()
+
+	t.Run("card_preferred_over_nickname", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			batchGetMembersFn: func(context.Context, string, []string) ([]*model.GroupMember, error) {
+				return []*model.GroupMember{
+					{UserUuid: "u2", Remark: ""},
+					{UserUuid: "u1", Remark: "群名片"},
+				}, nil
+			},
+		}, &fakeUserSvcRepo{
+			batchGetByUUIDsFn: func(context.Context, []string) ([]*model.UserInfo, error) {
+				return []*model.UserInfo{
+					{Uuid: "u1", Nickname: "昵称1"},
+					{Uuid: "u2", Nickname: "昵称2"},
+				}, nil
+			},
+		}, nil, nil)
+
+		resp, err := svc.BatchGetMemberDisplayName(context.Background(), &pb.BatchGetMemberDisplayNameRequest{
+			GroupUuid: "g1",
+			UserUuids: []string{"u1", "u3", "u2", "u1"},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Members, 2)
+		assert.Equal(t, "u1", resp.Members[0].UserUuid)
+		assert.Equal(t, "群名片", resp.Members[0].DisplayName)
+		assert.Equal(t, "昵称1", resp.Members[0].Nickname)
+		assert.Equal(t, "u2", resp.Members[1].UserUuid)
+		assert.Equal(t, "昵称2", resp.Members[1].DisplayName)
+	})
+
+	t.Run("repo_error", func(t *testing.T) {
+		svc := NewGroupService(&fakeGroupRepoForService{
+			batchGetMembersFn: func(context.Context, string, []string) ([]*model.GroupMember, error) {
+				return nil, errors.New("db down")
+			},
+		}, &fakeUserSvcRepo{}, nil, nil)
+		_, err := svc.BatchGetMemberDisplayName(context.Background(), &pb.BatchGetMemberDisplayNameRequest{
+			GroupUuid: "g1",
+			UserUuids: []string{"u1"},
+		})
+		requireGroupStatusCode(t, err, codes.Internal, consts.CodeInternalError)
+	})
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	pb "ChatServer/apps/user/pb"
+	"ChatServer/pkg/pushnotify"
 	"context"
 )
 
@@ -170,6 +171,88 @@ type IDeviceService interface {
 	// UpdateDeviceStatus 更新设备在线状态（内部调用）
 	// 由 connect 服务在连接建立/断开时调用。
 	UpdateDeviceStatus(ctx context.Context, req *pb.UpdateDeviceStatusRequest) error
+
+	// RegisterPushToken 为当前设备登记推送 token
+	RegisterPushToken(ctx context.Context, req *pb.RegisterPushTokenRequest) (*pb.RegisterPushTokenResponse, error)
+
+	// UnregisterPushToken 清除当前设备的推送 token
+	UnregisterPushToken(ctx context.Context, req *pb.UnregisterPushTokenRequest) error
+
+	// UpdateNotificationSettings 更新设备通知偏好
+	UpdateNotificationSettings(ctx context.Context, req *pb.UpdateNotificationSettingsRequest) (*pb.UpdateNotificationSettingsResponse, error)
+
+	// GetNotificationSettings 获取设备通知偏好
+	GetNotificationSettings(ctx context.Context, req *pb.GetNotificationSettingsRequest) (*pb.GetNotificationSettingsResponse, error)
+}
+
+// ==================== 群组服务接口 ====================
+
+// IGroupService 群组服务接口
+// 职责：群名片、成员个人群设置、群成员展示名、群邀请与入群审核
+type IGroupService interface {
+	// SetGroupCard 设置群名片
+	SetGroupCard(ctx context.Context, req *pb.SetGroupCardRequest) error
+
+	// UpdateGroupSettings 更新本人的群设置
+	UpdateGroupSettings(ctx context.Context, req *pb.UpdateGroupSettingsRequest) error
+
+	// GetGroupSettings 获取本人的群设置
+	GetGroupSettings(ctx context.Context, req *pb.GetGroupSettingsRequest) (*pb.GetGroupSettingsResponse, error)
+
+	// BatchGetMemberDisplayName 批量获取群成员展示名（内部调用）
+	// 群名片优先，为空时回退为全局昵称。
+	BatchGetMemberDisplayName(ctx context.Context, req *pb.BatchGetMemberDisplayNameRequest) (*pb.BatchGetMemberDisplayNameResponse, error)
+
+	// CreateGroupInvite 创建群邀请（二维码 / 分享链接）
+	CreateGroupInvite(ctx context.Context, req *pb.CreateGroupInviteRequest) (*pb.CreateGroupInviteResponse, error)
+
+	// RevokeGroupInvite 撤销群邀请
+	RevokeGroupInvite(ctx context.Context, req *pb.RevokeGroupInviteRequest) error
+
+	// PreviewGroupInvite 预览群邀请对应的群信息
+	PreviewGroupInvite(ctx context.Context, req *pb.PreviewGroupInviteRequest) (*pb.PreviewGroupInviteResponse, error)
+
+	// JoinGroupByInvite 通过群邀请入群
+	JoinGroupByInvite(ctx context.Context, req *pb.JoinGroupByInviteRequest) (*pb.JoinGroupByInviteResponse, error)
+
+	// ListGroupJoinRequests 获取待审核的入群申请
+	ListGroupJoinRequests(ctx context.Context, req *pb.ListGroupJoinRequestsRequest) (*pb.ListGroupJoinRequestsResponse, error)
+
+	// HandleGroupJoinRequest 处理入群申请
+	HandleGroupJoinRequest(ctx context.Context, req *pb.HandleGroupJoinRequestRequest) error
+
+	// SetGroupAvatar 设置群头像（为空表示恢复自动生成）
+	SetGroupAvatar(ctx context.Context, req *pb.SetGroupAvatarRequest) error
+
+	// PostGroupAnnouncement 发布群公告
+	PostGroupAnnouncement(ctx context.Context, req *pb.PostGroupAnnouncementRequest) (*pb.PostGroupAnnouncementResponse, error)
+
+	// EditGroupAnnouncement 编辑群公告
+	EditGroupAnnouncement(ctx context.Context, req *pb.EditGroupAnnouncementRequest) error
+
+	// PinGroupAnnouncement 置顶/取消置顶群公告
+	PinGroupAnnouncement(ctx context.Context, req *pb.PinGroupAnnouncementRequest) error
+
+	// DeleteGroupAnnouncement 删除群公告
+	DeleteGroupAnnouncement(ctx context.Context, req *pb.DeleteGroupAnnouncementRequest) error
+
+	// ListGroupAnnouncements 获取群公告列表
+	ListGroupAnnouncements(ctx context.Context, req *pb.ListGroupAnnouncementsRequest) (*pb.ListGroupAnnouncementsResponse, error)
+
+	// AckGroupAnnouncement 确认群公告
+	AckGroupAnnouncement(ctx context.Context, req *pb.AckGroupAnnouncementRequest) error
+
+	// GetGroupAnnouncementAcks 查询公告确认情况
+	GetGroupAnnouncementAcks(ctx context.Context, req *pb.GetGroupAnnouncementAcksRequest) (*pb.GetGroupAnnouncementAcksResponse, error)
+}
+
+// ==================== 离线通知服务接口 ====================
+
+// INotificationService 离线推送通知服务接口
+// 职责：消费未送达事件，按免打扰、设备平台与会话折叠规则经推送通道下发系统推送
+type INotificationService interface {
+	// HandleUndelivered 处理一条未送达事件
+	HandleUndelivered(ctx context.Context, event *pushnotify.Event) error
 }
 
 // ==================== 别名类型定义（用于向后兼容）====================
@@ -188,3 +271,9 @@ type BlacklistService = IBlacklistService
 
 // DeviceService 别名 IDeviceService
 type DeviceService = IDeviceService
+
+// GroupService 别名 IGroupService
+type GroupService = IGroupService
+
+// NotificationService 别名 INotificationService
+type NotificationService = INotificationService
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"ChatServer/apps/user/internal/repository"
+	"ChatServer/consts"
+	"ChatServer/model"
+	"ChatServer/pkg/logger"
+	"ChatServer/pkg/pushnotify"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPreviewRunes 通知正文预览的最大字符数
+const maxPreviewRunes = 100
+
+// 关闭消息预览时使用的通用文案
+const (
+	hiddenPreviewTitle = "新消息"
+	hiddenPreviewBody  = "你收到了一条新消息"
+)
+
+// NotificationConfig 离线通知策略
+type NotificationConfig struct {
+	// Platforms 允许系统推送的设备平台（不区分大小写），为空时不限制
+	Platforms []string
+	// CollapseWindow 同一会话的突发消息在窗口内只推送第一条
+	CollapseWindow time.Duration
+}
+
+// notificationServiceImpl 离线通知服务实现
+type notificationServiceImpl struct {
+	deviceRepo     repository.IDeviceRepository
+	notifyRepo     repository.INotificationRepository
+	userRepo       repository.IUserRepository
+	dispatcher     *pushnotify.Dispatcher
+	platforms      map[string]struct{}
+	collapseWindow time.Duration
+	nowFunc        func() time.Time
+}
+
+// NewNotificationService 创建离线通知服务实例
+// userRepo 可为 nil，此时通知标题不展示发送者昵称
+func NewNotificationService(
+	deviceRepo repository.IDeviceRepository,
+	notifyRepo repository.INotificationRepository,
+	userRepo repository.IUserRepository,
+	dispatcher *pushnotify.Dispatcher,
+	cfg NotificationConfig,
+) NotificationService {
+	platforms := make(map[string]struct{}, len(cfg.Platforms))
+	for _, platform := range cfg.Platforms {
+		platforms[strings.ToLower(platform)] = struct{}{}
+	}
+	return &notificationServiceImpl{
+		deviceRepo:     deviceRepo,
+		notifyRepo:     notifyRepo,
+		userRepo:       userRepo,
+		dispatcher:     dispatcher,
+		platforms:      platforms,
+		collapseWindow: cfg.CollapseWindow,
+		nowFunc:        time.Now,
+	}
+}
+
+// HandleUndelivered 处理一条未送达事件：
+// 1. 消息类事件跳过自己发出的消息；会话免打扰（群聊含群设置中的消息免打扰）且未被 @ 时不推送；
+// 2. 只推送已登记 token、未登出/未被踢、且平台允许系统推送的设备；
+// 3. 按设备通知偏好过滤：关闭推送或处于免打扰时段的设备不推送，关闭预览时使用通用文案，关闭提示音时静默；
+// 4. 同一会话在折叠窗口内只推送第一条（通知栏按会话折叠）；
+// 5. 推送通道返回 token 失效时清除该设备的 token。
+// 下发失败只记录日志，不重试（Kafka 消息照常提交）。
+func (s *notificationServiceImpl) HandleUndelivered(ctx context.Context, event *pushnotify.Event) error {
+	if event == nil || event.UserUUID == "" {
+		return nil
+	}
+
+	msg, isMessage := event.Message()
+	collapseID := event.Type
+	if isMessage {
+		if msg.FromUUID == event.UserUUID {
+			return nil
+		}
+		collapseID = msg.ConvID
+		if !msg.Mentions(event.UserUUID) {
+			muted, err := s.conversationMuted(ctx, event.UserUUID, msg.ConvID)
+			if err != nil {
+				logger.Warn(ctx, "离线通知：查询会话免打扰失败，按未开启处理",
+					logger.String("user_uuid", event.UserUUID),
+					logger.String("conv_id", msg.ConvID),
+					logger.ErrorField("error", err),
+				)
+			} else if muted {
+				return nil
+			}
+		}
+	}
+
+	targets, err := s.pushTargets(ctx, event)
+	if err != nil {
+		logger.Error(ctx, "离线通知：查询推送设备失败",
+			logger.String("user_uuid", event.UserUUID),
+			logger.ErrorField("error", err),
+		)
+		return err
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	count, err := s.notifyRepo.IncrCollapseCount(ctx, event.UserUUID, collapseID, s.collapseWindow)
+	if err != nil {
+		logger.Warn(ctx, "离线通知：会话折叠计数失败，直接推送",
+			logger.String("user_uuid", event.UserUUID),
+			logger.ErrorField("error", err),
+		)
+	} else if count > 1 {
+		return nil
+	}
+
+	base := s.buildNotification(ctx, event, msg, isMessage)
+	base.CollapseKey = collapseID
+	for _, target := range targets {
+		n := *base
+		n.Provider = target.provider
+		n.Token = target.session.PushToken
+		n.Platform = target.session.Platform
+		if !target.setting.ShowPreview {
+			n.Title = hiddenPreviewTitle
+			n.Body = hiddenPreviewBody
+		}
+		if target.setting.Sound {
+			n.Sound = pushnotify.SoundDefault
+		}
+		s.send(ctx, target.session, &n)
+	}
+	return nil
+}
+
+// conversationMuted 判断用户是否对会话开启了免打扰：会话行的 mute，群聊另看成员的群消息免打扰设置。
+// 会话不存在视为未开启。
+func (s *notificationServiceImpl) conversationMuted(ctx context.Context, userUUID, convID string) (bool, error) {
+	conv, err := s.notifyRepo.GetConversation(ctx, userUUID, convID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if conv.Mute || conv.Type != model.ConversationTypeGroup {
+		return conv.Mute, nil
+	}
+	return s.notifyRepo.IsGroupNotifyMuted(ctx, conv.TargetUuid, userUUID)
+}
+
+// pushTarget 一个待推送设备及其通道、通知偏好
+type pushTarget struct {
+	session  *model.DeviceSession
+	provider string
+	setting  *model.DeviceNotificationSetting
+}
+
+// pushTargets 筛选可推送的设备：已登记 token、平台允许、通知偏好允许，事件指定设备时只取该设备
+func (s *notificationServiceImpl) pushTargets(ctx context.Context, event *pushnotify.Event) ([]pushTarget, error) {
+	sessions, err := s.deviceRepo.GetPushTargets(ctx, event.UserUUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+	settings, err := s.deviceRepo.ListNotificationSettings(ctx, event.UserUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.nowFunc()
+	targets := make([]pushTarget, 0, len(sessions))
+	for _, session := range sessions {
+		if session == nil || session.PushToken == "" {
+			continue
+		}
+		if event.DeviceID != "" && session.DeviceId != event.DeviceID {
+			continue
+		}
+		if len(s.platforms) > 0 {
+			if _, ok := s.platforms[strings.ToLower(session.Platform)]; !ok {
+				continue
+			}
+		}
+		provider := session.PushProvider
+		if provider == "" {
+			provider = pushnotify.DefaultProvider(session.Platform)
+		}
+		if provider == "" {
+			continue
+		}
+		setting, ok := settings[session.DeviceId]
+		if !ok {
+			setting = defaultNotificationSetting(session.UserUuid, session.DeviceId)
+		}
+		if !setting.PushEnabled || inQuietHours(setting, now) {
+			continue
+		}
+		targets = append(targets, pushTarget{session: session, provider: provider, setting: setting})
+	}
+	return targets, nil
+}
+
+// buildNotification 生成通知标题/正文与透传字段（token/通道由调用方按设备填充）
+func (s *notificationServiceImpl) buildNotification(ctx context.Context, event *pushnotify.Event, msg *pushnotify.MessagePayload, isMessage bool) *pushnotify.Notification {
+	n := &pushnotify.Notification{
+		Title: "新通知",
+		Body:  "你有一条新通知",
+		Data: map[string]string{
+			"type": event.Type,
+		},
+	}
+	if event.Seq > 0 {
+		n.Data["seq"] = strconv.FormatInt(event.Seq, 10)
+	}
+	if !isMessage {
+		return n
+	}
+
+	n.Title = "新消息"
+	if s.userRepo != nil && msg.FromUUID != "" {
+		sender, err := s.userRepo.GetByUUID(ctx, msg.FromUUID)
+		if err == nil && sender != nil && sender.Nickname != "" {
+			n.Title = sender.Nickname
+		}
+	}
+	n.Body = messagePreview(msg.MsgType, msg.Content)
+	if msg.Mentions(event.UserUUID) {
+		n.Body = "[有人@你] " + n.Body
+	}
+	n.Data["conv_id"] = msg.ConvID
+	n.Data["msg_id"] = msg.MsgID
+	return n
+}
+
+// send 下发到单个设备，token 失效时清除
+func (s *notificationServiceImpl) send(ctx context.Context, session *model.DeviceSession, n *pushnotify.Notification) {
+	err := s.dispatcher.Send(ctx, n)
+	if err == nil {
+		return
+	}
+	if errors.Is(err, pushnotify.ErrTokenInvalid) {
+		logger.Info(ctx, "离线通知：推送 token 已失效，清除",
+			logger.String("user_uuid", session.UserUuid),
+			logger.String("device_id", session.DeviceId),
+			logger.String("provider", n.Provider),
+		)
+		if clearErr := s.deviceRepo.UpdatePushToken(ctx, session.UserUuid, session.DeviceId, "", ""); clearErr != nil && !errors.Is(clearErr, repository.ErrRecordNotFound) {
+			logger.Warn(ctx, "离线通知：清除失效 token 失败",
+				logger.String("user_uuid", session.UserUuid),
+				logger.String("device_id", session.DeviceId),
+				logger.ErrorField("error", clearErr),
+			)
+		}
+		return
+	}
+	logger.Warn(ctx, "离线通知：下发失败",
+		logger.String("user_uuid", session.UserUuid),
+		logger.String("device_id", session.DeviceId),
+		logger.String("provider", n.Provider),
+		logger.ErrorField("error", err),
+	)
+}
+
+// inQuietHours 判断 now 是否处于设备的免打扰时段（按设备时区，时区无效时使用服务端时区）
+func inQuietHours(setting *model.DeviceNotificationSetting, now time.Time) bool {
+	if !setting.QuietHoursEnabled || setting.QuietStart == setting.QuietEnd {
+		return false
+	}
+	loc := time.Local
+	if setting.Timezone != "" {
+		if tz, err := time.LoadLocation(setting.Timezone); err == nil {
+			loc = tz
+		}
+	}
+	local := now.In(loc)
+	minute := int16(local.Hour()*60 + local.Minute())
+	if setting.QuietStart < setting.QuietEnd {
+		return minute >= setting.QuietStart && minute < setting.QuietEnd
+	}
+	// 跨零点，如 22:00-08:00
+	return minute >= setting.QuietStart || minute < setting.QuietEnd
+}
+
+// messagePreview 按消息类型生成正文预览：文本取 content.text，其余使用占位文案
+func messagePreview(msgType int32, content string) string {
+	if msgType == consts.MsgTypeCall {
+		return "[音视频通话]"
+	}
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal([]byte(content), &body); err == nil && body.Text != "" {
+		runes := []rune(body.Text)
+		if len(runes) > maxPreviewRunes {
+			return string(runes[:maxPreviewRunes]) + "…"
+		}
+		return body.Text
+	}
+	return "[新消息]"
+}
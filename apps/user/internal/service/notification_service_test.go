@@ -0,0 +1,302 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"ChatServer/apps/user/internal/repository"
+	"ChatServer/model"
+	"ChatServer/pkg/pushnotify"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeNotificationRepository struct {
+	convs      map[string]*model.Conversation
+	groupMuted map[string]bool
+	mutedErr   error
+	counts     map[string]int64
+}
+
+func (f *fakeNotificationRepository) GetConversation(_ context.Context, ownerUUID, convID string) (*model.Conversation, error) {
+	if f.mutedErr != nil {
+		return nil, f.mutedErr
+	}
+	conv, ok := f.convs[ownerUUID+"/"+convID]
+	if !ok {
+		return nil, repository.ErrRecordNotFound
+	}
+	return conv, nil
+}
+
+func (f *fakeNotificationRepository) IsGroupNotifyMuted(_ context.Context, groupUUID, userUUID string) (bool, error) {
+	return f.groupMuted[groupUUID+"/"+userUUID], nil
+}
+
+func (f *fakeNotificationRepository) IncrCollapseCount(_ context.Context, userUUID, collapseID string, _ time.Duration) (int64, error) {
+	if f.counts == nil {
+		f.counts = map[string]int64{}
+	}
+	key := userUUID + "/" + collapseID
+	f.counts[key]++
+	return f.counts[key], nil
+}
+
+// newStubPushServer 启动 HTTP 桩推送服务：记录收到的通知，token=expired 时返回 410
+func newStubPushServer(t *testing.T) (*pushnotify.HTTPProvider, func() []pushnotify.Notification) {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		received []pushnotify.Notification
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n pushnotify.Notification
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&n))
+		if n.Token == "expired" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		mu.Lock()
+		received = append(received, n)
+		mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return pushnotify.NewHTTPProvider(srv.URL), func() []pushnotify.Notification {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]pushnotify.Notification(nil), received...)
+	}
+}
+
+func messageEvent(t *testing.T, userUUID string, payload pushnotify.MessagePayload) *pushnotify.Event {
+	t.Helper()
+	data, err := json.Marshal(payload)
+	require.NoError(t, err)
+	return &pushnotify.Event{UserUUID: userUUID, Type: "message", Seq: 9, Data: data}
+}
+
+func TestUserNotificationServiceHandleUndelivered(t *testing.T) {
+	initUserDeviceTestLogger()
+
+	sessions := []*model.DeviceSession{
+		{UserUuid: "u1", DeviceId: "ios-1", Platform: "iOS", PushToken: "tok-ios"},
+		{UserUuid: "u1", DeviceId: "android-1", Platform: "Android", PushProvider: "xiaomi", PushToken: "tok-mi"},
+		{UserUuid: "u1", DeviceId: "web-1", Platform: "Web", PushProvider: "fcm", PushToken: "tok-web"},
+	}
+	deviceRepo := func(cleared *[]string) *fakeDeviceRepository {
+		return &fakeDeviceRepository{
+			getPushTargetsFn: func(_ context.Context, userUUID string) ([]*model.DeviceSession, error) {
+				require.Equal(t, "u1", userUUID)
+				return sessions, nil
+			},
+			updatePushTokenFn: func(_ context.Context, userUUID, deviceID, provider, token string) error {
+				require.Empty(t, token)
+				*cleared = append(*cleared, userUUID+"/"+deviceID)
+				return nil
+			},
+		}
+	}
+	cfg := NotificationConfig{Platforms: []string{"ios", "android"}}
+	payload := pushnotify.MessagePayload{MsgID: "m1", ConvID: "c1", FromUUID: "u2", Content: `{"text":"hello"}`}
+
+	t.Run("dispatch_by_platform_and_collapse", func(t *testing.T) {
+		provider, received := newStubPushServer(t)
+		var cleared []string
+		svc := NewNotificationService(deviceRepo(&cleared), &fakeNotificationRepository{}, nil, pushnotify.NewDispatcher(provider), cfg)
+
+		require.NoError(t, svc.HandleUndelivered(context.Background(), messageEvent(t, "u1", payload)))
+		require.NoError(t, svc.HandleUndelivered(context.Background(), messageEvent(t, "u1", payload)))
+
+		got := received()
+		require.Len(t, got, 2, "web device skipped, second event collapsed")
+		byToken := map[string]pushnotify.Notification{}
+		for _, n := range got {
+			byToken[n.Token] = n
+		}
+		assert.Equal(t, "apns", byToken["tok-ios"].Provider)
+		assert.Equal(t, "xiaomi", byToken["tok-mi"].Provider)
+		assert.Equal(t, "hello", byToken["tok-ios"].Body)
+		assert.Equal(t, "c1", byToken["tok-ios"].CollapseKey)
+		assert.Equal(t, map[string]string{"type": "message", "seq": "9", "conv_id": "c1", "msg_id": "m1"}, byToken["tok-ios"].Data)
+		assert.Empty(t, cleared)
+	})
+
+	t.Run("muted_conversation_unless_mentioned", func(t *testing.T) {
+		provider, received := newStubPushServer(t)
+		var cleared []string
+		notifyRepo := &fakeNotificationRepository{convs: map[string]*model.Conversation{"u1/c1": {Mute: true}}}
+		svc := NewNotificationService(deviceRepo(&cleared), notifyRepo, nil, pushnotify.NewDispatcher(provider), cfg)
+
+		require.NoError(t, svc.HandleUndelivered(context.Background(), messageEvent(t, "u1", payload)))
+		assert.Empty(t, received())
+
+		mentioned := payload
+		mentioned.AtUsers = []string{pushnotify.AtAllUUID}
+		require.NoError(t, svc.HandleUndelivered(context.Background(), messageEvent(t, "u1", mentioned)))
+		got := received()
+		require.Len(t, got, 2)
+		assert.Equal(t, "[有人@你] hello", got[0].Body)
+	})
+
+	t.Run("group_muted_member_unless_mentioned", func(t *testing.T) {
+		provider, received := newStubPushServer(t)
+		var cleared []string
+		notifyRepo := &fakeNotificationRepository{
+			convs: map[string]*model.Conversation{
+				"u1/c1": {Type: model.ConversationTypeGroup, TargetUuid: "g1"},
+			},
+			groupMuted: map[string]bool{"g1/u1": true},
+		}
+		svc := NewNotificationService(deviceRepo(&cleared), notifyRepo, nil, pushnotify.NewDispatcher(provider), cfg)
+
+		// 会话行未开启免打扰，但成员在群设置里开启了消息免打扰，不推送。
+		require.NoError(t, svc.HandleUndelivered(context.Background(), messageEvent(t, "u1", payload)))
+		assert.Empty(t, received())
+
+		mentioned := payload
+		mentioned.AtUsers = []string{"u1"}
+		require.NoError(t, svc.HandleUndelivered(context.Background(), messageEvent(t, "u1", mentioned)))
+		got := received()
+		require.NotEmpty(t, got)
+		assert.Equal(t, "[有人@你] hello", got[0].Body)
+	})
+
+	t.Run("skip_own_message_and_unknown_device", func(t *testing.T) {
+		provider, received := newStubPushServer(t)
+		var cleared []string
+		svc := NewNotificationService(deviceRepo(&cleared), &fakeNotificationRepository{}, nil, pushnotify.NewDispatcher(provider), cfg)
+
+		own := payload
+		own.FromUUID = "u1"
+		require.NoError(t, svc.HandleUndelivered(context.Background(), messageEvent(t, "u1", own)))
+
+		event := messageEvent(t, "u1", payload)
+		event.DeviceID = "web-1"
+		require.NoError(t, svc.HandleUndelivered(context.Background(), event))
+		assert.Empty(t, received())
+	})
+
+	t.Run("invalid_token_cleared", func(t *testing.T) {
+		provider, _ := newStubPushServer(t)
+		var cleared []string
+		repo := deviceRepo(&cleared)
+		repo.getPushTargetsFn = func(_ context.Context, _ string) ([]*model.DeviceSession, error) {
+			return []*model.DeviceSession{{UserUuid: "u1", DeviceId: "ios-1", Platform: "iOS", PushToken: "expired"}}, nil
+		}
+		svc := NewNotificationService(repo, &fakeNotificationRepository{}, nil, pushnotify.NewDispatcher(provider), cfg)
+
+		require.NoError(t, svc.HandleUndelivered(context.Background(), messageEvent(t, "u1", payload)))
+		assert.Equal(t, []string{"u1/ios-1"}, cleared)
+	})
+
+	t.Run("non_message_event_to_file_stub", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "push.jsonl")
+		var cleared []string
+		svc := NewNotificationService(deviceRepo(&cleared), &fakeNotificationRepository{}, nil,
+			pushnotify.NewDispatcher(pushnotify.NewFileProvider(path)), cfg)
+
+		event := &pushnotify.Event{UserUUID: "u1", DeviceID: "ios-1", Type: "friend_apply"}
+		require.NoError(t, svc.HandleUndelivered(context.Background(), event))
+
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		defer f.Close()
+		var lines []pushnotify.Notification
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var n pushnotify.Notification
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &n))
+			lines = append(lines, n)
+		}
+		require.Len(t, lines, 1)
+		assert.Equal(t, "tok-ios", lines[0].Token)
+		assert.Equal(t, "friend_apply", lines[0].CollapseKey)
+		assert.Equal(t, "新通知", lines[0].Title)
+	})
+
+	t.Run("device_notification_settings", func(t *testing.T) {
+		provider, received := newStubPushServer(t)
+		var cleared []string
+		repo := deviceRepo(&cleared)
+		repo.getPushTargetsFn = func(_ context.Context, _ string) ([]*model.DeviceSession, error) {
+			return []*model.DeviceSession{
+				{UserUuid: "u1", DeviceId: "ios-1", Platform: "iOS", PushToken: "tok-ios"},
+				{UserUuid: "u1", DeviceId: "ios-2", Platform: "iOS", PushToken: "tok-ios-2"},
+				{UserUuid: "u1", DeviceId: "android-1", Platform: "Android", PushToken: "tok-fcm"},
+				{UserUuid: "u1", DeviceId: "android-2", Platform: "Android", PushToken: "tok-fcm-2"},
+			}, nil
+		}
+		repo.listNotifySettingsFn = func(_ context.Context, _ string) (map[string]*model.DeviceNotificationSetting, error) {
+			return map[string]*model.DeviceNotificationSetting{
+				// 关闭推送
+				"ios-2": {DeviceId: "ios-2", ShowPreview: true, Sound: true},
+				// 免打扰 22:00-08:00（上海时间），当前为上海 23:30
+				"android-1": {DeviceId: "android-1", PushEnabled: true, QuietHoursEnabled: true, QuietStart: 22 * 60, QuietEnd: 8 * 60, Timezone: "Asia/Shanghai", ShowPreview: true, Sound: true},
+				// 隐藏预览 + 静音
+				"android-2": {DeviceId: "android-2", PushEnabled: true, QuietHoursEnabled: true, QuietStart: 60, QuietEnd: 120, Timezone: "UTC"},
+			}, nil
+		}
+		svc := NewNotificationService(repo, &fakeNotificationRepository{}, nil, pushnotify.NewDispatcher(provider), cfg)
+		svc.(*notificationServiceImpl).nowFunc = func() time.Time {
+			return time.Date(2026, 10, 18, 15, 30, 0, 0, time.UTC)
+		}
+
+		require.NoError(t, svc.HandleUndelivered(context.Background(), messageEvent(t, "u1", payload)))
+
+		got := received()
+		require.Len(t, got, 2)
+		byToken := map[string]pushnotify.Notification{}
+		for _, n := range got {
+			byToken[n.Token] = n
+		}
+		assert.Equal(t, "hello", byToken["tok-ios"].Body)
+		assert.Equal(t, pushnotify.SoundDefault, byToken["tok-ios"].Sound)
+		assert.Equal(t, "新消息", byToken["tok-fcm-2"].Title)
+		assert.Equal(t, "你收到了一条新消息", byToken["tok-fcm-2"].Body)
+		assert.Empty(t, byToken["tok-fcm-2"].Sound)
+		assert.Equal(t, "c1", byToken["tok-fcm-2"].Data["conv_id"])
+	})
+
+	t.Run("query_targets_error", func(t *testing.T) {
+		wantErr := errors.New("db failed")
+		svc := NewNotificationService(&fakeDeviceRepository{
+			getPushTargetsFn: func(_ context.Context, _ string) ([]*model.DeviceSession, error) {
+				return nil, wantErr
+			},
+		}, &fakeNotificationRepository{}, nil, pushnotify.NewDispatcher(nil), cfg)
+		err := svc.HandleUndelivered(context.Background(), messageEvent(t, "u1", payload))
+		require.ErrorIs(t, err, wantErr)
+	})
+}
+
+func TestUserNotificationInQuietHours(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 10, 18, hour, minute, 0, 0, time.UTC)
+	}
+	overnight := &model.DeviceNotificationSetting{QuietHoursEnabled: true, QuietStart: 22 * 60, QuietEnd: 8 * 60, Timezone: "UTC"}
+	daytime := &model.DeviceNotificationSetting{QuietHoursEnabled: true, QuietStart: 12 * 60, QuietEnd: 14 * 60, Timezone: "UTC"}
+
+	assert.True(t, inQuietHours(overnight, at(23, 0)))
+	assert.True(t, inQuietHours(overnight, at(7, 59)))
+	assert.False(t, inQuietHours(overnight, at(8, 0)))
+	assert.True(t, inQuietHours(daytime, at(12, 0)))
+	assert.False(t, inQuietHours(daytime, at(14, 0)))
+
+	disabled := *overnight
+	disabled.QuietHoursEnabled = false
+	assert.False(t, inQuietHours(&disabled, at(23, 0)))
+
+	shanghai := *daytime
+	shanghai.Timezone = "Asia/Shanghai"
+	assert.True(t, inQuietHours(&shanghai, at(4, 30)), "12:30 in Asia/Shanghai")
+}
@@ -26,14 +26,17 @@ type userServiceImpl struct {
 	userRepo   repository.IUserRepository
 	authRepo   repository.IAuthRepository
 	deviceRepo repository.IDeviceRepository
+	kicker     ConnectKicker
 }
 
 // NewUserService 创建用户信息服务实例
-func NewUserService(userRepo repository.IUserRepository, authRepo repository.IAuthRepository, deviceRepo repository.IDeviceRepository) UserService {
+// kicker 可为 nil，此时注销账号不主动断开 WebSocket 连接
+func NewUserService(userRepo repository.IUserRepository, authRepo repository.IAuthRepository, deviceRepo repository.IDeviceRepository, kicker ConnectKicker) UserService {
 	return &userServiceImpl{
 		userRepo:   userRepo,
 		authRepo:   authRepo,
 		deviceRepo: deviceRepo,
+		kicker:     kicker,
 	}
 }
 
@@ -613,7 +616,8 @@ func (s *userServiceImpl) GetQRCode(ctx context.Context, req *pb.GetQRCodeReques
 //  3. 验证密码是否正确
 //  4. 软删除用户（设置 deleted_at 时间戳）
 //  5. 删除用户的所有设备会话（登出所有设备）
-//  6. 返回注销时间和恢复截止时间
+//  6. 断开用户全部设备的 WebSocket 连接
+//  7. 返回注销时间和恢复截止时间
 //
 // 错误码映射：
 //   - codes.NotFound: 用户不存在
@@ -678,7 +682,12 @@ func (s *userServiceImpl) DeleteAccount(ctx context.Context, req *pb.DeleteAccou
 		}
 	}, 5*time.Second)
 
-	// 6. 计算恢复截止时间（30天后）
+	// 6. 断开用户全部设备的 WebSocket 连接（异步，失败走重试队列）
+	if s.kicker != nil {
+		s.kicker.KickUser(ctx, userUUID, KickReasonAccountDeleted)
+	}
+
+	// 7. 计算恢复截止时间（30天后）
 	deleteAt := time.Now()
 	recoverDeadline := deleteAt.Add(30 * 24 * time.Hour)
 
@@ -689,7 +698,7 @@ func (s *userServiceImpl) DeleteAccount(ctx context.Context, req *pb.DeleteAccou
 		logger.String("recover_deadline", recoverDeadline.Format(time.RFC3339)),
 	)
 
-	// 7. 返回注销时间和恢复截止时间
+	// 8. 返回注销时间和恢复截止时间
 	return &pb.DeleteAccountResponse{
 		DeleteAt:        deleteAt.Format(time.RFC3339),
 		RecoverDeadline: recoverDeadline.Format(time.RFC3339),
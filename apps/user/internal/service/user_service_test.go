@@ -190,7 +190,7 @@ func TestUserServiceProfileAndSearch(t *testing.T) {
 	initUserSvcTestLogger()
 
 	t.Run("get_profile_missing_user_uuid", func(t *testing.T) {
-		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 		resp, err := svc.GetProfile(context.Background(), &pb.GetProfileRequest{})
 		require.Nil(t, resp)
 		requireUserSvcStatus(t, err, codes.Unauthenticated, consts.CodeUnauthorized)
@@ -202,7 +202,7 @@ func TestUserServiceProfileAndSearch(t *testing.T) {
 				require.Equal(t, "u1", uuid)
 				return &model.UserInfo{Uuid: "u1", Nickname: "n1"}, nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 		resp, err := svc.GetProfile(userSvcCtx("u1"), &pb.GetProfileRequest{})
 		require.NoError(t, err)
 		require.NotNil(t, resp)
@@ -211,7 +211,7 @@ func TestUserServiceProfileAndSearch(t *testing.T) {
 	})
 
 	t.Run("search_user_missing_user_uuid", func(t *testing.T) {
-		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 		resp, err := svc.SearchUser(context.Background(), &pb.SearchUserRequest{Keyword: "a", Page: 1, PageSize: 20})
 		require.Nil(t, resp)
 		requireUserSvcStatus(t, err, codes.Unauthenticated, consts.CodeUnauthorized)
@@ -222,7 +222,7 @@ func TestUserServiceProfileAndSearch(t *testing.T) {
 			searchUserFn: func(_ context.Context, _ string, _, _ int) ([]*model.UserInfo, int64, error) {
 				return nil, 0, errors.New("db error")
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 		resp, err := svc.SearchUser(userSvcCtx("u1"), &pb.SearchUserRequest{Keyword: "a", Page: 1, PageSize: 20})
 		require.Nil(t, resp)
 		requireUserSvcStatus(t, err, codes.Internal, consts.CodeInternalError)
@@ -236,7 +236,7 @@ func TestUserServiceProfileAndSearch(t *testing.T) {
 				require.Equal(t, 20, pageSize)
 				return []*model.UserInfo{{Uuid: "u2", Nickname: "n2"}}, 1, nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 		resp, err := svc.SearchUser(userSvcCtx("u1"), &pb.SearchUserRequest{Keyword: "alice", Page: 1, PageSize: 20})
 		require.NoError(t, err)
 		require.NotNil(t, resp)
@@ -249,14 +249,14 @@ func TestUserServiceUpdateAndAvatar(t *testing.T) {
 	initUserSvcTestLogger()
 
 	t.Run("update_profile_empty_request", func(t *testing.T) {
-		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 		resp, err := svc.UpdateProfile(userSvcCtx("u1"), &pb.UpdateProfileRequest{})
 		require.Nil(t, resp)
 		requireUserSvcStatus(t, err, codes.InvalidArgument, consts.CodeParamError)
 	})
 
 	t.Run("update_profile_birthday_format_error", func(t *testing.T) {
-		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 		resp, err := svc.UpdateProfile(userSvcCtx("u1"), &pb.UpdateProfileRequest{Birthday: "2026/02/06"})
 		require.Nil(t, resp)
 		requireUserSvcStatus(t, err, codes.InvalidArgument, consts.CodeBirthdayFormatError)
@@ -272,7 +272,7 @@ func TestUserServiceUpdateAndAvatar(t *testing.T) {
 			getByUUIDFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
 				return &model.UserInfo{Uuid: "u1", Nickname: "new-nick"}, nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 		resp, err := svc.UpdateProfile(userSvcCtx("u1"), &pb.UpdateProfileRequest{Nickname: "new-nick"})
 		require.NoError(t, err)
 		require.NotNil(t, resp)
@@ -280,7 +280,7 @@ func TestUserServiceUpdateAndAvatar(t *testing.T) {
 	})
 
 	t.Run("upload_avatar_empty_url", func(t *testing.T) {
-		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 		resp, err := svc.UploadAvatar(userSvcCtx("u1"), &pb.UploadAvatarRequest{})
 		require.Nil(t, resp)
 		requireUserSvcStatus(t, err, codes.InvalidArgument, consts.CodeParamError)
@@ -293,7 +293,7 @@ func TestUserServiceUpdateAndAvatar(t *testing.T) {
 				require.Equal(t, "https://cdn/a.png", avatar)
 				return nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 		resp, err := svc.UploadAvatar(userSvcCtx("u1"), &pb.UploadAvatarRequest{AvatarUrl: "https://cdn/a.png"})
 		require.NoError(t, err)
 		require.NotNil(t, resp)
@@ -310,7 +310,7 @@ func TestUserServiceChangePasswordAndEmail(t *testing.T) {
 			getByUUIDFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
 				return &model.UserInfo{Uuid: "u1", Password: oldHash}, nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 		err := svc.ChangePassword(userSvcCtx("u1"), &pb.ChangePasswordRequest{OldPassword: "wrong", NewPassword: "newpass123"})
 		requireUserSvcStatus(t, err, codes.Unauthenticated, consts.CodePasswordError)
 	})
@@ -320,7 +320,7 @@ func TestUserServiceChangePasswordAndEmail(t *testing.T) {
 			getByUUIDFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
 				return &model.UserInfo{Uuid: "u1", Password: oldHash}, nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 		err := svc.ChangePassword(userSvcCtx("u1"), &pb.ChangePasswordRequest{OldPassword: "oldpass123", NewPassword: "oldpass123"})
 		requireUserSvcStatus(t, err, codes.FailedPrecondition, consts.CodePasswordSameAsOld)
 	})
@@ -337,7 +337,7 @@ func TestUserServiceChangePasswordAndEmail(t *testing.T) {
 				require.NotEmpty(t, password)
 				return nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 		err := svc.ChangePassword(userSvcCtx("u1"), &pb.ChangePasswordRequest{OldPassword: "oldpass123", NewPassword: "newpass123"})
 		require.NoError(t, err)
 		assert.True(t, updated)
@@ -348,7 +348,7 @@ func TestUserServiceChangePasswordAndEmail(t *testing.T) {
 			existsByEmailFn: func(_ context.Context, _ string) (bool, error) {
 				return true, nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 		resp, err := svc.ChangeEmail(userSvcCtx("u1"), &pb.ChangeEmailRequest{NewEmail: "a@test.com", VerifyCode: "123456"})
 		require.Nil(t, resp)
 		requireUserSvcStatus(t, err, codes.AlreadyExists, consts.CodeEmailAlreadyExist)
@@ -363,7 +363,7 @@ func TestUserServiceChangePasswordAndEmail(t *testing.T) {
 			verifyVerifyCodeFn: func(_ context.Context, _, _ string, _ int32) (bool, error) {
 				return false, repository.ErrRedisNil
 			},
-		}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcDeviceRepo{}, nil)
 		resp, err := svc.ChangeEmail(userSvcCtx("u1"), &pb.ChangeEmailRequest{NewEmail: "a@test.com", VerifyCode: "123456"})
 		require.Nil(t, resp)
 		requireUserSvcStatus(t, err, codes.Unauthenticated, consts.CodeVerifyCodeExpire)
@@ -390,7 +390,7 @@ func TestUserServiceChangePasswordAndEmail(t *testing.T) {
 			deleteVerifyCodeFn: func(_ context.Context, _ string, _ int32) error {
 				return errors.New("delete code failed")
 			},
-		}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcDeviceRepo{}, nil)
 		resp, err := svc.ChangeEmail(userSvcCtx("u1"), &pb.ChangeEmailRequest{NewEmail: "a@test.com", VerifyCode: "123456"})
 		require.NoError(t, err)
 		require.NotNil(t, resp)
@@ -407,7 +407,7 @@ func TestUserServiceQRCodeDeleteAndBatch(t *testing.T) {
 			getQRCodeByUserUUIDFn: func(_ context.Context, _ string) (string, time.Time, error) {
 				return "tk1", expireAt, nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 		resp, err := svc.GetQRCode(userSvcCtx("u1"), &pb.GetQRCodeRequest{})
 		require.NoError(t, err)
 		require.NotNil(t, resp)
@@ -422,14 +422,14 @@ func TestUserServiceQRCodeDeleteAndBatch(t *testing.T) {
 			saveQRCodeFn: func(_ context.Context, _, _ string) error {
 				return errors.New("save failed")
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 		resp, err := svc.GetQRCode(userSvcCtx("u1"), &pb.GetQRCodeRequest{})
 		require.Nil(t, resp)
 		requireUserSvcStatus(t, err, codes.Internal, consts.CodeInternalError)
 	})
 
 	t.Run("parse_qrcode_empty_or_expired_or_success", func(t *testing.T) {
-		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		svc := NewUserService(&fakeUserSvcRepo{}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 		resp1, err1 := svc.ParseQRCode(context.Background(), &pb.ParseQRCodeRequest{})
 		require.Nil(t, resp1)
 		requireUserSvcStatus(t, err1, codes.InvalidArgument, consts.CodeQRCodeFormatError)
@@ -438,7 +438,7 @@ func TestUserServiceQRCodeDeleteAndBatch(t *testing.T) {
 			getUUIDByQRCodeTokenFn: func(_ context.Context, _ string) (string, error) {
 				return "", repository.ErrRedisNil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 		resp2, err2 := svcExpired.ParseQRCode(context.Background(), &pb.ParseQRCodeRequest{Token: "tk1"})
 		require.Nil(t, resp2)
 		requireUserSvcStatus(t, err2, codes.NotFound, consts.CodeQRCodeExpired)
@@ -448,7 +448,7 @@ func TestUserServiceQRCodeDeleteAndBatch(t *testing.T) {
 				require.Equal(t, "tk1", token)
 				return "u1", nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 		resp3, err3 := svcOK.ParseQRCode(context.Background(), &pb.ParseQRCodeRequest{Token: "tk1"})
 		require.NoError(t, err3)
 		require.NotNil(t, resp3)
@@ -457,11 +457,12 @@ func TestUserServiceQRCodeDeleteAndBatch(t *testing.T) {
 
 	t.Run("delete_account_password_wrong_and_success", func(t *testing.T) {
 		hash := hashUserSvcPassword(t, "pass123456")
+		kicker := &fakeConnectKicker{}
 		svcWrong := NewUserService(&fakeUserSvcRepo{
 			getByUUIDFn: func(_ context.Context, _ string) (*model.UserInfo, error) {
 				return &model.UserInfo{Uuid: "u1", Password: hash}, nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, kicker)
 		respWrong, errWrong := svcWrong.DeleteAccount(userSvcCtx("u1"), &pb.DeleteAccountRequest{Password: "wrong"})
 		require.Nil(t, respWrong)
 		requireUserSvcStatus(t, errWrong, codes.Unauthenticated, consts.CodePasswordError)
@@ -474,11 +475,13 @@ func TestUserServiceQRCodeDeleteAndBatch(t *testing.T) {
 				require.Equal(t, "u1", userUUID)
 				return nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, kicker)
+		assert.Empty(t, kicker.users)
 		respOK, errOK := svcOK.DeleteAccount(userSvcCtx("u1"), &pb.DeleteAccountRequest{Password: "pass123456"})
 		require.NoError(t, errOK)
 		require.NotNil(t, respOK)
 		assert.NotEmpty(t, respOK.DeleteAt)
+		assert.Equal(t, []string{"u1/" + KickReasonAccountDeleted}, kicker.users)
 	})
 
 	t.Run("batch_get_profile_empty_too_many_success", func(t *testing.T) {
@@ -486,7 +489,7 @@ func TestUserServiceQRCodeDeleteAndBatch(t *testing.T) {
 			batchGetByUUIDsFn: func(_ context.Context, _ []string) ([]*model.UserInfo, error) {
 				return []*model.UserInfo{{Uuid: "u1", Nickname: "n1"}}, nil
 			},
-		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{})
+		}, &fakeUserSvcAuthRepo{}, &fakeUserSvcDeviceRepo{}, nil)
 
 		respEmpty, errEmpty := svc.BatchGetProfile(context.Background(), &pb.BatchGetProfileRequest{UserUuids: []string{}})
 		require.NoError(t, errEmpty)
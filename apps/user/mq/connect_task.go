@@ -0,0 +1,64 @@
+package mq
+
+import (
+	connectpb "ChatServer/apps/connect/pb"
+	"context"
+	"errors"
+	"time"
+)
+
+// ==================== connect 踢线任务 ====================
+
+// ConnectKick 踢线任务参数
+type ConnectKick struct {
+	UserUUID string `json:"user_uuid"`
+	DeviceID string `json:"device_id,omitempty"` // 为空表示用户全部在线设备
+	Reason   string `json:"reason"`
+	// IssuedAt 踢线指令签发时间（Unix 毫秒），只断开在此之前建立的连接；0 表示不限制
+	IssuedAt int64 `json:"issued_at,omitempty"`
+}
+
+// BuildConnectKickTask 构造一个 connect 踢线任务，deviceID 为空表示全部在线设备
+// issuedAt 为首次签发时间（Unix 毫秒），重试时沿用原值，避免延迟投递误伤之后建立的新连接
+func BuildConnectKickTask(userUUID, deviceID, reason string, issuedAt int64) RedisTask {
+	return RedisTask{
+		Type: CmdConnectKick,
+		Kick: &ConnectKick{
+			UserUUID: userUUID,
+			DeviceID: deviceID,
+			Reason:   reason,
+			IssuedAt: issuedAt,
+		},
+		Timestamp:  time.Now(),
+		RetryCount: 0,
+		MaxRetries: 3,
+	}
+}
+
+// ExecuteConnectKick 调用 connect.KickConnection 断开连接
+// deviceID 为空时先查询用户在线设备再逐个断开；目标不在线视为成功
+// 携带签发时间，connect 只断开签发前建立的连接
+func ExecuteConnectKick(ctx context.Context, client connectpb.ConnectServiceClient, kick ConnectKick) error {
+	deviceIDs := []string{kick.DeviceID}
+	if kick.DeviceID == "" {
+		resp, err := client.GetOnlineStatus(ctx, &connectpb.GetOnlineStatusRequest{UserUuid: kick.UserUUID})
+		if err != nil {
+			return err
+		}
+		deviceIDs = resp.GetOnlineDevices()
+	}
+
+	var errs []error
+	for _, deviceID := range deviceIDs {
+		_, err := client.KickConnection(ctx, &connectpb.KickConnectionRequest{
+			UserUuid:     kick.UserUUID,
+			DeviceId:     deviceID,
+			Reason:       kick.Reason,
+			IssuedBefore: kick.IssuedAt,
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
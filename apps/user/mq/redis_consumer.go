@@ -1,10 +1,12 @@
 package mq
 
 import (
+	connectpb "ChatServer/apps/connect/pb"
 	"ChatServer/pkg/kafka"
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 
 	"github.com/redis/go-redis/v9"
 )
@@ -17,6 +19,10 @@ type RedisRetryConsumer struct {
 	redisClient *redis.Client
 	producer    *kafka.Producer
 	logger      kafka.Logger
+
+	// connectClient 执行 connect 踢线任务，为 nil 时该类任务按失败处理
+	connectClient connectpb.ConnectServiceClient
+	connectMu     sync.RWMutex
 }
 
 // NewRedisRetryConsumer 创建 Redis 重试队列消费者
@@ -37,6 +43,14 @@ func NewRedisRetryConsumer(
 	}
 }
 
+// SetConnectClient 设置执行 connect 踢线任务的客户端
+// 可在消费者启动后调用（connect 客户端晚于 Kafka 初始化）
+func (c *RedisRetryConsumer) SetConnectClient(client connectpb.ConnectServiceClient) {
+	c.connectMu.Lock()
+	defer c.connectMu.Unlock()
+	c.connectClient = client
+}
+
 // Start 启动消费者（阻塞式运行）
 func (c *RedisRetryConsumer) Start(ctx context.Context) error {
 	c.logger.Info(ctx, "Redis 重试队列消费者启动", nil)
@@ -111,6 +125,8 @@ func (c *RedisRetryConsumer) executeRedisTask(ctx context.Context, task RedisTas
 		return c.executePipeline(ctx, task)
 	case CmdLua:
 		return c.executeLuaScript(ctx, task)
+	case CmdConnectKick:
+		return c.executeConnectKick(ctx, task)
 	default:
 		return fmt.Errorf("未知的命令类型: %s", task.Type)
 	}
@@ -146,3 +162,17 @@ func (c *RedisRetryConsumer) executeLuaScript(ctx context.Context, task RedisTas
 	cmd := script.Run(ctx, c.redisClient, task.LuaKeys, task.LuaArgs...)
 	return cmd.Err()
 }
+
+// executeConnectKick 执行 connect 踢线
+func (c *RedisRetryConsumer) executeConnectKick(ctx context.Context, task RedisTask) error {
+	if task.Kick == nil {
+		return fmt.Errorf("踢线任务缺少参数")
+	}
+	c.connectMu.RLock()
+	client := c.connectClient
+	c.connectMu.RUnlock()
+	if client == nil {
+		return fmt.Errorf("connect 客户端未配置")
+	}
+	return ExecuteConnectKick(ctx, client, *task.Kick)
+}
@@ -14,6 +14,8 @@ const (
 	CmdSimple   CommandType = "simple"   // Set, Del, HSet...
 	CmdPipeline CommandType = "pipeline" // 批量操作
 	CmdLua      CommandType = "lua"      // Lua 脚本
+	// CmdConnectKick connect 踢线（非 Redis 命令，复用同一重试队列）
+	CmdConnectKick CommandType = "connect_kick"
 )
 
 // RedisTask 存放在 Kafka 里的消息体
@@ -32,6 +34,9 @@ type RedisTask struct {
 	LuaKeys   []string      `json:"lua_keys,omitempty"`
 	LuaArgs   []interface{} `json:"lua_args,omitempty"`
 
+	// 场景 4: connect 踢线（connect 不可达时重试）
+	Kick *ConnectKick `json:"kick,omitempty"`
+
 	// 元数据（用于追踪和重试控制）
 	TraceID     string    `json:"trace_id,omitempty"`
 	UserUUID    string    `json:"user_uuid,omitempty"`
@@ -0,0 +1,29 @@
+package config
+
+import "time"
+
+// ConnectAckConfig connect 下行 ack_required 消息的回执与重传配置。
+type ConnectAckConfig struct {
+	// WindowSize 单连接待回执窗口上限（<=0 关闭回执跟踪）。
+	WindowSize int `json:"windowSize" yaml:"windowSize"`
+	// Timeout 首次等待回执超时。
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+	// MaxBackoff 重传间隔上限。
+	MaxBackoff time.Duration `json:"maxBackoff" yaml:"maxBackoff"`
+	// MaxRetries 最大重传次数，耗尽后断开连接。
+	MaxRetries int `json:"maxRetries" yaml:"maxRetries"`
+}
+
+// DefaultConnectAckConfig 返回默认配置（可通过环境变量覆盖）。
+// - CONNECT_ACK_WINDOW_SIZE: 待回执窗口（默认 128）
+// - CONNECT_ACK_TIMEOUT_MS: 首次回执超时毫秒（默认 5000）
+// - CONNECT_ACK_MAX_BACKOFF_MS: 重传间隔上限毫秒（默认 30000）
+// - CONNECT_ACK_MAX_RETRIES: 最大重传次数（默认 3）
+func DefaultConnectAckConfig() ConnectAckConfig {
+	return ConnectAckConfig{
+		WindowSize: getenvInt("CONNECT_ACK_WINDOW_SIZE", 128),
+		Timeout:    time.Duration(getenvInt("CONNECT_ACK_TIMEOUT_MS", 5000)) * time.Millisecond,
+		MaxBackoff: time.Duration(getenvInt("CONNECT_ACK_MAX_BACKOFF_MS", 30000)) * time.Millisecond,
+		MaxRetries: getenvInt("CONNECT_ACK_MAX_RETRIES", 3),
+	}
+}
@@ -0,0 +1,27 @@
+package config
+
+// ConnectAdminConfig connect 运维接口配置。
+type ConnectAdminConfig struct {
+	// Addr 运维 HTTP 监听地址，应只在内网暴露。
+	Addr string `json:"addr" yaml:"addr"`
+	// Token 运维接口鉴权令牌（Authorization: Bearer <token>），为空时不启动运维接口。
+	Token string `json:"-" yaml:"-"`
+	// DrainEndpoint 排空时建议客户端重连的备用接入地址（可被请求参数覆盖）。
+	DrainEndpoint string `json:"drainEndpoint" yaml:"drainEndpoint"`
+	// DrainRate 排空时每秒关闭的连接数（可被请求参数覆盖）。
+	DrainRate int `json:"drainRate" yaml:"drainRate"`
+}
+
+// DefaultConnectAdminConfig 返回默认配置（可通过环境变量覆盖）。
+// - CONNECT_ADMIN_ADDR: 运维接口监听地址（默认 127.0.0.1:8082）
+// - CONNECT_ADMIN_TOKEN: 运维接口令牌（默认空，不启动运维接口）
+// - CONNECT_DRAIN_ENDPOINT: 排空时下发的备用接入地址（默认空）
+// - CONNECT_DRAIN_RATE: 排空时每秒关闭的连接数（默认 100，上限 10000）
+func DefaultConnectAdminConfig() ConnectAdminConfig {
+	return ConnectAdminConfig{
+		Addr:          getenvString("CONNECT_ADMIN_ADDR", "127.0.0.1:8082"),
+		Token:         getenvString("CONNECT_ADMIN_TOKEN", ""),
+		DrainEndpoint: getenvString("CONNECT_DRAIN_ENDPOINT", ""),
+		DrainRate:     getenvInt("CONNECT_DRAIN_RATE", 100),
+	}
+}
@@ -0,0 +1,29 @@
+package config
+
+import "time"
+
+// ConnectAuthConfig connect 握手与连接存活期间的登录态检查配置。
+type ConnectAuthConfig struct {
+	// ExpiringLead 距 access token 过期多久时下发 auth_expiring。
+	ExpiringLead time.Duration `json:"expiringLead" yaml:"expiringLead"`
+	// RevokeCheckInterval 登录态吊销检查周期（<=0 关闭）。
+	RevokeCheckInterval time.Duration `json:"revokeCheckInterval" yaml:"revokeCheckInterval"`
+	// AllowedOrigins /ws、/sse 握手允许的 Origin 白名单，支持 https://*.example.com 通配子域名；为空时不校验。
+	AllowedOrigins []string `json:"allowedOrigins" yaml:"allowedOrigins"`
+	// AllowQueryToken 是否允许通过 ?token= 传递 access token（会进入代理/访问日志，生产建议关闭）。
+	AllowQueryToken bool `json:"allowQueryToken" yaml:"allowQueryToken"`
+}
+
+// DefaultConnectAuthConfig 返回默认配置（可通过环境变量覆盖）。
+// - CONNECT_AUTH_EXPIRING_LEAD_SECONDS: 过期提醒提前量（默认 300）
+// - CONNECT_AUTH_REVOKE_CHECK_SECONDS: 吊销检查周期（默认 60，0 关闭）
+// - CONNECT_WS_ALLOWED_ORIGINS: 握手 Origin 白名单，逗号分隔（默认空，不校验；未携带 Origin 的原生客户端始终放行）
+// - CONNECT_AUTH_ALLOW_QUERY_TOKEN: 是否允许 query 传 token（默认 true，兼容旧客户端）
+func DefaultConnectAuthConfig() ConnectAuthConfig {
+	return ConnectAuthConfig{
+		ExpiringLead:        time.Duration(getenvInt("CONNECT_AUTH_EXPIRING_LEAD_SECONDS", 300)) * time.Second,
+		RevokeCheckInterval: time.Duration(getenvInt("CONNECT_AUTH_REVOKE_CHECK_SECONDS", 60)) * time.Second,
+		AllowedOrigins:      splitCSV(getenvString("CONNECT_WS_ALLOWED_ORIGINS", "")),
+		AllowQueryToken:     getenvBool("CONNECT_AUTH_ALLOW_QUERY_TOKEN", true),
+	}
+}
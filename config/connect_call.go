@@ -0,0 +1,33 @@
+package config
+
+import "time"
+
+// ConnectCallConfig connect 1:1 通话信令配置。
+type ConnectCallConfig struct {
+	// Enabled 是否启用 call_* 通话信令。
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// RingTimeout 振铃超时，超时未接听记为未接。
+	RingTimeout time.Duration `json:"ringTimeout" yaml:"ringTimeout"`
+	// DisconnectGrace 参与设备断线后等待重连的宽限期。
+	DisconnectGrace time.Duration `json:"disconnectGrace" yaml:"disconnectGrace"`
+	// StateTTL 通话状态 TTL（由参与设备心跳续期）。
+	StateTTL time.Duration `json:"stateTTL" yaml:"stateTTL"`
+	// MsgGRPCAddr msg 服务地址，用于写入通话记录消息，为空时不写记录。
+	MsgGRPCAddr string `json:"msgGrpcAddr" yaml:"msgGrpcAddr"`
+}
+
+// DefaultConnectCallConfig 返回默认配置（可通过环境变量覆盖）。
+// - CONNECT_CALL_ENABLED: 是否启用（默认 true）
+// - CONNECT_CALL_RING_TIMEOUT_SECONDS: 振铃超时秒数（默认 45）
+// - CONNECT_CALL_DISCONNECT_GRACE_SECONDS: 断线宽限秒数（默认 15）
+// - CONNECT_CALL_STATE_TTL_SECONDS: 通话状态 TTL 秒数（默认 120）
+// - MSG_GRPC_ADDR: msg 服务 gRPC 地址（默认空，不写通话记录）
+func DefaultConnectCallConfig() ConnectCallConfig {
+	return ConnectCallConfig{
+		Enabled:         getenvBool("CONNECT_CALL_ENABLED", true),
+		RingTimeout:     time.Duration(getenvInt("CONNECT_CALL_RING_TIMEOUT_SECONDS", 45)) * time.Second,
+		DisconnectGrace: time.Duration(getenvInt("CONNECT_CALL_DISCONNECT_GRACE_SECONDS", 15)) * time.Second,
+		StateTTL:        time.Duration(getenvInt("CONNECT_CALL_STATE_TTL_SECONDS", 120)) * time.Second,
+		MsgGRPCAddr:     getenvString("MSG_GRPC_ADDR", ""),
+	}
+}
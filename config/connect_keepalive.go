@@ -0,0 +1,51 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConnectKeepaliveConfig connect 连接保活配置。
+type ConnectKeepaliveConfig struct {
+	// PingPeriod 协议层 Ping 周期。
+	PingPeriod time.Duration `json:"pingPeriod" yaml:"pingPeriod"`
+	// PongWait 读超时窗口，超过该时间未收到 Pong 或上行帧判定为半开连接。
+	PongWait time.Duration `json:"pongWait" yaml:"pongWait"`
+	// HeartbeatInterval 默认推荐的应用层心跳间隔（hello 帧下发）。
+	HeartbeatInterval time.Duration `json:"heartbeatInterval" yaml:"heartbeatInterval"`
+	// PlatformHeartbeatIntervals 按平台（小写）覆盖的推荐心跳间隔。
+	PlatformHeartbeatIntervals map[string]time.Duration `json:"platformHeartbeatIntervals" yaml:"platformHeartbeatIntervals"`
+	// HeartbeatMissedLimit 连续错过多少次心跳断开连接（<=0 不检查）。
+	HeartbeatMissedLimit int `json:"heartbeatMissedLimit" yaml:"heartbeatMissedLimit"`
+}
+
+// DefaultConnectKeepaliveConfig 返回默认配置（可通过环境变量覆盖）。
+// - CONNECT_WS_PING_PERIOD_SECONDS: 协议层 Ping 周期（默认 25）
+// - CONNECT_WS_PONG_WAIT_SECONDS: 读超时窗口（默认 40，需大于 Ping 周期）
+// - CONNECT_HEARTBEAT_INTERVAL_SECONDS: 默认推荐心跳间隔（默认 30）
+// - CONNECT_HEARTBEAT_PLATFORM_INTERVALS: 按平台的推荐心跳间隔（秒），格式 platform=seconds,platform=seconds
+// （默认 ios=40,android=40,web=30,windows=60,mac=60）
+// - CONNECT_HEARTBEAT_MISSED_LIMIT: 连续错过多少次心跳断开（默认 3，0 表示不检查）
+func DefaultConnectKeepaliveConfig() ConnectKeepaliveConfig {
+	return ConnectKeepaliveConfig{
+		PingPeriod:                 time.Duration(getenvInt("CONNECT_WS_PING_PERIOD_SECONDS", 25)) * time.Second,
+		PongWait:                   time.Duration(getenvInt("CONNECT_WS_PONG_WAIT_SECONDS", 40)) * time.Second,
+		HeartbeatInterval:          time.Duration(getenvInt("CONNECT_HEARTBEAT_INTERVAL_SECONDS", 30)) * time.Second,
+		PlatformHeartbeatIntervals: parseSecondsCSV(getenvString("CONNECT_HEARTBEAT_PLATFORM_INTERVALS", "ios=40,android=40,web=30,windows=60,mac=60")),
+		HeartbeatMissedLimit:       getenvInt("CONNECT_HEARTBEAT_MISSED_LIMIT", 3),
+	}
+}
+
+// parseSecondsCSV 解析 key=seconds,key=seconds 格式，key 转小写，忽略非正数的项。
+func parseSecondsCSV(value string) map[string]time.Duration {
+	result := make(map[string]time.Duration)
+	for key, val := range parseKeyValueCSV(value) {
+		seconds, err := strconv.Atoi(val)
+		if err != nil || seconds <= 0 {
+			continue
+		}
+		result[strings.ToLower(key)] = time.Duration(seconds) * time.Second
+	}
+	return result
+}
@@ -0,0 +1,25 @@
+package config
+
+import "time"
+
+// ConnectPresenceConfig connect 好友在线状态推送配置。
+type ConnectPresenceConfig struct {
+	// Enabled 是否推送好友在线状态（需要 Redis 与集群路由）。
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// OnlineDelay 首个设备上线后延迟多久推送上线。
+	OnlineDelay time.Duration `json:"onlineDelay" yaml:"onlineDelay"`
+	// OfflineGrace 最后一个设备下线后等待多久仍未重连才推送下线。
+	OfflineGrace time.Duration `json:"offlineGrace" yaml:"offlineGrace"`
+}
+
+// DefaultConnectPresenceConfig 返回默认配置（可通过环境变量覆盖）。
+// - CONNECT_PRESENCE_ENABLED: 是否启用（默认 true）
+// - CONNECT_PRESENCE_ONLINE_DELAY_MS: 上线推送延迟毫秒数（默认 2000）
+// - CONNECT_PRESENCE_OFFLINE_GRACE_SECONDS: 下线宽限秒数（默认 30）
+func DefaultConnectPresenceConfig() ConnectPresenceConfig {
+	return ConnectPresenceConfig{
+		Enabled:      getenvBool("CONNECT_PRESENCE_ENABLED", true),
+		OnlineDelay:  time.Duration(getenvInt("CONNECT_PRESENCE_ONLINE_DELAY_MS", 2000)) * time.Millisecond,
+		OfflineGrace: time.Duration(getenvInt("CONNECT_PRESENCE_OFFLINE_GRACE_SECONDS", 30)) * time.Second,
+	}
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	rediskey "ChatServer/consts/redisKey"
+	"time"
+)
+
+// ConnectRouteConfig connect 集群路由配置。
+type ConnectRouteConfig struct {
+	// NodeAddr 本节点对集群内其他服务暴露的 gRPC 地址（host:port）。
+	NodeAddr string `json:"nodeAddr" yaml:"nodeAddr"`
+	// RouteTTL 连接路由过期时间，由连接心跳续期。
+	RouteTTL time.Duration `json:"routeTTL" yaml:"routeTTL"`
+	// NodeTTL 节点存活 Key 过期时间，超过该时间未续期视为节点宕机。
+	NodeTTL time.Duration `json:"nodeTTL" yaml:"nodeTTL"`
+	// NodeHeartbeatInterval 节点存活 Key 续期周期。
+	NodeHeartbeatInterval time.Duration `json:"nodeHeartbeatInterval" yaml:"nodeHeartbeatInterval"`
+	// OutboxMaxEntries 下行补发缓冲单 Key 最多保留的消息数。
+	OutboxMaxEntries int `json:"outboxMaxEntries" yaml:"outboxMaxEntries"`
+	// OutboxTTL 下行补发缓冲过期时间（断线重连可补发的窗口）。
+	OutboxTTL time.Duration `json:"outboxTTL" yaml:"outboxTTL"`
+}
+
+// DefaultConnectRouteConfig 返回默认配置（可通过环境变量覆盖）。
+// - CONNECT_NODE_ADDR: 本节点 gRPC 地址（默认空，由 connect 启动时用 hostname + gRPC 端口推导）
+// - CONNECT_ROUTE_TTL_SECONDS: 路由过期秒数（默认 180）
+// - CONNECT_NODE_TTL_SECONDS: 节点存活秒数（默认 30）
+// - CONNECT_NODE_HEARTBEAT_SECONDS: 节点续期周期秒数（默认 10）
+// - CONNECT_OUTBOX_MAX_ENTRIES: 补发缓冲容量（默认 200）
+// - CONNECT_OUTBOX_TTL_SECONDS: 补发缓冲过期秒数（默认 300）
+func DefaultConnectRouteConfig() ConnectRouteConfig {
+	return ConnectRouteConfig{
+		NodeAddr:              getenvString("CONNECT_NODE_ADDR", ""),
+		RouteTTL:              time.Duration(getenvInt("CONNECT_ROUTE_TTL_SECONDS", int(rediskey.ConnectRouteTTL/time.Second))) * time.Second,
+		NodeTTL:               time.Duration(getenvInt("CONNECT_NODE_TTL_SECONDS", int(rediskey.ConnectNodeTTL/time.Second))) * time.Second,
+		NodeHeartbeatInterval: time.Duration(getenvInt("CONNECT_NODE_HEARTBEAT_SECONDS", 10)) * time.Second,
+		OutboxMaxEntries:      getenvInt("CONNECT_OUTBOX_MAX_ENTRIES", 200),
+		OutboxTTL:             time.Duration(getenvInt("CONNECT_OUTBOX_TTL_SECONDS", int(rediskey.ConnectOutboxTTL/time.Second))) * time.Second,
+	}
+}
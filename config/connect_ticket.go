@@ -0,0 +1,21 @@
+package config
+
+import "time"
+
+// ConnectTicketConfig gateway 签发 WebSocket 一次性接入票据的配置。
+type ConnectTicketConfig struct {
+	// TTL 票据有效期，客户端应在拿到票据后立即建连。
+	TTL time.Duration `json:"ttl" yaml:"ttl"`
+	// BindIP 是否将票据绑定到签发时的客户端 IP（gateway 与 connect 看到的客户端 IP 不一致时需关闭）。
+	BindIP bool `json:"bindIP" yaml:"bindIP"`
+}
+
+// DefaultConnectTicketConfig 返回默认配置（可通过环境变量覆盖）。
+// - CONNECT_TICKET_TTL_SECONDS: 票据有效期（默认 30）
+// - CONNECT_TICKET_BIND_IP: 是否绑定客户端 IP（默认 true）
+func DefaultConnectTicketConfig() ConnectTicketConfig {
+	return ConnectTicketConfig{
+		TTL:    time.Duration(getenvInt("CONNECT_TICKET_TTL_SECONDS", 30)) * time.Second,
+		BindIP: getenvBool("CONNECT_TICKET_BIND_IP", true),
+	}
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConnectWSConfig connect 单连接 WebSocket 参数。
+type ConnectWSConfig struct {
+	// SendQueueSize 单连接写队列容量（帧数）。
+	SendQueueSize int `json:"sendQueueSize" yaml:"sendQueueSize"`
+	// SlowConsumerPolicies 消息类型 -> 写队列满时的策略（drop_oldest/coalesce/spill/disconnect）。
+	SlowConsumerPolicies map[string]string `json:"slowConsumerPolicies" yaml:"slowConsumerPolicies"`
+	// SlowConsumerDefaultPolicy 未配置类型的默认策略。
+	SlowConsumerDefaultPolicy string `json:"slowConsumerDefaultPolicy" yaml:"slowConsumerDefaultPolicy"`
+	// Compression 是否协商 permessage-deflate 压缩。
+	Compression bool `json:"compression" yaml:"compression"`
+	// CompressionLevel flate 压缩级别（-2~9）。
+	CompressionLevel int `json:"compressionLevel" yaml:"compressionLevel"`
+	// CompressionThreshold 压缩阈值（字节），小于该值的帧不压缩。
+	CompressionThreshold int `json:"compressionThreshold" yaml:"compressionThreshold"`
+	// Batch 是否允许向声明 batch=1 的客户端下发批量帧。
+	Batch bool `json:"batch" yaml:"batch"`
+	// MaxMessageBytes 单条上行消息大小上限（字节）。
+	MaxMessageBytes int `json:"maxMessageBytes" yaml:"maxMessageBytes"`
+	// MaxTopics 单连接可显式订阅的主题数上限。
+	MaxTopics int `json:"maxTopics" yaml:"maxTopics"`
+	// UplinkLimits 单连接按帧类型的上行限速，type -> 令牌桶参数。
+	UplinkLimits map[string]RateLimitSpec `json:"uplinkLimits" yaml:"uplinkLimits"`
+	// UplinkUserLimits 单用户（本节点全部连接合计）按帧类型的上行限速。
+	UplinkUserLimits map[string]RateLimitSpec `json:"uplinkUserLimits" yaml:"uplinkUserLimits"`
+	// UplinkViolationWindow 上行超限计数窗口。
+	UplinkViolationWindow time.Duration `json:"uplinkViolationWindow" yaml:"uplinkViolationWindow"`
+	// UplinkCloseAfter 窗口内超限次数达到该值时临时断开（<=0 不断开）。
+	UplinkCloseAfter int `json:"uplinkCloseAfter" yaml:"uplinkCloseAfter"`
+	// UplinkBanAfter 封禁窗口内被断开次数达到该值时临时封禁（<=0 不封禁）。
+	UplinkBanAfter int `json:"uplinkBanAfter" yaml:"uplinkBanAfter"`
+	// UplinkBanWindow 断开次数计数窗口。
+	UplinkBanWindow time.Duration `json:"uplinkBanWindow" yaml:"uplinkBanWindow"`
+	// UplinkBanDuration 临时封禁时长。
+	UplinkBanDuration time.Duration `json:"uplinkBanDuration" yaml:"uplinkBanDuration"`
+	// UplinkBanByIP 封禁时是否同时封禁客户端 IP。
+	UplinkBanByIP bool `json:"uplinkBanByIP" yaml:"uplinkBanByIP"`
+}
+
+// RateLimitSpec 令牌桶参数：每秒补充 Rate 个令牌，桶容量 Burst。
+type RateLimitSpec struct {
+	Rate  float64 `json:"rate" yaml:"rate"`
+	Burst int     `json:"burst" yaml:"burst"`
+}
+
+// DefaultConnectWSConfig 返回默认配置（可通过环境变量覆盖）。
+// - CONNECT_WS_SEND_QUEUE_SIZE: 写队列容量（默认 64）
+// - CONNECT_WS_SLOW_CONSUMER_POLICIES: 按类型的策略，格式 type=policy,type=policy
+// （默认 typing=drop_oldest,presence=drop_oldest,unread_count=coalesce）
+// - CONNECT_WS_SLOW_CONSUMER_DEFAULT_POLICY: 默认策略（默认 spill）
+// - CONNECT_WS_COMPRESSION: 是否协商 permessage-deflate（默认 true）
+// - CONNECT_WS_COMPRESSION_LEVEL: 压缩级别（默认 1，BestSpeed）
+// - CONNECT_WS_COMPRESSION_THRESHOLD_BYTES: 压缩阈值（默认 512）
+// - CONNECT_WS_BATCH: 是否允许批量帧（默认 true）
+// - CONNECT_WS_MAX_MESSAGE_BYTES: 单条上行消息大小上限（默认 1048576）
+// - CONNECT_WS_MAX_TOPICS: 单连接可显式订阅的主题数上限（默认 32，不含自动加入的主题）
+// - CONNECT_WS_UPLINK_LIMITS: 单连接按类型限速，格式 type=rate:burst,type=rate:burst，未配置类型使用 default
+// （默认 heartbeat=1:5,typing=3:10,message=20:40,client_ack=50:100,call_ice=20:60,default=10:20）
+// - CONNECT_WS_UPLINK_USER_LIMITS: 单用户按类型限速，格式同上（默认 message=50:100,typing=10:20,client_ack=100:200,default=30:60）
+// （两项均不含有效配置（如 off）时关闭上行限速）
+// - CONNECT_WS_UPLINK_VIOLATION_WINDOW_SECONDS: 超限计数窗口（默认 60）
+// - CONNECT_WS_UPLINK_CLOSE_AFTER: 窗口内超限多少次后临时断开（默认 20）
+// - CONNECT_WS_UPLINK_BAN_AFTER: 封禁窗口内被断开多少次后临时封禁（默认 3）
+// - CONNECT_WS_UPLINK_BAN_WINDOW_SECONDS: 断开次数计数窗口（默认 600）
+// - CONNECT_WS_UPLINK_BAN_SECONDS: 封禁时长（默认 600）
+// - CONNECT_WS_UPLINK_BAN_BY_IP: 是否同时封禁 IP（默认 false）
+func DefaultConnectWSConfig() ConnectWSConfig {
+	return ConnectWSConfig{
+		SendQueueSize:             getenvInt("CONNECT_WS_SEND_QUEUE_SIZE", 64),
+		SlowConsumerPolicies:      parseKeyValueCSV(getenvString("CONNECT_WS_SLOW_CONSUMER_POLICIES", "typing=drop_oldest,presence=drop_oldest,unread_count=coalesce")),
+		SlowConsumerDefaultPolicy: getenvString("CONNECT_WS_SLOW_CONSUMER_DEFAULT_POLICY", "spill"),
+		Compression:               getenvBool("CONNECT_WS_COMPRESSION", true),
+		CompressionLevel:          getenvInt("CONNECT_WS_COMPRESSION_LEVEL", 1),
+		CompressionThreshold:      getenvInt("CONNECT_WS_COMPRESSION_THRESHOLD_BYTES", 512),
+		Batch:                     getenvBool("CONNECT_WS_BATCH", true),
+		MaxMessageBytes:           getenvInt("CONNECT_WS_MAX_MESSAGE_BYTES", 1<<20),
+		MaxTopics:                 getenvInt("CONNECT_WS_MAX_TOPICS", 32),
+		UplinkLimits:              parseRateLimitCSV(getenvString("CONNECT_WS_UPLINK_LIMITS", "heartbeat=1:5,typing=3:10,message=20:40,client_ack=50:100,call_ice=20:60,default=10:20")),
+		UplinkUserLimits:          parseRateLimitCSV(getenvString("CONNECT_WS_UPLINK_USER_LIMITS", "message=50:100,typing=10:20,client_ack=100:200,default=30:60")),
+		UplinkViolationWindow:     time.Duration(getenvInt("CONNECT_WS_UPLINK_VIOLATION_WINDOW_SECONDS", 60)) * time.Second,
+		UplinkCloseAfter:          getenvInt("CONNECT_WS_UPLINK_CLOSE_AFTER", 20),
+		UplinkBanAfter:            getenvInt("CONNECT_WS_UPLINK_BAN_AFTER", 3),
+		UplinkBanWindow:           time.Duration(getenvInt("CONNECT_WS_UPLINK_BAN_WINDOW_SECONDS", 600)) * time.Second,
+		UplinkBanDuration:         time.Duration(getenvInt("CONNECT_WS_UPLINK_BAN_SECONDS", 600)) * time.Second,
+		UplinkBanByIP:             getenvBool("CONNECT_WS_UPLINK_BAN_BY_IP", false),
+	}
+}
+
+// parseRateLimitCSV 解析 type=rate:burst,type=rate:burst 格式，忽略格式非法或非正数的项。
+func parseRateLimitCSV(value string) map[string]RateLimitSpec {
+	result := make(map[string]RateLimitSpec)
+	for key, val := range parseKeyValueCSV(value) {
+		rateText, burstText, ok := strings.Cut(val, ":")
+		if !ok {
+			continue
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rateText), 64)
+		if err != nil || rate <= 0 {
+			continue
+		}
+		burst, err := strconv.Atoi(strings.TrimSpace(burstText))
+		if err != nil || burst <= 0 {
+			continue
+		}
+		result[key] = RateLimitSpec{Rate: rate, Burst: burst}
+	}
+	return result
+}
+
+// parseKeyValueCSV 解析 k=v,k=v 格式，忽略缺少 "=" 或 key 为空的项。
+func parseKeyValueCSV(value string) map[string]string {
+	result := make(map[string]string)
+	for _, item := range splitCSV(value) {
+		key, val, ok := strings.Cut(item, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		result[key] = strings.TrimSpace(val)
+	}
+	return result
+}
@@ -0,0 +1,34 @@
+package config
+
+import "ChatServer/pkg/fanout"
+
+// FanoutConfig 群消息扩散配置（msg 服务使用）。
+type FanoutConfig struct {
+	// ReadDiffusionThreshold 成员数超过该值的群使用读扩散。
+	ReadDiffusionThreshold int `json:"readDiffusionThreshold" yaml:"readDiffusionThreshold"`
+	// StoreBatchSize 写扩散单批更新的成员会话数。
+	StoreBatchSize int `json:"storeBatchSize" yaml:"storeBatchSize"`
+	// BroadcastChunkSize BroadcastToUsers 单批用户数（上限 1000）。
+	BroadcastChunkSize int `json:"broadcastChunkSize" yaml:"broadcastChunkSize"`
+}
+
+// DefaultFanoutConfig 返回默认配置（可通过环境变量覆盖）。
+// - GROUP_READ_DIFFUSION_THRESHOLD: 读扩散阈值（默认 2000）
+// - GROUP_FANOUT_STORE_BATCH_SIZE: 写扩散批量大小（默认 500）
+// - GROUP_FANOUT_BROADCAST_CHUNK_SIZE: 推送批量大小（默认 1000）
+func DefaultFanoutConfig() FanoutConfig {
+	return FanoutConfig{
+		ReadDiffusionThreshold: getenvInt("GROUP_READ_DIFFUSION_THRESHOLD", fanout.DefaultReadDiffusionThreshold),
+		StoreBatchSize:         getenvInt("GROUP_FANOUT_STORE_BATCH_SIZE", fanout.DefaultStoreBatchSize),
+		BroadcastChunkSize:     getenvInt("GROUP_FANOUT_BROADCAST_CHUNK_SIZE", fanout.MaxBroadcastUsers),
+	}
+}
+
+// Options 转换为扩散器配置。
+func (c FanoutConfig) Options() fanout.Options {
+	return fanout.Options{
+		ReadDiffusionThreshold: c.ReadDiffusionThreshold,
+		StoreBatchSize:         c.StoreBatchSize,
+		BroadcastChunkSize:     c.BroadcastChunkSize,
+	}
+}
@@ -36,11 +36,12 @@ CREATE TABLE IF NOT EXISTS `group_info` (
   `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '自增id',
   `uuid` CHAR(20) NOT NULL COMMENT '群组唯一id',
   `name` VARCHAR(64) NOT NULL COMMENT '群名称',
-  `notice` VARCHAR(500) DEFAULT NULL COMMENT '群公告',
+  `notice` VARCHAR(500) DEFAULT NULL COMMENT '群公告(已废弃,见 group_announcement)',
   `member_cnt` INT NOT NULL DEFAULT 1 COMMENT '群人数',
   `owner_uuid` CHAR(20) NOT NULL COMMENT '群主uuid',
   `add_mode` TINYINT NOT NULL DEFAULT 0 COMMENT '加群方式,0.直接 1.审核',
   `avatar` VARCHAR(255) NOT NULL DEFAULT 'https://cube.elemecdn.com/0/88/03b0d39583f48206768a7534e55bcpng.png' COMMENT '群头像URL',
+  `avatar_custom` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否为自定义头像',
   `status` TINYINT NOT NULL DEFAULT 0 COMMENT '状态,0.正常 1.禁用 2.解散',
   `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) COMMENT '创建时间',
   `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3) COMMENT '更新时间',
@@ -61,6 +62,9 @@ CREATE TABLE IF NOT EXISTS `group_member` (
   `status` TINYINT NOT NULL DEFAULT 0 COMMENT '0正常 1退出 2踢出 3待审核',
   `mute_until` DATETIME(3) DEFAULT NULL COMMENT '禁言到期时间',
   `inviter_uuid` CHAR(20) DEFAULT NULL COMMENT '邀请人uuid',
+  `save_to_contacts` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '保存到通讯录',
+  `hide_member_nickname` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '隐藏群成员昵称',
+  `mute_notify` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '消息免打扰',
   `joined_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) COMMENT '入群时间',
   `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) COMMENT '创建时间',
   `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3) COMMENT '更新时间',
@@ -72,6 +76,34 @@ CREATE TABLE IF NOT EXISTS `group_member` (
   KEY `idx_group_member_deleted_at` (`deleted_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='群成员关系';
 
+CREATE TABLE IF NOT EXISTS `group_announcement` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '自增id',
+  `uuid` CHAR(20) NOT NULL COMMENT '公告唯一id',
+  `group_uuid` CHAR(20) NOT NULL COMMENT '群uuid',
+  `author_uuid` CHAR(20) NOT NULL COMMENT '发布人uuid',
+  `editor_uuid` CHAR(20) DEFAULT NULL COMMENT '最后编辑人uuid',
+  `content` VARCHAR(2000) NOT NULL COMMENT '公告内容',
+  `pinned` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否置顶',
+  `require_ack` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否需要成员确认',
+  `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) COMMENT '创建时间',
+  `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3) COMMENT '更新时间',
+  `deleted_at` DATETIME(3) DEFAULT NULL COMMENT '删除时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_group_announcement_uuid` (`uuid`),
+  KEY `idx_group_pin_created` (`group_uuid`, `pinned`, `created_at`),
+  KEY `idx_group_announcement_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='群公告';
+
+CREATE TABLE IF NOT EXISTS `group_announcement_ack` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '自增id',
+  `announcement_uuid` CHAR(20) NOT NULL COMMENT '公告uuid',
+  `user_uuid` CHAR(20) NOT NULL COMMENT '确认人uuid',
+  `acked_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) COMMENT '确认时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uidx_announcement_user` (`announcement_uuid`, `user_uuid`),
+  KEY `idx_group_announcement_ack_user_uuid` (`user_uuid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='群公告确认记录';
+
 CREATE TABLE IF NOT EXISTS `user_relation` (
   `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '自增id',
   `user_uuid` CHAR(20) NOT NULL COMMENT '用户uuid',
@@ -125,6 +157,8 @@ CREATE TABLE IF NOT EXISTS `device_session` (
   `app_version` VARCHAR(32) DEFAULT NULL COMMENT 'APP版本',
   `ip` VARCHAR(64) DEFAULT NULL COMMENT '登录IP',
   `user_agent` VARCHAR(512) DEFAULT NULL COMMENT 'User Agent',
+  `push_provider` VARCHAR(32) NOT NULL DEFAULT '' COMMENT '推送通道(apns/fcm/huawei等)',
+  `push_token` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '推送token',
   `expire_at` DATETIME(3) DEFAULT NULL COMMENT '过期时间',
   `status` TINYINT NOT NULL DEFAULT 0 COMMENT '状态',
   `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
@@ -135,7 +169,25 @@ CREATE TABLE IF NOT EXISTS `device_session` (
   KEY `idx_device_expire_at` (`expire_at`),
   KEY `idx_device_deleted_at` (`deleted_at`),
   KEY `idx_device_user_updated` (`user_uuid`, `updated_at`, `id`),
-  KEY `idx_device_user_status_deleted` (`user_uuid`, `status`, `deleted_at`)
+  KEY `idx_device_user_status_deleted` (`user_uuid`, `status`, `deleted_at`),
+  KEY `idx_device_push_token` (`push_token`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='设备会话';
 
+CREATE TABLE IF NOT EXISTS `device_notification_setting` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `user_uuid` CHAR(20) NOT NULL COMMENT '用户uuid',
+  `device_id` VARCHAR(64) NOT NULL COMMENT '设备唯一指纹',
+  `push_enabled` TINYINT(1) NOT NULL DEFAULT 1 COMMENT '是否接收系统推送',
+  `quiet_hours_enabled` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否开启免打扰时段',
+  `quiet_start` SMALLINT NOT NULL DEFAULT 1320 COMMENT '免打扰开始(当天分钟数)',
+  `quiet_end` SMALLINT NOT NULL DEFAULT 480 COMMENT '免打扰结束(当天分钟数)',
+  `timezone` VARCHAR(64) NOT NULL DEFAULT '' COMMENT 'IANA时区(空为服务端时区)',
+  `show_preview` TINYINT(1) NOT NULL DEFAULT 1 COMMENT '是否展示消息预览',
+  `sound` TINYINT(1) NOT NULL DEFAULT 1 COMMENT '是否播放提示音',
+  `created_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+  `updated_at` DATETIME(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uidx_user_device_notify` (`user_uuid`, `device_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='设备通知偏好';
+
 SET FOREIGN_KEY_CHECKS = 1;
@@ -0,0 +1,45 @@
+package config
+
+import "time"
+
+// PushNotifyConfig 离线推送通知链路配置。
+// 推送方（user 服务的 connect 路由客户端）把未送达推送写入 Topic，通知消费者读取后经推送通道下发。
+type PushNotifyConfig struct {
+	// Enabled 是否启用离线通知（上报 + 消费）
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Topic 未送达事件 Kafka topic
+	Topic string `json:"topic" yaml:"topic"`
+	// GroupID 通知消费者组
+	GroupID string `json:"groupId" yaml:"groupId"`
+	// EnvelopeTypes 触发离线通知的信封类型
+	EnvelopeTypes []string `json:"envelopeTypes" yaml:"envelopeTypes"`
+	// Platforms 允许系统推送的设备平台（小写），其他平台的设备即使登记了 token 也不推送
+	Platforms []string `json:"platforms" yaml:"platforms"`
+	// CollapseWindow 同一会话的突发消息在窗口内只推送第一条
+	CollapseWindow time.Duration `json:"collapseWindow" yaml:"collapseWindow"`
+	// StubFile 本地桩：推送写入该文件（JSON Lines）
+	StubFile string `json:"stubFile" yaml:"stubFile"`
+	// StubURL 本地桩：推送 POST 到该地址，优先于 StubFile
+	StubURL string `json:"stubURL" yaml:"stubURL"`
+}
+
+// DefaultPushNotifyConfig 返回默认配置（可通过环境变量覆盖）。
+// - PUSH_NOTIFY_ENABLED: 是否启用（默认 false）
+// - KAFKA_PUSH_NOTIFY_TOPIC: 未送达事件 topic（默认 push-undelivered）
+// - KAFKA_PUSH_NOTIFY_GROUP_ID: 消费者组（默认 push-notify-consumer-group）
+// - PUSH_NOTIFY_ENVELOPE_TYPES: 触发通知的信封类型 CSV（默认 message）
+// - PUSH_NOTIFY_PLATFORMS: 允许推送的平台 CSV（默认 ios,android）
+// - PUSH_NOTIFY_COLLAPSE_SECONDS: 会话折叠窗口（默认 30）
+// - PUSH_NOTIFY_STUB_FILE / PUSH_NOTIFY_STUB_URL: 本地桩通道（默认均为空，未配置真实通道时推送被丢弃）
+func DefaultPushNotifyConfig() PushNotifyConfig {
+	return PushNotifyConfig{
+		Enabled:        getenvBool("PUSH_NOTIFY_ENABLED", false),
+		Topic:          getenvString("KAFKA_PUSH_NOTIFY_TOPIC", "push-undelivered"),
+		GroupID:        getenvString("KAFKA_PUSH_NOTIFY_GROUP_ID", "push-notify-consumer-group"),
+		EnvelopeTypes:  splitCSV(getenvString("PUSH_NOTIFY_ENVELOPE_TYPES", "message")),
+		Platforms:      splitCSV(getenvString("PUSH_NOTIFY_PLATFORMS", "ios,android")),
+		CollapseWindow: time.Duration(getenvInt("PUSH_NOTIFY_COLLAPSE_SECONDS", 30)) * time.Second,
+		StubFile:       getenvString("PUSH_NOTIFY_STUB_FILE", ""),
+		StubURL:        getenvString("PUSH_NOTIFY_STUB_URL", ""),
+	}
+}
@@ -174,6 +174,16 @@ const (
 	CodeCannotQuitAsOwner = 14014 // 群主不能退群
 	// 管理员数量已达上限
 	CodeAdminLimitExceeded = 14015 // 管理员数量已达上限
+	// 群名片过长
+	CodeGroupCardTooLong = 14016 // 群名片过长
+	// 群邀请已失效
+	CodeGroupInviteInvalid = 14017 // 群邀请已失效
+	// 群邀请使用次数已达上限
+	CodeGroupInviteExhausted = 14018 // 群邀请使用次数已达上限
+	// 群公告不存在
+	CodeGroupAnnouncementNotFound = 14019 // 群公告不存在
+	// 入群申请待审核
+	CodeGroupApplyPending = 14020 // 入群申请待审核
 )
 
 // 设备会话错误 (15xxx)
@@ -222,6 +232,28 @@ const (
 	CodeConnectMessageFormatError = 17003 // WebSocket 上行消息格式错误
 	// WebSocket 上行消息类型不支持
 	CodeConnectMessageTypeNotSupport = 17004 // WebSocket 上行消息类型不支持
+	// connect 节点排空中，拒绝新握手
+	CodeConnectDraining = 17005 // connect 节点排空中
+	// SSE 上行找不到对应的下行流
+	CodeConnectStreamNotFound = 17006 // SSE 下行流不存在
+	// 上行消息超出限速
+	CodeConnectRateLimited = 17007 // 上行消息过于频繁
+	// 上行滥用被临时封禁
+	CodeConnectBanned = 17008 // 连接已被临时封禁
+	// 订阅主题非法（保留主题或格式不合法）
+	CodeConnectTopicInvalid = 17009 // 订阅主题非法
+	// 订阅主题数超过单连接上限
+	CodeConnectTopicLimit = 17010 // 订阅主题数超过上限
+	// 通话不存在或已结束
+	CodeConnectCallNotFound = 17011 // 通话不存在或已结束
+	// 通话信令非法（参数缺失、角色或状态不符）
+	CodeConnectCallInvalid = 17012 // 通话信令非法
+	// 握手来源（Origin）不在白名单内
+	CodeConnectOriginNotAllowed = 17013 // 握手来源不允许
+	// 被叫不是主叫的好友
+	CodeConnectCallNotFriend = 17014 // 只能向好友发起通话
+	// 主被叫任一方拉黑了对方（不向主叫暴露是谁拉黑了谁）
+	CodeConnectCallBlocked = 17015 // 存在拉黑关系，无法发起通话
 )
 
 // 服务端错误 (3xxxx)
@@ -307,21 +339,26 @@ var CodeMessage = map[int]string{
 	CodeMessageDeleted:        "消息已删除",
 
 	// 群组模块
-	CodeGroupNotFound:       "群组不存在",
-	CodeNotGroupMember:      "不是群成员",
-	CodeNoPermission:        "没有权限",
-	CodeGroupFull:           "群成员已满",
-	CodeGroupNameTooLong:    "群名称过长",
-	CodeGroupNoticeTooLong:  "群公告过长",
-	CodeGroupAlreadyDismiss: "群组已解散",
-	CodeGroupMemberNotFound: "群成员不存在",
-	CodeCannotKickOwner:     "不能踢出群主",
-	CodeCannotKickAdmin:     "不能踢出管理员",
-	CodeAlreadyGroupMember:  "已经是群成员",
-	CodeGroupApplyNotFound:  "入群申请不存在",
-	CodeGroupInviteLimit:    "邀请人数超限",
-	CodeCannotQuitAsOwner:   "群主不能退群",
-	CodeAdminLimitExceeded:  "管理员数量已达上限",
+	CodeGroupNotFound:             "群组不存在",
+	CodeNotGroupMember:            "不是群成员",
+	CodeNoPermission:              "没有权限",
+	CodeGroupFull:                 "群成员已满",
+	CodeGroupNameTooLong:          "群名称过长",
+	CodeGroupNoticeTooLong:        "群公告过长",
+	CodeGroupAlreadyDismiss:       "群组已解散",
+	CodeGroupMemberNotFound:       "群成员不存在",
+	CodeCannotKickOwner:           "不能踢出群主",
+	CodeCannotKickAdmin:           "不能踢出管理员",
+	CodeAlreadyGroupMember:        "已经是群成员",
+	CodeGroupApplyNotFound:        "入群申请不存在",
+	CodeGroupInviteLimit:          "邀请人数超限",
+	CodeCannotQuitAsOwner:         "群主不能退群",
+	CodeAdminLimitExceeded:        "管理员数量已达上限",
+	CodeGroupCardTooLong:          "群名片过长",
+	CodeGroupInviteInvalid:        "群邀请已失效",
+	CodeGroupInviteExhausted:      "群邀请使用次数已达上限",
+	CodeGroupAnnouncementNotFound: "群公告不存在",
+	CodeGroupApplyPending:         "入群申请已提交，请等待审核",
 
 	// 设备会话
 	CodeDeviceCreateFail:    "设备会话创建失败",
@@ -346,6 +383,17 @@ var CodeMessage = map[int]string{
 	CodeConnectDeviceIDRequired:      "缺少 device_id",
 	CodeConnectMessageFormatError:    "消息格式错误",
 	CodeConnectMessageTypeNotSupport: "消息类型不支持",
+	CodeConnectDraining:              "节点维护中，请重新连接",
+	CodeConnectStreamNotFound:        "下行连接不存在，请先建立 SSE 连接",
+	CodeConnectRateLimited:           "消息发送过于频繁",
+	CodeConnectBanned:                "连接已被临时封禁，请稍后再试",
+	CodeConnectTopicInvalid:          "订阅主题不合法",
+	CodeConnectTopicLimit:            "订阅主题数量超过上限",
+	CodeConnectCallNotFound:          "通话不存在或已结束",
+	CodeConnectCallInvalid:           "通话信令不合法",
+	CodeConnectOriginNotAllowed:      "请求来源不允许",
+	CodeConnectCallNotFriend:         "只能向好友发起通话",
+	CodeConnectCallBlocked:           "对方暂时无法接听你的通话",
 
 	// 服务端错误
 	CodeInternalError:      "服务器内部错误",
@@ -369,3 +417,9 @@ func IsNonServerError(code int) bool {
 const (
 	VerifyCodeExpireMinutes = 10
 )
+
+// 消息类型（message.msg_type），0-99 普通气泡，100+ 控制类
+const (
+	// MsgTypeCall 音视频通话记录，content 为 {"call_id","media","result","duration"}
+	MsgTypeCall = 20
+)
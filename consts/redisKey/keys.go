@@ -44,6 +44,27 @@ const (
 
 	// QRCodeTTL 用户二维码缓存 TTL
 	QRCodeTTL = 48 * time.Hour
+
+	// GroupInviteDefaultTTL 群邀请链接默认有效期
+	GroupInviteDefaultTTL = 7 * 24 * time.Hour
+	// GroupInviteMaxTTL 群邀请链接最长有效期
+	GroupInviteMaxTTL = 30 * 24 * time.Hour
+
+	// ConnectRouteTTL 连接路由缓存 TTL（由心跳续期）
+	ConnectRouteTTL = 3 * time.Minute
+	// ConnectNodeTTL connect 节点存活 TTL（由节点心跳续期）
+	ConnectNodeTTL = 30 * time.Second
+	// ConnectOutboxTTL 下行补发缓冲 TTL（断线重连补发窗口）
+	ConnectOutboxTTL = 5 * time.Minute
+	// ConnectOutboxMarkTTL 补发缓冲写入标记 TTL（需长于 ConnectOutboxTTL，用于区分"缓冲已过期"与"从未写入"）
+	ConnectOutboxMarkTTL = 24 * time.Hour
+	// ConnectPresenceTTL 用户最近一次推送的在线状态 TTL
+	ConnectPresenceTTL = 24 * time.Hour
+	// ConnectTicketTTL WebSocket 一次性接入票据默认有效期
+	ConnectTicketTTL = 30 * time.Second
+
+	// PushNotifyCollapseTTL 离线通知会话折叠窗口默认时长
+	PushNotifyCollapseTTL = 30 * time.Second
 )
 
 // ==================== Key 构造函数 ====================
@@ -103,6 +124,11 @@ func QRCodeUserKey(userUUID string) string {
 	return fmt.Sprintf("user:qrcode:user:%s", userUUID)
 }
 
+// GroupInviteKey 生成群邀请 token Key: user:group:invite:{token}
+func GroupInviteKey(token string) string {
+	return fmt.Sprintf("user:group:invite:%s", token)
+}
+
 // FriendRelationKey 生成好友关系 Key: user:relation:friend:{user_uuid}
 func FriendRelationKey(userUUID string) string {
 	return fmt.Sprintf("user:relation:friend:%s", userUUID)
@@ -139,3 +165,92 @@ func GatewayUserRateLimitKey(userUUID string) string {
 func GatewayIPRateLimitKey(ip string) string {
 	return fmt.Sprintf("rate:limit:ip:%s", ip)
 }
+
+// ==================== Connect Key 构造函数 ====================
+
+// ConnectRouteKey 连接路由 Key: connect:route:user:{user_uuid}
+// Hash 结构：field=device_id，value=node_id
+func ConnectRouteKey(userUUID string) string {
+	return fmt.Sprintf("connect:route:user:%s", userUUID)
+}
+
+// ConnectNodeKey connect 节点存活 Key: connect:node:{node_id}
+// 值为节点 gRPC 地址，节点宕机后随 TTL 过期
+func ConnectNodeKey(nodeID string) string {
+	return fmt.Sprintf("connect:node:%s", nodeID)
+}
+
+// ConnectNodesKey connect 存活节点集合 Key: connect:nodes
+// ZSet 结构：score=节点存活 Key 过期时间（毫秒），member=node_id，用于向全部节点广播
+func ConnectNodesKey() string {
+	return "connect:nodes"
+}
+
+// ConnectUserOutboxKey 用户级下行补发缓冲 Key: connect:outbox:user:{user_uuid}
+// ZSet 结构：score=写入时间（微秒），member=conv_id\nseq\n + MessageEnvelope protobuf 编码
+func ConnectUserOutboxKey(userUUID string) string {
+	return fmt.Sprintf("connect:outbox:user:%s", userUUID)
+}
+
+// ConnectDeviceOutboxKey 设备级下行补发缓冲 Key: connect:outbox:device:{user_uuid}:{device_id}
+func ConnectDeviceOutboxKey(userUUID, deviceID string) string {
+	return fmt.Sprintf("connect:outbox:device:%s:%s", userUUID, deviceID)
+}
+
+// ConnectOutboxFloorKey 补发缓冲各会话已淘汰的最大 seq Key: {outbox_key}:floor
+// Hash 结构：field=conv_id，value=已淘汰的最大 seq；重连时该会话游标小于该值说明缺口已超出缓冲范围，需要全量按 seq 拉取
+func ConnectOutboxFloorKey(outboxKey string) string {
+	return outboxKey + ":floor"
+}
+
+// ConnectOutboxMarkKey 补发缓冲各会话已写入的最大 seq Key: {outbox_key}:mark
+// Hash 结构：field=conv_id，value=已写入的最大 seq；TTL 长于缓冲本身，缓冲过期后重连时该会话游标小于该值说明消息已随缓冲过期，
+// 缓冲重建时并入 floor
+func ConnectOutboxMarkKey(outboxKey string) string {
+	return outboxKey + ":mark"
+}
+
+// ConnectUplinkStrikeKey 上行超限被断开次数 Key: connect:uplink:strike:{user_uuid}
+func ConnectUplinkStrikeKey(userUUID string) string {
+	return fmt.Sprintf("connect:uplink:strike:%s", userUUID)
+}
+
+// ConnectUplinkBanUserKey 上行滥用用户封禁 Key: connect:uplink:ban:user:{user_uuid}
+func ConnectUplinkBanUserKey(userUUID string) string {
+	return fmt.Sprintf("connect:uplink:ban:user:%s", userUUID)
+}
+
+// ConnectUplinkBanIPKey 上行滥用 IP 封禁 Key: connect:uplink:ban:ip:{ip}
+func ConnectUplinkBanIPKey(ip string) string {
+	return fmt.Sprintf("connect:uplink:ban:ip:%s", ip)
+}
+
+// ConnectCallKey 通话状态 Key: connect:call:{call_id}
+// 值为通话 JSON（主被叫、接听设备、状态、时间），TTL 由参与方心跳续期
+func ConnectCallKey(callID string) string {
+	return fmt.Sprintf("connect:call:%s", callID)
+}
+
+// ConnectCallUserKey 用户进行中的通话 Key: connect:call:user:{user_uuid}
+// 值为 call_id，存在即占线（跨该用户全部设备）
+func ConnectCallUserKey(userUUID string) string {
+	return fmt.Sprintf("connect:call:user:%s", userUUID)
+}
+
+// ConnectPresenceKey 用户最近一次推送给好友的在线状态 Key: connect:presence:user:{user_uuid}
+// 值为 "1"（在线）/"0"（离线），多节点通过 SET GET 保证同一次状态变化只推送一次
+func ConnectPresenceKey(userUUID string) string {
+	return fmt.Sprintf("connect:presence:user:%s", userUUID)
+}
+
+// ConnectTicketKey WebSocket 一次性接入票据 Key: connect:ticket:{ticket}
+// 值为票据 JSON（user_uuid、device_id、client_ip、access token 哈希），由 gateway 签发，connect 握手时 GETDEL 兑换
+func ConnectTicketKey(ticket string) string {
+	return fmt.Sprintf("connect:ticket:%s", ticket)
+}
+
+// PushNotifyCollapseKey 离线通知会话折叠计数 Key: notify:collapse:{user_uuid}:{collapse_id}
+// collapse_id 为会话 ID（非消息类通知为信封类型）；窗口内第一条事件推送，其余只计数
+func PushNotifyCollapseKey(userUUID, collapseID string) string {
+	return fmt.Sprintf("notify:collapse:%s:%s", userUUID, collapseID)
+}
@@ -3,6 +3,7 @@ module ChatServer
 go 1.25
 
 require (
+	github.com/alicebob/miniredis/v2 v2.30.0
 	github.com/bwmarrin/snowflake v0.3.0
 	github.com/envoyproxy/protoc-gen-validate v1.3.0
 	github.com/gin-gonic/gin v1.11.0
@@ -27,8 +28,10 @@ require (
 )
 
 require (
+	github.com/alicebob/gopher-json v0.0.0-20200520072559-a9ecdc9d1d3a // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/yuin/gopher-lua v0.0.0-20220504180219-658193537a64 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
@@ -8,6 +8,11 @@ import (
 
 // Conversation 记录会话元数据，支持单聊/群聊。
 // type: 0=p2p，1=group
+//
+// 群聊按成员数选择扩散模式（见 pkg/fanout）：
+//   - 写扩散：每条消息更新每个成员的 LastMsg*/UnreadCount；
+//   - 读扩散：消息只更新一次 GroupConversation，成员行只维护 ReadSeq，
+//     未读数在读取时按 GroupConversation.MaxSeq - ReadSeq 计算，UnreadCount 不再维护。
 type Conversation struct {
 	Id          int64          `gorm:"column:id;primaryKey;autoIncrement;comment:自增id"`
 	ConvId      string         `gorm:"column:conv_id;type:char(40);not null;comment:会话ID(可用p2p-<sorted uuids>或群uuid)"`
@@ -18,6 +23,7 @@ type Conversation struct {
 	LastMsgAt   *time.Time     `gorm:"column:last_msg_at;comment:最后消息时间"`
 	LastMsgPrev string         `gorm:"column:last_msg_preview;type:varchar(255);comment:最后消息预览（文本内容或占位[图片]/[语音]等）"`
 	UnreadCount int            `gorm:"column:unread_count;not null;default:0;comment:未读数"`
+	ReadSeq     int64          `gorm:"column:read_seq;not null;default:0;comment:已读位点(会话内seq)"`
 	Mute        bool           `gorm:"column:mute;not null;default:false;comment:免打扰"`
 	Pin         bool           `gorm:"column:pin;not null;default:false;comment:置顶"`
 	Status      int8           `gorm:"column:status;not null;default:0;index:idx_owner_status_update,priority:2;comment:0正常 1关闭/删除"`
@@ -27,3 +33,10 @@ type Conversation struct {
 }
 
 func (Conversation) TableName() string { return "conversation" }
+
+const (
+	// ConversationTypeP2P 单聊
+	ConversationTypeP2P int8 = 0
+	// ConversationTypeGroup 群聊
+	ConversationTypeGroup int8 = 1
+)
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+)
+
+// DeviceNotificationSetting 设备通知偏好（每用户每设备一行，未设置过的设备使用默认值）。
+// 离线通知下发前按此表决定是否推送、是否展示消息预览与播放提示音。
+// 注意：布尔字段不设置 gorm default，避免 Create 时 false 被当作零值替换为默认值。
+type DeviceNotificationSetting struct {
+	Id       int64  `gorm:"column:id;primaryKey;autoIncrement"`
+	UserUuid string `gorm:"column:user_uuid;type:char(20);not null;uniqueIndex:uidx_user_device_notify;comment:用户uuid"`
+	DeviceId string `gorm:"column:device_id;type:varchar(64);not null;uniqueIndex:uidx_user_device_notify;comment:设备唯一指纹"`
+
+	// PushEnabled 是否接收系统推送
+	PushEnabled bool `gorm:"column:push_enabled;not null;comment:是否接收系统推送"`
+
+	// 免打扰时段：当天分钟数 [QuietStart, QuietEnd)，QuietStart > QuietEnd 表示跨零点
+	QuietHoursEnabled bool   `gorm:"column:quiet_hours_enabled;not null;comment:是否开启免打扰时段"`
+	QuietStart        int16  `gorm:"column:quiet_start;not null;comment:免打扰开始(当天分钟数)"`
+	QuietEnd          int16  `gorm:"column:quiet_end;not null;comment:免打扰结束(当天分钟数)"`
+	Timezone          string `gorm:"column:timezone;type:varchar(64);not null;default:'';comment:IANA时区(空为服务端时区)"`
+
+	// ShowPreview 通知是否展示发送者与消息内容
+	ShowPreview bool `gorm:"column:show_preview;not null;comment:是否展示消息预览"`
+	// Sound 通知是否播放提示音
+	Sound bool `gorm:"column:sound;not null;comment:是否播放提示音"`
+
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (DeviceNotificationSetting) TableName() string { return "device_notification_setting" }
+
+const (
+	// DefaultQuietStart 默认免打扰开始时间 22:00
+	DefaultQuietStart int16 = 22 * 60
+	// DefaultQuietEnd 默认免打扰结束时间 08:00
+	DefaultQuietEnd int16 = 8 * 60
+)
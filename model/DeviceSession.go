@@ -24,6 +24,10 @@ type DeviceSession struct {
 	IP         string `gorm:"column:ip;type:varchar(64);comment:登录IP"`
 	UserAgent  string `gorm:"column:user_agent;type:varchar(512);comment:User Agent(精简)"` // 仅保留必要信息
 
+	// 离线推送：客户端登记的系统推送通道与 token（空表示未登记）
+	PushProvider string `gorm:"column:push_provider;type:varchar(32);not null;default:'';comment:推送通道(apns/fcm/huawei等)"`
+	PushToken    string `gorm:"column:push_token;type:varchar(255);not null;default:'';index;comment:推送token"`
+
 	// 时间与状态
 	ExpireAt *time.Time `gorm:"column:expire_at;index;comment:过期时间(用于清理过期会话)"`
 
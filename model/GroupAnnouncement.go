@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GroupAnnouncement 群公告（替代 group_info.notice 单字段，保留历史）。
+// 群主/管理员可发布、编辑、置顶、删除；RequireAck 为 true 时要求成员确认已读，
+// 确认记录见 GroupAnnouncementAck。
+type GroupAnnouncement struct {
+	Id         int64          `gorm:"column:id;primaryKey;autoIncrement;comment:自增id"`
+	Uuid       string         `gorm:"column:uuid;type:char(20);uniqueIndex;not null;comment:公告唯一id"`
+	GroupUuid  string         `gorm:"column:group_uuid;type:char(20);not null;index:idx_group_pin_created,priority:1;comment:群uuid"`
+	AuthorUuid string         `gorm:"column:author_uuid;type:char(20);not null;comment:发布人uuid"`
+	EditorUuid string         `gorm:"column:editor_uuid;type:char(20);comment:最后编辑人uuid"`
+	Content    string         `gorm:"column:content;type:varchar(2000);not null;comment:公告内容"`
+	Pinned     bool           `gorm:"column:pinned;not null;default:false;index:idx_group_pin_created,priority:2;comment:是否置顶"`
+	RequireAck bool           `gorm:"column:require_ack;not null;default:false;comment:是否需要成员确认"`
+	CreatedAt  time.Time      `gorm:"column:created_at;autoCreateTime;index:idx_group_pin_created,priority:3"`
+	UpdatedAt  time.Time      `gorm:"column:updated_at;autoUpdateTime"`
+	DeletedAt  gorm.DeletedAt `gorm:"column:deleted_at;index"`
+}
+
+func (GroupAnnouncement) TableName() string { return "group_announcement" }
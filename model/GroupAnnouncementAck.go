@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+)
+
+// GroupAnnouncementAck 群成员对公告的确认记录（每人每条公告一行）。
+// 公告内容被修改时清空已有确认，成员需重新确认。
+type GroupAnnouncementAck struct {
+	Id               int64     `gorm:"column:id;primaryKey;autoIncrement;comment:自增id"`
+	AnnouncementUuid string    `gorm:"column:announcement_uuid;type:char(20);not null;uniqueIndex:uidx_announcement_user;comment:公告uuid"`
+	UserUuid         string    `gorm:"column:user_uuid;type:char(20);not null;uniqueIndex:uidx_announcement_user;index;comment:确认人uuid"`
+	AckedAt          time.Time `gorm:"column:acked_at;autoCreateTime;comment:确认时间"`
+}
+
+func (GroupAnnouncementAck) TableName() string { return "group_announcement_ack" }
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+)
+
+// GroupConversation 记录读扩散模式下群会话的共享状态（每个群一行）。
+// 大群消息只更新这一行，成员未读数 = MaxSeq - Conversation.ReadSeq。
+type GroupConversation struct {
+	Id            int64      `gorm:"column:id;primaryKey;autoIncrement;comment:自增id"`
+	ConvId        string     `gorm:"column:conv_id;type:char(40);not null;uniqueIndex;comment:会话ID,关联 conversation.conv_id"`
+	GroupUuid     string     `gorm:"column:group_uuid;type:char(20);not null;uniqueIndex;comment:群uuid"`
+	MaxSeq        int64      `gorm:"column:max_seq;not null;default:0;comment:会话当前最大seq"`
+	LastMsgId     string     `gorm:"column:last_msg_id;type:char(64);comment:最后消息ID"`
+	LastMsgAt     *time.Time `gorm:"column:last_msg_at;comment:最后消息时间"`
+	LastMsgPrev   string     `gorm:"column:last_msg_preview;type:varchar(255);comment:最后消息预览"`
+	LastMsgSender string     `gorm:"column:last_msg_sender;type:char(20);comment:最后消息发送者uuid"`
+	CreatedAt     time.Time  `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt     time.Time  `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (GroupConversation) TableName() string { return "group_conversation" }
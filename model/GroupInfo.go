@@ -6,21 +6,45 @@ import (
 	"gorm.io/gorm"
 )
 
+// GroupInfo 群基础信息。
+// Notice 字段已废弃：群公告迁移到 GroupAnnouncement（支持历史、置顶与确认），该字段不再写入。
 type GroupInfo struct {
-	Id        int64          `gorm:"column:id;primaryKey;autoIncrement;comment:自增id"`
-	Uuid      string         `gorm:"column:uuid;type:char(20);uniqueIndex;not null;comment:群组唯一id"`
-	Name      string         `gorm:"column:name;type:varchar(64);not null;comment:群名称"`
-	Notice    string         `gorm:"column:notice;type:varchar(500);comment:群公告"`
-	MemberCnt int            `gorm:"column:member_cnt;not null;default:1;comment:群人数"` // 默认群主1人
-	OwnerUuid string         `gorm:"column:owner_uuid;type:char(20);not null;index;comment:群主uuid"`
-	AddMode   int8           `gorm:"column:add_mode;not null;default:0;comment:加群方式,0.直接 1.审核"`
-	Avatar    string         `gorm:"column:avatar;type:varchar(255);not null;default:https://cube.elemecdn.com/0/88/03b0d39583f48206768a7534e55bcpng.png;comment:群头像URL"`
-	Status    int8           `gorm:"column:status;not null;default:0;comment:状态,0.正常 1.禁用 2.解散"`
-	CreatedAt time.Time      `gorm:"column:created_at;autoCreateTime"`
-	UpdatedAt time.Time      `gorm:"column:updated_at;autoUpdateTime"`
-	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index"`
+	Id        int64  `gorm:"column:id;primaryKey;autoIncrement;comment:自增id"`
+	Uuid      string `gorm:"column:uuid;type:char(20);uniqueIndex;not null;comment:群组唯一id"`
+	Name      string `gorm:"column:name;type:varchar(64);not null;comment:群名称"`
+	Notice    string `gorm:"column:notice;type:varchar(500);comment:群公告(已废弃,见 group_announcement)"`
+	MemberCnt int    `gorm:"column:member_cnt;not null;default:1;comment:群人数"` // 默认群主1人
+	OwnerUuid string `gorm:"column:owner_uuid;type:char(20);not null;index;comment:群主uuid"`
+	AddMode   int8   `gorm:"column:add_mode;not null;default:0;comment:加群方式,0.直接 1.审核"`
+	Avatar    string `gorm:"column:avatar;type:varchar(255);not null;default:https://cube.elemecdn.com/0/88/03b0d39583f48206768a7534e55bcpng.png;comment:群头像URL"`
+	// AvatarCustom 为 true 表示群主显式设置的自定义头像，自动生成的九宫格头像不会覆盖它
+	AvatarCustom bool           `gorm:"column:avatar_custom;not null;default:false;comment:是否为自定义头像"`
+	Status       int8           `gorm:"column:status;not null;default:0;comment:状态,0.正常 1.禁用 2.解散"`
+	CreatedAt    time.Time      `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt    time.Time      `gorm:"column:updated_at;autoUpdateTime"`
+	DeletedAt    gorm.DeletedAt `gorm:"column:deleted_at;index"`
 }
 
 func (GroupInfo) TableName() string {
 	return "group_info"
 }
+
+// GroupMaxMembers 群成员上限（含群主），成员入群与通过入群申请时在同一事务内校验。
+// 超过读扩散阈值（pkg/fanout，默认 2000）的大群改用读扩散，上限按万人群设置。
+const GroupMaxMembers = 50000
+
+const (
+	// GroupStatusNormal 正常
+	GroupStatusNormal int8 = 0
+	// GroupStatusDisabled 禁用
+	GroupStatusDisabled int8 = 1
+	// GroupStatusDismissed 解散
+	GroupStatusDismissed int8 = 2
+)
+
+const (
+	// GroupAddModeDirect 直接加入
+	GroupAddModeDirect int8 = 0
+	// GroupAddModeApproval 需审核
+	GroupAddModeApproval int8 = 1
+)
@@ -0,0 +1,63 @@
+package connectroute
+
+import (
+	connectpb "ChatServer/apps/connect/pb"
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// Direct 单节点直连客户端：调用直接转发给唯一的 connect 节点，
+// 按节点返回的投递结果把未送达推送上报给离线通知链路，与 Router 的上报口径一致。
+// 不查路由、不写补发缓冲（单节点模式没有 Redis）。
+type Direct struct {
+	connectpb.ConnectServiceClient
+	undelivered UndeliveredSink
+}
+
+var _ connectpb.ConnectServiceClient = (*Direct)(nil)
+
+// NewDirect 包装直连客户端，client 为 nil 时返回 nil；sink 为 nil 时原样返回 client（无需上报）
+func NewDirect(client connectpb.ConnectServiceClient, sink UndeliveredSink) connectpb.ConnectServiceClient {
+	if client == nil {
+		return nil
+	}
+	if sink == nil {
+		return client
+	}
+	return &Direct{ConnectServiceClient: client, undelivered: sink}
+}
+
+// PushToDevice 直连推送，设备未入队时上报
+func (d *Direct) PushToDevice(ctx context.Context, in *connectpb.PushToDeviceRequest, opts ...grpc.CallOption) (*connectpb.PushToDeviceResponse, error) {
+	resp, err := d.ConnectServiceClient.PushToDevice(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Delivered {
+		reportUndelivered(ctx, d.undelivered, in.Message, in.DeviceId, in.UserUuid)
+	}
+	return resp, nil
+}
+
+// PushToUser 直连推送，没有任何设备入队时上报
+func (d *Direct) PushToUser(ctx context.Context, in *connectpb.PushToUserRequest, opts ...grpc.CallOption) (*connectpb.PushToUserResponse, error) {
+	resp, err := d.ConnectServiceClient.PushToUser(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if resp.DeliveredCount == 0 {
+		reportUndelivered(ctx, d.undelivered, in.Message, "", in.UserUuid)
+	}
+	return resp, nil
+}
+
+// BroadcastToUsers 直连广播，上报节点回报的未入队用户
+func (d *Direct) BroadcastToUsers(ctx context.Context, in *connectpb.BroadcastToUsersRequest, opts ...grpc.CallOption) (*connectpb.BroadcastToUsersResponse, error) {
+	resp, err := d.ConnectServiceClient.BroadcastToUsers(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	reportUndelivered(ctx, d.undelivered, in.Message, "", resp.UndeliveredUserUuids...)
+	return resp, nil
+}
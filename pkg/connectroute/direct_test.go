@@ -0,0 +1,69 @@
+package connectroute
+
+import (
+	"context"
+	"testing"
+
+	connectpb "ChatServer/apps/connect/pb"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// fakeDirectClient 单节点 connect 客户端，只有 online 中的设备会入队成功。
+type fakeDirectClient struct {
+	connectpb.ConnectServiceClient
+	online map[string]string // user_uuid -> device_id
+}
+
+func (c *fakeDirectClient) PushToDevice(_ context.Context, in *connectpb.PushToDeviceRequest, _ ...grpc.CallOption) (*connectpb.PushToDeviceResponse, error) {
+	return &connectpb.PushToDeviceResponse{Delivered: c.online[in.UserUuid] == in.DeviceId}, nil
+}
+
+func (c *fakeDirectClient) PushToUser(_ context.Context, in *connectpb.PushToUserRequest, _ ...grpc.CallOption) (*connectpb.PushToUserResponse, error) {
+	if _, ok := c.online[in.UserUuid]; ok {
+		return &connectpb.PushToUserResponse{DeliveredCount: 1}, nil
+	}
+	return &connectpb.PushToUserResponse{}, nil
+}
+
+func (c *fakeDirectClient) BroadcastToUsers(_ context.Context, in *connectpb.BroadcastToUsersRequest, _ ...grpc.CallOption) (*connectpb.BroadcastToUsersResponse, error) {
+	out := &connectpb.BroadcastToUsersResponse{}
+	for _, userUUID := range in.UserUuids {
+		if _, ok := c.online[userUUID]; ok {
+			out.SuccessCount++
+			out.TotalDelivered++
+		} else {
+			out.UndeliveredUserUuids = append(out.UndeliveredUserUuids, userUUID)
+		}
+	}
+	return out, nil
+}
+
+func TestDirectReportsUndelivered(t *testing.T) {
+	ctx := context.Background()
+	sink := &recordingSink{}
+	client := NewDirect(&fakeDirectClient{online: map[string]string{"alice": "a1"}}, sink)
+	message := &connectpb.MessageEnvelope{Type: "message"}
+
+	resp, err := client.PushToDevice(ctx, &connectpb.PushToDeviceRequest{UserUuid: "alice", DeviceId: "a1", Message: message})
+	require.NoError(t, err)
+	assert.True(t, resp.Delivered)
+	_, err = client.PushToDevice(ctx, &connectpb.PushToDeviceRequest{UserUuid: "alice", DeviceId: "a2", Message: message})
+	require.NoError(t, err)
+	_, err = client.PushToUser(ctx, &connectpb.PushToUserRequest{UserUuid: "alice", Message: message})
+	require.NoError(t, err)
+	_, err = client.PushToUser(ctx, &connectpb.PushToUserRequest{UserUuid: "bob", Message: message})
+	require.NoError(t, err)
+	_, err = client.BroadcastToUsers(ctx, &connectpb.BroadcastToUsersRequest{UserUuids: []string{"alice", "carol"}, Message: message})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"alice/a2", "bob", "carol"}, sink.users())
+}
+
+func TestNewDirectWithoutSink(t *testing.T) {
+	client := &fakeDirectClient{}
+	assert.Same(t, client, NewDirect(client, nil))
+	assert.Nil(t, NewDirect(nil, &recordingSink{}))
+}
@@ -0,0 +1,313 @@
+package connectroute
+
+import (
+	connectpb "ChatServer/apps/connect/pb"
+	rediskey "ChatServer/consts/redisKey"
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// DefaultOutboxMaxEntries 单个补发缓冲最多保留的消息数
+	DefaultOutboxMaxEntries = 200
+	// MaxResumeCursors 握手时最多接受的会话补发游标数，超出部分忽略（按未携带处理）
+	MaxResumeCursors = 500
+)
+
+// luaOutboxAppend 写入补发缓冲并按容量淘汰最旧消息，记录每个会话已写入、已淘汰的最大 seq。
+// 缓冲过期后重建时，写入标记中的会话视为已全部淘汰，并入 floor。
+// KEYS[1]: 补发缓冲 ZSet
+// KEYS[2]: 各会话已淘汰最大 seq（floor Hash）
+// KEYS[3]: 各会话已写入最大 seq（mark Hash，TTL 长于缓冲）
+// ARGV[1]: score（写入时间，微秒）
+// ARGV[2]: member（conv_id\nseq\nMessageEnvelope protobuf 编码）
+// ARGV[3]: 最大保留条数
+// ARGV[4]: 过期时间（秒）
+// ARGV[5]: 写入标记过期时间（秒）
+// 返回: 1
+const luaOutboxAppend = `
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	local marks = redis.call('HGETALL', KEYS[3])
+	if #marks > 0 then
+		redis.call('HSET', KEYS[2], unpack(marks))
+	end
+end
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+local msgConv, msgSeq = string.match(ARGV[2], '^([^\n]*)\n(%d+)\n')
+if msgSeq and tonumber(msgSeq) > tonumber(redis.call('HGET', KEYS[3], msgConv) or '0') then
+	redis.call('HSET', KEYS[3], msgConv, msgSeq)
+end
+redis.call('EXPIRE', KEYS[3], ARGV[5])
+local overflow = redis.call('ZCARD', KEYS[1]) - tonumber(ARGV[3])
+if overflow > 0 then
+	local removed = redis.call('ZRANGE', KEYS[1], 0, overflow - 1)
+	redis.call('ZREMRANGEBYRANK', KEYS[1], 0, overflow - 1)
+	for _, member in ipairs(removed) do
+		local conv, seq = string.match(member, '^([^\n]*)\n(%d+)\n')
+		if seq then
+			local floor = tonumber(redis.call('HGET', KEYS[2], conv) or '0')
+			if tonumber(seq) > floor then
+				redis.call('HSET', KEYS[2], conv, seq)
+			end
+		end
+	end
+end
+redis.call('EXPIRE', KEYS[1], ARGV[4])
+if redis.call('EXISTS', KEYS[2]) == 1 then
+	redis.call('EXPIRE', KEYS[2], ARGV[4])
+end
+return 1
+`
+
+var outboxAppendScript = redis.NewScript(luaOutboxAppend)
+
+// ResyncReason 无法补发、需要客户端全量按 seq 拉取的原因
+type ResyncReason string
+
+const (
+	// ResyncNone 缓冲完整覆盖缺口，无需全量拉取
+	ResyncNone ResyncReason = ""
+	// ResyncGapTooLarge 缺口超出缓冲范围，部分消息已被淘汰（或在缓冲重建前随旧缓冲过期）
+	ResyncGapTooLarge ResyncReason = "gap_too_large"
+	// ResyncOutboxExpired 缓冲已过期（断线时间超过 TTL），且写入标记显示有游标之后的消息随之丢失
+	ResyncOutboxExpired ResyncReason = "outbox_expired"
+)
+
+// OutboxConfig 补发缓冲配置
+type OutboxConfig struct {
+	// MaxEntries 单个缓冲最多保留的消息数
+	MaxEntries int
+	// TTL 缓冲过期时间，每次写入续期
+	TTL time.Duration
+	// MarkTTL 写入标记过期时间，每次写入续期，不短于 TTL
+	MarkTTL time.Duration
+	// OpTimeout 单次 Redis 操作超时
+	OpTimeout time.Duration
+}
+
+// Outbox 短期下行补发缓冲。
+// 带 seq 的下行消息在投递前写入 Redis（用户级 / 设备级各一个 ZSet，score 为写入时间），
+// 客户端切网重连时携带各会话最后收到的 seq，connect 从缓冲中补发缺口内的消息。
+// 约定：seq 为会话内序号，消息按 (conv_id, seq) 唯一定位。
+type Outbox struct {
+	rdb *redis.Client
+	cfg OutboxConfig
+	now func() time.Time
+}
+
+// NewOutbox 创建补发缓冲，rdb 为 nil 时返回 nil（不缓冲、不补发）
+func NewOutbox(rdb *redis.Client, cfg OutboxConfig) *Outbox {
+	if rdb == nil {
+		return nil
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultOutboxMaxEntries
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = rediskey.ConnectOutboxTTL
+	}
+	if cfg.MarkTTL <= 0 {
+		cfg.MarkTTL = rediskey.ConnectOutboxMarkTTL
+	}
+	if cfg.MarkTTL < cfg.TTL {
+		cfg.MarkTTL = cfg.TTL
+	}
+	if cfg.OpTimeout <= 0 {
+		cfg.OpTimeout = DefaultOpTimeout
+	}
+	return &Outbox{rdb: rdb, cfg: cfg, now: time.Now}
+}
+
+// AppendUsers 将消息写入多个用户的用户级缓冲，seq<=0 的消息不缓冲
+func (o *Outbox) AppendUsers(ctx context.Context, userUUIDs []string, envelope *connectpb.MessageEnvelope) error {
+	if envelope.GetSeq() <= 0 || len(userUUIDs) == 0 {
+		return nil
+	}
+	keys := make([]string, len(userUUIDs))
+	for i, userUUID := range userUUIDs {
+		keys[i] = rediskey.ConnectUserOutboxKey(userUUID)
+	}
+	return o.append(ctx, keys, envelope)
+}
+
+// AppendDevice 将消息写入设备级缓冲，seq<=0 的消息不缓冲
+func (o *Outbox) AppendDevice(ctx context.Context, userUUID, deviceID string, envelope *connectpb.MessageEnvelope) error {
+	if envelope.GetSeq() <= 0 {
+		return nil
+	}
+	return o.append(ctx, []string{rediskey.ConnectDeviceOutboxKey(userUUID, deviceID)}, envelope)
+}
+
+// append 管道批量执行写入脚本
+func (o *Outbox) append(ctx context.Context, keys []string, envelope *connectpb.MessageEnvelope) error {
+	member, err := encodeOutboxMember(envelope)
+	if err != nil {
+		return err
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, o.cfg.OpTimeout)
+	defer cancel()
+
+	score := o.now().UnixMicro()
+	ttlSeconds := int64(o.cfg.TTL / time.Second)
+	markTTLSeconds := int64(o.cfg.MarkTTL / time.Second)
+	pipe := o.rdb.Pipeline()
+	for _, key := range keys {
+		outboxAppendScript.Eval(opCtx, pipe,
+			[]string{key, rediskey.ConnectOutboxFloorKey(key), rediskey.ConnectOutboxMarkKey(key)},
+			score, member, o.cfg.MaxEntries, ttlSeconds, markTTLSeconds)
+	}
+	_, err = pipe.Exec(opCtx)
+	return err
+}
+
+// Since 读取设备在各会话游标之后的缓冲消息（用户级 + 设备级合并，按 (conv_id, seq) 去重、按写入顺序排列）。
+// cursors 为 conv_id -> 客户端最后收到的 seq，未出现的会话视为从未收到。
+// reason 非空表示缓冲无法完整覆盖缺口，客户端需要全量按 seq 拉取；此时仍返回可补发的部分。
+// 缓冲不存在时按写入标记判断：标记中某会话的 seq 大于游标才视为过期丢失，从未写入过则无需拉取。
+func (o *Outbox) Since(ctx context.Context, userUUID, deviceID string, cursors map[string]int64) ([]*connectpb.MessageEnvelope, ResyncReason, error) {
+	opCtx, cancel := context.WithTimeout(ctx, o.cfg.OpTimeout)
+	defer cancel()
+
+	userKey := rediskey.ConnectUserOutboxKey(userUUID)
+	deviceKey := rediskey.ConnectDeviceOutboxKey(userUUID, deviceID)
+
+	pipe := o.rdb.Pipeline()
+	userExists := pipe.Exists(opCtx, userKey)
+	deviceExists := pipe.Exists(opCtx, deviceKey)
+	userMark := pipe.HGetAll(opCtx, rediskey.ConnectOutboxMarkKey(userKey))
+	deviceMark := pipe.HGetAll(opCtx, rediskey.ConnectOutboxMarkKey(deviceKey))
+	userFloor := pipe.HGetAll(opCtx, rediskey.ConnectOutboxFloorKey(userKey))
+	deviceFloor := pipe.HGetAll(opCtx, rediskey.ConnectOutboxFloorKey(deviceKey))
+	userEntries := pipe.ZRangeWithScores(opCtx, userKey, 0, -1)
+	deviceEntries := pipe.ZRangeWithScores(opCtx, deviceKey, 0, -1)
+	if _, err := pipe.Exec(opCtx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, ResyncNone, err
+	}
+
+	reason := ResyncNone
+	if (userExists.Val() == 0 && beyondCursors(userMark.Val(), cursors)) ||
+		(deviceExists.Val() == 0 && beyondCursors(deviceMark.Val(), cursors)) {
+		reason = ResyncOutboxExpired
+	}
+	if beyondCursors(userFloor.Val(), cursors) || beyondCursors(deviceFloor.Val(), cursors) {
+		reason = ResyncGapTooLarge
+	}
+
+	type scoredEnvelope struct {
+		score    float64
+		envelope *connectpb.MessageEnvelope
+	}
+	seen := make(map[outboxMsgKey]struct{})
+	entries := make([]scoredEnvelope, 0, len(userEntries.Val())+len(deviceEntries.Val()))
+	for _, z := range append(userEntries.Val(), deviceEntries.Val()...) {
+		member, _ := z.Member.(string)
+		envelope, ok := decodeOutboxMember(member)
+		if !ok || envelope.GetSeq() <= cursors[envelope.GetConvId()] {
+			continue
+		}
+		key := outboxMsgKey{convID: envelope.GetConvId(), seq: envelope.GetSeq()}
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		entries = append(entries, scoredEnvelope{score: z.Score, envelope: envelope})
+	}
+	// 按写入顺序补发；同一会话的消息在其占用的位置内再按 seq 升序（并发推送时写入顺序与 seq 可能交错）。
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].score < entries[j].score
+	})
+	envelopes := make([]*connectpb.MessageEnvelope, len(entries))
+	slots := make(map[string][]int)
+	for i, entry := range entries {
+		envelopes[i] = entry.envelope
+		slots[entry.envelope.GetConvId()] = append(slots[entry.envelope.GetConvId()], i)
+	}
+	for _, idx := range slots {
+		convEnvelopes := make([]*connectpb.MessageEnvelope, len(idx))
+		for i, pos := range idx {
+			convEnvelopes[i] = envelopes[pos]
+		}
+		sort.Slice(convEnvelopes, func(i, j int) bool {
+			return convEnvelopes[i].GetSeq() < convEnvelopes[j].GetSeq()
+		})
+		for i, pos := range idx {
+			envelopes[pos] = convEnvelopes[i]
+		}
+	}
+	return envelopes, reason, nil
+}
+
+// beyondCursors 判断 conv_id -> seq 记录中是否有会话的 seq 大于客户端游标（未出现的会话游标为 0）
+func beyondCursors(seqs map[string]string, cursors map[string]int64) bool {
+	for convID, raw := range seqs {
+		seq, err := strconv.ParseInt(raw, 10, 64)
+		if err == nil && cursors[convID] < seq {
+			return true
+		}
+	}
+	return false
+}
+
+// outboxMsgKey 缓冲内消息的唯一标识
+type outboxMsgKey struct {
+	convID string
+	seq    int64
+}
+
+// encodeOutboxMember 编码缓冲成员：conv_id\nseq\n + protobuf，前缀供 Lua 脚本记录淘汰 floor。
+func encodeOutboxMember(envelope *connectpb.MessageEnvelope) (string, error) {
+	payload, err := proto.Marshal(envelope)
+	if err != nil {
+		return "", err
+	}
+	prefix := envelope.GetConvId() + "\n" + strconv.FormatInt(envelope.GetSeq(), 10) + "\n"
+	return prefix + string(payload), nil
+}
+
+// decodeOutboxMember 跳过 conv_id/seq 前缀解码信封
+func decodeOutboxMember(member string) (*connectpb.MessageEnvelope, bool) {
+	for i := 0; i < 2; i++ {
+		idx := strings.IndexByte(member, '\n')
+		if idx < 0 {
+			return nil, false
+		}
+		member = member[idx+1:]
+	}
+	var envelope connectpb.MessageEnvelope
+	if err := proto.Unmarshal([]byte(member), &envelope); err != nil {
+		return nil, false
+	}
+	return &envelope, true
+}
+
+// ParseResumeCursors 解析握手参数 resume：以逗号分隔的 conv_id:seq 列表。
+// 格式非法或 seq<=0 的项忽略，同一会话取最大 seq，最多接受 MaxResumeCursors 项。
+func ParseResumeCursors(raw string) map[string]int64 {
+	cursors := make(map[string]int64)
+	for _, item := range strings.Split(raw, ",") {
+		if len(cursors) >= MaxResumeCursors {
+			break
+		}
+		idx := strings.LastIndexByte(item, ':')
+		if idx <= 0 {
+			continue
+		}
+		convID := strings.TrimSpace(item[:idx])
+		seq, err := strconv.ParseInt(strings.TrimSpace(item[idx+1:]), 10, 64)
+		if convID == "" || err != nil || seq <= 0 {
+			continue
+		}
+		if seq > cursors[convID] {
+			cursors[convID] = seq
+		}
+	}
+	return cursors
+}
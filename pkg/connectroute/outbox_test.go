@@ -0,0 +1,211 @@
+package connectroute
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	connectpb "ChatServer/apps/connect/pb"
+	rediskey "ChatServer/consts/redisKey"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestOutbox 创建基于 miniredis 的补发缓冲，写入时间按调用顺序递增。
+func newTestOutbox(t *testing.T, cfg OutboxConfig) (*Outbox, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	outbox := NewOutbox(rdb, cfg)
+	now := time.Unix(1700000000, 0)
+	outbox.now = func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	}
+	return outbox, mr
+}
+
+func outboxMsg(convID string, seq int64) *connectpb.MessageEnvelope {
+	return &connectpb.MessageEnvelope{Type: "message", ConvId: convID, Seq: seq}
+}
+
+type outboxKey struct {
+	conv string
+	seq  int64
+}
+
+func outboxKeys(envelopes []*connectpb.MessageEnvelope) []outboxKey {
+	keys := make([]outboxKey, len(envelopes))
+	for i, envelope := range envelopes {
+		keys[i] = outboxKey{envelope.GetConvId(), envelope.GetSeq()}
+	}
+	return keys
+}
+
+func TestOutboxSinceKeysByConversation(t *testing.T) {
+	outbox, _ := newTestOutbox(t, OutboxConfig{})
+	ctx := context.Background()
+
+	require.NoError(t, outbox.AppendUsers(ctx, []string{"alice"}, outboxMsg("convA", 4)))
+	require.NoError(t, outbox.AppendUsers(ctx, []string{"alice"}, outboxMsg("convA", 5)))
+	require.NoError(t, outbox.AppendUsers(ctx, []string{"alice"}, outboxMsg("convB", 5)))
+	require.NoError(t, outbox.AppendDevice(ctx, "alice", "a1", outboxMsg("convB", 6)))
+	require.NoError(t, outbox.AppendDevice(ctx, "alice", "a1", outboxMsg("convA", 5)))
+	require.NoError(t, outbox.AppendUsers(ctx, []string{"alice"}, outboxMsg("convC", 1)))
+	require.NoError(t, outbox.AppendUsers(ctx, []string{"alice"}, &connectpb.MessageEnvelope{Type: "notice"}))
+
+	envelopes, reason, err := outbox.Since(ctx, "alice", "a1", map[string]int64{"convA": 4, "convB": 4})
+	require.NoError(t, err)
+	assert.Equal(t, ResyncNone, reason)
+	// 不同会话的相同 seq 都要补发；用户级与设备级重复的 (convA, 5) 只补发一次；未携带游标的 convC 全部补发。
+	assert.Equal(t, []outboxKey{{"convA", 5}, {"convB", 5}, {"convB", 6}, {"convC", 1}}, outboxKeys(envelopes))
+
+	envelopes, _, err = outbox.Since(ctx, "alice", "a2", map[string]int64{"convA": 5, "convB": 5, "convC": 1})
+	require.NoError(t, err)
+	assert.Empty(t, envelopes)
+}
+
+func TestOutboxSinceOrdersWithinConversation(t *testing.T) {
+	outbox, _ := newTestOutbox(t, OutboxConfig{})
+	ctx := context.Background()
+
+	// 并发推送时写入顺序可能与 seq 不一致，同一会话内仍按 seq 补发。
+	require.NoError(t, outbox.AppendUsers(ctx, []string{"alice"}, outboxMsg("convA", 2)))
+	require.NoError(t, outbox.AppendUsers(ctx, []string{"alice"}, outboxMsg("convB", 1)))
+	require.NoError(t, outbox.AppendUsers(ctx, []string{"alice"}, outboxMsg("convA", 1)))
+
+	envelopes, _, err := outbox.Since(ctx, "alice", "a1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []outboxKey{{"convA", 1}, {"convB", 1}, {"convA", 2}}, outboxKeys(envelopes))
+}
+
+func TestOutboxOverflowFloor(t *testing.T) {
+	outbox, _ := newTestOutbox(t, OutboxConfig{MaxEntries: 3})
+	ctx := context.Background()
+
+	for _, msg := range []*connectpb.MessageEnvelope{
+		outboxMsg("convA", 1), outboxMsg("convA", 2), outboxMsg("convB", 1),
+		outboxMsg("convA", 3), outboxMsg("convB", 2),
+	} {
+		require.NoError(t, outbox.AppendUsers(ctx, []string{"alice"}, msg))
+	}
+
+	// 淘汰了 (convA, 1) 与 (convA, 2)，convB 未被淘汰。
+	cases := []struct {
+		name    string
+		cursors map[string]int64
+		reason  ResyncReason
+		replay  []outboxKey
+	}{
+		{"cursor_at_floor", map[string]int64{"convA": 2}, ResyncNone, []outboxKey{{"convB", 1}, {"convA", 3}, {"convB", 2}}},
+		{"cursor_below_floor", map[string]int64{"convA": 1, "convB": 1}, ResyncGapTooLarge, []outboxKey{{"convA", 3}, {"convB", 2}}},
+		{"unknown_conversation_evicted", map[string]int64{"convB": 2}, ResyncGapTooLarge, []outboxKey{{"convA", 3}}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			envelopes, reason, err := outbox.Since(ctx, "alice", "a1", tc.cursors)
+			require.NoError(t, err)
+			assert.Equal(t, tc.reason, reason)
+			assert.Equal(t, tc.replay, outboxKeys(envelopes))
+		})
+	}
+}
+
+func TestOutboxExpiry(t *testing.T) {
+	outbox, mr := newTestOutbox(t, OutboxConfig{TTL: time.Minute})
+	ctx := context.Background()
+
+	require.NoError(t, outbox.AppendUsers(ctx, []string{"alice"}, outboxMsg("convA", 1)))
+	require.NoError(t, outbox.AppendUsers(ctx, []string{"alice"}, outboxMsg("convA", 2)))
+	envelopes, reason, err := outbox.Since(ctx, "alice", "a1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, ResyncNone, reason)
+	assert.Len(t, envelopes, 2)
+
+	// 缓冲过期后写入标记仍在：游标落后于标记才算丢失。
+	mr.FastForward(time.Minute + time.Second)
+	assert.True(t, mr.Exists(rediskey.ConnectOutboxMarkKey(rediskey.ConnectUserOutboxKey("alice"))))
+	cases := []struct {
+		name    string
+		cursors map[string]int64
+		reason  ResyncReason
+	}{
+		{"no_cursor", nil, ResyncOutboxExpired},
+		{"cursor_behind_mark", map[string]int64{"convA": 1}, ResyncOutboxExpired},
+		{"cursor_at_mark", map[string]int64{"convA": 2}, ResyncNone},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			envelopes, reason, err := outbox.Since(ctx, "alice", "a1", tc.cursors)
+			require.NoError(t, err)
+			assert.Equal(t, tc.reason, reason)
+			assert.Empty(t, envelopes)
+		})
+	}
+
+	// 缓冲重建后，过期前写入的会话按已淘汰处理。
+	require.NoError(t, outbox.AppendUsers(ctx, []string{"alice"}, outboxMsg("convB", 1)))
+	envelopes, reason, err = outbox.Since(ctx, "alice", "a1", map[string]int64{"convA": 1})
+	require.NoError(t, err)
+	assert.Equal(t, ResyncGapTooLarge, reason)
+	assert.Equal(t, []outboxKey{{"convB", 1}}, outboxKeys(envelopes))
+
+	envelopes, reason, err = outbox.Since(ctx, "alice", "a1", map[string]int64{"convA": 2})
+	require.NoError(t, err)
+	assert.Equal(t, ResyncNone, reason)
+	assert.Equal(t, []outboxKey{{"convB", 1}}, outboxKeys(envelopes))
+}
+
+func TestOutboxNeverWritten(t *testing.T) {
+	outbox, _ := newTestOutbox(t, OutboxConfig{})
+	ctx := context.Background()
+
+	// 从未写入过缓冲（如新用户、一直没有消息）不要求全量拉取。
+	envelopes, reason, err := outbox.Since(ctx, "alice", "a1", map[string]int64{"convA": 3})
+	require.NoError(t, err)
+	assert.Equal(t, ResyncNone, reason)
+	assert.Empty(t, envelopes)
+}
+
+func TestOutboxDeviceExpiry(t *testing.T) {
+	outbox, mr := newTestOutbox(t, OutboxConfig{TTL: time.Minute})
+	ctx := context.Background()
+
+	require.NoError(t, outbox.AppendDevice(ctx, "alice", "a1", outboxMsg("convA", 1)))
+	mr.FastForward(30 * time.Second)
+	require.NoError(t, outbox.AppendUsers(ctx, []string{"alice"}, outboxMsg("convB", 1)))
+	mr.FastForward(45 * time.Second)
+
+	// 用户级缓冲仍在，设备级缓冲已过期且有未收到的消息。
+	envelopes, reason, err := outbox.Since(ctx, "alice", "a1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, ResyncOutboxExpired, reason)
+	assert.Equal(t, []outboxKey{{"convB", 1}}, outboxKeys(envelopes))
+
+	// 其他设备不受影响。
+	_, reason, err = outbox.Since(ctx, "alice", "a2", nil)
+	require.NoError(t, err)
+	assert.Equal(t, ResyncNone, reason)
+}
+
+func TestParseResumeCursors(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want map[string]int64
+	}{
+		{"", map[string]int64{}},
+		{"convA:5", map[string]int64{"convA": 5}},
+		{"convA:5, convB:7", map[string]int64{"convA": 5, "convB": 7}},
+		{"convA:5,convA:3", map[string]int64{"convA": 5}},
+		{"p2p:a:b:9", map[string]int64{"p2p:a:b": 9}},
+		{"convA,:5,convB:x,convC:0,convD:-1,convE:2", map[string]int64{"convE": 2}},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, ParseResumeCursors(tc.raw), tc.raw)
+	}
+}
@@ -0,0 +1,213 @@
+package connectroute
+
+import (
+	rediskey "ChatServer/consts/redisKey"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultOpTimeout 单次路由读写的 Redis 超时
+	DefaultOpTimeout = time.Second
+	// DefaultNodeHeartbeatInterval 节点存活 Key 的续期周期，需明显小于 ConnectNodeTTL
+	DefaultNodeHeartbeatInterval = 10 * time.Second
+)
+
+// luaDeleteRouteIfOwner 仅当路由仍指向指定节点时删除
+// KEYS[1]: 路由 Hash
+// ARGV[1]: field(device_id)
+// ARGV[2]: 期望的 node_id
+// 返回: 1 表示已删除，0 表示路由已被其他节点接管或不存在
+const luaDeleteRouteIfOwner = `
+if redis.call('HGET', KEYS[1], ARGV[1]) == ARGV[2] then
+	return redis.call('HDEL', KEYS[1], ARGV[1])
+end
+return 0
+`
+
+// luaRefreshRoute 心跳续期路由
+// KEYS[1]: 路由 Hash
+// ARGV[1]: field(device_id)
+// ARGV[2]: 本节点 node_id
+// ARGV[3]: 过期时间（秒）
+// 返回: 1 表示已续期，0 表示设备已被其他节点接管（不覆盖）
+const luaRefreshRoute = `
+local owner = redis.call('HGET', KEYS[1], ARGV[1])
+if owner and owner ~= ARGV[2] then
+	return 0
+end
+if not owner then
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+end
+redis.call('EXPIRE', KEYS[1], ARGV[3])
+return 1
+`
+
+var (
+	deleteRouteIfOwnerScript = redis.NewScript(luaDeleteRouteIfOwner)
+	refreshRouteScript       = redis.NewScript(luaRefreshRoute)
+)
+
+// Node 描述一个 connect 节点。
+// ID 每次进程启动都重新生成，节点重启后旧路由会因 ID 对不上而被识别为过期路由。
+type Node struct {
+	ID   string
+	Addr string
+}
+
+// RegistryConfig 路由注册表配置
+type RegistryConfig struct {
+	// RouteTTL 用户路由 Hash 的过期时间，连接心跳时续期
+	RouteTTL time.Duration
+	// NodeTTL 节点存活 Key 的过期时间
+	NodeTTL time.Duration
+	// NodeHeartbeatInterval 节点存活 Key 的续期周期
+	NodeHeartbeatInterval time.Duration
+	// OpTimeout 单次 Redis 操作超时
+	OpTimeout time.Duration
+}
+
+// Registry 维护本节点上连接的集群路由（user_uuid/device_id -> node_id）。
+// 路由结构：
+// - connect:route:user:{uuid} Hash，field=device_id，value=node_id，TTL 由心跳续期；
+// - connect:node:{node_id} String，value=gRPC 地址，TTL 由节点心跳续期；
+// - connect:nodes ZSet，member=node_id，score=节点存活 Key 过期时间，供按主题广播时枚举节点。
+// 节点宕机后节点 Key 过期，路由方（Router）查到指向失联节点的路由时会顺手清理。
+type Registry struct {
+	rdb  *redis.Client
+	node Node
+	cfg  RegistryConfig
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewRegistry 创建路由注册表，rdb 为 nil 时返回 nil（单节点模式，不登记路由）
+func NewRegistry(rdb *redis.Client, node Node, cfg RegistryConfig) *Registry {
+	if rdb == nil {
+		return nil
+	}
+	if cfg.RouteTTL <= 0 {
+		cfg.RouteTTL = rediskey.ConnectRouteTTL
+	}
+	if cfg.NodeTTL <= 0 {
+		cfg.NodeTTL = rediskey.ConnectNodeTTL
+	}
+	if cfg.NodeHeartbeatInterval <= 0 || cfg.NodeHeartbeatInterval >= cfg.NodeTTL {
+		cfg.NodeHeartbeatInterval = min(DefaultNodeHeartbeatInterval, cfg.NodeTTL/3)
+	}
+	if cfg.OpTimeout <= 0 {
+		cfg.OpTimeout = DefaultOpTimeout
+	}
+	return &Registry{
+		rdb:    rdb,
+		node:   node,
+		cfg:    cfg,
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+}
+
+// Node 返回本节点信息
+func (r *Registry) Node() Node {
+	return r.node
+}
+
+// Start 登记节点存活并启动后台续期，首次登记失败直接返回错误
+func (r *Registry) Start(ctx context.Context) error {
+	if err := r.heartbeatNode(ctx); err != nil {
+		close(r.doneCh)
+		return err
+	}
+	go r.nodeLoop()
+	return nil
+}
+
+// Stop 停止节点续期并删除节点存活 Key。
+// 删除后其他节点上的 Router 会把指向本节点的路由视为过期路由并清理。
+func (r *Registry) Stop(ctx context.Context) error {
+	r.stopOnce.Do(func() {
+		close(r.stopCh)
+	})
+	<-r.doneCh
+
+	opCtx, cancel := context.WithTimeout(ctx, r.cfg.OpTimeout)
+	defer cancel()
+	pipe := r.rdb.TxPipeline()
+	pipe.Del(opCtx, rediskey.ConnectNodeKey(r.node.ID))
+	pipe.ZRem(opCtx, rediskey.ConnectNodesKey(), r.node.ID)
+	_, err := pipe.Exec(opCtx)
+	return err
+}
+
+// Register 登记设备路由到本节点
+func (r *Registry) Register(ctx context.Context, userUUID, deviceID string) error {
+	opCtx, cancel := context.WithTimeout(ctx, r.cfg.OpTimeout)
+	defer cancel()
+
+	key := rediskey.ConnectRouteKey(userUUID)
+	pipe := r.rdb.TxPipeline()
+	pipe.HSet(opCtx, key, deviceID, r.node.ID)
+	pipe.Expire(opCtx, key, r.cfg.RouteTTL)
+	_, err := pipe.Exec(opCtx)
+	return err
+}
+
+// Unregister 注销设备路由。
+// 只删除仍指向本节点的路由，设备已在其他节点重连时保留新路由。
+func (r *Registry) Unregister(ctx context.Context, userUUID, deviceID string) error {
+	opCtx, cancel := context.WithTimeout(ctx, r.cfg.OpTimeout)
+	defer cancel()
+
+	err := deleteRouteIfOwnerScript.Run(opCtx, r.rdb, []string{rediskey.ConnectRouteKey(userUUID)}, deviceID, r.node.ID).Err()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	return err
+}
+
+// Refresh 心跳续期路由 TTL。
+// 路由缺失（过期或被误清理）时补写；已被其他节点接管时不覆盖。
+func (r *Registry) Refresh(ctx context.Context, userUUID, deviceID string) error {
+	opCtx, cancel := context.WithTimeout(ctx, r.cfg.OpTimeout)
+	defer cancel()
+
+	ttlSeconds := int64(r.cfg.RouteTTL / time.Second)
+	return refreshRouteScript.Run(opCtx, r.rdb, []string{rediskey.ConnectRouteKey(userUUID)}, deviceID, r.node.ID, ttlSeconds).Err()
+}
+
+// nodeLoop 周期续期节点存活 Key
+func (r *Registry) nodeLoop() {
+	defer close(r.doneCh)
+
+	ticker := time.NewTicker(r.cfg.NodeHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+			// 单次续期失败不退出，等待下一个周期重试；连续失败超过 NodeTTL 时其他节点会视本节点为宕机。
+			_ = r.heartbeatNode(context.Background())
+		}
+	}
+}
+
+// heartbeatNode 写入节点存活 Key，并在节点集合中刷新过期时间
+func (r *Registry) heartbeatNode(ctx context.Context) error {
+	opCtx, cancel := context.WithTimeout(ctx, r.cfg.OpTimeout)
+	defer cancel()
+	pipe := r.rdb.TxPipeline()
+	pipe.Set(opCtx, rediskey.ConnectNodeKey(r.node.ID), r.node.Addr, r.cfg.NodeTTL)
+	pipe.ZAdd(opCtx, rediskey.ConnectNodesKey(), redis.Z{
+		Score:  float64(time.Now().Add(r.cfg.NodeTTL).UnixMilli()),
+		Member: r.node.ID,
+	})
+	_, err := pipe.Exec(opCtx)
+	return err
+}
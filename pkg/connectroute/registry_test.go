@@ -0,0 +1,69 @@
+package connectroute
+
+import (
+	rediskey "ChatServer/consts/redisKey"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryStartStop(t *testing.T) {
+	cluster := newTestCluster(t)
+	ctx := context.Background()
+	reg, _ := cluster.addNode("n1", "node-1")
+
+	addr, err := cluster.mr.Get(rediskey.ConnectNodeKey("n1"))
+	require.NoError(t, err)
+	assert.Equal(t, "node-1", addr)
+	assert.Equal(t, rediskey.ConnectNodeTTL, cluster.mr.TTL(rediskey.ConnectNodeKey("n1")))
+	members, err := cluster.mr.ZMembers(rediskey.ConnectNodesKey())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"n1"}, members)
+
+	require.NoError(t, reg.Stop(ctx))
+	assert.False(t, cluster.mr.Exists(rediskey.ConnectNodeKey("n1")))
+	assert.False(t, cluster.mr.Exists(rediskey.ConnectNodesKey()))
+}
+
+func TestRegistryRegisterAndUnregisterIfOwner(t *testing.T) {
+	cluster := newTestCluster(t)
+	ctx := context.Background()
+	reg1, _ := cluster.addNode("n1", "node-1")
+	reg2, _ := cluster.addNode("n2", "node-2")
+	key := rediskey.ConnectRouteKey("alice")
+
+	require.NoError(t, reg1.Register(ctx, "alice", "a1"))
+	assert.Equal(t, "n1", cluster.mr.HGet(key, "a1"))
+	assert.Equal(t, rediskey.ConnectRouteTTL, cluster.mr.TTL(key))
+
+	// 设备重连到 n2 后，n1 上旧连接的注销不能删掉新路由。
+	require.NoError(t, reg2.Register(ctx, "alice", "a1"))
+	require.NoError(t, reg1.Unregister(ctx, "alice", "a1"))
+	assert.Equal(t, "n2", cluster.mr.HGet(key, "a1"))
+
+	require.NoError(t, reg2.Unregister(ctx, "alice", "a1"))
+	assert.False(t, cluster.mr.Exists(key))
+
+	// 注销不存在的路由不报错。
+	require.NoError(t, reg2.Unregister(ctx, "alice", "a1"))
+}
+
+func TestRegistryRefresh(t *testing.T) {
+	cluster := newTestCluster(t)
+	ctx := context.Background()
+	reg1, _ := cluster.addNode("n1", "node-1")
+	reg2, _ := cluster.addNode("n2", "node-2")
+	key := rediskey.ConnectRouteKey("alice")
+
+	// 路由缺失时补写。
+	require.NoError(t, reg1.Refresh(ctx, "alice", "a1"))
+	assert.Equal(t, "n1", cluster.mr.HGet(key, "a1"))
+	assert.Equal(t, rediskey.ConnectRouteTTL, cluster.mr.TTL(key))
+
+	// 已被其他节点接管时不覆盖。
+	require.NoError(t, reg2.Register(ctx, "alice", "a1"))
+	require.NoError(t, reg1.Refresh(ctx, "alice", "a1"))
+	assert.Equal(t, "n2", cluster.mr.HGet(key, "a1"))
+}
@@ -0,0 +1,510 @@
+package connectroute
+
+import (
+	connectpb "ChatServer/apps/connect/pb"
+	rediskey "ChatServer/consts/redisKey"
+	"ChatServer/pkg/logger"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Dialer 按节点地址创建 connect gRPC 客户端
+type Dialer func(addr string) (*grpc.ClientConn, error)
+
+// defaultDialer 明文 gRPC 拨号（集群内网）
+func defaultDialer(addr string) (*grpc.ClientConn, error) {
+	return grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+}
+
+// RouterConfig 路由客户端配置
+type RouterConfig struct {
+	// Dialer 自定义拨号，默认明文 gRPC
+	Dialer Dialer
+	// OpTimeout 单次路由查询的 Redis 超时
+	OpTimeout time.Duration
+	// Outbox 下行补发缓冲，非 nil 时带 seq 的推送会先写入缓冲（用户离线也写入），供重连补发
+	Outbox *Outbox
+	// Undelivered 未送达推送上报，非 nil 时用户/设备无在线连接的推送会交给离线通知链路
+	Undelivered UndeliveredSink
+}
+
+// Router 集群路由客户端，实现 connectpb.ConnectServiceClient。
+// 调用方（msg / user 服务）像使用单个 connect 客户端一样调用，Router 负责：
+// 1. 从 Redis 查询 user_uuid/device_id 所在节点；
+// 2. 按节点分组后并发转发到对应 connect 节点（节点侧只处理本地连接）；
+// 3. 发现路由指向已失联节点（节点存活 Key 已过期）时清理该路由；
+// 4. 配置 Outbox 时，带 seq 的推送在转发前写入补发缓冲；
+// 5. PublishToTopic 不查用户路由，转发到全部存活节点（不写补发缓冲）；
+// 6. 配置 Undelivered 时，单推/广推中没有任何设备收到（无路由或写队列拒绝）的用户上报给离线通知链路。
+type Router struct {
+	rdb         *redis.Client
+	dial        Dialer
+	opTimeout   time.Duration
+	outbox      *Outbox
+	undelivered UndeliveredSink
+
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+var _ connectpb.ConnectServiceClient = (*Router)(nil)
+
+// NewRouter 创建集群路由客户端，rdb 为 nil 时返回 nil（调用方应回退到单节点直连）
+func NewRouter(rdb *redis.Client, cfg RouterConfig) *Router {
+	if rdb == nil {
+		return nil
+	}
+	if cfg.Dialer == nil {
+		cfg.Dialer = defaultDialer
+	}
+	if cfg.OpTimeout <= 0 {
+		cfg.OpTimeout = DefaultOpTimeout
+	}
+	return &Router{
+		rdb:         rdb,
+		dial:        cfg.Dialer,
+		opTimeout:   cfg.OpTimeout,
+		outbox:      cfg.Outbox,
+		undelivered: cfg.Undelivered,
+		conns:       make(map[string]*grpc.ClientConn),
+	}
+}
+
+// Close 关闭所有节点连接
+func (r *Router) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var firstErr error
+	for addr, conn := range r.conns {
+		if err := conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(r.conns, addr)
+	}
+	return firstErr
+}
+
+// Lookup 查询用户的在线设备路由：device_id -> 节点地址（已过滤失联节点）
+func (r *Router) Lookup(ctx context.Context, userUUID string) (map[string]string, error) {
+	table, err := r.resolve(ctx, []string{userUUID})
+	if err != nil {
+		return nil, err
+	}
+	return table[userUUID], nil
+}
+
+// PushToDevice 转发到设备所在节点，设备无路由时返回 Delivered=false
+func (r *Router) PushToDevice(ctx context.Context, in *connectpb.PushToDeviceRequest, opts ...grpc.CallOption) (*connectpb.PushToDeviceResponse, error) {
+	if r.outbox != nil {
+		if err := r.outbox.AppendDevice(ctx, in.UserUuid, in.DeviceId, in.Message); err != nil {
+			logOutboxError(ctx, "PushToDevice", err)
+		}
+	}
+
+	devices, err := r.Lookup(ctx, in.UserUuid)
+	if err != nil {
+		return nil, err
+	}
+	addr, ok := devices[in.DeviceId]
+	if !ok {
+		r.notifyUndelivered(ctx, in.Message, in.DeviceId, in.UserUuid)
+		return &connectpb.PushToDeviceResponse{Delivered: false}, nil
+	}
+	client, err := r.client(addr)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.PushToDevice(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Delivered {
+		r.notifyUndelivered(ctx, in.Message, in.DeviceId, in.UserUuid)
+	}
+	return resp, nil
+}
+
+// PushToUser 转发到用户设备所在的每个节点，汇总投递设备数
+func (r *Router) PushToUser(ctx context.Context, in *connectpb.PushToUserRequest, opts ...grpc.CallOption) (*connectpb.PushToUserResponse, error) {
+	if r.outbox != nil {
+		if err := r.outbox.AppendUsers(ctx, []string{in.UserUuid}, in.Message); err != nil {
+			logOutboxError(ctx, "PushToUser", err)
+		}
+	}
+
+	devices, err := r.Lookup(ctx, in.UserUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	var delivered int32
+	err = r.forEachNode(ctx, groupByNode(map[string]map[string]string{in.UserUuid: devices}), func(ctx context.Context, client connectpb.ConnectServiceClient, _ []string) error {
+		resp, callErr := client.PushToUser(ctx, in, opts...)
+		if callErr != nil {
+			return callErr
+		}
+		mu.Lock()
+		delivered += resp.DeliveredCount
+		mu.Unlock()
+		return nil
+	})
+	if err != nil && delivered == 0 {
+		return nil, err
+	}
+	if delivered == 0 {
+		r.notifyUndelivered(ctx, in.Message, "", in.UserUuid)
+	}
+	return &connectpb.PushToUserResponse{DeliveredCount: delivered}, nil
+}
+
+// BroadcastToUsers 按节点分组转发。
+// 同一用户的设备分布在多个节点时，SuccessCount 会在每个节点各计一次，结果按请求用户数封顶。
+// 没有路由、或所在节点全部回报未入队的用户视为未送达；节点调用失败时结果未知，不上报。
+func (r *Router) BroadcastToUsers(ctx context.Context, in *connectpb.BroadcastToUsersRequest, opts ...grpc.CallOption) (*connectpb.BroadcastToUsersResponse, error) {
+	if r.outbox != nil {
+		if err := r.outbox.AppendUsers(ctx, in.UserUuids, in.Message); err != nil {
+			logOutboxError(ctx, "BroadcastToUsers", err)
+		}
+	}
+
+	table, err := r.resolve(ctx, in.UserUuids)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	out := &connectpb.BroadcastToUsersResponse{}
+	// reached 至少一个节点已入队，或节点调用失败（结果未知）的用户
+	reached := make(map[string]struct{}, len(in.UserUuids))
+	err = r.forEachNode(ctx, groupByNode(table), func(ctx context.Context, client connectpb.ConnectServiceClient, userUUIDs []string) error {
+		resp, callErr := client.BroadcastToUsers(ctx, &connectpb.BroadcastToUsersRequest{
+			UserUuids: userUUIDs,
+			Message:   in.Message,
+		}, opts...)
+		mu.Lock()
+		defer mu.Unlock()
+		if callErr != nil {
+			for _, userUUID := range userUUIDs {
+				reached[userUUID] = struct{}{}
+			}
+			return callErr
+		}
+		out.SuccessCount += resp.SuccessCount
+		out.TotalDelivered += resp.TotalDelivered
+		missed := make(map[string]struct{}, len(resp.UndeliveredUserUuids))
+		for _, userUUID := range resp.UndeliveredUserUuids {
+			missed[userUUID] = struct{}{}
+		}
+		for _, userUUID := range userUUIDs {
+			if _, ok := missed[userUUID]; !ok {
+				reached[userUUID] = struct{}{}
+			}
+		}
+		return nil
+	})
+	out.SuccessCount = min(out.SuccessCount, int32(len(in.UserUuids)))
+	for _, userUUID := range in.UserUuids {
+		if _, ok := reached[userUUID]; !ok {
+			out.UndeliveredUserUuids = append(out.UndeliveredUserUuids, userUUID)
+		}
+	}
+	r.notifyUndelivered(ctx, in.Message, "", out.UndeliveredUserUuids...)
+	if err != nil && out.TotalDelivered == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PublishToTopic 转发到全部存活节点，各节点投递本地订阅了该主题的连接，汇总投递连接数。
+// topic=all 即集群级系统广播，无需枚举用户。
+func (r *Router) PublishToTopic(ctx context.Context, in *connectpb.PublishToTopicRequest, opts ...grpc.CallOption) (*connectpb.PublishToTopicResponse, error) {
+	addrs, err := r.Nodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string][]string, len(addrs))
+	for _, addr := range addrs {
+		groups[addr] = nil
+	}
+
+	var mu sync.Mutex
+	var delivered int32
+	err = r.forEachNode(ctx, groups, func(ctx context.Context, client connectpb.ConnectServiceClient, _ []string) error {
+		resp, callErr := client.PublishToTopic(ctx, in, opts...)
+		if callErr != nil {
+			return callErr
+		}
+		mu.Lock()
+		delivered += resp.DeliveredCount
+		mu.Unlock()
+		return nil
+	})
+	if err != nil && delivered == 0 {
+		return nil, err
+	}
+	return &connectpb.PublishToTopicResponse{DeliveredCount: delivered}, nil
+}
+
+// Nodes 返回全部存活节点的 gRPC 地址（已去重）。
+// 节点集合中已过期的成员会被顺手清理。
+func (r *Router) Nodes(ctx context.Context) ([]string, error) {
+	opCtx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	key := rediskey.ConnectNodesKey()
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	nodeIDs, err := r.rdb.ZRangeByScore(opCtx, key, &redis.ZRangeBy{Min: now, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, err
+	}
+	// 清理失败不影响本次结果，过期成员已被上面的分数范围排除。
+	_ = r.rdb.ZRemRangeByScore(opCtx, key, "-inf", "("+now).Err()
+	if len(nodeIDs) == 0 {
+		return nil, nil
+	}
+
+	nodeKeys := make([]string, len(nodeIDs))
+	for i, nodeID := range nodeIDs {
+		nodeKeys[i] = rediskey.ConnectNodeKey(nodeID)
+	}
+	values, err := r.rdb.MGet(opCtx, nodeKeys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{}, len(values))
+	addrs := make([]string, 0, len(values))
+	for _, value := range values {
+		addr, _ := value.(string)
+		if addr == "" {
+			continue
+		}
+		if _, ok := seen[addr]; ok {
+			continue
+		}
+		seen[addr] = struct{}{}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// KickConnection 转发到设备所在节点，设备无路由时返回 Success=false
+func (r *Router) KickConnection(ctx context.Context, in *connectpb.KickConnectionRequest, opts ...grpc.CallOption) (*connectpb.KickConnectionResponse, error) {
+	devices, err := r.Lookup(ctx, in.UserUuid)
+	if err != nil {
+		return nil, err
+	}
+	addr, ok := devices[in.DeviceId]
+	if !ok {
+		return &connectpb.KickConnectionResponse{Success: false}, nil
+	}
+	client, err := r.client(addr)
+	if err != nil {
+		return nil, err
+	}
+	return client.KickConnection(ctx, in, opts...)
+}
+
+// GetOnlineStatus 向用户设备所在节点查询物理在线状态并合并
+func (r *Router) GetOnlineStatus(ctx context.Context, in *connectpb.GetOnlineStatusRequest, opts ...grpc.CallOption) (*connectpb.GetOnlineStatusResponse, error) {
+	resp, err := r.BatchGetOnlineStatus(ctx, &connectpb.BatchGetOnlineStatusRequest{UserUuids: []string{in.UserUuid}}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	item := resp.Items[0]
+	return &connectpb.GetOnlineStatusResponse{
+		IsOnline:      item.IsOnline,
+		OnlineDevices: item.OnlineDevices,
+	}, nil
+}
+
+// BatchGetOnlineStatus 按节点分组查询并按请求顺序合并结果，无路由的用户视为离线
+func (r *Router) BatchGetOnlineStatus(ctx context.Context, in *connectpb.BatchGetOnlineStatusRequest, opts ...grpc.CallOption) (*connectpb.BatchGetOnlineStatusResponse, error) {
+	table, err := r.resolve(ctx, in.UserUuids)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	merged := make(map[string][]string, len(in.UserUuids))
+	err = r.forEachNode(ctx, groupByNode(table), func(ctx context.Context, client connectpb.ConnectServiceClient, userUUIDs []string) error {
+		resp, callErr := client.BatchGetOnlineStatus(ctx, &connectpb.BatchGetOnlineStatusRequest{UserUuids: userUUIDs}, opts...)
+		if callErr != nil {
+			return callErr
+		}
+		mu.Lock()
+		for _, item := range resp.Items {
+			merged[item.UserUuid] = append(merged[item.UserUuid], item.OnlineDevices...)
+		}
+		mu.Unlock()
+		return nil
+	})
+	if err != nil && len(merged) == 0 && len(table) > 0 {
+		return nil, err
+	}
+
+	items := make([]*connectpb.UserOnlineStatus, 0, len(in.UserUuids))
+	for _, userUUID := range in.UserUuids {
+		devices := merged[userUUID]
+		items = append(items, &connectpb.UserOnlineStatus{
+			UserUuid:      userUUID,
+			IsOnline:      len(devices) > 0,
+			OnlineDevices: devices,
+		})
+	}
+	return &connectpb.BatchGetOnlineStatusResponse{Items: items}, nil
+}
+
+// resolve 批量查询路由：user_uuid -> device_id -> 节点地址。
+// 指向失联节点的路由不返回，并尽力从 Redis 中清理。
+func (r *Router) resolve(ctx context.Context, userUUIDs []string) (map[string]map[string]string, error) {
+	table := make(map[string]map[string]string, len(userUUIDs))
+	if len(userUUIDs) == 0 {
+		return table, nil
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.opTimeout)
+	defer cancel()
+
+	pipe := r.rdb.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(userUUIDs))
+	for i, userUUID := range userUUIDs {
+		cmds[i] = pipe.HGetAll(opCtx, rediskey.ConnectRouteKey(userUUID))
+	}
+	if _, err := pipe.Exec(opCtx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	nodeIDs := make([]string, 0)
+	nodeIndex := make(map[string]int)
+	routes := make(map[string]map[string]string, len(userUUIDs))
+	for i, userUUID := range userUUIDs {
+		devices := cmds[i].Val()
+		if len(devices) == 0 {
+			continue
+		}
+		routes[userUUID] = devices
+		for _, nodeID := range devices {
+			if _, ok := nodeIndex[nodeID]; !ok {
+				nodeIndex[nodeID] = len(nodeIDs)
+				nodeIDs = append(nodeIDs, nodeID)
+			}
+		}
+	}
+	if len(nodeIDs) == 0 {
+		return table, nil
+	}
+
+	nodeKeys := make([]string, len(nodeIDs))
+	for i, nodeID := range nodeIDs {
+		nodeKeys[i] = rediskey.ConnectNodeKey(nodeID)
+	}
+	addrs, err := r.rdb.MGet(opCtx, nodeKeys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	stale := r.rdb.Pipeline()
+	staleCount := 0
+	for userUUID, devices := range routes {
+		for deviceID, nodeID := range devices {
+			addr, _ := addrs[nodeIndex[nodeID]].(string)
+			if addr == "" {
+				deleteRouteIfOwnerScript.Eval(opCtx, stale, []string{rediskey.ConnectRouteKey(userUUID)}, deviceID, nodeID)
+				staleCount++
+				continue
+			}
+			if table[userUUID] == nil {
+				table[userUUID] = make(map[string]string, len(devices))
+			}
+			table[userUUID][deviceID] = addr
+		}
+	}
+	if staleCount > 0 {
+		// 清理失败不影响本次路由结果，下次查询会再次尝试。
+		_, _ = stale.Exec(opCtx)
+	}
+	return table, nil
+}
+
+// forEachNode 并发对每个节点执行 fn，返回第一个错误
+func (r *Router) forEachNode(ctx context.Context, groups map[string][]string, fn func(ctx context.Context, client connectpb.ConnectServiceClient, userUUIDs []string) error) error {
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
+	setErr := func(err error) {
+		errMu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		errMu.Unlock()
+	}
+
+	for addr, userUUIDs := range groups {
+		client, err := r.client(addr)
+		if err != nil {
+			setErr(err)
+			continue
+		}
+		wg.Add(1)
+		go func(client connectpb.ConnectServiceClient, userUUIDs []string) {
+			defer wg.Done()
+			if err := fn(ctx, client, userUUIDs); err != nil {
+				setErr(err)
+			}
+		}(client, userUUIDs)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// client 获取（或创建并缓存）节点的 gRPC 客户端
+func (r *Router) client(addr string) (connectpb.ConnectServiceClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if conn, ok := r.conns[addr]; ok {
+		return connectpb.NewConnectServiceClient(conn), nil
+	}
+	conn, err := r.dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	r.conns[addr] = conn
+	return connectpb.NewConnectServiceClient(conn), nil
+}
+
+// groupByNode 将路由表转换为 节点地址 -> 用户列表（同一用户在同一节点只出现一次）
+func groupByNode(table map[string]map[string]string) map[string][]string {
+	groups := make(map[string][]string)
+	for userUUID, devices := range table {
+		seen := make(map[string]struct{}, len(devices))
+		for _, addr := range devices {
+			if _, ok := seen[addr]; ok {
+				continue
+			}
+			seen[addr] = struct{}{}
+			groups[addr] = append(groups[addr], userUUID)
+		}
+	}
+	return groups
+}
+
+// logOutboxError 补发缓冲写入失败不影响实时投递，仅记录日志（重连时客户端会收到 resync）
+func logOutboxError(ctx context.Context, method string, err error) {
+	logger.Warn(ctx, "写入下行补发缓冲失败",
+		logger.String("method", method),
+		logger.ErrorField("error", err),
+	)
+}
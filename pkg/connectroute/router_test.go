@@ -0,0 +1,345 @@
+package connectroute
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	connectpb "ChatServer/apps/connect/pb"
+	rediskey "ChatServer/consts/redisKey"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeNode 内存版 connect 节点：只有 accepting 中的设备会被视为入队成功。
+type fakeNode struct {
+	connectpb.UnimplementedConnectServiceServer
+
+	mu        sync.Mutex
+	accepting map[string][]string // user_uuid -> device_id
+	fail      bool
+	received  []string // 收到广播的用户（按调用记录）
+}
+
+func (n *fakeNode) accept(userUUID string, deviceIDs ...string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.accepting[userUUID] = append(n.accepting[userUUID], deviceIDs...)
+}
+
+func (n *fakeNode) BroadcastToUsers(_ context.Context, in *connectpb.BroadcastToUsersRequest) (*connectpb.BroadcastToUsersResponse, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.fail {
+		return nil, errors.New("node unavailable")
+	}
+	out := &connectpb.BroadcastToUsersResponse{}
+	for _, userUUID := range in.UserUuids {
+		n.received = append(n.received, userUUID)
+		if devices := len(n.accepting[userUUID]); devices > 0 {
+			out.SuccessCount++
+			out.TotalDelivered += int32(devices)
+		} else {
+			out.UndeliveredUserUuids = append(out.UndeliveredUserUuids, userUUID)
+		}
+	}
+	return out, nil
+}
+
+func (n *fakeNode) PushToUser(_ context.Context, in *connectpb.PushToUserRequest) (*connectpb.PushToUserResponse, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.fail {
+		return nil, errors.New("node unavailable")
+	}
+	return &connectpb.PushToUserResponse{DeliveredCount: int32(len(n.accepting[in.UserUuid]))}, nil
+}
+
+func (n *fakeNode) PushToDevice(_ context.Context, in *connectpb.PushToDeviceRequest) (*connectpb.PushToDeviceResponse, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.fail {
+		return nil, errors.New("node unavailable")
+	}
+	for _, deviceID := range n.accepting[in.UserUuid] {
+		if deviceID == in.DeviceId {
+			return &connectpb.PushToDeviceResponse{Delivered: true}, nil
+		}
+	}
+	return &connectpb.PushToDeviceResponse{Delivered: false}, nil
+}
+
+func (n *fakeNode) BatchGetOnlineStatus(_ context.Context, in *connectpb.BatchGetOnlineStatusRequest) (*connectpb.BatchGetOnlineStatusResponse, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.fail {
+		return nil, errors.New("node unavailable")
+	}
+	out := &connectpb.BatchGetOnlineStatusResponse{}
+	for _, userUUID := range in.UserUuids {
+		devices := n.accepting[userUUID]
+		out.Items = append(out.Items, &connectpb.UserOnlineStatus{
+			UserUuid:      userUUID,
+			IsOnline:      len(devices) > 0,
+			OnlineDevices: devices,
+		})
+	}
+	return out, nil
+}
+
+// recordingSink 记录未送达上报。
+type recordingSink struct {
+	mu     sync.Mutex
+	events []Undelivered
+}
+
+func (s *recordingSink) Undelivered(_ context.Context, events []Undelivered) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, events...)
+}
+
+// users 返回上报的 user_uuid/device_id（排序后）。
+func (s *recordingSink) users() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := make([]string, 0, len(s.events))
+	for _, event := range s.events {
+		key := event.UserUUID
+		if event.DeviceID != "" {
+			key += "/" + event.DeviceID
+		}
+		users = append(users, key)
+	}
+	sort.Strings(users)
+	return users
+}
+
+// testCluster 基于 miniredis 与 bufconn 的多节点集群。
+type testCluster struct {
+	t         *testing.T
+	mr        *miniredis.Miniredis
+	rdb       *redis.Client
+	listeners map[string]*bufconn.Listener
+}
+
+func newTestCluster(t *testing.T) *testCluster {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	return &testCluster{t: t, mr: mr, rdb: rdb, listeners: make(map[string]*bufconn.Listener)}
+}
+
+// addNode 启动一个节点并登记节点存活，返回其注册表与 gRPC 实现。
+func (c *testCluster) addNode(nodeID, addr string) (*Registry, *fakeNode) {
+	c.t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	node := &fakeNode{accepting: make(map[string][]string)}
+	connectpb.RegisterConnectServiceServer(server, node)
+	go func() { _ = server.Serve(listener) }()
+	c.t.Cleanup(server.Stop)
+	c.listeners[addr] = listener
+
+	registry := NewRegistry(c.rdb, Node{ID: nodeID, Addr: addr}, RegistryConfig{})
+	require.NoError(c.t, registry.Start(context.Background()))
+	c.t.Cleanup(func() { _ = registry.Stop(context.Background()) })
+	return registry, node
+}
+
+// router 创建经 bufconn 拨号的路由客户端。
+func (c *testCluster) router(cfg RouterConfig) *Router {
+	c.t.Helper()
+	cfg.Dialer = func(addr string) (*grpc.ClientConn, error) {
+		listener, ok := c.listeners[addr]
+		if !ok {
+			return nil, errors.New("unknown node " + addr)
+		}
+		return grpc.NewClient("passthrough:///"+addr,
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+	}
+	router := NewRouter(c.rdb, cfg)
+	c.t.Cleanup(func() { _ = router.Close() })
+	return router
+}
+
+func TestRouterBroadcastReportsUndelivered(t *testing.T) {
+	cluster := newTestCluster(t)
+	ctx := context.Background()
+	reg1, node1 := cluster.addNode("n1", "node-1")
+	reg2, node2 := cluster.addNode("n2", "node-2")
+	sink := &recordingSink{}
+	router := cluster.router(RouterConfig{Undelivered: sink})
+
+	// alice 在 n1 入队成功；bob 有路由但写队列拒绝；carol 的两台设备只有 n2 上入队成功；dave 无路由。
+	require.NoError(t, reg1.Register(ctx, "alice", "a1"))
+	node1.accept("alice", "a1")
+	require.NoError(t, reg2.Register(ctx, "bob", "b1"))
+	require.NoError(t, reg1.Register(ctx, "carol", "c1"))
+	require.NoError(t, reg2.Register(ctx, "carol", "c2"))
+	node2.accept("carol", "c2")
+
+	resp, err := router.BroadcastToUsers(ctx, &connectpb.BroadcastToUsersRequest{
+		UserUuids: []string{"alice", "bob", "carol", "dave"},
+		Message:   &connectpb.MessageEnvelope{Type: "message"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), resp.SuccessCount)
+	assert.Equal(t, int32(2), resp.TotalDelivered)
+	assert.Equal(t, []string{"bob", "dave"}, resp.UndeliveredUserUuids)
+	assert.Equal(t, []string{"bob", "dave"}, sink.users())
+}
+
+func TestRouterBroadcastSkipsUnknownResultOnNodeFailure(t *testing.T) {
+	cluster := newTestCluster(t)
+	ctx := context.Background()
+	reg1, node1 := cluster.addNode("n1", "node-1")
+	reg2, node2 := cluster.addNode("n2", "node-2")
+	sink := &recordingSink{}
+	router := cluster.router(RouterConfig{Undelivered: sink})
+
+	require.NoError(t, reg1.Register(ctx, "alice", "a1"))
+	node1.accept("alice", "a1")
+	require.NoError(t, reg2.Register(ctx, "bob", "b1"))
+	node2.fail = true
+
+	resp, err := router.BroadcastToUsers(ctx, &connectpb.BroadcastToUsersRequest{
+		UserUuids: []string{"alice", "bob"},
+		Message:   &connectpb.MessageEnvelope{Type: "message"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), resp.TotalDelivered)
+	// 节点调用失败时 bob 是否收到未知，不上报。
+	assert.Empty(t, resp.UndeliveredUserUuids)
+	assert.Empty(t, sink.users())
+}
+
+func TestRouterLookupCleansStaleRoutes(t *testing.T) {
+	cluster := newTestCluster(t)
+	ctx := context.Background()
+	reg1, _ := cluster.addNode("n1", "node-1")
+	reg2, _ := cluster.addNode("n2", "node-2")
+	router := cluster.router(RouterConfig{})
+	key := rediskey.ConnectRouteKey("alice")
+
+	require.NoError(t, reg1.Register(ctx, "alice", "a1"))
+	require.NoError(t, reg2.Register(ctx, "alice", "a2"))
+	devices, err := router.Lookup(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a1": "node-1", "a2": "node-2"}, devices)
+
+	// n2 停止续期后节点 Key 过期，指向它的路由被过滤并清理；n1 正常续期。
+	cluster.mr.FastForward(rediskey.ConnectNodeTTL)
+	require.NoError(t, reg1.heartbeatNode(ctx))
+	devices, err = router.Lookup(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a1": "node-1"}, devices)
+	assert.False(t, cluster.mr.Exists(rediskey.ConnectNodeKey("n2")))
+	assert.Empty(t, cluster.mr.HGet(key, "a2"))
+	assert.Equal(t, "n1", cluster.mr.HGet(key, "a1"))
+
+	// 节点重启后 ID 变化，旧 ID 的路由同样视为过期。
+	cluster.mr.HSet(key, "a3", "n-old")
+	devices, err = router.Lookup(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a1": "node-1"}, devices)
+	assert.Empty(t, cluster.mr.HGet(key, "a3"))
+}
+
+func TestRouterPushToUserMergesNodes(t *testing.T) {
+	cluster := newTestCluster(t)
+	ctx := context.Background()
+	reg1, node1 := cluster.addNode("n1", "node-1")
+	reg2, node2 := cluster.addNode("n2", "node-2")
+	sink := &recordingSink{}
+	router := cluster.router(RouterConfig{Undelivered: sink})
+
+	require.NoError(t, reg1.Register(ctx, "alice", "a1"))
+	require.NoError(t, reg2.Register(ctx, "alice", "a2"))
+	require.NoError(t, reg2.Register(ctx, "alice", "a3"))
+	node1.accept("alice", "a1")
+	node2.accept("alice", "a2", "a3")
+
+	resp, err := router.PushToUser(ctx, &connectpb.PushToUserRequest{UserUuid: "alice", Message: &connectpb.MessageEnvelope{Type: "message"}})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), resp.DeliveredCount)
+
+	// 部分节点失败时按已投递结果返回，不上报未送达。
+	node2.mu.Lock()
+	node2.fail = true
+	node2.mu.Unlock()
+	resp, err = router.PushToUser(ctx, &connectpb.PushToUserRequest{UserUuid: "alice", Message: &connectpb.MessageEnvelope{Type: "message"}})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), resp.DeliveredCount)
+	assert.Empty(t, sink.users())
+
+	// 无路由的用户上报未送达。
+	resp, err = router.PushToUser(ctx, &connectpb.PushToUserRequest{UserUuid: "bob", Message: &connectpb.MessageEnvelope{Type: "message"}})
+	require.NoError(t, err)
+	assert.Zero(t, resp.DeliveredCount)
+	assert.Equal(t, []string{"bob"}, sink.users())
+}
+
+func TestRouterBatchGetOnlineStatusMergesNodes(t *testing.T) {
+	cluster := newTestCluster(t)
+	ctx := context.Background()
+	reg1, node1 := cluster.addNode("n1", "node-1")
+	reg2, node2 := cluster.addNode("n2", "node-2")
+	router := cluster.router(RouterConfig{})
+
+	require.NoError(t, reg1.Register(ctx, "alice", "a1"))
+	require.NoError(t, reg2.Register(ctx, "alice", "a2"))
+	require.NoError(t, reg2.Register(ctx, "bob", "b1"))
+	node1.accept("alice", "a1")
+	node2.accept("alice", "a2")
+	node2.accept("bob", "b1")
+
+	resp, err := router.BatchGetOnlineStatus(ctx, &connectpb.BatchGetOnlineStatusRequest{UserUuids: []string{"carol", "alice", "bob"}})
+	require.NoError(t, err)
+	require.Len(t, resp.Items, 3)
+
+	// 结果按请求顺序返回，同一用户多个节点上的设备合并。
+	assert.Equal(t, "carol", resp.Items[0].UserUuid)
+	assert.False(t, resp.Items[0].IsOnline)
+	assert.Equal(t, "alice", resp.Items[1].UserUuid)
+	assert.True(t, resp.Items[1].IsOnline)
+	assert.ElementsMatch(t, []string{"a1", "a2"}, resp.Items[1].OnlineDevices)
+	assert.Equal(t, []string{"b1"}, resp.Items[2].OnlineDevices)
+}
+
+func TestRouterNodes(t *testing.T) {
+	cluster := newTestCluster(t)
+	ctx := context.Background()
+	cluster.addNode("n1", "node-1")
+	cluster.addNode("n2", "node-2")
+	// 同一地址重启后的新旧节点 ID 只返回一次；分数已过期的成员被清理。
+	cluster.addNode("n1-restarted", "node-1")
+	_, err := cluster.rdb.ZAdd(ctx, rediskey.ConnectNodesKey(), redis.Z{
+		Score:  float64(time.Now().Add(-time.Second).UnixMilli()),
+		Member: "n-dead",
+	}).Result()
+	require.NoError(t, err)
+	router := cluster.router(RouterConfig{})
+
+	addrs, err := router.Nodes(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"node-1", "node-2"}, addrs)
+	members, err := cluster.mr.ZMembers(rediskey.ConnectNodesKey())
+	require.NoError(t, err)
+	assert.NotContains(t, members, "n-dead")
+}
@@ -0,0 +1,70 @@
+package connectroute
+
+import (
+	rediskey "ChatServer/consts/redisKey"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ticketBytes 票据随机字节数（base64url 编码后 43 个字符）
+const ticketBytes = 32
+
+// ErrTicketNotFound 票据不存在、已过期或已被兑换
+var ErrTicketNotFound = errors.New("connect ticket not found")
+
+// Ticket 一次性 WebSocket 接入票据内容。
+// 由 gateway 在已认证请求中签发，客户端以 /ws?ticket= 代替长期有效的 access token；
+// connect 握手时原子兑换（GETDEL），兑换后立即失效。
+type Ticket struct {
+	UserUUID string `json:"user_uuid"`
+	DeviceID string `json:"device_id"`
+	// ClientIP 签发时的客户端 IP，非空时兑换方 IP 必须一致
+	ClientIP string `json:"client_ip,omitempty"`
+	// TokenHash 签发时 access token 的 md5，兑换时与 auth:at 比对，登出/踢设备后票据随之失效
+	TokenHash string `json:"token_hash"`
+	// ExpiresAt access token 过期时间（unix 秒），0 表示未知；连接据此下发 auth_expiring
+	ExpiresAt int64 `json:"expires_at,omitempty"`
+}
+
+// IssueTicket 生成票据并写入 Redis，返回票据字符串
+func IssueTicket(ctx context.Context, rdb *redis.Client, ticket Ticket, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		ttl = rediskey.ConnectTicketTTL
+	}
+	raw := make([]byte, ticketBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	id := base64.RawURLEncoding.EncodeToString(raw)
+
+	payload, err := json.Marshal(ticket)
+	if err != nil {
+		return "", err
+	}
+	if err := rdb.Set(ctx, rediskey.ConnectTicketKey(id), payload, ttl).Err(); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// RedeemTicket 兑换票据（读取并删除），票据不存在时返回 ErrTicketNotFound
+func RedeemTicket(ctx context.Context, rdb *redis.Client, id string) (*Ticket, error) {
+	payload, err := rdb.GetDel(ctx, rediskey.ConnectTicketKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrTicketNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ticket Ticket
+	if err := json.Unmarshal(payload, &ticket); err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
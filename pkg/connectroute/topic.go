@@ -0,0 +1,91 @@
+package connectroute
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 主题约定：
+// - all：全部在线连接（集群级系统广播），每个连接隐式订阅；
+// - platform:{platform}：按平台，如 platform:android；
+// - app:{platform}:{major} / app:{platform}:{version}：按 App 主版本或完整版本，如 app:android:2；
+// - user:{user_uuid}：按用户；
+// 以上为握手时自动加入的保留主题，客户端不能显式订阅或退订；
+// 其余主题由客户端经 subscribe 帧显式订阅，仅用于公共频道类广播，不应承载私有数据。
+const (
+	// TopicAll 全部在线连接
+	TopicAll = "all"
+
+	topicPlatformPrefix = "platform:"
+	topicAppPrefix      = "app:"
+	topicUserPrefix     = "user:"
+	// maxTopicLen 主题名最大长度，与 PublishToTopicRequest.topic 校验一致
+	maxTopicLen = 128
+)
+
+var (
+	// knownPlatforms 与设备信息中的 platform 枚举一致（小写）
+	knownPlatforms = map[string]struct{}{
+		"ios":     {},
+		"android": {},
+		"web":     {},
+		"windows": {},
+		"mac":     {},
+	}
+	appVersionPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+){0,3}$`)
+	topicPattern      = regexp.MustCompile(`^[A-Za-z0-9_.:\-]+$`)
+)
+
+// PlatformTopic 平台主题，platform 大小写不敏感
+func PlatformTopic(platform string) string {
+	return topicPlatformPrefix + strings.ToLower(platform)
+}
+
+// AppVersionTopic App 版本主题，version 可以是主版本号（"2"）或完整版本号（"2.3.1"）
+func AppVersionTopic(platform, version string) string {
+	return topicAppPrefix + strings.ToLower(platform) + ":" + version
+}
+
+// UserTopic 用户主题
+func UserTopic(userUUID string) string {
+	return topicUserPrefix + userUUID
+}
+
+// AutoTopics 计算连接握手时自动加入的主题（不含隐式的 all）。
+// platform 不在已知枚举内时不加入平台与版本主题；appVersion 允许带 v 前缀，格式非法时只忽略版本主题。
+func AutoTopics(userUUID, platform, appVersion string) []string {
+	topics := make([]string, 0, 4)
+	if userUUID != "" {
+		topics = append(topics, UserTopic(userUUID))
+	}
+
+	platform = strings.ToLower(strings.TrimSpace(platform))
+	if _, ok := knownPlatforms[platform]; !ok {
+		return topics
+	}
+	topics = append(topics, PlatformTopic(platform))
+
+	version := strings.TrimPrefix(strings.TrimSpace(strings.ToLower(appVersion)), "v")
+	if !appVersionPattern.MatchString(version) {
+		return topics
+	}
+	major, _, _ := strings.Cut(version, ".")
+	topics = append(topics, AppVersionTopic(platform, major))
+	if version != major {
+		topics = append(topics, AppVersionTopic(platform, version))
+	}
+	return topics
+}
+
+// IsReservedTopic 是否为握手自动加入的保留主题
+func IsReservedTopic(topic string) bool {
+	return topic == TopicAll ||
+		strings.HasPrefix(topic, topicPlatformPrefix) ||
+		strings.HasPrefix(topic, topicAppPrefix) ||
+		strings.HasPrefix(topic, topicUserPrefix)
+}
+
+// ValidSubscribeTopic 校验客户端可显式订阅的主题：非保留、长度不超过 128、仅含字母数字与 _.:-
+func ValidSubscribeTopic(topic string) bool {
+	return topic != "" && len(topic) <= maxTopicLen && !IsReservedTopic(topic) && topicPattern.MatchString(topic)
+}
@@ -0,0 +1,63 @@
+package connectroute
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoTopics(t *testing.T) {
+	tests := []struct {
+		name       string
+		userUUID   string
+		platform   string
+		appVersion string
+		want       []string
+	}{
+		{name: "full_version", userUUID: "u1", platform: "android", appVersion: "2.3.1", want: []string{"user:u1", "platform:android", "app:android:2", "app:android:2.3.1"}},
+		{name: "major_only", userUUID: "u1", platform: "ios", appVersion: "3", want: []string{"user:u1", "platform:ios", "app:ios:3"}},
+		{name: "v_prefix_and_case", userUUID: "u1", platform: " iOS ", appVersion: "V1.2", want: []string{"user:u1", "platform:ios", "app:ios:1", "app:ios:1.2"}},
+		{name: "four_segments", userUUID: "u1", platform: "web", appVersion: "1.2.3.4", want: []string{"user:u1", "platform:web", "app:web:1", "app:web:1.2.3.4"}},
+		{name: "too_many_segments", userUUID: "u1", platform: "web", appVersion: "1.2.3.4.5", want: []string{"user:u1", "platform:web"}},
+		{name: "prerelease_ignored", userUUID: "u1", platform: "mac", appVersion: "2.0.0-beta", want: []string{"user:u1", "platform:mac"}},
+		{name: "empty_version", userUUID: "u1", platform: "windows", want: []string{"user:u1", "platform:windows"}},
+		{name: "trailing_dot", userUUID: "u1", platform: "android", appVersion: "2.", want: []string{"user:u1", "platform:android"}},
+		{name: "unknown_platform", userUUID: "u1", platform: "linux", appVersion: "2.3", want: []string{"user:u1"}},
+		{name: "no_user", platform: "android", appVersion: "2", want: []string{"platform:android", "app:android:2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, AutoTopics(tt.userUUID, tt.platform, tt.appVersion))
+		})
+	}
+}
+
+func TestValidSubscribeTopic(t *testing.T) {
+	tests := []struct {
+		topic    string
+		reserved bool
+		valid    bool
+	}{
+		{topic: "live:room-1", valid: true},
+		{topic: "news.sports_cn", valid: true},
+		{topic: "allx", valid: true},
+		{topic: strings.Repeat("a", maxTopicLen), valid: true},
+		{topic: strings.Repeat("a", maxTopicLen+1)},
+		{topic: ""},
+		{topic: "has space"},
+		{topic: "slash/topic"},
+		{topic: "中文"},
+		{topic: "all", reserved: true},
+		{topic: "platform:android", reserved: true},
+		{topic: "app:ios:2", reserved: true},
+		{topic: "user:u1", reserved: true},
+		{topic: "user:", reserved: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.topic, func(t *testing.T) {
+			assert.Equal(t, tt.reserved, IsReservedTopic(tt.topic))
+			assert.Equal(t, tt.valid, ValidSubscribeTopic(tt.topic))
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package connectroute
+
+import (
+	connectpb "ChatServer/apps/connect/pb"
+	"context"
+)
+
+// Undelivered 一条未送达的推送：目标用户没有在线设备，或指定设备不在线
+type Undelivered struct {
+	UserUUID string
+	// DeviceID 非空表示 PushToDevice 的目标设备
+	DeviceID string
+	Message  *connectpb.MessageEnvelope
+}
+
+// UndeliveredSink 接收未送达推送，交给离线通知链路（APNs/FCM/厂商通道）。
+// Router 在推送路径上同步调用，实现方应尽快返回（异步投递、失败只记日志）。
+type UndeliveredSink interface {
+	Undelivered(ctx context.Context, events []Undelivered)
+}
+
+// notifyUndelivered 上报未送达推送，未配置 sink 时忽略
+func (r *Router) notifyUndelivered(ctx context.Context, message *connectpb.MessageEnvelope, deviceID string, userUUIDs ...string) {
+	reportUndelivered(ctx, r.undelivered, message, deviceID, userUUIDs...)
+}
+
+// reportUndelivered 按用户构造未送达事件交给 sink
+func reportUndelivered(ctx context.Context, sink UndeliveredSink, message *connectpb.MessageEnvelope, deviceID string, userUUIDs ...string) {
+	if sink == nil || len(userUUIDs) == 0 {
+		return
+	}
+	events := make([]Undelivered, 0, len(userUUIDs))
+	for _, userUUID := range userUUIDs {
+		events = append(events, Undelivered{UserUUID: userUUID, DeviceID: deviceID, Message: message})
+	}
+	sink.Undelivered(ctx, events)
+}
@@ -0,0 +1,69 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+
+	connectpb "ChatServer/apps/connect/pb"
+
+	"google.golang.org/grpc"
+)
+
+// MaxBroadcastUsers BroadcastToUsers 单次调用的用户数上限（与 connect.proto 校验规则一致）。
+const MaxBroadcastUsers = 1000
+
+// Broadcaster connect 服务批量推送能力，connectpb.ConnectServiceClient 即满足该接口。
+type Broadcaster interface {
+	BroadcastToUsers(ctx context.Context, in *connectpb.BroadcastToUsersRequest, opts ...grpc.CallOption) (*connectpb.BroadcastToUsersResponse, error)
+}
+
+// BroadcastResult 分批推送汇总结果。
+type BroadcastResult struct {
+	// Chunks 实际发起的 BroadcastToUsers 调用次数。
+	Chunks int
+	// FailedChunks 调用失败的批次数。
+	FailedChunks int
+	// SuccessCount 至少一个设备成功入队的用户数（各批次累加）。
+	SuccessCount int
+	// TotalDelivered 成功入队的设备总数（各批次累加）。
+	TotalDelivered int
+	// Err 各失败批次的错误（errors.Join）。
+	Err error
+}
+
+// BroadcastInChunks 将用户列表按 chunkSize 切分后依次调用 BroadcastToUsers。
+// 单批失败不影响后续批次；chunkSize <= 0 或超过上限时使用 MaxBroadcastUsers。
+func BroadcastInChunks(ctx context.Context, broadcaster Broadcaster, userUUIDs []string, envelope *connectpb.MessageEnvelope, chunkSize int) BroadcastResult {
+	var result BroadcastResult
+	if broadcaster == nil || len(userUUIDs) == 0 {
+		return result
+	}
+	if chunkSize <= 0 || chunkSize > MaxBroadcastUsers {
+		chunkSize = MaxBroadcastUsers
+	}
+
+	var errs []error
+	for start := 0; start < len(userUUIDs); start += chunkSize {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		end := min(start+chunkSize, len(userUUIDs))
+		result.Chunks++
+
+		resp, err := broadcaster.BroadcastToUsers(ctx, &connectpb.BroadcastToUsersRequest{
+			UserUuids: userUUIDs[start:end],
+			Message:   envelope,
+		})
+		if err != nil {
+			result.FailedChunks++
+			errs = append(errs, err)
+			continue
+		}
+		result.SuccessCount += int(resp.GetSuccessCount())
+		result.TotalDelivered += int(resp.GetTotalDelivered())
+	}
+
+	result.Err = errors.Join(errs...)
+	return result
+}
@@ -0,0 +1,86 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	connectpb "ChatServer/apps/connect/pb"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+// countingBroadcaster 记录 BroadcastToUsers 调用，failAt 为返回错误的调用序号（从 1 开始）
+type countingBroadcaster struct {
+	mu      sync.Mutex
+	calls   int
+	maxSize int
+	failAt  int
+}
+
+func (b *countingBroadcaster) BroadcastToUsers(_ context.Context, in *connectpb.BroadcastToUsersRequest, _ ...grpc.CallOption) (*connectpb.BroadcastToUsersResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls++
+	b.maxSize = max(b.maxSize, len(in.UserUuids))
+	if b.failAt > 0 && b.calls == b.failAt {
+		return nil, errors.New("connect unavailable")
+	}
+	n := int32(len(in.UserUuids))
+	return &connectpb.BroadcastToUsersResponse{SuccessCount: n, TotalDelivered: n}, nil
+}
+
+func makeMembers(n int) []string {
+	members := make([]string, n)
+	for i := range members {
+		members[i] = fmt.Sprintf("u%019d", i)
+	}
+	return members
+}
+
+func TestBroadcastInChunks(t *testing.T) {
+	envelope := &connectpb.MessageEnvelope{Type: "message"}
+
+	t.Run("default_chunk_size", func(t *testing.T) {
+		broadcaster := &countingBroadcaster{}
+		result := BroadcastInChunks(context.Background(), broadcaster, makeMembers(2500), envelope, 0)
+		assert.Equal(t, 3, result.Chunks)
+		assert.Equal(t, MaxBroadcastUsers, broadcaster.maxSize)
+		assert.Equal(t, 2500, result.SuccessCount)
+		assert.Equal(t, 2500, result.TotalDelivered)
+		assert.NoError(t, result.Err)
+	})
+
+	t.Run("chunk_size_capped", func(t *testing.T) {
+		broadcaster := &countingBroadcaster{}
+		result := BroadcastInChunks(context.Background(), broadcaster, makeMembers(1500), envelope, 5000)
+		assert.Equal(t, 2, result.Chunks)
+		assert.Equal(t, MaxBroadcastUsers, broadcaster.maxSize)
+	})
+
+	t.Run("failure_continues", func(t *testing.T) {
+		broadcaster := &countingBroadcaster{failAt: 2}
+		result := BroadcastInChunks(context.Background(), broadcaster, makeMembers(3000), envelope, 0)
+		assert.Equal(t, 3, result.Chunks)
+		assert.Equal(t, 1, result.FailedChunks)
+		assert.Equal(t, 2000, result.SuccessCount)
+		assert.Error(t, result.Err)
+	})
+
+	t.Run("canceled_context_stops", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		broadcaster := &countingBroadcaster{}
+		result := BroadcastInChunks(ctx, broadcaster, makeMembers(10), envelope, 0)
+		assert.Zero(t, result.Chunks)
+		assert.ErrorIs(t, result.Err, context.Canceled)
+	})
+
+	t.Run("nothing_to_send", func(t *testing.T) {
+		assert.Zero(t, BroadcastInChunks(context.Background(), nil, makeMembers(10), envelope, 0).Chunks)
+		assert.Zero(t, BroadcastInChunks(context.Background(), &countingBroadcaster{}, nil, envelope, 0).Chunks)
+	})
+}
@@ -0,0 +1,149 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	connectpb "ChatServer/apps/connect/pb"
+)
+
+const (
+	// DefaultReadDiffusionThreshold 默认读扩散阈值：成员数超过该值的群改用读扩散。
+	DefaultReadDiffusionThreshold = 2000
+	// DefaultStoreBatchSize 写扩散时单次批量更新的成员会话数。
+	DefaultStoreBatchSize = 500
+)
+
+var errStoreRequired = errors.New("conversation store is required")
+
+// Mode 群消息扩散模式。
+type Mode int
+
+const (
+	// ModeWrite 写扩散：每条消息更新每个成员的会话行（未读数、最后消息）。
+	ModeWrite Mode = iota
+	// ModeRead 读扩散：消息只更新一次群会话，成员未读数在读取时计算。
+	ModeRead
+)
+
+// String 返回模式名称（用于日志与指标）。
+func (m Mode) String() string {
+	if m == ModeRead {
+		return "read"
+	}
+	return "write"
+}
+
+// ChooseMode 根据群成员数选择扩散模式。
+// threshold <= 0 时使用 DefaultReadDiffusionThreshold。
+func ChooseMode(memberCount, threshold int) Mode {
+	if threshold <= 0 {
+		threshold = DefaultReadDiffusionThreshold
+	}
+	if memberCount > threshold {
+		return ModeRead
+	}
+	return ModeWrite
+}
+
+// UnreadCount 计算读扩散模式下的未读数：max_seq - read_seq（不小于 0）。
+func UnreadCount(maxSeq, readSeq int64) int64 {
+	if readSeq >= maxSeq {
+		return 0
+	}
+	if readSeq < 0 {
+		readSeq = 0
+	}
+	return maxSeq - readSeq
+}
+
+// GroupMessage 一条待扩散的群消息。
+type GroupMessage struct {
+	ConvID     string
+	GroupUUID  string
+	MsgID      string
+	Seq        int64
+	SenderUUID string
+	Preview    string
+	SendTime   time.Time
+}
+
+// ConversationStore 会话存储。
+type ConversationStore interface {
+	// UpdateMemberConversations 写扩散：更新指定成员的会话行（最后消息 + 未读数 +1，发送者自己不加未读）。
+	UpdateMemberConversations(ctx context.Context, msg *GroupMessage, memberUUIDs []string) error
+	// UpdateGroupConversation 读扩散：更新群共享会话行（max_seq + 最后消息）。
+	UpdateGroupConversation(ctx context.Context, msg *GroupMessage) error
+}
+
+// Options 扩散器配置。
+type Options struct {
+	// ReadDiffusionThreshold 读扩散阈值，<=0 时使用默认值。
+	ReadDiffusionThreshold int
+	// StoreBatchSize 写扩散批量大小，<=0 时使用默认值。
+	StoreBatchSize int
+	// BroadcastChunkSize BroadcastToUsers 单批用户数，<=0 或超过上限时使用 MaxBroadcastUsers。
+	BroadcastChunkSize int
+}
+
+// Result 单条消息的扩散结果。
+type Result struct {
+	Mode      Mode
+	Members   int
+	Broadcast BroadcastResult
+}
+
+// Dispatcher 群消息扩散器：按成员数选择写/读扩散更新会话，再分批推送在线连接。
+type Dispatcher struct {
+	store       ConversationStore
+	broadcaster Broadcaster
+	opts        Options
+}
+
+// NewDispatcher 创建群消息扩散器。broadcaster 为 nil 时只更新会话不推送。
+func NewDispatcher(store ConversationStore, broadcaster Broadcaster, opts Options) *Dispatcher {
+	if opts.ReadDiffusionThreshold <= 0 {
+		opts.ReadDiffusionThreshold = DefaultReadDiffusionThreshold
+	}
+	if opts.StoreBatchSize <= 0 {
+		opts.StoreBatchSize = DefaultStoreBatchSize
+	}
+	if opts.BroadcastChunkSize <= 0 || opts.BroadcastChunkSize > MaxBroadcastUsers {
+		opts.BroadcastChunkSize = MaxBroadcastUsers
+	}
+	return &Dispatcher{store: store, broadcaster: broadcaster, opts: opts}
+}
+
+// Dispatch 扩散一条群消息。
+// 会话更新失败直接返回错误（消息已落库，调用方可重试）；推送失败只记录在结果中，
+// 离线成员依赖拉取补齐。
+func (d *Dispatcher) Dispatch(ctx context.Context, msg *GroupMessage, memberUUIDs []string, envelope *connectpb.MessageEnvelope) (*Result, error) {
+	if d.store == nil {
+		return nil, errStoreRequired
+	}
+
+	result := &Result{
+		Mode:    ChooseMode(len(memberUUIDs), d.opts.ReadDiffusionThreshold),
+		Members: len(memberUUIDs),
+	}
+
+	switch result.Mode {
+	case ModeRead:
+		if err := d.store.UpdateGroupConversation(ctx, msg); err != nil {
+			return nil, err
+		}
+	default:
+		for start := 0; start < len(memberUUIDs); start += d.opts.StoreBatchSize {
+			end := min(start+d.opts.StoreBatchSize, len(memberUUIDs))
+			if err := d.store.UpdateMemberConversations(ctx, msg, memberUUIDs[start:end]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if d.broadcaster != nil && envelope != nil {
+		result.Broadcast = BroadcastInChunks(ctx, d.broadcaster, memberUUIDs, envelope, d.opts.BroadcastChunkSize)
+	}
+	return result, nil
+}
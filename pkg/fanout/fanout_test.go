@@ -0,0 +1,175 @@
+package fanout
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	connectpb "ChatServer/apps/connect/pb"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryConvRow 模拟 conversation 表中的一行
+type memoryConvRow struct {
+	lastMsgID   string
+	lastPreview string
+	unreadCount int
+	readSeq     int64
+}
+
+// memoryConvStore 内存会话存储，与 ConversationDB 语义一致：写扩散每个成员更新一行，读扩散只更新群共享行与发送者位点
+type memoryConvStore struct {
+	mu          sync.Mutex
+	members     map[string]*memoryConvRow
+	maxSeq      int64
+	rowsUpdated int
+	statements  int
+}
+
+func newMemoryConvStore(memberUUIDs []string) *memoryConvStore {
+	members := make(map[string]*memoryConvRow, len(memberUUIDs))
+	for _, uuid := range memberUUIDs {
+		members[uuid] = &memoryConvRow{}
+	}
+	return &memoryConvStore{members: members}
+}
+
+func (s *memoryConvStore) UpdateMemberConversations(_ context.Context, msg *GroupMessage, memberUUIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statements++
+	for _, uuid := range memberUUIDs {
+		row := s.members[uuid]
+		row.lastMsgID = msg.MsgID
+		row.lastPreview = msg.Preview
+		if uuid == msg.SenderUUID {
+			row.readSeq = max(row.readSeq, msg.Seq)
+		} else {
+			row.unreadCount++
+		}
+		s.rowsUpdated++
+	}
+	return nil
+}
+
+func (s *memoryConvStore) UpdateGroupConversation(_ context.Context, msg *GroupMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statements += 2
+	s.maxSeq = max(s.maxSeq, msg.Seq)
+	s.rowsUpdated++
+	if row := s.members[msg.SenderUUID]; row != nil && row.readSeq < msg.Seq {
+		row.readSeq = msg.Seq
+		s.rowsUpdated++
+	}
+	return nil
+}
+
+// unread 按扩散模式读取成员未读数
+func (s *memoryConvStore) unread(mode Mode, uuid string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	row := s.members[uuid]
+	if mode == ModeRead {
+		return UnreadCount(s.maxSeq, row.readSeq)
+	}
+	return int64(row.unreadCount)
+}
+
+func TestChooseModeAndUnreadCount(t *testing.T) {
+	assert.Equal(t, ModeWrite, ChooseMode(DefaultReadDiffusionThreshold, 0))
+	assert.Equal(t, ModeRead, ChooseMode(DefaultReadDiffusionThreshold+1, 0))
+	assert.Equal(t, ModeRead, ChooseMode(11, 10))
+	assert.Equal(t, int64(20), UnreadCount(120, 100))
+	assert.Zero(t, UnreadCount(100, 120))
+	assert.Equal(t, int64(5), UnreadCount(5, -1))
+}
+
+func TestDispatcherDispatch(t *testing.T) {
+	envelope := &connectpb.MessageEnvelope{Type: "message"}
+	send := func(t *testing.T, d *Dispatcher, members []string, seq int64) *Result {
+		t.Helper()
+		msg := &GroupMessage{ConvID: "g1", GroupUUID: "g1", MsgID: fmt.Sprintf("m%d", seq), Seq: seq, SenderUUID: members[0], SendTime: time.Now()}
+		result, err := d.Dispatch(context.Background(), msg, members, envelope)
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("write_diffusion_small_group", func(t *testing.T) {
+		members := makeMembers(1200)
+		store := newMemoryConvStore(members)
+		broadcaster := &countingBroadcaster{}
+		d := NewDispatcher(store, broadcaster, Options{ReadDiffusionThreshold: 2000})
+
+		result := send(t, d, members, 1)
+		assert.Equal(t, ModeWrite, result.Mode)
+		assert.Equal(t, len(members), store.rowsUpdated)
+		assert.Zero(t, store.unread(ModeWrite, members[0]))
+		assert.Equal(t, int64(1), store.unread(ModeWrite, members[1]))
+		assert.Equal(t, 2, broadcaster.calls)
+		assert.Equal(t, MaxBroadcastUsers, broadcaster.maxSize)
+	})
+
+	t.Run("read_diffusion_large_group", func(t *testing.T) {
+		members := makeMembers(5000)
+		store := newMemoryConvStore(members)
+		broadcaster := &countingBroadcaster{}
+		d := NewDispatcher(store, broadcaster, Options{ReadDiffusionThreshold: 2000})
+
+		result := send(t, d, members, 1)
+		send(t, d, members, 2)
+		assert.Equal(t, ModeRead, result.Mode)
+		assert.Equal(t, 4, store.rowsUpdated, "group row + sender read_seq per message")
+		assert.Equal(t, 5, result.Broadcast.Chunks)
+		assert.Equal(t, len(members), result.Broadcast.SuccessCount)
+
+		// 未读数在读取时计算：发送者 0，其他成员 max_seq - read_seq。
+		assert.Zero(t, store.unread(ModeRead, members[0]))
+		assert.Equal(t, int64(2), store.unread(ModeRead, members[1]))
+	})
+
+	t.Run("store_required", func(t *testing.T) {
+		_, err := NewDispatcher(nil, nil, Options{}).Dispatch(context.Background(), &GroupMessage{}, makeMembers(1), envelope)
+		assert.Error(t, err)
+	})
+}
+
+// BenchmarkGroupFanout 对比写扩散与读扩散在不同群规模下单条消息的扩散开销：
+// rows/op 为会话存储更新的行数，stmts/op 为执行的 SQL 语句数（写扩散按 StoreBatchSize 分批），rpcs/op 为 BroadcastToUsers 调用次数（两种模式相同）。
+// 运行：go test ./pkg/fanout -run '^$' -bench BenchmarkGroupFanout -benchmem
+func BenchmarkGroupFanout(b *testing.B) {
+	envelope := &connectpb.MessageEnvelope{Type: "message"}
+	for _, size := range []int{500, 5000, 50000} {
+		members := makeMembers(size)
+		for _, mode := range []Mode{ModeWrite, ModeRead} {
+			threshold := size // 成员数不超过阈值：写扩散
+			if mode == ModeRead {
+				threshold = 1
+			}
+
+			b.Run(fmt.Sprintf("%s/members=%d", mode, size), func(b *testing.B) {
+				store := newMemoryConvStore(members)
+				broadcaster := &countingBroadcaster{}
+				d := NewDispatcher(store, broadcaster, Options{ReadDiffusionThreshold: threshold})
+				msg := &GroupMessage{ConvID: "g1", GroupUUID: "g1", MsgID: "m", SenderUUID: members[0], Preview: "hello", SendTime: time.Now()}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					msg.Seq = int64(i + 1)
+					if _, err := d.Dispatch(context.Background(), msg, members, envelope); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.StopTimer()
+				b.ReportMetric(float64(store.rowsUpdated)/float64(b.N), "rows/op")
+				b.ReportMetric(float64(store.statements)/float64(b.N), "stmts/op")
+				b.ReportMetric(float64(broadcaster.calls)/float64(b.N), "rpcs/op")
+			})
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package fanout
+
+import (
+	"context"
+
+	"ChatServer/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ConversationDB 基于 conversation / group_conversation 表的会话存储（群消息落库后由 msg 服务调用）。
+// 写扩散更新成员各自的会话行；读扩散只更新群共享行，成员行只维护 read_seq。
+type ConversationDB struct {
+	db *gorm.DB
+}
+
+var _ ConversationStore = (*ConversationDB)(nil)
+
+// NewConversationDB 创建会话存储。
+func NewConversationDB(db *gorm.DB) *ConversationDB {
+	return &ConversationDB{db: db}
+}
+
+// UpdateMemberConversations 写扩散：一条 UPDATE 更新一批成员的会话行。
+// 发送者自己不加未读，且已读位点推进到本条消息。
+func (s *ConversationDB) UpdateMemberConversations(ctx context.Context, msg *GroupMessage, memberUUIDs []string) error {
+	if len(memberUUIDs) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).
+		Model(&model.Conversation{}).
+		Where("conv_id = ? AND owner_uuid IN ?", msg.ConvID, memberUUIDs).
+		Updates(map[string]interface{}{
+			"last_msg_id":      msg.MsgID,
+			"last_msg_at":      msg.SendTime,
+			"last_msg_preview": msg.Preview,
+			"unread_count":     gorm.Expr("unread_count + IF(owner_uuid = ?, 0, 1)", msg.SenderUUID),
+			"read_seq":         gorm.Expr("IF(owner_uuid = ?, GREATEST(read_seq, ?), read_seq)", msg.SenderUUID, msg.Seq),
+		}).Error
+}
+
+// UpdateGroupConversation 读扩散：upsert 群共享会话行，并推进发送者自己的已读位点。
+// 乱序到达的旧消息只会被忽略（max_seq 取较大值，最后消息仅在 seq 更大时覆盖）。
+func (s *ConversationDB) UpdateGroupConversation(ctx context.Context, msg *GroupMessage) error {
+	sendTime := msg.SendTime
+	row := model.GroupConversation{
+		ConvId:        msg.ConvID,
+		GroupUuid:     msg.GroupUUID,
+		MaxSeq:        msg.Seq,
+		LastMsgId:     msg.MsgID,
+		LastMsgAt:     &sendTime,
+		LastMsgPrev:   msg.Preview,
+		LastMsgSender: msg.SenderUUID,
+	}
+	newer := func(column string) clause.Assignment {
+		return clause.Assignment{
+			Column: clause.Column{Name: column},
+			Value:  gorm.Expr("IF(VALUES(max_seq) > max_seq, VALUES(" + column + "), " + column + ")"),
+		}
+	}
+
+	// max_seq 必须最后赋值：MySQL 按顺序执行赋值，前面的 IF 需要比较更新前的 max_seq。
+	db := s.db.WithContext(ctx)
+	err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "conv_id"}},
+		DoUpdates: clause.Set{
+			newer("last_msg_id"),
+			newer("last_msg_at"),
+			newer("last_msg_preview"),
+			newer("last_msg_sender"),
+			{Column: clause.Column{Name: "max_seq"}, Value: gorm.Expr("GREATEST(max_seq, VALUES(max_seq))")},
+		},
+	}).Create(&row).Error
+	if err != nil {
+		return err
+	}
+	// 两条语句均幂等，第二条失败时调用方重试即可，无需事务。
+	return db.Model(&model.Conversation{}).
+		Where("conv_id = ? AND owner_uuid = ? AND read_seq < ?", msg.ConvID, msg.SenderUUID, msg.Seq).
+		Update("read_seq", msg.Seq).Error
+}
+
+// GroupUnreadCounts 读取读扩散群的成员未读数（max_seq - read_seq），key 为 conv_id。
+// 没有群共享行的会话（写扩散群）不出现在结果中，调用方继续使用 conversation.unread_count。
+func (s *ConversationDB) GroupUnreadCounts(ctx context.Context, ownerUUID string, convIDs []string) (map[string]int64, error) {
+	if len(convIDs) == 0 {
+		return map[string]int64{}, nil
+	}
+	var rows []struct {
+		ConvId  string
+		MaxSeq  int64
+		ReadSeq int64
+	}
+	err := s.db.WithContext(ctx).
+		Table(model.Conversation{}.TableName()+" AS c").
+		Select("c.conv_id, g.max_seq, c.read_seq").
+		Joins("JOIN "+model.GroupConversation{}.TableName()+" AS g ON g.conv_id = c.conv_id").
+		Where("c.owner_uuid = ? AND c.conv_id IN ? AND c.deleted_at IS NULL", ownerUUID, convIDs).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	unread := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		unread[row.ConvId] = UnreadCount(row.MaxSeq, row.ReadSeq)
+	}
+	return unread, nil
+}
+
+// MarkRead 推进成员已读位点并清零写扩散未读数，seq 小于当前位点时位点不回退。
+func (s *ConversationDB) MarkRead(ctx context.Context, ownerUUID, convID string, seq int64) error {
+	return s.db.WithContext(ctx).
+		Model(&model.Conversation{}).
+		Where("conv_id = ? AND owner_uuid = ?", convID, ownerUUID).
+		Updates(map[string]interface{}{
+			"read_seq":     gorm.Expr("GREATEST(read_seq, ?)", seq),
+			"unread_count": 0,
+		}).Error
+}
@@ -0,0 +1,82 @@
+package fanout
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gmysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// sqlRecorder 记录 DryRun 模式下生成的 SQL。
+type sqlRecorder struct {
+	gormlogger.Interface
+	mu   sync.Mutex
+	sqls []string
+}
+
+func (r *sqlRecorder) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+	r.mu.Lock()
+	r.sqls = append(r.sqls, sql)
+	r.mu.Unlock()
+}
+
+func newDryRunStore(t *testing.T) (*ConversationDB, *sqlRecorder) {
+	t.Helper()
+	recorder := &sqlRecorder{Interface: gormlogger.Discard}
+	db, err := gorm.Open(gmysql.New(gmysql.Config{DSN: "user:pass@tcp(127.0.0.1:1)/chat_server", SkipInitializeWithVersion: true}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 recorder,
+	})
+	require.NoError(t, err)
+	return NewConversationDB(db), recorder
+}
+
+func TestConversationDBWriteDiffusion(t *testing.T) {
+	store, recorder := newDryRunStore(t)
+	msg := &GroupMessage{ConvID: "g1", GroupUUID: "g1", MsgID: "m7", Seq: 7, SenderUUID: "alice", Preview: "hi", SendTime: time.Now()}
+
+	require.NoError(t, store.UpdateMemberConversations(context.Background(), msg, nil))
+	require.Empty(t, recorder.sqls)
+
+	// 一批成员一条 UPDATE，发送者不加未读且推进已读位点。
+	require.NoError(t, store.UpdateMemberConversations(context.Background(), msg, []string{"alice", "bob"}))
+	require.Len(t, recorder.sqls, 1)
+	sql := recorder.sqls[0]
+	assert.True(t, strings.HasPrefix(sql, "UPDATE `conversation` SET"), sql)
+	assert.Contains(t, sql, "`unread_count`=unread_count + IF(owner_uuid = 'alice', 0, 1)")
+	assert.Contains(t, sql, "`read_seq`=IF(owner_uuid = 'alice', GREATEST(read_seq, 7), read_seq)")
+	assert.Contains(t, sql, "WHERE (conv_id = 'g1' AND owner_uuid IN ('alice','bob'))")
+}
+
+func TestConversationDBReadDiffusion(t *testing.T) {
+	ctx := context.Background()
+	store, recorder := newDryRunStore(t)
+	msg := &GroupMessage{ConvID: "g1", GroupUUID: "g1", MsgID: "m7", Seq: 7, SenderUUID: "alice", Preview: "hi", SendTime: time.Now()}
+
+	// 群共享行 upsert（旧消息不覆盖最后消息、max_seq 不回退）+ 发送者已读位点，成员行不动。
+	require.NoError(t, store.UpdateGroupConversation(ctx, msg))
+	require.Len(t, recorder.sqls, 2)
+	upsert := recorder.sqls[0]
+	assert.True(t, strings.HasPrefix(upsert, "INSERT INTO `group_conversation`"), upsert)
+	assert.Contains(t, upsert, "`last_msg_id`=IF(VALUES(max_seq) > max_seq, VALUES(last_msg_id), last_msg_id)")
+	assert.True(t, strings.HasSuffix(upsert, "`max_seq`=GREATEST(max_seq, VALUES(max_seq))"), upsert)
+	assert.Contains(t, recorder.sqls[1], "WHERE (conv_id = 'g1' AND owner_uuid = 'alice' AND read_seq < 7)")
+
+	_, err := store.GroupUnreadCounts(ctx, "bob", []string{"g1", "g2"})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT c.conv_id, g.max_seq, c.read_seq FROM conversation AS c JOIN group_conversation AS g ON g.conv_id = c.conv_id "+
+		"WHERE c.owner_uuid = 'bob' AND c.conv_id IN ('g1','g2') AND c.deleted_at IS NULL", recorder.sqls[2])
+
+	require.NoError(t, store.MarkRead(ctx, "bob", "g1", 7))
+	assert.Contains(t, recorder.sqls[3], "`read_seq`=GREATEST(read_seq, 7),`unread_count`=0")
+}
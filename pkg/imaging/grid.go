@@ -0,0 +1,154 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// MaxGridTiles 九宫格最多容纳的头像数
+const MaxGridTiles = 9
+
+// GridOptions 九宫格拼图选项
+type GridOptions struct {
+	// Size 输出图片边长（像素），输出为正方形
+	Size int
+	// Gap 格子之间以及格子与边框的间距（像素）
+	Gap int
+	// Background 背景色
+	Background color.Color
+	// Placeholder 缺失头像（下载或解码失败）时的填充色
+	Placeholder color.Color
+}
+
+// DefaultGridOptions 返回默认的群头像拼图选项（仿微信风格）
+func DefaultGridOptions() GridOptions {
+	return GridOptions{
+		Size:        240,
+		Gap:         6,
+		Background:  color.RGBA{R: 0xDD, G: 0xDE, B: 0xE0, A: 0xFF},
+		Placeholder: color.RGBA{R: 0xB8, G: 0xBB, B: 0xC0, A: 0xFF},
+	}
+}
+
+// ComposeGrid 将最多 9 张头像拼成九宫格图片
+// 布局规则（与微信群头像一致）：
+//   - 1 张：单格居中
+//   - 2~4 张：2 列
+//   - 5~9 张：3 列
+//   - 不满一行的余数放在第一行并水平居中，整体垂直居中
+//
+// tiles 中的 nil 使用占位色填充；超过 9 张时只取前 9 张。
+func ComposeGrid(tiles []image.Image, opts GridOptions) *image.RGBA {
+	if opts.Size <= 0 {
+		opts = DefaultGridOptions()
+	}
+	if len(tiles) > MaxGridTiles {
+		tiles = tiles[:MaxGridTiles]
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, opts.Size, opts.Size))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(opts.Background), image.Point{}, draw.Src)
+
+	n := len(tiles)
+	if n == 0 {
+		return canvas
+	}
+
+	cols := gridColumns(n)
+	rows := (n + cols - 1) / cols
+	cell := (opts.Size - opts.Gap*(cols+1)) / cols
+	if cell <= 0 {
+		return canvas
+	}
+
+	totalHeight := rows*cell + (rows-1)*opts.Gap
+	top := (opts.Size - totalHeight) / 2
+	firstRow := n - (rows-1)*cols
+
+	idx := 0
+	for row := 0; row < rows; row++ {
+		inRow := cols
+		if row == 0 {
+			inRow = firstRow
+		}
+		rowWidth := inRow*cell + (inRow-1)*opts.Gap
+		left := (opts.Size - rowWidth) / 2
+		y := top + row*(cell+opts.Gap)
+
+		for col := 0; col < inRow; col++ {
+			x := left + col*(cell+opts.Gap)
+			rect := image.Rect(x, y, x+cell, y+cell)
+			if tile := tiles[idx]; tile != nil {
+				draw.Draw(canvas, rect, ScaleSquare(tile, cell), image.Point{}, draw.Over)
+			} else {
+				draw.Draw(canvas, rect, image.NewUniform(opts.Placeholder), image.Point{}, draw.Src)
+			}
+			idx++
+		}
+	}
+
+	return canvas
+}
+
+// ScaleSquare 居中裁剪为正方形后缩放到 size×size
+// 缩小时按区域平均采样，放大时退化为最近邻，避免引入第三方图像库。
+func ScaleSquare(src image.Image, size int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	b := src.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	if side <= 0 || size <= 0 {
+		return dst
+	}
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+
+	for dy := 0; dy < size; dy++ {
+		sy0 := y0 + dy*side/size
+		sy1 := y0 + (dy+1)*side/size
+		if sy1 <= sy0 {
+			sy1 = sy0 + 1
+		}
+		for dx := 0; dx < size; dx++ {
+			sx0 := x0 + dx*side/size
+			sx1 := x0 + (dx+1)*side/size
+			if sx1 <= sx0 {
+				sx1 = sx0 + 1
+			}
+
+			var r, g, bl, a, count uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					bl += uint64(pb)
+					a += uint64(pa)
+					count++
+				}
+			}
+			dst.SetRGBA64(dx, dy, color.RGBA64{
+				R: uint16(r / count),
+				G: uint16(g / count),
+				B: uint16(bl / count),
+				A: uint16(a / count),
+			})
+		}
+	}
+	return dst
+}
+
+// gridColumns 根据头像数量计算列数
+func gridColumns(n int) int {
+	switch {
+	case n <= 1:
+		return 1
+	case n <= 4:
+		return 2
+	default:
+		return 3
+	}
+}
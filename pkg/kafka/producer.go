@@ -33,6 +33,16 @@ func (p *Producer) Send(ctx context.Context, data []byte) error {
 	})
 }
 
+// SendBatch 一次写入多条消息（同步写入按批等待，逐条 Send 会多次等待批量超时）
+func (p *Producer) SendBatch(ctx context.Context, values [][]byte) error {
+	now := time.Now()
+	messages := make([]kafka.Message, 0, len(values))
+	for _, value := range values {
+		messages = append(messages, kafka.Message{Value: value, Time: now})
+	}
+	return p.writer.WriteMessages(ctx, messages...)
+}
+
 // Close 关闭生产者
 func (p *Producer) Close() error {
 	return p.writer.Close()
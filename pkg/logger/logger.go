@@ -3,6 +3,7 @@ package logger
 import (
 	"ChatServer/pkg/ctxmeta"
 	"context"
+	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -197,3 +198,38 @@ func Duration(key string, value time.Duration) zap.Field {
 func Time(key string, value time.Time) zap.Field {
 	return zap.Time(key, value)
 }
+
+// sensitiveQueryKeys 日志中需要脱敏的 query 参数（小写）
+var sensitiveQueryKeys = map[string]struct{}{
+	"token":         {},
+	"access_token":  {},
+	"refresh_token": {},
+	"ticket":        {},
+	"password":      {},
+}
+
+// Query 创建 URL query 字段，token/ticket 等敏感参数的值替换为 ***，其余参数保持原样与原顺序
+func Query(key, rawQuery string) zap.Field {
+	return zap.String(key, RedactQuery(rawQuery))
+}
+
+// RedactQuery 脱敏 URL query 中的敏感参数值
+func RedactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	parts := strings.Split(rawQuery, "&")
+	for i, part := range parts {
+		name, _, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if _, sensitive := sensitiveQueryKeys[strings.ToLower(name)]; sensitive {
+			parts[i] = part[:strings.IndexByte(part, '=')+1] + "***"
+		}
+	}
+	return strings.Join(parts, "&")
+}
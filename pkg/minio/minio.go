@@ -444,6 +444,23 @@ func (c *MinIOClient) generateURL(objectName string) string {
 	return fmt.Sprintf("%s/%s/%s", baseURL, bucketName, objectName)
 }
 
+// ObjectNameFromURL 从 Upload 返回的访问 URL 反解对象名称
+// 仅识别本客户端 generateURL 生成的地址（BaseURL/bucket/object），其他外部地址返回 false。
+func (c *MinIOClient) ObjectNameFromURL(rawURL string) (string, bool) {
+	prefix := strings.TrimSuffix(c.config.BaseURL, "/") + "/" + c.config.BucketName + "/"
+	if !strings.HasPrefix(rawURL, prefix) {
+		return "", false
+	}
+	objectName := strings.TrimPrefix(rawURL, prefix)
+	if i := strings.IndexAny(objectName, "?#"); i >= 0 {
+		objectName = objectName[:i]
+	}
+	if objectName == "" {
+		return "", false
+	}
+	return objectName, true
+}
+
 // detectContentType 根据文件扩展名检测 Content-Type
 // ⚠️ 注意：此方法仅用于后备，不应作为主要的类型检测方式
 // 优先使用 http.DetectContentType 基于文件内容检测
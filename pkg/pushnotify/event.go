@@ -0,0 +1,79 @@
+package pushnotify
+
+import (
+	"ChatServer/pkg/connectroute"
+	"encoding/json"
+)
+
+// Event 未送达事件（Kafka 消息体，JSON）。
+// 由推送方的 connectroute.Router 在用户/设备无在线连接时产生，离线通知服务消费后下发系统推送。
+type Event struct {
+	UserUUID string `json:"user_uuid"`
+	// DeviceID 非空表示原推送只针对该设备（PushToDevice）
+	DeviceID string `json:"device_id,omitempty"`
+	// Type 原信封类型（message 等）
+	Type     string `json:"type"`
+	Seq      int64  `json:"seq,omitempty"`
+	ServerTs int64  `json:"server_ts,omitempty"`
+	TraceID  string `json:"trace_id,omitempty"`
+	// Data 原信封业务负载
+	Data []byte `json:"data,omitempty"`
+}
+
+// MessagePayload type=message 信封的 data（msg.MsgItem 的 JSON 形式，字段名同 proto）。
+// 离线通知只关心会话、发送者与内容预览。
+type MessagePayload struct {
+	MsgID    string   `json:"msg_id"`
+	ConvID   string   `json:"conv_id"`
+	FromUUID string   `json:"from_uuid"`
+	MsgType  int32    `json:"msg_type"`
+	Content  string   `json:"content"`
+	AtUsers  []string `json:"at_users"`
+}
+
+// newEvent 由未送达推送构造事件
+func newEvent(item connectroute.Undelivered) *Event {
+	return &Event{
+		UserUUID: item.UserUUID,
+		DeviceID: item.DeviceID,
+		Type:     item.Message.GetType(),
+		Seq:      item.Message.GetSeq(),
+		ServerTs: item.Message.GetServerTs(),
+		TraceID:  item.Message.GetTraceId(),
+		Data:     item.Message.GetData(),
+	}
+}
+
+// DecodeEvent 解析 Kafka 消息体
+func DecodeEvent(raw []byte) (*Event, error) {
+	var event Event
+	if err := json.Unmarshal(raw, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Message 按 type=message 解析 data，格式不符时返回 false
+func (e *Event) Message() (*MessagePayload, bool) {
+	if len(e.Data) == 0 {
+		return nil, false
+	}
+	var payload MessagePayload
+	if err := json.Unmarshal(e.Data, &payload); err != nil || payload.ConvID == "" {
+		return nil, false
+	}
+	return &payload, true
+}
+
+// Mentions 是否 @ 了指定用户（含 @All）
+func (p *MessagePayload) Mentions(userUUID string) bool {
+	for _, uuid := range p.AtUsers {
+		if uuid == userUUID || uuid == AtAllUUID {
+			return true
+		}
+	}
+	return false
+}
+
+// AtAllUUID @All 使用的特殊 UUID（见 msg.SendMessageRequest.at_users）
+const AtAllUUID = "00000000000000000000"
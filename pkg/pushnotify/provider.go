@@ -0,0 +1,97 @@
+package pushnotify
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// 推送通道名称（device_session.push_provider）
+const (
+	ProviderAPNs   = "apns"
+	ProviderFCM    = "fcm"
+	ProviderHuawei = "huawei"
+	ProviderXiaomi = "xiaomi"
+	ProviderOPPO   = "oppo"
+	ProviderVivo   = "vivo"
+)
+
+// SoundDefault 系统默认提示音
+const SoundDefault = "default"
+
+var (
+	// ErrTokenInvalid 推送 token 已失效（卸载/重装/过期），调用方应清除该 token
+	ErrTokenInvalid = errors.New("push token invalid")
+	// ErrProviderNotFound 没有可用的推送通道实现
+	ErrProviderNotFound = errors.New("push provider not found")
+)
+
+// Notification 一条待下发的系统推送
+type Notification struct {
+	// Provider 推送通道（apns/fcm/厂商）
+	Provider string `json:"provider"`
+	Token    string `json:"token"`
+	Platform string `json:"platform"`
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+	// CollapseKey 折叠标识（APNs apns-collapse-id / FCM collapse_key），同会话通知在通知栏互相覆盖
+	CollapseKey string `json:"collapse_key,omitempty"`
+	// Sound 提示音（APNs aps.sound / FCM notification.sound），空表示静默通知
+	Sound string `json:"sound,omitempty"`
+	// Data 透传给客户端的自定义字段（conv_id、msg_id、seq 等）
+	Data map[string]string `json:"data,omitempty"`
+}
+
+// Provider 推送通道实现（APNs/FCM/厂商通道或本地桩）
+type Provider interface {
+	// Name 通道名称，用于日志与注册
+	Name() string
+	// Send 下发一条推送，token 失效时返回 ErrTokenInvalid
+	Send(ctx context.Context, n *Notification) error
+}
+
+// Dispatcher 按 Notification.Provider 选择通道下发，未注册的通道交给 fallback
+type Dispatcher struct {
+	providers map[string]Provider
+	fallback  Provider
+}
+
+// NewDispatcher 创建分发器，fallback 可为 nil
+func NewDispatcher(fallback Provider, providers ...Provider) *Dispatcher {
+	d := &Dispatcher{providers: make(map[string]Provider, len(providers)), fallback: fallback}
+	for _, p := range providers {
+		d.providers[p.Name()] = p
+	}
+	return d
+}
+
+// Send 选择通道并下发
+func (d *Dispatcher) Send(ctx context.Context, n *Notification) error {
+	if p, ok := d.providers[n.Provider]; ok {
+		return p.Send(ctx, n)
+	}
+	if d.fallback != nil {
+		return d.fallback.Send(ctx, n)
+	}
+	return ErrProviderNotFound
+}
+
+// ValidProvider 判断通道名称是否受支持
+func ValidProvider(name string) bool {
+	switch name {
+	case ProviderAPNs, ProviderFCM, ProviderHuawei, ProviderXiaomi, ProviderOPPO, ProviderVivo:
+		return true
+	}
+	return false
+}
+
+// DefaultProvider 按设备平台推断默认通道：iOS 走 APNs，Android 走 FCM，其他平台不支持系统推送
+func DefaultProvider(platform string) string {
+	switch strings.ToLower(platform) {
+	case "ios":
+		return ProviderAPNs
+	case "android":
+		return ProviderFCM
+	}
+	return ""
+}
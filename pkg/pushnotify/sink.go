@@ -0,0 +1,124 @@
+package pushnotify
+
+import (
+	"ChatServer/pkg/async"
+	"ChatServer/pkg/connectroute"
+	"ChatServer/pkg/kafka"
+	"ChatServer/pkg/logger"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const (
+	// publishTimeout 单批未送达事件写入 Kafka 的超时
+	publishTimeout = 5 * time.Second
+	// localHandleTimeout 进程内处理单批未送达事件的超时
+	localHandleTimeout = 30 * time.Second
+)
+
+// KafkaSink 将未送达推送写入 Kafka，实现 connectroute.UndeliveredSink。
+// 只有 types 中的信封类型会产生事件（typing/presence 等瞬时帧离线后无意义）。
+type KafkaSink struct {
+	producer *kafka.Producer
+	types    map[string]struct{}
+}
+
+var _ connectroute.UndeliveredSink = (*KafkaSink)(nil)
+
+// NewKafkaSink 创建 Kafka 上报器，producer 为 nil 或 types 为空时返回 nil（不上报）
+func NewKafkaSink(producer *kafka.Producer, types []string) connectroute.UndeliveredSink {
+	if producer == nil || len(types) == 0 {
+		return nil
+	}
+	return &KafkaSink{producer: producer, types: typeSet(types)}
+}
+
+// Undelivered 过滤信封类型后异步写入 Kafka，失败只记录日志
+func (s *KafkaSink) Undelivered(ctx context.Context, items []connectroute.Undelivered) {
+	payloads := make([][]byte, 0, len(items))
+	for _, item := range items {
+		if _, ok := s.types[item.Message.GetType()]; !ok {
+			continue
+		}
+		payload, err := json.Marshal(newEvent(item))
+		if err != nil {
+			continue
+		}
+		payloads = append(payloads, payload)
+	}
+	if len(payloads) == 0 {
+		return
+	}
+
+	async.RunSafe(ctx, func(runCtx context.Context) {
+		if err := s.producer.SendBatch(runCtx, payloads); err != nil {
+			logger.Warn(runCtx, "写入未送达事件失败",
+				logger.Int("count", len(payloads)),
+				logger.ErrorField("error", err),
+			)
+		}
+	}, publishTimeout)
+}
+
+// LocalSink 在进程内直接处理未送达推送，实现 connectroute.UndeliveredSink。
+// 用于没有 Kafka 的单节点部署：不经消息队列，进程重启时尚未处理的事件会丢失。
+type LocalSink struct {
+	handle func(ctx context.Context, event *Event) error
+	types  map[string]struct{}
+}
+
+var _ connectroute.UndeliveredSink = (*LocalSink)(nil)
+
+// NewLocalSink 创建进程内上报器，handle 为 nil 或 types 为空时返回 nil（不上报）
+func NewLocalSink(handle func(ctx context.Context, event *Event) error, types []string) connectroute.UndeliveredSink {
+	if handle == nil || len(types) == 0 {
+		return nil
+	}
+	return &LocalSink{handle: handle, types: typeSet(types)}
+}
+
+// Undelivered 过滤信封类型后异步逐条处理，失败只记录日志
+func (s *LocalSink) Undelivered(ctx context.Context, items []connectroute.Undelivered) {
+	events := make([]*Event, 0, len(items))
+	for _, item := range items {
+		if _, ok := s.types[item.Message.GetType()]; ok {
+			events = append(events, newEvent(item))
+		}
+	}
+	if len(events) == 0 {
+		return
+	}
+
+	async.RunSafe(ctx, func(runCtx context.Context) {
+		for _, event := range events {
+			if err := s.handle(runCtx, event); err != nil {
+				logger.Warn(runCtx, "处理未送达事件失败",
+					logger.String("user_uuid", event.UserUUID),
+					logger.ErrorField("error", err),
+				)
+			}
+		}
+	}, localHandleTimeout)
+}
+
+// typeSet 信封类型列表转集合
+func typeSet(types []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(types))
+	for _, t := range types {
+		set[t] = struct{}{}
+	}
+	return set
+}
+
+// NewKafkaHandler 将事件处理函数适配为 Kafka 消费回调
+func NewKafkaHandler(handle func(ctx context.Context, event *Event) error) kafka.MessageHandler {
+	return func(ctx context.Context, message []byte) error {
+		event, err := DecodeEvent(message)
+		if err != nil {
+			logger.Warn(ctx, "解析未送达事件失败", logger.ErrorField("error", err))
+			return err
+		}
+		return handle(ctx, event)
+	}
+}
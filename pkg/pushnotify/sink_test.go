@@ -0,0 +1,59 @@
+package pushnotify
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	connectpb "ChatServer/apps/connect/pb"
+	"ChatServer/config"
+	"ChatServer/pkg/async"
+	"ChatServer/pkg/connectroute"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var pushNotifyAsyncOnce sync.Once
+
+func initPushNotifyTestAsync(t *testing.T) {
+	t.Helper()
+	pushNotifyAsyncOnce.Do(func() {
+		require.NoError(t, async.Init(config.DefaultAsyncConfig()))
+	})
+}
+
+func TestLocalSinkHandlesConfiguredTypes(t *testing.T) {
+	initPushNotifyTestAsync(t)
+	handled := make(chan *Event, 4)
+	sink := NewLocalSink(func(_ context.Context, event *Event) error {
+		handled <- event
+		return nil
+	}, []string{"message"})
+	require.NotNil(t, sink)
+
+	sink.Undelivered(context.Background(), []connectroute.Undelivered{
+		{UserUUID: "alice", Message: &connectpb.MessageEnvelope{Type: "typing"}},
+		{UserUUID: "bob", DeviceID: "b1", Message: &connectpb.MessageEnvelope{Type: "message", Seq: 7}},
+	})
+
+	select {
+	case event := <-handled:
+		assert.Equal(t, "bob", event.UserUUID)
+		assert.Equal(t, "b1", event.DeviceID)
+		assert.Equal(t, int64(7), event.Seq)
+	case <-time.After(time.Second):
+		t.Fatal("undelivered event not handled")
+	}
+	select {
+	case event := <-handled:
+		t.Fatalf("unexpected event for %s", event.UserUUID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNewLocalSinkDisabled(t *testing.T) {
+	assert.Nil(t, NewLocalSink(nil, []string{"message"}))
+	assert.Nil(t, NewLocalSink(func(context.Context, *Event) error { return nil }, nil))
+}
@@ -0,0 +1,86 @@
+package pushnotify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileProvider 本地桩：把推送按 JSON Lines 追加写入文件，用于本地联调与测试
+type FileProvider struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileProvider 创建文件桩通道
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path: path}
+}
+
+// Name 通道名称
+func (p *FileProvider) Name() string { return "file" }
+
+// Send 追加一行 JSON
+func (p *FileProvider) Send(_ context.Context, n *Notification) error {
+	line, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	f, err := os.OpenFile(p.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// HTTPProvider 本地桩：把推送以 JSON POST 到指定地址（如测试用 mock 服务）。
+// 响应 404/410 视为 token 失效（与 APNs 410 Unregistered 语义一致），其他非 2xx 视为失败。
+type HTTPProvider struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPProvider 创建 HTTP 桩通道
+func NewHTTPProvider(url string) *HTTPProvider {
+	return &HTTPProvider{url: url, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// Name 通道名称
+func (p *HTTPProvider) Name() string { return "http" }
+
+// Send POST 推送 JSON
+func (p *HTTPProvider) Send(ctx context.Context, n *Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrTokenInvalid
+	}
+	return fmt.Errorf("push stub responded %d", resp.StatusCode)
+}